@@ -0,0 +1,8 @@
+// Package api holds the OpenAPI 3.1 specification of the HTTP API, served at /openapi.json and
+// used by the request validator.
+package api
+
+import _ "embed"
+
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/accounting/mock_lib"
+	"github.com/ariesmaulana/payroll/app/timeclock"
+	"github.com/ariesmaulana/payroll/app/user"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/ariesmaulana/payroll/lib/openapi"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// The contract tests fail when the routes or the handlers of user and timeclock drift from openapi.json.
+// A new route, a renamed JSON field or query parameter, a changed guard or response must be documented.
+
+// endpoint is a route registered on the router, handler is its method (eg: user.(*Handler).Login)
+type endpoint struct {
+	handler string
+	auth    bool
+	stepUp  bool
+	scopes  []string
+}
+
+// newRouter registers the routes with the service mocks already generated in app/accounting/mock_lib
+func newRouter(t *testing.T) (chi.Router, *mock_lib.MockUserService, *mock_lib.MockTimeclockService) {
+	ctrl := gomock.NewController(t)
+	userService := mock_lib.NewMockUserService(ctrl)
+	timeclockService := mock_lib.NewMockTimeclockService(ctrl)
+
+	r := chi.NewRouter()
+	user.RegisterRoutes(r, user.NewHandler(userService))
+	timeclock.RegisterRoutes(r, timeclock.NewHandler(timeclockService))
+	return r, userService, timeclockService
+}
+
+func loadSpec(t *testing.T) *openapi.Spec {
+	spec, err := openapi.Load(OpenAPI)
+	require.NoError(t, err)
+	return spec
+}
+
+func funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
+
+// walkRoutes lists the endpoints by "METHOD path", the guards are told from the middlewares of the route
+func walkRoutes(t *testing.T, r chi.Router) map[string]endpoint {
+	endpoints := make(map[string]endpoint)
+	err := chi.Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		name := strings.TrimSuffix(funcName(handler), "-fm")
+		e := endpoint{handler: name[strings.LastIndex(name, "/")+1:]}
+		for _, mw := range middlewares {
+			switch name := funcName(mw); {
+			case name == funcName(middleware.AuthMiddleware):
+				e.auth = true
+			case name == funcName(middleware.RequireStepUp):
+				e.stepUp = true
+			case strings.HasPrefix(name, funcName(middleware.RequireScope)):
+				e.scopes = append(e.scopes, grantedScopes(mw)...)
+			}
+		}
+		endpoints[method+" "+route] = e
+		return nil
+	})
+	require.NoError(t, err)
+	return endpoints
+}
+
+// grantedScopes are the scopes letting an api token through the RequireScope middleware
+func grantedScopes(mw func(http.Handler) http.Handler) []string {
+	var scopes []string
+	for _, scope := range data.APIScopes {
+		reached := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(contextutil.WithTokenUser(req.Context(), &contextutil.AuthUser{TokenId: 1, Scopes: []string{scope}}))
+		mw(next).ServeHTTP(httptest.NewRecorder(), req)
+		if reached {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func operationKey(route *openapi.Route) string {
+	return route.Method + " " + route.Path
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	r, _, _ := newRouter(t)
+	endpoints := walkRoutes(t, r)
+
+	var routes, operations []string
+	for key := range endpoints {
+		routes = append(routes, key)
+	}
+	operationIds := make(map[string]bool)
+	for _, route := range spec.Routes() {
+		operations = append(operations, operationKey(route))
+		assert.NotEmpty(t, route.Operation.OperationID, operationKey(route))
+		assert.False(t, operationIds[route.Operation.OperationID], "operationId %s is used twice", route.Operation.OperationID)
+		operationIds[route.Operation.OperationID] = true
+	}
+	require.ElementsMatch(t, routes, operations, "every route is an operation of the spec")
+
+	for _, route := range spec.Routes() {
+		key := operationKey(route)
+		t.Run(key, func(t *testing.T) {
+			e := endpoints[key]
+
+			var schemes []string
+			var scopes []string
+			for _, requirement := range route.Operation.Security {
+				for scheme, required := range requirement {
+					schemes = append(schemes, scheme)
+					if scheme == "apiToken" {
+						scopes = append(scopes, required...)
+					}
+				}
+			}
+			if e.auth {
+				assert.Contains(t, schemes, "bearerAuth", "the route requires a login")
+			} else {
+				assert.Empty(t, schemes, "the route is public")
+			}
+			assert.ElementsMatch(t, e.scopes, scopes, "api token scopes")
+
+			stepUp := false
+			for _, param := range route.Parameters {
+				if param.In == "header" && param.Name == middleware.StepUpHeader {
+					stepUp = param.Required
+				}
+			}
+			assert.Equal(t, e.stepUp, stepUp, "%s header", middleware.StepUpHeader)
+		})
+	}
+}
+
+// handlerSource is what a handler reads from the request: the JSON fields of its request struct and
+// the path and query parameters
+type handlerSource struct {
+	request string
+	params  map[string]bool
+}
+
+// parseHandlers reads the handlers of a file, keyed like endpoint.handler. The JSON type of a field is
+// empty for a named type (eg: data.UserRole), its values are checked by the enum of the spec.
+func parseHandlers(t *testing.T, pkg string, path string) (map[string]handlerSource, map[string]map[string]string) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	require.NoError(t, err)
+
+	requests := make(map[string]map[string]string)
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, s := range decl.Specs {
+				typeSpec, ok := s.(*ast.TypeSpec)
+				if !ok || !strings.HasSuffix(typeSpec.Name.Name, "Request") {
+					continue
+				}
+				fields := make(map[string]string)
+				for _, field := range typeSpec.Type.(*ast.StructType).Fields.List {
+					tag, _ := strconv.Unquote(field.Tag.Value)
+					name := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+					fields[name] = jsonType(field.Type)
+				}
+				requests[typeSpec.Name.Name] = fields
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				funcs[decl.Name.Name] = decl
+			}
+		}
+	}
+
+	handlers := make(map[string]handlerSource)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil {
+			continue
+		}
+		source := handlerSource{params: make(map[string]bool)}
+		var inspect func(node ast.Node) bool
+		inspect = func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Ident:
+				if _, ok := requests[node.Name]; ok {
+					source.request = node.Name
+				}
+			case *ast.CallExpr:
+				// chi.URLParam(r, "name") and r.URL.Query().Get("name")
+				if sel, ok := node.Fun.(*ast.SelectorExpr); ok && len(node.Args) > 0 {
+					if lit, ok := node.Args[len(node.Args)-1].(*ast.BasicLit); ok && (sel.Sel.Name == "URLParam" || isQueryGet(sel)) {
+						name, _ := strconv.Unquote(lit.Value)
+						source.params[name] = true
+					}
+				}
+				// helpers of the file reading the request, eg: serviceAccountParam(w, r)
+				if ident, ok := node.Fun.(*ast.Ident); ok && funcs[ident.Name] != nil {
+					ast.Inspect(funcs[ident.Name], inspect)
+				}
+			}
+			return true
+		}
+		ast.Inspect(fn.Body, inspect)
+		handlers[pkg+".(*Handler)."+fn.Name.Name] = source
+	}
+	return handlers, requests
+}
+
+func isQueryGet(sel *ast.SelectorExpr) bool {
+	call, ok := sel.X.(*ast.CallExpr)
+	if !ok || sel.Sel.Name != "Get" {
+		return false
+	}
+	query, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && query.Sel.Name == "Query"
+}
+
+func jsonType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		switch {
+		case expr.Name == "string":
+			return "string"
+		case expr.Name == "bool":
+			return "boolean"
+		case strings.HasPrefix(expr.Name, "int"):
+			return "integer"
+		case strings.HasPrefix(expr.Name, "float"):
+			return "number"
+		}
+	case *ast.ArrayType:
+		return "array"
+	case *ast.MapType:
+		return "object"
+	}
+	return ""
+}
+
+func TestSpecMatchesHandlers(t *testing.T) {
+	spec := loadSpec(t)
+	r, _, _ := newRouter(t)
+	endpoints := walkRoutes(t, r)
+
+	handlers := make(map[string]handlerSource)
+	requests := make(map[string]map[string]string)
+	for pkg, path := range map[string]string{"user": "../app/user/handler.go", "timeclock": "../app/timeclock/handler.go"} {
+		h, req := parseHandlers(t, pkg, path)
+		for name, source := range h {
+			handlers[name] = source
+		}
+		for name, fields := range req {
+			requests[pkg+"."+name] = fields
+		}
+	}
+
+	// a handler or a request struct may serve several operations, eg: GET and POST of the SSO callback,
+	// the operations together document every field and parameter
+	params := make(map[string]map[string]bool)
+	properties := make(map[string]map[string]string)
+	for _, route := range spec.Routes() {
+		key := operationKey(route)
+		name := endpoints[key].handler
+		source, ok := handlers[name]
+		require.True(t, ok, "%s: handler %s is not found", key, name)
+		pkg := name[:strings.Index(name, ".")]
+
+		if params[name] == nil {
+			params[name] = make(map[string]bool)
+		}
+		for _, param := range route.Parameters {
+			if param.In == "path" || param.In == "query" {
+				params[name][param.Name] = true
+			}
+		}
+
+		body := route.Operation.RequestBody
+		if source.request == "" {
+			assert.Nil(t, body, "%s: the handler reads no body", key)
+			continue
+		}
+		request := pkg + "." + source.request
+		if properties[request] == nil {
+			properties[request] = make(map[string]string)
+		}
+		if body == nil {
+			continue
+		}
+		media := body.Content["application/json"]
+		require.NotNil(t, media, "%s: application/json body", key)
+		schema := media.Schema
+		for field, property := range schema.Properties {
+			properties[request][field] = ""
+			for _, typ := range property.Type {
+				if typ != "null" {
+					properties[request][field] = typ
+				}
+			}
+		}
+	}
+
+	for name, documented := range params {
+		assert.Equal(t, handlers[name].params, documented, "path and query parameters of %s", name)
+	}
+	for request, documented := range properties {
+		fields := requests[request]
+		assert.ElementsMatch(t, keys(fields), keys(documented), "JSON fields of %s", request)
+		for field, typ := range fields {
+			if typ != "" && documented[field] != "" {
+				assert.Equal(t, typ, documented[field], "type of %s.%s", request, field)
+			}
+		}
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	list := make([]string, 0, len(m))
+	for key := range m {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// stubService answers every method of the mock with a successful output having every field set
+func stubService(mock any, recorder any) {
+	mockValue := reflect.ValueOf(mock)
+	recorderValue := reflect.ValueOf(recorder)
+	for i := 0; i < recorderValue.NumMethod(); i++ {
+		method := recorderValue.Method(i)
+		args := make([]reflect.Value, method.Type().NumIn())
+		for j := range args {
+			args[j] = reflect.ValueOf(gomock.Any())
+		}
+		call := method.Call(args)[0].Interface().(*gomock.Call)
+
+		name := recorderValue.Type().Method(i).Name
+		outType := mockValue.MethodByName(name).Type().Out(0)
+		out := reflect.New(outType.Elem())
+		fill(out.Elem(), "", 0)
+		call.Return(out.Interface()).AnyTimes()
+	}
+}
+
+// fill sets every exported field, a slice or map gets one element. RetryAfter stays 0, a throttled
+// output is a failure.
+func fill(v reflect.Value, name string, depth int) {
+	if depth > 6 {
+		return
+	}
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Date(2025, 6, 19, 17, 30, 0, 0, common.JakartaTZ)))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() && field.Name != "RetryAfter" {
+				fill(v.Field(i), v.Type().Field(i).Name, depth+1)
+			}
+		}
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), name, depth+1)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0), name, depth+1)
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key, "", depth+1)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fill(value, name, depth+1)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, value)
+	case reflect.String:
+		if strings.HasSuffix(name, "URL") {
+			v.SetString("https://sso.example.com/authorize")
+		} else {
+			v.SetString("1")
+		}
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	}
+}
+
+// exampleRequest builds the request of an operation from the examples of the spec
+func exampleRequest(t *testing.T, route *openapi.Route, accessToken string, stepUpToken string) *http.Request {
+	path := route.Path
+	query := url.Values{}
+	header := http.Header{}
+	for _, param := range route.Parameters {
+		if param.Example == nil {
+			require.False(t, param.Required, "required parameter %s has no example", param.Name)
+			continue
+		}
+		value := fmt.Sprint(param.Example)
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", value)
+		case "query":
+			query.Set(param.Name, value)
+		case "header":
+			header.Set(param.Name, value)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var body []byte
+	if requestBody := route.Operation.RequestBody; requestBody != nil {
+		media := requestBody.Content["application/json"]
+		require.NotNil(t, media)
+		require.NotEmpty(t, media.Example, "request body has no example")
+		body = media.Example
+	}
+
+	req := httptest.NewRequest(route.Method, path, bytes.NewReader(body))
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	if len(route.Operation.Security) > 0 {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	if req.Header.Get(middleware.StepUpHeader) != "" {
+		req.Header.Set(middleware.StepUpHeader, stepUpToken)
+	}
+	return req
+}
+
+func TestSpecExamplesRoundTrip(t *testing.T) {
+	spec := loadSpec(t)
+
+	jwtutil.SetSecret("contract-test-secret")
+	accessToken, err := jwtutil.GenerateJWT(1, "admin", data.RAdmin, 0)
+	require.NoError(t, err)
+	stepUpToken, err := jwtutil.GenerateChallengeJWT(1, "admin", data.RAdmin, 0, jwtutil.PurposeStepUp)
+	require.NoError(t, err)
+
+	routes, userService, timeclockService := newRouter(t)
+	stubService(userService, userService.EXPECT())
+	stubService(timeclockService, timeclockService.EXPECT())
+
+	r := chi.NewRouter()
+	r.Use(middleware.TraceMiddleware)
+	r.Use(middleware.OpenAPIValidator(spec))
+	r.Mount("/", routes)
+
+	for _, route := range spec.Routes() {
+		t.Run(operationKey(route), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, exampleRequest(t, route, accessToken, stepUpToken))
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Empty(t, route.ValidateResponse(rec.Code, rec.Body.Bytes()), rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/data"
+)
+
+// defaultAccounts is used for every account key that has no mapping configured
+var defaultAccounts = map[data.AccountKey]*data.AccountMapping{
+	data.AccSalaryExpense:        {AccountKey: data.AccSalaryExpense, AccountCode: "6-1100", AccountName: "Beban Gaji"},
+	data.AccOvertimeExpense:      {AccountKey: data.AccOvertimeExpense, AccountCode: "6-1200", AccountName: "Beban Lembur"},
+	data.AccBonusExpense:         {AccountKey: data.AccBonusExpense, AccountCode: "6-1400", AccountName: "Beban THR dan Bonus"},
+	data.AccReimbursementExpense: {AccountKey: data.AccReimbursementExpense, AccountCode: "6-1300", AccountName: "Beban Reimbursement"},
+	data.AccTaxPayable:           {AccountKey: data.AccTaxPayable, AccountCode: "2-1300", AccountName: "Utang PPh 21"},
+	data.AccBpjsPayable:          {AccountKey: data.AccBpjsPayable, AccountCode: "2-1400", AccountName: "Utang BPJS"},
+	data.AccEmployeeReceivable:   {AccountKey: data.AccEmployeeReceivable, AccountCode: "1-1400", AccountName: "Piutang Karyawan"},
+	data.AccSalaryPayable:        {AccountKey: data.AccSalaryPayable, AccountCode: "2-1100", AccountName: "Utang Gaji"},
+}
+
+// accountKeys keeps the order of the journal lines stable
+var accountKeys = []data.AccountKey{
+	data.AccSalaryExpense,
+	data.AccOvertimeExpense,
+	data.AccBonusExpense,
+	data.AccReimbursementExpense,
+	data.AccTaxPayable,
+	data.AccBpjsPayable,
+	data.AccEmployeeReceivable,
+	data.AccSalaryPayable,
+}
+
+func isValidAccountKey(key data.AccountKey) bool {
+	_, ok := defaultAccounts[key]
+	return ok
+}
+
+// chartOfAccounts resolves an account key (and cost center) into the account to post to.
+// key of the map is accountKey + "|" + costCenter
+type chartOfAccounts map[string]*data.AccountMapping
+
+func newChartOfAccounts(mappings []*data.AccountMapping) chartOfAccounts {
+	chart := make(chartOfAccounts)
+	for key, m := range defaultAccounts {
+		chart[chartKey(key, "")] = m
+	}
+	for _, m := range mappings {
+		chart[chartKey(m.AccountKey, m.CostCenter)] = m
+	}
+	return chart
+}
+
+func chartKey(key data.AccountKey, costCenter string) string {
+	return string(key) + "|" + costCenter
+}
+
+// resolve returns the mapping of the cost center, and falls back to the default mapping of the key
+func (c chartOfAccounts) resolve(key data.AccountKey, costCenter string) *data.AccountMapping {
+	if m, ok := c[chartKey(key, costCenter)]; ok {
+		return m
+	}
+	return c[chartKey(key, "")]
+}
+
+// list returns the effective mappings sorted by account key order then cost center
+func (c chartOfAccounts) list() []*data.AccountMapping {
+	order := make(map[data.AccountKey]int)
+	for i, key := range accountKeys {
+		order[key] = i
+	}
+
+	result := make([]*data.AccountMapping, 0, len(c))
+	for _, m := range c {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].AccountKey != result[j].AccountKey {
+			return order[result[i].AccountKey] < order[result[j].AccountKey]
+		}
+		return result[i].CostCenter < result[j].CostCenter
+	})
+	return result
+}
+
+// buildPayrollJournal turns a payroll batch into one journal entry:
+//
+//	Dr salary expense (per cost center)          base salary
+//	Dr overtime expense (per cost center)        overtime pay
+//	Dr bonus expense (per cost center)           THR / bonus
+//	Dr reimbursement expense (per cost center)   reimbursement
+//	    Cr tax payable                           PPh 21 withheld
+//	    Cr BPJS payable                          BPJS withheld
+//	    Cr employee receivable                   loan installment (kasbon) deducted
+//	    Cr net salary payable                    gross - tax - BPJS - loan
+//
+// A negative amount, eg: a correction taking back an overpayment, is posted on the other side.
+// costCenters key is userId and value is the user cost center. Lines with zero amount are skipped.
+// The items must have their pay components, see itemsWithoutComponents.
+func buildPayrollJournal(
+	payroll *data.Payroll,
+	items []*data.PayrollItem,
+	costCenters map[int]string,
+	chart chartOfAccounts,
+) *data.JournalEntry {
+	// key is the expense account, value is the amount per cost center
+	expenses := make(map[data.AccountKey]map[string]int)
+	addExpense := func(key data.AccountKey, costCenter string, amount int) {
+		if expenses[key] == nil {
+			expenses[key] = make(map[string]int)
+		}
+		expenses[key][costCenter] += amount
+	}
+	var tax, bpjs, loan, net int
+
+	for _, item := range items {
+		costCenter := costCenters[item.UserId]
+		addExpense(data.AccSalaryExpense, costCenter, item.BaseSalaryAmount)
+		addExpense(data.AccOvertimeExpense, costCenter, item.OvertimeAmount)
+		addExpense(data.AccBonusExpense, costCenter, item.BonusAmount)
+		addExpense(data.AccReimbursementExpense, costCenter, item.ReimbursementTotal)
+		tax += item.TaxAmount
+		bpjs += item.BpjsAmount
+		loan += item.LoanDeduction
+		net += item.TotalSalary - item.TaxAmount - item.BpjsAmount - item.LoanDeduction
+	}
+
+	period := payroll.PeriodStart.Format("2006-01-02") + " s/d " + payroll.PeriodEnd.Format("2006-01-02")
+	journal := &data.JournalEntry{
+		PayrollId:   payroll.Id,
+		Date:        payroll.PeriodEnd,
+		Reference:   fmt.Sprintf("PAYROLL-%d", payroll.Id),
+		Description: payrollLabel(payroll.Type) + " " + period,
+	}
+
+	addLine := func(key data.AccountKey, costCenter string, debit, credit int) {
+		if debit == 0 && credit == 0 {
+			return
+		}
+		if debit < 0 || credit < 0 {
+			debit, credit = -credit, -debit
+		}
+		account := chart.resolve(key, costCenter)
+		journal.Lines = append(journal.Lines, &data.JournalLine{
+			AccountKey:  key,
+			AccountCode: account.AccountCode,
+			AccountName: account.AccountName,
+			CostCenter:  costCenter,
+			Description: account.AccountName + " " + period,
+			Debit:       debit,
+			Credit:      credit,
+		})
+		journal.TotalDebit += debit
+		journal.TotalCredit += credit
+	}
+
+	for _, key := range []data.AccountKey{data.AccSalaryExpense, data.AccOvertimeExpense, data.AccBonusExpense, data.AccReimbursementExpense} {
+		costCenterNames := make([]string, 0, len(expenses[key]))
+		for costCenter := range expenses[key] {
+			costCenterNames = append(costCenterNames, costCenter)
+		}
+		sort.Strings(costCenterNames)
+
+		for _, costCenter := range costCenterNames {
+			addLine(key, costCenter, expenses[key][costCenter], 0)
+		}
+	}
+	addLine(data.AccTaxPayable, "", 0, tax)
+	addLine(data.AccBpjsPayable, "", 0, bpjs)
+	addLine(data.AccEmployeeReceivable, "", 0, loan)
+	addLine(data.AccSalaryPayable, "", 0, net)
+
+	return journal
+}
+
+// itemsWithoutComponents returns the user of every item whose pay components do not add up to its total.
+// Items stored before the components were split only have the total and the reimbursement, their
+// expense accounts are unknown so they are not posted.
+func itemsWithoutComponents(items []*data.PayrollItem) []int {
+	var userIds []int
+	for _, item := range items {
+		if item.BaseSalaryAmount+item.OvertimeAmount+item.BonusAmount+item.ReimbursementTotal != item.TotalSalary {
+			userIds = append(userIds, item.UserId)
+		}
+	}
+	return userIds
+}
+
+func isBalanced(journal *data.JournalEntry) bool {
+	var debit, credit int
+	for _, line := range journal.Lines {
+		debit += line.Debit
+		credit += line.Credit
+	}
+	return debit == credit && debit == journal.TotalDebit && credit == journal.TotalCredit
+}
+
+var journalCSVHeader = []string{
+	"date", "reference", "account_code", "account_name", "cost_center", "description", "debit", "credit",
+}
+
+// writeJournalCSV writes one row per journal line, using a flat layout that
+// can be mapped by the journal import of Accurate, Jurnal.id and Odoo
+func writeJournalCSV(w io.Writer, journal *data.JournalEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(journalCSVHeader); err != nil {
+		return err
+	}
+
+	date := journal.Date.Format("2006-01-02")
+	for _, line := range journal.Lines {
+		row := []string{
+			date,
+			journal.Reference,
+			line.AccountCode,
+			line.AccountName,
+			line.CostCenter,
+			line.Description,
+			strconv.Itoa(line.Debit),
+			strconv.Itoa(line.Credit),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func payrollLabel(payrollType data.PayrollType) string {
+	if payrollType == data.PayrollTHR {
+		return "THR"
+	}
+	return "Payroll"
+}
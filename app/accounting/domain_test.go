@@ -0,0 +1,218 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPayrollJournalBalanced(t *testing.T) {
+	t.Parallel()
+
+	payroll := &data.Payroll{
+		Id:          7,
+		PeriodStart: common.NewDate(2025, 1, 1),
+		PeriodEnd:   common.NewDate(2025, 1, 31),
+	}
+
+	type testCase struct {
+		name        string
+		items       []*data.PayrollItem
+		costCenters map[int]string
+		totalDebit  int
+		lines       int
+	}
+
+	scenarios := []testCase{
+		{
+			name: "single cost center without deduction",
+			items: []*data.PayrollItem{
+				{UserId: 1, BaseSalaryAmount: 3000000, OvertimeAmount: 100000, ReimbursementTotal: 50000, TotalSalary: 3150000},
+				{UserId: 2, BaseSalaryAmount: 2000000, TotalSalary: 2000000},
+			},
+			costCenters: map[int]string{1: "GENERAL", 2: "GENERAL"},
+			totalDebit:  5150000,
+			lines:       4, // salary, overtime, reimbursement, net salary
+		},
+		{
+			name: "multiple cost center with tax and bpjs",
+			items: []*data.PayrollItem{
+				{UserId: 1, BaseSalaryAmount: 10000000, OvertimeAmount: 250000, TaxAmount: 300000, BpjsAmount: 200000, TotalSalary: 10250000},
+				{UserId: 2, BaseSalaryAmount: 7000000, ReimbursementTotal: 125000, TaxAmount: 105000, BpjsAmount: 140000, TotalSalary: 7125000},
+				{UserId: 3, BaseSalaryAmount: 5333333, OvertimeAmount: 66666, TaxAmount: 1, BpjsAmount: 106666, TotalSalary: 5399999},
+			},
+			costCenters: map[int]string{1: "ENG", 2: "SALES", 3: "ENG"},
+			totalDebit:  22774999,
+			lines:       7, // 2 salary, overtime, reimbursement, tax, bpjs, net salary
+		},
+		{
+			name: "thr with tax",
+			items: []*data.PayrollItem{
+				{UserId: 1, BonusAmount: 10000000, TaxAmount: 200000, TotalSalary: 10000000},
+				{UserId: 2, BonusAmount: 2500000, TotalSalary: 2500000},
+			},
+			costCenters: map[int]string{1: "GENERAL", 2: "GENERAL"},
+			totalDebit:  12500000,
+			lines:       3, // bonus, tax, net salary
+		},
+		{
+			name: "with loan deduction",
+			items: []*data.PayrollItem{
+				{UserId: 1, BaseSalaryAmount: 5000000, TaxAmount: 50000, LoanDeduction: 1000000, TotalSalary: 5000000},
+				{UserId: 2, BaseSalaryAmount: 4000000, TotalSalary: 4000000},
+			},
+			costCenters: map[int]string{1: "GENERAL", 2: "GENERAL"},
+			totalDebit:  9000000,
+			lines:       4, // salary, tax, employee receivable, net salary
+		},
+		{
+			name: "negative correction",
+			items: []*data.PayrollItem{
+				{UserId: 1, BaseSalaryAmount: -500000, TotalSalary: -500000},
+				{UserId: 2, BaseSalaryAmount: 200000, TotalSalary: 200000},
+			},
+			costCenters: map[int]string{1: "ENG", 2: "SALES"},
+			totalDebit:  500000,
+			lines:       3, // salary credited on ENG, salary on SALES, net salary debited
+		},
+		{
+			name:        "empty payroll",
+			items:       nil,
+			costCenters: map[int]string{},
+			totalDebit:  0,
+			lines:       0,
+		},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			journal := buildPayrollJournal(payroll, sc.items, sc.costCenters, newChartOfAccounts(nil))
+
+			assert.True(t, isBalanced(journal))
+			assert.Equal(t, sc.totalDebit, journal.TotalDebit)
+			assert.Equal(t, journal.TotalDebit, journal.TotalCredit)
+			assert.Len(t, journal.Lines, sc.lines)
+			assert.Equal(t, "PAYROLL-7", journal.Reference)
+
+			for _, line := range journal.Lines {
+				// a line is either debit or credit, never both
+				assert.True(t, (line.Debit == 0) != (line.Credit == 0))
+			}
+		})
+	}
+}
+
+func TestBuildPayrollJournalNegativeCorrection(t *testing.T) {
+	t.Parallel()
+
+	payroll := &data.Payroll{
+		Id:          3,
+		Type:        data.PayrollCorrection,
+		PeriodStart: common.NewDate(2025, 1, 1),
+		PeriodEnd:   common.NewDate(2025, 1, 31),
+	}
+	items := []*data.PayrollItem{{UserId: 1, BaseSalaryAmount: -500000, TotalSalary: -500000}}
+
+	journal := buildPayrollJournal(payroll, items, map[int]string{1: "GENERAL"}, newChartOfAccounts(nil))
+	assert.True(t, isBalanced(journal))
+	assert.Len(t, journal.Lines, 2)
+
+	// the overpaid salary is taken back from the expense and owed by the employee
+	assert.Equal(t, data.AccSalaryExpense, journal.Lines[0].AccountKey)
+	assert.Equal(t, 0, journal.Lines[0].Debit)
+	assert.Equal(t, 500000, journal.Lines[0].Credit)
+	assert.Equal(t, data.AccSalaryPayable, journal.Lines[1].AccountKey)
+	assert.Equal(t, 500000, journal.Lines[1].Debit)
+	assert.Equal(t, 0, journal.Lines[1].Credit)
+}
+
+func TestItemsWithoutComponents(t *testing.T) {
+	t.Parallel()
+
+	items := []*data.PayrollItem{
+		{UserId: 1, BaseSalaryAmount: 3000000, OvertimeAmount: 100000, ReimbursementTotal: 50000, TotalSalary: 3150000},
+		{UserId: 2, BonusAmount: 2500000, TotalSalary: 2500000},
+		{UserId: 3, BaseSalaryAmount: -500000, TotalSalary: -500000},
+		// stored before the pay components were split
+		{UserId: 4, ReimbursementTotal: 50000, TotalSalary: 3150000},
+		{UserId: 5, TotalSalary: 2000000},
+	}
+
+	assert.Equal(t, []int{4, 5}, itemsWithoutComponents(items))
+	assert.Empty(t, itemsWithoutComponents(items[:3]))
+}
+
+func TestBuildPayrollJournalUseCostCenterMapping(t *testing.T) {
+	t.Parallel()
+
+	payroll := &data.Payroll{
+		Id:          1,
+		PeriodStart: common.NewDate(2025, 1, 1),
+		PeriodEnd:   common.NewDate(2025, 1, 31),
+	}
+	items := []*data.PayrollItem{
+		{UserId: 1, BaseSalaryAmount: 1000, OvertimeAmount: 100, TotalSalary: 1100},
+		{UserId: 2, BaseSalaryAmount: 2000, ReimbursementTotal: 50, TotalSalary: 2050},
+	}
+	chart := newChartOfAccounts([]*data.AccountMapping{
+		{AccountKey: data.AccSalaryExpense, CostCenter: "ENG", AccountCode: "6-1101", AccountName: "Beban Gaji Engineering"},
+		{AccountKey: data.AccSalaryPayable, AccountCode: "2-9999", AccountName: "Utang Gaji Karyawan"},
+	})
+
+	journal := buildPayrollJournal(payroll, items, map[int]string{1: "ENG", 2: "OPS"}, chart)
+	assert.True(t, isBalanced(journal))
+	assert.Len(t, journal.Lines, 5)
+
+	// cost center with its own mapping
+	assert.Equal(t, "ENG", journal.Lines[0].CostCenter)
+	assert.Equal(t, "6-1101", journal.Lines[0].AccountCode)
+	assert.Equal(t, 1000, journal.Lines[0].Debit)
+
+	// cost center fallback to default mapping
+	assert.Equal(t, "OPS", journal.Lines[1].CostCenter)
+	assert.Equal(t, "6-1100", journal.Lines[1].AccountCode)
+	assert.Equal(t, 2000, journal.Lines[1].Debit)
+
+	// overtime and reimbursement are posted to the cost center of the employee
+	assert.Equal(t, data.AccOvertimeExpense, journal.Lines[2].AccountKey)
+	assert.Equal(t, "ENG", journal.Lines[2].CostCenter)
+	assert.Equal(t, 100, journal.Lines[2].Debit)
+	assert.Equal(t, data.AccReimbursementExpense, journal.Lines[3].AccountKey)
+	assert.Equal(t, "OPS", journal.Lines[3].CostCenter)
+	assert.Equal(t, 50, journal.Lines[3].Debit)
+
+	// configured default mapping override the built in one
+	assert.Equal(t, "2-9999", journal.Lines[4].AccountCode)
+	assert.Equal(t, 3150, journal.Lines[4].Credit)
+}
+
+func TestWriteJournalCSV(t *testing.T) {
+	t.Parallel()
+
+	payroll := &data.Payroll{
+		Id:          3,
+		PeriodStart: common.NewDate(2025, 2, 1),
+		PeriodEnd:   common.NewDate(2025, 2, 28),
+	}
+	items := []*data.PayrollItem{
+		{UserId: 1, BaseSalaryAmount: 5000000, TaxAmount: 50000, BpjsAmount: 100000, TotalSalary: 5000000},
+	}
+	journal := buildPayrollJournal(payroll, items, map[int]string{1: "GENERAL"}, newChartOfAccounts(nil))
+
+	var buf bytes.Buffer
+	err := writeJournalCSV(&buf, journal)
+	assert.Nil(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, rows, 5) // header + salary, tax, bpjs, net salary
+	assert.Equal(t, journalCSVHeader, rows[0])
+	assert.Equal(t, []string{"2025-02-28", "PAYROLL-3", "6-1100", "Beban Gaji", "GENERAL", "Beban Gaji 2025-02-01 s/d 2025-02-28", "5000000", "0"}, rows[1])
+	assert.Equal(t, []string{"2-1100", "4850000"}, []string{rows[4][2], rows[4][7]})
+}
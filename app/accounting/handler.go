@@ -0,0 +1,120 @@
+package accounting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/app/accounting/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// PayrollJournal export the journal of a payroll, format is `json` (default) or `csv`
+func (h *Handler) PayrollJournal(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	payrollId, err := strconv.Atoi(chi.URLParam(r, "payrollId"))
+	if err != nil {
+		response.Error(w, r, "Param 'payrollId' harus angka")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		response.Error(w, r, "Param 'format' harus json atau csv")
+		return
+	}
+
+	out := h.service.PayrollJournal(r.Context(), &lib.PayrollJournalIn{
+		Trace:     trace,
+		PayrollId: payrollId,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=journal-payroll-%d.csv", payrollId))
+		if err := writeJournalCSV(w, out.Journal); err != nil {
+			log.Error(trace).Err(err).Msg("PayrollJournal/ failed write csv")
+		}
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Journal)
+}
+
+func (h *Handler) ListAccountMappings(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.ListAccountMappings(r.Context(), &lib.ListAccountMappingsIn{
+		Trace: trace,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+type setAccountMappingRequest struct {
+	AccountKey  string `json:"account_key"`
+	CostCenter  string `json:"cost_center"` // optional, empty means default for all cost centers
+	AccountCode string `json:"account_code"`
+	AccountName string `json:"account_name"`
+}
+
+func (h *Handler) SetAccountMapping(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req setAccountMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.SetAccountMapping(r.Context(), &lib.SetAccountMappingIn{
+		Trace:       trace,
+		AccountKey:  data.AccountKey(req.AccountKey),
+		CostCenter:  req.CostCenter,
+		AccountCode: req.AccountCode,
+		AccountName: req.AccountName,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
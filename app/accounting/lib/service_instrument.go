@@ -0,0 +1,63 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named accounting.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="accounting"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) PayrollJournal(ctx context.Context, in *PayrollJournalIn) *PayrollJournalOut {
+	ctx, span := tracing.Start(ctx, "accounting.PayrollJournal")
+	start := time.Now()
+	out := s.next.PayrollJournal(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("accounting", "PayrollJournal", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListAccountMappings(ctx context.Context, in *ListAccountMappingsIn) *ListAccountMappingsOut {
+	ctx, span := tracing.Start(ctx, "accounting.ListAccountMappings")
+	start := time.Now()
+	out := s.next.ListAccountMappings(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("accounting", "ListAccountMappings", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SetAccountMapping(ctx context.Context, in *SetAccountMappingIn) *SetAccountMappingOut {
+	ctx, span := tracing.Start(ctx, "accounting.SetAccountMapping")
+	start := time.Now()
+	out := s.next.SetAccountMapping(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("accounting", "SetAccountMapping", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
@@ -0,0 +1,58 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// PayrollJournal builds the balanced journal entry of a payroll batch
+	PayrollJournal(ctx context.Context, in *PayrollJournalIn) *PayrollJournalOut
+
+	ListAccountMappings(ctx context.Context, in *ListAccountMappingsIn) *ListAccountMappingsOut
+	SetAccountMapping(ctx context.Context, in *SetAccountMappingIn) *SetAccountMappingOut
+}
+
+type PayrollJournalIn struct {
+	Trace     *contextutil.Trace
+	PayrollId int
+}
+
+type PayrollJournalOut struct {
+	Success bool
+	apperror.Failure
+
+	Journal *data.JournalEntry
+}
+
+type ListAccountMappingsIn struct {
+	Trace *contextutil.Trace
+}
+
+type ListAccountMappingsOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result is the effective chart of accounts, configured mapping overrides the default one
+	Result []*data.AccountMapping
+}
+
+type SetAccountMappingIn struct {
+	Trace       *contextutil.Trace
+	AccountKey  data.AccountKey
+	CostCenter  string
+	AccountCode string
+	AccountName string
+}
+
+type SetAccountMappingOut struct {
+	Success bool
+	apperror.Failure
+
+	Id int
+}
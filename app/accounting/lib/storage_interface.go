@@ -0,0 +1,20 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	// GetAccountMappings returns every configured chart-of-accounts mapping
+	GetAccountMappings(ctx context.Context) ([]*data.AccountMapping, error)
+
+	// UpsertAccountMapping inserts or replaces the mapping of accountKey for a cost center.
+	// An empty costCenter is the default mapping for the key.
+	UpsertAccountMapping(ctx context.Context, accountKey data.AccountKey, costCenter string, accountCode string, accountName string, updatedBy string) (int, error)
+}
@@ -0,0 +1,490 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ariesmaulana/payroll/app/user/lib (interfaces: ServiceInterface)
+//
+// Generated by this command:
+//
+//	mockgen -mock_names ServiceInterface=MockUserService github.com/ariesmaulana/payroll/app/user/lib ServiceInterface
+//
+
+// Package mock_lib is a generated GoMock package.
+package mock_lib
+
+import (
+	context "context"
+	reflect "reflect"
+
+	lib "github.com/ariesmaulana/payroll/app/user/lib"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserService is a mock of ServiceInterface interface.
+type MockUserService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockUserServiceMockRecorder is the mock recorder for MockUserService.
+type MockUserServiceMockRecorder struct {
+	mock *MockUserService
+}
+
+// NewMockUserService creates a new mock instance.
+func NewMockUserService(ctrl *gomock.Controller) *MockUserService {
+	mock := &MockUserService{ctrl: ctrl}
+	mock.recorder = &MockUserServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateAPIToken mocks base method.
+func (m *MockUserService) AuthenticateAPIToken(ctx context.Context, in *lib.AuthenticateAPITokenIn) *lib.AuthenticateAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.AuthenticateAPITokenOut)
+	return ret0
+}
+
+// AuthenticateAPIToken indicates an expected call of AuthenticateAPIToken.
+func (mr *MockUserServiceMockRecorder) AuthenticateAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIToken", reflect.TypeOf((*MockUserService)(nil).AuthenticateAPIToken), ctx, in)
+}
+
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(ctx context.Context, in *lib.ChangePasswordIn) *lib.ChangePasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ChangePasswordOut)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), ctx, in)
+}
+
+// CompleteSSOLogin mocks base method.
+func (m *MockUserService) CompleteSSOLogin(ctx context.Context, in *lib.CompleteSSOLoginIn) *lib.LoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteSSOLogin", ctx, in)
+	ret0, _ := ret[0].(*lib.LoginOut)
+	return ret0
+}
+
+// CompleteSSOLogin indicates an expected call of CompleteSSOLogin.
+func (mr *MockUserServiceMockRecorder) CompleteSSOLogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteSSOLogin", reflect.TypeOf((*MockUserService)(nil).CompleteSSOLogin), ctx, in)
+}
+
+// ConfirmMFAEnrollment mocks base method.
+func (m *MockUserService) ConfirmMFAEnrollment(ctx context.Context, in *lib.ConfirmMFAEnrollmentIn) *lib.ConfirmMFAEnrollmentOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmMFAEnrollment", ctx, in)
+	ret0, _ := ret[0].(*lib.ConfirmMFAEnrollmentOut)
+	return ret0
+}
+
+// ConfirmMFAEnrollment indicates an expected call of ConfirmMFAEnrollment.
+func (mr *MockUserServiceMockRecorder) ConfirmMFAEnrollment(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFAEnrollment", reflect.TypeOf((*MockUserService)(nil).ConfirmMFAEnrollment), ctx, in)
+}
+
+// CreateAPIToken mocks base method.
+func (m *MockUserService) CreateAPIToken(ctx context.Context, in *lib.CreateAPITokenIn) *lib.CreateAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateAPITokenOut)
+	return ret0
+}
+
+// CreateAPIToken indicates an expected call of CreateAPIToken.
+func (mr *MockUserServiceMockRecorder) CreateAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIToken", reflect.TypeOf((*MockUserService)(nil).CreateAPIToken), ctx, in)
+}
+
+// CreateServiceAccount mocks base method.
+func (m *MockUserService) CreateServiceAccount(ctx context.Context, in *lib.CreateServiceAccountIn) *lib.CreateServiceAccountOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceAccount", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateServiceAccountOut)
+	return ret0
+}
+
+// CreateServiceAccount indicates an expected call of CreateServiceAccount.
+func (mr *MockUserServiceMockRecorder) CreateServiceAccount(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceAccount", reflect.TypeOf((*MockUserService)(nil).CreateServiceAccount), ctx, in)
+}
+
+// CreateUser mocks base method.
+func (m *MockUserService) CreateUser(ctx context.Context, in *lib.CreateUserIn) *lib.CreateUserOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateUserOut)
+	return ret0
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserServiceMockRecorder) CreateUser(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), ctx, in)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockUserService) ForgotPassword(ctx context.Context, in *lib.ForgotPasswordIn) *lib.ForgotPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ForgotPasswordOut)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockUserServiceMockRecorder) ForgotPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockUserService)(nil).ForgotPassword), ctx, in)
+}
+
+// ListAPITokens mocks base method.
+func (m *MockUserService) ListAPITokens(ctx context.Context, in *lib.ListAPITokensIn) *lib.ListAPITokensOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPITokens", ctx, in)
+	ret0, _ := ret[0].(*lib.ListAPITokensOut)
+	return ret0
+}
+
+// ListAPITokens indicates an expected call of ListAPITokens.
+func (mr *MockUserServiceMockRecorder) ListAPITokens(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPITokens", reflect.TypeOf((*MockUserService)(nil).ListAPITokens), ctx, in)
+}
+
+// ListServiceAccounts mocks base method.
+func (m *MockUserService) ListServiceAccounts(ctx context.Context, in *lib.ListServiceAccountsIn) *lib.ListServiceAccountsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServiceAccounts", ctx, in)
+	ret0, _ := ret[0].(*lib.ListServiceAccountsOut)
+	return ret0
+}
+
+// ListServiceAccounts indicates an expected call of ListServiceAccounts.
+func (mr *MockUserServiceMockRecorder) ListServiceAccounts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServiceAccounts", reflect.TypeOf((*MockUserService)(nil).ListServiceAccounts), ctx, in)
+}
+
+// Login mocks base method.
+func (m *MockUserService) Login(ctx context.Context, in *lib.LoginIn) *lib.LoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, in)
+	ret0, _ := ret[0].(*lib.LoginOut)
+	return ret0
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUserServiceMockRecorder) Login(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, in)
+}
+
+// Logout mocks base method.
+func (m *MockUserService) Logout(ctx context.Context, in *lib.LogoutIn) *lib.LogoutOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, in)
+	ret0, _ := ret[0].(*lib.LogoutOut)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUserServiceMockRecorder) Logout(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUserService)(nil).Logout), ctx, in)
+}
+
+// MFAStepUp mocks base method.
+func (m *MockUserService) MFAStepUp(ctx context.Context, in *lib.MFAStepUpIn) *lib.MFAStepUpOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MFAStepUp", ctx, in)
+	ret0, _ := ret[0].(*lib.MFAStepUpOut)
+	return ret0
+}
+
+// MFAStepUp indicates an expected call of MFAStepUp.
+func (mr *MockUserServiceMockRecorder) MFAStepUp(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MFAStepUp", reflect.TypeOf((*MockUserService)(nil).MFAStepUp), ctx, in)
+}
+
+// RefreshToken mocks base method.
+func (m *MockUserService) RefreshToken(ctx context.Context, in *lib.RefreshTokenIn) *lib.RefreshTokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, in)
+	ret0, _ := ret[0].(*lib.RefreshTokenOut)
+	return ret0
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockUserServiceMockRecorder) RefreshToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockUserService)(nil).RefreshToken), ctx, in)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUserService) ResetPassword(ctx context.Context, in *lib.ResetPasswordIn) *lib.ResetPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ResetPasswordOut)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserServiceMockRecorder) ResetPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserService)(nil).ResetPassword), ctx, in)
+}
+
+// RevokeAPIToken mocks base method.
+func (m *MockUserService) RevokeAPIToken(ctx context.Context, in *lib.RevokeAPITokenIn) *lib.RevokeAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.RevokeAPITokenOut)
+	return ret0
+}
+
+// RevokeAPIToken indicates an expected call of RevokeAPIToken.
+func (mr *MockUserServiceMockRecorder) RevokeAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIToken", reflect.TypeOf((*MockUserService)(nil).RevokeAPIToken), ctx, in)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockUserService) RevokeSessions(ctx context.Context, in *lib.RevokeSessionsIn) *lib.RevokeSessionsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, in)
+	ret0, _ := ret[0].(*lib.RevokeSessionsOut)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockUserServiceMockRecorder) RevokeSessions(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockUserService)(nil).RevokeSessions), ctx, in)
+}
+
+// SetBankAccount mocks base method.
+func (m *MockUserService) SetBankAccount(ctx context.Context, in *lib.SetBankAccountIn) *lib.SetBankAccountOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBankAccount", ctx, in)
+	ret0, _ := ret[0].(*lib.SetBankAccountOut)
+	return ret0
+}
+
+// SetBankAccount indicates an expected call of SetBankAccount.
+func (mr *MockUserServiceMockRecorder) SetBankAccount(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBankAccount", reflect.TypeOf((*MockUserService)(nil).SetBankAccount), ctx, in)
+}
+
+// SetPassword mocks base method.
+func (m *MockUserService) SetPassword(ctx context.Context, in *lib.SetPasswordIn) *lib.SetPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.SetPasswordOut)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockUserServiceMockRecorder) SetPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockUserService)(nil).SetPassword), ctx, in)
+}
+
+// SetReligion mocks base method.
+func (m *MockUserService) SetReligion(ctx context.Context, in *lib.SetReligionIn) *lib.SetReligionOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReligion", ctx, in)
+	ret0, _ := ret[0].(*lib.SetReligionOut)
+	return ret0
+}
+
+// SetReligion indicates an expected call of SetReligion.
+func (mr *MockUserServiceMockRecorder) SetReligion(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReligion", reflect.TypeOf((*MockUserService)(nil).SetReligion), ctx, in)
+}
+
+// SetTaxProfile mocks base method.
+func (m *MockUserService) SetTaxProfile(ctx context.Context, in *lib.SetTaxProfileIn) *lib.SetTaxProfileOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTaxProfile", ctx, in)
+	ret0, _ := ret[0].(*lib.SetTaxProfileOut)
+	return ret0
+}
+
+// SetTaxProfile indicates an expected call of SetTaxProfile.
+func (mr *MockUserServiceMockRecorder) SetTaxProfile(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTaxProfile", reflect.TypeOf((*MockUserService)(nil).SetTaxProfile), ctx, in)
+}
+
+// StartMFAEnrollment mocks base method.
+func (m *MockUserService) StartMFAEnrollment(ctx context.Context, in *lib.StartMFAEnrollmentIn) *lib.StartMFAEnrollmentOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartMFAEnrollment", ctx, in)
+	ret0, _ := ret[0].(*lib.StartMFAEnrollmentOut)
+	return ret0
+}
+
+// StartMFAEnrollment indicates an expected call of StartMFAEnrollment.
+func (mr *MockUserServiceMockRecorder) StartMFAEnrollment(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartMFAEnrollment", reflect.TypeOf((*MockUserService)(nil).StartMFAEnrollment), ctx, in)
+}
+
+// StartSSOLogin mocks base method.
+func (m *MockUserService) StartSSOLogin(ctx context.Context, in *lib.StartSSOLoginIn) *lib.StartSSOLoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartSSOLogin", ctx, in)
+	ret0, _ := ret[0].(*lib.StartSSOLoginOut)
+	return ret0
+}
+
+// StartSSOLogin indicates an expected call of StartSSOLogin.
+func (mr *MockUserServiceMockRecorder) StartSSOLogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSSOLogin", reflect.TypeOf((*MockUserService)(nil).StartSSOLogin), ctx, in)
+}
+
+// UnlockUser mocks base method.
+func (m *MockUserService) UnlockUser(ctx context.Context, in *lib.UnlockUserIn) *lib.UnlockUserOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, in)
+	ret0, _ := ret[0].(*lib.UnlockUserOut)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockUserServiceMockRecorder) UnlockUser(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockUserService)(nil).UnlockUser), ctx, in)
+}
+
+// UserBankAccounts mocks base method.
+func (m *MockUserService) UserBankAccounts(ctx context.Context, in *lib.UserBankAccountsIn) *lib.UserBankAccountsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserBankAccounts", ctx, in)
+	ret0, _ := ret[0].(*lib.UserBankAccountsOut)
+	return ret0
+}
+
+// UserBankAccounts indicates an expected call of UserBankAccounts.
+func (mr *MockUserServiceMockRecorder) UserBankAccounts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserBankAccounts", reflect.TypeOf((*MockUserService)(nil).UserBankAccounts), ctx, in)
+}
+
+// UserContacts mocks base method.
+func (m *MockUserService) UserContacts(ctx context.Context, in *lib.UserContactsIn) *lib.UserContactsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserContacts", ctx, in)
+	ret0, _ := ret[0].(*lib.UserContactsOut)
+	return ret0
+}
+
+// UserContacts indicates an expected call of UserContacts.
+func (mr *MockUserServiceMockRecorder) UserContacts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserContacts", reflect.TypeOf((*MockUserService)(nil).UserContacts), ctx, in)
+}
+
+// UserCostCenter mocks base method.
+func (m *MockUserService) UserCostCenter(ctx context.Context, in *lib.UserCostCenterIn) *lib.UserCostCenterOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserCostCenter", ctx, in)
+	ret0, _ := ret[0].(*lib.UserCostCenterOut)
+	return ret0
+}
+
+// UserCostCenter indicates an expected call of UserCostCenter.
+func (mr *MockUserServiceMockRecorder) UserCostCenter(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserCostCenter", reflect.TypeOf((*MockUserService)(nil).UserCostCenter), ctx, in)
+}
+
+// UserEmployments mocks base method.
+func (m *MockUserService) UserEmployments(ctx context.Context, in *lib.UserEmploymentsIn) *lib.UserEmploymentsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserEmployments", ctx, in)
+	ret0, _ := ret[0].(*lib.UserEmploymentsOut)
+	return ret0
+}
+
+// UserEmployments indicates an expected call of UserEmployments.
+func (mr *MockUserServiceMockRecorder) UserEmployments(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserEmployments", reflect.TypeOf((*MockUserService)(nil).UserEmployments), ctx, in)
+}
+
+// UserSalary mocks base method.
+func (m *MockUserService) UserSalary(ctx context.Context, in *lib.UserSalaryIn) *lib.UserSalaryOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSalary", ctx, in)
+	ret0, _ := ret[0].(*lib.UserSalaryOut)
+	return ret0
+}
+
+// UserSalary indicates an expected call of UserSalary.
+func (mr *MockUserServiceMockRecorder) UserSalary(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSalary", reflect.TypeOf((*MockUserService)(nil).UserSalary), ctx, in)
+}
+
+// UserTaxProfiles mocks base method.
+func (m *MockUserService) UserTaxProfiles(ctx context.Context, in *lib.UserTaxProfilesIn) *lib.UserTaxProfilesOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserTaxProfiles", ctx, in)
+	ret0, _ := ret[0].(*lib.UserTaxProfilesOut)
+	return ret0
+}
+
+// UserTaxProfiles indicates an expected call of UserTaxProfiles.
+func (mr *MockUserServiceMockRecorder) UserTaxProfiles(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserTaxProfiles", reflect.TypeOf((*MockUserService)(nil).UserTaxProfiles), ctx, in)
+}
+
+// ValidateSession mocks base method.
+func (m *MockUserService) ValidateSession(ctx context.Context, in *lib.ValidateSessionIn) *lib.ValidateSessionOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateSession", ctx, in)
+	ret0, _ := ret[0].(*lib.ValidateSessionOut)
+	return ret0
+}
+
+// ValidateSession indicates an expected call of ValidateSession.
+func (mr *MockUserServiceMockRecorder) ValidateSession(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateSession", reflect.TypeOf((*MockUserService)(nil).ValidateSession), ctx, in)
+}
+
+// VerifyMFALogin mocks base method.
+func (m *MockUserService) VerifyMFALogin(ctx context.Context, in *lib.VerifyMFALoginIn) *lib.LoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFALogin", ctx, in)
+	ret0, _ := ret[0].(*lib.LoginOut)
+	return ret0
+}
+
+// VerifyMFALogin indicates an expected call of VerifyMFALogin.
+func (mr *MockUserServiceMockRecorder) VerifyMFALogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFALogin", reflect.TypeOf((*MockUserService)(nil).VerifyMFALogin), ctx, in)
+}
@@ -0,0 +1,24 @@
+package accounting
+
+import (
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, handler *Handler) {
+	r.Route("/accounting", func(r chi.Router) {
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			// (chart of accounts)
+			r.With(middleware.RequireScope(data.ScopeAccountingRead)).Get("/accounts", handler.ListAccountMappings)
+			r.Put("/accounts", handler.SetAccountMapping)
+
+			// (journal)
+			r.With(middleware.RequireScope(data.ScopeAccountingRead)).Get("/payroll/{payrollId}/journal", handler.PayrollJournal)
+		})
+	})
+}
@@ -0,0 +1,198 @@
+package accounting
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/app/accounting/lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage          lib.StorageInterface
+	timeclockService timeclockLib.ServiceInterface
+	userService      userLib.ServiceInterface
+}
+
+func NewService(storage lib.StorageInterface, timeclockService timeclockLib.ServiceInterface, userService userLib.ServiceInterface) *Service {
+	return &Service{
+		storage:          storage,
+		timeclockService: timeclockService,
+		userService:      userService,
+	}
+}
+
+func (s *Service) PayrollJournal(ctx context.Context, in *lib.PayrollJournalIn) *lib.PayrollJournalOut {
+	resp := &lib.PayrollJournalOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("PayrollJournal/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("PayrollJournal/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if in.PayrollId <= 0 {
+		log.Warn(in.Trace).Msg("PayrollJournal/ invalid payroll id")
+		resp.Fail(apperror.CodeInvalidInput, "Payroll tidak valid")
+		return resp
+	}
+
+	detail := s.timeclockService.GetPayrollDetail(ctx, &timeclockLib.GetPayrollDetailIn{
+		Trace:     in.Trace,
+		PayrollId: in.PayrollId,
+	})
+	if !detail.Success {
+		log.Warn(in.Trace).Str("reason", detail.Message).Msg("PayrollJournal/ failed get payroll detail")
+		resp.Failure = detail.Failure
+		return resp
+	}
+	if userIds := itemsWithoutComponents(detail.Items); len(userIds) > 0 {
+		log.Warn(in.Trace).Int("payrollId", in.PayrollId).Interface("userIds", userIds).Msg("PayrollJournal/ pay components missing")
+		resp.Fail(apperror.CodeJournalIncomplete, "Komponen gaji payroll ini tidak tersimpan, jurnal tidak bisa dibuat")
+		return resp
+	}
+
+	costCenters := s.userService.UserCostCenter(ctx, &userLib.UserCostCenterIn{
+		Trace: in.Trace,
+	})
+	if !costCenters.Success {
+		log.Warn(in.Trace).Msg("PayrollJournal/ failed get user cost center")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("PayrollJournal/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	mappings, err := s.storage.GetAccountMappings(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("PayrollJournal/ get account mappings failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	journal := buildPayrollJournal(detail.Payroll, detail.Items, costCenters.Result, newChartOfAccounts(mappings))
+	if !isBalanced(journal) {
+		log.Error(in.Trace).
+			Int("debit", journal.TotalDebit).
+			Int("credit", journal.TotalCredit).
+			Msg("PayrollJournal/ journal not balanced")
+		resp.Fail(apperror.CodeJournalUnbalanced, "Jurnal tidak balance")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Journal = journal
+	return resp
+}
+
+func (s *Service) ListAccountMappings(ctx context.Context, in *lib.ListAccountMappingsIn) *lib.ListAccountMappingsOut {
+	resp := &lib.ListAccountMappingsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListAccountMappings/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListAccountMappings/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListAccountMappings/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	mappings, err := s.storage.GetAccountMappings(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListAccountMappings/ get account mappings failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = newChartOfAccounts(mappings).list()
+	return resp
+}
+
+func (s *Service) SetAccountMapping(ctx context.Context, in *lib.SetAccountMappingIn) *lib.SetAccountMappingOut {
+	resp := &lib.SetAccountMappingOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SetAccountMapping/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SetAccountMapping/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if !isValidAccountKey(in.AccountKey) {
+		log.Warn(in.Trace).Str("accountKey", string(in.AccountKey)).Msg("SetAccountMapping/ invalid account key")
+		resp.Fail(apperror.CodeInvalidInput, "Account key tidak valid")
+		return resp
+	}
+
+	if in.AccountCode == "" || in.AccountName == "" {
+		log.Warn(in.Trace).Msg("SetAccountMapping/ account code or name empty")
+		resp.Fail(apperror.CodeInvalidInput, "Kode dan nama akun wajib diisi")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetAccountMapping/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	id, err := s.storage.UpsertAccountMapping(ctx, in.AccountKey, in.CostCenter, in.AccountCode, in.AccountName, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetAccountMapping/ upsert failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetAccountMapping/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Id = id
+	return resp
+}
@@ -0,0 +1,227 @@
+package accounting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/app/accounting/lib"
+	mocks "github.com/ariesmaulana/payroll/app/accounting/mock_lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServiceSetAccountMapping(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := NewService(storage, mocks.NewMockTimeclockService(ctrl), mocks.NewMockUserService(ctrl))
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 999, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "set-account-mapping-test"}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.SetAccountMappingIn
+		success bool
+		errMsg  string
+	}{
+		{
+			name:    "success set default mapping",
+			ctx:     adminCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccSalaryPayable, AccountCode: "2-1101", AccountName: "Utang Gaji Karyawan"},
+			success: true,
+		},
+		{
+			name:    "success set cost center mapping",
+			ctx:     adminCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccSalaryExpense, CostCenter: "ENG", AccountCode: "6-1101", AccountName: "Beban Gaji Engineering"},
+			success: true,
+		},
+		{
+			name:    "success update existing mapping",
+			ctx:     adminCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccSalaryExpense, CostCenter: "ENG", AccountCode: "6-1102", AccountName: "Beban Gaji Engineering"},
+			success: true,
+		},
+		{
+			name:    "fail invalid account key",
+			ctx:     adminCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: "unknown", AccountCode: "1-0000", AccountName: "Kas"},
+			success: false,
+			errMsg:  "Account key tidak valid",
+		},
+		{
+			name:    "fail empty account code",
+			ctx:     adminCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccTaxPayable, AccountName: "Utang PPh 21"},
+			success: false,
+			errMsg:  "Kode dan nama akun wajib diisi",
+		},
+		{
+			name:    "fail not admin",
+			ctx:     employeeCtx,
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccTaxPayable, AccountCode: "2-1300", AccountName: "Utang PPh 21"},
+			success: false,
+			errMsg:  "forbidden: Hanya admin yang bisa akses",
+		},
+		{
+			name:    "unauthorized",
+			ctx:     context.Background(),
+			in:      &lib.SetAccountMappingIn{Trace: trace, AccountKey: data.AccTaxPayable, AccountCode: "2-1300", AccountName: "Utang PPh 21"},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resp := service.SetAccountMapping(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+		})
+	}
+
+	mappings, err := storage.GetAccountMappings(adminCtx)
+	assert.Nil(t, err)
+	assert.Len(t, mappings, 2)
+
+	list := service.ListAccountMappings(adminCtx, &lib.ListAccountMappingsIn{Trace: trace})
+	assert.True(t, list.Success)
+	assert.Len(t, list.Result, len(defaultAccounts)+1)
+
+	list = service.ListAccountMappings(employeeCtx, &lib.ListAccountMappingsIn{Trace: trace})
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", list.Message)
+}
+
+func TestServicePayrollJournal(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeclockMock := mocks.NewMockTimeclockService(ctrl)
+	userMock := mocks.NewMockUserService(ctrl)
+	service := NewService(storage, timeclockMock, userMock)
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 999, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "payroll-journal-test"}
+
+	payroll := &data.Payroll{
+		Id:          1,
+		PeriodStart: common.NewDate(2025, 1, 1),
+		PeriodEnd:   common.NewDate(2025, 1, 31),
+	}
+	items := []*data.PayrollItem{
+		{UserId: 1, BaseSalaryAmount: 3000000, OvertimeAmount: 150000, ReimbursementTotal: 100000, TotalSalary: 3250000},
+		{UserId: 2, BaseSalaryAmount: 2000000, ReimbursementTotal: 50000, TotalSalary: 2050000},
+	}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.PayrollJournalIn
+		mock    func()
+		success bool
+		errMsg  string
+	}{
+		{
+			name: "success",
+			ctx:  adminCtx,
+			in:   &lib.PayrollJournalIn{Trace: trace, PayrollId: 1},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(&timeclockLib.GetPayrollDetailOut{Success: true, Payroll: payroll, Items: items}).
+					Times(1)
+				userMock.EXPECT().
+					UserCostCenter(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserCostCenterIn{})).
+					Return(&userLib.UserCostCenterOut{Success: true, Result: map[int]string{1: "ENG", 2: "OPS"}}).
+					Times(1)
+			},
+			success: true,
+		},
+		{
+			name: "fail items without pay components",
+			ctx:  adminCtx,
+			in:   &lib.PayrollJournalIn{Trace: trace, PayrollId: 1},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(&timeclockLib.GetPayrollDetailOut{Success: true, Payroll: payroll, Items: []*data.PayrollItem{
+						{UserId: 1, ReimbursementTotal: 100000, TotalSalary: 3250000},
+					}}).
+					Times(1)
+			},
+			success: false,
+			errMsg:  "Komponen gaji payroll ini tidak tersimpan, jurnal tidak bisa dibuat",
+		},
+		{
+			name: "fail payroll not found",
+			ctx:  adminCtx,
+			in:   &lib.PayrollJournalIn{Trace: trace, PayrollId: 2},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(&timeclockLib.GetPayrollDetailOut{Failure: apperror.Failed(apperror.CodePayrollNotFound, "Payroll tidak ditemukan")}).
+					Times(1)
+			},
+			success: false,
+			errMsg:  "Payroll tidak ditemukan",
+		},
+		{
+			name:    "fail invalid payroll id",
+			ctx:     adminCtx,
+			in:      &lib.PayrollJournalIn{Trace: trace},
+			mock:    func() {},
+			success: false,
+			errMsg:  "Payroll tidak valid",
+		},
+		{
+			name:    "fail not admin",
+			ctx:     employeeCtx,
+			in:      &lib.PayrollJournalIn{Trace: trace, PayrollId: 1},
+			mock:    func() {},
+			success: false,
+			errMsg:  "forbidden: Hanya admin yang bisa akses",
+		},
+		{
+			name:    "unauthorized",
+			ctx:     context.Background(),
+			in:      &lib.PayrollJournalIn{Trace: trace, PayrollId: 1},
+			mock:    func() {},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.mock()
+			resp := service.PayrollJournal(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			if sc.success {
+				assert.Equal(t, 5300000, resp.Journal.TotalDebit)
+				assert.Equal(t, resp.Journal.TotalDebit, resp.Journal.TotalCredit)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package accounting
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/app/accounting/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) GetAccountMappings(ctx context.Context) ([]*data.AccountMapping, error) {
+	const query = `
+		SELECT id, account_key, cost_center, account_code, account_name,
+		       created_at, updated_at, created_by, updated_by
+		FROM account_mappings
+		ORDER BY account_key, cost_center
+	`
+
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.AccountMapping, 0)
+	for rows.Next() {
+		var m data.AccountMapping
+		err := rows.Scan(
+			&m.Id,
+			&m.AccountKey,
+			&m.CostCenter,
+			&m.AccountCode,
+			&m.AccountName,
+			&m.CreatedAt,
+			&m.UpdatedAt,
+			&m.CreatedBy,
+			&m.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Storage) UpsertAccountMapping(
+	ctx context.Context,
+	accountKey data.AccountKey,
+	costCenter string,
+	accountCode string,
+	accountName string,
+	updatedBy string,
+) (int, error) {
+	const query = `
+		INSERT INTO account_mappings (account_key, cost_center, account_code, account_name, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (account_key, cost_center) DO UPDATE
+		SET account_code = EXCLUDED.account_code,
+		    account_name = EXCLUDED.account_name,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`
+
+	var id int
+	err := s.db(ctx).QueryRow(ctx, query, accountKey, costCenter, accountCode, accountName, updatedBy).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+)
+
+const (
+	// actorSystem is recorded when there is no logged in user
+	actorSystem = "system"
+
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	// verifyBatch is the number of events loaded at once by VerifyChain
+	verifyBatch = 500
+
+	// chainLockKey with the hash of the entity type is the advisory lock serializing appends to its chain
+	chainLockKey = 0x61756474 // "audt"
+)
+
+// diffFields compares before and after as JSON objects and returns the changed fields.
+// A nil before (create) returns every field of after, both nil returns an empty object.
+func diffFields(before any, after any) (json.RawMessage, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]data.AuditChange)
+	for field, value := range afterFields {
+		old, ok := beforeFields[field]
+		if ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		diff[field] = data.AuditChange{Before: old, After: value}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			diff[field] = data.AuditChange{Before: old}
+		}
+	}
+
+	raw, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(raw)
+}
+
+// jsonFields decodes the JSON form of v into its fields, a value that is not an object becomes field "value"
+func jsonFields(v any) (map[string]any, error) {
+	if v == nil {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	switch fields := decoded.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return fields, nil
+	default:
+		return map[string]any{"value": fields}, nil
+	}
+}
+
+// canonicalJSON re-encodes a JSON document with sorted keys and no spaces,
+// postgres jsonb does not keep the original text so the hash is computed on this form
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+
+	var decoded any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// chainPayload is what the hash of an event covers, the id is left out as it is assigned on insert
+type chainPayload struct {
+	PrevHash    string          `json:"prev_hash"`
+	OccurredAt  string          `json:"occurred_at"`
+	Actor       string          `json:"actor"`
+	ActorUserId int             `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityId    string          `json:"entity_id"`
+	Diff        json.RawMessage `json:"diff"`
+	TraceId     string          `json:"trace_id"`
+	IP          string          `json:"ip"`
+}
+
+// chainHash returns the hex sha256 of the previous hash and the event
+func chainHash(prevHash string, event *data.AuditEvent) (string, error) {
+	diff, err := canonicalJSON(event.Diff)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(chainPayload{
+		PrevHash:    prevHash,
+		OccurredAt:  event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		Actor:       event.Actor,
+		ActorUserId: event.ActorUserId,
+		Action:      event.Action,
+		EntityType:  event.EntityType,
+		EntityId:    event.EntityId,
+		Diff:        diff,
+		TraceId:     event.TraceId,
+		IP:          event.IP,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyEvents checks each event links to the last hash of its entity type and its hash matches its content.
+// lastHashes key is the entity type, it is updated with every valid event so the next batch continues
+// from it. It returns the number of valid events, fewer than len(events) means events[valid] is the
+// first broken one.
+func verifyEvents(lastHashes map[string]string, events []*data.AuditEvent) (int, error) {
+	for i, event := range events {
+		prevHash := lastHashes[event.EntityType]
+		if event.PrevHash != prevHash {
+			return i, nil
+		}
+		hash, err := chainHash(prevHash, event)
+		if err != nil {
+			return i, err
+		}
+		if hash != event.Hash {
+			return i, nil
+		}
+		lastHashes[event.EntityType] = hash
+	}
+	return len(events), nil
+}
+
+// validateFilter normalizes the paging of the filter and returns a message when it is invalid
+func validateFilter(filter *data.AuditFilter) string {
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return "Rentang waktu tidak valid"
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return "Limit atau offset tidak valid"
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffFields(t *testing.T) {
+	t.Parallel()
+
+	type profile struct {
+		Npwp       string
+		PtkpStatus string
+		Salary     int
+	}
+
+	scenarios := []struct {
+		name   string
+		before any
+		after  any
+		diff   string
+	}{
+		{
+			name:  "create",
+			after: profile{Npwp: "123", PtkpStatus: "TK/0", Salary: 5000000},
+			diff:  `{"Npwp":{"before":null,"after":"123"},"PtkpStatus":{"before":null,"after":"TK/0"},"Salary":{"before":null,"after":5000000}}`,
+		},
+		{
+			name:   "only changed fields",
+			before: profile{Npwp: "123", PtkpStatus: "TK/0", Salary: 5000000},
+			after:  profile{Npwp: "123", PtkpStatus: "K/1", Salary: 5000000},
+			diff:   `{"PtkpStatus":{"before":"TK/0","after":"K/1"}}`,
+		},
+		{
+			name:   "removed field",
+			before: map[string]any{"religion": "islam", "note": "x"},
+			after:  map[string]any{"religion": "islam"},
+			diff:   `{"note":{"before":"x","after":null}}`,
+		},
+		{
+			name: "read",
+			diff: `{}`,
+		},
+		{
+			name:  "scalar",
+			after: 42,
+			diff:  `{"value":{"before":null,"after":42}}`,
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			diff, err := diffFields(sc.before, sc.after)
+			require.NoError(t, err)
+			assert.JSONEq(t, sc.diff, string(diff))
+		})
+	}
+}
+
+func newTestEvent(diff string) *data.AuditEvent {
+	return &data.AuditEvent{
+		OccurredAt:  time.Date(2025, 6, 2, 3, 4, 5, 123456000, time.UTC),
+		Actor:       "admin",
+		ActorUserId: 1,
+		Action:      "tax_profile.update",
+		EntityType:  "user",
+		EntityId:    "7",
+		Diff:        json.RawMessage(diff),
+		TraceId:     "trace-1",
+		IP:          "10.0.0.1",
+	}
+}
+
+func TestChainHash(t *testing.T) {
+	t.Parallel()
+
+	event := newTestEvent(`{"PtkpStatus":{"before":"TK/0","after":"K/1"}}`)
+	hash, err := chainHash("", event)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// jsonb gives back the diff with other spacing and key order, the hash must not change
+	stored := newTestEvent(`{"PtkpStatus": {"after": "K/1", "before": "TK/0"}}`)
+	stored.OccurredAt = event.OccurredAt.In(time.FixedZone("WIB", 7*3600))
+	again, err := chainHash("", stored)
+	require.NoError(t, err)
+	assert.Equal(t, hash, again)
+
+	other, err := chainHash(hash, event)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "the previous hash is part of the hash")
+
+	event.Actor = "hacker"
+	changed, err := chainHash("", event)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed)
+}
+
+// newTestChain returns n events chained like AppendEvent does
+func newTestChain(t *testing.T, n int) []*data.AuditEvent {
+	t.Helper()
+	events := make([]*data.AuditEvent, 0, n)
+	prevHash := ""
+	for i := 1; i <= n; i++ {
+		event := newTestEvent(`{"Amount":{"before":null,"after":100}}`)
+		event.Id = int64(i)
+		event.PrevHash = prevHash
+		hash, err := chainHash(prevHash, event)
+		require.NoError(t, err)
+		event.Hash = hash
+		prevHash = hash
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestVerifyEvents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		events := newTestChain(t, 3)
+		lastHashes := map[string]string{}
+		valid, err := verifyEvents(lastHashes, events)
+		require.NoError(t, err)
+		assert.Equal(t, 3, valid)
+		assert.Equal(t, map[string]string{"user": events[2].Hash}, lastHashes)
+
+		// verifying in batches continues from the last hash
+		valid, err = verifyEvents(map[string]string{"user": events[0].Hash}, events[1:])
+		require.NoError(t, err)
+		assert.Equal(t, 2, valid)
+	})
+
+	t.Run("chain per entity type", func(t *testing.T) {
+		users := newTestChain(t, 2)
+		payroll := newTestEvent(`{}`)
+		payroll.EntityType = "payroll"
+		payroll.Hash, _ = chainHash("", payroll)
+
+		// the payroll event starts its own chain between the two user events
+		valid, err := verifyEvents(map[string]string{}, []*data.AuditEvent{users[0], payroll, users[1]})
+		require.NoError(t, err)
+		assert.Equal(t, 3, valid)
+
+		// moved to another chain, the event no longer links
+		users[1].EntityType = "payroll"
+		valid, err = verifyEvents(map[string]string{}, []*data.AuditEvent{users[0], payroll, users[1]})
+		require.NoError(t, err)
+		assert.Equal(t, 2, valid)
+	})
+
+	t.Run("edited diff", func(t *testing.T) {
+		events := newTestChain(t, 3)
+		events[1].Diff = json.RawMessage(`{"Amount":{"before":null,"after":1000000}}`)
+		valid, err := verifyEvents(map[string]string{}, events)
+		require.NoError(t, err)
+		assert.Equal(t, 1, valid)
+	})
+
+	t.Run("deleted event", func(t *testing.T) {
+		events := newTestChain(t, 3)
+		valid, err := verifyEvents(map[string]string{}, []*data.AuditEvent{events[0], events[2]})
+		require.NoError(t, err)
+		assert.Equal(t, 1, valid)
+	})
+
+	t.Run("rehashed event", func(t *testing.T) {
+		// recomputing the hash of an edited event still breaks the link of the next one
+		events := newTestChain(t, 3)
+		events[1].Actor = "hacker"
+		events[1].Hash, _ = chainHash(events[1].PrevHash, events[1])
+		valid, err := verifyEvents(map[string]string{}, events)
+		require.NoError(t, err)
+		assert.Equal(t, 2, valid)
+	})
+}
+
+func TestValidateFilter(t *testing.T) {
+	t.Parallel()
+
+	filter := data.AuditFilter{}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, defaultListLimit, filter.Limit)
+
+	filter = data.AuditFilter{Limit: 10000}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, maxListLimit, filter.Limit)
+
+	now := time.Now()
+	filter = data.AuditFilter{From: now, To: now.Add(-time.Hour)}
+	assert.Equal(t, "Rentang waktu tidak valid", validateFilter(&filter))
+
+	filter = data.AuditFilter{Offset: -1}
+	assert.Equal(t, "Limit atau offset tidak valid", validateFilter(&filter))
+}
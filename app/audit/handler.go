@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/audit/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// parseTime accepts a date (2006-01-02, Asia/Jakarta midnight) or an RFC3339 time, empty is zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, common.JakartaTZ); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseInt parses an optional number, empty is 0
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := data.AuditFilter{
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		EntityId:   query.Get("entity_id"),
+	}
+
+	var err error
+	if filter.From, err = parseTime(query.Get("from")); err != nil {
+		response.Error(w, r, "Param 'from' harus tanggal (YYYY-MM-DD) atau RFC3339")
+		return
+	}
+	if filter.To, err = parseTime(query.Get("to")); err != nil {
+		response.Error(w, r, "Param 'to' harus tanggal (YYYY-MM-DD) atau RFC3339")
+		return
+	}
+	if filter.Limit, err = parseInt(query.Get("limit")); err != nil {
+		response.Error(w, r, "Param 'limit' harus angka")
+		return
+	}
+	if filter.Offset, err = parseInt(query.Get("offset")); err != nil {
+		response.Error(w, r, "Param 'offset' harus angka")
+		return
+	}
+
+	out := h.service.ListEvents(r.Context(), &lib.ListEventsIn{
+		Trace:  trace,
+		Filter: filter,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+func (h *Handler) VerifyChain(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.VerifyChain(r.Context(), &lib.VerifyChainIn{
+		Trace: trace,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
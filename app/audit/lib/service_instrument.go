@@ -0,0 +1,63 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named audit.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="audit"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) Record(ctx context.Context, in *RecordIn) *RecordOut {
+	ctx, span := tracing.Start(ctx, "audit.Record")
+	start := time.Now()
+	out := s.next.Record(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("audit", "Record", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListEvents(ctx context.Context, in *ListEventsIn) *ListEventsOut {
+	ctx, span := tracing.Start(ctx, "audit.ListEvents")
+	start := time.Now()
+	out := s.next.ListEvents(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("audit", "ListEvents", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) VerifyChain(ctx context.Context, in *VerifyChainIn) *VerifyChainOut {
+	ctx, span := tracing.Start(ctx, "audit.VerifyChain")
+	start := time.Now()
+	out := s.next.VerifyChain(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("audit", "VerifyChain", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
@@ -0,0 +1,69 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// Record appends an event to the audit trail, the actor is the user of the context
+	Record(ctx context.Context, in *RecordIn) *RecordOut
+
+	// ListEvents searches the audit trail (admin only)
+	ListEvents(ctx context.Context, in *ListEventsIn) *ListEventsOut
+
+	// VerifyChain recomputes the hash chain of every entity type from its first event and reports the first
+	// broken event (admin only)
+	VerifyChain(ctx context.Context, in *VerifyChainIn) *VerifyChainOut
+}
+
+type RecordIn struct {
+	Trace      *contextutil.Trace
+	Action     string
+	EntityType string
+	EntityId   string
+
+	// Before and After are the entity (struct or map) before and after the action, only changed fields are kept.
+	// Before is nil on create, both are nil on a read.
+	Before any
+	After  any
+
+	// Actor and ActorUserId replace the user of the context, for actions without a login (eg: reset password)
+	Actor       string
+	ActorUserId int
+}
+
+type RecordOut struct {
+	Success bool
+	apperror.Failure
+	EventId int64
+}
+
+type ListEventsIn struct {
+	Trace  *contextutil.Trace
+	Filter data.AuditFilter
+}
+
+type ListEventsOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.AuditEvent
+}
+
+type VerifyChainIn struct {
+	Trace *contextutil.Trace
+}
+
+type VerifyChainOut struct {
+	Success bool
+	apperror.Failure
+
+	Valid    bool
+	Checked  int   // number of events verified
+	BrokenAt int64 // id of the first event whose hash or link does not match, 0 when Valid
+}
@@ -0,0 +1,23 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	// AppendEvent chains the event to the last one of its entity type and inserts it, fills Id, PrevHash
+	// and Hash. Appends of an entity type are serialized with an advisory lock so its chain never forks.
+	AppendEvent(ctx context.Context, event *data.AuditEvent) error
+
+	// GetEvents returns the events matching the filter, newest first
+	GetEvents(ctx context.Context, filter *data.AuditFilter) ([]*data.AuditEvent, error)
+
+	// GetEventsAfter returns up to limit events with id greater than afterId, oldest first, to walk the chain
+	GetEventsAfter(ctx context.Context, afterId int64, limit int) ([]*data.AuditEvent, error)
+}
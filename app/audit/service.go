@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/audit/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage lib.StorageInterface
+}
+
+func NewService(storage lib.StorageInterface) *Service {
+	return &Service{storage: storage}
+}
+
+func (s *Service) Record(ctx context.Context, in *lib.RecordIn) *lib.RecordOut {
+	resp := &lib.RecordOut{}
+
+	if in.Action == "" || in.EntityType == "" {
+		log.Warn(in.Trace).Msg("Record/ action or entity type missing")
+		resp.Fail(apperror.CodeInvalidInput, "Action dan entity wajib diisi")
+		return resp
+	}
+
+	diff, err := diffFields(in.Before, in.After)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Str("action", in.Action).Msg("Record/ diff failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	event := &data.AuditEvent{
+		OccurredAt:  time.Now().UTC().Truncate(time.Microsecond), // precision of postgres timestamp
+		Actor:       actorSystem,
+		ActorUserId: in.ActorUserId,
+		Action:      in.Action,
+		EntityType:  in.EntityType,
+		EntityId:    in.EntityId,
+		Diff:        diff,
+	}
+	if in.Actor != "" {
+		event.Actor = in.Actor
+	} else if user, ok := contextutil.GetUser(ctx); ok {
+		event.Actor = user.Actor()
+		event.ActorUserId = user.Id
+	}
+	if in.Trace != nil {
+		event.TraceId = in.Trace.TraceID
+		event.IP = in.Trace.IP
+	}
+
+	if err := s.storage.AppendEvent(ctx, event); err != nil {
+		log.Error(in.Trace).Err(err).Str("action", in.Action).Msg("Record/ append event failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.EventId = event.Id
+	return resp
+}
+
+func (s *Service) ListEvents(ctx context.Context, in *lib.ListEventsIn) *lib.ListEventsOut {
+	resp := &lib.ListEventsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListEvents/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListEvents/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	filter := in.Filter
+	if msg := validateFilter(&filter); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("ListEvents/ invalid filter")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListEvents/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	events, err := s.storage.GetEvents(ctx, &filter)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListEvents/ get events failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = events
+	return resp
+}
+
+func (s *Service) VerifyChain(ctx context.Context, in *lib.VerifyChainIn) *lib.VerifyChainOut {
+	resp := &lib.VerifyChainOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("VerifyChain/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("VerifyChain/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("VerifyChain/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	lastHashes := make(map[string]string)
+	lastId := int64(0)
+	for {
+		events, err := s.storage.GetEventsAfter(ctx, lastId, verifyBatch)
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("VerifyChain/ get events failed")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		valid, err := verifyEvents(lastHashes, events)
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("VerifyChain/ hash failed")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+		resp.Checked += valid
+		if valid < len(events) {
+			log.Warn(in.Trace).Int64("eventId", events[valid].Id).Msg("VerifyChain/ chain broken")
+			resp.Success = true
+			resp.BrokenAt = events[valid].Id
+			return resp
+		}
+
+		lastId = events[len(events)-1].Id
+	}
+
+	resp.Success = true
+	resp.Valid = true
+	return resp
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/ariesmaulana/payroll/app/audit/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRecordAndList(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool))
+
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 2, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "audit-record-test", IP: "10.0.0.1"}
+
+	out := service.Record(employeeCtx, &lib.RecordIn{
+		Trace: trace, Action: "reimbursement.create", EntityType: "reimbursement", EntityId: "1",
+		After: map[string]any{"amount": 150000, "description": "parkir"},
+	})
+	require.True(t, out.Success, out.Message)
+
+	out = service.Record(adminCtx, &lib.RecordIn{
+		Trace: trace, Action: "tax_profile.update", EntityType: "user", EntityId: "2",
+		Before: map[string]any{"ptkp_status": "TK/0", "npwp": "123"},
+		After:  map[string]any{"ptkp_status": "K/1", "npwp": "123"},
+	})
+	require.True(t, out.Success, out.Message)
+
+	// without a login, eg: forgot password
+	out = service.Record(con.Context, &lib.RecordIn{Trace: trace, Action: "payroll.run", EntityType: "payroll", EntityId: "1"})
+	require.True(t, out.Success, out.Message)
+
+	out = service.Record(adminCtx, &lib.RecordIn{Trace: trace, EntityType: "payroll"})
+	assert.False(t, out.Success)
+	assert.Equal(t, "Action dan entity wajib diisi", out.Message)
+
+	list := service.ListEvents(adminCtx, &lib.ListEventsIn{Trace: trace})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 3)
+	assert.Equal(t, "system", list.Result[0].Actor, "newest first")
+
+	update := list.Result[1]
+	assert.Equal(t, "test_user", update.Actor)
+	assert.Equal(t, 1, update.ActorUserId)
+	assert.Equal(t, "audit-record-test", update.TraceId)
+	assert.Equal(t, "10.0.0.1", update.IP)
+	assert.JSONEq(t, `{"ptkp_status":{"before":"TK/0","after":"K/1"}}`, string(update.Diff))
+	assert.Equal(t, "", update.PrevHash, "the first event of the user chain")
+
+	list = service.ListEvents(adminCtx, &lib.ListEventsIn{Trace: trace, Filter: data.AuditFilter{EntityType: "user", EntityId: "2"}})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 1)
+	assert.Equal(t, "tax_profile.update", list.Result[0].Action)
+
+	list = service.ListEvents(employeeCtx, &lib.ListEventsIn{Trace: trace})
+	assert.False(t, list.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", list.Message)
+}
+
+func TestServiceVerifyChain(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool))
+
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	trace := &contextutil.Trace{TraceID: "audit-verify-test"}
+
+	for i := 0; i < 3; i++ {
+		out := service.Record(adminCtx, &lib.RecordIn{Trace: trace, Action: "payslip.read_all", EntityType: "payslip", EntityId: "2025-06"})
+		require.True(t, out.Success, out.Message)
+	}
+
+	verify := service.VerifyChain(adminCtx, &lib.VerifyChainIn{Trace: trace})
+	require.True(t, verify.Success, verify.Message)
+	assert.True(t, verify.Valid)
+	assert.Equal(t, 3, verify.Checked)
+
+	// the table is append only
+	_, err := con.Pool.Exec(con.Context, `UPDATE audit_events SET actor = 'hacker' WHERE id = 2`)
+	assert.ErrorContains(t, err, "append only")
+	_, err = con.Pool.Exec(con.Context, `DELETE FROM audit_events WHERE id = 2`)
+	assert.ErrorContains(t, err, "append only")
+
+	// someone with enough privilege to drop the trigger still gets caught by the chain
+	_, err = con.Pool.Exec(con.Context, `ALTER TABLE audit_events DISABLE TRIGGER audit_events_no_update`)
+	require.NoError(t, err)
+	_, err = con.Pool.Exec(con.Context, `UPDATE audit_events SET actor = 'hacker' WHERE id = 2`)
+	require.NoError(t, err)
+
+	verify = service.VerifyChain(adminCtx, &lib.VerifyChainIn{Trace: trace})
+	require.True(t, verify.Success, verify.Message)
+	assert.False(t, verify.Valid)
+	assert.Equal(t, int64(2), verify.BrokenAt)
+	assert.Equal(t, 1, verify.Checked)
+
+	verify = service.VerifyChain(test.UserContext(con.Context, 2, data.REmployee), &lib.VerifyChainIn{Trace: trace})
+	assert.False(t, verify.Success)
+}
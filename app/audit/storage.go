@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ariesmaulana/payroll/app/audit/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) AppendEvent(ctx context.Context, event *data.AuditEvent) error {
+	// a savepoint when the caller runs in a transaction, the event commits with the change it records
+	tx, err := s.BeginTxWriter(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// every entity type is its own chain, an audited write only waits for the writes of the same type.
+	// Released when the outermost transaction ends, the next append waits and sees this event as the last one.
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, int32(chainLockKey), event.EntityType)
+	if err != nil {
+		return err
+	}
+
+	var prevHash string
+	err = tx.QueryRow(ctx, `SELECT hash FROM audit_events WHERE entity_type = $1 ORDER BY id DESC LIMIT 1`, event.EntityType).Scan(&prevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	hash, err := chainHash(prevHash, event)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO audit_events (
+			occurred_at, actor, actor_user_id, action, entity_type, entity_id,
+			diff, trace_id, ip, prev_hash, hash
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query,
+		event.OccurredAt, event.Actor, event.ActorUserId, event.Action, event.EntityType, event.EntityId,
+		string(event.Diff), event.TraceId, event.IP, prevHash, hash,
+	).Scan(&event.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	event.PrevHash = prevHash
+	event.Hash = hash
+	return nil
+}
+
+const auditEventColumns = `
+	id, occurred_at, actor, actor_user_id, action, entity_type, entity_id,
+	diff::text, trace_id, ip, prev_hash, hash
+`
+
+// scanAuditEvent scans one row selected with auditEventColumns
+func scanAuditEvent(row pgx.Row) (*data.AuditEvent, error) {
+	var e data.AuditEvent
+	var diff string
+	err := row.Scan(
+		&e.Id, &e.OccurredAt, &e.Actor, &e.ActorUserId, &e.Action, &e.EntityType, &e.EntityId,
+		&diff, &e.TraceId, &e.IP, &e.PrevHash, &e.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Diff = []byte(diff)
+	return &e, nil
+}
+
+func (s *Storage) GetEvents(ctx context.Context, filter *data.AuditFilter) ([]*data.AuditEvent, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityId != "" {
+		add("entity_id = $%d", filter.EntityId)
+	}
+	if !filter.From.IsZero() {
+		add("occurred_at >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		add("occurred_at < $%d", filter.To.UTC())
+	}
+
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	return s.queryEvents(ctx, query, args...)
+}
+
+func (s *Storage) GetEventsAfter(ctx context.Context, afterId int64, limit int) ([]*data.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events WHERE id > $1 ORDER BY id LIMIT $2`
+	return s.queryEvents(ctx, query, afterId, limit)
+}
+
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...interface{}) ([]*data.AuditEvent, error) {
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.AuditEvent, 0)
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, event)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,96 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// name of the built-in jobs, stored in the run history
+const (
+	JobAttendanceAutoClose = "attendance-auto-close"
+	JobClockInReminder     = "clock-in-reminder"
+	JobPayrollDraft        = "payroll-draft"
+	JobApprovalReminder    = "approval-reminder"
+)
+
+// actorScheduler is recorded as created_by / updated_by and in the audit trail for what the jobs do
+const actorScheduler = "scheduler"
+
+func validateFilter(filter *data.JobRunFilter) string {
+	switch filter.Status {
+	case "", data.JobRunning, data.JobSuccess, data.JobFailed:
+	default:
+		return "Status tidak valid"
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return "Limit atau offset tidak valid"
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	return ""
+}
+
+// payrollPeriod returns the payroll period that ends on date when date is the cutoff day.
+// The period of cutoff day 25 is 26 of the previous month to 25, cutoff day 0 is the calendar month
+// ending on the last day of the month.
+func payrollPeriod(date time.Time, cutoffDay int) (start time.Time, end time.Time, ok bool) {
+	y, m, d := date.Date()
+	loc := date.Location()
+	end = time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+	if cutoffDay == 0 {
+		if end.AddDate(0, 0, 1).Month() == m {
+			return time.Time{}, time.Time{}, false
+		}
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc), end, true
+	}
+
+	if d != cutoffDay {
+		return time.Time{}, time.Time{}, false
+	}
+	// cutoff day is at most 28, day after it always exists in the previous month
+	return time.Date(y, m-1, cutoffDay+1, 0, 0, 0, 0, loc), end, true
+}
+
+// missingClockIn returns the active users who have not checked in
+func missingClockIn(contacts []*data.UserContact, attended map[int]bool) []*data.UserContact {
+	result := make([]*data.UserContact, 0)
+	for _, c := range contacts {
+		if !attended[c.Id] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// admins returns the contacts that approve and review, the recipients of the approval reminder and the draft
+func admins(contacts []*data.UserContact) []*data.UserContact {
+	result := make([]*data.UserContact, 0)
+	for _, c := range contacts {
+		if c.Role == data.RAdmin {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// sendSummary is the detail of a job that notifies users, failed holds the email that could not be sent
+func sendSummary(action string, sent int, failed []string) string {
+	detail := fmt.Sprintf("%s: sent %d", action, sent)
+	if len(failed) > 0 {
+		detail += fmt.Sprintf(", failed %d (%s)", len(failed), strings.Join(failed, ", "))
+	}
+	return detail
+}
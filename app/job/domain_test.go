@@ -0,0 +1,85 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFilter(t *testing.T) {
+	filter := data.JobRunFilter{}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, defaultListLimit, filter.Limit)
+
+	filter = data.JobRunFilter{Status: data.JobFailed, Limit: 10000}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, maxListLimit, filter.Limit)
+
+	filter = data.JobRunFilter{Status: "done"}
+	assert.Equal(t, "Status tidak valid", validateFilter(&filter))
+
+	filter = data.JobRunFilter{Offset: -1}
+	assert.Equal(t, "Limit atau offset tidak valid", validateFilter(&filter))
+}
+
+func TestPayrollPeriod(t *testing.T) {
+	scenarios := []struct {
+		name      string
+		date      time.Time
+		cutoffDay int
+		ok        bool
+		start     time.Time
+		end       time.Time
+	}{
+		{
+			name: "cutoff day 25", date: common.NewDate(2025, 3, 25), cutoffDay: 25, ok: true,
+			start: common.NewDate(2025, 2, 26), end: common.NewDate(2025, 3, 25),
+		},
+		{
+			name: "cutoff in january starts in december", date: common.NewDate(2025, 1, 20), cutoffDay: 20, ok: true,
+			start: common.NewDate(2024, 12, 21), end: common.NewDate(2025, 1, 20),
+		},
+		{
+			name: "cutoff day 28 after february", date: common.NewDate(2025, 3, 28), cutoffDay: 28, ok: true,
+			start: common.NewDate(2025, 3, 1), end: common.NewDate(2025, 3, 28),
+		},
+		{name: "not the cutoff day", date: common.NewDate(2025, 3, 24), cutoffDay: 25},
+		{
+			name: "end of month", date: common.NewDate(2024, 2, 29), cutoffDay: 0, ok: true,
+			start: common.NewDate(2024, 2, 1), end: common.NewDate(2024, 2, 29),
+		},
+		{name: "not the end of month", date: common.NewDate(2025, 2, 27), cutoffDay: 0},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			start, end, ok := payrollPeriod(sc.date, sc.cutoffDay)
+			assert.Equal(t, sc.ok, ok)
+			assert.Equal(t, sc.start, start)
+			assert.Equal(t, sc.end, end)
+		})
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	contacts := []*data.UserContact{
+		{Id: 1, Email: "admin@example.com", Role: data.RAdmin},
+		{Id: 2, Email: "budi@example.com", Role: data.REmployee},
+		{Id: 3, Email: "sari@example.com", Role: data.REmployee},
+	}
+
+	missing := missingClockIn(contacts, map[int]bool{2: true})
+	assert.Equal(t, []*data.UserContact{contacts[0], contacts[2]}, missing)
+
+	assert.Equal(t, []*data.UserContact{contacts[0]}, admins(contacts))
+	assert.Empty(t, admins(contacts[1:]))
+}
+
+func TestSendSummary(t *testing.T) {
+	assert.Equal(t, "clock-in reminder: sent 3", sendSummary("clock-in reminder", 3, nil))
+	assert.Equal(t, "clock-in reminder: sent 1, failed 2 (a@example.com, b@example.com)",
+		sendSummary("clock-in reminder", 1, []string{"a@example.com", "b@example.com"}))
+}
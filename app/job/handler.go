@@ -0,0 +1,63 @@
+package job
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// parseInt parses an optional number, empty is 0
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (h *Handler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := data.JobRunFilter{
+		Job:    query.Get("job"),
+		Status: data.JobRunStatus(query.Get("status")),
+	}
+
+	var err error
+	if filter.Limit, err = parseInt(query.Get("limit")); err != nil {
+		response.Error(w, r, "Param 'limit' harus angka")
+		return
+	}
+	if filter.Offset, err = parseInt(query.Get("offset")); err != nil {
+		response.Error(w, r, "Param 'offset' harus angka")
+		return
+	}
+
+	out := h.service.ListRuns(r.Context(), &lib.ListRunsIn{
+		Trace:  trace,
+		Filter: filter,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
@@ -0,0 +1,225 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	loanLib "github.com/ariesmaulana/payroll/app/loan/lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/notifier"
+	"github.com/ariesmaulana/payroll/lib/scheduler"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Policy configures the built-in jobs
+type Policy struct {
+	// ShiftEnd is the checkout time of day set on an attendance the employee forgot to check out
+	ShiftEnd time.Time
+
+	// PayrollCutoffDay is the last day of the payroll period (1-28), 0 is the end of the month
+	PayrollCutoffDay int
+}
+
+// Jobs are the built-in scheduled jobs, they act as an admin named "scheduler"
+type Jobs struct {
+	timeclockService timeclockLib.ServiceInterface
+	userService      userLib.ServiceInterface
+	loanService      loanLib.ServiceInterface
+	notifier         notifier.Notifier
+	policy           Policy
+}
+
+func NewJobs(timeclockService timeclockLib.ServiceInterface, userService userLib.ServiceInterface, loanService loanLib.ServiceInterface,
+	notifier notifier.Notifier, policy Policy) *Jobs {
+	return &Jobs{
+		timeclockService: timeclockService,
+		userService:      userService,
+		loanService:      loanService,
+		notifier:         notifier,
+		policy:           policy,
+	}
+}
+
+// Register adds the built-in jobs to the scheduler, schedules is the cron expression per job name.
+// A job without schedule is disabled.
+func (j *Jobs) Register(s *scheduler.Scheduler, schedules map[string]string) error {
+	jobs := []struct {
+		name string
+		run  scheduler.JobFunc
+	}{
+		{JobAttendanceAutoClose, j.autoCloseAttendances},
+		{JobClockInReminder, j.remindClockIn},
+		{JobPayrollDraft, j.createPayrollDraft},
+		{JobApprovalReminder, j.remindApprovals},
+	}
+	for _, job := range jobs {
+		if err := s.Add(job.name, schedules[job.name], job.run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// systemContext is the admin context the job calls the services with, the trace identifies the run in the log.
+// The trace ID is the one of the span the scheduler started for the run, when there is one.
+func systemContext(ctx context.Context, job string, scheduledAt time.Time) (context.Context, *contextutil.Trace) {
+	trace := &contextutil.Trace{
+		TraceID: fmt.Sprintf("job-%s-%d", job, scheduledAt.Unix()),
+		Method:  "JOB",
+		Path:    job,
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() {
+		trace.TraceID = sc.TraceID().String()
+		trace.SpanID = sc.SpanID().String()
+	}
+	ctx = contextutil.WithTrace(ctx, trace)
+	ctx = contextutil.WithUser(ctx, &contextutil.AuthUser{Username: actorScheduler, Role: data.RAdmin})
+	return ctx, trace
+}
+
+// notify sends the message to every contact and returns the summary, it fails when a message was not sent
+func (j *Jobs) notify(ctx context.Context, action string, contacts []*data.UserContact, message func(c *data.UserContact) *notifier.Message) (string, error) {
+	sent := 0
+	failed := make([]string, 0)
+	for _, c := range contacts {
+		if err := j.notifier.Send(ctx, message(c)); err != nil {
+			failed = append(failed, c.Email)
+			continue
+		}
+		sent++
+	}
+
+	summary := sendSummary(action, sent, failed)
+	if len(failed) > 0 {
+		return "", errors.New(summary)
+	}
+	return summary, nil
+}
+
+func (j *Jobs) contacts(ctx context.Context, trace *contextutil.Trace) ([]*data.UserContact, error) {
+	out := j.userService.UserContacts(ctx, &userLib.UserContactsIn{Trace: trace})
+	if !out.Success {
+		return nil, fmt.Errorf("get user contacts: %s", out.Message)
+	}
+	return out.Result, nil
+}
+
+// autoCloseAttendances checks out at the shift end every attendance the employee forgot to check out.
+// Run before the shift end (eg: after midnight) it closes until yesterday.
+func (j *Jobs) autoCloseAttendances(ctx context.Context, scheduledAt time.Time) (string, error) {
+	ctx, trace := systemContext(ctx, JobAttendanceAutoClose, scheduledAt)
+
+	date := common.TruncateToJakartaDate(scheduledAt)
+	local := scheduledAt.In(common.JakartaTZ)
+	shiftEnd := time.Date(local.Year(), local.Month(), local.Day(), j.policy.ShiftEnd.Hour(), j.policy.ShiftEnd.Minute(), 0, 0, common.JakartaTZ)
+	if local.Before(shiftEnd) {
+		date = date.AddDate(0, 0, -1)
+	}
+
+	out := j.timeclockService.CloseOpenAttendances(ctx, &timeclockLib.CloseOpenAttendancesIn{
+		Trace:    trace,
+		Date:     date,
+		ShiftEnd: j.policy.ShiftEnd,
+	})
+	if !out.Success {
+		return "", errors.New(out.Message)
+	}
+	return fmt.Sprintf("closed %d attendances until %s", len(out.Closed), date.Format("2006-01-02")), nil
+}
+
+// remindClockIn emails the active users who have not checked in today
+func (j *Jobs) remindClockIn(ctx context.Context, scheduledAt time.Time) (string, error) {
+	ctx, trace := systemContext(ctx, JobClockInReminder, scheduledAt)
+	today := common.TruncateToJakartaDate(scheduledAt)
+
+	contacts, err := j.contacts(ctx, trace)
+	if err != nil {
+		return "", err
+	}
+	attended := j.timeclockService.AttendanceUserIds(ctx, &timeclockLib.AttendanceUserIdsIn{Trace: trace, Date: today})
+	if !attended.Success {
+		return "", fmt.Errorf("get attendances: %s", attended.Message)
+	}
+
+	return j.notify(ctx, "clock-in reminder", missingClockIn(contacts, attended.Result), func(c *data.UserContact) *notifier.Message {
+		return &notifier.Message{
+			To:      c.Email,
+			Subject: "Pengingat clock-in",
+			Body: fmt.Sprintf("Halo %s,\n\nKamu belum clock-in hari ini (%s). Jangan lupa clock-in supaya kehadiranmu terhitung di payroll.",
+				c.Fullname, today.Format("02-01-2006")),
+		}
+	})
+}
+
+// createPayrollDraft stores the draft of the payroll period ending today when today is the cutoff day,
+// then tells the admins to review it
+func (j *Jobs) createPayrollDraft(ctx context.Context, scheduledAt time.Time) (string, error) {
+	ctx, trace := systemContext(ctx, JobPayrollDraft, scheduledAt)
+
+	start, end, ok := payrollPeriod(common.TruncateToJakartaDate(scheduledAt), j.policy.PayrollCutoffDay)
+	if !ok {
+		return "not the cutoff day", nil
+	}
+
+	out := j.timeclockService.CreatePayrollDraft(ctx, &timeclockLib.CreatePayrollDraftIn{
+		Trace:       trace,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	})
+	if !out.Success {
+		return "", errors.New(out.Message)
+	}
+	period := fmt.Sprintf("%s - %s", start.Format("02-01-2006"), end.Format("02-01-2006"))
+	if !out.Created {
+		return "draft of " + period + " already exists", nil
+	}
+
+	contacts, err := j.contacts(ctx, trace)
+	if err != nil {
+		return "", err
+	}
+	summary, err := j.notify(ctx, "draft notification", admins(contacts), func(c *data.UserContact) *notifier.Message {
+		return &notifier.Message{
+			To:      c.Email,
+			Subject: "Draft payroll " + period,
+			Body: fmt.Sprintf("Halo %s,\n\nDraft payroll periode %s sudah dibuat: %d karyawan, total Rp%d.\nSilakan review sebelum payroll dijalankan.",
+				c.Fullname, period, out.Draft.Employees, out.Draft.TotalSalary),
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("draft %d created, %w", out.Draft.Id, err)
+	}
+	return fmt.Sprintf("draft %d of %s created, %s", out.Draft.Id, period, summary), nil
+}
+
+// remindApprovals tells the admins how many requests wait for their approval
+func (j *Jobs) remindApprovals(ctx context.Context, scheduledAt time.Time) (string, error) {
+	ctx, trace := systemContext(ctx, JobApprovalReminder, scheduledAt)
+
+	loans := j.loanService.ListLoans(ctx, &loanLib.ListLoansIn{Trace: trace, Status: data.LoanPending})
+	if !loans.Success {
+		return "", fmt.Errorf("get pending loans: %s", loans.Message)
+	}
+	if len(loans.Result) == 0 {
+		return "no pending approval", nil
+	}
+
+	contacts, err := j.contacts(ctx, trace)
+	if err != nil {
+		return "", err
+	}
+	return j.notify(ctx, fmt.Sprintf("%d pending loans", len(loans.Result)), admins(contacts), func(c *data.UserContact) *notifier.Message {
+		return &notifier.Message{
+			To:      c.Email,
+			Subject: "Pengajuan menunggu persetujuan",
+			Body: fmt.Sprintf("Halo %s,\n\nAda %d pengajuan kasbon yang menunggu persetujuan. Silakan review pengajuan tersebut.",
+				c.Fullname, len(loans.Result)),
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/job/mock_lib"
+	loanLib "github.com/ariesmaulana/payroll/app/loan/lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeNotifier keeps the messages, sending to an address in fail returns an error
+type fakeNotifier struct {
+	sent []*notifier.Message
+	fail map[string]bool
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, msg *notifier.Message) error {
+	if n.fail[msg.To] {
+		return errors.New("mailbox unavailable")
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+type jobsTest struct {
+	timeclock *mock_lib.MockTimeclockService
+	user      *mock_lib.MockUserService
+	loan      *mock_lib.MockLoanService
+	notifier  *fakeNotifier
+	jobs      *Jobs
+}
+
+func newJobsTest(t *testing.T, policy Policy) *jobsTest {
+	ctrl := gomock.NewController(t)
+	jt := &jobsTest{
+		timeclock: mock_lib.NewMockTimeclockService(ctrl),
+		user:      mock_lib.NewMockUserService(ctrl),
+		loan:      mock_lib.NewMockLoanService(ctrl),
+		notifier:  &fakeNotifier{fail: map[string]bool{}},
+	}
+	jt.jobs = NewJobs(jt.timeclock, jt.user, jt.loan, jt.notifier, policy)
+	return jt
+}
+
+var testContacts = []*data.UserContact{
+	{Id: 1, Fullname: "Admin", Email: "admin@example.com", Role: data.RAdmin},
+	{Id: 2, Fullname: "Budi", Email: "budi@example.com", Role: data.REmployee},
+	{Id: 3, Fullname: "Sari", Email: "sari@example.com", Role: data.REmployee},
+}
+
+// schedulerUser checks the services are called as the scheduler admin
+func schedulerUser(t *testing.T, ctx context.Context) {
+	user, ok := contextutil.GetUser(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "scheduler", user.Actor())
+	assert.Equal(t, data.RAdmin, user.Role)
+}
+
+func TestJobAutoCloseAttendances(t *testing.T) {
+	shiftEnd := time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)
+	jt := newJobsTest(t, Policy{ShiftEnd: shiftEnd})
+
+	jt.timeclock.EXPECT().CloseOpenAttendances(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *timeclockLib.CloseOpenAttendancesIn) *timeclockLib.CloseOpenAttendancesOut {
+			schedulerUser(t, ctx)
+			assert.Equal(t, common.NewDate(2025, 3, 10), in.Date)
+			assert.Equal(t, shiftEnd, in.ShiftEnd)
+			return &timeclockLib.CloseOpenAttendancesOut{Success: true, Closed: []*data.Attendance{{Id: 1}, {Id: 2}}}
+		})
+	detail, err := jt.jobs.autoCloseAttendances(context.Background(), common.NewDateTime(2025, 3, 10, 18, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "closed 2 attendances until 2025-03-10", detail)
+
+	// run after midnight, today's shift has not ended yet
+	jt.timeclock.EXPECT().CloseOpenAttendances(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *timeclockLib.CloseOpenAttendancesIn) *timeclockLib.CloseOpenAttendancesOut {
+			assert.Equal(t, common.NewDate(2025, 3, 10), in.Date)
+			return &timeclockLib.CloseOpenAttendancesOut{Failure: apperror.Failed(apperror.CodeInternal, "internal error")}
+		})
+	_, err = jt.jobs.autoCloseAttendances(context.Background(), common.NewDateTime(2025, 3, 11, 1, 0, 0))
+	assert.EqualError(t, err, "internal error")
+}
+
+func TestJobRemindClockIn(t *testing.T) {
+	jt := newJobsTest(t, Policy{})
+
+	jt.user.EXPECT().UserContacts(gomock.Any(), gomock.Any()).Return(&userLib.UserContactsOut{Success: true, Result: testContacts}).Times(2)
+	jt.timeclock.EXPECT().AttendanceUserIds(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *timeclockLib.AttendanceUserIdsIn) *timeclockLib.AttendanceUserIdsOut {
+			assert.Equal(t, common.NewDate(2025, 3, 10), in.Date)
+			return &timeclockLib.AttendanceUserIdsOut{Success: true, Result: map[int]bool{1: true}}
+		}).Times(2)
+
+	detail, err := jt.jobs.remindClockIn(context.Background(), common.NewDateTime(2025, 3, 10, 9, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "clock-in reminder: sent 2", detail)
+	require.Len(t, jt.notifier.sent, 2)
+	assert.Equal(t, "budi@example.com", jt.notifier.sent[0].To)
+	assert.Contains(t, jt.notifier.sent[0].Body, "10-03-2025")
+
+	jt.notifier.fail["sari@example.com"] = true
+	_, err = jt.jobs.remindClockIn(context.Background(), common.NewDateTime(2025, 3, 10, 9, 0, 0))
+	assert.EqualError(t, err, "clock-in reminder: sent 1, failed 1 (sari@example.com)")
+}
+
+func TestJobCreatePayrollDraft(t *testing.T) {
+	jt := newJobsTest(t, Policy{PayrollCutoffDay: 25})
+
+	detail, err := jt.jobs.createPayrollDraft(context.Background(), common.NewDateTime(2025, 3, 24, 6, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "not the cutoff day", detail)
+
+	jt.timeclock.EXPECT().CreatePayrollDraft(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *timeclockLib.CreatePayrollDraftIn) *timeclockLib.CreatePayrollDraftOut {
+			schedulerUser(t, ctx)
+			assert.Equal(t, common.NewDate(2025, 2, 26), in.PeriodStart)
+			assert.Equal(t, common.NewDate(2025, 3, 25), in.PeriodEnd)
+			return &timeclockLib.CreatePayrollDraftOut{
+				Success: true, Created: true,
+				Draft: &data.PayrollDraft{Id: 7, Employees: 2, TotalSalary: 12500000},
+			}
+		})
+	jt.user.EXPECT().UserContacts(gomock.Any(), gomock.Any()).Return(&userLib.UserContactsOut{Success: true, Result: testContacts})
+
+	detail, err = jt.jobs.createPayrollDraft(context.Background(), common.NewDateTime(2025, 3, 25, 6, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "draft 7 of 26-02-2025 - 25-03-2025 created, draft notification: sent 1", detail)
+	require.Len(t, jt.notifier.sent, 1)
+	assert.Equal(t, "admin@example.com", jt.notifier.sent[0].To)
+	assert.Contains(t, jt.notifier.sent[0].Body, "2 karyawan, total Rp12500000")
+
+	// run again on the same day, eg: by the new leader
+	jt.timeclock.EXPECT().CreatePayrollDraft(gomock.Any(), gomock.Any()).Return(&timeclockLib.CreatePayrollDraftOut{Success: true})
+	detail, err = jt.jobs.createPayrollDraft(context.Background(), common.NewDateTime(2025, 3, 25, 6, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "draft of 26-02-2025 - 25-03-2025 already exists", detail)
+	assert.Len(t, jt.notifier.sent, 1)
+}
+
+func TestJobRemindApprovals(t *testing.T) {
+	jt := newJobsTest(t, Policy{})
+
+	jt.loan.EXPECT().ListLoans(gomock.Any(), gomock.Any()).Return(&loanLib.ListLoansOut{Success: true, Result: []*data.Loan{}})
+	detail, err := jt.jobs.remindApprovals(context.Background(), common.NewDateTime(2025, 3, 10, 9, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "no pending approval", detail)
+
+	jt.loan.EXPECT().ListLoans(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *loanLib.ListLoansIn) *loanLib.ListLoansOut {
+			schedulerUser(t, ctx)
+			assert.Equal(t, data.LoanPending, in.Status)
+			return &loanLib.ListLoansOut{Success: true, Result: []*data.Loan{{Id: 1}, {Id: 2}}}
+		})
+	jt.user.EXPECT().UserContacts(gomock.Any(), gomock.Any()).Return(&userLib.UserContactsOut{Success: true, Result: testContacts})
+
+	detail, err = jt.jobs.remindApprovals(context.Background(), common.NewDateTime(2025, 3, 10, 9, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "2 pending loans: sent 1", detail)
+	require.Len(t, jt.notifier.sent, 1)
+	assert.Contains(t, jt.notifier.sent[0].Body, "Ada 2 pengajuan kasbon")
+}
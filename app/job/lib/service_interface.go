@@ -0,0 +1,27 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// ListRuns searches the run history of the scheduled jobs (admin only)
+	ListRuns(ctx context.Context, in *ListRunsIn) *ListRunsOut
+}
+
+type ListRunsIn struct {
+	Trace  *contextutil.Trace
+	Filter data.JobRunFilter
+}
+
+type ListRunsOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.JobRun
+}
@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	// InsertRun stores a running run and fills Id. It returns false when the job already has a run at
+	// ScheduledAt, eg: started by the previous leader.
+	InsertRun(ctx context.Context, run *data.JobRun) (bool, error)
+
+	// FinishRun sets the result of a run
+	FinishRun(ctx context.Context, id int64, status data.JobRunStatus, detail string, finishedAt time.Time) error
+
+	// GetRuns returns the runs matching the filter, latest started first
+	GetRuns(ctx context.Context, filter *data.JobRunFilter) ([]*data.JobRun, error)
+}
@@ -0,0 +1,40 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/scheduler"
+)
+
+var _ scheduler.Recorder = (*Recorder)(nil)
+
+// Recorder keeps the run history of the scheduler in job_runs
+type Recorder struct {
+	storage lib.StorageInterface
+}
+
+func NewRecorder(storage lib.StorageInterface) *Recorder {
+	return &Recorder{storage: storage}
+}
+
+func (r *Recorder) StartRun(ctx context.Context, job string, scheduledAt time.Time, instance string) (int64, bool, error) {
+	run := &data.JobRun{
+		Job:         job,
+		ScheduledAt: scheduledAt,
+		StartedAt:   time.Now(),
+		Status:      data.JobRunning,
+		Instance:    instance,
+	}
+	started, err := r.storage.InsertRun(ctx, run)
+	if err != nil {
+		return 0, false, err
+	}
+	return run.Id, started, nil
+}
+
+func (r *Recorder) FinishRun(ctx context.Context, runId int64, status data.JobRunStatus, detail string) error {
+	return r.storage.FinishRun(ctx, runId, status, detail, time.Now())
+}
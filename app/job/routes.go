@@ -0,0 +1,19 @@
+package job
+
+import (
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, handler *Handler) {
+	r.Route("/jobs", func(r chi.Router) {
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			// (admin)
+			r.Get("/runs", handler.ListRuns)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage lib.StorageInterface
+}
+
+func NewService(storage lib.StorageInterface) *Service {
+	return &Service{storage: storage}
+}
+
+func (s *Service) ListRuns(ctx context.Context, in *lib.ListRunsIn) *lib.ListRunsOut {
+	resp := &lib.ListRunsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListRuns/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListRuns/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	filter := in.Filter
+	if msg := validateFilter(&filter); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("ListRuns/ invalid filter")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListRuns/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	runs, err := s.storage.GetRuns(ctx, &filter)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListRuns/ get runs failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = runs
+	return resp
+}
@@ -0,0 +1,72 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRecorderAndListRuns(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+	recorder := NewRecorder(storage)
+	service := NewService(storage)
+
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 2, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "job-runs-test"}
+
+	scheduledAt := common.NewDateTime(2025, 3, 10, 18, 0, 0)
+	runId, started, err := recorder.StartRun(con.Context, JobAttendanceAutoClose, scheduledAt, "host-a")
+	require.NoError(t, err)
+	require.True(t, started)
+
+	// the same schedule on another instance is not run again
+	_, started, err = recorder.StartRun(con.Context, JobAttendanceAutoClose, scheduledAt, "host-b")
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	require.NoError(t, recorder.FinishRun(con.Context, runId, data.JobSuccess, "closed 2 attendances"))
+
+	failedId, started, err := recorder.StartRun(con.Context, JobClockInReminder, scheduledAt.Add(time.Hour), "host-a")
+	require.NoError(t, err)
+	require.True(t, started)
+	require.NoError(t, recorder.FinishRun(con.Context, failedId, data.JobFailed, "smtp down"))
+
+	list := service.ListRuns(adminCtx, &lib.ListRunsIn{Trace: trace})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 2)
+
+	list = service.ListRuns(adminCtx, &lib.ListRunsIn{Trace: trace, Filter: data.JobRunFilter{Job: JobAttendanceAutoClose}})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 1)
+	run := list.Result[0]
+	assert.Equal(t, runId, run.Id)
+	assert.True(t, scheduledAt.Equal(run.ScheduledAt))
+	assert.Equal(t, data.JobSuccess, run.Status)
+	assert.Equal(t, "closed 2 attendances", run.Detail)
+	assert.Equal(t, "host-a", run.Instance)
+	assert.NotNil(t, run.FinishedAt)
+
+	list = service.ListRuns(adminCtx, &lib.ListRunsIn{Trace: trace, Filter: data.JobRunFilter{Status: data.JobFailed}})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 1)
+	assert.Equal(t, "smtp down", list.Result[0].Detail)
+
+	list = service.ListRuns(adminCtx, &lib.ListRunsIn{Trace: trace, Filter: data.JobRunFilter{Status: "done"}})
+	assert.False(t, list.Success)
+	assert.Equal(t, "Status tidak valid", list.Message)
+
+	list = service.ListRuns(employeeCtx, &lib.ListRunsIn{Trace: trace})
+	assert.False(t, list.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", list.Message)
+}
@@ -0,0 +1,113 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) InsertRun(ctx context.Context, run *data.JobRun) (bool, error) {
+	const query = `
+		INSERT INTO job_runs (job, scheduled_at, started_at, status, instance)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (job, scheduled_at) DO NOTHING
+		RETURNING id
+	`
+	err := s.db(ctx).QueryRow(ctx, query, run.Job, run.ScheduledAt.UTC(), run.StartedAt.UTC(), run.Status, run.Instance).Scan(&run.Id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Storage) FinishRun(ctx context.Context, id int64, status data.JobRunStatus, detail string, finishedAt time.Time) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		UPDATE job_runs
+		SET status = $2, detail = $3, finished_at = $4
+		WHERE id = $1
+	`, id, status, detail, finishedAt.UTC())
+	return err
+}
+
+func (s *Storage) GetRuns(ctx context.Context, filter *data.JobRunFilter) ([]*data.JobRun, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Job != "" {
+		add("job = $%d", filter.Job)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+
+	query := `SELECT id, job, scheduled_at, started_at, finished_at, status, detail, instance FROM job_runs`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY started_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.JobRun, 0)
+	for rows.Next() {
+		var r data.JobRun
+		err := rows.Scan(&r.Id, &r.Job, &r.ScheduledAt, &r.StartedAt, &r.FinishedAt, &r.Status, &r.Detail, &r.Instance)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &r)
+	}
+	return result, rows.Err()
+}
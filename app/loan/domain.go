@@ -0,0 +1,137 @@
+package loan
+
+import (
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+)
+
+// maxLoanTenor is the longest repayment of an employee loan, in months
+const maxLoanTenor = 60
+
+// Policy is the company rule applied when deducting loans from payroll
+type Policy struct {
+	// MinTakeHome is the lowest take home pay left after loan deduction, the rest stays outstanding
+	MinTakeHome int
+}
+
+func validateRequestLoan(in *lib.RequestLoanIn) string {
+	switch in.Type {
+	case data.LoanKasbon:
+		if in.InterestRate != 0 {
+			return "Kasbon tidak boleh berbunga"
+		}
+		if in.Tenor > 1 {
+			return "Kasbon harus lunas dalam 1 kali potong gaji"
+		}
+	case data.LoanEmployee:
+		if in.Tenor < 1 || in.Tenor > maxLoanTenor {
+			return "Tenor pinjaman tidak valid"
+		}
+		if in.InterestRate < 0 {
+			return "Bunga pinjaman tidak valid"
+		}
+	default:
+		return "Tipe pinjaman tidak valid"
+	}
+
+	if in.Principal <= 0 {
+		return "Nominal pinjaman harus lebih dari 0"
+	}
+	if in.Reason == "" {
+		return "Alasan pinjaman wajib diisi"
+	}
+	return ""
+}
+
+// flatInterest is the interest of the whole tenor, rate is in basis points per year
+func flatInterest(principal int, rate int, tenor int) int {
+	return principal * rate * tenor / (12 * 10000)
+}
+
+// installmentSchedule splits the total payable into equal monthly installments,
+// the remainder of the division is paid in the last installment.
+// Each installment is due at the end of its payroll month.
+func installmentSchedule(loanId int, total int, tenor int, firstYear int, firstMonth int) []*data.LoanInstallment {
+	amount := total / tenor
+	schedule := make([]*data.LoanInstallment, 0, tenor)
+	for i := 0; i < tenor; i++ {
+		installment := &data.LoanInstallment{
+			LoanId: loanId,
+			Seq:    i + 1,
+			// day 0 of the next month is the last day of the month
+			DueDate: common.NewDate(firstYear, firstMonth+i+1, 0),
+			Amount:  amount,
+		}
+		if i == tenor-1 {
+			installment.Amount = total - amount*(tenor-1)
+		}
+		schedule = append(schedule, installment)
+	}
+	return schedule
+}
+
+// dueAmount is what a payroll should deduct from a loan.
+// Installment not fully deducted in the previous payroll is carried over.
+func dueAmount(loan *data.LoanDue, fullSettlement bool) int {
+	if fullSettlement {
+		return loan.Outstanding
+	}
+	return min(max(loan.Scheduled-loan.Repaid, 0), loan.Outstanding)
+}
+
+// payrollDeductions sums the due of every loan per user and caps it so the take home pay
+// never drops below minTakeHome. User not in netPay is not paid by the payroll, so not deducted.
+func payrollDeductions(loans []*data.LoanDue, netPay map[int]int, fullSettlement bool, minTakeHome int) map[int]int {
+	due := make(map[int]int)
+	for _, loan := range loans {
+		if _, ok := netPay[loan.UserId]; !ok {
+			continue
+		}
+		due[loan.UserId] += dueAmount(loan, fullSettlement)
+	}
+
+	result := make(map[int]int)
+	for userId, amount := range due {
+		deduction := min(amount, max(netPay[userId]-minTakeHome, 0))
+		if deduction > 0 {
+			result[userId] = deduction
+		}
+	}
+	return result
+}
+
+// loanDeductions spreads the deduction of every user to their loans, loans must be ordered by user then
+// oldest first. Result key is loanId.
+func loanDeductions(loans []*data.LoanDue, deductions map[int]int, fullSettlement bool) map[int]int {
+	loansPerUser := make(map[int][]*data.LoanDue)
+	for _, loan := range loans {
+		loansPerUser[loan.UserId] = append(loansPerUser[loan.UserId], loan)
+	}
+
+	result := make(map[int]int)
+	for userId, deduction := range deductions {
+		for loanId, amount := range allocateDeduction(loansPerUser[userId], deduction, fullSettlement) {
+			result[loanId] = amount
+		}
+	}
+	return result
+}
+
+// allocateDeduction spreads the deduction of a user to the loans, loans must be ordered oldest first.
+// Result key is loanId.
+func allocateDeduction(loans []*data.LoanDue, deduction int, fullSettlement bool) map[int]int {
+	result := make(map[int]int)
+	for _, loan := range loans {
+		if deduction <= 0 {
+			break
+		}
+		amount := min(dueAmount(loan, fullSettlement), deduction)
+		if amount <= 0 {
+			continue
+		}
+		result[loan.LoanId] = amount
+		deduction -= amount
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequestLoan(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name    string
+		in      *lib.RequestLoanIn
+		message string
+	}{
+		{name: "kasbon", in: &lib.RequestLoanIn{Type: data.LoanKasbon, Principal: 500000, Tenor: 1, Reason: "berobat"}, message: ""},
+		{name: "interest free loan", in: &lib.RequestLoanIn{Type: data.LoanEmployee, Principal: 6000000, Tenor: 12, Reason: "renovasi"}, message: ""},
+		{name: "interest bearing loan", in: &lib.RequestLoanIn{Type: data.LoanEmployee, Principal: 6000000, InterestRate: 600, Tenor: 12, Reason: "renovasi"}, message: ""},
+		{name: "kasbon with interest", in: &lib.RequestLoanIn{Type: data.LoanKasbon, Principal: 500000, InterestRate: 100, Tenor: 1, Reason: "berobat"}, message: "Kasbon tidak boleh berbunga"},
+		{name: "kasbon with installment", in: &lib.RequestLoanIn{Type: data.LoanKasbon, Principal: 500000, Tenor: 3, Reason: "berobat"}, message: "Kasbon harus lunas dalam 1 kali potong gaji"},
+		{name: "loan without tenor", in: &lib.RequestLoanIn{Type: data.LoanEmployee, Principal: 6000000, Reason: "renovasi"}, message: "Tenor pinjaman tidak valid"},
+		{name: "loan tenor too long", in: &lib.RequestLoanIn{Type: data.LoanEmployee, Principal: 6000000, Tenor: 61, Reason: "renovasi"}, message: "Tenor pinjaman tidak valid"},
+		{name: "negative interest", in: &lib.RequestLoanIn{Type: data.LoanEmployee, Principal: 6000000, InterestRate: -1, Tenor: 12, Reason: "renovasi"}, message: "Bunga pinjaman tidak valid"},
+		{name: "invalid type", in: &lib.RequestLoanIn{Type: "gadai", Principal: 500000, Tenor: 1, Reason: "berobat"}, message: "Tipe pinjaman tidak valid"},
+		{name: "zero principal", in: &lib.RequestLoanIn{Type: data.LoanKasbon, Tenor: 1, Reason: "berobat"}, message: "Nominal pinjaman harus lebih dari 0"},
+		{name: "without reason", in: &lib.RequestLoanIn{Type: data.LoanKasbon, Principal: 500000, Tenor: 1}, message: "Alasan pinjaman wajib diisi"},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.message, validateRequestLoan(sc.in))
+		})
+	}
+}
+
+func TestFlatInterest(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, flatInterest(6000000, 0, 12))
+	// 6% a year for 12 months
+	assert.Equal(t, 360000, flatInterest(6000000, 600, 12))
+	// 6% a year for 6 months
+	assert.Equal(t, 180000, flatInterest(6000000, 600, 6))
+}
+
+func TestInstallmentSchedule(t *testing.T) {
+	t.Parallel()
+
+	schedule := installmentSchedule(9, 1000000, 3, 2025, 11)
+	assert.Len(t, schedule, 3)
+
+	assert.Equal(t, 9, schedule[0].LoanId)
+	assert.Equal(t, 1, schedule[0].Seq)
+	assert.Equal(t, common.NewDate(2025, 11, 30), schedule[0].DueDate)
+	assert.Equal(t, 333333, schedule[0].Amount)
+
+	assert.Equal(t, common.NewDate(2025, 12, 31), schedule[1].DueDate)
+	assert.Equal(t, 333333, schedule[1].Amount)
+
+	// crosses the year and the remainder goes to the last installment
+	assert.Equal(t, 3, schedule[2].Seq)
+	assert.Equal(t, common.NewDate(2026, 1, 31), schedule[2].DueDate)
+	assert.Equal(t, 333334, schedule[2].Amount)
+}
+
+func TestPayrollDeductions(t *testing.T) {
+	t.Parallel()
+
+	loans := []*data.LoanDue{
+		// installment of this month
+		{LoanId: 1, UserId: 1, Outstanding: 900000, Scheduled: 300000, Repaid: 0},
+		// second loan of the same user with arrears from last month
+		{LoanId: 2, UserId: 1, Outstanding: 800000, Scheduled: 400000, Repaid: 200000},
+		// installment not due yet
+		{LoanId: 3, UserId: 2, Outstanding: 1000000, Scheduled: 0, Repaid: 0},
+		// last installment is bigger than outstanding
+		{LoanId: 4, UserId: 3, Outstanding: 50000, Scheduled: 600000, Repaid: 500000},
+		// user not paid by this payroll
+		{LoanId: 5, UserId: 4, Outstanding: 100000, Scheduled: 100000, Repaid: 0},
+	}
+
+	scenarios := []struct {
+		name           string
+		netPay         map[int]int
+		fullSettlement bool
+		minTakeHome    int
+		expected       map[int]int
+	}{
+		{
+			name:     "installment due",
+			netPay:   map[int]int{1: 5000000, 2: 5000000, 3: 5000000},
+			expected: map[int]int{1: 500000, 3: 50000},
+		},
+		{
+			name:        "capped by minimum take home",
+			netPay:      map[int]int{1: 1300000, 2: 5000000, 3: 900000},
+			minTakeHome: 1000000,
+			expected:    map[int]int{1: 300000},
+		},
+		{
+			name:           "final settlement deducts outstanding",
+			netPay:         map[int]int{1: 10000000, 2: 500000},
+			fullSettlement: true,
+			expected:       map[int]int{1: 1700000, 2: 500000},
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, payrollDeductions(loans, sc.netPay, sc.fullSettlement, sc.minTakeHome))
+		})
+	}
+}
+
+func TestAllocateDeduction(t *testing.T) {
+	t.Parallel()
+
+	loans := []*data.LoanDue{
+		{LoanId: 1, UserId: 1, Outstanding: 900000, Scheduled: 300000, Repaid: 0},
+		{LoanId: 2, UserId: 1, Outstanding: 800000, Scheduled: 400000, Repaid: 200000},
+	}
+
+	// oldest loan is paid first
+	assert.Equal(t, map[int]int{1: 300000, 2: 100000}, allocateDeduction(loans, 400000, false))
+	assert.Equal(t, map[int]int{1: 300000, 2: 200000}, allocateDeduction(loans, 500000, false))
+	// never more than due
+	assert.Equal(t, map[int]int{1: 300000, 2: 200000}, allocateDeduction(loans, 900000, false))
+	assert.Equal(t, map[int]int{1: 900000, 2: 800000}, allocateDeduction(loans, 1700000, true))
+	assert.Empty(t, allocateDeduction(loans, 0, false))
+}
+
+func TestLoanDeductions(t *testing.T) {
+	t.Parallel()
+
+	loans := []*data.LoanDue{
+		{LoanId: 1, UserId: 1, Outstanding: 900000, Scheduled: 300000, Repaid: 0},
+		{LoanId: 2, UserId: 1, Outstanding: 800000, Scheduled: 400000, Repaid: 200000},
+		{LoanId: 3, UserId: 2, Outstanding: 1000000, Scheduled: 500000, Repaid: 0},
+		{LoanId: 4, UserId: 3, Outstanding: 100000, Scheduled: 100000, Repaid: 0},
+	}
+
+	// user 3 is not deducted by this payroll
+	assert.Equal(t, map[int]int{1: 300000, 2: 100000, 3: 500000}, loanDeductions(loans, map[int]int{1: 400000, 2: 500000}, false))
+	assert.Equal(t, map[int]int{3: 1000000}, loanDeductions(loans, map[int]int{2: 1000000}, true))
+	assert.Empty(t, loanDeductions(loans, nil, false))
+}
@@ -0,0 +1,233 @@
+package loan
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+type requestLoanRequest struct {
+	Type         string `json:"type"` // kasbon or employee
+	Principal    int    `json:"principal"`
+	InterestRate int    `json:"interest_rate"` // basis points per year, eg: 600 is 6% flat
+	Tenor        int    `json:"tenor"`         // months
+	Reason       string `json:"reason"`
+}
+
+func (h *Handler) RequestLoan(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req requestLoanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.RequestLoan(r.Context(), &lib.RequestLoanIn{
+		Trace:        trace,
+		Type:         data.LoanType(req.Type),
+		Principal:    req.Principal,
+		InterestRate: req.InterestRate,
+		Tenor:        req.Tenor,
+		Reason:       req.Reason,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+type approveLoanRequest struct {
+	FirstDueYear  int `json:"first_due_year"`
+	FirstDueMonth int `json:"first_due_month"`
+}
+
+func (h *Handler) ApproveLoan(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	loanId, err := strconv.Atoi(chi.URLParam(r, "loanId"))
+	if err != nil {
+		response.Error(w, r, "Param 'loanId' harus angka")
+		return
+	}
+
+	var req approveLoanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.ApproveLoan(r.Context(), &lib.ApproveLoanIn{
+		Trace:         trace,
+		LoanId:        loanId,
+		FirstDueYear:  req.FirstDueYear,
+		FirstDueMonth: req.FirstDueMonth,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+type noteRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *Handler) RejectLoan(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	loanId, err := strconv.Atoi(chi.URLParam(r, "loanId"))
+	if err != nil {
+		response.Error(w, r, "Param 'loanId' harus angka")
+		return
+	}
+
+	var req noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.RejectLoan(r.Context(), &lib.RejectLoanIn{
+		Trace:  trace,
+		LoanId: loanId,
+		Note:   req.Note,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+func (h *Handler) SettleLoan(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	loanId, err := strconv.Atoi(chi.URLParam(r, "loanId"))
+	if err != nil {
+		response.Error(w, r, "Param 'loanId' harus angka")
+		return
+	}
+
+	var req noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.SettleLoan(r.Context(), &lib.SettleLoanIn{
+		Trace:  trace,
+		LoanId: loanId,
+		Note:   req.Note,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+func (h *Handler) ListLoans(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.ListLoans(r.Context(), &lib.ListLoansIn{
+		Trace:  trace,
+		Status: data.LoanStatus(r.URL.Query().Get("status")),
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+func (h *Handler) SelfLoans(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.SelfLoans(r.Context(), &lib.SelfLoansIn{
+		Trace: trace,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+func (h *Handler) GetLoanDetail(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	loanId, err := strconv.Atoi(chi.URLParam(r, "loanId"))
+	if err != nil {
+		response.Error(w, r, "Param 'loanId' harus angka")
+		return
+	}
+
+	out := h.service.GetLoanDetail(r.Context(), &lib.GetLoanDetailIn{
+		Trace:  trace,
+		LoanId: loanId,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
@@ -0,0 +1,154 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named loan.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="loan"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) RequestLoan(ctx context.Context, in *RequestLoanIn) *RequestLoanOut {
+	ctx, span := tracing.Start(ctx, "loan.RequestLoan")
+	start := time.Now()
+	out := s.next.RequestLoan(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "RequestLoan", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ApproveLoan(ctx context.Context, in *ApproveLoanIn) *ApproveLoanOut {
+	ctx, span := tracing.Start(ctx, "loan.ApproveLoan")
+	start := time.Now()
+	out := s.next.ApproveLoan(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "ApproveLoan", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RejectLoan(ctx context.Context, in *RejectLoanIn) *RejectLoanOut {
+	ctx, span := tracing.Start(ctx, "loan.RejectLoan")
+	start := time.Now()
+	out := s.next.RejectLoan(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "RejectLoan", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListLoans(ctx context.Context, in *ListLoansIn) *ListLoansOut {
+	ctx, span := tracing.Start(ctx, "loan.ListLoans")
+	start := time.Now()
+	out := s.next.ListLoans(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "ListLoans", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SelfLoans(ctx context.Context, in *SelfLoansIn) *SelfLoansOut {
+	ctx, span := tracing.Start(ctx, "loan.SelfLoans")
+	start := time.Now()
+	out := s.next.SelfLoans(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "SelfLoans", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GetLoanDetail(ctx context.Context, in *GetLoanDetailIn) *GetLoanDetailOut {
+	ctx, span := tracing.Start(ctx, "loan.GetLoanDetail")
+	start := time.Now()
+	out := s.next.GetLoanDetail(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "GetLoanDetail", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SettleLoan(ctx context.Context, in *SettleLoanIn) *SettleLoanOut {
+	ctx, span := tracing.Start(ctx, "loan.SettleLoan")
+	start := time.Now()
+	out := s.next.SettleLoan(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "SettleLoan", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) PayrollDeductions(ctx context.Context, in *PayrollDeductionsIn) *PayrollDeductionsOut {
+	ctx, span := tracing.Start(ctx, "loan.PayrollDeductions")
+	start := time.Now()
+	out := s.next.PayrollDeductions(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "PayrollDeductions", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RecordPayrollDeductions(ctx context.Context, in *RecordPayrollDeductionsIn) *RecordPayrollDeductionsOut {
+	ctx, span := tracing.Start(ctx, "loan.RecordPayrollDeductions")
+	start := time.Now()
+	out := s.next.RecordPayrollDeductions(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "RecordPayrollDeductions", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) OutstandingBalances(ctx context.Context, in *OutstandingBalancesIn) *OutstandingBalancesOut {
+	ctx, span := tracing.Start(ctx, "loan.OutstandingBalances")
+	start := time.Now()
+	out := s.next.OutstandingBalances(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("loan", "OutstandingBalances", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
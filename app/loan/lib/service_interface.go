@@ -0,0 +1,172 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// RequestLoan submits a kasbon or a loan for the logged in employee, it waits for admin approval
+	RequestLoan(ctx context.Context, in *RequestLoanIn) *RequestLoanOut
+
+	// ApproveLoan activates a pending loan and generates its installment schedule (admin only)
+	ApproveLoan(ctx context.Context, in *ApproveLoanIn) *ApproveLoanOut
+	RejectLoan(ctx context.Context, in *RejectLoanIn) *RejectLoanOut
+
+	ListLoans(ctx context.Context, in *ListLoansIn) *ListLoansOut
+	SelfLoans(ctx context.Context, in *SelfLoansIn) *SelfLoansOut
+	GetLoanDetail(ctx context.Context, in *GetLoanDetailIn) *GetLoanDetailOut
+
+	// SettleLoan pays off the whole outstanding of a loan outside payroll, eg: on termination (admin only)
+	SettleLoan(ctx context.Context, in *SettleLoanIn) *SettleLoanOut
+
+	// PayrollDeductions calculates the loan deduction of each employee in a payroll run. Called in the payroll
+	// transaction, the loans stay locked until it ends.
+	PayrollDeductions(ctx context.Context, in *PayrollDeductionsIn) *PayrollDeductionsOut
+
+	// RecordPayrollDeductions applies the deductions per loan calculated by PayrollDeductions. Recording the
+	// same payroll twice is a no-op, called in the payroll transaction it commits with the payroll.
+	RecordPayrollDeductions(ctx context.Context, in *RecordPayrollDeductionsIn) *RecordPayrollDeductionsOut
+
+	// OutstandingBalances returns the outstanding of active loans per user
+	OutstandingBalances(ctx context.Context, in *OutstandingBalancesIn) *OutstandingBalancesOut
+}
+
+type RequestLoanIn struct {
+	Trace        *contextutil.Trace
+	Type         data.LoanType
+	Principal    int
+	InterestRate int // basis points per year, must be 0 for kasbon
+	Tenor        int // number of monthly installments, kasbon is always 1
+	Reason       string
+}
+
+type RequestLoanOut struct {
+	Success bool
+	apperror.Failure
+	LoanId int
+}
+
+type ApproveLoanIn struct {
+	Trace  *contextutil.Trace
+	LoanId int
+
+	// first installment is deducted from the payroll of this month
+	FirstDueYear  int
+	FirstDueMonth int
+}
+
+type ApproveLoanOut struct {
+	Success bool
+	apperror.Failure
+	Schedule []*data.LoanInstallment
+}
+
+type RejectLoanIn struct {
+	Trace  *contextutil.Trace
+	LoanId int
+	Note   string
+}
+
+type RejectLoanOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type ListLoansIn struct {
+	Trace  *contextutil.Trace
+	Status data.LoanStatus // empty means every status
+}
+
+type ListLoansOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.Loan
+}
+
+type SelfLoansIn struct {
+	Trace *contextutil.Trace
+}
+
+type SelfLoansOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.Loan
+}
+
+type GetLoanDetailIn struct {
+	Trace  *contextutil.Trace
+	LoanId int
+}
+
+type GetLoanDetailOut struct {
+	Success bool
+	apperror.Failure
+	Loan       *data.Loan
+	Schedule   []*data.LoanInstallment
+	Repayments []*data.LoanRepayment
+}
+
+type SettleLoanIn struct {
+	Trace  *contextutil.Trace
+	LoanId int
+	Note   string
+}
+
+type SettleLoanOut struct {
+	Success bool
+	apperror.Failure
+	Amount int // outstanding paid off
+}
+
+type PayrollDeductionsIn struct {
+	Trace     *contextutil.Trace
+	PeriodEnd time.Time
+
+	// FullSettlement deducts the whole outstanding instead of the installment due, used by final settlement
+	FullSettlement bool
+
+	// NetPay key is userId and value is the take home pay before loan deduction
+	NetPay map[int]int
+}
+
+type PayrollDeductionsOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result key is userId and value is the deduction, user without deduction is not listed
+	Result map[int]int
+
+	// Loans key is loanId and value is its part of the deduction, the oldest loan of a user paid first
+	Loans map[int]int
+}
+
+type RecordPayrollDeductionsIn struct {
+	Trace      *contextutil.Trace
+	PayrollId  int
+	Deductions map[int]int // key is loanId, the Loans of PayrollDeductionsOut
+}
+
+type RecordPayrollDeductionsOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type OutstandingBalancesIn struct {
+	Trace  *contextutil.Trace
+	UserId int // 0 means every user
+}
+
+type OutstandingBalancesOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result key is userId and value is the outstanding, user without active loan is not listed
+	Result map[int]int
+}
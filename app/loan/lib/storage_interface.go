@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	InsertLoan(ctx context.Context, userId int, loanType data.LoanType, principal int, interestRate int, tenor int, totalPayable int, reason string, createdBy string) (int, error)
+
+	// GetLoanById returns nil if the loan does not exist
+	GetLoanById(ctx context.Context, loanId int) (*data.Loan, error)
+
+	// GetLoanByIdForUpdate is GetLoanById locking the loan until the transaction ends, so two admins
+	// reviewing or settling the same loan do it one after the other
+	GetLoanByIdForUpdate(ctx context.Context, loanId int) (*data.Loan, error)
+
+	// GetLoans returns loans ordered by id, userId 0 means every user and empty status means every status
+	GetLoans(ctx context.Context, userId int, status data.LoanStatus) ([]*data.Loan, error)
+
+	ApproveLoan(ctx context.Context, loanId int, approvedBy string) error
+	RejectLoan(ctx context.Context, loanId int, reviewNote string, updatedBy string) error
+
+	InsertInstallments(ctx context.Context, schedule []*data.LoanInstallment, createdBy string) error
+	GetInstallmentsByLoanId(ctx context.Context, loanId int) ([]*data.LoanInstallment, error)
+
+	// GetLoansDue locks and returns every active loan with the installments due up to dueDate,
+	// ordered by user then approval so the oldest loan is deducted first
+	GetLoansDue(ctx context.Context, dueDate time.Time) ([]*data.LoanDue, error)
+
+	// InsertRepayment returns false if the payroll already deducted this loan
+	InsertRepayment(ctx context.Context, loanId int, payrollId int, source data.LoanRepaymentSource, amount int, note string, createdBy string) (bool, error)
+	GetRepaymentsByLoanId(ctx context.Context, loanId int) ([]*data.LoanRepayment, error)
+
+	// DecreaseOutstanding reduces the outstanding of a loan and marks it paid off once it reaches zero
+	DecreaseOutstanding(ctx context.Context, loanId int, amount int, updatedBy string) error
+
+	// GetOutstandingByUser returns the outstanding of active loans per user, userId 0 means every user
+	GetOutstandingByUser(ctx context.Context, userId int) (map[int]int, error)
+}
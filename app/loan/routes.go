@@ -0,0 +1,33 @@
+package loan
+
+import (
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, handler *Handler) {
+	r.Route("/loans", func(r chi.Router) {
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			// read only routes are open to api tokens with loan:read
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(data.ScopeLoanRead))
+				r.Get("/self", handler.SelfLoans)
+				r.Get("/{loanId}", handler.GetLoanDetail)
+				r.Get("/", handler.ListLoans)
+			})
+
+			// (employee)
+			r.Post("/", handler.RequestLoan)
+
+			// (admin)
+			r.Post("/{loanId}/approve", handler.ApproveLoan)
+			r.Post("/{loanId}/reject", handler.RejectLoan)
+			r.Post("/{loanId}/settle", handler.SettleLoan)
+		})
+	})
+}
@@ -0,0 +1,505 @@
+package loan
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage lib.StorageInterface
+	policy  Policy
+}
+
+func NewService(storage lib.StorageInterface, policy Policy) *Service {
+	return &Service{
+		storage: storage,
+		policy:  policy,
+	}
+}
+
+func (s *Service) RequestLoan(ctx context.Context, in *lib.RequestLoanIn) *lib.RequestLoanOut {
+	resp := &lib.RequestLoanOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("RequestLoan/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Type == data.LoanKasbon && in.Tenor == 0 {
+		in.Tenor = 1
+	}
+	if msg := validateRequestLoan(in); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("RequestLoan/ invalid input")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return resp
+	}
+
+	total := in.Principal + flatInterest(in.Principal, in.InterestRate, in.Tenor)
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RequestLoan/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loanId, err := s.storage.InsertLoan(ctx, user.Id, in.Type, in.Principal, in.InterestRate, in.Tenor, total, in.Reason, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RequestLoan/ insert loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("RequestLoan/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.LoanId = loanId
+	return resp
+}
+
+func (s *Service) ApproveLoan(ctx context.Context, in *lib.ApproveLoanIn) *lib.ApproveLoanOut {
+	resp := &lib.ApproveLoanOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ApproveLoan/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ApproveLoan/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if in.FirstDueYear <= 0 || in.FirstDueMonth < 1 || in.FirstDueMonth > 12 {
+		log.Warn(in.Trace).Msg("ApproveLoan/ invalid first due month")
+		resp.Fail(apperror.CodeInvalidInput, "Bulan potongan pertama tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ApproveLoan/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loan, err := s.storage.GetLoanByIdForUpdate(ctx, in.LoanId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ApproveLoan/ get loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if loan == nil {
+		log.Warn(in.Trace).Int("loanId", in.LoanId).Msg("ApproveLoan/ loan not found")
+		resp.Fail(apperror.CodeLoanNotFound, "Pinjaman tidak ditemukan")
+		return resp
+	}
+	if loan.Status != data.LoanPending {
+		log.Warn(in.Trace).Str("status", string(loan.Status)).Msg("ApproveLoan/ loan already reviewed")
+		resp.Fail(apperror.CodeLoanProcessed, "Pinjaman sudah diproses")
+		return resp
+	}
+
+	schedule := installmentSchedule(loan.Id, loan.TotalPayable, loan.Tenor, in.FirstDueYear, in.FirstDueMonth)
+	if err := s.storage.InsertInstallments(ctx, schedule, user.Actor()); err != nil {
+		log.Error(in.Trace).Err(err).Msg("ApproveLoan/ insert installments failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := s.storage.ApproveLoan(ctx, loan.Id, user.Actor()); err != nil {
+		log.Error(in.Trace).Err(err).Msg("ApproveLoan/ approve loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("ApproveLoan/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Schedule = schedule
+	return resp
+}
+
+func (s *Service) RejectLoan(ctx context.Context, in *lib.RejectLoanIn) *lib.RejectLoanOut {
+	resp := &lib.RejectLoanOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("RejectLoan/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("RejectLoan/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if in.Note == "" {
+		log.Warn(in.Trace).Msg("RejectLoan/ empty note")
+		resp.Fail(apperror.CodeInvalidInput, "Alasan penolakan wajib diisi")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RejectLoan/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loan, err := s.storage.GetLoanByIdForUpdate(ctx, in.LoanId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RejectLoan/ get loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if loan == nil {
+		log.Warn(in.Trace).Int("loanId", in.LoanId).Msg("RejectLoan/ loan not found")
+		resp.Fail(apperror.CodeLoanNotFound, "Pinjaman tidak ditemukan")
+		return resp
+	}
+	if loan.Status != data.LoanPending {
+		log.Warn(in.Trace).Str("status", string(loan.Status)).Msg("RejectLoan/ loan already reviewed")
+		resp.Fail(apperror.CodeLoanProcessed, "Pinjaman sudah diproses")
+		return resp
+	}
+
+	if err := s.storage.RejectLoan(ctx, loan.Id, in.Note, user.Actor()); err != nil {
+		log.Error(in.Trace).Err(err).Msg("RejectLoan/ reject loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("RejectLoan/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+func (s *Service) ListLoans(ctx context.Context, in *lib.ListLoansIn) *lib.ListLoansOut {
+	resp := &lib.ListLoansOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListLoans/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListLoans/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	switch in.Status {
+	case "", data.LoanPending, data.LoanActive, data.LoanRejected, data.LoanPaidOff:
+	default:
+		log.Warn(in.Trace).Str("status", string(in.Status)).Msg("ListLoans/ invalid status")
+		resp.Fail(apperror.CodeInvalidInput, "Status pinjaman tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListLoans/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loans, err := s.storage.GetLoans(ctx, 0, in.Status)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListLoans/ get loans failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = loans
+	return resp
+}
+
+func (s *Service) SelfLoans(ctx context.Context, in *lib.SelfLoansIn) *lib.SelfLoansOut {
+	resp := &lib.SelfLoansOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SelfLoans/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SelfLoans/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loans, err := s.storage.GetLoans(ctx, user.Id, "")
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SelfLoans/ get loans failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = loans
+	return resp
+}
+
+func (s *Service) GetLoanDetail(ctx context.Context, in *lib.GetLoanDetailIn) *lib.GetLoanDetailOut {
+	resp := &lib.GetLoanDetailOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("GetLoanDetail/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetLoanDetail/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loan, err := s.storage.GetLoanById(ctx, in.LoanId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetLoanDetail/ get loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	// employee can only see their own loan, other loan is reported as not found
+	if loan == nil || (user.Role != data.RAdmin && loan.UserId != user.Id) {
+		log.Warn(in.Trace).Int("loanId", in.LoanId).Msg("GetLoanDetail/ loan not found")
+		resp.Fail(apperror.CodeLoanNotFound, "Pinjaman tidak ditemukan")
+		return resp
+	}
+
+	schedule, err := s.storage.GetInstallmentsByLoanId(ctx, loan.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetLoanDetail/ get installments failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	repayments, err := s.storage.GetRepaymentsByLoanId(ctx, loan.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetLoanDetail/ get repayments failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Loan = loan
+	resp.Schedule = schedule
+	resp.Repayments = repayments
+	return resp
+}
+
+func (s *Service) SettleLoan(ctx context.Context, in *lib.SettleLoanIn) *lib.SettleLoanOut {
+	resp := &lib.SettleLoanOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SettleLoan/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SettleLoan/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SettleLoan/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loan, err := s.storage.GetLoanByIdForUpdate(ctx, in.LoanId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SettleLoan/ get loan failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if loan == nil {
+		log.Warn(in.Trace).Int("loanId", in.LoanId).Msg("SettleLoan/ loan not found")
+		resp.Fail(apperror.CodeLoanNotFound, "Pinjaman tidak ditemukan")
+		return resp
+	}
+	if loan.Status != data.LoanActive {
+		log.Warn(in.Trace).Str("status", string(loan.Status)).Msg("SettleLoan/ loan not active")
+		resp.Fail(apperror.CodeLoanNotActive, "Pinjaman tidak aktif")
+		return resp
+	}
+
+	_, err = s.storage.InsertRepayment(ctx, loan.Id, 0, data.RepaymentSettlement, loan.Outstanding, in.Note, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SettleLoan/ insert repayment failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := s.storage.DecreaseOutstanding(ctx, loan.Id, loan.Outstanding, user.Actor()); err != nil {
+		log.Error(in.Trace).Err(err).Msg("SettleLoan/ decrease outstanding failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("SettleLoan/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Amount = loan.Outstanding
+	return resp
+}
+
+func (s *Service) PayrollDeductions(ctx context.Context, in *lib.PayrollDeductionsIn) *lib.PayrollDeductionsOut {
+	resp := &lib.PayrollDeductionsOut{}
+
+	// a writer, the loans due stay locked until the payroll transaction of the caller ends
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("PayrollDeductions/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	loans, err := s.storage.GetLoansDue(ctx, in.PeriodEnd)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("PayrollDeductions/ get loans due failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("PayrollDeductions/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = payrollDeductions(loans, in.NetPay, in.FullSettlement, s.policy.MinTakeHome)
+	resp.Loans = loanDeductions(loans, resp.Result, in.FullSettlement)
+	return resp
+}
+
+func (s *Service) RecordPayrollDeductions(ctx context.Context, in *lib.RecordPayrollDeductionsIn) *lib.RecordPayrollDeductionsOut {
+	resp := &lib.RecordPayrollDeductionsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("RecordPayrollDeductions/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RecordPayrollDeductions/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	for loanId, amount := range in.Deductions {
+		inserted, err := s.storage.InsertRepayment(ctx, loanId, in.PayrollId, data.RepaymentPayroll, amount, "", user.Actor())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msgf("RecordPayrollDeductions/ insert repayment loan_id=%d failed", loanId)
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+		// already recorded by a previous call for the same payroll
+		if !inserted {
+			continue
+		}
+
+		if err := s.storage.DecreaseOutstanding(ctx, loanId, amount, user.Actor()); err != nil {
+			log.Error(in.Trace).Err(err).Msgf("RecordPayrollDeductions/ decrease outstanding loan_id=%d failed", loanId)
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("RecordPayrollDeductions/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+func (s *Service) OutstandingBalances(ctx context.Context, in *lib.OutstandingBalancesIn) *lib.OutstandingBalancesOut {
+	resp := &lib.OutstandingBalancesOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("OutstandingBalances/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	balances, err := s.storage.GetOutstandingByUser(ctx, in.UserId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("OutstandingBalances/ get outstanding failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = balances
+	return resp
+}
@@ -0,0 +1,342 @@
+package loan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceRequestLoan(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool), Policy{})
+
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "request-loan-test"}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.RequestLoanIn
+		success bool
+		errMsg  string
+		total   int
+	}{
+		{
+			name:    "success kasbon without tenor",
+			ctx:     employeeCtx,
+			in:      &lib.RequestLoanIn{Trace: trace, Type: data.LoanKasbon, Principal: 500000, Reason: "berobat"},
+			success: true,
+			total:   500000,
+		},
+		{
+			name:    "success loan with interest",
+			ctx:     employeeCtx,
+			in:      &lib.RequestLoanIn{Trace: trace, Type: data.LoanEmployee, Principal: 6000000, InterestRate: 600, Tenor: 12, Reason: "renovasi"},
+			success: true,
+			total:   6360000,
+		},
+		{
+			name:   "fail invalid input",
+			ctx:    employeeCtx,
+			in:     &lib.RequestLoanIn{Trace: trace, Type: data.LoanKasbon, Principal: 500000, InterestRate: 100, Reason: "berobat"},
+			errMsg: "Kasbon tidak boleh berbunga",
+		},
+		{
+			name:   "fail unauthorized",
+			ctx:    context.Background(),
+			in:     &lib.RequestLoanIn{Trace: trace, Type: data.LoanKasbon, Principal: 500000, Reason: "berobat"},
+			errMsg: "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resp := service.RequestLoan(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			if !sc.success {
+				return
+			}
+
+			detail := service.GetLoanDetail(sc.ctx, &lib.GetLoanDetailIn{Trace: trace, LoanId: resp.LoanId})
+			assert.True(t, detail.Success)
+			assert.Equal(t, data.LoanPending, detail.Loan.Status)
+			assert.Equal(t, sc.total, detail.Loan.TotalPayable)
+			assert.Equal(t, sc.total, detail.Loan.Outstanding)
+		})
+	}
+}
+
+func TestServiceApproveLoan(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool), Policy{})
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "approve-loan-test"}
+
+	requested := service.RequestLoan(employeeCtx, &lib.RequestLoanIn{
+		Trace: trace, Type: data.LoanEmployee, Principal: 3000000, Tenor: 3, Reason: "sekolah anak",
+	})
+	assert.True(t, requested.Success)
+
+	rejected := service.RequestLoan(employeeCtx, &lib.RequestLoanIn{
+		Trace: trace, Type: data.LoanKasbon, Principal: 500000, Reason: "liburan",
+	})
+	assert.True(t, rejected.Success)
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.ApproveLoanIn
+		success bool
+		errMsg  string
+	}{
+		{
+			name:   "fail unauthorized",
+			ctx:    context.Background(),
+			in:     &lib.ApproveLoanIn{Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1},
+			errMsg: "unauthorized",
+		},
+		{
+			name:   "fail not admin",
+			ctx:    employeeCtx,
+			in:     &lib.ApproveLoanIn{Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1},
+			errMsg: "forbidden: Hanya admin yang bisa akses",
+		},
+		{
+			name:   "fail invalid month",
+			ctx:    adminCtx,
+			in:     &lib.ApproveLoanIn{Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 13},
+			errMsg: "Bulan potongan pertama tidak valid",
+		},
+		{
+			name:   "fail not found",
+			ctx:    adminCtx,
+			in:     &lib.ApproveLoanIn{Trace: trace, LoanId: 12345, FirstDueYear: 2025, FirstDueMonth: 1},
+			errMsg: "Pinjaman tidak ditemukan",
+		},
+		{
+			name:    "success",
+			ctx:     adminCtx,
+			in:      &lib.ApproveLoanIn{Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1},
+			success: true,
+		},
+		{
+			name:   "fail already approved",
+			ctx:    adminCtx,
+			in:     &lib.ApproveLoanIn{Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1},
+			errMsg: "Pinjaman sudah diproses",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resp := service.ApproveLoan(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+		})
+	}
+
+	detail := service.GetLoanDetail(employeeCtx, &lib.GetLoanDetailIn{Trace: trace, LoanId: requested.LoanId})
+	assert.True(t, detail.Success)
+	assert.Equal(t, data.LoanActive, detail.Loan.Status)
+	assert.Len(t, detail.Schedule, 3)
+	assert.Equal(t, common.NewDate(2025, 3, 31).Format("2006-01-02"), detail.Schedule[2].DueDate.Format("2006-01-02"))
+
+	// reject
+	resp := service.RejectLoan(employeeCtx, &lib.RejectLoanIn{Trace: trace, LoanId: rejected.LoanId, Note: "bukan admin"})
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", resp.Message)
+
+	resp = service.RejectLoan(adminCtx, &lib.RejectLoanIn{Trace: trace, LoanId: rejected.LoanId})
+	assert.Equal(t, "Alasan penolakan wajib diisi", resp.Message)
+
+	resp = service.RejectLoan(adminCtx, &lib.RejectLoanIn{Trace: trace, LoanId: rejected.LoanId, Note: "kasbon bukan untuk liburan"})
+	assert.True(t, resp.Success)
+
+	resp = service.RejectLoan(adminCtx, &lib.RejectLoanIn{Trace: trace, LoanId: requested.LoanId, Note: "terlambat"})
+	assert.Equal(t, "Pinjaman sudah diproses", resp.Message)
+
+	// other employee can not see the loan
+	otherCtx := test.UserContext(con.Context, 2, data.REmployee)
+	other := service.GetLoanDetail(otherCtx, &lib.GetLoanDetailIn{Trace: trace, LoanId: requested.LoanId})
+	assert.False(t, other.Success)
+	assert.Equal(t, "Pinjaman tidak ditemukan", other.Message)
+
+	list := service.ListLoans(adminCtx, &lib.ListLoansIn{Trace: trace, Status: data.LoanRejected})
+	assert.True(t, list.Success)
+	assert.Len(t, list.Result, 1)
+	assert.Equal(t, "kasbon bukan untuk liburan", list.Result[0].ReviewNote)
+
+	list = service.ListLoans(adminCtx, &lib.ListLoansIn{Trace: trace, Status: "unknown"})
+	assert.Equal(t, "Status pinjaman tidak valid", list.Message)
+}
+
+func TestServicePayrollDeductions(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool), Policy{MinTakeHome: 1000000})
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "payroll-deduction-test"}
+
+	requested := service.RequestLoan(employeeCtx, &lib.RequestLoanIn{
+		Trace: trace, Type: data.LoanEmployee, Principal: 3000000, Tenor: 3, Reason: "sekolah anak",
+	})
+	assert.True(t, requested.Success)
+
+	approved := service.ApproveLoan(adminCtx, &lib.ApproveLoanIn{
+		Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1,
+	})
+	assert.True(t, approved.Success)
+
+	// not due yet
+	deductions := service.PayrollDeductions(adminCtx, &lib.PayrollDeductionsIn{
+		Trace: trace, PeriodEnd: common.NewDate(2024, 12, 31), NetPay: map[int]int{1: 5000000},
+	})
+	assert.True(t, deductions.Success)
+	assert.Empty(t, deductions.Result)
+
+	// january, take home only allows 600.000 of the 1.000.000 installment
+	deductions = service.PayrollDeductions(adminCtx, &lib.PayrollDeductionsIn{
+		Trace: trace, PeriodEnd: common.NewDate(2025, 1, 31), NetPay: map[int]int{1: 1600000, 2: 5000000},
+	})
+	assert.True(t, deductions.Success)
+	assert.Equal(t, map[int]int{1: 600000}, deductions.Result)
+	assert.Equal(t, map[int]int{requested.LoanId: 600000}, deductions.Loans)
+
+	recorded := service.RecordPayrollDeductions(adminCtx, &lib.RecordPayrollDeductionsIn{
+		Trace: trace, PayrollId: 1, Deductions: deductions.Loans,
+	})
+	assert.True(t, recorded.Success)
+
+	// recording the same payroll again is a no-op
+	recorded = service.RecordPayrollDeductions(adminCtx, &lib.RecordPayrollDeductionsIn{
+		Trace: trace, PayrollId: 1, Deductions: deductions.Loans,
+	})
+	assert.True(t, recorded.Success)
+
+	balances := service.OutstandingBalances(adminCtx, &lib.OutstandingBalancesIn{Trace: trace})
+	assert.True(t, balances.Success)
+	assert.Equal(t, map[int]int{1: 2400000}, balances.Result)
+
+	// february deducts the arrears of january together with the installment
+	deductions = service.PayrollDeductions(adminCtx, &lib.PayrollDeductionsIn{
+		Trace: trace, PeriodEnd: common.NewDate(2025, 2, 28), NetPay: map[int]int{1: 5000000},
+	})
+	assert.True(t, deductions.Success)
+	assert.Equal(t, map[int]int{1: 1400000}, deductions.Result)
+
+	// final settlement deducts the whole outstanding
+	deductions = service.PayrollDeductions(adminCtx, &lib.PayrollDeductionsIn{
+		Trace: trace, PeriodEnd: common.NewDate(2025, 2, 28), FullSettlement: true, NetPay: map[int]int{1: 10000000},
+	})
+	assert.True(t, deductions.Success)
+	assert.Equal(t, map[int]int{1: 2400000}, deductions.Result)
+
+	recorded = service.RecordPayrollDeductions(adminCtx, &lib.RecordPayrollDeductionsIn{
+		Trace: trace, PayrollId: 2, Deductions: deductions.Loans,
+	})
+	assert.True(t, recorded.Success)
+
+	detail := service.GetLoanDetail(adminCtx, &lib.GetLoanDetailIn{Trace: trace, LoanId: requested.LoanId})
+	assert.True(t, detail.Success)
+	assert.Equal(t, data.LoanPaidOff, detail.Loan.Status)
+	assert.Equal(t, 0, detail.Loan.Outstanding)
+	assert.Len(t, detail.Repayments, 2)
+
+	balances = service.OutstandingBalances(adminCtx, &lib.OutstandingBalancesIn{Trace: trace, UserId: 1})
+	assert.True(t, balances.Success)
+	assert.Empty(t, balances.Result)
+}
+
+func TestServiceSettleLoan(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	service := NewService(NewStorage(con.Pool), Policy{})
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "settle-loan-test"}
+
+	requested := service.RequestLoan(employeeCtx, &lib.RequestLoanIn{
+		Trace: trace, Type: data.LoanEmployee, Principal: 1200000, Tenor: 12, Reason: "motor",
+	})
+	assert.True(t, requested.Success)
+
+	pending := service.SettleLoan(adminCtx, &lib.SettleLoanIn{Trace: trace, LoanId: requested.LoanId})
+	assert.Equal(t, "Pinjaman tidak aktif", pending.Message)
+
+	approved := service.ApproveLoan(adminCtx, &lib.ApproveLoanIn{
+		Trace: trace, LoanId: requested.LoanId, FirstDueYear: 2025, FirstDueMonth: 1,
+	})
+	assert.True(t, approved.Success)
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.SettleLoanIn
+		success bool
+		errMsg  string
+		amount  int
+	}{
+		{
+			name:   "fail unauthorized",
+			ctx:    context.Background(),
+			in:     &lib.SettleLoanIn{Trace: trace, LoanId: requested.LoanId},
+			errMsg: "unauthorized",
+		},
+		{
+			name:   "fail not admin",
+			ctx:    employeeCtx,
+			in:     &lib.SettleLoanIn{Trace: trace, LoanId: requested.LoanId},
+			errMsg: "forbidden: Hanya admin yang bisa akses",
+		},
+		{
+			name:   "fail not found",
+			ctx:    adminCtx,
+			in:     &lib.SettleLoanIn{Trace: trace, LoanId: 12345},
+			errMsg: "Pinjaman tidak ditemukan",
+		},
+		{
+			name:    "success",
+			ctx:     adminCtx,
+			in:      &lib.SettleLoanIn{Trace: trace, LoanId: requested.LoanId, Note: "resign, dibayar tunai"},
+			success: true,
+			amount:  1200000,
+		},
+		{
+			name:   "fail already paid off",
+			ctx:    adminCtx,
+			in:     &lib.SettleLoanIn{Trace: trace, LoanId: requested.LoanId},
+			errMsg: "Pinjaman tidak aktif",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resp := service.SettleLoan(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			assert.Equal(t, sc.amount, resp.Amount)
+		})
+	}
+
+	self := service.SelfLoans(employeeCtx, &lib.SelfLoansIn{Trace: trace})
+	assert.True(t, self.Success)
+	assert.Len(t, self.Result, 1)
+	assert.Equal(t, data.LoanPaidOff, self.Result[0].Status)
+}
@@ -0,0 +1,323 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) InsertLoan(
+	ctx context.Context,
+	userId int,
+	loanType data.LoanType,
+	principal int,
+	interestRate int,
+	tenor int,
+	totalPayable int,
+	reason string,
+	createdBy string,
+) (int, error) {
+	const query = `
+		INSERT INTO loans (
+			user_id, loan_type, principal, interest_rate, tenor,
+			total_payable, outstanding, status, reason,
+			created_by, updated_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, 'pending', $7, $8, $8)
+		RETURNING id
+	`
+	var id int
+	err := s.db(ctx).QueryRow(ctx, query,
+		userId, loanType, principal, interestRate, tenor, totalPayable, reason, createdBy,
+	).Scan(&id)
+	return id, err
+}
+
+const loanColumns = `
+	id, user_id, loan_type, principal, interest_rate, tenor,
+	total_payable, outstanding, status, reason, review_note,
+	approved_by, approved_at,
+	created_at, updated_at, created_by, updated_by
+`
+
+// scanLoan scans one row selected with loanColumns
+func scanLoan(row pgx.Row) (*data.Loan, error) {
+	var l data.Loan
+	err := row.Scan(
+		&l.Id, &l.UserId, &l.Type, &l.Principal, &l.InterestRate, &l.Tenor,
+		&l.TotalPayable, &l.Outstanding, &l.Status, &l.Reason, &l.ReviewNote,
+		&l.ApprovedBy, &l.ApprovedAt,
+		&l.CreatedAt, &l.UpdatedAt, &l.CreatedBy, &l.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *Storage) GetLoanById(ctx context.Context, loanId int) (*data.Loan, error) {
+	query := `SELECT ` + loanColumns + ` FROM loans WHERE id = $1`
+
+	loan, err := scanLoan(s.db(ctx).QueryRow(ctx, query, loanId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (s *Storage) GetLoanByIdForUpdate(ctx context.Context, loanId int) (*data.Loan, error) {
+	query := `SELECT ` + loanColumns + ` FROM loans WHERE id = $1 FOR UPDATE`
+
+	loan, err := scanLoan(s.db(ctx).QueryRow(ctx, query, loanId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (s *Storage) GetLoans(ctx context.Context, userId int, status data.LoanStatus) ([]*data.Loan, error) {
+	query := `
+		SELECT ` + loanColumns + `
+		FROM loans
+		WHERE ($1 = 0 OR user_id = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY id
+	`
+	rows, err := s.db(ctx).Query(ctx, query, userId, string(status))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.Loan, 0)
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, loan)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) ApproveLoan(ctx context.Context, loanId int, approvedBy string) error {
+	const query = `
+		UPDATE loans
+		SET status = 'active',
+		    approved_by = $2,
+		    approved_at = CURRENT_TIMESTAMP,
+		    updated_by = $2,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, loanId, approvedBy)
+	return err
+}
+
+func (s *Storage) RejectLoan(ctx context.Context, loanId int, reviewNote string, updatedBy string) error {
+	const query = `
+		UPDATE loans
+		SET status = 'rejected',
+		    review_note = $2,
+		    updated_by = $3,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, loanId, reviewNote, updatedBy)
+	return err
+}
+
+func (s *Storage) InsertInstallments(ctx context.Context, schedule []*data.LoanInstallment, createdBy string) error {
+	const query = `
+		INSERT INTO loan_installments (loan_id, seq, due_date, amount, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, installment := range schedule {
+		_, err := s.db(ctx).Exec(ctx, query, installment.LoanId, installment.Seq, installment.DueDate, installment.Amount, createdBy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetInstallmentsByLoanId(ctx context.Context, loanId int) ([]*data.LoanInstallment, error) {
+	const query = `
+		SELECT loan_id, seq, due_date, amount
+		FROM loan_installments
+		WHERE loan_id = $1
+		ORDER BY seq
+	`
+	rows, err := s.db(ctx).Query(ctx, query, loanId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.LoanInstallment, 0)
+	for rows.Next() {
+		var installment data.LoanInstallment
+		if err := rows.Scan(&installment.LoanId, &installment.Seq, &installment.DueDate, &installment.Amount); err != nil {
+			return nil, err
+		}
+		result = append(result, &installment)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) GetLoansDue(ctx context.Context, dueDate time.Time) ([]*data.LoanDue, error) {
+	const query = `
+		SELECT l.id, l.user_id, l.outstanding,
+		       COALESCE((SELECT SUM(i.amount) FROM loan_installments i
+		                 WHERE i.loan_id = l.id AND i.due_date <= $1), 0),
+		       COALESCE((SELECT SUM(r.amount) FROM loan_repayments r
+		                 WHERE r.loan_id = l.id), 0)
+		FROM loans l
+		WHERE l.status = 'active'
+		ORDER BY l.user_id, l.approved_at, l.id
+		FOR UPDATE OF l
+	`
+	rows, err := s.db(ctx).Query(ctx, query, dueDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.LoanDue, 0)
+	for rows.Next() {
+		var due data.LoanDue
+		if err := rows.Scan(&due.LoanId, &due.UserId, &due.Outstanding, &due.Scheduled, &due.Repaid); err != nil {
+			return nil, err
+		}
+		result = append(result, &due)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) InsertRepayment(
+	ctx context.Context,
+	loanId int,
+	payrollId int,
+	source data.LoanRepaymentSource,
+	amount int,
+	note string,
+	createdBy string,
+) (bool, error) {
+	const query = `
+		INSERT INTO loan_repayments (loan_id, payroll_id, source, amount, note, created_by)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6)
+		ON CONFLICT (loan_id, payroll_id) DO NOTHING
+	`
+	tag, err := s.db(ctx).Exec(ctx, query, loanId, payrollId, source, amount, note, createdBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) GetRepaymentsByLoanId(ctx context.Context, loanId int) ([]*data.LoanRepayment, error) {
+	const query = `
+		SELECT id, loan_id, COALESCE(payroll_id, 0), source, amount, note, created_at, created_by
+		FROM loan_repayments
+		WHERE loan_id = $1
+		ORDER BY id
+	`
+	rows, err := s.db(ctx).Query(ctx, query, loanId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.LoanRepayment, 0)
+	for rows.Next() {
+		var r data.LoanRepayment
+		err := rows.Scan(&r.Id, &r.LoanId, &r.PayrollId, &r.Source, &r.Amount, &r.Note, &r.CreatedAt, &r.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &r)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) DecreaseOutstanding(ctx context.Context, loanId int, amount int, updatedBy string) error {
+	const query = `
+		UPDATE loans
+		SET outstanding = outstanding - $2,
+		    status = CASE WHEN outstanding - $2 <= 0 THEN 'paid_off' ELSE status END,
+		    updated_by = $3,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, loanId, amount, updatedBy)
+	return err
+}
+
+func (s *Storage) GetOutstandingByUser(ctx context.Context, userId int) (map[int]int, error) {
+	const query = `
+		SELECT user_id, SUM(outstanding)
+		FROM loans
+		WHERE status = 'active'
+		  AND ($1 = 0 OR user_id = $1)
+		GROUP BY user_id
+	`
+	rows, err := s.db(ctx).Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]int)
+	for rows.Next() {
+		var id, outstanding int
+		if err := rows.Scan(&id, &outstanding); err != nil {
+			return nil, err
+		}
+		result[id] = outstanding
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,250 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/pdf"
+)
+
+// Employer is the withholding agent (pemotong pajak) printed on every tax form
+type Employer struct {
+	Name string
+	Npwp string
+}
+
+// build1721A1 calculates the yearly PPh 21 of one employee from the accumulated payroll.
+// Reimbursement is not an income, so it is not part of the gross.
+// seq is the running number of the bukti potong in the year.
+func build1721A1(year int, seq int, employer Employer, summary *data.YearlyPayrollSummary, profile *data.UserTaxProfile) *data.Form1721A1 {
+	form := &data.Form1721A1{
+		UserId:       summary.UserId,
+		Year:         year,
+		Number:       fmt.Sprintf("1.1-%02d.%02d-%07d", summary.LastMonth, year%100, seq),
+		MonthStart:   summary.FirstMonth,
+		MonthEnd:     summary.LastMonth,
+		EmployerName: employer.Name,
+		EmployerNpwp: employer.Npwp,
+		PTKPStatus:   common.PTKPTK0,
+	}
+	if profile != nil {
+		form.Fullname = profile.Fullname
+		form.Npwp = profile.Npwp
+		form.Nik = profile.Nik
+		form.PTKPStatus = profile.PTKPStatus
+	}
+
+	months := summary.LastMonth - summary.FirstMonth + 1
+
+	form.Salary = summary.BaseSalaryAmount
+	form.OtherAllowance = summary.OvertimeAmount
+	form.Bonus = summary.BonusAmount
+	form.Gross = form.Salary + form.OtherAllowance + form.Bonus
+
+	form.BiayaJabatan = common.BiayaJabatan(form.Gross, months)
+	form.PensionContribution = summary.BpjsAmount
+	form.TotalDeduction = form.BiayaJabatan + form.PensionContribution
+	form.Netto = form.Gross - form.TotalDeduction
+
+	form.PTKP = common.PTKPAmount(form.PTKPStatus)
+	form.PKP = common.RoundDownThousand(form.Netto - form.PTKP)
+	form.TaxDue = common.AnnualPPh21(form.PKP)
+	form.TaxWithheld = summary.TaxAmount
+
+	return form
+}
+
+// form1721A1CSVHeader follows the column order of the 1721-A1 import template of e-Bupot / Coretax
+var form1721A1CSVHeader = []string{
+	"Masa Pajak", "Tahun Pajak", "Pembetulan", "Nomor Bukti Potong",
+	"Masa Perolehan Awal", "Masa Perolehan Akhir",
+	"NPWP", "NIK", "Nama", "Status PTKP", "Kode Objek Pajak",
+	"Gaji", "Tunjangan Lainnya", "Bonus THR", "Penghasilan Bruto",
+	"Biaya Jabatan", "Iuran Pensiun", "Jumlah Pengurangan", "Penghasilan Neto",
+	"PTKP", "PKP", "PPh 21 Terutang", "PPh 21 Dipotong",
+	"NPWP Pemotong",
+}
+
+// kodeObjekPajakPegawaiTetap is the tax object code of salary for permanent employee
+const kodeObjekPajakPegawaiTetap = "21-100-01"
+
+func write1721A1CSV(w io.Writer, forms []*data.Form1721A1) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(form1721A1CSVHeader); err != nil {
+		return err
+	}
+
+	for _, f := range forms {
+		row := []string{
+			strconv.Itoa(f.MonthEnd),
+			strconv.Itoa(f.Year),
+			"0",
+			f.Number,
+			strconv.Itoa(f.MonthStart),
+			strconv.Itoa(f.MonthEnd),
+			f.Npwp,
+			f.Nik,
+			f.Fullname,
+			string(f.PTKPStatus),
+			kodeObjekPajakPegawaiTetap,
+			strconv.Itoa(f.Salary),
+			strconv.Itoa(f.OtherAllowance),
+			strconv.Itoa(f.Bonus),
+			strconv.Itoa(f.Gross),
+			strconv.Itoa(f.BiayaJabatan),
+			strconv.Itoa(f.PensionContribution),
+			strconv.Itoa(f.TotalDeduction),
+			strconv.Itoa(f.Netto),
+			strconv.Itoa(f.PTKP),
+			strconv.Itoa(f.PKP),
+			strconv.Itoa(f.TaxDue),
+			strconv.Itoa(f.TaxWithheld),
+			f.EmployerNpwp,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// render1721A1PDF prints the form into a one page PDF
+func render1721A1PDF(w io.Writer, f *data.Form1721A1) error {
+	doc := pdf.NewDocument()
+	doc.AddPage()
+
+	const left, valueX, amountX = 50, 230, 545
+	y := 60.0
+	line := func(label, value string) {
+		doc.Text(left, y, pdf.FontRegular, 10, label)
+		doc.Text(valueX, y, pdf.FontRegular, 10, ": "+value)
+		y += 16
+	}
+	amount := func(label string, value int) {
+		doc.Text(left, y, pdf.FontRegular, 10, label)
+		text := common.FormatRupiah(value)
+		// right aligned, helvetica digit is about 0.556 of the font size
+		doc.Text(amountX-float64(len(text))*5.56, y, pdf.FontRegular, 10, text)
+		y += 16
+	}
+	section := func(title string) {
+		y += 8
+		doc.Text(left, y, pdf.FontBold, 11, title)
+		doc.Line(left, amountX, y+4)
+		y += 20
+	}
+
+	doc.Text(left, y, pdf.FontBold, 14, "BUKTI PEMOTONGAN PAJAK PENGHASILAN PASAL 21")
+	y += 18
+	doc.Text(left, y, pdf.FontRegular, 10, "BAGI PEGAWAI TETAP ATAU PENERIMA PENSIUN (FORMULIR 1721-A1)")
+	y += 24
+
+	line("Nomor", f.Number)
+	line("Masa Perolehan", fmt.Sprintf("%02d - %02d / %d", f.MonthStart, f.MonthEnd, f.Year))
+	line("NPWP Pemotong", f.EmployerNpwp)
+	line("Nama Pemotong", f.EmployerName)
+
+	section("A. IDENTITAS PENERIMA PENGHASILAN")
+	line("NPWP", f.Npwp)
+	line("NIK", f.Nik)
+	line("Nama", f.Fullname)
+	line("Status PTKP", string(f.PTKPStatus))
+
+	section("B. RINCIAN PENGHASILAN DAN PENGHITUNGAN PPh PASAL 21")
+	amount("1. Gaji/Pensiun atau THT/JHT", f.Salary)
+	amount("3. Tunjangan Lainnya, Uang Lembur", f.OtherAllowance)
+	amount("7. Tantiem, Bonus, Gratifikasi, Jasa Produksi dan THR", f.Bonus)
+	amount("8. Jumlah Penghasilan Bruto (1 s.d. 7)", f.Gross)
+	amount("9. Biaya Jabatan", f.BiayaJabatan)
+	amount("10. Iuran Pensiun atau Iuran THT/JHT", f.PensionContribution)
+	amount("11. Jumlah Pengurangan (9 + 10)", f.TotalDeduction)
+	amount("12. Jumlah Penghasilan Neto (8 - 11)", f.Netto)
+	amount("15. Penghasilan Tidak Kena Pajak (PTKP)", f.PTKP)
+	amount("16. Penghasilan Kena Pajak Setahun", f.PKP)
+	amount("17. PPh Pasal 21 atas Penghasilan Kena Pajak", f.TaxDue)
+	amount("19. PPh Pasal 21 Terutang", f.TaxDue)
+	amount("20. PPh Pasal 21 yang Telah Dipotong", f.TaxWithheld)
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// buildBupotPPh21 turns the payroll items into the monthly withholding rows.
+// Employee without NPWP and NIK can not be reported, they are returned as error instead of row.
+func buildBupotPPh21(items []*data.PayrollItem, profiles map[int]*data.UserTaxProfile) ([]*data.BupotPPh21, []*data.TaxProfileError) {
+	rows := []*data.BupotPPh21{}
+	errs := []*data.TaxProfileError{}
+
+	for _, item := range items {
+		profile := profiles[item.UserId]
+		if profile == nil || (profile.Npwp == "" && profile.Nik == "") {
+			e := &data.TaxProfileError{UserId: item.UserId, Message: "NPWP atau NIK belum diisi"}
+			if profile != nil {
+				e.Fullname = profile.Fullname
+			}
+			errs = append(errs, e)
+			continue
+		}
+
+		gross := item.BaseSalaryAmount + item.OvertimeAmount + item.BonusAmount
+		rows = append(rows, &data.BupotPPh21{
+			UserId:        item.UserId,
+			Fullname:      profile.Fullname,
+			Npwp:          profile.Npwp,
+			Nik:           profile.Nik,
+			PTKPStatus:    profile.PTKPStatus,
+			TaxObjectCode: kodeObjekPajakPegawaiTetap,
+			Gross:         gross,
+			TERRate:       common.TERRate(common.TERCategoryOf(profile.PTKPStatus), gross),
+			TaxWithheld:   item.TaxAmount,
+		})
+	}
+
+	return rows, errs
+}
+
+// bupotPPh21CSVHeader follows the column order of the e-Bupot 21/26 (BPMP) / Coretax bulk upload template
+var bupotPPh21CSVHeader = []string{
+	"Masa Pajak", "Tahun Pajak", "Status", "NPWP", "NIK", "Nama", "Status PTKP",
+	"Kode Objek Pajak", "Penghasilan Bruto", "Tarif TER", "PPh 21 Dipotong",
+	"NPWP Pemotong", "Tanggal Pemotongan",
+}
+
+// writeBupotPPh21CSV writes the monthly rows, withheldAt is the payroll date printed as the withholding date
+func writeBupotPPh21CSV(w io.Writer, withheldAt time.Time, employerNpwp string, rows []*data.BupotPPh21) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(bupotPPh21CSVHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := []string{
+			strconv.Itoa(int(withheldAt.Month())),
+			strconv.Itoa(withheldAt.Year()),
+			"Normal",
+			row.Npwp,
+			row.Nik,
+			row.Fullname,
+			string(row.PTKPStatus),
+			row.TaxObjectCode,
+			strconv.Itoa(row.Gross),
+			fmt.Sprintf("%d.%02d", row.TERRate/100, row.TERRate%100),
+			strconv.Itoa(row.TaxWithheld),
+			employerNpwp,
+			withheldAt.Format("02/01/2006"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,187 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild1721A1(t *testing.T) {
+	t.Parallel()
+
+	employer := Employer{Name: "PT Contoh", Npwp: "0123456789012000"}
+
+	type testCase struct {
+		name     string
+		summary  *data.YearlyPayrollSummary
+		profile  *data.UserTaxProfile
+		expected *data.Form1721A1
+	}
+
+	scenarios := []testCase{
+		{
+			name: "full year single employee",
+			summary: &data.YearlyPayrollSummary{
+				UserId: 1, FirstMonth: 1, LastMonth: 12,
+				BaseSalaryAmount: 120000000, ReimbursementTotal: 2000000, TaxAmount: 3000000,
+			},
+			profile: &data.UserTaxProfile{UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0},
+			expected: &data.Form1721A1{
+				Number: "1.1-12.25-0000001", MonthStart: 1, MonthEnd: 12,
+				Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0,
+				Salary: 120000000, Gross: 120000000,
+				BiayaJabatan: 6000000, TotalDeduction: 6000000, Netto: 114000000,
+				PTKP: 54000000, PKP: 60000000, TaxDue: 3000000, TaxWithheld: 3000000,
+			},
+		},
+		{
+			name: "joined in july, married one dependant, below ptkp",
+			summary: &data.YearlyPayrollSummary{
+				UserId: 2, FirstMonth: 7, LastMonth: 12,
+				BaseSalaryAmount: 30000000, OvertimeAmount: 1500000,
+			},
+			profile: &data.UserTaxProfile{UserId: 2, Fullname: "Budi Santoso", Nik: "3175012345670001", PTKPStatus: common.PTKPK1},
+			expected: &data.Form1721A1{
+				Number: "1.1-12.25-0000001", MonthStart: 7, MonthEnd: 12,
+				Fullname: "Budi Santoso", Nik: "3175012345670001", PTKPStatus: common.PTKPK1,
+				Salary: 30000000, OtherAllowance: 1500000, Gross: 31500000,
+				BiayaJabatan: 1575000, TotalDeduction: 1575000, Netto: 29925000,
+				PTKP: 63000000, PKP: 0, TaxDue: 0,
+			},
+		},
+		{
+			name: "without tax profile use TK/0",
+			summary: &data.YearlyPayrollSummary{
+				UserId: 3, FirstMonth: 1, LastMonth: 3,
+				BaseSalaryAmount: 90000000, BpjsAmount: 900000,
+			},
+			profile: nil,
+			expected: &data.Form1721A1{
+				Number: "1.1-03.25-0000001", MonthStart: 1, MonthEnd: 3, PTKPStatus: common.PTKPTK0,
+				Salary: 90000000, Gross: 90000000,
+				BiayaJabatan: 1500000, PensionContribution: 900000, TotalDeduction: 2400000, Netto: 87600000,
+				PTKP: 54000000, PKP: 33600000, TaxDue: 1680000,
+			},
+		},
+		{
+			name: "with thr",
+			summary: &data.YearlyPayrollSummary{
+				UserId: 4, FirstMonth: 1, LastMonth: 12,
+				BaseSalaryAmount: 120000000, BonusAmount: 10000000, TaxAmount: 4200000,
+			},
+			profile: &data.UserTaxProfile{UserId: 4, Fullname: "Citra Dewi", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0},
+			expected: &data.Form1721A1{
+				Number: "1.1-12.25-0000001", MonthStart: 1, MonthEnd: 12,
+				Fullname: "Citra Dewi", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0,
+				Salary: 120000000, Bonus: 10000000, Gross: 130000000,
+				BiayaJabatan: 6000000, TotalDeduction: 6000000, Netto: 124000000,
+				PTKP: 54000000, PKP: 70000000, TaxDue: 4500000, TaxWithheld: 4200000,
+			},
+		},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			form := build1721A1(2025, 1, employer, sc.summary, sc.profile)
+
+			sc.expected.UserId = sc.summary.UserId
+			sc.expected.Year = 2025
+			sc.expected.EmployerName = employer.Name
+			sc.expected.EmployerNpwp = employer.Npwp
+			assert.Equal(t, sc.expected, form)
+		})
+	}
+}
+
+func TestWrite1721A1CSV(t *testing.T) {
+	t.Parallel()
+
+	form := build1721A1(2025, 2, Employer{Npwp: "0123456789012000"}, &data.YearlyPayrollSummary{
+		UserId: 1, FirstMonth: 1, LastMonth: 12, BaseSalaryAmount: 120000000,
+	}, &data.UserTaxProfile{UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0})
+
+	var buf bytes.Buffer
+	err := write1721A1CSV(&buf, []*data.Form1721A1{form})
+	assert.Nil(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, form1721A1CSVHeader, rows[0])
+	assert.Equal(t, "1.1-12.25-0000002", rows[1][3])
+	assert.Equal(t, kodeObjekPajakPegawaiTetap, rows[1][10])
+	assert.Equal(t, "3000000", rows[1][21])
+}
+
+func TestRender1721A1PDF(t *testing.T) {
+	t.Parallel()
+
+	form := build1721A1(2025, 1, Employer{Name: "PT (Contoh)"}, &data.YearlyPayrollSummary{
+		UserId: 1, FirstMonth: 1, LastMonth: 12, BaseSalaryAmount: 120000000,
+	}, nil)
+
+	var buf bytes.Buffer
+	err := render1721A1PDF(&buf, form)
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("%%EOF\n")))
+	assert.Contains(t, buf.String(), `PT \(Contoh\)`)
+	assert.Contains(t, buf.String(), "120.000.000")
+}
+
+func TestBuildBupotPPh21(t *testing.T) {
+	t.Parallel()
+
+	items := []*data.PayrollItem{
+		{UserId: 1, BaseSalaryAmount: 9500000, OvertimeAmount: 500000, ReimbursementTotal: 250000, TaxAmount: 150000},
+		{UserId: 2, BaseSalaryAmount: 5000000, TaxAmount: 0},
+		{UserId: 3, BaseSalaryAmount: 7000000, TaxAmount: 87500},
+	}
+	profiles := map[int]*data.UserTaxProfile{
+		1: {UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPK1},
+		2: {UserId: 2, Fullname: "Budi Santoso", PTKPStatus: common.PTKPTK0},
+	}
+
+	rows, errs := buildBupotPPh21(items, profiles)
+
+	assert.Equal(t, []*data.BupotPPh21{
+		{
+			UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPK1,
+			TaxObjectCode: "21-100-01", Gross: 10000000, TERRate: 150, TaxWithheld: 150000,
+		},
+	}, rows)
+	assert.Equal(t, []*data.TaxProfileError{
+		{UserId: 2, Fullname: "Budi Santoso", Message: "NPWP atau NIK belum diisi"},
+		{UserId: 3, Message: "NPWP atau NIK belum diisi"},
+	}, errs)
+}
+
+func TestWriteBupotPPh21CSV(t *testing.T) {
+	t.Parallel()
+
+	rows := []*data.BupotPPh21{
+		{
+			UserId: 1, Fullname: "Ayu Lestari", Nik: "3175012345670001", PTKPStatus: common.PTKPTK0,
+			TaxObjectCode: "21-100-01", Gross: 5600000, TERRate: 25, TaxWithheld: 14000,
+		},
+	}
+
+	var buf bytes.Buffer
+	err := writeBupotPPh21CSV(&buf, common.NewDate(2025, 3, 31), "0123456789012000", rows)
+	assert.Nil(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, bupotPPh21CSVHeader, records[0])
+	assert.Equal(t, []string{
+		"3", "2025", "Normal", "", "3175012345670001", "Ayu Lestari", "TK/0",
+		"21-100-01", "5600000", "0.25", "14000", "0123456789012000", "31/03/2025",
+	}, records[1])
+}
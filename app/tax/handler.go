@@ -0,0 +1,199 @@
+package tax
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/app/tax/lib"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+type generate1721A1Request struct {
+	Year int `json:"year"`
+}
+
+func (h *Handler) Generate1721A1(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req generate1721A1Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.Generate1721A1(r.Context(), &lib.Generate1721A1In{
+		Trace: trace,
+		Year:  req.Year,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+// List1721A1 returns every form in a year, format is `json` (default) or `csv` for e-Bupot / Coretax import
+func (h *Handler) List1721A1(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		response.Error(w, r, "Param 'year' harus angka")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		response.Error(w, r, "Param 'format' harus json atau csv")
+		return
+	}
+
+	out := h.service.List1721A1(r.Context(), &lib.List1721A1In{
+		Trace: trace,
+		Year:  year,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=1721a1-%d.csv", year))
+		if err := write1721A1CSV(w, out.Result); err != nil {
+			log.Error(trace).Err(err).Msg("List1721A1/ failed write csv")
+		}
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+// Self1721A1 returns the form of the user login, format is `json` (default) or `pdf`
+func (h *Handler) Self1721A1(w http.ResponseWriter, r *http.Request) {
+	h.get1721A1(w, r, 0)
+}
+
+// User1721A1 returns the form of another user (admin only), format is `json` (default) or `pdf`
+func (h *Handler) User1721A1(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.Error(w, r, "Param 'userId' harus angka")
+		return
+	}
+	h.get1721A1(w, r, userId)
+}
+
+func (h *Handler) get1721A1(w http.ResponseWriter, r *http.Request, userId int) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		response.Error(w, r, "Param 'year' harus angka")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "pdf" {
+		response.Error(w, r, "Param 'format' harus json atau pdf")
+		return
+	}
+
+	out := h.service.Get1721A1(r.Context(), &lib.Get1721A1In{
+		Trace:  trace,
+		Year:   year,
+		UserId: userId,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	if format == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=1721a1-%d-%d.pdf", out.Result.Year, out.Result.UserId))
+		if err := render1721A1PDF(w, out.Result); err != nil {
+			log.Error(trace).Err(err).Msg("Get1721A1/ failed write pdf")
+		}
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+// BupotPPh21 exports the monthly PPh 21 of a payroll, format is `json` (default) or `csv` for e-Bupot / Coretax import.
+// Employee with incomplete tax profile is returned as the validation error list.
+func (h *Handler) BupotPPh21(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	payrollId, err := strconv.Atoi(chi.URLParam(r, "payrollId"))
+	if err != nil {
+		response.Error(w, r, "Param 'payrollId' harus angka")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		response.Error(w, r, "Param 'format' harus json atau csv")
+		return
+	}
+
+	out := h.service.BupotPPh21(r.Context(), &lib.BupotPPh21In{
+		Trace:     trace,
+		PayrollId: payrollId,
+	})
+
+	if len(out.Errors) > 0 {
+		response.FailData(w, r, out.Err, out.Errors)
+		return
+	}
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=bupot-pph21-%s.csv", out.WithheldAt.Format("2006-01")))
+		if err := writeBupotPPh21CSV(w, out.WithheldAt, out.EmployerNpwp, out.Result); err != nil {
+			log.Error(trace).Err(err).Msg("BupotPPh21/ failed write csv")
+		}
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
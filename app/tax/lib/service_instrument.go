@@ -0,0 +1,76 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named tax.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="tax"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) Generate1721A1(ctx context.Context, in *Generate1721A1In) *Generate1721A1Out {
+	ctx, span := tracing.Start(ctx, "tax.Generate1721A1")
+	start := time.Now()
+	out := s.next.Generate1721A1(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("tax", "Generate1721A1", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) List1721A1(ctx context.Context, in *List1721A1In) *List1721A1Out {
+	ctx, span := tracing.Start(ctx, "tax.List1721A1")
+	start := time.Now()
+	out := s.next.List1721A1(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("tax", "List1721A1", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) Get1721A1(ctx context.Context, in *Get1721A1In) *Get1721A1Out {
+	ctx, span := tracing.Start(ctx, "tax.Get1721A1")
+	start := time.Now()
+	out := s.next.Get1721A1(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("tax", "Get1721A1", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) BupotPPh21(ctx context.Context, in *BupotPPh21In) *BupotPPh21Out {
+	ctx, span := tracing.Start(ctx, "tax.BupotPPh21")
+	start := time.Now()
+	out := s.next.BupotPPh21(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("tax", "BupotPPh21", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
@@ -0,0 +1,80 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// Generate1721A1 is the yearly batch job, it (re)generates the 1721-A1 form of every employee
+	// that received salary in the year
+	Generate1721A1(ctx context.Context, in *Generate1721A1In) *Generate1721A1Out
+	List1721A1(ctx context.Context, in *List1721A1In) *List1721A1Out
+	Get1721A1(ctx context.Context, in *Get1721A1In) *Get1721A1Out
+
+	// BupotPPh21 returns the monthly PPh 21 withholding of a payroll for the e-Bupot 21/26 bulk upload
+	BupotPPh21(ctx context.Context, in *BupotPPh21In) *BupotPPh21Out
+}
+
+type Generate1721A1In struct {
+	Trace *contextutil.Trace
+	Year  int
+}
+
+type Generate1721A1Out struct {
+	Success bool
+	apperror.Failure
+
+	Total int // number of form generated
+}
+
+type List1721A1In struct {
+	Trace *contextutil.Trace
+	Year  int
+}
+
+type List1721A1Out struct {
+	Success bool
+	apperror.Failure
+
+	Result []*data.Form1721A1
+}
+
+type Get1721A1In struct {
+	Trace *contextutil.Trace
+	Year  int
+
+	// UserId is the owner of the form, 0 means the user login.
+	// Only admin can get form of other user.
+	UserId int
+}
+
+type Get1721A1Out struct {
+	Success bool
+	apperror.Failure
+
+	Result *data.Form1721A1
+}
+
+type BupotPPh21In struct {
+	Trace     *contextutil.Trace
+	PayrollId int
+}
+
+type BupotPPh21Out struct {
+	Success bool
+	apperror.Failure
+
+	WithheldAt   time.Time // payroll period end, used as masa pajak and tanggal pemotongan
+	EmployerNpwp string
+	Result       []*data.BupotPPh21
+
+	// Errors is filled when some employee has incomplete tax profile, the export is refused
+	Errors []*data.TaxProfileError
+}
@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	// Upsert1721A1 inserts the form or replaces the existing form of the same user and year
+	Upsert1721A1(ctx context.Context, form *data.Form1721A1, updatedBy string) (int, error)
+
+	// Get1721A1ByYear returns every generated form in a year ordered by number
+	Get1721A1ByYear(ctx context.Context, year int) ([]*data.Form1721A1, error)
+
+	// Get1721A1ByUserAndYear returns nil if the form is not generated yet
+	Get1721A1ByUserAndYear(ctx context.Context, userId int, year int) (*data.Form1721A1, error)
+}
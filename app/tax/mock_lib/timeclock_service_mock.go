@@ -0,0 +1,280 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ariesmaulana/payroll/app/timeclock/lib (interfaces: ServiceInterface)
+//
+// Generated by this command:
+//
+//	mockgen -mock_names ServiceInterface=MockTimeclockService github.com/ariesmaulana/payroll/app/timeclock/lib ServiceInterface
+//
+
+// Package mock_lib is a generated GoMock package.
+package mock_lib
+
+import (
+	context "context"
+	reflect "reflect"
+
+	lib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTimeclockService is a mock of ServiceInterface interface.
+type MockTimeclockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTimeclockServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockTimeclockServiceMockRecorder is the mock recorder for MockTimeclockService.
+type MockTimeclockServiceMockRecorder struct {
+	mock *MockTimeclockService
+}
+
+// NewMockTimeclockService creates a new mock instance.
+func NewMockTimeclockService(ctrl *gomock.Controller) *MockTimeclockService {
+	mock := &MockTimeclockService{ctrl: ctrl}
+	mock.recorder = &MockTimeclockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTimeclockService) EXPECT() *MockTimeclockServiceMockRecorder {
+	return m.recorder
+}
+
+// AddAttendancePeriod mocks base method.
+func (m *MockTimeclockService) AddAttendancePeriod(ctx context.Context, in *lib.AddAttendancePeriodIn) *lib.AddAttendancePeriodOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAttendancePeriod", ctx, in)
+	ret0, _ := ret[0].(*lib.AddAttendancePeriodOut)
+	return ret0
+}
+
+// AddAttendancePeriod indicates an expected call of AddAttendancePeriod.
+func (mr *MockTimeclockServiceMockRecorder) AddAttendancePeriod(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAttendancePeriod", reflect.TypeOf((*MockTimeclockService)(nil).AddAttendancePeriod), ctx, in)
+}
+
+// AddOvertime mocks base method.
+func (m *MockTimeclockService) AddOvertime(ctx context.Context, in *lib.AddOvertimeIn) *lib.AddOvertimeOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddOvertime", ctx, in)
+	ret0, _ := ret[0].(*lib.AddOvertimeOut)
+	return ret0
+}
+
+// AddOvertime indicates an expected call of AddOvertime.
+func (mr *MockTimeclockServiceMockRecorder) AddOvertime(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOvertime", reflect.TypeOf((*MockTimeclockService)(nil).AddOvertime), ctx, in)
+}
+
+// AttendanceUserIds mocks base method.
+func (m *MockTimeclockService) AttendanceUserIds(ctx context.Context, in *lib.AttendanceUserIdsIn) *lib.AttendanceUserIdsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttendanceUserIds", ctx, in)
+	ret0, _ := ret[0].(*lib.AttendanceUserIdsOut)
+	return ret0
+}
+
+// AttendanceUserIds indicates an expected call of AttendanceUserIds.
+func (mr *MockTimeclockServiceMockRecorder) AttendanceUserIds(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttendanceUserIds", reflect.TypeOf((*MockTimeclockService)(nil).AttendanceUserIds), ctx, in)
+}
+
+// CheckoutAttendance mocks base method.
+func (m *MockTimeclockService) CheckoutAttendance(ctx context.Context, in *lib.CheckoutAttendanceIn) *lib.CheckoutAttendanceOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckoutAttendance", ctx, in)
+	ret0, _ := ret[0].(*lib.CheckoutAttendanceOut)
+	return ret0
+}
+
+// CheckoutAttendance indicates an expected call of CheckoutAttendance.
+func (mr *MockTimeclockServiceMockRecorder) CheckoutAttendance(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckoutAttendance", reflect.TypeOf((*MockTimeclockService)(nil).CheckoutAttendance), ctx, in)
+}
+
+// CloseOpenAttendances mocks base method.
+func (m *MockTimeclockService) CloseOpenAttendances(ctx context.Context, in *lib.CloseOpenAttendancesIn) *lib.CloseOpenAttendancesOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseOpenAttendances", ctx, in)
+	ret0, _ := ret[0].(*lib.CloseOpenAttendancesOut)
+	return ret0
+}
+
+// CloseOpenAttendances indicates an expected call of CloseOpenAttendances.
+func (mr *MockTimeclockServiceMockRecorder) CloseOpenAttendances(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseOpenAttendances", reflect.TypeOf((*MockTimeclockService)(nil).CloseOpenAttendances), ctx, in)
+}
+
+// CreatePayrollDraft mocks base method.
+func (m *MockTimeclockService) CreatePayrollDraft(ctx context.Context, in *lib.CreatePayrollDraftIn) *lib.CreatePayrollDraftOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayrollDraft", ctx, in)
+	ret0, _ := ret[0].(*lib.CreatePayrollDraftOut)
+	return ret0
+}
+
+// CreatePayrollDraft indicates an expected call of CreatePayrollDraft.
+func (mr *MockTimeclockServiceMockRecorder) CreatePayrollDraft(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayrollDraft", reflect.TypeOf((*MockTimeclockService)(nil).CreatePayrollDraft), ctx, in)
+}
+
+// GenerateAllPaySlips mocks base method.
+func (m *MockTimeclockService) GenerateAllPaySlips(ctx context.Context, in *lib.GenerateAllPaySlipsIn) *lib.GenerateAllPaySlipsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateAllPaySlips", ctx, in)
+	ret0, _ := ret[0].(*lib.GenerateAllPaySlipsOut)
+	return ret0
+}
+
+// GenerateAllPaySlips indicates an expected call of GenerateAllPaySlips.
+func (mr *MockTimeclockServiceMockRecorder) GenerateAllPaySlips(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAllPaySlips", reflect.TypeOf((*MockTimeclockService)(nil).GenerateAllPaySlips), ctx, in)
+}
+
+// GenerateAllTHRSlips mocks base method.
+func (m *MockTimeclockService) GenerateAllTHRSlips(ctx context.Context, in *lib.GenerateAllTHRSlipsIn) *lib.GenerateAllTHRSlipsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateAllTHRSlips", ctx, in)
+	ret0, _ := ret[0].(*lib.GenerateAllTHRSlipsOut)
+	return ret0
+}
+
+// GenerateAllTHRSlips indicates an expected call of GenerateAllTHRSlips.
+func (mr *MockTimeclockServiceMockRecorder) GenerateAllTHRSlips(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAllTHRSlips", reflect.TypeOf((*MockTimeclockService)(nil).GenerateAllTHRSlips), ctx, in)
+}
+
+// GenerateSelfPaySlip mocks base method.
+func (m *MockTimeclockService) GenerateSelfPaySlip(ctx context.Context, in *lib.GenerateSelfPaySlipIn) *lib.GenerateSelfPaySlipOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateSelfPaySlip", ctx, in)
+	ret0, _ := ret[0].(*lib.GenerateSelfPaySlipOut)
+	return ret0
+}
+
+// GenerateSelfPaySlip indicates an expected call of GenerateSelfPaySlip.
+func (mr *MockTimeclockServiceMockRecorder) GenerateSelfPaySlip(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateSelfPaySlip", reflect.TypeOf((*MockTimeclockService)(nil).GenerateSelfPaySlip), ctx, in)
+}
+
+// GenerateSelfTHRSlip mocks base method.
+func (m *MockTimeclockService) GenerateSelfTHRSlip(ctx context.Context, in *lib.GenerateSelfTHRSlipIn) *lib.GenerateSelfTHRSlipOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateSelfTHRSlip", ctx, in)
+	ret0, _ := ret[0].(*lib.GenerateSelfTHRSlipOut)
+	return ret0
+}
+
+// GenerateSelfTHRSlip indicates an expected call of GenerateSelfTHRSlip.
+func (mr *MockTimeclockServiceMockRecorder) GenerateSelfTHRSlip(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateSelfTHRSlip", reflect.TypeOf((*MockTimeclockService)(nil).GenerateSelfTHRSlip), ctx, in)
+}
+
+// GetPayrollDetail mocks base method.
+func (m *MockTimeclockService) GetPayrollDetail(ctx context.Context, in *lib.GetPayrollDetailIn) *lib.GetPayrollDetailOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayrollDetail", ctx, in)
+	ret0, _ := ret[0].(*lib.GetPayrollDetailOut)
+	return ret0
+}
+
+// GetPayrollDetail indicates an expected call of GetPayrollDetail.
+func (mr *MockTimeclockServiceMockRecorder) GetPayrollDetail(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayrollDetail", reflect.TypeOf((*MockTimeclockService)(nil).GetPayrollDetail), ctx, in)
+}
+
+// ListPayrollDrafts mocks base method.
+func (m *MockTimeclockService) ListPayrollDrafts(ctx context.Context, in *lib.ListPayrollDraftsIn) *lib.ListPayrollDraftsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPayrollDrafts", ctx, in)
+	ret0, _ := ret[0].(*lib.ListPayrollDraftsOut)
+	return ret0
+}
+
+// ListPayrollDrafts indicates an expected call of ListPayrollDrafts.
+func (mr *MockTimeclockServiceMockRecorder) ListPayrollDrafts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPayrollDrafts", reflect.TypeOf((*MockTimeclockService)(nil).ListPayrollDrafts), ctx, in)
+}
+
+// RunPayroll mocks base method.
+func (m *MockTimeclockService) RunPayroll(ctx context.Context, in *lib.RunPayrollIn) *lib.RunPayrollOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunPayroll", ctx, in)
+	ret0, _ := ret[0].(*lib.RunPayrollOut)
+	return ret0
+}
+
+// RunPayroll indicates an expected call of RunPayroll.
+func (mr *MockTimeclockServiceMockRecorder) RunPayroll(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPayroll", reflect.TypeOf((*MockTimeclockService)(nil).RunPayroll), ctx, in)
+}
+
+// RunTHR mocks base method.
+func (m *MockTimeclockService) RunTHR(ctx context.Context, in *lib.RunTHRIn) *lib.RunTHROut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunTHR", ctx, in)
+	ret0, _ := ret[0].(*lib.RunTHROut)
+	return ret0
+}
+
+// RunTHR indicates an expected call of RunTHR.
+func (mr *MockTimeclockServiceMockRecorder) RunTHR(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunTHR", reflect.TypeOf((*MockTimeclockService)(nil).RunTHR), ctx, in)
+}
+
+// SubmitAttendance mocks base method.
+func (m *MockTimeclockService) SubmitAttendance(ctx context.Context, in *lib.SubmitAttendanceIn) *lib.SubmitAttendanceOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitAttendance", ctx, in)
+	ret0, _ := ret[0].(*lib.SubmitAttendanceOut)
+	return ret0
+}
+
+// SubmitAttendance indicates an expected call of SubmitAttendance.
+func (mr *MockTimeclockServiceMockRecorder) SubmitAttendance(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitAttendance", reflect.TypeOf((*MockTimeclockService)(nil).SubmitAttendance), ctx, in)
+}
+
+// SubmitReimbursement mocks base method.
+func (m *MockTimeclockService) SubmitReimbursement(ctx context.Context, in *lib.SubmitReimbursementIn) *lib.SubmitReimbursementOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitReimbursement", ctx, in)
+	ret0, _ := ret[0].(*lib.SubmitReimbursementOut)
+	return ret0
+}
+
+// SubmitReimbursement indicates an expected call of SubmitReimbursement.
+func (mr *MockTimeclockServiceMockRecorder) SubmitReimbursement(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitReimbursement", reflect.TypeOf((*MockTimeclockService)(nil).SubmitReimbursement), ctx, in)
+}
+
+// YearlyPayrollSummary mocks base method.
+func (m *MockTimeclockService) YearlyPayrollSummary(ctx context.Context, in *lib.YearlyPayrollSummaryIn) *lib.YearlyPayrollSummaryOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "YearlyPayrollSummary", ctx, in)
+	ret0, _ := ret[0].(*lib.YearlyPayrollSummaryOut)
+	return ret0
+}
+
+// YearlyPayrollSummary indicates an expected call of YearlyPayrollSummary.
+func (mr *MockTimeclockServiceMockRecorder) YearlyPayrollSummary(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "YearlyPayrollSummary", reflect.TypeOf((*MockTimeclockService)(nil).YearlyPayrollSummary), ctx, in)
+}
@@ -0,0 +1,30 @@
+package tax
+
+import (
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, handler *Handler) {
+	r.Route("/tax", func(r chi.Router) {
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			// (1721-A1 annual withholding certificate)
+			r.Post("/1721a1/generate", handler.Generate1721A1)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(data.ScopeTaxRead))
+				r.Get("/1721a1", handler.List1721A1)
+				r.Get("/1721a1/self", handler.Self1721A1)
+				r.Get("/1721a1/{userId}", handler.User1721A1)
+
+				// (monthly PPh 21 e-Bupot 21/26)
+				r.Get("/pph21/{payrollId}", handler.BupotPPh21)
+			})
+		})
+	})
+}
@@ -0,0 +1,239 @@
+package tax
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/app/tax/lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage          lib.StorageInterface
+	timeclockService timeclockLib.ServiceInterface
+	userService      userLib.ServiceInterface
+	employer         Employer
+}
+
+func NewService(storage lib.StorageInterface, timeclockService timeclockLib.ServiceInterface, userService userLib.ServiceInterface, employer Employer) *Service {
+	return &Service{
+		storage:          storage,
+		timeclockService: timeclockService,
+		userService:      userService,
+		employer:         employer,
+	}
+}
+
+func (s *Service) Generate1721A1(ctx context.Context, in *lib.Generate1721A1In) *lib.Generate1721A1Out {
+	resp := &lib.Generate1721A1Out{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("Generate1721A1/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("Generate1721A1/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	summary := s.timeclockService.YearlyPayrollSummary(ctx, &timeclockLib.YearlyPayrollSummaryIn{
+		Trace: in.Trace,
+		Year:  in.Year,
+	})
+	if !summary.Success {
+		log.Warn(in.Trace).Str("reason", summary.Message).Msg("Generate1721A1/ failed get yearly payroll")
+		resp.Failure = summary.Failure
+		return resp
+	}
+
+	if len(summary.Result) == 0 {
+		log.Warn(in.Trace).Int("year", in.Year).Msg("Generate1721A1/ no payroll in year")
+		resp.Fail(apperror.CodePayrollNotFound, "Belum ada payroll di tahun ini")
+		return resp
+	}
+
+	profiles := s.userService.UserTaxProfiles(ctx, &userLib.UserTaxProfilesIn{
+		Trace: in.Trace,
+	})
+	if !profiles.Success {
+		log.Warn(in.Trace).Msg("Generate1721A1/ failed get tax profiles")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Generate1721A1/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// summary is ordered by user id, so the number stays the same when the batch is re-run
+	for i, sum := range summary.Result {
+		form := build1721A1(in.Year, i+1, s.employer, sum, profiles.Result[sum.UserId])
+		_, err := s.storage.Upsert1721A1(ctx, form, user.Actor())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msgf("Generate1721A1/ upsert form user_id=%d failed", sum.UserId)
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Generate1721A1/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Total = len(summary.Result)
+	return resp
+}
+
+func (s *Service) List1721A1(ctx context.Context, in *lib.List1721A1In) *lib.List1721A1Out {
+	resp := &lib.List1721A1Out{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("List1721A1/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("List1721A1/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("List1721A1/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	forms, err := s.storage.Get1721A1ByYear(ctx, in.Year)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("List1721A1/ get forms failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = forms
+	return resp
+}
+
+func (s *Service) Get1721A1(ctx context.Context, in *lib.Get1721A1In) *lib.Get1721A1Out {
+	resp := &lib.Get1721A1Out{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("Get1721A1/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	userId := in.UserId
+	if userId == 0 {
+		userId = user.Id
+	}
+
+	if userId != user.Id && user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("Get1721A1/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("Get1721A1/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Get1721A1/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	form, err := s.storage.Get1721A1ByUserAndYear(ctx, userId, in.Year)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Get1721A1/ get form failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if form == nil {
+		log.Warn(in.Trace).Msg("Get1721A1/ form not found")
+		resp.Fail(apperror.CodeNotFound, "Bukti potong 1721-A1 belum tersedia")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = form
+	return resp
+}
+
+func (s *Service) BupotPPh21(ctx context.Context, in *lib.BupotPPh21In) *lib.BupotPPh21Out {
+	resp := &lib.BupotPPh21Out{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("BupotPPh21/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	payroll := s.timeclockService.GetPayrollDetail(ctx, &timeclockLib.GetPayrollDetailIn{
+		Trace:     in.Trace,
+		PayrollId: in.PayrollId,
+	})
+	if !payroll.Success {
+		log.Warn(in.Trace).Str("reason", payroll.Message).Msg("BupotPPh21/ failed get payroll")
+		resp.Failure = payroll.Failure
+		return resp
+	}
+
+	profiles := s.userService.UserTaxProfiles(ctx, &userLib.UserTaxProfilesIn{
+		Trace: in.Trace,
+	})
+	if !profiles.Success {
+		log.Warn(in.Trace).Msg("BupotPPh21/ failed get tax profiles")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	rows, errs := buildBupotPPh21(payroll.Items, profiles.Result)
+	if len(errs) > 0 {
+		log.Warn(in.Trace).Int("total", len(errs)).Msg("BupotPPh21/ incomplete tax profile")
+		resp.Fail(apperror.CodeTaxDataIncomplete, "Data pajak karyawan belum lengkap")
+		resp.Errors = errs
+		return resp
+	}
+
+	resp.Success = true
+	resp.WithheldAt = payroll.Payroll.PeriodEnd
+	resp.EmployerNpwp = s.employer.Npwp
+	resp.Result = rows
+	return resp
+}
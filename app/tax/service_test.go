@@ -0,0 +1,261 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/app/tax/lib"
+	mocks "github.com/ariesmaulana/payroll/app/tax/mock_lib"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServiceGenerate1721A1(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeclockMock := mocks.NewMockTimeclockService(ctrl)
+	userMock := mocks.NewMockUserService(ctrl)
+	service := NewService(storage, timeclockMock, userMock, Employer{Name: "PT Contoh", Npwp: "0123456789012000"})
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	otherEmployeeCtx := test.UserContext(con.Context, 2, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "generate-1721a1-test"}
+
+	summaries := []*data.YearlyPayrollSummary{
+		{UserId: 1, FirstMonth: 1, LastMonth: 12, BaseSalaryAmount: 120000000},
+		{UserId: 2, FirstMonth: 7, LastMonth: 12, BaseSalaryAmount: 30000000},
+	}
+	profiles := map[int]*data.UserTaxProfile{
+		1: {UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0},
+	}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.Generate1721A1In
+		mock    func()
+		success bool
+		errMsg  string
+		total   int
+	}{
+		{
+			name: "success",
+			ctx:  adminCtx,
+			in:   &lib.Generate1721A1In{Trace: trace, Year: 2025},
+			mock: func() {
+				timeclockMock.EXPECT().
+					YearlyPayrollSummary(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.YearlyPayrollSummaryIn{})).
+					Return(&timeclockLib.YearlyPayrollSummaryOut{Success: true, Result: summaries}).
+					Times(1)
+				userMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true, Result: profiles}).
+					Times(1)
+			},
+			success: true,
+			total:   2,
+		},
+		{
+			name: "success re-run batch",
+			ctx:  adminCtx,
+			in:   &lib.Generate1721A1In{Trace: trace, Year: 2025},
+			mock: func() {
+				timeclockMock.EXPECT().
+					YearlyPayrollSummary(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.YearlyPayrollSummaryIn{})).
+					Return(&timeclockLib.YearlyPayrollSummaryOut{Success: true, Result: summaries}).
+					Times(1)
+				userMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true, Result: profiles}).
+					Times(1)
+			},
+			success: true,
+			total:   2,
+		},
+		{
+			name: "fail no payroll",
+			ctx:  adminCtx,
+			in:   &lib.Generate1721A1In{Trace: trace, Year: 2024},
+			mock: func() {
+				timeclockMock.EXPECT().
+					YearlyPayrollSummary(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.YearlyPayrollSummaryIn{})).
+					Return(&timeclockLib.YearlyPayrollSummaryOut{Success: true}).
+					Times(1)
+			},
+			success: false,
+			errMsg:  "Belum ada payroll di tahun ini",
+		},
+		{
+			name:    "fail invalid year",
+			ctx:     adminCtx,
+			in:      &lib.Generate1721A1In{Trace: trace},
+			mock:    func() {},
+			success: false,
+			errMsg:  "Tahun tidak valid",
+		},
+		{
+			name:    "fail not admin",
+			ctx:     employeeCtx,
+			in:      &lib.Generate1721A1In{Trace: trace, Year: 2025},
+			mock:    func() {},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.mock()
+			resp := service.Generate1721A1(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			assert.Equal(t, sc.total, resp.Total)
+		})
+	}
+
+	list := service.List1721A1(adminCtx, &lib.List1721A1In{Trace: trace, Year: 2025})
+	assert.True(t, list.Success)
+	assert.Len(t, list.Result, 2)
+
+	self := service.Get1721A1(employeeCtx, &lib.Get1721A1In{Trace: trace, Year: 2025})
+	assert.True(t, self.Success)
+	assert.Equal(t, "1.1-12.25-0000001", self.Result.Number)
+	assert.Equal(t, 3000000, self.Result.TaxDue)
+
+	other := service.Get1721A1(otherEmployeeCtx, &lib.Get1721A1In{Trace: trace, Year: 2025, UserId: 1})
+	assert.False(t, other.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", other.Message)
+
+	byAdmin := service.Get1721A1(adminCtx, &lib.Get1721A1In{Trace: trace, Year: 2025, UserId: 2})
+	assert.True(t, byAdmin.Success)
+	assert.Equal(t, common.PTKPTK0, byAdmin.Result.PTKPStatus)
+
+	notFound := service.Get1721A1(employeeCtx, &lib.Get1721A1In{Trace: trace, Year: 2024})
+	assert.False(t, notFound.Success)
+	assert.Equal(t, "Bukti potong 1721-A1 belum tersedia", notFound.Message)
+}
+
+func TestServiceBupotPPh21(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeclockMock := mocks.NewMockTimeclockService(ctrl)
+	userMock := mocks.NewMockUserService(ctrl)
+	service := NewService(storage, timeclockMock, userMock, Employer{Name: "PT Contoh", Npwp: "0123456789012000"})
+
+	adminCtx := test.UserContext(con.Context, 999, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 1, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "bupot-pph21-test"}
+
+	payroll := &timeclockLib.GetPayrollDetailOut{
+		Success: true,
+		Payroll: &data.Payroll{Id: 1, PeriodStart: common.NewDate(2025, 3, 1), PeriodEnd: common.NewDate(2025, 3, 31)},
+		Items: []*data.PayrollItem{
+			{UserId: 1, BaseSalaryAmount: 10000000, TaxAmount: 200000},
+			{UserId: 2, BaseSalaryAmount: 6000000, TaxAmount: 45000},
+		},
+	}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.BupotPPh21In
+		mock    func()
+		success bool
+		errMsg  string
+		total   int
+		errors  int
+	}{
+		{
+			name: "success",
+			ctx:  adminCtx,
+			in:   &lib.BupotPPh21In{Trace: trace, PayrollId: 1},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(payroll).
+					Times(1)
+				userMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true, Result: map[int]*data.UserTaxProfile{
+						1: {UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0},
+						2: {UserId: 2, Fullname: "Budi Santoso", Nik: "3175012345670001", PTKPStatus: common.PTKPK0},
+					}}).
+					Times(1)
+			},
+			success: true,
+			total:   2,
+		},
+		{
+			name: "fail missing tax id",
+			ctx:  adminCtx,
+			in:   &lib.BupotPPh21In{Trace: trace, PayrollId: 1},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(payroll).
+					Times(1)
+				userMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true, Result: map[int]*data.UserTaxProfile{
+						1: {UserId: 1, Fullname: "Ayu Lestari", Npwp: "012345678901000", PTKPStatus: common.PTKPTK0},
+					}}).
+					Times(1)
+			},
+			success: false,
+			errMsg:  "Data pajak karyawan belum lengkap",
+			errors:  1,
+		},
+		{
+			name: "fail payroll not found",
+			ctx:  adminCtx,
+			in:   &lib.BupotPPh21In{Trace: trace, PayrollId: 99},
+			mock: func() {
+				timeclockMock.EXPECT().
+					GetPayrollDetail(gomock.Any(), gomock.AssignableToTypeOf(&timeclockLib.GetPayrollDetailIn{})).
+					Return(&timeclockLib.GetPayrollDetailOut{Failure: apperror.Failed(apperror.CodePayrollNotFound, "Payroll tidak ditemukan")}).
+					Times(1)
+			},
+			success: false,
+			errMsg:  "Payroll tidak ditemukan",
+		},
+		{
+			name:    "fail not admin",
+			ctx:     employeeCtx,
+			in:      &lib.BupotPPh21In{Trace: trace, PayrollId: 1},
+			mock:    func() {},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.mock()
+			resp := service.BupotPPh21(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			assert.Len(t, resp.Result, sc.total)
+			assert.Len(t, resp.Errors, sc.errors)
+		})
+	}
+}
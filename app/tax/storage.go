@@ -0,0 +1,159 @@
+package tax
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ariesmaulana/payroll/app/tax/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) Upsert1721A1(ctx context.Context, f *data.Form1721A1, updatedBy string) (int, error) {
+	const query = `
+		INSERT INTO tax_forms_1721a1 (
+			user_id, year, number, month_start, month_end,
+			employer_name, employer_npwp, fullname, npwp, nik, ptkp_status,
+			salary, other_allowance, bonus, gross,
+			biaya_jabatan, pension_contribution, total_deduction, netto,
+			ptkp, pkp, tax_due, tax_withheld,
+			created_by, updated_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+		        $16, $17, $18, $19, $20, $21, $22, $23, $24, $24)
+		ON CONFLICT (user_id, year) DO UPDATE
+		SET number = EXCLUDED.number,
+		    month_start = EXCLUDED.month_start,
+		    month_end = EXCLUDED.month_end,
+		    employer_name = EXCLUDED.employer_name,
+		    employer_npwp = EXCLUDED.employer_npwp,
+		    fullname = EXCLUDED.fullname,
+		    npwp = EXCLUDED.npwp,
+		    nik = EXCLUDED.nik,
+		    ptkp_status = EXCLUDED.ptkp_status,
+		    salary = EXCLUDED.salary,
+		    other_allowance = EXCLUDED.other_allowance,
+		    bonus = EXCLUDED.bonus,
+		    gross = EXCLUDED.gross,
+		    biaya_jabatan = EXCLUDED.biaya_jabatan,
+		    pension_contribution = EXCLUDED.pension_contribution,
+		    total_deduction = EXCLUDED.total_deduction,
+		    netto = EXCLUDED.netto,
+		    ptkp = EXCLUDED.ptkp,
+		    pkp = EXCLUDED.pkp,
+		    tax_due = EXCLUDED.tax_due,
+		    tax_withheld = EXCLUDED.tax_withheld,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`
+
+	var id int
+	err := s.db(ctx).QueryRow(ctx, query,
+		f.UserId, f.Year, f.Number, f.MonthStart, f.MonthEnd,
+		f.EmployerName, f.EmployerNpwp, f.Fullname, f.Npwp, f.Nik, f.PTKPStatus,
+		f.Salary, f.OtherAllowance, f.Bonus, f.Gross,
+		f.BiayaJabatan, f.PensionContribution, f.TotalDeduction, f.Netto,
+		f.PTKP, f.PKP, f.TaxDue, f.TaxWithheld,
+		updatedBy,
+	).Scan(&id)
+	return id, err
+}
+
+const form1721A1Columns = `
+	id, user_id, year, number, month_start, month_end,
+	employer_name, employer_npwp, fullname, npwp, nik, ptkp_status,
+	salary, other_allowance, bonus, gross,
+	biaya_jabatan, pension_contribution, total_deduction, netto,
+	ptkp, pkp, tax_due, tax_withheld,
+	created_at, updated_at, created_by, updated_by
+`
+
+// scanForm1721A1 scans one row selected with form1721A1Columns
+func scanForm1721A1(row pgx.Row) (*data.Form1721A1, error) {
+	var f data.Form1721A1
+	err := row.Scan(
+		&f.Id, &f.UserId, &f.Year, &f.Number, &f.MonthStart, &f.MonthEnd,
+		&f.EmployerName, &f.EmployerNpwp, &f.Fullname, &f.Npwp, &f.Nik, &f.PTKPStatus,
+		&f.Salary, &f.OtherAllowance, &f.Bonus, &f.Gross,
+		&f.BiayaJabatan, &f.PensionContribution, &f.TotalDeduction, &f.Netto,
+		&f.PTKP, &f.PKP, &f.TaxDue, &f.TaxWithheld,
+		&f.CreatedAt, &f.UpdatedAt, &f.CreatedBy, &f.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *Storage) Get1721A1ByYear(ctx context.Context, year int) ([]*data.Form1721A1, error) {
+	query := `SELECT ` + form1721A1Columns + ` FROM tax_forms_1721a1 WHERE year = $1 ORDER BY number`
+
+	rows, err := s.db(ctx).Query(ctx, query, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.Form1721A1, 0)
+	for rows.Next() {
+		f, err := scanForm1721A1(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Storage) Get1721A1ByUserAndYear(ctx context.Context, userId int, year int) (*data.Form1721A1, error) {
+	query := `SELECT ` + form1721A1Columns + ` FROM tax_forms_1721a1 WHERE user_id = $1 AND year = $2`
+
+	f, err := scanForm1721A1(s.db(ctx).QueryRow(ctx, query, userId, year))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -1,6 +1,13 @@
 package timeclock
 
-import "time"
+import (
+	"sort"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/timeclock/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+)
 
 func countWorkdays(start, end time.Time) int {
 	start = start.Truncate(24 * time.Hour)
@@ -87,3 +94,213 @@ func calculateOvertimeSalary(
 
 	return result
 }
+
+// calculateWithholdingTax returns the PPh 21 to withhold from gross (salary + overtime + bonus) paid in a month.
+// TER applies to the total gross of the month, so when another payroll has been paid in the same month
+// (paid, can be nil) the tax is recalculated on the combined gross minus what was already withheld.
+// Employee without tax profile is withheld as TK/0.
+func calculateWithholdingTax(profile *data.UserTaxProfile, paid *data.UserMonthlyTax, gross int) int {
+	status := common.PTKPTK0
+	if profile != nil {
+		status = profile.PTKPStatus
+	}
+	if paid == nil {
+		return common.MonthlyPPh21(status, gross)
+	}
+
+	tax := common.MonthlyPPh21(status, paid.Gross+gross) - paid.Tax
+	if tax < 0 {
+		return 0
+	}
+	return tax
+}
+
+// monthsOfService returns the number of full months worked from joinDate until at
+func monthsOfService(joinDate, at time.Time) int {
+	if at.Before(joinDate) {
+		return 0
+	}
+	months := (at.Year()-joinDate.Year())*12 + int(at.Month()) - int(joinDate.Month())
+	if at.Day() < joinDate.Day() {
+		months--
+	}
+	return months
+}
+
+// calculateTHR returns the THR based on Permenaker 6/2016, counted until the religious holiday:
+// less than 1 month of service is not eligible, 12 months or more get one month wage,
+// and in between is prorated months / 12 of the monthly wage.
+func calculateTHR(baseSalary int, joinDate, holiday time.Time) (amount int, months int) {
+	months = monthsOfService(joinDate, holiday)
+	if months < 1 {
+		return 0, months
+	}
+	if months >= 12 {
+		return baseSalary, months
+	}
+	return baseSalary * months / 12, months
+}
+
+func thrPayslip(item *data.PayrollItem) *data.UserTHRPayslip {
+	return &data.UserTHRPayslip{
+		UserID:    item.UserId,
+		PayrollId: item.PayrollId,
+		THRAmount: item.BonusAmount,
+		TaxAmount: item.TaxAmount,
+		NetAmount: item.BonusAmount - item.TaxAmount,
+	}
+}
+
+// payrollLine is what a payroll run pays to one employee
+type payrollLine struct {
+	userId          int
+	attendanceCount int
+	overtimeHours   int
+	baseSalary      int
+	overtime        int
+	bonus           int
+	reimbursement   int
+	tax             int
+	bpjs            int
+	loanDeduction   int
+}
+
+// taxableGross is the income subject to PPh 21, reimbursement is not an income
+func (l *payrollLine) taxableGross() int {
+	return l.baseSalary + l.overtime + l.bonus
+}
+
+func (l *payrollLine) totalSalary() int {
+	return l.baseSalary + l.overtime + l.bonus + l.reimbursement
+}
+
+// netPay is the take home pay before loan deduction
+func (l *payrollLine) netPay() int {
+	return l.totalSalary() - l.tax - l.bpjs
+}
+
+// item is the payroll item stored for the line
+func (l *payrollLine) item(payrollId int) *data.PayrollItem {
+	return &data.PayrollItem{
+		PayrollId:          payrollId,
+		UserId:             l.userId,
+		AttendanceCount:    l.attendanceCount,
+		OvertimeHours:      l.overtimeHours,
+		BaseSalaryAmount:   l.baseSalary,
+		OvertimeAmount:     l.overtime,
+		BonusAmount:        l.bonus,
+		ReimbursementTotal: l.reimbursement,
+		TaxAmount:          l.tax,
+		BpjsAmount:         l.bpjs,
+		LoanDeduction:      l.loanDeduction,
+		TotalSalary:        l.totalSalary(),
+	}
+}
+
+// deductsLoan tells whether a payroll type deducts the loan installment,
+// off-cycle bonus and correction are paid in full
+func deductsLoan(payrollType data.PayrollType) bool {
+	return payrollType == data.PayrollRegular || payrollType == data.PayrollFinalSettlement
+}
+
+// withholdsBpjs tells whether a payroll type pays the monthly wage BPJS is contributed on,
+// off-cycle bonus and correction are not a wage period
+func withholdsBpjs(payrollType data.PayrollType) bool {
+	return payrollType == data.PayrollRegular || payrollType == data.PayrollFinalSettlement
+}
+
+// validateRunPayroll checks the input required by each payroll type, it returns the error message or empty string
+func validateRunPayroll(payrollType data.PayrollType, in *lib.RunPayrollIn) string {
+	selected := make(map[int]bool, len(in.UserIds))
+	for _, userId := range in.UserIds {
+		if selected[userId] {
+			return "Karyawan tidak boleh dipilih lebih dari sekali"
+		}
+		selected[userId] = true
+	}
+
+	switch payrollType {
+	case data.PayrollRegular:
+		return ""
+	case data.PayrollFinalSettlement:
+		if len(in.UserIds) == 0 {
+			return "Karyawan wajib dipilih untuk final settlement"
+		}
+	case data.PayrollBonus, data.PayrollCorrection:
+		if len(in.Amounts) == 0 {
+			return "Nominal per karyawan wajib diisi"
+		}
+		for _, amount := range in.Amounts {
+			if payrollType == data.PayrollBonus && amount <= 0 {
+				return "Nominal bonus harus lebih dari 0"
+			}
+			if amount == 0 {
+				return "Nominal koreksi tidak boleh 0"
+			}
+		}
+	default:
+		return "Tipe payroll tidak valid"
+	}
+
+	if in.Note == "" {
+		return "Catatan wajib diisi untuk payroll off-cycle"
+	}
+	return ""
+}
+
+// amountPayrollLines turns the amount per employee of a bonus or correction run into payroll lines.
+// Correction adjusts the base salary, bonus is an irregular income.
+func amountPayrollLines(payrollType data.PayrollType, amounts map[int]int) []*payrollLine {
+	userIds := make([]int, 0, len(amounts))
+	for userId := range amounts {
+		userIds = append(userIds, userId)
+	}
+	sort.Ints(userIds)
+
+	lines := make([]*payrollLine, 0, len(userIds))
+	for _, userId := range userIds {
+		line := &payrollLine{userId: userId}
+		if payrollType == data.PayrollCorrection {
+			line.baseSalary = amounts[userId]
+		} else {
+			line.bonus = amounts[userId]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// groupPayslipRuns sums the runs per user, runs must be ordered by user
+func groupPayslipRuns(runs []*data.PayslipRun) []*data.UserPayslip {
+	var payslips []*data.UserPayslip
+	var current *data.UserPayslip
+	for _, run := range runs {
+		if current == nil || current.UserID != run.UserId {
+			current = &data.UserPayslip{UserID: run.UserId}
+			payslips = append(payslips, current)
+		}
+		current.TotalSalary += run.TotalSalary
+		current.AttendanceCount += run.AttendanceCount
+		current.OvertimeHours += run.OvertimeHours
+		current.ReimbursementSum += run.ReimbursementTotal
+		current.Runs = append(current.Runs, run)
+	}
+	return payslips
+}
+
+// defaultDraftLimit is the number of drafts listed when no limit is given, a year of monthly drafts
+const defaultDraftLimit = 12
+
+// newPayrollDraft sums the dry run items of a regular payroll into its draft
+func newPayrollDraft(periodStart, periodEnd time.Time, items []*data.PayrollItem) *data.PayrollDraft {
+	draft := &data.PayrollDraft{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Employees:   len(items),
+		Items:       items,
+	}
+	for _, item := range items {
+		draft.TotalSalary += item.TotalSalary
+	}
+	return draft
+}
@@ -0,0 +1,162 @@
+package timeclock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/timeclock/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateTHR(t *testing.T) {
+	t.Parallel()
+
+	holiday := common.NewDate(2025, 3, 31)
+
+	scenarios := []struct {
+		name     string
+		joinDate time.Time
+		amount   int
+		months   int
+	}{
+		{name: "more than a year", joinDate: common.NewDate(2020, 6, 15), amount: 12000000, months: 57},
+		{name: "exactly a year", joinDate: common.NewDate(2024, 3, 31), amount: 12000000, months: 12},
+		{name: "one day short of a year", joinDate: common.NewDate(2024, 4, 1), amount: 11000000, months: 11},
+		{name: "six months", joinDate: common.NewDate(2024, 9, 30), amount: 6000000, months: 6},
+		{name: "one month", joinDate: common.NewDate(2025, 2, 28), amount: 1000000, months: 1},
+		{name: "less than a month", joinDate: common.NewDate(2025, 3, 1), amount: 0, months: 0},
+		{name: "join after holiday", joinDate: common.NewDate(2025, 4, 1), amount: 0, months: 0},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			amount, months := calculateTHR(12000000, sc.joinDate, holiday)
+			assert.Equal(t, sc.amount, amount)
+			assert.Equal(t, sc.months, months)
+		})
+	}
+}
+
+func TestCalculateWithholdingTax(t *testing.T) {
+	t.Parallel()
+
+	profile := &data.UserTaxProfile{UserId: 1, PTKPStatus: common.PTKPTK0}
+
+	// first payroll of the month: 10.000.000 x 2%
+	assert.Equal(t, 200000, calculateWithholdingTax(profile, nil, 10000000))
+
+	// thr paid after the salary in the same month: 20.000.000 x 9% - 200.000 already withheld
+	paid := &data.UserMonthlyTax{UserId: 1, Gross: 10000000, Tax: 200000}
+	assert.Equal(t, 1600000, calculateWithholdingTax(profile, paid, 10000000))
+
+	// no profile withheld as TK/0
+	assert.Equal(t, 200000, calculateWithholdingTax(nil, nil, 10000000))
+
+	// married with one dependant use TER B: 10.000.000 x 1.5%
+	assert.Equal(t, 150000, calculateWithholdingTax(&data.UserTaxProfile{PTKPStatus: common.PTKPK1}, nil, 10000000))
+}
+
+func TestValidateRunPayroll(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name        string
+		payrollType data.PayrollType
+		in          *lib.RunPayrollIn
+		message     string
+	}{
+		{name: "regular", payrollType: data.PayrollRegular, in: &lib.RunPayrollIn{}, message: ""},
+		{name: "bonus", payrollType: data.PayrollBonus, in: &lib.RunPayrollIn{Amounts: map[int]int{1: 100}, Note: "bonus"}, message: ""},
+		{name: "negative correction", payrollType: data.PayrollCorrection, in: &lib.RunPayrollIn{Amounts: map[int]int{1: -100}, Note: "koreksi"}, message: ""},
+		{name: "final settlement", payrollType: data.PayrollFinalSettlement, in: &lib.RunPayrollIn{UserIds: []int{1}, Note: "resign"}, message: ""},
+		{name: "duplicate employee", payrollType: data.PayrollRegular, in: &lib.RunPayrollIn{UserIds: []int{1, 2, 1}}, message: "Karyawan tidak boleh dipilih lebih dari sekali"},
+		{name: "final settlement without employee", payrollType: data.PayrollFinalSettlement, in: &lib.RunPayrollIn{Note: "resign"}, message: "Karyawan wajib dipilih untuk final settlement"},
+		{name: "bonus without amount", payrollType: data.PayrollBonus, in: &lib.RunPayrollIn{Note: "bonus"}, message: "Nominal per karyawan wajib diisi"},
+		{name: "negative bonus", payrollType: data.PayrollBonus, in: &lib.RunPayrollIn{Amounts: map[int]int{1: -100}, Note: "bonus"}, message: "Nominal bonus harus lebih dari 0"},
+		{name: "zero correction", payrollType: data.PayrollCorrection, in: &lib.RunPayrollIn{Amounts: map[int]int{1: 0}, Note: "koreksi"}, message: "Nominal koreksi tidak boleh 0"},
+		{name: "without note", payrollType: data.PayrollBonus, in: &lib.RunPayrollIn{Amounts: map[int]int{1: 100}}, message: "Catatan wajib diisi untuk payroll off-cycle"},
+		{name: "thr is not an off-cycle type", payrollType: data.PayrollTHR, in: &lib.RunPayrollIn{Note: "thr"}, message: "Tipe payroll tidak valid"},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.message, validateRunPayroll(sc.payrollType, sc.in))
+		})
+	}
+}
+
+func TestAmountPayrollLines(t *testing.T) {
+	t.Parallel()
+
+	lines := amountPayrollLines(data.PayrollBonus, map[int]int{3: 300, 1: 100})
+	assert.Len(t, lines, 2)
+	assert.Equal(t, 1, lines[0].userId)
+	assert.Equal(t, 100, lines[0].bonus)
+	assert.Equal(t, 0, lines[0].baseSalary)
+	assert.Equal(t, 3, lines[1].userId)
+	assert.Equal(t, 300, lines[1].totalSalary())
+
+	lines = amountPayrollLines(data.PayrollCorrection, map[int]int{2: -50})
+	assert.Len(t, lines, 1)
+	assert.Equal(t, -50, lines[0].baseSalary)
+	assert.Equal(t, 0, lines[0].bonus)
+	assert.Equal(t, -50, lines[0].totalSalary())
+}
+
+func TestGroupPayslipRuns(t *testing.T) {
+	t.Parallel()
+
+	payslips := groupPayslipRuns([]*data.PayslipRun{
+		{PayrollId: 1, UserId: 1, Type: data.PayrollRegular, AttendanceCount: 20, OvertimeHours: 2, ReimbursementTotal: 100, TotalSalary: 1000},
+		{PayrollId: 2, UserId: 1, Type: data.PayrollBonus, TotalSalary: 500},
+		{PayrollId: 1, UserId: 2, Type: data.PayrollRegular, AttendanceCount: 18, TotalSalary: 900},
+	})
+
+	assert.Len(t, payslips, 2)
+	assert.Equal(t, 1, payslips[0].UserID)
+	assert.Equal(t, 1500, payslips[0].TotalSalary)
+	assert.Equal(t, 20, payslips[0].AttendanceCount)
+	assert.Equal(t, 100, payslips[0].ReimbursementSum)
+	assert.Len(t, payslips[0].Runs, 2)
+	assert.Equal(t, 2, payslips[1].UserID)
+	assert.Equal(t, 900, payslips[1].TotalSalary)
+	assert.Len(t, payslips[1].Runs, 1)
+}
+
+func TestPayrollLineItem(t *testing.T) {
+	t.Parallel()
+
+	line := &payrollLine{userId: 3, attendanceCount: 20, baseSalary: 1000, overtime: 100, reimbursement: 50, tax: 30, bpjs: 30, loanDeduction: 200}
+	item := line.item(9)
+	assert.Equal(t, 9, item.PayrollId)
+	assert.Equal(t, 3, item.UserId)
+	assert.Equal(t, 1150, item.TotalSalary)
+	assert.Equal(t, 30, item.TaxAmount)
+	assert.Equal(t, 30, item.BpjsAmount)
+	assert.Equal(t, 200, item.LoanDeduction)
+	assert.Equal(t, 1090, line.netPay())
+}
+
+func TestNewPayrollDraft(t *testing.T) {
+	start := common.NewDate(2025, 1, 26)
+	end := common.NewDate(2025, 2, 25)
+	items := []*data.PayrollItem{
+		{UserId: 1, TotalSalary: 5000000},
+		{UserId: 2, TotalSalary: 7500000},
+	}
+
+	draft := newPayrollDraft(start, end, items)
+	assert.Equal(t, start, draft.PeriodStart)
+	assert.Equal(t, end, draft.PeriodEnd)
+	assert.Equal(t, 2, draft.Employees)
+	assert.Equal(t, 12500000, draft.TotalSalary)
+	assert.Nil(t, draft.PayrollId)
+
+	draft = newPayrollDraft(start, end, nil)
+	assert.Equal(t, 0, draft.Employees)
+	assert.Equal(t, 0, draft.TotalSalary)
+}
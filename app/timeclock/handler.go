@@ -8,6 +8,7 @@ import (
 
 	"github.com/ariesmaulana/payroll/app/timeclock/lib"
 	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/ariesmaulana/payroll/internal/response"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 	log "github.com/ariesmaulana/payroll/lib/logger"
@@ -30,19 +31,19 @@ func (h *Handler) AddAttendancePeriod(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
 		log.Warn(trace).Msg("Trace not found in context")
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	var req addAttendancePeriodRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, r, "Invalid request body")
 		return
 	}
 
 	period, err := time.Parse("2006-01-02", req.CheckInDate)
 	if err != nil {
-		http.Error(w, "Invalid period format, must be YYYY-MM-DD", http.StatusBadRequest)
+		response.Error(w, r, "Invalid period format, must be YYYY-MM-DD")
 		return
 	}
 
@@ -53,7 +54,7 @@ func (h *Handler) AddAttendancePeriod(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -64,7 +65,7 @@ func (h *Handler) SubmitAttendance(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
 		log.Warn(trace).Msg("Trace not found in context")
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
@@ -74,7 +75,7 @@ func (h *Handler) SubmitAttendance(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -82,34 +83,52 @@ func (h *Handler) SubmitAttendance(w http.ResponseWriter, r *http.Request) {
 }
 
 type addOvertimeRequest struct {
-	OTDate time.Time `json:"ot_date"`
-	Hours  int       `json:"hours"`
-	Reason string    `json:"reason"`
+	OTDate string `json:"ot_date"` // format: YYYY-MM-DD, empty is today
+	Hours  int    `json:"hours"`
+	Reason string `json:"reason"`
 }
 
 func (h *Handler) AddOvertime(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
 		log.Warn(trace).Msg("Trace not found in context")
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	var req addOvertimeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, r, "Invalid request body")
 		return
 	}
 
+	// today keeps the time for the after working hours check, a past day is submitted at its end
+	period := common.NewDateTimeNow()
+	if req.OTDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.OTDate, common.JakartaTZ)
+		if err != nil {
+			response.Error(w, r, "Format tanggal lembur tidak valid")
+			return
+		}
+		today := common.NewDateToday()
+		if date.After(today) {
+			response.Error(w, r, "Tanggal lembur tidak boleh setelah hari ini")
+			return
+		}
+		if date.Before(today) {
+			period = date.Add(24*time.Hour - time.Second)
+		}
+	}
+
 	out := h.service.AddOvertime(r.Context(), &lib.AddOvertimeIn{
 		Trace:  trace,
 		Hours:  req.Hours,
 		Reason: req.Reason,
-		Period: common.NewDateTimeNow(),
+		Period: period,
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -119,7 +138,7 @@ func (h *Handler) AddOvertime(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CheckoutAttendance(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
@@ -129,7 +148,7 @@ func (h *Handler) CheckoutAttendance(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -146,19 +165,19 @@ func (h *Handler) SubmitReimbursement(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
 		log.Warn(trace).Msg("Trace not found in context")
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	var req submitReimbursementRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, r, "Invalid request body")
 		return
 	}
 
 	period, err := time.Parse("2006-01-02", req.Period)
 	if err != nil {
-		http.Error(w, "Invalid period format, must be YYYY-MM-DD", http.StatusBadRequest)
+		response.Error(w, r, "Invalid period format, must be YYYY-MM-DD")
 		return
 	}
 
@@ -170,7 +189,7 @@ func (h *Handler) SubmitReimbursement(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -180,40 +199,61 @@ func (h *Handler) SubmitReimbursement(w http.ResponseWriter, r *http.Request) {
 type runPayrollRequest struct {
 	Start string `json:"start"` // format: YYYY-MM-DD
 	End   string `json:"end"`   // format: YYYY-MM-DD
+
+	Type    string         `json:"type"`     // regular (default), bonus, correction, final_settlement
+	UserIds []int          `json:"user_ids"` // empty means every employee
+	Amounts map[string]int `json:"amounts"`  // key is user id, for bonus and correction run
+	Note    string         `json:"note"`
+	DryRun  bool           `json:"dry_run"` // preview, the payroll is not stored
 }
 
 func (h *Handler) RunPayroll(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	var req runPayrollRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, r, "Invalid request body")
 		return
 	}
 
 	start, err := time.Parse("2006-01-02", req.Start)
 	if err != nil {
-		http.Error(w, "Invalid start date format", http.StatusBadRequest)
+		response.Error(w, r, "Invalid start date format")
 		return
 	}
 	end, err := time.Parse("2006-01-02", req.End)
 	if err != nil {
-		http.Error(w, "Invalid end date format", http.StatusBadRequest)
+		response.Error(w, r, "Invalid end date format")
 		return
 	}
 
+	amounts := make(map[int]int, len(req.Amounts))
+	for key, amount := range req.Amounts {
+		userId, err := strconv.Atoi(key)
+		if err != nil {
+			response.Error(w, r, "Invalid user id in amounts")
+			return
+		}
+		amounts[userId] = amount
+	}
+
 	out := h.service.RunPayroll(r.Context(), &lib.RunPayrollIn{
 		Trace:       trace,
+		Type:        data.PayrollType(req.Type),
 		PeriodStart: start,
 		PeriodEnd:   end,
+		UserIds:     req.UserIds,
+		Amounts:     amounts,
+		Note:        req.Note,
+		DryRun:      req.DryRun,
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -223,26 +263,26 @@ func (h *Handler) RunPayroll(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GenerateSelfPaySlip(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	monthStr := r.URL.Query().Get("month")
 	yearStr := r.URL.Query().Get("year")
 	if monthStr == "" || yearStr == "" {
-		http.Error(w, "Query param 'month' dan 'year' wajib diisi", http.StatusBadRequest)
+		response.Error(w, r, "Query param 'month' dan 'year' wajib diisi")
 		return
 	}
 
 	month, err := strconv.Atoi(monthStr)
 	if err != nil {
-		http.Error(w, "Param 'month' harus angka", http.StatusBadRequest)
+		response.Error(w, r, "Param 'month' harus angka")
 		return
 	}
 
 	year, err := strconv.Atoi(yearStr)
 	if err != nil {
-		http.Error(w, "Param 'year' harus angka", http.StatusBadRequest)
+		response.Error(w, r, "Param 'year' harus angka")
 		return
 	}
 
@@ -253,7 +293,7 @@ func (h *Handler) GenerateSelfPaySlip(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -263,26 +303,26 @@ func (h *Handler) GenerateSelfPaySlip(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GenerateAllPaySlips(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	monthStr := r.URL.Query().Get("month")
 	yearStr := r.URL.Query().Get("year")
 	if monthStr == "" || yearStr == "" {
-		http.Error(w, "Query param 'month' dan 'year' wajib diisi", http.StatusBadRequest)
+		response.Error(w, r, "Query param 'month' dan 'year' wajib diisi")
 		return
 	}
 
 	month, err := strconv.Atoi(monthStr)
 	if err != nil {
-		http.Error(w, "Param 'month' harus angka", http.StatusBadRequest)
+		response.Error(w, r, "Param 'month' harus angka")
 		return
 	}
 
 	year, err := strconv.Atoi(yearStr)
 	if err != nil {
-		http.Error(w, "Param 'year' harus angka", http.StatusBadRequest)
+		response.Error(w, r, "Param 'year' harus angka")
 		return
 	}
 
@@ -293,7 +333,137 @@ func (h *Handler) GenerateAllPaySlips(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if !out.Success {
-		http.Error(w, out.Message, http.StatusBadRequest)
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+func (h *Handler) ListPayrollDrafts(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			response.Error(w, r, "Param 'limit' harus angka")
+			return
+		}
+	}
+
+	out := h.service.ListPayrollDrafts(r.Context(), &lib.ListPayrollDraftsIn{
+		Trace: trace,
+		Limit: limit,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+type runTHRRequest struct {
+	PayDate  string            `json:"pay_date"` // format: YYYY-MM-DD
+	Holidays map[string]string `json:"holidays"` // key is religion, value is holiday date YYYY-MM-DD
+}
+
+func (h *Handler) RunTHR(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req runTHRRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	payDate, err := time.Parse("2006-01-02", req.PayDate)
+	if err != nil {
+		response.Error(w, r, "Invalid pay date format")
+		return
+	}
+
+	holidays := make(map[data.Religion]time.Time)
+	for religion, date := range req.Holidays {
+		holiday, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			response.Error(w, r, "Invalid holiday date format")
+			return
+		}
+		holidays[data.Religion(religion)] = holiday
+	}
+
+	out := h.service.RunTHR(r.Context(), &lib.RunTHRIn{
+		Trace:    trace,
+		PayDate:  payDate,
+		Holidays: holidays,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+func (h *Handler) GenerateSelfTHRSlip(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		response.Error(w, r, "Param 'year' harus angka")
+		return
+	}
+
+	out := h.service.GenerateSelfTHRSlip(r.Context(), &lib.GenerateSelfTHRSlipIn{
+		Trace: trace,
+		Year:  year,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out)
+}
+
+func (h *Handler) GenerateAllTHRSlips(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		response.Error(w, r, "Param 'year' harus angka")
+		return
+	}
+
+	out := h.service.GenerateAllTHRSlips(r.Context(), &lib.GenerateAllTHRSlipsIn{
+		Trace: trace,
+		Year:  year,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
 		return
 	}
 
@@ -0,0 +1,245 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named timeclock.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="timeclock"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) AddAttendancePeriod(ctx context.Context, in *AddAttendancePeriodIn) *AddAttendancePeriodOut {
+	ctx, span := tracing.Start(ctx, "timeclock.AddAttendancePeriod")
+	start := time.Now()
+	out := s.next.AddAttendancePeriod(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "AddAttendancePeriod", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SubmitAttendance(ctx context.Context, in *SubmitAttendanceIn) *SubmitAttendanceOut {
+	ctx, span := tracing.Start(ctx, "timeclock.SubmitAttendance")
+	start := time.Now()
+	out := s.next.SubmitAttendance(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "SubmitAttendance", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) AddOvertime(ctx context.Context, in *AddOvertimeIn) *AddOvertimeOut {
+	ctx, span := tracing.Start(ctx, "timeclock.AddOvertime")
+	start := time.Now()
+	out := s.next.AddOvertime(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "AddOvertime", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CheckoutAttendance(ctx context.Context, in *CheckoutAttendanceIn) *CheckoutAttendanceOut {
+	ctx, span := tracing.Start(ctx, "timeclock.CheckoutAttendance")
+	start := time.Now()
+	out := s.next.CheckoutAttendance(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "CheckoutAttendance", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CloseOpenAttendances(ctx context.Context, in *CloseOpenAttendancesIn) *CloseOpenAttendancesOut {
+	ctx, span := tracing.Start(ctx, "timeclock.CloseOpenAttendances")
+	start := time.Now()
+	out := s.next.CloseOpenAttendances(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "CloseOpenAttendances", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) AttendanceUserIds(ctx context.Context, in *AttendanceUserIdsIn) *AttendanceUserIdsOut {
+	ctx, span := tracing.Start(ctx, "timeclock.AttendanceUserIds")
+	start := time.Now()
+	out := s.next.AttendanceUserIds(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "AttendanceUserIds", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SubmitReimbursement(ctx context.Context, in *SubmitReimbursementIn) *SubmitReimbursementOut {
+	ctx, span := tracing.Start(ctx, "timeclock.SubmitReimbursement")
+	start := time.Now()
+	out := s.next.SubmitReimbursement(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "SubmitReimbursement", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RunPayroll(ctx context.Context, in *RunPayrollIn) *RunPayrollOut {
+	ctx, span := tracing.Start(ctx, "timeclock.RunPayroll")
+	start := time.Now()
+	out := s.next.RunPayroll(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "RunPayroll", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CreatePayrollDraft(ctx context.Context, in *CreatePayrollDraftIn) *CreatePayrollDraftOut {
+	ctx, span := tracing.Start(ctx, "timeclock.CreatePayrollDraft")
+	start := time.Now()
+	out := s.next.CreatePayrollDraft(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "CreatePayrollDraft", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListPayrollDrafts(ctx context.Context, in *ListPayrollDraftsIn) *ListPayrollDraftsOut {
+	ctx, span := tracing.Start(ctx, "timeclock.ListPayrollDrafts")
+	start := time.Now()
+	out := s.next.ListPayrollDrafts(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "ListPayrollDrafts", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GenerateSelfPaySlip(ctx context.Context, in *GenerateSelfPaySlipIn) *GenerateSelfPaySlipOut {
+	ctx, span := tracing.Start(ctx, "timeclock.GenerateSelfPaySlip")
+	start := time.Now()
+	out := s.next.GenerateSelfPaySlip(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "GenerateSelfPaySlip", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GenerateAllPaySlips(ctx context.Context, in *GenerateAllPaySlipsIn) *GenerateAllPaySlipsOut {
+	ctx, span := tracing.Start(ctx, "timeclock.GenerateAllPaySlips")
+	start := time.Now()
+	out := s.next.GenerateAllPaySlips(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "GenerateAllPaySlips", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GetPayrollDetail(ctx context.Context, in *GetPayrollDetailIn) *GetPayrollDetailOut {
+	ctx, span := tracing.Start(ctx, "timeclock.GetPayrollDetail")
+	start := time.Now()
+	out := s.next.GetPayrollDetail(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "GetPayrollDetail", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) YearlyPayrollSummary(ctx context.Context, in *YearlyPayrollSummaryIn) *YearlyPayrollSummaryOut {
+	ctx, span := tracing.Start(ctx, "timeclock.YearlyPayrollSummary")
+	start := time.Now()
+	out := s.next.YearlyPayrollSummary(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "YearlyPayrollSummary", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RunTHR(ctx context.Context, in *RunTHRIn) *RunTHROut {
+	ctx, span := tracing.Start(ctx, "timeclock.RunTHR")
+	start := time.Now()
+	out := s.next.RunTHR(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "RunTHR", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GenerateSelfTHRSlip(ctx context.Context, in *GenerateSelfTHRSlipIn) *GenerateSelfTHRSlipOut {
+	ctx, span := tracing.Start(ctx, "timeclock.GenerateSelfTHRSlip")
+	start := time.Now()
+	out := s.next.GenerateSelfTHRSlip(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "GenerateSelfTHRSlip", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) GenerateAllTHRSlips(ctx context.Context, in *GenerateAllTHRSlipsIn) *GenerateAllTHRSlipsOut {
+	ctx, span := tracing.Start(ctx, "timeclock.GenerateAllTHRSlips")
+	start := time.Now()
+	out := s.next.GenerateAllTHRSlips(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("timeclock", "GenerateAllTHRSlips", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
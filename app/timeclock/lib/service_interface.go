@@ -1,10 +1,13 @@
 package lib
 
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
 import (
 	"context"
 	"time"
 
 	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 )
 
@@ -14,12 +17,37 @@ type ServiceInterface interface {
 	AddOvertime(ctx context.Context, in *AddOvertimeIn) *AddOvertimeOut
 	CheckoutAttendance(ctx context.Context, in *CheckoutAttendanceIn) *CheckoutAttendanceOut
 
+	// CloseOpenAttendances checks out every attendance until the date that has no checkout at the shift end (admin only)
+	CloseOpenAttendances(ctx context.Context, in *CloseOpenAttendancesIn) *CloseOpenAttendancesOut
+
+	// AttendanceUserIds returns the users who checked in on the date, for internal use
+	AttendanceUserIds(ctx context.Context, in *AttendanceUserIdsIn) *AttendanceUserIdsOut
+
 	SubmitReimbursement(ctx context.Context, in *SubmitReimbursementIn) *SubmitReimbursementOut
 
+	// RunPayroll calculates and stores a payroll (admin only), with DryRun it only returns the calculation
 	RunPayroll(ctx context.Context, in *RunPayrollIn) *RunPayrollOut
 
+	// CreatePayrollDraft stores the regular payroll of the period calculated without paying it, for review (admin only).
+	// A period has one draft, creating it again returns the existing one.
+	CreatePayrollDraft(ctx context.Context, in *CreatePayrollDraftIn) *CreatePayrollDraftOut
+
+	// ListPayrollDrafts returns the latest drafts (admin only)
+	ListPayrollDrafts(ctx context.Context, in *ListPayrollDraftsIn) *ListPayrollDraftsOut
+
 	GenerateSelfPaySlip(ctx context.Context, in *GenerateSelfPaySlipIn) *GenerateSelfPaySlipOut
 	GenerateAllPaySlips(ctx context.Context, in *GenerateAllPaySlipsIn) *GenerateAllPaySlipsOut
+
+	// GetPayrollDetail returns a payroll batch together with every payroll item in it (admin only)
+	GetPayrollDetail(ctx context.Context, in *GetPayrollDetailIn) *GetPayrollDetailOut
+
+	// YearlyPayrollSummary returns the accumulated payroll per user in a year (admin only)
+	YearlyPayrollSummary(ctx context.Context, in *YearlyPayrollSummaryIn) *YearlyPayrollSummaryOut
+
+	// RunTHR pays the THR of every employee whose religion holiday is given, as a separate payroll (admin only)
+	RunTHR(ctx context.Context, in *RunTHRIn) *RunTHROut
+	GenerateSelfTHRSlip(ctx context.Context, in *GenerateSelfTHRSlipIn) *GenerateSelfTHRSlipOut
+	GenerateAllTHRSlips(ctx context.Context, in *GenerateAllTHRSlipsIn) *GenerateAllTHRSlipsOut
 }
 
 type AddAttendancePeriodIn struct {
@@ -30,7 +58,7 @@ type AddAttendancePeriodIn struct {
 
 type AddAttendancePeriodOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 }
 
 type SubmitAttendanceIn struct {
@@ -40,7 +68,7 @@ type SubmitAttendanceIn struct {
 
 type SubmitAttendanceOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 }
 
 type AddOvertimeIn struct {
@@ -52,7 +80,7 @@ type AddOvertimeIn struct {
 
 type AddOvertimeOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 
 	// Id this is id overtime, we need return this for testing purpose
 	Id int
@@ -65,7 +93,34 @@ type CheckoutAttendanceIn struct {
 
 type CheckoutAttendanceOut struct {
 	Success bool
-	Message string
+	apperror.Failure
+}
+
+type CloseOpenAttendancesIn struct {
+	Trace *contextutil.Trace
+	Date  time.Time // attendances until this date (inclusive) are closed
+
+	// ShiftEnd is the checkout time of day, only the clock of it is used
+	ShiftEnd time.Time
+}
+
+type CloseOpenAttendancesOut struct {
+	Success bool
+	apperror.Failure
+
+	Closed []*data.Attendance
+}
+
+type AttendanceUserIdsIn struct {
+	Trace *contextutil.Trace
+	Date  time.Time
+}
+
+type AttendanceUserIdsOut struct {
+	Success bool
+	apperror.Failure
+
+	Result map[int]bool
 }
 
 type SubmitReimbursementIn struct {
@@ -77,7 +132,7 @@ type SubmitReimbursementIn struct {
 
 type SubmitReimbursementOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 
 	// Id this is id reimbursment, we need return this for testing purpose
 	Id int
@@ -85,14 +140,60 @@ type SubmitReimbursementOut struct {
 
 type RunPayrollIn struct {
 	Trace       *contextutil.Trace
+	Type        data.PayrollType // empty means regular
 	PeriodStart time.Time
 	PeriodEnd   time.Time
+
+	// UserIds limits the run to some employees, empty means every employee.
+	// Required for final settlement.
+	UserIds []int
+
+	// Amounts is the bonus (bonus run) or the adjustment (correction run) per employee, key is userId
+	Amounts map[int]int
+
+	// Note is the reason of an off-cycle run, required for every type except regular
+	Note string
+
+	// DryRun calculates the payroll without storing it, a stored payroll is final
+	DryRun bool
 }
 
 type RunPayrollOut struct {
-	Success   bool
-	Message   string
+	Success bool
+	apperror.Failure
 	PayrollId int // for testing purpose
+
+	// Skipped is the employee already paid by a regular or final settlement payroll in the period
+	Skipped []int
+
+	// Items is what the run pays to each employee, PayrollId of the items is 0 on DryRun
+	Items []*data.PayrollItem
+}
+
+type CreatePayrollDraftIn struct {
+	Trace       *contextutil.Trace
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+type CreatePayrollDraftOut struct {
+	Success bool
+	apperror.Failure
+
+	Draft   *data.PayrollDraft
+	Created bool // false when the period already had a draft, Draft is then nil
+}
+
+type ListPayrollDraftsIn struct {
+	Trace *contextutil.Trace
+	Limit int // 0 means the default
+}
+
+type ListPayrollDraftsOut struct {
+	Success bool
+	apperror.Failure
+
+	Result []*data.PayrollDraft
 }
 
 type GenerateSelfPaySlipIn struct {
@@ -103,9 +204,11 @@ type GenerateSelfPaySlipIn struct {
 
 type GenerateSelfPaySlipOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 
-	TotalSalary       int
+	TotalSalary       int                // gross of every run in the month
+	Runs              []*data.PayslipRun // every payroll run that paid the user in the month
+	LoanOutstanding   int                // outstanding of active loans (kasbon) after every deduction so far
 	ListReimbursement []*data.Reimbursement
 	ListOvertimes     []*data.Overtime
 	ListAttendAnce    []*data.Attendance
@@ -118,8 +221,75 @@ type GenerateAllPaySlipsIn struct {
 }
 
 type GenerateAllPaySlipsOut struct {
-	Success          bool
-	Message          string
+	Success bool
+	apperror.Failure
 	TotalSalaryAll   int
 	ListUserPayslips []*data.UserPayslip
 }
+
+type GetPayrollDetailIn struct {
+	Trace     *contextutil.Trace
+	PayrollId int
+}
+
+type GetPayrollDetailOut struct {
+	Success bool
+	apperror.Failure
+
+	Payroll *data.Payroll
+	Items   []*data.PayrollItem
+}
+
+type YearlyPayrollSummaryIn struct {
+	Trace *contextutil.Trace
+	Year  int
+}
+
+type YearlyPayrollSummaryOut struct {
+	Success bool
+	apperror.Failure
+
+	Result []*data.YearlyPayrollSummary
+}
+
+type RunTHRIn struct {
+	Trace   *contextutil.Trace
+	PayDate time.Time
+
+	// Holidays is the holiday date of each religion paid in this run, eg: Idul Fitri for islam.
+	// Employee with other religion is not paid in this run.
+	Holidays map[data.Religion]time.Time
+}
+
+type RunTHROut struct {
+	Success bool
+	apperror.Failure
+	PayrollId int
+
+	Total           int   // number of employee paid
+	MissingReligion []int // user id without religion, they can not be paid until the religion is set
+}
+
+type GenerateSelfTHRSlipIn struct {
+	Trace *contextutil.Trace
+	Year  int
+}
+
+type GenerateSelfTHRSlipOut struct {
+	Success bool
+	apperror.Failure
+
+	Payslip *data.UserTHRPayslip
+}
+
+type GenerateAllTHRSlipsIn struct {
+	Trace *contextutil.Trace
+	Year  int
+}
+
+type GenerateAllTHRSlipsOut struct {
+	Success bool
+	apperror.Failure
+	TotalTHRAll      int
+	ListUserPayslips []*data.UserTHRPayslip
+}
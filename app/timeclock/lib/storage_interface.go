@@ -15,6 +15,11 @@ type StorageInterface interface {
 	InsertAttendanceCheckin(ctx context.Context, userId int, period time.Time, checkin time.Time, createdBy string) (int, error)
 	UpdateAttendanceCheckout(ctx context.Context, userId int, period time.Time, checkout time.Time, updatedBy string) error
 
+	// CloseOpenAttendances sets the checkout of every attendance until the date (inclusive) without checkout
+	// to shiftEnd ("15:04:05"), or to the checkin when it is after the shift end, and flags them auto closed.
+	// It returns the closed attendances.
+	CloseOpenAttendances(ctx context.Context, until time.Time, shiftEnd string, updatedBy string) ([]*data.Attendance, error)
+
 	GetDetailAttendance(ctx context.Context, id int) (*data.Attendance, error)
 	GetAllAttendanceByPeriod(ctx context.Context, startDate, endDate time.Time) ([]*data.Attendance, error)
 	GetDetailAttendanceByUserAndPeriod(ctx context.Context, userId int, period time.Time) (*data.Attendance, error)
@@ -46,7 +51,7 @@ type StorageInterface interface {
 	//GetReimbursementsByUserAndPeriod Get list of reimbursement entries for a user in the given period range
 	GetReimbursementsByUserAndPeriod(ctx context.Context, userId int, start, end time.Time) ([]*data.Reimbursement, error)
 
-	// InsertPayroll inserts a new payroll record for a specific period.
+	// InsertPayroll inserts a new payroll record of a payroll type for a specific period.
 	//
 	// totalAttendance: total number of attendance records within the period.
 	// For example, if 10 users attended 5 days each, the total is 50.
@@ -60,20 +65,66 @@ type StorageInterface interface {
 	// totalSalary: final calculated total salary payout for all employees in this period.
 	//
 	// This function returns the generated payroll ID or an error if insert fails.
-	InsertPayroll(ctx context.Context, periodStart time.Time, periodEnd time.Time, totalAttendance int, totalOvertime int,
-		totalReimbursement int, totalSalary int, createdBy string) (int, error)
-	IsPayrollAlreadyRun(ctx context.Context, date time.Time) (bool, error)
+	InsertPayroll(ctx context.Context, payrollType data.PayrollType, periodStart time.Time, periodEnd time.Time, totalAttendance int, totalOvertime int,
+		totalReimbursement int, totalSalary int, note string, createdBy string) (int, error)
+
+	// IsPayrollAlreadyRun checks if the user has been paid by a regular or final settlement payroll covering date,
+	// attendance, overtime and reimbursement of that date can not be changed anymore
+	IsPayrollAlreadyRun(ctx context.Context, userId int, date time.Time) (bool, error)
+
+	// LockPayrollPeriod holds, until the transaction ends, the lock of every month the period touches,
+	// so two runs settling overlapping periods do not pay the same employee twice
+	LockPayrollPeriod(ctx context.Context, startDate time.Time, endDate time.Time) error
+
+	// GetSettledUserIdsByPeriod returns the users paid by a regular or final settlement payroll overlapping the period
+	GetSettledUserIdsByPeriod(ctx context.Context, startDate time.Time, endDate time.Time) (map[int]bool, error)
 
-	// GetPayrollByPeriod retrieves payroll metadata for a given period (start to end).
+	// GetPayrollsByPeriod retrieves every payroll run (any type) overlapping the period (start to end).
+	GetPayrollsByPeriod(ctx context.Context, startDate time.Time, endDate time.Time) ([]*data.Payroll, error)
+
+	// GetPayslipRunsByPeriod returns what every payroll run overlapping the period paid per user,
+	// userId 0 means every user
+	GetPayslipRunsByPeriod(ctx context.Context, userId int, startDate time.Time, endDate time.Time) ([]*data.PayslipRun, error)
+
+	// GetPayrollById retrieves payroll metadata by its id.
 	// It returns nil if no payroll is found.
-	GetPayrollByPeriod(ctx context.Context, startDate time.Time, endDate time.Time) (*data.Payroll, error)
+	GetPayrollById(ctx context.Context, id int) (*data.Payroll, error)
 
-	// PayrollItem is the detail salary breakdown per user in a payroll period
-	InsertPayrollItem(ctx context.Context, payrollId int, userId int, attendanceCount int, overtimeHours int, reimbursementTotal int, totalSalary int, createdBy string) (int, error)
+	// PayrollItem is the detail salary breakdown per user in a payroll period.
+	// totalSalary is the gross amount (base + overtime + bonus + reimbursement), taxAmount, bpjsAmount
+	// and loanDeduction are withheld from it.
+	InsertPayrollItem(ctx context.Context, payrollId int, userId int, attendanceCount int, overtimeHours int,
+		baseSalaryAmount int, overtimeAmount int, bonusAmount int, reimbursementTotal int, taxAmount int, bpjsAmount int,
+		loanDeduction int, totalSalary int, createdBy string) (int, error)
 
 	// GetPayrollItemsByPayrollID returns all payroll items for a specific payroll batch
 	GetPayrollItemsByPayrollID(ctx context.Context, payrollId int) ([]*data.PayrollItem, error)
 
+	// GetYearlyPayrollSummary accumulates payroll items per user for every payroll ending in the given year
+	GetYearlyPayrollSummary(ctx context.Context, year int) ([]*data.YearlyPayrollSummary, error)
+
 	// GetPayrollItemByPayrollIDAndUserID returns one user's payroll item in a specific payroll
 	GetPayrollItemByPayrollIDAndUserID(ctx context.Context, payrollId int, userId int) (*data.PayrollItem, error)
+
+	// GetMonthlyTaxByUser sums the taxable gross (base + overtime + bonus) and the PPh 21 withheld per user
+	// over every payroll paid (period end) in the month. Key is userId.
+	GetMonthlyTaxByUser(ctx context.Context, year int, month int) (map[int]*data.UserMonthlyTax, error)
+
+	// GetPayrollItemsByTypeAndYear returns the payroll items of every payroll of the type paid in the year
+	GetPayrollItemsByTypeAndYear(ctx context.Context, payrollType data.PayrollType, year int) ([]*data.PayrollItem, error)
+
+	// InsertPayrollDraft stores the draft of its period, fills Id. It returns false when the period already has a draft.
+	InsertPayrollDraft(ctx context.Context, draft *data.PayrollDraft, createdBy string) (bool, error)
+
+	// GetPayrollDrafts returns the latest drafts, newest period first
+	GetPayrollDrafts(ctx context.Context, limit int) ([]*data.PayrollDraft, error)
+
+	// SetPayrollDraftPayroll links the draft of the period to the regular payroll that paid it
+	SetPayrollDraftPayroll(ctx context.Context, periodStart time.Time, periodEnd time.Time, payrollId int) error
+
+	// GetPayrollPeriodTotals sums the payrolls per type and month of period end, from the month of since
+	GetPayrollPeriodTotals(ctx context.Context, since time.Time) ([]*data.PayrollPeriodTotal, error)
+
+	// InsertOutboxEvent records the domain event in the outbox, in the transaction of ctx
+	InsertOutboxEvent(ctx context.Context, event *data.OutboxEvent) error
 }
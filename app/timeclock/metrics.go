@@ -0,0 +1,66 @@
+package timeclock
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/timeclock/lib"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// clock-ins per hour is increase(payroll_clock_ins_total[1h])
+	clockIns = promauto.With(metrics.Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "payroll_clock_ins_total",
+		Help: "Attendances checked in, source is self (the employee) or admin",
+	}, []string{"source"})
+	payrollRunDuration = promauto.With(metrics.Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "payroll_run_duration_seconds",
+		Help:    "Duration of the stored payroll runs, from the request to the commit",
+		Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"type"})
+)
+
+// payrollTotalsMonths is how many months of payroll totals are exposed
+const payrollTotalsMonths = 12
+
+var (
+	payrollTotalSalaryDesc = prometheus.NewDesc("payroll_period_total_salary_rupiah",
+		"Total salary paid by the payrolls of the type and month (YYYY-MM) of period end", []string{"type", "period"}, nil)
+	payrollRunsDesc = prometheus.NewDesc("payroll_period_runs",
+		"Payrolls run of the type and month (YYYY-MM) of period end", []string{"type", "period"}, nil)
+)
+
+// payrollCollector reads the payroll totals from the database at every scrape, so every instance reports the same totals
+type payrollCollector struct {
+	storage lib.StorageInterface
+}
+
+func (c payrollCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- payrollTotalSalaryDesc
+	ch <- payrollRunsDesc
+}
+
+func (c payrollCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	totals, err := c.storage.GetPayrollPeriodTotals(ctx, time.Now().AddDate(0, 1-payrollTotalsMonths, 0))
+	if err != nil {
+		log.Error(nil).Err(err).Msg("metrics/ get payroll period totals failed")
+		return
+	}
+
+	for _, total := range totals {
+		ch <- prometheus.MustNewConstMetric(payrollTotalSalaryDesc, prometheus.GaugeValue, float64(total.TotalSalary), string(total.Type), total.Period)
+		ch <- prometheus.MustNewConstMetric(payrollRunsDesc, prometheus.GaugeValue, float64(total.Payrolls), string(total.Type), total.Period)
+	}
+}
+
+// RegisterPayrollMetrics exposes the payroll totals of the last 12 months per type and month of period end
+func RegisterPayrollMetrics(registry prometheus.Registerer, storage lib.StorageInterface) {
+	registry.MustRegister(payrollCollector{storage: storage})
+}
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ariesmaulana/payroll/app/audit/lib (interfaces: ServiceInterface)
+//
+// Generated by this command:
+//
+//	mockgen -mock_names ServiceInterface=MockAuditService github.com/ariesmaulana/payroll/app/audit/lib ServiceInterface
+//
+
+// Package mock_lib is a generated GoMock package.
+package mock_lib
+
+import (
+	context "context"
+	reflect "reflect"
+
+	lib "github.com/ariesmaulana/payroll/app/audit/lib"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAuditService is a mock of ServiceInterface interface.
+type MockAuditService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditServiceMockRecorder is the mock recorder for MockAuditService.
+type MockAuditServiceMockRecorder struct {
+	mock *MockAuditService
+}
+
+// NewMockAuditService creates a new mock instance.
+func NewMockAuditService(ctrl *gomock.Controller) *MockAuditService {
+	mock := &MockAuditService{ctrl: ctrl}
+	mock.recorder = &MockAuditServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditService) EXPECT() *MockAuditServiceMockRecorder {
+	return m.recorder
+}
+
+// ListEvents mocks base method.
+func (m *MockAuditService) ListEvents(ctx context.Context, in *lib.ListEventsIn) *lib.ListEventsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, in)
+	ret0, _ := ret[0].(*lib.ListEventsOut)
+	return ret0
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockAuditServiceMockRecorder) ListEvents(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockAuditService)(nil).ListEvents), ctx, in)
+}
+
+// Record mocks base method.
+func (m *MockAuditService) Record(ctx context.Context, in *lib.RecordIn) *lib.RecordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, in)
+	ret0, _ := ret[0].(*lib.RecordOut)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditServiceMockRecorder) Record(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAuditService)(nil).Record), ctx, in)
+}
+
+// VerifyChain mocks base method.
+func (m *MockAuditService) VerifyChain(ctx context.Context, in *lib.VerifyChainIn) *lib.VerifyChainOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChain", ctx, in)
+	ret0, _ := ret[0].(*lib.VerifyChainOut)
+	return ret0
+}
+
+// VerifyChain indicates an expected call of VerifyChain.
+func (mr *MockAuditServiceMockRecorder) VerifyChain(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChain", reflect.TypeOf((*MockAuditService)(nil).VerifyChain), ctx, in)
+}
@@ -0,0 +1,182 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ariesmaulana/payroll/app/loan/lib (interfaces: ServiceInterface)
+//
+// Generated by this command:
+//
+//	mockgen -mock_names ServiceInterface=MockLoanService github.com/ariesmaulana/payroll/app/loan/lib ServiceInterface
+//
+
+// Package mock_lib is a generated GoMock package.
+package mock_lib
+
+import (
+	context "context"
+	reflect "reflect"
+
+	lib "github.com/ariesmaulana/payroll/app/loan/lib"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoanService is a mock of ServiceInterface interface.
+type MockLoanService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoanServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockLoanServiceMockRecorder is the mock recorder for MockLoanService.
+type MockLoanServiceMockRecorder struct {
+	mock *MockLoanService
+}
+
+// NewMockLoanService creates a new mock instance.
+func NewMockLoanService(ctrl *gomock.Controller) *MockLoanService {
+	mock := &MockLoanService{ctrl: ctrl}
+	mock.recorder = &MockLoanServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoanService) EXPECT() *MockLoanServiceMockRecorder {
+	return m.recorder
+}
+
+// ApproveLoan mocks base method.
+func (m *MockLoanService) ApproveLoan(ctx context.Context, in *lib.ApproveLoanIn) *lib.ApproveLoanOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveLoan", ctx, in)
+	ret0, _ := ret[0].(*lib.ApproveLoanOut)
+	return ret0
+}
+
+// ApproveLoan indicates an expected call of ApproveLoan.
+func (mr *MockLoanServiceMockRecorder) ApproveLoan(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveLoan", reflect.TypeOf((*MockLoanService)(nil).ApproveLoan), ctx, in)
+}
+
+// GetLoanDetail mocks base method.
+func (m *MockLoanService) GetLoanDetail(ctx context.Context, in *lib.GetLoanDetailIn) *lib.GetLoanDetailOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoanDetail", ctx, in)
+	ret0, _ := ret[0].(*lib.GetLoanDetailOut)
+	return ret0
+}
+
+// GetLoanDetail indicates an expected call of GetLoanDetail.
+func (mr *MockLoanServiceMockRecorder) GetLoanDetail(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoanDetail", reflect.TypeOf((*MockLoanService)(nil).GetLoanDetail), ctx, in)
+}
+
+// ListLoans mocks base method.
+func (m *MockLoanService) ListLoans(ctx context.Context, in *lib.ListLoansIn) *lib.ListLoansOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoans", ctx, in)
+	ret0, _ := ret[0].(*lib.ListLoansOut)
+	return ret0
+}
+
+// ListLoans indicates an expected call of ListLoans.
+func (mr *MockLoanServiceMockRecorder) ListLoans(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoans", reflect.TypeOf((*MockLoanService)(nil).ListLoans), ctx, in)
+}
+
+// OutstandingBalances mocks base method.
+func (m *MockLoanService) OutstandingBalances(ctx context.Context, in *lib.OutstandingBalancesIn) *lib.OutstandingBalancesOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OutstandingBalances", ctx, in)
+	ret0, _ := ret[0].(*lib.OutstandingBalancesOut)
+	return ret0
+}
+
+// OutstandingBalances indicates an expected call of OutstandingBalances.
+func (mr *MockLoanServiceMockRecorder) OutstandingBalances(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OutstandingBalances", reflect.TypeOf((*MockLoanService)(nil).OutstandingBalances), ctx, in)
+}
+
+// PayrollDeductions mocks base method.
+func (m *MockLoanService) PayrollDeductions(ctx context.Context, in *lib.PayrollDeductionsIn) *lib.PayrollDeductionsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PayrollDeductions", ctx, in)
+	ret0, _ := ret[0].(*lib.PayrollDeductionsOut)
+	return ret0
+}
+
+// PayrollDeductions indicates an expected call of PayrollDeductions.
+func (mr *MockLoanServiceMockRecorder) PayrollDeductions(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PayrollDeductions", reflect.TypeOf((*MockLoanService)(nil).PayrollDeductions), ctx, in)
+}
+
+// RecordPayrollDeductions mocks base method.
+func (m *MockLoanService) RecordPayrollDeductions(ctx context.Context, in *lib.RecordPayrollDeductionsIn) *lib.RecordPayrollDeductionsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordPayrollDeductions", ctx, in)
+	ret0, _ := ret[0].(*lib.RecordPayrollDeductionsOut)
+	return ret0
+}
+
+// RecordPayrollDeductions indicates an expected call of RecordPayrollDeductions.
+func (mr *MockLoanServiceMockRecorder) RecordPayrollDeductions(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordPayrollDeductions", reflect.TypeOf((*MockLoanService)(nil).RecordPayrollDeductions), ctx, in)
+}
+
+// RejectLoan mocks base method.
+func (m *MockLoanService) RejectLoan(ctx context.Context, in *lib.RejectLoanIn) *lib.RejectLoanOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectLoan", ctx, in)
+	ret0, _ := ret[0].(*lib.RejectLoanOut)
+	return ret0
+}
+
+// RejectLoan indicates an expected call of RejectLoan.
+func (mr *MockLoanServiceMockRecorder) RejectLoan(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectLoan", reflect.TypeOf((*MockLoanService)(nil).RejectLoan), ctx, in)
+}
+
+// RequestLoan mocks base method.
+func (m *MockLoanService) RequestLoan(ctx context.Context, in *lib.RequestLoanIn) *lib.RequestLoanOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestLoan", ctx, in)
+	ret0, _ := ret[0].(*lib.RequestLoanOut)
+	return ret0
+}
+
+// RequestLoan indicates an expected call of RequestLoan.
+func (mr *MockLoanServiceMockRecorder) RequestLoan(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestLoan", reflect.TypeOf((*MockLoanService)(nil).RequestLoan), ctx, in)
+}
+
+// SelfLoans mocks base method.
+func (m *MockLoanService) SelfLoans(ctx context.Context, in *lib.SelfLoansIn) *lib.SelfLoansOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelfLoans", ctx, in)
+	ret0, _ := ret[0].(*lib.SelfLoansOut)
+	return ret0
+}
+
+// SelfLoans indicates an expected call of SelfLoans.
+func (mr *MockLoanServiceMockRecorder) SelfLoans(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelfLoans", reflect.TypeOf((*MockLoanService)(nil).SelfLoans), ctx, in)
+}
+
+// SettleLoan mocks base method.
+func (m *MockLoanService) SettleLoan(ctx context.Context, in *lib.SettleLoanIn) *lib.SettleLoanOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SettleLoan", ctx, in)
+	ret0, _ := ret[0].(*lib.SettleLoanOut)
+	return ret0
+}
+
+// SettleLoan indicates an expected call of SettleLoan.
+func (mr *MockLoanServiceMockRecorder) SettleLoan(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SettleLoan", reflect.TypeOf((*MockLoanService)(nil).SettleLoan), ctx, in)
+}
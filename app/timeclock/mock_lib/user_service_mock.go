@@ -41,6 +41,146 @@ func (m *MockServiceInterface) EXPECT() *MockServiceInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AuthenticateAPIToken mocks base method.
+func (m *MockServiceInterface) AuthenticateAPIToken(ctx context.Context, in *lib.AuthenticateAPITokenIn) *lib.AuthenticateAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.AuthenticateAPITokenOut)
+	return ret0
+}
+
+// AuthenticateAPIToken indicates an expected call of AuthenticateAPIToken.
+func (mr *MockServiceInterfaceMockRecorder) AuthenticateAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIToken", reflect.TypeOf((*MockServiceInterface)(nil).AuthenticateAPIToken), ctx, in)
+}
+
+// ChangePassword mocks base method.
+func (m *MockServiceInterface) ChangePassword(ctx context.Context, in *lib.ChangePasswordIn) *lib.ChangePasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ChangePasswordOut)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockServiceInterfaceMockRecorder) ChangePassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockServiceInterface)(nil).ChangePassword), ctx, in)
+}
+
+// CompleteSSOLogin mocks base method.
+func (m *MockServiceInterface) CompleteSSOLogin(ctx context.Context, in *lib.CompleteSSOLoginIn) *lib.LoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteSSOLogin", ctx, in)
+	ret0, _ := ret[0].(*lib.LoginOut)
+	return ret0
+}
+
+// CompleteSSOLogin indicates an expected call of CompleteSSOLogin.
+func (mr *MockServiceInterfaceMockRecorder) CompleteSSOLogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteSSOLogin", reflect.TypeOf((*MockServiceInterface)(nil).CompleteSSOLogin), ctx, in)
+}
+
+// ConfirmMFAEnrollment mocks base method.
+func (m *MockServiceInterface) ConfirmMFAEnrollment(ctx context.Context, in *lib.ConfirmMFAEnrollmentIn) *lib.ConfirmMFAEnrollmentOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmMFAEnrollment", ctx, in)
+	ret0, _ := ret[0].(*lib.ConfirmMFAEnrollmentOut)
+	return ret0
+}
+
+// ConfirmMFAEnrollment indicates an expected call of ConfirmMFAEnrollment.
+func (mr *MockServiceInterfaceMockRecorder) ConfirmMFAEnrollment(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFAEnrollment", reflect.TypeOf((*MockServiceInterface)(nil).ConfirmMFAEnrollment), ctx, in)
+}
+
+// CreateAPIToken mocks base method.
+func (m *MockServiceInterface) CreateAPIToken(ctx context.Context, in *lib.CreateAPITokenIn) *lib.CreateAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateAPITokenOut)
+	return ret0
+}
+
+// CreateAPIToken indicates an expected call of CreateAPIToken.
+func (mr *MockServiceInterfaceMockRecorder) CreateAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIToken", reflect.TypeOf((*MockServiceInterface)(nil).CreateAPIToken), ctx, in)
+}
+
+// CreateServiceAccount mocks base method.
+func (m *MockServiceInterface) CreateServiceAccount(ctx context.Context, in *lib.CreateServiceAccountIn) *lib.CreateServiceAccountOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceAccount", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateServiceAccountOut)
+	return ret0
+}
+
+// CreateServiceAccount indicates an expected call of CreateServiceAccount.
+func (mr *MockServiceInterfaceMockRecorder) CreateServiceAccount(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceAccount", reflect.TypeOf((*MockServiceInterface)(nil).CreateServiceAccount), ctx, in)
+}
+
+// CreateUser mocks base method.
+func (m *MockServiceInterface) CreateUser(ctx context.Context, in *lib.CreateUserIn) *lib.CreateUserOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, in)
+	ret0, _ := ret[0].(*lib.CreateUserOut)
+	return ret0
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockServiceInterfaceMockRecorder) CreateUser(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockServiceInterface)(nil).CreateUser), ctx, in)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockServiceInterface) ForgotPassword(ctx context.Context, in *lib.ForgotPasswordIn) *lib.ForgotPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ForgotPasswordOut)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockServiceInterfaceMockRecorder) ForgotPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockServiceInterface)(nil).ForgotPassword), ctx, in)
+}
+
+// ListAPITokens mocks base method.
+func (m *MockServiceInterface) ListAPITokens(ctx context.Context, in *lib.ListAPITokensIn) *lib.ListAPITokensOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPITokens", ctx, in)
+	ret0, _ := ret[0].(*lib.ListAPITokensOut)
+	return ret0
+}
+
+// ListAPITokens indicates an expected call of ListAPITokens.
+func (mr *MockServiceInterfaceMockRecorder) ListAPITokens(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPITokens", reflect.TypeOf((*MockServiceInterface)(nil).ListAPITokens), ctx, in)
+}
+
+// ListServiceAccounts mocks base method.
+func (m *MockServiceInterface) ListServiceAccounts(ctx context.Context, in *lib.ListServiceAccountsIn) *lib.ListServiceAccountsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServiceAccounts", ctx, in)
+	ret0, _ := ret[0].(*lib.ListServiceAccountsOut)
+	return ret0
+}
+
+// ListServiceAccounts indicates an expected call of ListServiceAccounts.
+func (mr *MockServiceInterfaceMockRecorder) ListServiceAccounts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServiceAccounts", reflect.TypeOf((*MockServiceInterface)(nil).ListServiceAccounts), ctx, in)
+}
+
 // Login mocks base method.
 func (m *MockServiceInterface) Login(ctx context.Context, in *lib.LoginIn) *lib.LoginOut {
 	m.ctrl.T.Helper()
@@ -55,6 +195,244 @@ func (mr *MockServiceInterfaceMockRecorder) Login(ctx, in any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockServiceInterface)(nil).Login), ctx, in)
 }
 
+// Logout mocks base method.
+func (m *MockServiceInterface) Logout(ctx context.Context, in *lib.LogoutIn) *lib.LogoutOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, in)
+	ret0, _ := ret[0].(*lib.LogoutOut)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockServiceInterfaceMockRecorder) Logout(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockServiceInterface)(nil).Logout), ctx, in)
+}
+
+// MFAStepUp mocks base method.
+func (m *MockServiceInterface) MFAStepUp(ctx context.Context, in *lib.MFAStepUpIn) *lib.MFAStepUpOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MFAStepUp", ctx, in)
+	ret0, _ := ret[0].(*lib.MFAStepUpOut)
+	return ret0
+}
+
+// MFAStepUp indicates an expected call of MFAStepUp.
+func (mr *MockServiceInterfaceMockRecorder) MFAStepUp(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MFAStepUp", reflect.TypeOf((*MockServiceInterface)(nil).MFAStepUp), ctx, in)
+}
+
+// RefreshToken mocks base method.
+func (m *MockServiceInterface) RefreshToken(ctx context.Context, in *lib.RefreshTokenIn) *lib.RefreshTokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, in)
+	ret0, _ := ret[0].(*lib.RefreshTokenOut)
+	return ret0
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockServiceInterfaceMockRecorder) RefreshToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockServiceInterface)(nil).RefreshToken), ctx, in)
+}
+
+// ResetPassword mocks base method.
+func (m *MockServiceInterface) ResetPassword(ctx context.Context, in *lib.ResetPasswordIn) *lib.ResetPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.ResetPasswordOut)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockServiceInterfaceMockRecorder) ResetPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockServiceInterface)(nil).ResetPassword), ctx, in)
+}
+
+// RevokeAPIToken mocks base method.
+func (m *MockServiceInterface) RevokeAPIToken(ctx context.Context, in *lib.RevokeAPITokenIn) *lib.RevokeAPITokenOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIToken", ctx, in)
+	ret0, _ := ret[0].(*lib.RevokeAPITokenOut)
+	return ret0
+}
+
+// RevokeAPIToken indicates an expected call of RevokeAPIToken.
+func (mr *MockServiceInterfaceMockRecorder) RevokeAPIToken(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIToken", reflect.TypeOf((*MockServiceInterface)(nil).RevokeAPIToken), ctx, in)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockServiceInterface) RevokeSessions(ctx context.Context, in *lib.RevokeSessionsIn) *lib.RevokeSessionsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, in)
+	ret0, _ := ret[0].(*lib.RevokeSessionsOut)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockServiceInterfaceMockRecorder) RevokeSessions(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockServiceInterface)(nil).RevokeSessions), ctx, in)
+}
+
+// SetBankAccount mocks base method.
+func (m *MockServiceInterface) SetBankAccount(ctx context.Context, in *lib.SetBankAccountIn) *lib.SetBankAccountOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBankAccount", ctx, in)
+	ret0, _ := ret[0].(*lib.SetBankAccountOut)
+	return ret0
+}
+
+// SetBankAccount indicates an expected call of SetBankAccount.
+func (mr *MockServiceInterfaceMockRecorder) SetBankAccount(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBankAccount", reflect.TypeOf((*MockServiceInterface)(nil).SetBankAccount), ctx, in)
+}
+
+// SetPassword mocks base method.
+func (m *MockServiceInterface) SetPassword(ctx context.Context, in *lib.SetPasswordIn) *lib.SetPasswordOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, in)
+	ret0, _ := ret[0].(*lib.SetPasswordOut)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockServiceInterfaceMockRecorder) SetPassword(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockServiceInterface)(nil).SetPassword), ctx, in)
+}
+
+// SetReligion mocks base method.
+func (m *MockServiceInterface) SetReligion(ctx context.Context, in *lib.SetReligionIn) *lib.SetReligionOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReligion", ctx, in)
+	ret0, _ := ret[0].(*lib.SetReligionOut)
+	return ret0
+}
+
+// SetReligion indicates an expected call of SetReligion.
+func (mr *MockServiceInterfaceMockRecorder) SetReligion(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReligion", reflect.TypeOf((*MockServiceInterface)(nil).SetReligion), ctx, in)
+}
+
+// SetTaxProfile mocks base method.
+func (m *MockServiceInterface) SetTaxProfile(ctx context.Context, in *lib.SetTaxProfileIn) *lib.SetTaxProfileOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTaxProfile", ctx, in)
+	ret0, _ := ret[0].(*lib.SetTaxProfileOut)
+	return ret0
+}
+
+// SetTaxProfile indicates an expected call of SetTaxProfile.
+func (mr *MockServiceInterfaceMockRecorder) SetTaxProfile(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTaxProfile", reflect.TypeOf((*MockServiceInterface)(nil).SetTaxProfile), ctx, in)
+}
+
+// StartMFAEnrollment mocks base method.
+func (m *MockServiceInterface) StartMFAEnrollment(ctx context.Context, in *lib.StartMFAEnrollmentIn) *lib.StartMFAEnrollmentOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartMFAEnrollment", ctx, in)
+	ret0, _ := ret[0].(*lib.StartMFAEnrollmentOut)
+	return ret0
+}
+
+// StartMFAEnrollment indicates an expected call of StartMFAEnrollment.
+func (mr *MockServiceInterfaceMockRecorder) StartMFAEnrollment(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartMFAEnrollment", reflect.TypeOf((*MockServiceInterface)(nil).StartMFAEnrollment), ctx, in)
+}
+
+// StartSSOLogin mocks base method.
+func (m *MockServiceInterface) StartSSOLogin(ctx context.Context, in *lib.StartSSOLoginIn) *lib.StartSSOLoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartSSOLogin", ctx, in)
+	ret0, _ := ret[0].(*lib.StartSSOLoginOut)
+	return ret0
+}
+
+// StartSSOLogin indicates an expected call of StartSSOLogin.
+func (mr *MockServiceInterfaceMockRecorder) StartSSOLogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSSOLogin", reflect.TypeOf((*MockServiceInterface)(nil).StartSSOLogin), ctx, in)
+}
+
+// UnlockUser mocks base method.
+func (m *MockServiceInterface) UnlockUser(ctx context.Context, in *lib.UnlockUserIn) *lib.UnlockUserOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, in)
+	ret0, _ := ret[0].(*lib.UnlockUserOut)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockServiceInterfaceMockRecorder) UnlockUser(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockServiceInterface)(nil).UnlockUser), ctx, in)
+}
+
+// UserBankAccounts mocks base method.
+func (m *MockServiceInterface) UserBankAccounts(ctx context.Context, in *lib.UserBankAccountsIn) *lib.UserBankAccountsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserBankAccounts", ctx, in)
+	ret0, _ := ret[0].(*lib.UserBankAccountsOut)
+	return ret0
+}
+
+// UserBankAccounts indicates an expected call of UserBankAccounts.
+func (mr *MockServiceInterfaceMockRecorder) UserBankAccounts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserBankAccounts", reflect.TypeOf((*MockServiceInterface)(nil).UserBankAccounts), ctx, in)
+}
+
+// UserContacts mocks base method.
+func (m *MockServiceInterface) UserContacts(ctx context.Context, in *lib.UserContactsIn) *lib.UserContactsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserContacts", ctx, in)
+	ret0, _ := ret[0].(*lib.UserContactsOut)
+	return ret0
+}
+
+// UserContacts indicates an expected call of UserContacts.
+func (mr *MockServiceInterfaceMockRecorder) UserContacts(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserContacts", reflect.TypeOf((*MockServiceInterface)(nil).UserContacts), ctx, in)
+}
+
+// UserCostCenter mocks base method.
+func (m *MockServiceInterface) UserCostCenter(ctx context.Context, in *lib.UserCostCenterIn) *lib.UserCostCenterOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserCostCenter", ctx, in)
+	ret0, _ := ret[0].(*lib.UserCostCenterOut)
+	return ret0
+}
+
+// UserCostCenter indicates an expected call of UserCostCenter.
+func (mr *MockServiceInterfaceMockRecorder) UserCostCenter(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserCostCenter", reflect.TypeOf((*MockServiceInterface)(nil).UserCostCenter), ctx, in)
+}
+
+// UserEmployments mocks base method.
+func (m *MockServiceInterface) UserEmployments(ctx context.Context, in *lib.UserEmploymentsIn) *lib.UserEmploymentsOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserEmployments", ctx, in)
+	ret0, _ := ret[0].(*lib.UserEmploymentsOut)
+	return ret0
+}
+
+// UserEmployments indicates an expected call of UserEmployments.
+func (mr *MockServiceInterfaceMockRecorder) UserEmployments(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserEmployments", reflect.TypeOf((*MockServiceInterface)(nil).UserEmployments), ctx, in)
+}
+
 // UserSalary mocks base method.
 func (m *MockServiceInterface) UserSalary(ctx context.Context, in *lib.UserSalaryIn) *lib.UserSalaryOut {
 	m.ctrl.T.Helper()
@@ -68,3 +446,45 @@ func (mr *MockServiceInterfaceMockRecorder) UserSalary(ctx, in any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSalary", reflect.TypeOf((*MockServiceInterface)(nil).UserSalary), ctx, in)
 }
+
+// UserTaxProfiles mocks base method.
+func (m *MockServiceInterface) UserTaxProfiles(ctx context.Context, in *lib.UserTaxProfilesIn) *lib.UserTaxProfilesOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserTaxProfiles", ctx, in)
+	ret0, _ := ret[0].(*lib.UserTaxProfilesOut)
+	return ret0
+}
+
+// UserTaxProfiles indicates an expected call of UserTaxProfiles.
+func (mr *MockServiceInterfaceMockRecorder) UserTaxProfiles(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserTaxProfiles", reflect.TypeOf((*MockServiceInterface)(nil).UserTaxProfiles), ctx, in)
+}
+
+// ValidateSession mocks base method.
+func (m *MockServiceInterface) ValidateSession(ctx context.Context, in *lib.ValidateSessionIn) *lib.ValidateSessionOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateSession", ctx, in)
+	ret0, _ := ret[0].(*lib.ValidateSessionOut)
+	return ret0
+}
+
+// ValidateSession indicates an expected call of ValidateSession.
+func (mr *MockServiceInterfaceMockRecorder) ValidateSession(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateSession", reflect.TypeOf((*MockServiceInterface)(nil).ValidateSession), ctx, in)
+}
+
+// VerifyMFALogin mocks base method.
+func (m *MockServiceInterface) VerifyMFALogin(ctx context.Context, in *lib.VerifyMFALoginIn) *lib.LoginOut {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFALogin", ctx, in)
+	ret0, _ := ret[0].(*lib.LoginOut)
+	return ret0
+}
+
+// VerifyMFALogin indicates an expected call of VerifyMFALogin.
+func (mr *MockServiceInterfaceMockRecorder) VerifyMFALogin(ctx, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFALogin", reflect.TypeOf((*MockServiceInterface)(nil).VerifyMFALogin), ctx, in)
+}
@@ -1,6 +1,7 @@
 package timeclock
 
 import (
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/ariesmaulana/payroll/lib/middleware"
 	"github.com/go-chi/chi/v5"
 )
@@ -13,22 +14,33 @@ func RegisterRoutes(r chi.Router, handler *Handler) {
 			r.Use(middleware.AuthMiddleware)
 
 			// (attendance)
-			r.Post("/add-period", handler.AddAttendancePeriod)
-			r.Post("/clock-in", handler.SubmitAttendance)
-			r.Post("/clock-out", handler.CheckoutAttendance)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(data.ScopeAttendanceWrite))
+				r.Post("/add-period", handler.AddAttendancePeriod)
+				r.Post("/clock-in", handler.SubmitAttendance)
+				r.Post("/clock-out", handler.CheckoutAttendance)
 
-			//  (overtime)
-			r.Post("/overtime", handler.AddOvertime)
+				//  (overtime)
+				r.Post("/overtime", handler.AddOvertime)
+			})
 
 			//reimbursement
-			r.Post("/reimbursement", handler.SubmitReimbursement)
+			r.With(middleware.RequireScope(data.ScopeReimbursementWrite)).Post("/reimbursement", handler.SubmitReimbursement)
 
 			// (payroll)
-			r.Post("/payroll/run", handler.RunPayroll)
+			// running payroll finalizes salaries, it requires a fresh TOTP step-up
+			r.With(middleware.RequireStepUp).Post("/payroll/run", handler.RunPayroll)
+			r.With(middleware.RequireStepUp).Post("/payroll/thr/run", handler.RunTHR)
+			r.Get("/payroll/drafts", handler.ListPayrollDrafts)
 
 			// (payslip)
-			r.Get("/payslip/self", handler.GenerateSelfPaySlip)
-			r.Get("/payslip/all", handler.GenerateAllPaySlips)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(data.ScopePayslipRead))
+				r.Get("/payslip/self", handler.GenerateSelfPaySlip)
+				r.Get("/payslip/all", handler.GenerateAllPaySlips)
+				r.Get("/payslip/thr/self", handler.GenerateSelfTHRSlip)
+				r.Get("/payslip/thr/all", handler.GenerateAllTHRSlips)
+			})
 		})
 	})
 }
@@ -2,43 +2,89 @@ package timeclock
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
+	auditLib "github.com/ariesmaulana/payroll/app/audit/lib"
+	loanLib "github.com/ariesmaulana/payroll/app/loan/lib"
 	"github.com/ariesmaulana/payroll/app/timeclock/lib"
 	userLib "github.com/ariesmaulana/payroll/app/user/lib"
 	"github.com/ariesmaulana/payroll/common"
 	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
 	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/outbox"
 )
 
 var _ lib.ServiceInterface = (*Service)(nil)
 
 type Service struct {
-	storage     lib.StorageInterface
-	userService userLib.ServiceInterface
+	storage      lib.StorageInterface
+	userService  userLib.ServiceInterface
+	loanService  loanLib.ServiceInterface
+	auditService auditLib.ServiceInterface
 }
 
-func NewService(storage lib.StorageInterface, userService userLib.ServiceInterface) *Service {
+func NewService(storage lib.StorageInterface, userService userLib.ServiceInterface, loanService loanLib.ServiceInterface, auditService auditLib.ServiceInterface) *Service {
 	return &Service{
-		storage:     storage,
-		userService: userService,
+		storage:      storage,
+		userService:  userService,
+		loanService:  loanService,
+		auditService: auditService,
 	}
 }
 
+// audit records the action in the audit trail, the caller fails with internal error when it is not recorded
+func (s *Service) audit(ctx context.Context, in *auditLib.RecordIn) bool {
+	out := s.auditService.Record(ctx, in)
+	if !out.Success {
+		log.Error(in.Trace).Str("action", in.Action).Str("reason", out.Message).Msg("audit/ record failed")
+	}
+	return out.Success
+}
+
+// publish records the domain event in the outbox within the transaction of ctx, the caller fails with
+// internal error when it is not recorded so the change is never committed without its event
+func (s *Service) publish(ctx context.Context, trace *contextutil.Trace, eventType string, aggregateType string, aggregateId string, payload any) bool {
+	event, err := outbox.NewEvent(eventType, aggregateType, aggregateId, payload, trace)
+	if err == nil {
+		err = s.storage.InsertOutboxEvent(ctx, event)
+	}
+	if err != nil {
+		log.Error(trace).Err(err).Str("event", eventType).Msg("outbox/ record event failed")
+		return false
+	}
+	return true
+}
+
+// aggregates of the outbox events, events of one aggregate are delivered in order
+const (
+	aggregateEmployee = "employee"
+	aggregatePayroll  = "payroll"
+)
+
+// attendanceEntityId identifies an attendance in the audit trail, clock in and clock out share it
+func attendanceEntityId(userId int, period time.Time) string {
+	return fmt.Sprintf("%d/%s", userId, period.Format("2006-01-02"))
+}
+
 func (s *Service) AddAttendancePeriod(ctx context.Context, in *lib.AddAttendancePeriodIn) *lib.AddAttendancePeriodOut {
 	resp := lib.AddAttendancePeriodOut{}
 
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("AddAttendancePeriod/ unauthorized access - user missing in context")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return &resp
 	}
 
 	if user.Role != data.RAdmin {
 		log.Warn(in.Trace).Msg("AddAttendancePeriod/ user not admin")
-		resp.Message = "forbidden: Hanya admin yang bisa akses"
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
 		return &resp
 	}
 
@@ -47,51 +93,72 @@ func (s *Service) AddAttendancePeriod(ctx context.Context, in *lib.AddAttendance
 		log.Warn(in.Trace).
 			Time("checkinDate", in.CheckInDate).
 			Msg("AddAttendancePeriod/ invalid period range")
-		resp.Message = "Checkin date wajib diisi"
+		resp.Fail(apperror.CodeInvalidInput, "Checkin date wajib diisi")
 		return &resp
 	}
 
 	if !s.isWeekDays(in.CheckInDate) {
 		log.Warn(in.Trace).Msg("AddAttendancePeriod/ failed weekends")
-		resp.Message = "Tidak bisa mengisi kehadiran saat Sabtu dan Minggu."
+		resp.Fail(apperror.CodeAttendanceWeekend, "Tidak bisa mengisi kehadiran saat Sabtu dan Minggu.")
 		return &resp
 	}
 
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddAttendancePeriod/ Failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
 	period := in.CheckInDate.Truncate(24 * time.Hour)
 	checkin := in.CheckInDate
 
-	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, period)
+	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, in.UserID, period)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddAttendancePeriod/ failed to check payroll existence")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	if payrollExists {
 		log.Warn(in.Trace).Msg("AddAttendancePeriod/ cannot update data after payroll is processed")
-		resp.Message = "Data tidak bisa diubah karena payroll sudah dijalankan"
+		resp.Fail(apperror.CodePayrollLocked, "Data tidak bisa diubah karena payroll sudah dijalankan")
 		return &resp
 	}
 
 	// Insert ke storage
-	_, err = s.storage.InsertAttendanceCheckin(ctx, in.UserID, period, checkin, user.Username)
+	_, err = s.storage.InsertAttendanceCheckin(ctx, in.UserID, period, checkin, user.Actor())
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddAttendancePeriod/ failed to insert attendance period")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "attendance.create",
+		EntityType: "attendance",
+		EntityId:   attendanceEntityId(in.UserID, period),
+		After:      map[string]any{"user_id": in.UserID, "period": period, "check_in": checkin},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventAttendanceCheckedIn, aggregateEmployee, strconv.Itoa(in.UserID),
+		map[string]any{"user_id": in.UserID, "period": period, "check_in": checkin}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddAttendancePeriod/ failed to commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
+	clockIns.WithLabelValues("admin").Inc()
 
 	resp.Success = true
 	return &resp
@@ -107,13 +174,13 @@ func (s *Service) SubmitAttendance(ctx context.Context, in *lib.SubmitAttendance
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("SubmitAttendance/ unauthorized access - user missing in context")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return &resp
 	}
 
 	if in.Period.IsZero() {
 		log.Warn(in.Trace).Msg("SubmitAttendance/ period missing")
-		resp.Message = "Wajib pilih periode waktu checkin"
+		resp.Fail(apperror.CodeInvalidInput, "Wajib pilih periode waktu checkin")
 		return &resp
 	}
 
@@ -121,44 +188,65 @@ func (s *Service) SubmitAttendance(ctx context.Context, in *lib.SubmitAttendance
 
 	if !s.isWeekDays(today) {
 		log.Warn(in.Trace).Msg("SubmitAttendance/ failed weekends")
-		resp.Message = "Tidak bisa mengisi kehadiran saat Sabtu dan Minggu."
+		resp.Fail(apperror.CodeAttendanceWeekend, "Tidak bisa mengisi kehadiran saat Sabtu dan Minggu.")
 		return &resp
 	}
 
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitAttendance/ Failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
 	period := today.Truncate(24 * time.Hour)
 	checkin := today
 
-	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, period)
+	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, user.Id, period)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitAttendance/ failed to check payroll existence")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	if payrollExists {
 		log.Warn(in.Trace).Msg("SubmitAttendance/ cannot update data after payroll is processed")
-		resp.Message = "Data tidak bisa diubah karena payroll sudah dijalankan"
+		resp.Fail(apperror.CodePayrollLocked, "Data tidak bisa diubah karena payroll sudah dijalankan")
 		return &resp
 	}
 
-	_, err = s.storage.InsertAttendanceCheckin(ctx, user.Id, period, checkin, user.Username)
+	_, err = s.storage.InsertAttendanceCheckin(ctx, user.Id, period, checkin, user.Actor())
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitAttendance/ failed to insert attendance period")
-		resp.Message = "Terjadi kesalahan, kemungkinan anda telah tercatat di hari ini"
+		resp.Fail(apperror.CodeAttendanceDuplicate, "Terjadi kesalahan, kemungkinan anda telah tercatat di hari ini")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "attendance.create",
+		EntityType: "attendance",
+		EntityId:   attendanceEntityId(user.Id, period),
+		After:      map[string]any{"user_id": user.Id, "period": period, "check_in": checkin},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventAttendanceCheckedIn, aggregateEmployee, strconv.Itoa(user.Id),
+		map[string]any{"user_id": user.Id, "period": period, "check_in": checkin}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitAttendance/ failed to commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
+	clockIns.WithLabelValues("self").Inc()
 
 	resp.Success = true
 	return &resp
@@ -176,77 +264,98 @@ func (s *Service) AddOvertime(ctx context.Context, in *lib.AddOvertimeIn) *lib.A
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("AddOvertime/ unauthorized")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return &resp
 	}
 
 	if in.Hours <= 0 || in.Hours > 3 {
 		log.Warn(in.Trace).Msg("AddOvertime/ invalid overtimes")
-		resp.Message = "Jumlah jam lembur tidak boleh lebih dari 3"
+		resp.Fail(apperror.CodeInvalidInput, "Jumlah jam lembur tidak boleh lebih dari 3")
 		return &resp
 	}
 
 	if in.Reason == "" {
 		log.Warn(in.Trace).Msg("AddOvertime/ invalid reason")
-		resp.Message = "Alasan harus diisi"
+		resp.Fail(apperror.CodeInvalidInput, "Alasan harus diisi")
 		return &resp
 	}
 
 	if in.Period.IsZero() {
 		log.Warn(in.Trace).Msg("AddOvertime/ period missing")
-		resp.Message = "Wajib pilih periode waktu overtime"
+		resp.Fail(apperror.CodeInvalidInput, "Wajib pilih periode waktu overtime")
 		return &resp
 	}
 
 	now := in.Period
 	if now.Hour() < 17 && in.Period.Format("2006-01-02") == now.Format("2006-01-02") {
 		log.Warn(in.Trace).Msg("AddOvertime/ invalid times")
-		resp.Message = "Lembur hanya bisa diajukan setelah jam kerja selesai"
+		resp.Fail(apperror.CodeInvalidInput, "Lembur hanya bisa diajukan setelah jam kerja selesai")
 		return &resp
 	}
 
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddOvertime/ Failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
 	period := now.Truncate(24 * time.Hour)
 
-	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, period)
+	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, user.Id, period)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddOvertime/ failed to check payroll existence")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	if payrollExists {
 		log.Warn(in.Trace).Msg("AddOvertime/ cannot update data after payroll is processed")
-		resp.Message = "Data tidak bisa diubah karena payroll sudah dijalankan"
+		resp.Fail(apperror.CodePayrollLocked, "Data tidak bisa diubah karena payroll sudah dijalankan")
 		return &resp
 	}
 
 	attn, err := s.storage.GetDetailAttendanceByUserAndPeriod(ctx, user.Id, period)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddOvertime/ error get attendance")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	if attn == nil {
 		log.Warn(in.Trace).Msg("AddOvertime/ attendance not found")
-		resp.Message = "Anda belum absen di hari tersebut"
+		resp.Fail(apperror.CodeAttendanceNotChecked, "Anda belum absen di hari tersebut")
 		return &resp
 	}
 
-	id, err := s.storage.InsertOvertime(ctx, user.Id, period, in.Hours, in.Reason, user.Username)
+	id, err := s.storage.InsertOvertime(ctx, user.Id, period, in.Hours, in.Reason, user.Actor())
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddOvertime/ Failed InsertOvertime")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "overtime.create",
+		EntityType: "overtime",
+		EntityId:   strconv.Itoa(id),
+		After:      map[string]any{"user_id": user.Id, "period": period, "hours": in.Hours, "reason": in.Reason},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventOvertimeSubmitted, aggregateEmployee, strconv.Itoa(user.Id),
+		map[string]any{"id": id, "user_id": user.Id, "period": period, "hours": in.Hours, "reason": in.Reason}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("AddOvertime/ failed to commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
@@ -261,13 +370,13 @@ func (s *Service) CheckoutAttendance(ctx context.Context, in *lib.CheckoutAttend
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("CheckoutAttendance/ unauthorized access - user missing in context")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return &resp
 	}
 
 	if in.Period.IsZero() {
 		log.Warn(in.Trace).Msg("CheckoutAttendance/ period missing")
-		resp.Message = "Wajib pilih periode waktu overtime"
+		resp.Fail(apperror.CodeInvalidInput, "Wajib pilih periode waktu overtime")
 		return &resp
 	}
 
@@ -277,21 +386,36 @@ func (s *Service) CheckoutAttendance(ctx context.Context, in *lib.CheckoutAttend
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("CheckoutAttendance/ Failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
-	err = s.storage.UpdateAttendanceCheckout(ctx, user.Id, today, time, user.Username)
+	err = s.storage.UpdateAttendanceCheckout(ctx, user.Id, today, time, user.Actor())
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("CheckoutAttendance/ failed to update checkout")
-		resp.Message = "Anda belum check-in atau sudah checkout"
+		resp.Fail(apperror.CodeAttendanceNotChecked, "Anda belum check-in atau sudah checkout")
+		return &resp
+	}
+
+	// checkout only succeeds on an attendance without checkout
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "attendance.checkout",
+		EntityType: "attendance",
+		EntityId:   attendanceEntityId(user.Id, today),
+		Before:     map[string]any{"check_out": nil},
+		After:      map[string]any{"check_out": time},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("CheckoutAttendance/ failed to commit")
-		resp.Message = "commit error"
+		resp.Fail(apperror.CodeInternal, "commit error")
 		return &resp
 	}
 
@@ -299,62 +423,172 @@ func (s *Service) CheckoutAttendance(ctx context.Context, in *lib.CheckoutAttend
 	return &resp
 }
 
+func (s *Service) CloseOpenAttendances(ctx context.Context, in *lib.CloseOpenAttendancesIn) *lib.CloseOpenAttendancesOut {
+	resp := &lib.CloseOpenAttendancesOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("CloseOpenAttendances/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("CloseOpenAttendances/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if in.Date.IsZero() {
+		log.Warn(in.Trace).Msg("CloseOpenAttendances/ date missing")
+		resp.Fail(apperror.CodeInvalidInput, "Tanggal wajib diisi")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CloseOpenAttendances/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	closed, err := s.storage.CloseOpenAttendances(ctx, in.Date, in.ShiftEnd.Format("15:04:05"), user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CloseOpenAttendances/ close attendances failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	for _, attendance := range closed {
+		if !s.audit(ctx, &auditLib.RecordIn{
+			Trace:      in.Trace,
+			Action:     "attendance.auto_close",
+			EntityType: "attendance",
+			EntityId:   attendanceEntityId(attendance.UserId, attendance.Periode),
+			Before:     map[string]any{"check_out": nil, "auto_closed": false},
+			After:      map[string]any{"check_out": attendance.CheckoutTime.Time.Format("15:04:05"), "auto_closed": true},
+		}) {
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("CloseOpenAttendances/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Closed = closed
+	return resp
+}
+
+func (s *Service) AttendanceUserIds(ctx context.Context, in *lib.AttendanceUserIdsIn) *lib.AttendanceUserIdsOut {
+	resp := &lib.AttendanceUserIdsOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("AttendanceUserIds/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	attendances, err := s.storage.GetAllAttendanceByPeriod(ctx, in.Date, in.Date)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("AttendanceUserIds/ get attendances failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Result = make(map[int]bool, len(attendances))
+	for _, attendance := range attendances {
+		resp.Result[attendance.UserId] = true
+	}
+	resp.Success = true
+	return resp
+}
+
 func (s *Service) SubmitReimbursement(ctx context.Context, in *lib.SubmitReimbursementIn) *lib.SubmitReimbursementOut {
 	resp := lib.SubmitReimbursementOut{}
 
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("SubmitReimbursement/ unauthorized access - user missing in context")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return &resp
 	}
 
 	if in.Period.IsZero() {
 		log.Warn(in.Trace).Msg("SubmitReimbursement/ period is missing")
-		resp.Message = "Periode wajib diisi"
+		resp.Fail(apperror.CodeInvalidInput, "Periode wajib diisi")
 		return &resp
 	}
 
 	if in.Amount <= 0 {
 		log.Warn(in.Trace).Msg("SubmitReimbursement/ invalid amount")
-		resp.Message = "Jumlah reimbursement harus lebih dari 0"
+		resp.Fail(apperror.CodeInvalidInput, "Jumlah reimbursement harus lebih dari 0")
 		return &resp
 	}
 
 	if in.Description == "" {
 		log.Warn(in.Trace).Msg("SubmitReimbursement/ description is empty")
-		resp.Message = "Deskripsi reimbursement wajib diisi"
+		resp.Fail(apperror.CodeInvalidInput, "Deskripsi reimbursement wajib diisi")
 		return &resp
 	}
 
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitReimbursement/ failed to begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
-	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, in.Period)
+	payrollExists, err := s.storage.IsPayrollAlreadyRun(ctx, user.Id, in.Period)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitReimbursement/ failed to check payroll existence")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	if payrollExists {
 		log.Warn(in.Trace).Msg("SubmitReimbursement/ cannot update data after payroll is processed")
-		resp.Message = "Data tidak bisa diubah karena payroll sudah dijalankan"
+		resp.Fail(apperror.CodePayrollLocked, "Data tidak bisa diubah karena payroll sudah dijalankan")
 		return &resp
 	}
 
-	id, err := s.storage.InsertReimbursement(ctx, user.Id, in.Period, in.Amount, in.Description, user.Username)
+	id, err := s.storage.InsertReimbursement(ctx, user.Id, in.Period, in.Amount, in.Description, user.Actor())
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitReimbursement/ insert error")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "reimbursement.create",
+		EntityType: "reimbursement",
+		EntityId:   strconv.Itoa(id),
+		After:      map[string]any{"user_id": user.Id, "period": in.Period, "amount": in.Amount, "description": in.Description},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventReimbursementSubmitted, aggregateEmployee, strconv.Itoa(user.Id),
+		map[string]any{"id": id, "user_id": user.Id, "period": in.Period, "amount": in.Amount, "description": in.Description}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("SubmitReimbursement/ commit error")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
@@ -364,19 +598,36 @@ func (s *Service) SubmitReimbursement(ctx context.Context, in *lib.SubmitReimbur
 }
 
 func (s *Service) RunPayroll(ctx context.Context, in *lib.RunPayrollIn) *lib.RunPayrollOut {
+	start := time.Now()
 	resp := lib.RunPayrollOut{}
 
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("RunPayroll/ unauthorized")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("RunPayroll/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
 		return &resp
 	}
 
 	// Cek periode valid
 	if in.PeriodStart.IsZero() || in.PeriodEnd.IsZero() || in.PeriodEnd.Before(in.PeriodStart) {
 		log.Warn(in.Trace).Msg("RunPayroll/ invalid period")
-		resp.Message = "Periode tidak valid"
+		resp.Fail(apperror.CodeInvalidInput, "Periode tidak valid")
+		return &resp
+	}
+
+	payrollType := in.Type
+	if payrollType == "" {
+		payrollType = data.PayrollRegular
+	}
+	if msg := validateRunPayroll(payrollType, in); msg != "" {
+		log.Warn(in.Trace).Str("type", string(payrollType)).Str("reason", msg).Msg("RunPayroll/ invalid input")
+		resp.Fail(apperror.CodeInvalidInput, msg)
 		return &resp
 	}
 
@@ -386,27 +637,211 @@ func (s *Service) RunPayroll(ctx context.Context, in *lib.RunPayrollIn) *lib.Run
 
 	if !userSalaries.Success {
 		log.Warn(in.Trace).Msg("RunPayroll/ invalid user salaries")
-		resp.Message = "tidak ditemukan employee"
+		resp.Fail(apperror.CodeUserNotFound, "tidak ditemukan employee")
+		return &resp
+	}
+
+	salaries := userSalaries.Result
+	for userId := range in.Amounts {
+		if _, ok := salaries[userId]; !ok {
+			log.Warn(in.Trace).Int("userId", userId).Msg("RunPayroll/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+	}
+	for _, userId := range in.UserIds {
+		if _, ok := salaries[userId]; !ok {
+			log.Warn(in.Trace).Int("userId", userId).Msg("RunPayroll/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+	}
+
+	taxProfiles := s.userService.UserTaxProfiles(ctx, &userLib.UserTaxProfilesIn{
+		Trace: in.Trace,
+	})
+	if !taxProfiles.Success {
+		log.Warn(in.Trace).Msg("RunPayroll/ failed get tax profiles")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("RunPayroll/ begin tx failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	var lines []*payrollLine
+	switch payrollType {
+	case data.PayrollRegular, data.PayrollFinalSettlement:
+		// a concurrent run of an overlapping period waits until this one commits, then sees who is settled
+		if err := s.storage.LockPayrollPeriod(ctx, in.PeriodStart, in.PeriodEnd); err != nil {
+			log.Error(in.Trace).Err(err).Msg("RunPayroll/ lock payroll period failed")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		lines, resp.Skipped, err = s.attendancePayrollLines(ctx, salaries, in)
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("RunPayroll/ calculate salary failed")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	default:
+		lines = amountPayrollLines(payrollType, in.Amounts)
+	}
 
-	salaries := userSalaries.Result
+	if len(lines) == 0 {
+		log.Warn(in.Trace).Msg("RunPayroll/ no employee to pay")
+		resp.Fail(apperror.CodeNoEligibleEmployee, "Tidak ada karyawan yang bisa diproses pada payroll ini")
+		return &resp
+	}
+
+	// tax already withheld by other payroll paid in the same month, eg: THR or bonus
+	paidThisMonth, err := s.storage.GetMonthlyTaxByUser(ctx, in.PeriodEnd.Year(), int(in.PeriodEnd.Month()))
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunPayroll/ error monthly tax")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	var totalAttendance, totalOvertime, totalReimbursement, totalSalaryThisPeriod int
+	for _, line := range lines {
+		line.tax = calculateWithholdingTax(taxProfiles.Result[line.userId], paidThisMonth[line.userId], line.taxableGross())
+		if withholdsBpjs(payrollType) {
+			line.bpjs = common.EmployeeBpjs(line.baseSalary)
+		}
+		totalAttendance += line.attendanceCount
+		totalOvertime += line.overtimeHours
+		totalReimbursement += line.reimbursement
+		totalSalaryThisPeriod += line.totalSalary()
+	}
+
+	var loanDeductions, deductionsPerLoan map[int]int
+	if deductsLoan(payrollType) {
+		netPay := make(map[int]int, len(lines))
+		for _, line := range lines {
+			netPay[line.userId] = line.netPay()
+		}
+
+		deductions := s.loanService.PayrollDeductions(ctx, &loanLib.PayrollDeductionsIn{
+			Trace:          in.Trace,
+			PeriodEnd:      in.PeriodEnd,
+			FullSettlement: payrollType == data.PayrollFinalSettlement,
+			NetPay:         netPay,
+		})
+		if !deductions.Success {
+			log.Warn(in.Trace).Str("reason", deductions.Message).Msg("RunPayroll/ failed get loan deductions")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		loanDeductions = deductions.Result
+		deductionsPerLoan = deductions.Loans
+
+		for _, line := range lines {
+			line.loanDeduction = loanDeductions[line.userId]
+		}
+	}
+
+	if in.DryRun {
+		resp.Success = true
+		for _, line := range lines {
+			resp.Items = append(resp.Items, line.item(0))
+		}
+		return &resp
+	}
+
+	payrollId, err := s.storage.InsertPayroll(ctx, payrollType, in.PeriodStart, in.PeriodEnd, totalAttendance, totalOvertime,
+		totalReimbursement, totalSalaryThisPeriod, in.Note, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunPayroll/ insert payroll failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	items := make([]*data.PayrollItem, 0, len(lines))
+	for _, line := range lines {
+		item := line.item(payrollId)
+		item.Id, err = s.storage.InsertPayrollItem(ctx, payrollId, line.userId, line.attendanceCount, line.overtimeHours,
+			line.baseSalary, line.overtime, line.bonus, line.reimbursement, line.tax, line.bpjs, line.loanDeduction, line.totalSalary(), user.Actor())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msgf("RunPayroll/ insert payroll item user_id=%d failed", line.userId)
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		items = append(items, item)
+	}
+
+	// the loan outstanding is reduced in the payroll transaction, both are stored or none
+	if len(deductionsPerLoan) > 0 {
+		recorded := s.loanService.RecordPayrollDeductions(ctx, &loanLib.RecordPayrollDeductionsIn{
+			Trace:      in.Trace,
+			PayrollId:  payrollId,
+			Deductions: deductionsPerLoan,
+		})
+		if !recorded.Success {
+			log.Error(in.Trace).Str("reason", recorded.Message).Msg("RunPayroll/ failed record loan deductions")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	// the draft of the period is paid by this payroll, an employee subset does not pay it
+	if payrollType == data.PayrollRegular && len(in.UserIds) == 0 {
+		if err := s.storage.SetPayrollDraftPayroll(ctx, in.PeriodStart, in.PeriodEnd, payrollId); err != nil {
+			log.Error(in.Trace).Err(err).Msg("RunPayroll/ link payroll draft failed")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payroll.run",
+		EntityType: "payroll",
+		EntityId:   strconv.Itoa(payrollId),
+		After: map[string]any{
+			"type": payrollType, "period_start": in.PeriodStart, "period_end": in.PeriodEnd,
+			"employees": len(lines), "total_salary": totalSalaryThisPeriod, "note": in.Note,
+		},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventPayrollFinalized, aggregatePayroll, strconv.Itoa(payrollId), map[string]any{
+		"id": payrollId, "type": payrollType, "period_start": in.PeriodStart, "period_end": in.PeriodEnd,
+		"employees": len(lines), "total_salary": totalSalaryThisPeriod, "note": in.Note,
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunPayroll/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	payrollRunDuration.WithLabelValues(string(payrollType)).Observe(time.Since(start).Seconds())
+
+	resp.Success = true
+	resp.PayrollId = payrollId
+	resp.Items = items
+	return &resp
+}
+
+// attendancePayrollLines calculates the prorated salary, overtime and reimbursement of the period.
+// Employee already paid by a regular or final settlement payroll in the period is skipped and returned.
+func (s *Service) attendancePayrollLines(ctx context.Context, salaries map[int]int, in *lib.RunPayrollIn) ([]*payrollLine, []int, error) {
 	// ambil semua user yang punya attendance
 	attendances, err := s.storage.GetAllAttendanceByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ fetch attendance summary failed")
-		resp.Message = "internal error"
-		return &resp
+		return nil, nil, err
 	}
-	totalAttendance := len(attendances)
 
 	// key is userId and value total attendance in this period
 	userAttendanceMap := make(map[int]int)
@@ -414,75 +849,172 @@ func (s *Service) RunPayroll(ctx context.Context, in *lib.RunPayrollIn) *lib.Run
 		userAttendanceMap[att.UserId]++
 	}
 
-	baseSalariesPerUser := calculateProratedSalary(salaries, userAttendanceMap, in.PeriodStart, in.PeriodEnd)
-	totalBaseSalariesThisMonth := sumValuesMap(baseSalariesPerUser)
+	usersOvertime, err := s.storage.GetOvertimeHoursByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// total overtime (jam)
-	totalOvertime, err := s.storage.GetTotalOvertimeByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
+	totalReimbursementPerUser, err := s.storage.GetReimbursementTotalsByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ error total overtime")
-		resp.Message = "internal error"
-		return &resp
+		return nil, nil, err
 	}
 
-	usersOvertime, err := s.storage.GetOvertimeHoursByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
+	settled, err := s.storage.GetSettledUserIdsByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ error total overtime hours")
-		resp.Message = "internal error"
-		return &resp
+		return nil, nil, err
 	}
+
+	baseSalariesPerUser := calculateProratedSalary(salaries, userAttendanceMap, in.PeriodStart, in.PeriodEnd)
 	baseSalaryOverTimes := calculateOvertimeSalary(salaries, userAttendanceMap, usersOvertime, in.PeriodStart, in.PeriodEnd)
-	totalOverTimeSalary := sumValuesMap(baseSalaryOverTimes)
 
-	// total reimbursement (rupiah)
-	totalReimbursement, err := s.storage.GetTotalReimbursementByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
-	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ error total reimbursement")
-		resp.Message = "internal error"
-		return &resp
+	selected := make(map[int]bool)
+	for _, userId := range in.UserIds {
+		selected[userId] = true
 	}
-	totalReimbursementPerUser, err := s.storage.GetReimbursementTotalsByPeriod(ctx, in.PeriodStart, in.PeriodEnd)
+
+	var lines []*payrollLine
+	var skipped []int
+	for userId := range userAttendanceMap {
+		if len(selected) > 0 && !selected[userId] {
+			continue
+		}
+		if settled[userId] {
+			skipped = append(skipped, userId)
+			continue
+		}
+
+		lines = append(lines, &payrollLine{
+			userId:          userId,
+			attendanceCount: userAttendanceMap[userId],
+			overtimeHours:   usersOvertime[userId],
+			baseSalary:      baseSalariesPerUser[userId],
+			overtime:        baseSalaryOverTimes[userId],
+			reimbursement:   totalReimbursementPerUser[userId],
+		})
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].userId < lines[j].userId })
+	sort.Ints(skipped)
+	return lines, skipped, nil
+}
+
+func (s *Service) CreatePayrollDraft(ctx context.Context, in *lib.CreatePayrollDraftIn) *lib.CreatePayrollDraftOut {
+	resp := &lib.CreatePayrollDraftOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("CreatePayrollDraft/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	// the same calculation as the regular payroll, RunPayroll checks the role and the period
+	preview := s.RunPayroll(ctx, &lib.RunPayrollIn{
+		Trace:       in.Trace,
+		Type:        data.PayrollRegular,
+		PeriodStart: in.PeriodStart,
+		PeriodEnd:   in.PeriodEnd,
+		DryRun:      true,
+	})
+	if !preview.Success {
+		log.Warn(in.Trace).Str("reason", preview.Message).Msg("CreatePayrollDraft/ calculate payroll failed")
+		resp.Failure = preview.Failure
+		return resp
+	}
+
+	draft := newPayrollDraft(in.PeriodStart, in.PeriodEnd, preview.Items)
+
+	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ error total reimbursement per user")
-		resp.Message = "internal error"
-		return &resp
+		log.Error(in.Trace).Err(err).Msg("CreatePayrollDraft/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
 	}
-	totalSalaryThisPeriod := totalBaseSalariesThisMonth + totalOverTimeSalary + totalReimbursement
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
-	payrollId, err := s.storage.InsertPayroll(ctx, in.PeriodStart, in.PeriodEnd, totalAttendance, totalOvertime, totalReimbursement, totalSalaryThisPeriod, user.Username)
+	created, err := s.storage.InsertPayrollDraft(ctx, draft, user.Actor())
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ insert payroll failed")
-		resp.Message = "internal error"
-		return &resp
+		log.Error(in.Trace).Err(err).Msg("CreatePayrollDraft/ insert draft failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if !created {
+		log.Info(in.Trace).Msg("CreatePayrollDraft/ period already has a draft")
+		resp.Success = true
+		return resp
 	}
 
-	for userId := range userAttendanceMap {
-		attendanceCount := userAttendanceMap[userId]
-		overtimeHours := usersOvertime[userId]
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payroll_draft.create",
+		EntityType: "payroll_draft",
+		EntityId:   strconv.Itoa(draft.Id),
+		After: map[string]any{
+			"period_start": draft.PeriodStart, "period_end": draft.PeriodEnd,
+			"employees": draft.Employees, "total_salary": draft.TotalSalary,
+		},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
 
-		userSalary := baseSalariesPerUser[userId]
-		userOvertimeSalary := baseSalaryOverTimes[userId]
-		userReimbursement := totalReimbursementPerUser[userId]
-		userTotalSalary := userSalary + userOvertimeSalary + userReimbursement
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreatePayrollDraft/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
 
-		_, err = s.storage.InsertPayrollItem(ctx, payrollId, userId, attendanceCount, overtimeHours, userReimbursement, userTotalSalary, user.Username)
-		if err != nil {
-			log.Error(in.Trace).Err(err).Msgf("RunPayroll/ insert payroll item user_id=%d failed", userId)
-			resp.Message = "internal error"
-			return &resp
-		}
+	resp.Success = true
+	resp.Created = true
+	resp.Draft = draft
+	return resp
+}
+
+func (s *Service) ListPayrollDrafts(ctx context.Context, in *lib.ListPayrollDraftsIn) *lib.ListPayrollDraftsOut {
+	resp := &lib.ListPayrollDraftsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListPayrollDrafts/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListPayrollDrafts/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
 	}
 
-	err = tx.Commit(ctx)
+	limit := in.Limit
+	if limit < 0 {
+		log.Warn(in.Trace).Msg("ListPayrollDrafts/ invalid limit")
+		resp.Fail(apperror.CodeInvalidInput, "Limit tidak valid")
+		return resp
+	}
+	if limit == 0 {
+		limit = defaultDraftLimit
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("RunPayroll/ commit failed")
-		resp.Message = "internal error"
-		return &resp
+		log.Error(in.Trace).Err(err).Msg("ListPayrollDrafts/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	drafts, err := s.storage.GetPayrollDrafts(ctx, limit)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListPayrollDrafts/ get drafts failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
 	}
 
 	resp.Success = true
-	resp.PayrollId = payrollId
-	return &resp
+	resp.Result = drafts
+	return resp
 }
 
 func (s *Service) GenerateSelfPaySlip(ctx context.Context, in *lib.GenerateSelfPaySlipIn) *lib.GenerateSelfPaySlipOut {
@@ -491,13 +1023,13 @@ func (s *Service) GenerateSelfPaySlip(ctx context.Context, in *lib.GenerateSelfP
 	user, ok := contextutil.GetUser(ctx)
 	if !ok {
 		log.Warn(in.Trace).Msg("GenerateSelfPaySlip/ unauthorized")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return resp
 	}
 
 	if in.Month <= 0 || in.Month > 12 || in.Year <= 0 {
 		log.Warn(in.Trace).Msg("GenerateSelfPaySlip/ invalid input")
-		resp.Message = "Bulan atau tahun tidak valid"
+		resp.Fail(apperror.CodeInvalidInput, "Bulan atau tahun tidak valid")
 		return resp
 	}
 
@@ -505,53 +1037,67 @@ func (s *Service) GenerateSelfPaySlip(ctx context.Context, in *lib.GenerateSelfP
 	periodStart := time.Date(in.Year, time.Month(in.Month), 1, 0, 0, 0, 0, location)
 	periodEnd := periodStart.AddDate(0, 1, -1)
 
-	payroll, err := s.storage.GetPayrollByPeriod(ctx, periodStart, periodEnd)
+	payrolls, err := s.storage.GetPayrollsByPeriod(ctx, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateSelfPaySlip/ get payroll failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
-	if payroll == nil {
+	if len(payrolls) == 0 {
 		log.Warn(in.Trace).Msg("GenerateSelfPaySlip/ payroll not found")
-		resp.Message = "Payroll belum tersedia untuk periode ini"
+		resp.Fail(apperror.CodePayrollNotFound, "Payroll belum tersedia untuk periode ini")
 		return resp
 	}
 
-	item, err := s.storage.GetPayrollItemByPayrollIDAndUserID(ctx, payroll.Id, user.Id)
+	runs, err := s.storage.GetPayslipRunsByPeriod(ctx, user.Id, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateSelfPaySlip/ get payroll item failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
-	if item == nil {
+	if len(runs) == 0 {
 		log.Warn(in.Trace).Msg("GenerateSelfPaySlip/ payroll item not found")
-		resp.Message = "Data payslip tidak tersedia"
+		resp.Fail(apperror.CodePayrollNotFound, "Data payslip tidak tersedia")
 		return resp
 	}
 
 	reimbursements, err := s.storage.GetReimbursementsByUserAndPeriod(ctx, user.Id, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateSelfPaySlip/ get reimbursements failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
 
 	overtimes, err := s.storage.GetOvertimesByUserAndPeriod(ctx, user.Id, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateSelfPaySlip/ get overtimes failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
 
 	attendances, err := s.storage.GetAttendancesByUserAndPeriods(ctx, user.Id, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateSelfPaySlip/ get attendances failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	balances := s.loanService.OutstandingBalances(ctx, &loanLib.OutstandingBalancesIn{
+		Trace:  in.Trace,
+		UserId: user.Id,
+	})
+	if !balances.Success {
+		log.Warn(in.Trace).Str("reason", balances.Message).Msg("GenerateSelfPaySlip/ failed get loan outstanding")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
 
 	resp.Success = true
-	resp.TotalSalary = item.TotalSalary
+	resp.LoanOutstanding = balances.Result[user.Id]
+	for _, run := range runs {
+		resp.TotalSalary += run.TotalSalary
+	}
+	resp.Runs = runs
 	resp.ListReimbursement = reimbursements
 	resp.ListOvertimes = overtimes
 	resp.ListAttendAnce = attendances
@@ -564,13 +1110,13 @@ func (s *Service) GenerateAllPaySlips(ctx context.Context, in *lib.GenerateAllPa
 	user, ok := contextutil.GetUser(ctx)
 	if !ok || user.Role != data.RAdmin {
 		log.Warn(in.Trace).Msg("GenerateAllPaySlips/ unauthorized")
-		resp.Message = "unauthorized"
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
 		return resp
 	}
 
 	if in.Month <= 0 || in.Month > 12 || in.Year <= 0 {
 		log.Warn(in.Trace).Msg("GenerateAllPaySlips/ invalid input")
-		resp.Message = "Bulan atau tahun tidak valid"
+		resp.Fail(apperror.CodeInvalidInput, "Bulan atau tahun tidak valid")
 		return resp
 	}
 
@@ -578,37 +1124,50 @@ func (s *Service) GenerateAllPaySlips(ctx context.Context, in *lib.GenerateAllPa
 	periodStart := time.Date(in.Year, time.Month(in.Month), 1, 0, 0, 0, 0, location)
 	periodEnd := periodStart.AddDate(0, 1, -1)
 
-	payroll, err := s.storage.GetPayrollByPeriod(ctx, periodStart, periodEnd)
+	payrolls, err := s.storage.GetPayrollsByPeriod(ctx, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateAllPaySlips/ get payroll failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
-	if payroll == nil {
+	if len(payrolls) == 0 {
 		log.Warn(in.Trace).Msg("GenerateAllPaySlips/ payroll not found")
-		resp.Message = "Payroll belum tersedia untuk periode ini"
+		resp.Fail(apperror.CodePayrollNotFound, "Payroll belum tersedia untuk periode ini")
 		return resp
 	}
 
-	items, err := s.storage.GetPayrollItemsByPayrollID(ctx, payroll.Id)
+	runs, err := s.storage.GetPayslipRunsByPeriod(ctx, 0, periodStart, periodEnd)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("GenerateAllPaySlips/ get payroll items failed")
-		resp.Message = "internal error"
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return resp
 	}
 
+	balances := s.loanService.OutstandingBalances(ctx, &loanLib.OutstandingBalancesIn{
+		Trace: in.Trace,
+	})
+	if !balances.Success {
+		log.Warn(in.Trace).Str("reason", balances.Message).Msg("GenerateAllPaySlips/ failed get loan outstanding")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	payslips := groupPayslipRuns(runs)
 	var totalSalaryAll int
-	var payslips []*data.UserPayslip
-	for _, item := range items {
-		payslip := &data.UserPayslip{
-			UserID:           item.UserId,
-			TotalSalary:      item.TotalSalary,
-			AttendanceCount:  item.AttendanceCount,
-			OvertimeHours:    item.OvertimeHours,
-			ReimbursementSum: item.ReimbursementTotal,
-		}
-		payslips = append(payslips, payslip)
-		totalSalaryAll += item.TotalSalary
+	for _, payslip := range payslips {
+		payslip.LoanOutstanding = balances.Result[payslip.UserID]
+		totalSalaryAll += payslip.TotalSalary
+	}
+
+	// salary of every employee is sensitive, record who looked at it
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payslip.read_all",
+		EntityType: "payslip",
+		EntityId:   fmt.Sprintf("%04d-%02d", in.Year, in.Month),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
 	}
 
 	resp.Success = true
@@ -616,3 +1175,354 @@ func (s *Service) GenerateAllPaySlips(ctx context.Context, in *lib.GenerateAllPa
 	resp.ListUserPayslips = payslips
 	return resp
 }
+
+func (s *Service) GetPayrollDetail(ctx context.Context, in *lib.GetPayrollDetailIn) *lib.GetPayrollDetailOut {
+	resp := &lib.GetPayrollDetailOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("GetPayrollDetail/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.PayrollId <= 0 {
+		log.Warn(in.Trace).Msg("GetPayrollDetail/ invalid payroll id")
+		resp.Fail(apperror.CodeInvalidInput, "Payroll tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetPayrollDetail/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	payroll, err := s.storage.GetPayrollById(ctx, in.PayrollId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetPayrollDetail/ get payroll failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if payroll == nil {
+		log.Warn(in.Trace).Int("payrollId", in.PayrollId).Msg("GetPayrollDetail/ payroll not found")
+		resp.Fail(apperror.CodePayrollNotFound, "Payroll tidak ditemukan")
+		return resp
+	}
+
+	items, err := s.storage.GetPayrollItemsByPayrollID(ctx, payroll.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GetPayrollDetail/ get payroll items failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payroll.read",
+		EntityType: "payroll",
+		EntityId:   strconv.Itoa(payroll.Id),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Payroll = payroll
+	resp.Items = items
+	return resp
+}
+
+func (s *Service) YearlyPayrollSummary(ctx context.Context, in *lib.YearlyPayrollSummaryIn) *lib.YearlyPayrollSummaryOut {
+	resp := &lib.YearlyPayrollSummaryOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("YearlyPayrollSummary/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("YearlyPayrollSummary/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("YearlyPayrollSummary/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	summaries, err := s.storage.GetYearlyPayrollSummary(ctx, in.Year)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("YearlyPayrollSummary/ get summary failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payroll_summary.read",
+		EntityType: "payroll_summary",
+		EntityId:   strconv.Itoa(in.Year),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = summaries
+	return resp
+}
+
+func (s *Service) RunTHR(ctx context.Context, in *lib.RunTHRIn) *lib.RunTHROut {
+	start := time.Now()
+	resp := &lib.RunTHROut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("RunTHR/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.PayDate.IsZero() || len(in.Holidays) == 0 {
+		log.Warn(in.Trace).Msg("RunTHR/ invalid input")
+		resp.Fail(apperror.CodeInvalidInput, "Tanggal pembayaran dan hari raya wajib diisi")
+		return resp
+	}
+
+	for religion, holiday := range in.Holidays {
+		if !data.IsValidReligion(religion) {
+			log.Warn(in.Trace).Str("religion", string(religion)).Msg("RunTHR/ invalid religion")
+			resp.Fail(apperror.CodeInvalidInput, "Agama tidak valid")
+			return resp
+		}
+		// THR must be paid at the latest 7 days before the holiday
+		if in.PayDate.After(holiday.AddDate(0, 0, -7)) {
+			log.Warn(in.Trace).Str("religion", string(religion)).Msg("RunTHR/ pay date too late")
+			resp.Fail(apperror.CodeTHRDeadline, "THR wajib dibayar paling lambat 7 hari sebelum hari raya")
+			return resp
+		}
+	}
+
+	employments := s.userService.UserEmployments(ctx, &userLib.UserEmploymentsIn{
+		Trace: in.Trace,
+	})
+	if !employments.Success {
+		log.Warn(in.Trace).Msg("RunTHR/ failed get employments")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	taxProfiles := s.userService.UserTaxProfiles(ctx, &userLib.UserTaxProfilesIn{
+		Trace: in.Trace,
+	})
+	if !taxProfiles.Success {
+		log.Warn(in.Trace).Msg("RunTHR/ failed get tax profiles")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunTHR/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// THR is paid once a year, employee already paid by previous run is skipped
+	paidItems, err := s.storage.GetPayrollItemsByTypeAndYear(ctx, data.PayrollTHR, in.PayDate.Year())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunTHR/ get paid thr failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	alreadyPaid := make(map[int]bool)
+	for _, item := range paidItems {
+		alreadyPaid[item.UserId] = true
+	}
+
+	paidThisMonth, err := s.storage.GetMonthlyTaxByUser(ctx, in.PayDate.Year(), int(in.PayDate.Month()))
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunTHR/ error monthly tax")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	type thrItem struct {
+		userId int
+		amount int
+		tax    int
+	}
+	var items []thrItem
+	var totalTHR int
+	for _, e := range employments.Result {
+		if e.Religion == "" {
+			resp.MissingReligion = append(resp.MissingReligion, e.UserId)
+			continue
+		}
+		holiday, ok := in.Holidays[e.Religion]
+		if !ok || alreadyPaid[e.UserId] {
+			continue
+		}
+
+		amount, _ := calculateTHR(e.BaseSalary, e.JoinDate, holiday)
+		if amount == 0 {
+			continue
+		}
+
+		tax := calculateWithholdingTax(taxProfiles.Result[e.UserId], paidThisMonth[e.UserId], amount)
+		items = append(items, thrItem{userId: e.UserId, amount: amount, tax: tax})
+		totalTHR += amount
+	}
+
+	if len(items) == 0 {
+		log.Warn(in.Trace).Msg("RunTHR/ no eligible employee")
+		resp.Fail(apperror.CodeNoEligibleEmployee, "Tidak ada karyawan yang berhak menerima THR")
+		return resp
+	}
+
+	payrollId, err := s.storage.InsertPayroll(ctx, data.PayrollTHR, in.PayDate, in.PayDate, 0, 0, 0, totalTHR, "", user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunTHR/ insert payroll failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	for _, item := range items {
+		_, err = s.storage.InsertPayrollItem(ctx, payrollId, item.userId, 0, 0,
+			0, 0, item.amount, 0, item.tax, 0, 0, item.amount, user.Actor())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msgf("RunTHR/ insert payroll item user_id=%d failed", item.userId)
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return resp
+		}
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "payroll.run",
+		EntityType: "payroll",
+		EntityId:   strconv.Itoa(payrollId),
+		After: map[string]any{
+			"type": data.PayrollTHR, "pay_date": in.PayDate, "employees": len(items), "total_salary": totalTHR,
+		},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if !s.publish(ctx, in.Trace, data.EventPayrollFinalized, aggregatePayroll, strconv.Itoa(payrollId), map[string]any{
+		"id": payrollId, "type": data.PayrollTHR, "pay_date": in.PayDate, "employees": len(items), "total_salary": totalTHR,
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RunTHR/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	payrollRunDuration.WithLabelValues(string(data.PayrollTHR)).Observe(time.Since(start).Seconds())
+
+	resp.Success = true
+	resp.PayrollId = payrollId
+	resp.Total = len(items)
+	return resp
+}
+
+func (s *Service) GenerateSelfTHRSlip(ctx context.Context, in *lib.GenerateSelfTHRSlipIn) *lib.GenerateSelfTHRSlipOut {
+	resp := &lib.GenerateSelfTHRSlipOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("GenerateSelfTHRSlip/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("GenerateSelfTHRSlip/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	items, err := s.storage.GetPayrollItemsByTypeAndYear(ctx, data.PayrollTHR, in.Year)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GenerateSelfTHRSlip/ get payroll items failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	for _, item := range items {
+		if item.UserId == user.Id {
+			resp.Success = true
+			resp.Payslip = thrPayslip(item)
+			return resp
+		}
+	}
+
+	log.Warn(in.Trace).Msg("GenerateSelfTHRSlip/ thr not found")
+	resp.Fail(apperror.CodePayrollNotFound, "THR belum tersedia untuk tahun ini")
+	return resp
+}
+
+func (s *Service) GenerateAllTHRSlips(ctx context.Context, in *lib.GenerateAllTHRSlipsIn) *lib.GenerateAllTHRSlipsOut {
+	resp := &lib.GenerateAllTHRSlipsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok || user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("GenerateAllTHRSlips/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+
+	if in.Year <= 0 {
+		log.Warn(in.Trace).Msg("GenerateAllTHRSlips/ invalid year")
+		resp.Fail(apperror.CodeInvalidInput, "Tahun tidak valid")
+		return resp
+	}
+
+	items, err := s.storage.GetPayrollItemsByTypeAndYear(ctx, data.PayrollTHR, in.Year)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("GenerateAllTHRSlips/ get payroll items failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if len(items) == 0 {
+		log.Warn(in.Trace).Msg("GenerateAllTHRSlips/ thr not found")
+		resp.Fail(apperror.CodePayrollNotFound, "THR belum tersedia untuk tahun ini")
+		return resp
+	}
+
+	for _, item := range items {
+		resp.ListUserPayslips = append(resp.ListUserPayslips, thrPayslip(item))
+		resp.TotalTHRAll += item.BonusAmount
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "thr_slip.read_all",
+		EntityType: "thr_slip",
+		EntityId:   strconv.Itoa(in.Year),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
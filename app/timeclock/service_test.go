@@ -3,19 +3,25 @@ package timeclock
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ariesmaulana/payroll/app/timeclock/lib"
 	"github.com/ariesmaulana/payroll/app/timeclock/mock_lib"
 
+	auditLib "github.com/ariesmaulana/payroll/app/audit/lib"
+	loanLib "github.com/ariesmaulana/payroll/app/loan/lib"
 	mocks "github.com/ariesmaulana/payroll/app/timeclock/mock_lib"
 	userLib "github.com/ariesmaulana/payroll/app/user/lib"
 
 	"github.com/ariesmaulana/payroll/common"
 	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
 	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -31,7 +37,7 @@ func TestServiceAddAttendancePeriod(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
 
 	// setup test users & contexts
 	userId := 999
@@ -160,15 +166,14 @@ func TestServiceAddAttendancePeriod(t *testing.T) {
 	}
 }
 
-func setupUserContext(role data.UserRole) (context.Context, int, string) {
-	userID := 999
-	username := "test_user"
-	ctx := contextutil.WithUser(context.Background(), &contextutil.AuthUser{
-		Id:       userID,
-		Username: username,
-		Role:     role,
-	})
-	return ctx, userID, username
+// testUserId is the user of the service contexts, it is not one of the seeded users
+const testUserId = 999
+
+// newAuditServiceMock accepts every audit record, the audit trail itself is tested in the audit app
+func newAuditServiceMock(ctrl *gomock.Controller) *mocks.MockAuditService {
+	auditServiceMock := mocks.NewMockAuditService(ctrl)
+	auditServiceMock.EXPECT().Record(gomock.Any(), gomock.Any()).Return(&auditLib.RecordOut{Success: true}).AnyTimes()
+	return auditServiceMock
 }
 
 func TestServiceSubmitAttendance(t *testing.T) {
@@ -181,9 +186,9 @@ func TestServiceSubmitAttendance(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
 
-	ctx, _, _ := setupUserContext(data.REmployee)
+	ctx := test.UserContext(context.Background(), testUserId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "submit-attendance-test"}
 	validDate := time.Date(2025, 6, 17, 9, 0, 0, 0, time.UTC)
 	weekend := time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)
@@ -243,9 +248,11 @@ func TestServiceAddOvertime(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
 
-	ctx, userId, userName := setupUserContext(data.REmployee)
+	userId := testUserId
+	userName := test.Username
+	ctx := test.UserContext(context.Background(), userId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "add-overtime-test"}
 	validPeriod := common.NewDateTime(2025, 1, 1, 18, 0, 0)
 
@@ -336,9 +343,9 @@ func TestServiceCheckoutAttendance(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
 
-	ctx, _, _ := setupUserContext(data.REmployee)
+	ctx := test.UserContext(context.Background(), testUserId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "checkout-attendance-test"}
 	validDate := time.Date(2025, 6, 17, 18, 0, 0, 0, time.UTC)
 
@@ -390,9 +397,9 @@ func TestServiceSubmitReimbursement(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
 
-	ctx, _, _ := setupUserContext(data.REmployee)
+	ctx := test.UserContext(context.Background(), testUserId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "submit-reimbursement-test"}
 	validDate := time.Date(2025, 6, 17, 0, 0, 0, 0, time.UTC)
 
@@ -458,10 +465,12 @@ func TestServiceGenerateSelfPaySlip(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	loanServiceMock := mock_lib.NewMockLoanService(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, loanServiceMock, newAuditServiceMock(ctrl))
 
 	// Setup user dan payroll data
-	ctx, userId, _ := setupUserContext(data.REmployee)
+	userId := testUserId
+	ctx := test.UserContext(context.Background(), userId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "self-payslip-test"}
 
 	periodStart := common.NewDate(2025, 1, 1)
@@ -471,12 +480,24 @@ func TestServiceGenerateSelfPaySlip(t *testing.T) {
 	assert.Nil(t, err)
 	defer tx.Rollback(ctx)
 
-	payrollID, err := timeclockStorage.InsertPayroll(ctx, periodStart, periodEnd, 10, 5, 100000, 1000000, "admin")
+	payrollID, err := timeclockStorage.InsertPayroll(ctx, data.PayrollRegular, periodStart, periodEnd, 10, 5, 100000, 1000000, "", "admin")
+	assert.Nil(t, err)
+
+	_, err = timeclockStorage.InsertPayrollItem(ctx, payrollID, userId, 10, 5, 850000, 50000, 0, 100000, 0, 0, 0, 1000000, "admin")
+	assert.Nil(t, err)
+
+	// off-cycle bonus paid in the same month
+	bonusID, err := timeclockStorage.InsertPayroll(ctx, data.PayrollBonus, periodEnd, periodEnd, 0, 0, 0, 500000, "bonus kinerja", "admin")
 	assert.Nil(t, err)
 
-	_, err = timeclockStorage.InsertPayrollItem(ctx, payrollID, userId, 10, 5, 100000, 1000000, "admin")
+	_, err = timeclockStorage.InsertPayrollItem(ctx, bonusID, userId, 0, 0, 0, 0, 500000, 0, 10000, 0, 0, 500000, "admin")
 	assert.Nil(t, err)
 
+	loanServiceMock.EXPECT().
+		OutstandingBalances(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.OutstandingBalancesIn{})).
+		Return(&loanLib.OutstandingBalancesOut{Success: true, Result: map[int]int{userId: 250000}}).
+		AnyTimes()
+
 	scenarios := []struct {
 		name    string
 		ctx     context.Context
@@ -513,6 +534,14 @@ func TestServiceGenerateSelfPaySlip(t *testing.T) {
 			assert.Equal(t, sc.errMsg, resp.Message)
 		})
 	}
+
+	resp := service.GenerateSelfPaySlip(ctx, &lib.GenerateSelfPaySlipIn{Trace: trace, Month: 1, Year: 2025})
+	assert.Equal(t, 1500000, resp.TotalSalary)
+	assert.Len(t, resp.Runs, 2)
+	assert.Equal(t, data.PayrollRegular, resp.Runs[0].Type)
+	assert.Equal(t, data.PayrollBonus, resp.Runs[1].Type)
+	assert.Equal(t, 490000, resp.Runs[1].NetSalary)
+	assert.Equal(t, 250000, resp.LoanOutstanding)
 }
 
 func TestServiceGenerateAllPaySlips(t *testing.T) {
@@ -525,9 +554,10 @@ func TestServiceGenerateAllPaySlips(t *testing.T) {
 
 	// create mock user service
 	userServiceMock := mocks.NewMockServiceInterface(ctrl)
-	service := NewService(timeclockStorage, userServiceMock)
+	loanServiceMock := mock_lib.NewMockLoanService(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, loanServiceMock, newAuditServiceMock(ctrl))
 
-	ctx, _, _ := setupUserContext(data.RAdmin)
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
 	trace := &contextutil.Trace{TraceID: "admin-payslip-test"}
 
 	periodStart := common.NewDate(2025, 1, 1)
@@ -537,15 +567,20 @@ func TestServiceGenerateAllPaySlips(t *testing.T) {
 	assert.Nil(t, err)
 	defer tx.Rollback(ctx)
 
-	payrollID, err := timeclockStorage.InsertPayroll(ctx, periodStart, periodEnd, 10, 5, 100000, 1000000, "admin")
+	payrollID, err := timeclockStorage.InsertPayroll(ctx, data.PayrollRegular, periodStart, periodEnd, 10, 5, 100000, 1000000, "", "admin")
 	assert.Nil(t, err)
 
 	_ = []int{1, 2, 3}
 	for i := 1; i <= 3; i++ {
-		_, err := timeclockStorage.InsertPayrollItem(ctx, payrollID, i, 10, 2, 50000, 500000, "admin")
+		_, err := timeclockStorage.InsertPayrollItem(ctx, payrollID, i, 10, 2, 430000, 20000, 0, 50000, 0, 0, 0, 500000, "admin")
 		assert.Nil(t, err)
 	}
 
+	loanServiceMock.EXPECT().
+		OutstandingBalances(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.OutstandingBalancesIn{})).
+		Return(&loanLib.OutstandingBalancesOut{Success: true, Result: map[int]int{2: 750000}}).
+		AnyTimes()
+
 	scenarios := []struct {
 		name    string
 		ctx     context.Context
@@ -582,6 +617,11 @@ func TestServiceGenerateAllPaySlips(t *testing.T) {
 			assert.Equal(t, sc.errMsg, resp.Message)
 		})
 	}
+
+	resp := service.GenerateAllPaySlips(ctx, &lib.GenerateAllPaySlipsIn{Trace: trace, Month: 1, Year: 2025})
+	assert.Len(t, resp.ListUserPayslips, 3)
+	assert.Equal(t, 0, resp.ListUserPayslips[0].LoanOutstanding)
+	assert.Equal(t, 750000, resp.ListUserPayslips[1].LoanOutstanding)
 }
 
 func TestServiceRunPayroll(t *testing.T) {
@@ -594,11 +634,13 @@ func TestServiceRunPayroll(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userServiceMock := mock_lib.NewMockServiceInterface(ctrl)
-
-	service := NewService(timeclockStorage, userServiceMock)
+	loanServiceMock := mock_lib.NewMockLoanService(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, loanServiceMock, newAuditServiceMock(ctrl))
 
 	// Setup context dan user
-	ctx, _, userName := setupUserContext(data.RAdmin)
+	userName := test.Username
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	employeeCtx := test.UserContext(context.Background(), testUserId, data.REmployee)
 	trace := &contextutil.Trace{TraceID: "run-payroll-test"}
 
 	start := common.NewDate(2025, 1, 1)
@@ -646,6 +688,68 @@ func TestServiceRunPayroll(t *testing.T) {
 	}
 
 	scenarios := []testCase{
+		{
+			name: "fail loan deduction",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				PeriodStart: start,
+				PeriodEnd:   end,
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+							2: 2000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true}).Times(1)
+				loanServiceMock.EXPECT().
+					PayrollDeductions(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.PayrollDeductionsIn{})).
+					Return(&loanLib.PayrollDeductionsOut{Failure: apperror.Failed(apperror.CodeInternal, "internal error")}).Times(1)
+			},
+			expected: expected{
+				success: false,
+				message: "internal error",
+			},
+		},
+		{
+			// nothing is stored, the next scenario can still run the period
+			name: "success preview payroll",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				DryRun:      true,
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+							2: 2000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true}).Times(1)
+				loanServiceMock.EXPECT().
+					PayrollDeductions(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.PayrollDeductionsIn{})).
+					Return(&loanLib.PayrollDeductionsOut{Success: true, Result: map[int]int{1: 200000}}).Times(1)
+			},
+			expected: expected{
+				success: true,
+				message: "",
+			},
+		},
 		{
 			name: "success run payroll",
 			ctx:  ctx,
@@ -665,12 +769,205 @@ func TestServiceRunPayroll(t *testing.T) {
 							2: 2000000,
 						},
 					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{
+						Success: true,
+						Result: map[int]*data.UserTaxProfile{
+							1: {UserId: 1, PTKPStatus: common.PTKPK1},
+						},
+					}).Times(1)
+				loanServiceMock.EXPECT().
+					PayrollDeductions(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.PayrollDeductionsIn{})).
+					DoAndReturn(func(_ context.Context, in *loanLib.PayrollDeductionsIn) *loanLib.PayrollDeductionsOut {
+						assert.False(t, in.FullSettlement)
+						assert.Len(t, in.NetPay, 2)
+						return &loanLib.PayrollDeductionsOut{Success: true, Result: map[int]int{1: 200000}, Loans: map[int]int{7: 200000}}
+					}).Times(1)
+				loanServiceMock.EXPECT().
+					RecordPayrollDeductions(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.RecordPayrollDeductionsIn{})).
+					DoAndReturn(func(ctx context.Context, in *loanLib.RecordPayrollDeductionsIn) *loanLib.RecordPayrollDeductionsOut {
+						_, inPayrollTx := database.Conn(ctx, nil).(pgx.Tx)
+						assert.True(t, inPayrollTx)
+						assert.NotZero(t, in.PayrollId)
+						assert.Equal(t, map[int]int{7: 200000}, in.Deductions)
+						return &loanLib.RecordPayrollDeductionsOut{Success: true}
+					}).Times(1)
+			},
+			expected: expected{
+				success: true,
+				message: "",
+			},
+		},
+		{
+			name: "fail regular already run",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				PeriodStart: start,
+				PeriodEnd:   end,
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+							2: 2000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true}).Times(1)
+			},
+			expected: expected{
+				success: false,
+				message: "Tidak ada karyawan yang bisa diproses pada payroll ini",
+			},
+		},
+		{
+			name: "success bonus run in paid period",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				Type:        data.PayrollBonus,
+				PeriodStart: end,
+				PeriodEnd:   end,
+				Amounts:     map[int]int{1: 5000000},
+				Note:        "bonus kinerja",
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+							2: 2000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true}).Times(1)
 			},
 			expected: expected{
 				success: true,
 				message: "",
 			},
 		},
+		{
+			name: "success correction run",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				Type:        data.PayrollCorrection,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Amounts:     map[int]int{2: -100000},
+				Note:        "koreksi potongan",
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+							2: 2000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{Success: true}).Times(1)
+			},
+			expected: expected{
+				success: true,
+				message: "",
+			},
+		},
+		{
+			name: "fail bonus for unknown user",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				Type:        data.PayrollBonus,
+				PeriodStart: end,
+				PeriodEnd:   end,
+				Amounts:     map[int]int{99: 5000000},
+				Note:        "bonus kinerja",
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+						},
+					}).Times(1)
+			},
+			expected: expected{
+				success: false,
+				message: "User tidak ditemukan",
+			},
+		},
+		{
+			name: "fail final settlement for unknown user",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				Type:        data.PayrollFinalSettlement,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				UserIds:     []int{99},
+				Note:        "resign",
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+						},
+					}).Times(1)
+			},
+			expected: expected{
+				success: false,
+				message: "User tidak ditemukan",
+			},
+		},
+		{
+			name: "fail final settlement without employee",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				Type:        data.PayrollFinalSettlement,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Note:        "resign",
+			},
+			mock: func() {},
+			expected: expected{
+				success: false,
+				message: "Karyawan wajib dipilih untuk final settlement",
+			},
+		},
+		{
+			name: "fail not admin",
+			ctx:  employeeCtx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				PeriodStart: start,
+				PeriodEnd:   end,
+			},
+			mock: func() {},
+			expected: expected{
+				success: false,
+				message: "forbidden: Hanya admin yang bisa akses",
+			},
+		},
 		{
 			name: "fail unauthorized",
 			ctx:  context.Background(),
@@ -720,6 +1017,34 @@ func TestServiceRunPayroll(t *testing.T) {
 				message: "tidak ditemukan employee",
 			},
 		},
+		{
+			name: "fail tax profile",
+			ctx:  ctx,
+			in: &lib.RunPayrollIn{
+				Trace:       trace,
+				PeriodStart: start,
+				PeriodEnd:   end,
+			},
+			mock: func() {
+				userServiceMock.EXPECT().
+					UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+					Return(&userLib.UserSalaryOut{
+						Success: true,
+						Result: map[int]int{
+							1: 3000000,
+						},
+					}).Times(1)
+				userServiceMock.EXPECT().
+					UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+					Return(&userLib.UserTaxProfilesOut{
+						Success: false,
+					}).Times(1)
+			},
+			expected: expected{
+				success: false,
+				message: "internal error",
+			},
+		},
 	}
 
 	for _, sc := range scenarios {
@@ -732,3 +1057,508 @@ func TestServiceRunPayroll(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceRunPayrollConcurrent(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userServiceMock := mock_lib.NewMockServiceInterface(ctrl)
+	loanServiceMock := mock_lib.NewMockLoanService(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, loanServiceMock, newAuditServiceMock(ctrl))
+
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	trace := &contextutil.Trace{TraceID: "run-payroll-concurrent-test"}
+
+	start := common.NewDate(2025, 2, 1)
+	end := common.NewDate(2025, 2, 28)
+	for i := 0; i < 5; i++ {
+		date := start.AddDate(0, 0, i)
+		_, err := timeclockStorage.InsertAttendanceCheckin(ctx, 1, date, date.Add(9*time.Hour), test.Username)
+		assert.Nil(t, err)
+	}
+
+	userServiceMock.EXPECT().
+		UserSalary(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserSalaryIn{})).
+		Return(&userLib.UserSalaryOut{Success: true, Result: map[int]int{1: 3000000}}).Times(2)
+	userServiceMock.EXPECT().
+		UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+		Return(&userLib.UserTaxProfilesOut{Success: true}).Times(2)
+	// only the run that gets the period first pays the employee
+	loanServiceMock.EXPECT().
+		PayrollDeductions(gomock.Any(), gomock.AssignableToTypeOf(&loanLib.PayrollDeductionsIn{})).
+		Return(&loanLib.PayrollDeductionsOut{Success: true}).Times(1)
+
+	outs := make([]*lib.RunPayrollOut, 2)
+	var wg sync.WaitGroup
+	for i := range outs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outs[i] = service.RunPayroll(ctx, &lib.RunPayrollIn{Trace: trace, PeriodStart: start, PeriodEnd: end})
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NotEqual(t, outs[0].Success, outs[1].Success, "one run pays, the other finds the period settled")
+	for _, out := range outs {
+		if !out.Success {
+			assert.Equal(t, "Tidak ada karyawan yang bisa diproses pada payroll ini", out.Message)
+			assert.Equal(t, []int{1}, out.Skipped)
+		}
+	}
+}
+
+func TestServiceYearlyPayrollSummary(t *testing.T) {
+	t.Parallel()
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+	// setup gomock
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// create mock user service
+	userServiceMock := mocks.NewMockServiceInterface(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
+
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	employeeCtx := test.UserContext(context.Background(), testUserId, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "yearly-payroll-summary-test"}
+
+	tx, err := timeclockStorage.BeginTxWriter(ctx)
+	assert.Nil(t, err)
+	defer tx.Rollback(ctx)
+
+	for month := 1; month <= 2; month++ {
+		payrollID, err := timeclockStorage.InsertPayroll(ctx, data.PayrollRegular, common.NewDate(2025, month, 1), common.NewDate(2025, month+1, 1).AddDate(0, 0, -1), 10, 5, 100000, 1000000, "", "admin")
+		assert.Nil(t, err)
+		_, err = timeclockStorage.InsertPayrollItem(ctx, payrollID, 1, 10, 5, 850000, 50000, 0, 100000, 10000, 5000, 0, 1000000, "admin")
+		assert.Nil(t, err)
+	}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.YearlyPayrollSummaryIn
+		success bool
+		errMsg  string
+		total   int
+	}{
+		{
+			name:    "success",
+			ctx:     ctx,
+			in:      &lib.YearlyPayrollSummaryIn{Trace: trace, Year: 2025},
+			success: true,
+			total:   1,
+		},
+		{
+			name:    "success empty year",
+			ctx:     ctx,
+			in:      &lib.YearlyPayrollSummaryIn{Trace: trace, Year: 2024},
+			success: true,
+			total:   0,
+		},
+		{
+			name:    "fail invalid year",
+			ctx:     ctx,
+			in:      &lib.YearlyPayrollSummaryIn{Trace: trace},
+			success: false,
+			errMsg:  "Tahun tidak valid",
+		},
+		{
+			name:    "unauthorized",
+			ctx:     employeeCtx,
+			in:      &lib.YearlyPayrollSummaryIn{Trace: trace, Year: 2025},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resp := service.YearlyPayrollSummary(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, resp.Success)
+			assert.Equal(t, sc.errMsg, resp.Message)
+			assert.Len(t, resp.Result, sc.total)
+		})
+	}
+
+	resp := service.YearlyPayrollSummary(ctx, &lib.YearlyPayrollSummaryIn{Trace: trace, Year: 2025})
+	assert.Equal(t, &data.YearlyPayrollSummary{
+		UserId:             1,
+		FirstMonth:         1,
+		LastMonth:          2,
+		BaseSalaryAmount:   1700000,
+		OvertimeAmount:     100000,
+		ReimbursementTotal: 200000,
+		TaxAmount:          20000,
+		BpjsAmount:         10000,
+	}, resp.Result[0])
+}
+
+func TestServiceRunTHR(t *testing.T) {
+	t.Parallel()
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+	// setup gomock
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// create mock user service
+	userServiceMock := mocks.NewMockServiceInterface(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
+
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	employeeId := testUserId
+	employeeCtx := test.UserContext(context.Background(), employeeId, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "run-thr-test"}
+
+	idulFitri := common.NewDate(2025, 3, 31)
+	natal := common.NewDate(2025, 12, 25)
+
+	employments := &userLib.UserEmploymentsOut{
+		Success: true,
+		Result: []*data.UserEmployment{
+			{UserId: employeeId, BaseSalary: 12000000, JoinDate: common.NewDate(2020, 1, 2), Religion: data.ReligionIslam},
+			{UserId: 2, BaseSalary: 6000000, JoinDate: common.NewDate(2024, 9, 30), Religion: data.ReligionIslam},
+			{UserId: 3, BaseSalary: 6000000, JoinDate: common.NewDate(2025, 3, 10), Religion: data.ReligionIslam},
+			{UserId: 4, BaseSalary: 8000000, JoinDate: common.NewDate(2021, 1, 4), Religion: data.ReligionKatolik},
+			{UserId: 5, BaseSalary: 8000000, JoinDate: common.NewDate(2021, 1, 4)},
+		},
+	}
+	profiles := &userLib.UserTaxProfilesOut{
+		Success: true,
+		Result: map[int]*data.UserTaxProfile{
+			employeeId: {UserId: employeeId, PTKPStatus: common.PTKPTK0},
+		},
+	}
+	mockUserService := func() {
+		userServiceMock.EXPECT().
+			UserEmployments(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserEmploymentsIn{})).
+			Return(employments).Times(1)
+		userServiceMock.EXPECT().
+			UserTaxProfiles(gomock.Any(), gomock.AssignableToTypeOf(&userLib.UserTaxProfilesIn{})).
+			Return(profiles).Times(1)
+	}
+
+	scenarios := []struct {
+		name    string
+		ctx     context.Context
+		in      *lib.RunTHRIn
+		mock    func()
+		success bool
+		errMsg  string
+		total   int
+	}{
+		{
+			name: "success idul fitri",
+			ctx:  ctx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 3, 20),
+				Holidays: map[data.Religion]time.Time{data.ReligionIslam: idulFitri},
+			},
+			mock:    mockUserService,
+			success: true,
+			total:   2, // user 3 worked less than a month, user 4 is not islam, user 5 has no religion
+		},
+		{
+			name: "fail already paid this year",
+			ctx:  ctx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 3, 21),
+				Holidays: map[data.Religion]time.Time{data.ReligionIslam: idulFitri},
+			},
+			mock:    mockUserService,
+			success: false,
+			errMsg:  "Tidak ada karyawan yang berhak menerima THR",
+		},
+		{
+			name: "success natal",
+			ctx:  ctx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 12, 15),
+				Holidays: map[data.Religion]time.Time{data.ReligionKatolik: natal, data.ReligionProtestan: natal},
+			},
+			mock:    mockUserService,
+			success: true,
+			total:   1,
+		},
+		{
+			name: "fail paid less than 7 days before holiday",
+			ctx:  ctx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 3, 28),
+				Holidays: map[data.Religion]time.Time{data.ReligionIslam: idulFitri},
+			},
+			mock:    func() {},
+			success: false,
+			errMsg:  "THR wajib dibayar paling lambat 7 hari sebelum hari raya",
+		},
+		{
+			name: "fail invalid religion",
+			ctx:  ctx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 3, 20),
+				Holidays: map[data.Religion]time.Time{"jedi": idulFitri},
+			},
+			mock:    func() {},
+			success: false,
+			errMsg:  "Agama tidak valid",
+		},
+		{
+			name:    "fail without holiday",
+			ctx:     ctx,
+			in:      &lib.RunTHRIn{Trace: trace, PayDate: common.NewDate(2025, 3, 20)},
+			mock:    func() {},
+			success: false,
+			errMsg:  "Tanggal pembayaran dan hari raya wajib diisi",
+		},
+		{
+			name: "unauthorized",
+			ctx:  employeeCtx,
+			in: &lib.RunTHRIn{
+				Trace:    trace,
+				PayDate:  common.NewDate(2025, 3, 20),
+				Holidays: map[data.Religion]time.Time{data.ReligionIslam: idulFitri},
+			},
+			mock:    func() {},
+			success: false,
+			errMsg:  "unauthorized",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.mock()
+			out := service.RunTHR(sc.ctx, sc.in)
+			assert.Equal(t, sc.success, out.Success)
+			assert.Equal(t, sc.errMsg, out.Message)
+			assert.Equal(t, sc.total, out.Total)
+			if sc.success {
+				assert.Equal(t, []int{5}, out.MissingReligion)
+			}
+		})
+	}
+
+	self := service.GenerateSelfTHRSlip(employeeCtx, &lib.GenerateSelfTHRSlipIn{Trace: trace, Year: 2025})
+	assert.True(t, self.Success)
+	assert.Equal(t, &data.UserTHRPayslip{
+		UserID:    employeeId,
+		PayrollId: self.Payslip.PayrollId,
+		THRAmount: 12000000,
+		TaxAmount: 480000, // TER A 4%
+		NetAmount: 11520000,
+	}, self.Payslip)
+
+	all := service.GenerateAllTHRSlips(ctx, &lib.GenerateAllTHRSlipsIn{Trace: trace, Year: 2025})
+	assert.True(t, all.Success)
+	assert.Len(t, all.ListUserPayslips, 3)
+	assert.Equal(t, 12000000+3000000+8000000, all.TotalTHRAll)
+
+	notYet := service.GenerateSelfTHRSlip(employeeCtx, &lib.GenerateSelfTHRSlipIn{Trace: trace, Year: 2024})
+	assert.False(t, notYet.Success)
+	assert.Equal(t, "THR belum tersedia untuk tahun ini", notYet.Message)
+}
+
+func TestServiceCloseOpenAttendances(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service := NewService(timeclockStorage, mock_lib.NewMockServiceInterface(ctrl), mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
+
+	userName := test.Username
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	employeeCtx := test.UserContext(context.Background(), testUserId, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "close-open-attendances-test"}
+
+	monday := common.NewDate(2025, 6, 16)
+	tuesday := common.NewDate(2025, 6, 17)
+	shiftEnd := time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)
+
+	// user 1 forgot to check out on monday, user 2 checked out, user 3 checked in after the shift end
+	_, err := timeclockStorage.InsertAttendanceCheckin(ctx, 1, monday, monday.Add(8*time.Hour), userName)
+	assert.Nil(t, err)
+	_, err = timeclockStorage.InsertAttendanceCheckin(ctx, 2, monday, monday.Add(8*time.Hour), userName)
+	assert.Nil(t, err)
+	assert.Nil(t, timeclockStorage.UpdateAttendanceCheckout(ctx, 2, monday, monday.Add(16*time.Hour), userName))
+	_, err = timeclockStorage.InsertAttendanceCheckin(ctx, 3, monday, monday.Add(18*time.Hour), userName)
+	assert.Nil(t, err)
+	// tuesday is after the date closed
+	_, err = timeclockStorage.InsertAttendanceCheckin(ctx, 1, tuesday, tuesday.Add(8*time.Hour), userName)
+	assert.Nil(t, err)
+
+	out := service.CloseOpenAttendances(employeeCtx, &lib.CloseOpenAttendancesIn{Trace: trace, Date: monday, ShiftEnd: shiftEnd})
+	assert.False(t, out.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", out.Message)
+
+	out = service.CloseOpenAttendances(ctx, &lib.CloseOpenAttendancesIn{Trace: trace, ShiftEnd: shiftEnd})
+	assert.False(t, out.Success)
+	assert.Equal(t, "Tanggal wajib diisi", out.Message)
+
+	out = service.CloseOpenAttendances(ctx, &lib.CloseOpenAttendancesIn{Trace: trace, Date: monday, ShiftEnd: shiftEnd})
+	assert.True(t, out.Success, out.Message)
+	assert.Len(t, out.Closed, 2)
+
+	closed, err := timeclockStorage.GetDetailAttendanceByUserAndPeriod(ctx, 1, monday)
+	assert.Nil(t, err)
+	assert.True(t, closed.AutoClosed)
+	assert.Equal(t, "17:00:00", closed.CheckoutTime.Time.Format("15:04:05"))
+
+	lateCheckin, err := timeclockStorage.GetDetailAttendanceByUserAndPeriod(ctx, 3, monday)
+	assert.Nil(t, err)
+	assert.True(t, lateCheckin.AutoClosed)
+	assert.Equal(t, "18:00:00", lateCheckin.CheckoutTime.Time.Format("15:04:05"), "checkout is never before checkin")
+
+	checkedOut, err := timeclockStorage.GetDetailAttendanceByUserAndPeriod(ctx, 2, monday)
+	assert.Nil(t, err)
+	assert.False(t, checkedOut.AutoClosed)
+
+	open, err := timeclockStorage.GetDetailAttendanceByUserAndPeriod(ctx, 1, tuesday)
+	assert.Nil(t, err)
+	assert.False(t, open.AutoClosed)
+
+	// closing again finds nothing left open
+	out = service.CloseOpenAttendances(ctx, &lib.CloseOpenAttendancesIn{Trace: trace, Date: monday, ShiftEnd: shiftEnd})
+	assert.True(t, out.Success, out.Message)
+	assert.Empty(t, out.Closed)
+
+	attended := service.AttendanceUserIds(ctx, &lib.AttendanceUserIdsIn{Trace: trace, Date: tuesday})
+	assert.True(t, attended.Success, attended.Message)
+	assert.Equal(t, map[int]bool{1: true}, attended.Result)
+}
+
+func TestServicePayrollDraft(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userServiceMock := mock_lib.NewMockServiceInterface(ctrl)
+	loanServiceMock := mock_lib.NewMockLoanService(ctrl)
+	service := NewService(timeclockStorage, userServiceMock, loanServiceMock, newAuditServiceMock(ctrl))
+
+	userName := test.Username
+	ctx := test.UserContext(context.Background(), testUserId, data.RAdmin)
+	employeeCtx := test.UserContext(context.Background(), testUserId, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "payroll-draft-test"}
+
+	start := common.NewDate(2025, 2, 1)
+	end := common.NewDate(2025, 2, 28)
+	for i := 0; i < 5; i++ {
+		date := common.NewDate(2025, 2, 3+i)
+		_, err := timeclockStorage.InsertAttendanceCheckin(ctx, 1, date, date.Add(9*time.Hour), userName)
+		assert.Nil(t, err)
+	}
+
+	expectCalculation := func(times int) {
+		userServiceMock.EXPECT().UserSalary(gomock.Any(), gomock.Any()).
+			Return(&userLib.UserSalaryOut{Success: true, Result: map[int]int{1: 4000000}}).Times(times)
+		userServiceMock.EXPECT().UserTaxProfiles(gomock.Any(), gomock.Any()).
+			Return(&userLib.UserTaxProfilesOut{Success: true}).Times(times)
+		loanServiceMock.EXPECT().PayrollDeductions(gomock.Any(), gomock.Any()).
+			Return(&loanLib.PayrollDeductionsOut{Success: true}).Times(times)
+	}
+
+	out := service.CreatePayrollDraft(employeeCtx, &lib.CreatePayrollDraftIn{Trace: trace, PeriodStart: start, PeriodEnd: end})
+	assert.False(t, out.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", out.Message)
+
+	expectCalculation(2)
+	out = service.CreatePayrollDraft(ctx, &lib.CreatePayrollDraftIn{Trace: trace, PeriodStart: start, PeriodEnd: end})
+	assert.True(t, out.Success, out.Message)
+	assert.True(t, out.Created)
+	assert.Equal(t, 1, out.Draft.Employees)
+	assert.Equal(t, 1000000, out.Draft.TotalSalary, "5 of 20 workdays of 4.000.000")
+
+	// the period already has its draft
+	out = service.CreatePayrollDraft(ctx, &lib.CreatePayrollDraftIn{Trace: trace, PeriodStart: start, PeriodEnd: end})
+	assert.True(t, out.Success, out.Message)
+	assert.False(t, out.Created)
+
+	drafts := service.ListPayrollDrafts(ctx, &lib.ListPayrollDraftsIn{Trace: trace})
+	assert.True(t, drafts.Success, drafts.Message)
+	if assert.Len(t, drafts.Result, 1) {
+		assert.Len(t, drafts.Result[0].Items, 1)
+		assert.Nil(t, drafts.Result[0].PayrollId)
+	}
+
+	// running the regular payroll of the period pays the draft
+	expectCalculation(1)
+	run := service.RunPayroll(ctx, &lib.RunPayrollIn{Trace: trace, PeriodStart: start, PeriodEnd: end})
+	assert.True(t, run.Success, run.Message)
+
+	drafts = service.ListPayrollDrafts(ctx, &lib.ListPayrollDraftsIn{Trace: trace})
+	assert.True(t, drafts.Success, drafts.Message)
+	if assert.Len(t, drafts.Result, 1) && assert.NotNil(t, drafts.Result[0].PayrollId) {
+		assert.Equal(t, run.PayrollId, *drafts.Result[0].PayrollId)
+	}
+
+	drafts = service.ListPayrollDrafts(employeeCtx, &lib.ListPayrollDraftsIn{Trace: trace})
+	assert.False(t, drafts.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", drafts.Message)
+}
+
+func TestServiceOutboxEvents(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	timeclockStorage := NewStorage(con.Pool)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service := NewService(timeclockStorage, mocks.NewMockServiceInterface(ctrl), mock_lib.NewMockLoanService(ctrl), newAuditServiceMock(ctrl))
+
+	userId := testUserId
+	ctx := test.UserContext(context.Background(), userId, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "outbox-events-test"}
+	period := time.Date(2025, 6, 17, 9, 0, 0, 0, time.UTC)
+
+	out := service.SubmitAttendance(ctx, &lib.SubmitAttendanceIn{Trace: trace, Period: period})
+	assert.True(t, out.Success, out.Message)
+
+	var eventType, aggregateType, aggregateId, traceId string
+	var payloadUserId int
+	err := con.Pool.QueryRow(con.Context, `
+		SELECT event_type, aggregate_type, aggregate_id, trace_id, (payload->>'user_id')::int
+		FROM outbox_events
+	`).Scan(&eventType, &aggregateType, &aggregateId, &traceId, &payloadUserId)
+	assert.Nil(t, err)
+	assert.Equal(t, data.EventAttendanceCheckedIn, eventType)
+	assert.Equal(t, "employee", aggregateType)
+	assert.Equal(t, fmt.Sprint(userId), aggregateId)
+	assert.Equal(t, "outbox-events-test", traceId)
+	assert.Equal(t, userId, payloadUserId)
+
+	// the change is rolled back with its event, neither is stored
+	failingAudit := mocks.NewMockAuditService(ctrl)
+	failingAudit.EXPECT().Record(gomock.Any(), gomock.Any()).Return(&auditLib.RecordOut{Failure: apperror.Failed(apperror.CodeInternal, "internal error")})
+	failingService := NewService(timeclockStorage, mocks.NewMockServiceInterface(ctrl), mock_lib.NewMockLoanService(ctrl), failingAudit)
+	reimbursement := failingService.SubmitReimbursement(ctx, &lib.SubmitReimbursementIn{
+		Trace: trace, Period: period, Amount: 50000, Description: "Parkir",
+	})
+	assert.False(t, reimbursement.Success)
+
+	var events, reimbursements int
+	assert.Nil(t, con.Pool.QueryRow(con.Context, `SELECT COUNT(*) FROM outbox_events`).Scan(&events))
+	assert.Nil(t, con.Pool.QueryRow(con.Context, `SELECT COUNT(*) FROM reimbursements`).Scan(&reimbursements))
+	assert.Equal(t, 1, events)
+	assert.Equal(t, 0, reimbursements)
+
+	reimbursement = service.SubmitReimbursement(ctx, &lib.SubmitReimbursementIn{
+		Trace: trace, Period: period, Amount: 50000, Description: "Parkir",
+	})
+	assert.True(t, reimbursement.Success, reimbursement.Message)
+	assert.Nil(t, con.Pool.QueryRow(con.Context, `SELECT event_type FROM outbox_events ORDER BY id DESC LIMIT 1`).Scan(&eventType))
+	assert.Equal(t, data.EventReimbursementSubmitted, eventType)
+}
@@ -2,11 +2,14 @@ package timeclock
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/ariesmaulana/payroll/app/timeclock/lib"
 	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/ariesmaulana/payroll/lib/outbox"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -21,6 +24,11 @@ func NewStorage(pool *pgxpool.Pool) *Storage {
 	return &Storage{pool: pool}
 }
 
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
 func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
@@ -40,7 +48,7 @@ func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
 
 func (s *Storage) InsertAttendanceCheckin(ctx context.Context, userId int, periode time.Time, checkin time.Time, createdBy string) (int, error) {
 	var id int
-	err := s.pool.QueryRow(ctx, `
+	err := s.db(ctx).QueryRow(ctx, `
 		INSERT INTO attendances (user_id, period, checkin_time, created_by, updated_by)
 		VALUES ($1, $2, $3, $4, $4)
 		RETURNING id
@@ -50,7 +58,7 @@ func (s *Storage) InsertAttendanceCheckin(ctx context.Context, userId int, perio
 }
 
 func (s *Storage) UpdateAttendanceCheckout(ctx context.Context, userId int, periode time.Time, checkout time.Time, updatedBy string) error {
-	_, err := s.pool.Exec(ctx, `
+	_, err := s.db(ctx).Exec(ctx, `
 		UPDATE attendances
 		SET checkout_time = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
 		WHERE user_id = $3 AND period = $4
@@ -58,14 +66,51 @@ func (s *Storage) UpdateAttendanceCheckout(ctx context.Context, userId int, peri
 	return err
 }
 
+func (s *Storage) CloseOpenAttendances(ctx context.Context, until time.Time, shiftEnd string, updatedBy string) ([]*data.Attendance, error) {
+	const query = `
+		UPDATE attendances
+		SET checkout_time = GREATEST(checkin_time, $2::time), auto_closed = true,
+		    updated_by = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE checkout_time IS NULL AND period <= $1
+		RETURNING id, user_id, period, checkin_time, checkout_time, auto_closed, created_at, updated_at, created_by, updated_by
+	`
+	rows, err := s.db(ctx).Query(ctx, query, until.Format("2006-01-02"), shiftEnd, updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.Attendance, 0)
+	for rows.Next() {
+		var a data.Attendance
+		err := rows.Scan(
+			&a.Id,
+			&a.UserId,
+			&a.Periode,
+			&a.CheckinTime,
+			&a.CheckoutTime,
+			&a.AutoClosed,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+			&a.CreatedBy,
+			&a.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &a)
+	}
+	return result, rows.Err()
+}
+
 func (s *Storage) GetDetailAttendance(ctx context.Context, id int) (*data.Attendance, error) {
 	const query = `
-		SELECT id, user_id, period, checkin_time, checkout_time, created_at, updated_at, created_by, updated_by
+		SELECT id, user_id, period, checkin_time, checkout_time, auto_closed, created_at, updated_at, created_by, updated_by
 		FROM attendances
 		WHERE id = $1
 	`
 
-	row := s.pool.QueryRow(ctx, query, id)
+	row := s.db(ctx).QueryRow(ctx, query, id)
 	result := &data.Attendance{}
 
 	err := row.Scan(
@@ -74,6 +119,7 @@ func (s *Storage) GetDetailAttendance(ctx context.Context, id int) (*data.Attend
 		&result.Periode,
 		&result.CheckinTime,
 		&result.CheckoutTime, // <<< ini penting
+		&result.AutoClosed,
 		&result.CreatedAt,
 		&result.UpdatedAt,
 		&result.CreatedBy,
@@ -91,14 +137,14 @@ func (s *Storage) GetDetailAttendance(ctx context.Context, id int) (*data.Attend
 
 func (s *Storage) GetAllAttendanceByPeriod(ctx context.Context, startDate, endDate time.Time) ([]*data.Attendance, error) {
 	const query = `
-		SELECT id, user_id, period, checkin_time, checkout_time, created_at, updated_at, created_by, updated_by
+		SELECT id, user_id, period, checkin_time, checkout_time, auto_closed, created_at, updated_at, created_by, updated_by
 		FROM attendances
 		WHERE period BETWEEN $1 AND $2
 		ORDER BY id
 	`
 	start := startDate.Format("2006-01-02")
 	end := endDate.Format("2006-01-02")
-	rows, err := s.pool.Query(ctx, query, start, end)
+	rows, err := s.db(ctx).Query(ctx, query, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +159,7 @@ func (s *Storage) GetAllAttendanceByPeriod(ctx context.Context, startDate, endDa
 			&a.Periode,
 			&a.CheckinTime,
 			&a.CheckoutTime,
+			&a.AutoClosed,
 			&a.CreatedAt,
 			&a.UpdatedAt,
 			&a.CreatedBy,
@@ -129,7 +176,7 @@ func (s *Storage) GetAllAttendanceByPeriod(ctx context.Context, startDate, endDa
 
 func (s *Storage) InsertOvertime(ctx context.Context, userId int, period time.Time, hours int, reason, createdBy string) (int, error) {
 	var id int
-	err := s.pool.QueryRow(ctx, `
+	err := s.db(ctx).QueryRow(ctx, `
 		INSERT INTO overtimes (user_id, period, hours, reason, created_by, updated_by)
 		VALUES ($1, $2, $3, $4, $5, $5)
 		RETURNING id
@@ -139,7 +186,7 @@ func (s *Storage) InsertOvertime(ctx context.Context, userId int, period time.Ti
 }
 
 func (s *Storage) GetOvertimeById(ctx context.Context, id int) (*data.Overtime, error) {
-	row := s.pool.QueryRow(ctx, `
+	row := s.db(ctx).QueryRow(ctx, `
 		SELECT id, user_id, period, hours, reason, created_at, updated_at, created_by, updated_by
 		FROM overtimes
 		WHERE id = $1
@@ -157,7 +204,7 @@ func (s *Storage) GetOvertimeById(ctx context.Context, id int) (*data.Overtime,
 }
 
 func (s *Storage) GetOvertimeByUserId(ctx context.Context, userId int) ([]*data.Overtime, error) {
-	rows, err := s.pool.Query(ctx, `
+	rows, err := s.db(ctx).Query(ctx, `
 		SELECT id, user_id, period, hours, reason, created_at, updated_at, created_by, updated_by
 		FROM overtimes
 		WHERE user_id = $1
@@ -185,12 +232,12 @@ func (s *Storage) GetOvertimeByUserId(ctx context.Context, userId int) ([]*data.
 
 func (s *Storage) GetDetailAttendanceByUserAndPeriod(ctx context.Context, userId int, period time.Time) (*data.Attendance, error) {
 	const query = `
-		SELECT id, user_id, period, checkin_time, checkout_time, created_at, updated_at, created_by, updated_by
+		SELECT id, user_id, period, checkin_time, checkout_time, auto_closed, created_at, updated_at, created_by, updated_by
 		FROM attendances
 		WHERE user_id = $1 AND period = $2
 	`
 
-	row := s.pool.QueryRow(ctx, query, userId, period)
+	row := s.db(ctx).QueryRow(ctx, query, userId, period)
 
 	result := &data.Attendance{}
 	err := row.Scan(
@@ -199,6 +246,7 @@ func (s *Storage) GetDetailAttendanceByUserAndPeriod(ctx context.Context, userId
 		&result.Periode,
 		&result.CheckinTime,
 		&result.CheckoutTime,
+		&result.AutoClosed,
 		&result.CreatedAt,
 		&result.UpdatedAt,
 		&result.CreatedBy,
@@ -215,7 +263,7 @@ func (s *Storage) GetDetailAttendanceByUserAndPeriod(ctx context.Context, userId
 
 func (s *Storage) InsertReimbursement(ctx context.Context, userId int, period time.Time, amount int, description string, createdBy string) (int, error) {
 	var id int
-	err := s.pool.QueryRow(ctx, `
+	err := s.db(ctx).QueryRow(ctx, `
 		INSERT INTO reimbursements (user_id, period, amount, description, created_by, updated_by)
 		VALUES ($1, $2, $3, $4, $5, $5)
 		RETURNING id
@@ -231,7 +279,7 @@ func (s *Storage) GetDetailReimbursement(ctx context.Context, id int) (*data.Rei
 		WHERE id = $1
 	`
 
-	row := s.pool.QueryRow(ctx, query, id)
+	row := s.db(ctx).QueryRow(ctx, query, id)
 
 	result := &data.Reimbursement{}
 	err := row.Scan(
@@ -257,53 +305,62 @@ func (s *Storage) GetDetailReimbursement(ctx context.Context, id int) (*data.Rei
 
 func (s *Storage) InsertPayroll(
 	ctx context.Context,
+	payrollType data.PayrollType,
 	periodStart time.Time,
 	periodEnd time.Time,
 	totalAttendance int,
 	totalOvertime int,
 	totalReimbursement int,
 	totalSalary int,
+	note string,
 	createdBy string,
 ) (int, error) {
 	const query = `
 		INSERT INTO payrolls (
+			payroll_type,
 			period_start,
 			period_end,
 			total_attendance,
 			total_overtime,
 			total_reimbursement,
 			total_salary,
+			note,
 			created_by,
 			updated_by
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
 		RETURNING id
 	`
 
 	var id int
-	err := s.pool.QueryRow(ctx, query,
+	err := s.db(ctx).QueryRow(ctx, query,
+		payrollType,
 		periodStart,
 		periodEnd,
 		totalAttendance,
 		totalOvertime,
 		totalReimbursement,
 		totalSalary,
+		note,
 		createdBy,
 	).Scan(&id)
 
 	return id, err
 }
 
-func (s *Storage) IsPayrollAlreadyRun(ctx context.Context, date time.Time) (bool, error) {
+func (s *Storage) IsPayrollAlreadyRun(ctx context.Context, userId int, date time.Time) (bool, error) {
 	const query = `
 		SELECT 1
-		FROM payrolls
-		WHERE period_start <= $1 AND period_end >= $1
+		FROM payrolls p
+		JOIN payroll_items pi ON pi.payroll_id = p.id
+		WHERE p.period_start <= $2 AND p.period_end >= $2
+		  AND p.payroll_type IN ('regular', 'final_settlement')
+		  AND pi.user_id = $1
 		LIMIT 1
 	`
 
 	var exists int
-	err := s.pool.QueryRow(ctx, query, date).Scan(&exists)
+	err := s.db(ctx).QueryRow(ctx, query, userId, date).Scan(&exists)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return false, nil
@@ -319,7 +376,13 @@ func (s *Storage) InsertPayrollItem(
 	userId int,
 	attendanceCount int,
 	overtimeHours int,
+	baseSalaryAmount int,
+	overtimeAmount int,
+	bonusAmount int,
 	reimbursementTotal int,
+	taxAmount int,
+	bpjsAmount int,
+	loanDeduction int,
 	totalSalary int,
 	createdBy string,
 ) (int, error) {
@@ -327,15 +390,18 @@ func (s *Storage) InsertPayrollItem(
 	query := `
 		INSERT INTO payroll_items (
 			payroll_id, user_id, attendance_count, overtime_hours,
-			reimbursement_total, total_salary,
+			base_salary_amount, overtime_amount, bonus_amount, reimbursement_total,
+			tax_amount, bpjs_amount, loan_deduction, total_salary,
 			created_by, updated_by
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13)
 		RETURNING id
 	`
-	err := s.pool.QueryRow(
+	err := s.db(ctx).QueryRow(
 		ctx, query,
-		payrollId, userId, attendanceCount, overtimeHours, reimbursementTotal, totalSalary, createdBy,
+		payrollId, userId, attendanceCount, overtimeHours,
+		baseSalaryAmount, overtimeAmount, bonusAmount, reimbursementTotal,
+		taxAmount, bpjsAmount, loanDeduction, totalSalary, createdBy,
 	).Scan(&id)
 	return id, err
 }
@@ -343,12 +409,13 @@ func (s *Storage) InsertPayrollItem(
 func (s *Storage) GetPayrollItemsByPayrollID(ctx context.Context, payrollId int) ([]*data.PayrollItem, error) {
 	query := `
 		SELECT id, payroll_id, user_id, attendance_count, overtime_hours,
-		       reimbursement_total, total_salary,
+		       base_salary_amount, overtime_amount, bonus_amount, reimbursement_total,
+		       tax_amount, bpjs_amount, loan_deduction, total_salary,
 		       created_at, updated_at, created_by, updated_by
 		FROM payroll_items
 		WHERE payroll_id = $1
 	`
-	rows, err := s.pool.Query(ctx, query, payrollId)
+	rows, err := s.db(ctx).Query(ctx, query, payrollId)
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +430,13 @@ func (s *Storage) GetPayrollItemsByPayrollID(ctx context.Context, payrollId int)
 			&item.UserId,
 			&item.AttendanceCount,
 			&item.OvertimeHours,
+			&item.BaseSalaryAmount,
+			&item.OvertimeAmount,
+			&item.BonusAmount,
 			&item.ReimbursementTotal,
+			&item.TaxAmount,
+			&item.BpjsAmount,
+			&item.LoanDeduction,
 			&item.TotalSalary,
 			&item.CreatedAt,
 			&item.UpdatedAt,
@@ -381,12 +454,13 @@ func (s *Storage) GetPayrollItemsByPayrollID(ctx context.Context, payrollId int)
 func (s *Storage) GetPayrollItemByPayrollIDAndUserID(ctx context.Context, payrollId int, userId int) (*data.PayrollItem, error) {
 	query := `
 		SELECT id, payroll_id, user_id, attendance_count, overtime_hours,
-		       reimbursement_total, total_salary,
+		       base_salary_amount, overtime_amount, bonus_amount, reimbursement_total,
+		       tax_amount, bpjs_amount, loan_deduction, total_salary,
 		       created_at, updated_at, created_by, updated_by
 		FROM payroll_items
 		WHERE payroll_id = $1 AND user_id = $2
 	`
-	row := s.pool.QueryRow(ctx, query, payrollId, userId)
+	row := s.db(ctx).QueryRow(ctx, query, payrollId, userId)
 
 	var item data.PayrollItem
 	err := row.Scan(
@@ -395,7 +469,13 @@ func (s *Storage) GetPayrollItemByPayrollIDAndUserID(ctx context.Context, payrol
 		&item.UserId,
 		&item.AttendanceCount,
 		&item.OvertimeHours,
+		&item.BaseSalaryAmount,
+		&item.OvertimeAmount,
+		&item.BonusAmount,
 		&item.ReimbursementTotal,
+		&item.TaxAmount,
+		&item.BpjsAmount,
+		&item.LoanDeduction,
 		&item.TotalSalary,
 		&item.CreatedAt,
 		&item.UpdatedAt,
@@ -411,6 +491,57 @@ func (s *Storage) GetPayrollItemByPayrollIDAndUserID(ctx context.Context, payrol
 	return &item, nil
 }
 
+func (s *Storage) GetYearlyPayrollSummary(ctx context.Context, year int) ([]*data.YearlyPayrollSummary, error) {
+	const query = `
+		SELECT pi.user_id,
+		       MIN(EXTRACT(MONTH FROM p.period_end))::int,
+		       MAX(EXTRACT(MONTH FROM p.period_end))::int,
+		       SUM(pi.base_salary_amount),
+		       SUM(pi.overtime_amount),
+		       SUM(pi.bonus_amount),
+		       SUM(pi.reimbursement_total),
+		       SUM(pi.tax_amount),
+		       SUM(pi.bpjs_amount)
+		FROM payroll_items pi
+		JOIN payrolls p ON p.id = pi.payroll_id
+		WHERE EXTRACT(YEAR FROM p.period_end) = $1
+		GROUP BY pi.user_id
+		ORDER BY pi.user_id
+	`
+
+	rows, err := s.db(ctx).Query(ctx, query, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.YearlyPayrollSummary, 0)
+	for rows.Next() {
+		var sum data.YearlyPayrollSummary
+		err := rows.Scan(
+			&sum.UserId,
+			&sum.FirstMonth,
+			&sum.LastMonth,
+			&sum.BaseSalaryAmount,
+			&sum.OvertimeAmount,
+			&sum.BonusAmount,
+			&sum.ReimbursementTotal,
+			&sum.TaxAmount,
+			&sum.BpjsAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &sum)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (s *Storage) GetTotalOvertimeByPeriod(ctx context.Context, startDate time.Time, endDate time.Time) (int, error) {
 	const query = `
 		SELECT COALESCE(SUM(hours), 0)
@@ -419,7 +550,7 @@ func (s *Storage) GetTotalOvertimeByPeriod(ctx context.Context, startDate time.T
 	`
 
 	var total int
-	err := s.pool.QueryRow(ctx, query, startDate, endDate).Scan(&total)
+	err := s.db(ctx).QueryRow(ctx, query, startDate, endDate).Scan(&total)
 	if err != nil {
 		return 0, err
 	}
@@ -434,7 +565,7 @@ func (s *Storage) GetTotalReimbursementByPeriod(ctx context.Context, startDate t
 	`
 
 	var total int
-	err := s.pool.QueryRow(ctx, query, startDate, endDate).Scan(&total)
+	err := s.db(ctx).QueryRow(ctx, query, startDate, endDate).Scan(&total)
 	if err != nil {
 		return 0, err
 	}
@@ -449,7 +580,7 @@ func (s *Storage) GetOvertimeHoursByPeriod(ctx context.Context, startDate time.T
 		GROUP BY user_id
 	`
 
-	rows, err := s.pool.Query(ctx, query, startDate, endDate)
+	rows, err := s.db(ctx).Query(ctx, query, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -475,7 +606,7 @@ func (s *Storage) GetReimbursementTotalsByPeriod(ctx context.Context, startDate
 		GROUP BY user_id
 	`
 
-	rows, err := s.pool.Query(ctx, query, startDate, endDate)
+	rows, err := s.db(ctx).Query(ctx, query, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -497,25 +628,67 @@ func (s *Storage) GetReimbursementTotalsByPeriod(ctx context.Context, startDate
 	return result, nil
 }
 
-func (s *Storage) GetPayrollByPeriod(ctx context.Context, startDate, endDate time.Time) (*data.Payroll, error) {
+func (s *Storage) GetPayrollsByPeriod(ctx context.Context, startDate, endDate time.Time) ([]*data.Payroll, error) {
 	const query = `
-		SELECT id, period_start, period_end, total_attendance, total_overtime,
-		       total_reimbursement, total_salary, created_at, updated_at, created_by, updated_by
+		SELECT id, payroll_type, period_start, period_end, total_attendance, total_overtime,
+		       total_reimbursement, total_salary, note, created_at, updated_at, created_by, updated_by
 		FROM payrolls
-		WHERE period_start = $1 AND period_end = $2
-		LIMIT 1
+		WHERE period_start <= $2 AND period_end >= $1
+		ORDER BY period_end, id
 	`
-	row := s.pool.QueryRow(ctx, query, startDate, endDate)
+	rows, err := s.db(ctx).Query(ctx, query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.Payroll, 0)
+	for rows.Next() {
+		var p data.Payroll
+		err := rows.Scan(
+			&p.Id,
+			&p.Type,
+			&p.PeriodStart,
+			&p.PeriodEnd,
+			&p.TotalAttendance,
+			&p.TotalOvertime,
+			&p.TotalReimbursement,
+			&p.TotalSalary,
+			&p.Note,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.CreatedBy,
+			&p.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &p)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) GetPayrollById(ctx context.Context, id int) (*data.Payroll, error) {
+	const query = `
+		SELECT id, payroll_type, period_start, period_end, total_attendance, total_overtime,
+		       total_reimbursement, total_salary, note, created_at, updated_at, created_by, updated_by
+		FROM payrolls
+		WHERE id = $1
+	`
+	row := s.db(ctx).QueryRow(ctx, query, id)
 
 	var p data.Payroll
 	err := row.Scan(
 		&p.Id,
+		&p.Type,
 		&p.PeriodStart,
 		&p.PeriodEnd,
 		&p.TotalAttendance,
 		&p.TotalOvertime,
 		&p.TotalReimbursement,
 		&p.TotalSalary,
+		&p.Note,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 		&p.CreatedBy,
@@ -539,7 +712,7 @@ func (s *Storage) GetReimbursementsByUserAndPeriod(ctx context.Context, userId i
 		WHERE user_id = $1 AND period BETWEEN $2 AND $3
 	`
 
-	rows, err := s.pool.Query(ctx, query, userId, start, end)
+	rows, err := s.db(ctx).Query(ctx, query, userId, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -576,7 +749,7 @@ func (s *Storage) GetOvertimesByUserAndPeriod(ctx context.Context, userId int, s
 		WHERE user_id = $1 AND period BETWEEN $2 AND $3
 	`
 
-	rows, err := s.pool.Query(ctx, query, userId, start, end)
+	rows, err := s.db(ctx).Query(ctx, query, userId, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -607,13 +780,13 @@ func (s *Storage) GetOvertimesByUserAndPeriod(ctx context.Context, userId int, s
 
 func (s *Storage) GetAttendancesByUserAndPeriods(ctx context.Context, userId int, start time.Time, end time.Time) ([]*data.Attendance, error) {
 	const query = `
-		SELECT id, user_id, period, checkin_time, checkout_time,
+		SELECT id, user_id, period, checkin_time, checkout_time, auto_closed,
 		       created_at, updated_at, created_by, updated_by
 		FROM attendances
 		WHERE user_id = $1 AND period BETWEEN $2 AND $3
 	`
 
-	rows, err := s.pool.Query(ctx, query, userId, start, end)
+	rows, err := s.db(ctx).Query(ctx, query, userId, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -628,6 +801,7 @@ func (s *Storage) GetAttendancesByUserAndPeriods(ctx context.Context, userId int
 			&a.Periode,
 			&a.CheckinTime,
 			&a.CheckoutTime,
+			&a.AutoClosed,
 			&a.CreatedAt,
 			&a.UpdatedAt,
 			&a.CreatedBy,
@@ -641,3 +815,273 @@ func (s *Storage) GetAttendancesByUserAndPeriods(ctx context.Context, userId int
 
 	return result, nil
 }
+
+func (s *Storage) GetMonthlyTaxByUser(ctx context.Context, year int, month int) (map[int]*data.UserMonthlyTax, error) {
+	const query = `
+		SELECT pi.user_id,
+		       SUM(pi.base_salary_amount + pi.overtime_amount + pi.bonus_amount),
+		       SUM(pi.tax_amount)
+		FROM payroll_items pi
+		JOIN payrolls p ON p.id = pi.payroll_id
+		WHERE EXTRACT(YEAR FROM p.period_end) = $1 AND EXTRACT(MONTH FROM p.period_end) = $2
+		GROUP BY pi.user_id
+	`
+
+	rows, err := s.db(ctx).Query(ctx, query, year, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]*data.UserMonthlyTax)
+	for rows.Next() {
+		var m data.UserMonthlyTax
+		if err := rows.Scan(&m.UserId, &m.Gross, &m.Tax); err != nil {
+			return nil, err
+		}
+		result[m.UserId] = &m
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Storage) GetPayrollItemsByTypeAndYear(ctx context.Context, payrollType data.PayrollType, year int) ([]*data.PayrollItem, error) {
+	query := `
+		SELECT pi.id, pi.payroll_id, pi.user_id, pi.attendance_count, pi.overtime_hours,
+		       pi.base_salary_amount, pi.overtime_amount, pi.bonus_amount, pi.reimbursement_total,
+		       pi.tax_amount, pi.bpjs_amount, pi.loan_deduction, pi.total_salary,
+		       pi.created_at, pi.updated_at, pi.created_by, pi.updated_by
+		FROM payroll_items pi
+		JOIN payrolls p ON p.id = pi.payroll_id
+		WHERE p.payroll_type = $1 AND EXTRACT(YEAR FROM p.period_end) = $2
+		ORDER BY pi.user_id
+	`
+	rows, err := s.db(ctx).Query(ctx, query, payrollType, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*data.PayrollItem, 0)
+	for rows.Next() {
+		var item data.PayrollItem
+		err := rows.Scan(
+			&item.Id,
+			&item.PayrollId,
+			&item.UserId,
+			&item.AttendanceCount,
+			&item.OvertimeHours,
+			&item.BaseSalaryAmount,
+			&item.OvertimeAmount,
+			&item.BonusAmount,
+			&item.ReimbursementTotal,
+			&item.TaxAmount,
+			&item.BpjsAmount,
+			&item.LoanDeduction,
+			&item.TotalSalary,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.CreatedBy,
+			&item.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+func (s *Storage) LockPayrollPeriod(ctx context.Context, startDate, endDate time.Time) error {
+	const query = `SELECT pg_advisory_xact_lock(hashtext('payroll_period.' || current_schema() || '.' || $1))`
+
+	// months are locked in ascending order so two runs of overlapping periods can not deadlock
+	month := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, startDate.Location())
+	for ; !month.After(endDate); month = month.AddDate(0, 1, 0) {
+		if _, err := s.db(ctx).Exec(ctx, query, month.Format("2006-01")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetSettledUserIdsByPeriod(ctx context.Context, startDate, endDate time.Time) (map[int]bool, error) {
+	const query = `
+		SELECT DISTINCT pi.user_id
+		FROM payroll_items pi
+		JOIN payrolls p ON p.id = pi.payroll_id
+		WHERE p.payroll_type IN ('regular', 'final_settlement')
+		  AND p.period_start <= $2 AND p.period_end >= $1
+	`
+
+	rows, err := s.db(ctx).Query(ctx, query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]bool)
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		result[userId] = true
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) GetPayslipRunsByPeriod(ctx context.Context, userId int, startDate, endDate time.Time) ([]*data.PayslipRun, error) {
+	const query = `
+		SELECT p.id, pi.user_id, p.payroll_type, p.period_start, p.period_end, p.note,
+		       pi.attendance_count, pi.overtime_hours,
+		       pi.base_salary_amount, pi.overtime_amount, pi.bonus_amount, pi.reimbursement_total,
+		       pi.tax_amount, pi.bpjs_amount, pi.loan_deduction, pi.total_salary
+		FROM payroll_items pi
+		JOIN payrolls p ON p.id = pi.payroll_id
+		WHERE p.period_start <= $3 AND p.period_end >= $2
+		  AND ($1 = 0 OR pi.user_id = $1)
+		ORDER BY pi.user_id, p.period_end, p.id
+	`
+	rows, err := s.db(ctx).Query(ctx, query, userId, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.PayslipRun, 0)
+	for rows.Next() {
+		var run data.PayslipRun
+		err := rows.Scan(
+			&run.PayrollId,
+			&run.UserId,
+			&run.Type,
+			&run.PeriodStart,
+			&run.PeriodEnd,
+			&run.Note,
+			&run.AttendanceCount,
+			&run.OvertimeHours,
+			&run.BaseSalaryAmount,
+			&run.OvertimeAmount,
+			&run.BonusAmount,
+			&run.ReimbursementTotal,
+			&run.TaxAmount,
+			&run.BpjsAmount,
+			&run.LoanDeduction,
+			&run.TotalSalary,
+		)
+		if err != nil {
+			return nil, err
+		}
+		run.NetSalary = run.TotalSalary - run.TaxAmount - run.BpjsAmount - run.LoanDeduction
+		result = append(result, &run)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) InsertPayrollDraft(ctx context.Context, draft *data.PayrollDraft, createdBy string) (bool, error) {
+	items, err := json.Marshal(draft.Items)
+	if err != nil {
+		return false, err
+	}
+
+	const query = `
+		INSERT INTO payroll_drafts (period_start, period_end, employees, total_salary, items, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (period_start, period_end) DO NOTHING
+		RETURNING id
+	`
+	err = s.db(ctx).QueryRow(ctx, query, draft.PeriodStart, draft.PeriodEnd, draft.Employees, draft.TotalSalary,
+		string(items), createdBy).Scan(&draft.Id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Storage) GetPayrollDrafts(ctx context.Context, limit int) ([]*data.PayrollDraft, error) {
+	const query = `
+		SELECT id, period_start, period_end, employees, total_salary, items::text, payroll_id, created_at, created_by
+		FROM payroll_drafts
+		ORDER BY period_end DESC, id DESC
+		LIMIT $1
+	`
+	rows, err := s.db(ctx).Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.PayrollDraft, 0)
+	for rows.Next() {
+		var d data.PayrollDraft
+		var items string
+		err := rows.Scan(
+			&d.Id,
+			&d.PeriodStart,
+			&d.PeriodEnd,
+			&d.Employees,
+			&d.TotalSalary,
+			&items,
+			&d.PayrollId,
+			&d.CreatedAt,
+			&d.CreatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(items), &d.Items); err != nil {
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) SetPayrollDraftPayroll(ctx context.Context, periodStart time.Time, periodEnd time.Time, payrollId int) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		UPDATE payroll_drafts
+		SET payroll_id = $3
+		WHERE period_start = $1 AND period_end = $2 AND payroll_id IS NULL
+	`, periodStart, periodEnd, payrollId)
+	return err
+}
+
+func (s *Storage) GetPayrollPeriodTotals(ctx context.Context, since time.Time) ([]*data.PayrollPeriodTotal, error) {
+	const query = `
+		SELECT payroll_type, to_char(period_end, 'YYYY-MM'), COUNT(*), SUM(total_salary)
+		FROM payrolls
+		WHERE period_end >= date_trunc('month', $1::date)
+		GROUP BY 1, 2
+		ORDER BY 2, 1
+	`
+
+	rows, err := s.db(ctx).Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.PayrollPeriodTotal, 0)
+	for rows.Next() {
+		var t data.PayrollPeriodTotal
+		if err := rows.Scan(&t.Type, &t.Period, &t.Payrolls, &t.TotalSalary); err != nil {
+			return nil, err
+		}
+		result = append(result, &t)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) InsertOutboxEvent(ctx context.Context, event *data.OutboxEvent) error {
+	return outbox.Insert(ctx, s.db(ctx), event)
+}
@@ -0,0 +1,318 @@
+package user
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/oidc"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+)
+
+// refreshTokenTTL is how long a login lasts without activity, every refresh rotates the token
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// newToken returns an opaque random token (refresh token, reset token), it is only given to the client once
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what is stored in the database for refresh token and recovery code,
+// so a leaked table can not be used to login
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionRevokedReason checks an access token against the current state of its user,
+// it returns the reason the token is rejected or nil
+func sessionRevokedReason(state *data.SessionState, tokenVersion int) *apperror.Error {
+	if !state.IsActive {
+		return apperror.New(apperror.CodeUserInactive, "User tidak aktif")
+	}
+	if state.TokenVersion != tokenVersion {
+		return apperror.New(apperror.CodeTokenRevoked, "Sesi sudah dicabut")
+	}
+	if state.AccessRevoked {
+		return apperror.New(apperror.CodeTokenRevoked, "Sesi sudah logout")
+	}
+	return nil
+}
+
+// MFAPolicy decides who must use TOTP as second factor
+type MFAPolicy struct {
+	// RequiredRoles can not login without MFA, user of other roles may enroll voluntarily
+	RequiredRoles []data.UserRole
+
+	// Issuer is the account name shown on the authenticator app
+	Issuer string
+}
+
+func (p MFAPolicy) requires(role data.UserRole) bool {
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// recoveryCodeCount is the number of one time recovery codes given after MFA enrolment
+const recoveryCodeCount = 10
+
+// newRecoveryCodes returns codes formatted as xxxxxxxx-xxxxxxxx (80 bit each)
+func newRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes = append(codes, code[:8]+"-"+code[8:])
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, dash and space so the code can be typed as the user likes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(code)
+	normalized = strings.ReplaceAll(normalized, "-", "")
+	normalized = strings.ReplaceAll(normalized, " ", "")
+	return hashToken(normalized)
+}
+
+var dummyHash struct {
+	once  sync.Once
+	value string
+}
+
+// dummyPasswordHash is verified when the username does not exist, so an unknown username
+// costs the same hashing work as a wrong password and can not be told apart by response time.
+// It is made lazily with the default hasher, every stored hash is upgraded to it on login.
+func dummyPasswordHash() string {
+	dummyHash.once.Do(func() {
+		dummyHash.value, _ = common.HashPassword("dummy password of unknown user")
+	})
+	return dummyHash.value
+}
+
+// loginFailureWindow is how long a failed attempt is remembered
+const loginFailureWindow = time.Hour
+
+// throttleRule slows down login after some failed attempts and locks when there are too many
+type throttleRule struct {
+	FreeAttempts int           // failures without delay
+	BaseDelay    time.Duration // delay after the first failure past FreeAttempts, doubled on every next failure
+	MaxDelay     time.Duration
+	LockoutAfter int // failures that lock the username / ip for Lockout
+	Lockout      time.Duration
+}
+
+// loginThrottleRules, ip gets more room because an office shares one public ip
+var loginThrottleRules = map[data.LoginThrottleScope]throttleRule{
+	data.ThrottleUsername: {FreeAttempts: 3, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute, LockoutAfter: 10, Lockout: 30 * time.Minute},
+	data.ThrottleIP:       {FreeAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Minute, LockoutAfter: 100, Lockout: time.Hour},
+}
+
+// delay returns how long the next attempt must wait after failedCount failures and whether it is a lockout
+func (r throttleRule) delay(failedCount int) (time.Duration, bool) {
+	if failedCount >= r.LockoutAfter {
+		return r.Lockout, true
+	}
+	if failedCount < r.FreeAttempts {
+		return 0, false
+	}
+
+	delay := r.BaseDelay
+	for i := r.FreeAttempts; i < failedCount && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, r.MaxDelay), false
+}
+
+// retryAfterSeconds rounds the remaining lock up so the client never retries too early
+func retryAfterSeconds(throttles []*data.LoginThrottle, now time.Time) int {
+	var wait time.Duration
+	for _, t := range throttles {
+		if t.LockedUntil != nil && t.LockedUntil.Sub(now) > wait {
+			wait = t.LockedUntil.Sub(now)
+		}
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return int((wait + time.Second - 1) / time.Second)
+}
+
+// passwordMaxLength limits the hash input, a huge password only burns cpu
+const passwordMaxLength = 128
+
+// PasswordPolicy is checked on password change and reset
+type PasswordPolicy struct {
+	MinLength int
+
+	// Breached is the lower case password of a breach list, see LoadBreachedPasswords
+	Breached map[string]struct{}
+
+	ResetTokenTTL time.Duration
+
+	// ResetURL is the page of the forgot password link, the token is appended to it
+	ResetURL string
+}
+
+// LoadBreachedPasswords reads a breach list file, one password per line, empty line and # comment skipped
+func LoadBreachedPasswords(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	return breached, scanner.Err()
+}
+
+// validate returns the reason the password is rejected or empty string.
+// The breach list is compared case insensitive, "Password1" is as weak as "password1".
+func (p PasswordPolicy) validate(password string, username string) string {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Sprintf("Password minimal %d karakter", p.MinLength)
+	}
+	if len(password) > passwordMaxLength {
+		return fmt.Sprintf("Password maksimal %d karakter", passwordMaxLength)
+	}
+	if strings.EqualFold(password, username) {
+		return "Password tidak boleh sama dengan username"
+	}
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		return "Password terlalu umum, pernah bocor di internet"
+	}
+	return ""
+}
+
+// temporaryPasswordLength is long enough for any sane MinLength, the user is expected to change it
+const temporaryPasswordLength = 20
+
+// newTemporaryPassword is given to a user created or reset by admin without password
+func newTemporaryPassword() (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	return token[:temporaryPasswordLength], nil
+}
+
+// validateNewUser returns the reason the account can not be created or empty string, the password is checked by PasswordPolicy
+func validateNewUser(fullname string, username string, email string, role data.UserRole, baseSalary int) string {
+	if fullname == "" || len(fullname) > 100 {
+		return "Nama lengkap wajib diisi, maksimal 100 karakter"
+	}
+	if !common.ValidateUsername(username) {
+		return "Username harus 5-20 karakter huruf, angka atau underscore"
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > 100 {
+		return "Email tidak valid"
+	}
+	if role != data.RAdmin && role != data.REmployee {
+		return "Role harus admin atau employee"
+	}
+	if baseSalary <= 0 {
+		return "Gaji pokok harus lebih dari 0"
+	}
+	return ""
+}
+
+// ssoStateTTL is how long the user has to login on the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// SSOProvider is an OpenID Connect identity provider (Google Workspace, Keycloak) users can login with,
+// the local password and TOTP are not used, the provider enforces its own second factor
+type SSOProvider struct {
+	Client *oidc.Provider
+
+	// GroupsClaim is the ID token claim listing the groups of the user
+	GroupsClaim string
+
+	// RoleMap maps a group of the provider to a payroll role. When set, the role is synced on every login
+	// and a user in none of the mapped groups becomes employee. When empty the role is managed in payroll.
+	RoleMap map[string]data.UserRole
+}
+
+// role returns the payroll role of the groups, admin wins over employee. False when the role is not synced.
+func (p SSOProvider) role(groups []string) (data.UserRole, bool) {
+	if len(p.RoleMap) == 0 {
+		return "", false
+	}
+
+	for _, group := range groups {
+		if p.RoleMap[group] == data.RAdmin {
+			return data.RAdmin, true
+		}
+	}
+	return data.REmployee, true
+}
+
+const (
+	// apiTokenDefaultDays is the lifetime of an api token when none is given, apiTokenMaxDays the longest allowed
+	apiTokenDefaultDays = 90
+	apiTokenMaxDays     = 365
+
+	// apiTokenPrefixLen is how much of the token is kept in clear to recognize it in the token list
+	apiTokenPrefixLen = 12
+)
+
+// newAPIToken returns the token given to the client once and its displayable prefix
+func newAPIToken() (string, string, error) {
+	random, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	token := data.APITokenPrefix + random
+	return token, token[:apiTokenPrefixLen], nil
+}
+
+// normalizeScopes sorts and dedupes the scopes, it returns the reason they are rejected or empty string
+func normalizeScopes(scopes []string) ([]string, string) {
+	if len(scopes) == 0 {
+		return nil, "Scope token tidak boleh kosong"
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(data.APIScopes, scope) {
+			return nil, fmt.Sprintf("Scope %q tidak dikenal", scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	slices.Sort(result)
+	return result, ""
+}
@@ -0,0 +1,272 @@
+package user
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewToken(t *testing.T) {
+	t.Parallel()
+
+	first, err := newToken()
+	assert.NoError(t, err)
+	second, err := newToken()
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashToken(t *testing.T) {
+	t.Parallel()
+
+	hash := hashToken("token")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, hashToken("token"))
+	assert.NotEqual(t, hash, hashToken("other"))
+}
+
+func TestSessionRevokedReason(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name         string
+		state        *data.SessionState
+		tokenVersion int
+		code         apperror.Code
+		message      string
+	}{
+		{name: "valid", state: &data.SessionState{IsActive: true, TokenVersion: 2}, tokenVersion: 2},
+		{name: "inactive user", state: &data.SessionState{IsActive: false, TokenVersion: 2}, tokenVersion: 2, code: apperror.CodeUserInactive, message: "User tidak aktif"},
+		{name: "revoked by admin", state: &data.SessionState{IsActive: true, TokenVersion: 3}, tokenVersion: 2, code: apperror.CodeTokenRevoked, message: "Sesi sudah dicabut"},
+		{name: "logged out", state: &data.SessionState{IsActive: true, TokenVersion: 2, AccessRevoked: true}, tokenVersion: 2, code: apperror.CodeTokenRevoked, message: "Sesi sudah logout"},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			appErr := sessionRevokedReason(sc.state, sc.tokenVersion)
+			if sc.code == "" {
+				assert.Nil(t, appErr)
+				return
+			}
+			assert.Equal(t, sc.code, appErr.Code)
+			assert.Equal(t, sc.message, appErr.ID)
+		})
+	}
+}
+
+func TestMFAPolicyRequires(t *testing.T) {
+	t.Parallel()
+
+	policy := MFAPolicy{RequiredRoles: []data.UserRole{data.RAdmin}}
+	assert.True(t, policy.requires(data.RAdmin))
+	assert.False(t, policy.requires(data.REmployee))
+	assert.False(t, MFAPolicy{}.requires(data.RAdmin))
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	codes, err := newRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{8}-[a-z2-7]{8}$`, code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	hash := hashRecoveryCode("abcdefgh-ijklmnop")
+	assert.Equal(t, hash, hashRecoveryCode("ABCDEFGH IJKLMNOP"))
+	assert.Equal(t, hash, hashRecoveryCode("abcdefghijklmnop"))
+	assert.NotEqual(t, hash, hashRecoveryCode("abcdefgh-ijklmnoq"))
+}
+
+func TestThrottleRuleDelay(t *testing.T) {
+	t.Parallel()
+
+	rule := throttleRule{FreeAttempts: 3, BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Second, LockoutAfter: 8, Lockout: 30 * time.Minute}
+
+	scenarios := []struct {
+		failed int
+		delay  time.Duration
+		locked bool
+	}{
+		{failed: 1, delay: 0},
+		{failed: 2, delay: 0},
+		{failed: 3, delay: 2 * time.Second},
+		{failed: 4, delay: 4 * time.Second},
+		{failed: 5, delay: 8 * time.Second},
+		{failed: 6, delay: 10 * time.Second},
+		{failed: 7, delay: 10 * time.Second},
+		{failed: 8, delay: 30 * time.Minute, locked: true},
+		{failed: 20, delay: 30 * time.Minute, locked: true},
+	}
+
+	for _, sc := range scenarios {
+		delay, locked := rule.delay(sc.failed)
+		assert.Equal(t, sc.delay, delay, "failed %d", sc.failed)
+		assert.Equal(t, sc.locked, locked, "failed %d", sc.failed)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	soon := now.Add(1500 * time.Millisecond)
+	later := now.Add(90 * time.Second)
+	past := now.Add(-time.Second)
+
+	assert.Equal(t, 0, retryAfterSeconds(nil, now))
+	assert.Equal(t, 0, retryAfterSeconds([]*data.LoginThrottle{{LockedUntil: &past}}, now))
+	assert.Equal(t, 2, retryAfterSeconds([]*data.LoginThrottle{{LockedUntil: &soon}}, now))
+	assert.Equal(t, 90, retryAfterSeconds([]*data.LoginThrottle{{LockedUntil: &soon}, {LockedUntil: &later}}, now))
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	t.Parallel()
+
+	// same format and cost as a real hash, so an unknown username is verified as slow as a known one
+	hashed, err := common.HashPassword("password")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Split(hashed, "$")[:4], strings.Split(dummyPasswordHash(), "$")[:4])
+	assert.Equal(t, dummyPasswordHash(), dummyPasswordHash())
+
+	assert.False(t, common.VerifyPassword("", dummyPasswordHash()))
+	assert.False(t, common.VerifyPassword("password", dummyPasswordHash()))
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	policy := PasswordPolicy{
+		MinLength: 10,
+		Breached:  map[string]struct{}{"password123": {}},
+	}
+
+	scenarios := []struct {
+		name     string
+		password string
+		message  string
+	}{
+		{name: "valid", password: "kopi-susu-gula-aren", message: ""},
+		{name: "too short", password: "pendek", message: "Password minimal 10 karakter"},
+		{name: "too long", password: strings.Repeat("a", passwordMaxLength+1), message: "Password maksimal 128 karakter"},
+		{name: "same as username", password: "BudiSantoso", message: "Password tidak boleh sama dengan username"},
+		{name: "breached", password: "Password123", message: "Password terlalu umum, pernah bocor di internet"},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.message, policy.validate(sc.password, "budisantoso"))
+		})
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# top passwords\n123456\n\nQwerty\n"), 0600))
+
+	breached, err := LoadBreachedPasswords(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"123456": {}, "qwerty": {}}, breached)
+
+	_, err = LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestSSOProviderRole(t *testing.T) {
+	t.Parallel()
+
+	mapped := SSOProvider{RoleMap: map[string]data.UserRole{
+		"payroll-admins": data.RAdmin,
+		"staff":          data.REmployee,
+	}}
+
+	scenarios := []struct {
+		name     string
+		provider SSOProvider
+		groups   []string
+		role     data.UserRole
+		synced   bool
+	}{
+		{name: "no role map keeps payroll role", provider: SSOProvider{}, groups: []string{"payroll-admins"}, synced: false},
+		{name: "admin group", provider: mapped, groups: []string{"staff", "payroll-admins"}, role: data.RAdmin, synced: true},
+		{name: "employee group", provider: mapped, groups: []string{"staff"}, role: data.REmployee, synced: true},
+		{name: "removed from every group is demoted", provider: mapped, groups: nil, role: data.REmployee, synced: true},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			role, synced := sc.provider.role(sc.groups)
+			assert.Equal(t, sc.synced, synced)
+			assert.Equal(t, sc.role, role)
+		})
+	}
+}
+
+func TestNewAPIToken(t *testing.T) {
+	t.Parallel()
+
+	token, prefix, err := newAPIToken()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, data.APITokenPrefix))
+	assert.Len(t, prefix, apiTokenPrefixLen)
+	assert.True(t, strings.HasPrefix(token, prefix))
+
+	other, _, err := newAPIToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	t.Parallel()
+
+	scopes, msg := normalizeScopes([]string{data.ScopePayslipRead, data.ScopeAttendanceWrite, data.ScopePayslipRead})
+	assert.Empty(t, msg)
+	assert.Equal(t, []string{data.ScopeAttendanceWrite, data.ScopePayslipRead}, scopes)
+
+	_, msg = normalizeScopes(nil)
+	assert.NotEmpty(t, msg)
+
+	_, msg = normalizeScopes([]string{data.ScopePayslipRead, "payroll:run"})
+	assert.Equal(t, `Scope "payroll:run" tidak dikenal`, msg)
+}
+
+func TestValidateNewUser(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, validateNewUser("Gita Savitri", "gita_s", "gita@example.com", data.RAdmin, 10_000_000))
+
+	assert.NotEmpty(t, validateNewUser("", "gita_s", "gita@example.com", data.REmployee, 10_000_000))
+	assert.NotEmpty(t, validateNewUser("Gita", "_gita", "gita@example.com", data.REmployee, 10_000_000))
+	assert.NotEmpty(t, validateNewUser("Gita", "gita_s", "Gita <gita@example.com>", data.REmployee, 10_000_000))
+	assert.NotEmpty(t, validateNewUser("Gita", "gita_s", "gita@example.com", "owner", 10_000_000))
+	assert.NotEmpty(t, validateNewUser("Gita", "gita_s", "gita@example.com", data.REmployee, 0))
+}
+
+func TestNewTemporaryPassword(t *testing.T) {
+	t.Parallel()
+
+	password, err := newTemporaryPassword()
+	assert.NoError(t, err)
+	assert.Len(t, password, temporaryPasswordLength)
+	assert.Empty(t, PasswordPolicy{MinLength: 12}.validate(password, "gita_s"))
+}
@@ -2,12 +2,18 @@ package user
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
@@ -27,13 +33,13 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	trace, ok := contextutil.GetTrace(r.Context())
 	if !ok {
 		log.Warn(trace).Msg("Trace not found in context")
-		http.Error(w, "Trace not found", http.StatusInternalServerError)
+		response.Error(w, r, "Trace not found")
 		return
 	}
 
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		response.Error(w, r, "Invalid request body")
 		return
 	}
 
@@ -41,12 +47,783 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		Trace:    trace,
 		UserName: req.UserName,
 		Password: req.Password,
+		IP:       clientIP(r),
 	})
+	if out.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(out.RetryAfter))
+		response.Error(w, r, "Too many login attempts")
+		return
+	}
+	if !out.Success {
+		// not the reason of the service, a caller can't tell an unknown username from an inactive user.
+		// A failure without error is an internal one too, it stays a 500 and not a wrong password.
+		appErr := out.Err
+		if appErr != nil && appErr.Code != apperror.CodeInternal {
+			appErr = apperror.New(apperror.CodeInvalidCredentials, "Username atau password tidak valid")
+		}
+		response.Fail(w, r, appErr)
+		return
+	}
+
+	if out.MFARequired {
+		response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", mfaChallengeResponse{
+			MFARequired:        true,
+			EnrollmentRequired: out.MFAEnrollRequired,
+			ChallengeToken:     out.ChallengeToken,
+			ExpiresIn:          out.ExpiresIn,
+		})
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", tokenResponse{
+		AccessToken:  out.Token,
+		RefreshToken: out.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    out.ExpiresIn,
+	})
+
+}
+
+// clientIP is the remote address without port, RealIP middleware already replaced it
+// with X-Real-IP / X-Forwarded-For when the request comes from a trusted proxy
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+}
+
+// mfaChallengeResponse is returned by login instead of tokenResponse when the second factor is needed,
+// continue with /users/login/mfa or with /users/login/mfa/enroll when enrollment_required
+type mfaChallengeResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.RefreshToken(r.Context(), &lib.RefreshTokenIn{
+		Trace:        trace,
+		RefreshToken: req.RefreshToken,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", tokenResponse{
+		AccessToken:  out.Token,
+		RefreshToken: out.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    out.ExpiresIn,
+	})
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	// body is optional, without refresh token only the access token is revoked
+	var req refreshTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, "Invalid request body")
+			return
+		}
+	}
+
+	out := h.service.Logout(r.Context(), &lib.LogoutIn{
+		Trace:        trace,
+		RefreshToken: req.RefreshToken,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+func (h *Handler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.Error(w, r, "Param 'userId' harus angka")
+		return
+	}
+
+	out := h.service.RevokeSessions(r.Context(), &lib.RevokeSessionsIn{
+		Trace:  trace,
+		UserId: userId,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type setTaxProfileRequest struct {
+	Npwp       string `json:"npwp"`
+	Nik        string `json:"nik"`
+	PTKPStatus string `json:"ptkp_status"` // TK/0 .. TK/3, K/0 .. K/3
+}
+
+func (h *Handler) SetTaxProfile(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.Error(w, r, "Param 'userId' harus angka")
+		return
+	}
+
+	var req setTaxProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.SetTaxProfile(r.Context(), &lib.SetTaxProfileIn{
+		Trace:      trace,
+		UserId:     userId,
+		Npwp:       req.Npwp,
+		Nik:        req.Nik,
+		PTKPStatus: common.PTKPStatus(req.PTKPStatus),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type setReligionRequest struct {
+	Religion string `json:"religion"` // islam, protestan, katolik, hindu, buddha, konghucu
+}
+
+func (h *Handler) SetReligion(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.Error(w, r, "Param 'userId' harus angka")
+		return
+	}
+
+	var req setReligionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.SetReligion(r.Context(), &lib.SetReligionIn{
+		Trace:    trace,
+		UserId:   userId,
+		Religion: data.Religion(req.Religion),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type loginMFARequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req loginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.VerifyMFALogin(r.Context(), &lib.VerifyMFALoginIn{
+		Trace:          trace,
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		RecoveryCode:   req.RecoveryCode,
+		IP:             clientIP(r),
+	})
+	if out.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(out.RetryAfter))
+		response.Error(w, r, "Too many login attempts")
+		return
+	}
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", tokenResponse{
+		AccessToken:  out.Token,
+		RefreshToken: out.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    out.ExpiresIn,
+	})
+}
+
+type mfaEnrollmentRequest struct {
+	// required on /users/login/mfa/enroll, not used on /users/mfa/enroll
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type startMFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type confirmMFAEnrollmentResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+
+	// only on enrolment during login
+	*tokenResponse
+}
+
+// decodeMFAEnrollment reads the optional body, logged in user may call enroll without body
+func decodeMFAEnrollment(r *http.Request, req *mfaEnrollmentRequest) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	return json.NewDecoder(r.Body).Decode(req)
+}
+
+func (h *Handler) StartMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req mfaEnrollmentRequest
+	if err := decodeMFAEnrollment(r, &req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.StartMFAEnrollment(r.Context(), &lib.StartMFAEnrollmentIn{
+		Trace:          trace,
+		ChallengeToken: req.ChallengeToken,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", startMFAEnrollmentResponse{
+		Secret:          out.Secret,
+		ProvisioningURI: out.ProvisioningURI,
+	})
+}
+
+func (h *Handler) ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req mfaEnrollmentRequest
+	if err := decodeMFAEnrollment(r, &req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.ConfirmMFAEnrollment(r.Context(), &lib.ConfirmMFAEnrollmentIn{
+		Trace:          trace,
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	resp := confirmMFAEnrollmentResponse{RecoveryCodes: out.RecoveryCodes}
+	if out.Token != "" {
+		resp.tokenResponse = &tokenResponse{
+			AccessToken:  out.Token,
+			RefreshToken: out.RefreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    out.ExpiresIn,
+		}
+	}
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", resp)
+}
+
+type mfaStepUpRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaStepUpResponse struct {
+	StepUpToken string `json:"step_up_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (h *Handler) MFAStepUp(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req mfaStepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.MFAStepUp(r.Context(), &lib.MFAStepUpIn{
+		Trace: trace,
+		Code:  req.Code,
+		IP:    clientIP(r),
+	})
+	if out.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(out.RetryAfter))
+		response.Fail(w, r, out.Err)
+		return
+	}
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", mfaStepUpResponse{
+		StepUpToken: out.StepUpToken,
+		ExpiresIn:   out.ExpiresIn,
+	})
+}
+
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.Error(w, r, "Param 'userId' harus angka")
+		return
+	}
+
+	out := h.service.UnlockUser(r.Context(), &lib.UnlockUserIn{
+		Trace:  trace,
+		UserId: userId,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.ChangePassword(r.Context(), &lib.ChangePasswordIn{
+		Trace:           trace,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+		IP:              clientIP(r),
+	})
+	if out.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(out.RetryAfter))
+		response.Fail(w, r, out.Err)
+		return
+	}
 	if !out.Success {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		response.Fail(w, r, out.Err)
 		return
 	}
 
-	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Token)
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", tokenResponse{
+		AccessToken:  out.Token,
+		RefreshToken: out.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    out.ExpiresIn,
+	})
+}
+
+type forgotPasswordRequest struct {
+	Login string `json:"login"` // username or email
+}
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.ForgotPassword(r.Context(), &lib.ForgotPasswordIn{
+		Trace: trace,
+		Login: req.Login,
+		IP:    clientIP(r),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "Jika akun terdaftar, link reset password sudah dikirim", nil)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.ResetPassword(r.Context(), &lib.ResetPasswordIn{
+		Trace:       trace,
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+		IP:          clientIP(r),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type ssoLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// StartSSOLogin answers the authorization url instead of redirecting, the frontend sends the browser there
+func (h *Handler) StartSSOLogin(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.StartSSOLogin(r.Context(), &lib.StartSSOLoginIn{
+		Trace:    trace,
+		Provider: chi.URLParam(r, "provider"),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", ssoLoginResponse{
+		AuthorizationURL: out.AuthorizationURL,
+	})
+}
+
+type ssoCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// SSOCallback takes code and state from the query when the provider redirects to the api directly (GET),
+// or from the body when the frontend callback page forwards them (POST)
+func (h *Handler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	req := ssoCallbackRequest{
+		Code:  r.URL.Query().Get("code"),
+		State: r.URL.Query().Get("state"),
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, "Invalid request body")
+			return
+		}
+	}
+
+	// the user cancelled or the provider refused the login
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		response.Error(w, r, "Login SSO dibatalkan: "+providerErr)
+		return
+	}
+
+	out := h.service.CompleteSSOLogin(r.Context(), &lib.CompleteSSOLoginIn{
+		Trace:    trace,
+		Provider: chi.URLParam(r, "provider"),
+		Code:     req.Code,
+		State:    req.State,
+		IP:       clientIP(r),
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	if out.MFARequired {
+		response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", mfaChallengeResponse{
+			MFARequired:        true,
+			EnrollmentRequired: out.MFAEnrollRequired,
+			ChallengeToken:     out.ChallengeToken,
+			ExpiresIn:          out.ExpiresIn,
+		})
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", tokenResponse{
+		AccessToken:  out.Token,
+		RefreshToken: out.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    out.ExpiresIn,
+	})
+}
+
+type createAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type createAPITokenResponse struct {
+	Token    string         `json:"token"` // only shown once
+	APIToken *data.APIToken `json:"api_token"`
+}
+
+// CreateAPIToken serves /users/tokens (personal access token) and /users/service-accounts/{serviceAccountId}/tokens
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	serviceAccountId, ok := serviceAccountParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.CreateAPIToken(r.Context(), &lib.CreateAPITokenIn{
+		Trace:            trace,
+		ServiceAccountId: serviceAccountId,
+		Name:             req.Name,
+		Scopes:           req.Scopes,
+		ExpiresInDays:    req.ExpiresInDays,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "Simpan token ini, token tidak ditampilkan lagi", createAPITokenResponse{
+		Token:    out.Token,
+		APIToken: out.APIToken,
+	})
+}
+
+func (h *Handler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	serviceAccountId, ok := serviceAccountParam(w, r)
+	if !ok {
+		return
+	}
+
+	out := h.service.ListAPITokens(r.Context(), &lib.ListAPITokensIn{
+		Trace:            trace,
+		ServiceAccountId: serviceAccountId,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.APITokens)
+}
+
+// serviceAccountParam is 0 on the personal token routes
+func serviceAccountParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	param := chi.URLParam(r, "serviceAccountId")
+	if param == "" {
+		return 0, true
+	}
+
+	serviceAccountId, err := strconv.Atoi(param)
+	if err != nil {
+		response.Error(w, r, "Param 'serviceAccountId' harus angka")
+		return 0, false
+	}
+	return serviceAccountId, true
+}
+
+func (h *Handler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	tokenId, err := strconv.Atoi(chi.URLParam(r, "tokenId"))
+	if err != nil {
+		response.Error(w, r, "Param 'tokenId' harus angka")
+		return
+	}
+
+	out := h.service.RevokeAPIToken(r.Context(), &lib.RevokeAPITokenIn{
+		Trace:   trace,
+		TokenId: tokenId,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+type createServiceAccountRequest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Role        data.UserRole `json:"role"`
+}
+
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req createServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.CreateServiceAccount(r.Context(), &lib.CreateServiceAccountIn{
+		Trace:       trace,
+		Name:        req.Name,
+		Description: req.Description,
+		Role:        req.Role,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.ServiceAccount)
+}
+
+func (h *Handler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.ListServiceAccounts(r.Context(), &lib.ListServiceAccountsIn{
+		Trace: trace,
+	})
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
 
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.ServiceAccounts)
 }
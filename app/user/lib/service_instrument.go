@@ -0,0 +1,440 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named user.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="user"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) Login(ctx context.Context, in *LoginIn) *LoginOut {
+	ctx, span := tracing.Start(ctx, "user.Login")
+	start := time.Now()
+	out := s.next.Login(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "Login", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) VerifyMFALogin(ctx context.Context, in *VerifyMFALoginIn) *LoginOut {
+	ctx, span := tracing.Start(ctx, "user.VerifyMFALogin")
+	start := time.Now()
+	out := s.next.VerifyMFALogin(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "VerifyMFALogin", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) StartMFAEnrollment(ctx context.Context, in *StartMFAEnrollmentIn) *StartMFAEnrollmentOut {
+	ctx, span := tracing.Start(ctx, "user.StartMFAEnrollment")
+	start := time.Now()
+	out := s.next.StartMFAEnrollment(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "StartMFAEnrollment", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ConfirmMFAEnrollment(ctx context.Context, in *ConfirmMFAEnrollmentIn) *ConfirmMFAEnrollmentOut {
+	ctx, span := tracing.Start(ctx, "user.ConfirmMFAEnrollment")
+	start := time.Now()
+	out := s.next.ConfirmMFAEnrollment(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ConfirmMFAEnrollment", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) MFAStepUp(ctx context.Context, in *MFAStepUpIn) *MFAStepUpOut {
+	ctx, span := tracing.Start(ctx, "user.MFAStepUp")
+	start := time.Now()
+	out := s.next.MFAStepUp(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "MFAStepUp", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) StartSSOLogin(ctx context.Context, in *StartSSOLoginIn) *StartSSOLoginOut {
+	ctx, span := tracing.Start(ctx, "user.StartSSOLogin")
+	start := time.Now()
+	out := s.next.StartSSOLogin(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "StartSSOLogin", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CompleteSSOLogin(ctx context.Context, in *CompleteSSOLoginIn) *LoginOut {
+	ctx, span := tracing.Start(ctx, "user.CompleteSSOLogin")
+	start := time.Now()
+	out := s.next.CompleteSSOLogin(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "CompleteSSOLogin", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RefreshToken(ctx context.Context, in *RefreshTokenIn) *RefreshTokenOut {
+	ctx, span := tracing.Start(ctx, "user.RefreshToken")
+	start := time.Now()
+	out := s.next.RefreshToken(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "RefreshToken", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) Logout(ctx context.Context, in *LogoutIn) *LogoutOut {
+	ctx, span := tracing.Start(ctx, "user.Logout")
+	start := time.Now()
+	out := s.next.Logout(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "Logout", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ChangePassword(ctx context.Context, in *ChangePasswordIn) *ChangePasswordOut {
+	ctx, span := tracing.Start(ctx, "user.ChangePassword")
+	start := time.Now()
+	out := s.next.ChangePassword(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ChangePassword", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ForgotPassword(ctx context.Context, in *ForgotPasswordIn) *ForgotPasswordOut {
+	ctx, span := tracing.Start(ctx, "user.ForgotPassword")
+	start := time.Now()
+	out := s.next.ForgotPassword(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ForgotPassword", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ResetPassword(ctx context.Context, in *ResetPasswordIn) *ResetPasswordOut {
+	ctx, span := tracing.Start(ctx, "user.ResetPassword")
+	start := time.Now()
+	out := s.next.ResetPassword(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ResetPassword", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CreateUser(ctx context.Context, in *CreateUserIn) *CreateUserOut {
+	ctx, span := tracing.Start(ctx, "user.CreateUser")
+	start := time.Now()
+	out := s.next.CreateUser(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "CreateUser", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SetPassword(ctx context.Context, in *SetPasswordIn) *SetPasswordOut {
+	ctx, span := tracing.Start(ctx, "user.SetPassword")
+	start := time.Now()
+	out := s.next.SetPassword(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "SetPassword", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UnlockUser(ctx context.Context, in *UnlockUserIn) *UnlockUserOut {
+	ctx, span := tracing.Start(ctx, "user.UnlockUser")
+	start := time.Now()
+	out := s.next.UnlockUser(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UnlockUser", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RevokeSessions(ctx context.Context, in *RevokeSessionsIn) *RevokeSessionsOut {
+	ctx, span := tracing.Start(ctx, "user.RevokeSessions")
+	start := time.Now()
+	out := s.next.RevokeSessions(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "RevokeSessions", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ValidateSession(ctx context.Context, in *ValidateSessionIn) *ValidateSessionOut {
+	ctx, span := tracing.Start(ctx, "user.ValidateSession")
+	start := time.Now()
+	out := s.next.ValidateSession(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ValidateSession", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CreateAPIToken(ctx context.Context, in *CreateAPITokenIn) *CreateAPITokenOut {
+	ctx, span := tracing.Start(ctx, "user.CreateAPIToken")
+	start := time.Now()
+	out := s.next.CreateAPIToken(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "CreateAPIToken", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListAPITokens(ctx context.Context, in *ListAPITokensIn) *ListAPITokensOut {
+	ctx, span := tracing.Start(ctx, "user.ListAPITokens")
+	start := time.Now()
+	out := s.next.ListAPITokens(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ListAPITokens", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) RevokeAPIToken(ctx context.Context, in *RevokeAPITokenIn) *RevokeAPITokenOut {
+	ctx, span := tracing.Start(ctx, "user.RevokeAPIToken")
+	start := time.Now()
+	out := s.next.RevokeAPIToken(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "RevokeAPIToken", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) CreateServiceAccount(ctx context.Context, in *CreateServiceAccountIn) *CreateServiceAccountOut {
+	ctx, span := tracing.Start(ctx, "user.CreateServiceAccount")
+	start := time.Now()
+	out := s.next.CreateServiceAccount(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "CreateServiceAccount", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListServiceAccounts(ctx context.Context, in *ListServiceAccountsIn) *ListServiceAccountsOut {
+	ctx, span := tracing.Start(ctx, "user.ListServiceAccounts")
+	start := time.Now()
+	out := s.next.ListServiceAccounts(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "ListServiceAccounts", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) AuthenticateAPIToken(ctx context.Context, in *AuthenticateAPITokenIn) *AuthenticateAPITokenOut {
+	ctx, span := tracing.Start(ctx, "user.AuthenticateAPIToken")
+	start := time.Now()
+	out := s.next.AuthenticateAPIToken(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "AuthenticateAPIToken", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserSalary(ctx context.Context, in *UserSalaryIn) *UserSalaryOut {
+	ctx, span := tracing.Start(ctx, "user.UserSalary")
+	start := time.Now()
+	out := s.next.UserSalary(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserSalary", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserCostCenter(ctx context.Context, in *UserCostCenterIn) *UserCostCenterOut {
+	ctx, span := tracing.Start(ctx, "user.UserCostCenter")
+	start := time.Now()
+	out := s.next.UserCostCenter(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserCostCenter", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserTaxProfiles(ctx context.Context, in *UserTaxProfilesIn) *UserTaxProfilesOut {
+	ctx, span := tracing.Start(ctx, "user.UserTaxProfiles")
+	start := time.Now()
+	out := s.next.UserTaxProfiles(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserTaxProfiles", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SetTaxProfile(ctx context.Context, in *SetTaxProfileIn) *SetTaxProfileOut {
+	ctx, span := tracing.Start(ctx, "user.SetTaxProfile")
+	start := time.Now()
+	out := s.next.SetTaxProfile(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "SetTaxProfile", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserContacts(ctx context.Context, in *UserContactsIn) *UserContactsOut {
+	ctx, span := tracing.Start(ctx, "user.UserContacts")
+	start := time.Now()
+	out := s.next.UserContacts(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserContacts", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserBankAccounts(ctx context.Context, in *UserBankAccountsIn) *UserBankAccountsOut {
+	ctx, span := tracing.Start(ctx, "user.UserBankAccounts")
+	start := time.Now()
+	out := s.next.UserBankAccounts(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserBankAccounts", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SetBankAccount(ctx context.Context, in *SetBankAccountIn) *SetBankAccountOut {
+	ctx, span := tracing.Start(ctx, "user.SetBankAccount")
+	start := time.Now()
+	out := s.next.SetBankAccount(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "SetBankAccount", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) UserEmployments(ctx context.Context, in *UserEmploymentsIn) *UserEmploymentsOut {
+	ctx, span := tracing.Start(ctx, "user.UserEmployments")
+	start := time.Now()
+	out := s.next.UserEmployments(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "UserEmployments", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) SetReligion(ctx context.Context, in *SetReligionIn) *SetReligionOut {
+	ctx, span := tracing.Start(ctx, "user.SetReligion")
+	start := time.Now()
+	out := s.next.SetReligion(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("user", "SetReligion", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
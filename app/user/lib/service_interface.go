@@ -1,28 +1,408 @@
 package lib
 
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
 import (
 	"context"
+	"time"
 
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 )
 
 type ServiceInterface interface {
+	// Login returns a challenge token instead of access token when MFA is needed (see LoginOut.MFARequired)
 	Login(ctx context.Context, in *LoginIn) *LoginOut
 
+	// VerifyMFALogin completes the login with TOTP code or recovery code
+	VerifyMFALogin(ctx context.Context, in *VerifyMFALoginIn) *LoginOut
+
+	// StartMFAEnrollment creates a pending TOTP secret, for logged in user or with enrolment challenge token
+	StartMFAEnrollment(ctx context.Context, in *StartMFAEnrollmentIn) *StartMFAEnrollmentOut
+
+	// ConfirmMFAEnrollment enables MFA after the first valid code and returns the recovery codes
+	ConfirmMFAEnrollment(ctx context.Context, in *ConfirmMFAEnrollmentIn) *ConfirmMFAEnrollmentOut
+
+	// MFAStepUp re-verifies the TOTP code of logged in user for payroll-finalizing action
+	MFAStepUp(ctx context.Context, in *MFAStepUpIn) *MFAStepUpOut
+
+	// StartSSOLogin returns the authorization url of the OpenID Connect provider, the browser is sent there
+	StartSSOLogin(ctx context.Context, in *StartSSOLoginIn) *StartSSOLoginOut
+
+	// CompleteSSOLogin exchanges the code of the provider callback for tokens, or for the MFA challenge as Login does.
+	// On the first login the account is linked to the user with the same verified email.
+	CompleteSSOLogin(ctx context.Context, in *CompleteSSOLoginIn) *LoginOut
+
+	// RefreshToken exchanges a refresh token for a new access token and a new refresh token.
+	// The old refresh token is revoked, using it again revokes every token of the login.
+	RefreshToken(ctx context.Context, in *RefreshTokenIn) *RefreshTokenOut
+	Logout(ctx context.Context, in *LogoutIn) *LogoutOut
+
+	// ChangePassword verifies the current password, logs out every session and returns new tokens
+	ChangePassword(ctx context.Context, in *ChangePasswordIn) *ChangePasswordOut
+
+	// ForgotPassword sends a single use reset link, it succeeds for unknown user too
+	ForgotPassword(ctx context.Context, in *ForgotPasswordIn) *ForgotPasswordOut
+	ResetPassword(ctx context.Context, in *ResetPasswordIn) *ResetPasswordOut
+
+	// CreateUser creates an employee or admin account (admin only),
+	// a temporary password is generated and returned when none is given
+	CreateUser(ctx context.Context, in *CreateUserIn) *CreateUserOut
+
+	// SetPassword replaces the password of a user and logs out every session (admin only),
+	// a temporary password is generated and returned when none is given
+	SetPassword(ctx context.Context, in *SetPasswordIn) *SetPasswordOut
+
+	// UnlockUser clears the failed login lockout of a user (admin only)
+	UnlockUser(ctx context.Context, in *UnlockUserIn) *UnlockUserOut
+
+	// RevokeSessions logs out every session of a user (admin only)
+	RevokeSessions(ctx context.Context, in *RevokeSessionsIn) *RevokeSessionsOut
+
+	// ValidateSession checks an access token is not revoked and its user is still active, used by AuthMiddleware
+	ValidateSession(ctx context.Context, in *ValidateSessionIn) *ValidateSessionOut
+
+	// CreateAPIToken creates a personal access token of the logged in user, or a token of a service account (admin only).
+	// The token is only returned here, it is stored hashed.
+	CreateAPIToken(ctx context.Context, in *CreateAPITokenIn) *CreateAPITokenOut
+	ListAPITokens(ctx context.Context, in *ListAPITokensIn) *ListAPITokensOut
+
+	// RevokeAPIToken revokes a token of the logged in user, admin may revoke any token
+	RevokeAPIToken(ctx context.Context, in *RevokeAPITokenIn) *RevokeAPITokenOut
+
+	CreateServiceAccount(ctx context.Context, in *CreateServiceAccountIn) *CreateServiceAccountOut
+	ListServiceAccounts(ctx context.Context, in *ListServiceAccountsIn) *ListServiceAccountsOut
+
+	// AuthenticateAPIToken resolves an api token to the user it acts as, used by AuthMiddleware
+	AuthenticateAPIToken(ctx context.Context, in *AuthenticateAPITokenIn) *AuthenticateAPITokenOut
+
 	UserSalary(ctx context.Context, in *UserSalaryIn) *UserSalaryOut
+	UserCostCenter(ctx context.Context, in *UserCostCenterIn) *UserCostCenterOut
+
+	UserTaxProfiles(ctx context.Context, in *UserTaxProfilesIn) *UserTaxProfilesOut
+	SetTaxProfile(ctx context.Context, in *SetTaxProfileIn) *SetTaxProfileOut
+
+	// UserContacts returns the contact of every active user, used to send reminders
+	UserContacts(ctx context.Context, in *UserContactsIn) *UserContactsOut
+
+	// UserBankAccounts returns the bank account of every user that has one, used for the bank transfer file
+	UserBankAccounts(ctx context.Context, in *UserBankAccountsIn) *UserBankAccountsOut
+	SetBankAccount(ctx context.Context, in *SetBankAccountIn) *SetBankAccountOut
+
+	UserEmployments(ctx context.Context, in *UserEmploymentsIn) *UserEmploymentsOut
+	SetReligion(ctx context.Context, in *SetReligionIn) *SetReligionOut
 }
 
 type LoginIn struct {
 	Trace    *contextutil.Trace
 	UserName string
 	Password string
+	IP       string // client ip, failed attempts are throttled per username and per ip
 }
 
 type LoginOut struct {
 	Success bool
-	Message string
+	apperror.Failure
+
+	Token        string // access token
+	RefreshToken string
+	ExpiresIn    int // access token lifetime in seconds
+
+	// MFARequired means the password is valid but Token is not issued yet,
+	// the client continues with ChallengeToken
+	MFARequired bool
+
+	// MFAEnrollRequired means the role requires MFA but the user has not enrolled,
+	// ChallengeToken can only be used to enroll
+	MFAEnrollRequired bool
+	ChallengeToken    string
+
+	// RetryAfter is set in seconds when the username or ip is throttled
+	RetryAfter int
+}
+
+type StartSSOLoginIn struct {
+	Trace    *contextutil.Trace
+	Provider string
+}
+
+type StartSSOLoginOut struct {
+	Success bool
+	apperror.Failure
+
+	AuthorizationURL string
+}
+
+type CompleteSSOLoginIn struct {
+	Trace    *contextutil.Trace
+	Provider string
+	Code     string
+	State    string
+	IP       string
+}
+
+type ChangePasswordIn struct {
+	Trace           *contextutil.Trace
+	CurrentPassword string
+	NewPassword     string
+	IP              string
+}
+
+type ChangePasswordOut struct {
+	Success bool
+	apperror.Failure
+
+	Token        string
+	RefreshToken string
+	ExpiresIn    int
+	RetryAfter   int
+}
+
+type ForgotPasswordIn struct {
+	Trace *contextutil.Trace
+	Login string // username or email
+	IP    string
+}
+
+type ForgotPasswordOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type ResetPasswordIn struct {
+	Trace       *contextutil.Trace
+	Token       string
+	NewPassword string
+	IP          string
+}
 
+type ResetPasswordOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type CreateUserIn struct {
+	Trace      *contextutil.Trace
+	Fullname   string
+	Username   string
+	Email      string
+	Password   string        // empty generates a temporary password
+	Role       data.UserRole // empty means employee
+	BaseSalary int
+	JoinDate   time.Time
+}
+
+type CreateUserOut struct {
+	Success bool
+	apperror.Failure
+
+	UserId int
+
+	// TemporaryPassword is only set when it was generated, it is not stored in clear anywhere
+	TemporaryPassword string
+}
+
+type SetPasswordIn struct {
+	Trace    *contextutil.Trace
+	UserId   int
+	Password string // empty generates a temporary password
+}
+
+type SetPasswordOut struct {
+	Success bool
+	apperror.Failure
+
+	TemporaryPassword string
+}
+
+type UnlockUserIn struct {
+	Trace  *contextutil.Trace
+	UserId int
+}
+
+type UnlockUserOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type VerifyMFALoginIn struct {
+	Trace          *contextutil.Trace
+	ChallengeToken string
+	Code           string // TOTP code
+	RecoveryCode   string // used when Code is empty
+	IP             string
+}
+
+type StartMFAEnrollmentIn struct {
+	Trace *contextutil.Trace
+
+	// ChallengeToken from Login when enrolment is required, empty for logged in user
+	ChallengeToken string
+}
+
+type StartMFAEnrollmentOut struct {
+	Success bool
+	apperror.Failure
+
+	Secret          string
+	ProvisioningURI string // otpauth:// uri, shown as QR code
+}
+
+type ConfirmMFAEnrollmentIn struct {
+	Trace          *contextutil.Trace
+	ChallengeToken string
+	Code           string
+}
+
+type ConfirmMFAEnrollmentOut struct {
+	Success bool
+	apperror.Failure
+
+	RecoveryCodes []string
+
+	// only set when enrolling with challenge token, the login is completed
+	Token        string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+type MFAStepUpIn struct {
+	Trace *contextutil.Trace
+	Code  string
+	IP    string
+}
+
+type MFAStepUpOut struct {
+	Success bool
+	apperror.Failure
+
+	StepUpToken string
+	ExpiresIn   int
+	RetryAfter  int
+}
+
+type RefreshTokenIn struct {
+	Trace        *contextutil.Trace
+	RefreshToken string
+}
+
+type RefreshTokenOut struct {
+	Success bool
+	apperror.Failure
+
+	Token        string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+type LogoutIn struct {
+	Trace        *contextutil.Trace
+	RefreshToken string // optional, revoked together with the access token
+}
+
+type LogoutOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type RevokeSessionsIn struct {
+	Trace  *contextutil.Trace
+	UserId int
+}
+
+type RevokeSessionsOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type ValidateSessionIn struct {
+	Trace        *contextutil.Trace
+	UserId       int
+	TokenVersion int
+	SessionId    string // jti of the access token
+}
+
+type ValidateSessionOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type CreateAPITokenIn struct {
+	Trace            *contextutil.Trace
+	ServiceAccountId int // 0 for personal access token of the logged in user
+	Name             string
+	Scopes           []string
+	ExpiresInDays    int // 0 for the default lifetime
+}
+
+type CreateAPITokenOut struct {
+	Success bool
+	apperror.Failure
+
+	Token    string // only shown once
+	APIToken *data.APIToken
+}
+
+type ListAPITokensIn struct {
+	Trace            *contextutil.Trace
+	ServiceAccountId int // 0 for the tokens of the logged in user
+}
+
+type ListAPITokensOut struct {
+	Success bool
+	apperror.Failure
+
+	APITokens []*data.APIToken
+}
+
+type RevokeAPITokenIn struct {
+	Trace   *contextutil.Trace
+	TokenId int
+}
+
+type RevokeAPITokenOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type CreateServiceAccountIn struct {
+	Trace       *contextutil.Trace
+	Name        string
+	Description string
+	Role        data.UserRole
+}
+
+type CreateServiceAccountOut struct {
+	Success bool
+	apperror.Failure
+
+	ServiceAccount *data.ServiceAccount
+}
+
+type ListServiceAccountsIn struct {
+	Trace *contextutil.Trace
+}
+
+type ListServiceAccountsOut struct {
+	Success bool
+	apperror.Failure
+
+	ServiceAccounts []*data.ServiceAccount
+}
+
+type AuthenticateAPITokenIn struct {
+	Trace *contextutil.Trace
 	Token string
+	IP    string
+}
+
+type AuthenticateAPITokenOut struct {
+	Success bool
+	apperror.Failure
+
+	User *contextutil.AuthUser
 }
 
 type UserSalaryIn struct {
@@ -31,8 +411,104 @@ type UserSalaryIn struct {
 
 type UserSalaryOut struct {
 	Success bool
-	Message string
+	apperror.Failure
 
 	// Result key is userId and value is baseSalary
 	Result map[int]int
 }
+
+type UserCostCenterIn struct {
+	Trace *contextutil.Trace
+}
+
+type UserCostCenterOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result key is userId and value is cost center
+	Result map[int]string
+}
+
+type UserTaxProfilesIn struct {
+	Trace *contextutil.Trace
+}
+
+type UserTaxProfilesOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result key is userId
+	Result map[int]*data.UserTaxProfile
+}
+
+type SetTaxProfileIn struct {
+	Trace      *contextutil.Trace
+	UserId     int
+	Npwp       string
+	Nik        string
+	PTKPStatus common.PTKPStatus
+}
+
+type SetTaxProfileOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type UserContactsIn struct {
+	Trace *contextutil.Trace
+}
+
+type UserContactsOut struct {
+	Success bool
+	apperror.Failure
+
+	Result []*data.UserContact
+}
+
+type UserBankAccountsIn struct {
+	Trace *contextutil.Trace
+}
+
+type UserBankAccountsOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result key is userId
+	Result map[int]*data.UserBankAccount
+}
+
+type SetBankAccountIn struct {
+	Trace         *contextutil.Trace
+	UserId        int
+	BankCode      string
+	AccountNumber string
+	AccountName   string
+}
+
+type SetBankAccountOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type UserEmploymentsIn struct {
+	Trace *contextutil.Trace
+}
+
+type UserEmploymentsOut struct {
+	Success bool
+	apperror.Failure
+
+	// Result is every active user, ordered by user id
+	Result []*data.UserEmployment
+}
+
+type SetReligionIn struct {
+	Trace    *contextutil.Trace
+	UserId   int
+	Religion data.Religion
+}
+
+type SetReligionOut struct {
+	Success bool
+	apperror.Failure
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ariesmaulana/payroll/common"
 	"github.com/ariesmaulana/payroll/data"
 	"github.com/jackc/pgx/v4"
 
@@ -15,7 +16,119 @@ type StorageInterface interface {
 	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
 
 	InsertUser(ctx context.Context, fullname string, username string, email string, password string, baseSalary int, joinDate time.Time) (int, error)
+
+	// IsUsernameOrEmailTaken also checks one against the other, GetUserByLogin accepts either as login
+	IsUsernameOrEmailTaken(ctx context.Context, username string, email string) (bool, error)
 	GetUserByUsername(ctx context.Context, username string) (*data.User, database.ErrType, error)
+	GetUserById(ctx context.Context, userId int) (*data.User, database.ErrType, error)
+
+	// GetUserByLogin finds the user by username or email (case insensitive)
+	GetUserByLogin(ctx context.Context, login string) (*data.User, database.ErrType, error)
+	GetUserByEmail(ctx context.Context, email string) (*data.User, database.ErrType, error)
+	UpdateUserPassword(ctx context.Context, userId int, passwordHash string, updatedBy string) error
 
 	GetAllUserBaseSalary(ctx context.Context) (map[int]int, database.ErrType, error)
+	GetAllUserCostCenter(ctx context.Context) (map[int]string, database.ErrType, error)
+
+	// GetAllUserEmployment returns base salary, join date and religion of every active user
+	GetAllUserEmployment(ctx context.Context) ([]*data.UserEmployment, database.ErrType, error)
+	UpdateUserReligion(ctx context.Context, userId int, religion data.Religion, updatedBy string) error
+	UpdateUserRole(ctx context.Context, userId int, role data.UserRole, updatedBy string) error
+
+	// GetAllUserTaxProfile returns tax profile of every user, user without profile get empty NPWP/NIK and TK/0
+	GetAllUserTaxProfile(ctx context.Context) ([]*data.UserTaxProfile, database.ErrType, error)
+
+	// GetUserTaxProfile returns database.ErrNotFound when the user does not exist, a user without profile gets TK/0
+	GetUserTaxProfile(ctx context.Context, userId int) (*data.UserTaxProfile, database.ErrType, error)
+	IsUserExists(ctx context.Context, userId int) (bool, error)
+	UpsertUserTaxProfile(ctx context.Context, userId int, npwp string, nik string, ptkpStatus common.PTKPStatus, updatedBy string) error
+
+	// GetActiveUserContacts returns the contact of every active user
+	GetActiveUserContacts(ctx context.Context) ([]*data.UserContact, error)
+
+	// GetAllUserBankAccount returns the bank account of every user that has one
+	GetAllUserBankAccount(ctx context.Context) ([]*data.UserBankAccount, error)
+
+	// GetUserBankAccount returns database.ErrNotFound when the user does not exist, a user without account gets empty fields
+	GetUserBankAccount(ctx context.Context, userId int) (*data.UserBankAccount, database.ErrType, error)
+	UpsertUserBankAccount(ctx context.Context, account *data.UserBankAccount, updatedBy string) error
+
+	// IncrementTokenVersion invalidates every access token issued to the user
+	IncrementTokenVersion(ctx context.Context, userId int, updatedBy string) error
+
+	InsertRefreshToken(ctx context.Context, userId int, tokenHash string, familyId string, expiresAt time.Time) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*data.RefreshToken, database.ErrType, error)
+
+	// RevokeRefreshToken returns false if the token was already revoked, eg: used by another request
+	RevokeRefreshToken(ctx context.Context, tokenId int) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId int) error
+
+	InsertRevokedAccessToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error
+
+	// GetSessionState returns what is needed to check an access token is not revoked
+	GetSessionState(ctx context.Context, userId int, jti string) (*data.SessionState, database.ErrType, error)
+
+	GetUserMFA(ctx context.Context, userId int) (*data.UserMFA, database.ErrType, error)
+
+	// UpsertPendingMFA stores a new secret waiting for confirmation, an enabled MFA is disabled until confirmed again
+	UpsertPendingMFA(ctx context.Context, userId int, secret string) error
+	EnableMFA(ctx context.Context, userId int) error
+
+	// UseTOTPStep returns false if the step (or a later one) was already used, ie: the code is replayed
+	UseTOTPStep(ctx context.Context, userId int, step int64) (bool, error)
+
+	ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error
+
+	// UseRecoveryCode returns false if the code is unknown or already used
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+
+	// GetActiveLoginThrottles returns the username / ip counters that are locked right now
+	GetActiveLoginThrottles(ctx context.Context, username string, ip string) ([]*data.LoginThrottle, error)
+
+	// IncrementLoginFailure returns the failed count in the window, including this failure
+	IncrementLoginFailure(ctx context.Context, scope data.LoginThrottleScope, key string, window time.Duration) (int, error)
+	SetLoginLockedUntil(ctx context.Context, scope data.LoginThrottleScope, key string, lockedUntil time.Time) error
+
+	// ResetLoginThrottle returns false if there was no counter
+	ResetLoginThrottle(ctx context.Context, scope data.LoginThrottleScope, key string) (bool, error)
+
+	InsertAuthEvent(ctx context.Context, event *data.AuthEvent) error
+
+	// InsertPasswordResetToken also invalidates the previous unused token of the user
+	InsertPasswordResetToken(ctx context.Context, userId int, tokenHash string, expiresAt time.Time) error
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (*data.PasswordResetToken, database.ErrType, error)
+
+	// UsePasswordResetToken returns false if the token was already used
+	UsePasswordResetToken(ctx context.Context, tokenId int) (bool, error)
+
+	InsertOIDCLoginState(ctx context.Context, state *data.OIDCLoginState) error
+
+	// TakeOIDCLoginState deletes and returns the pending login, a state can only be used once
+	TakeOIDCLoginState(ctx context.Context, state string) (*data.OIDCLoginState, database.ErrType, error)
+
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*data.User, database.ErrType, error)
+
+	// InsertUserIdentity returns false if the user is already linked to another account of the provider
+	InsertUserIdentity(ctx context.Context, userId int, provider string, subject string, email string) (bool, error)
+
+	// InsertServiceAccount returns database.ErrDuplicate when the name is taken
+	InsertServiceAccount(ctx context.Context, name string, description string, role data.UserRole, createdBy string) (int, database.ErrType, error)
+	GetServiceAccount(ctx context.Context, serviceAccountId int) (*data.ServiceAccount, database.ErrType, error)
+	GetAllServiceAccount(ctx context.Context) ([]*data.ServiceAccount, error)
+
+	InsertAPIToken(ctx context.Context, token *data.APIToken, tokenHash string, createdBy string) (int, error)
+	GetAPIToken(ctx context.Context, tokenId int) (*data.APIToken, database.ErrType, error)
+
+	// GetAPITokens returns the tokens of a user (serviceAccountId 0) or of a service account, newest first
+	GetAPITokens(ctx context.Context, userId int, serviceAccountId int) ([]*data.APIToken, error)
+
+	// RevokeAPIToken returns false if the token was already revoked
+	RevokeAPIToken(ctx context.Context, tokenId int) (bool, error)
+
+	// GetAPITokenOwner returns the token of the hash with the user or service account it acts as
+	GetAPITokenOwner(ctx context.Context, tokenHash string) (*data.APITokenOwner, database.ErrType, error)
+
+	// TouchAPIToken records the last use, at most once a minute so every request does not write
+	TouchAPIToken(ctx context.Context, tokenId int, ip string) error
 }
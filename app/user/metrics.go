@@ -0,0 +1,21 @@
+package user
+
+import (
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var loginFailures = promauto.With(metrics.Default).NewCounterVec(prometheus.CounterOpts{
+	Name: "payroll_login_failures_total",
+	Help: "Rejected logins by event: login_failed, login_throttled or mfa_failed",
+}, []string{"event"})
+
+// countLoginFailure counts the auth events of a rejected login
+func countLoginFailure(event data.AuthEventType) {
+	switch event {
+	case data.AuthLoginFailed, data.AuthLoginThrottled, data.AuthMFAFailed:
+		loginFailures.WithLabelValues(string(event)).Inc()
+	}
+}
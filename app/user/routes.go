@@ -1,9 +1,55 @@
 package user
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
 
 func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/login", h.Login)
+		r.Post("/refresh", h.RefreshToken)
+
+		// second step of login, authenticated by the challenge token in the body
+		r.Post("/login/mfa", h.LoginMFA)
+		r.Post("/login/mfa/enroll", h.StartMFAEnrollment)
+		r.Post("/login/mfa/enroll/confirm", h.ConfirmMFAEnrollment)
+
+		// single sign-on with OpenID Connect provider, eg: /users/sso/google/login
+		r.Get("/sso/{provider}/login", h.StartSSOLogin)
+		r.Get("/sso/{provider}/callback", h.SSOCallback)
+		r.Post("/sso/{provider}/callback", h.SSOCallback)
+
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(data.ScopeEmployeeWrite))
+				r.Put("/{userId}/tax-profile", h.SetTaxProfile)
+				r.Put("/{userId}/religion", h.SetReligion)
+			})
+			r.Post("/logout", h.Logout)
+			r.Post("/password", h.ChangePassword)
+
+			r.Post("/mfa/enroll", h.StartMFAEnrollment)
+			r.Post("/mfa/enroll/confirm", h.ConfirmMFAEnrollment)
+			r.Post("/mfa/step-up", h.MFAStepUp)
+			r.Post("/{userId}/revoke-sessions", h.RevokeSessions)
+			r.Post("/{userId}/unlock", h.UnlockUser)
+
+			// api tokens are managed with a login session only
+			r.Post("/tokens", h.CreateAPIToken)
+			r.Get("/tokens", h.ListAPITokens)
+			r.Delete("/tokens/{tokenId}", h.RevokeAPIToken)
+			r.Post("/service-accounts", h.CreateServiceAccount)
+			r.Get("/service-accounts", h.ListServiceAccounts)
+			r.Post("/service-accounts/{serviceAccountId}/tokens", h.CreateAPIToken)
+			r.Get("/service-accounts/{serviceAccountId}/tokens", h.ListAPITokens)
+		})
 	})
 }
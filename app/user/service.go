@@ -2,72 +2,485 @@ package user
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	auditLib "github.com/ariesmaulana/payroll/app/audit/lib"
 	"github.com/ariesmaulana/payroll/app/user/lib"
 	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/internal/oidc"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
 	"github.com/ariesmaulana/payroll/lib/database"
 	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/notifier"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
 var _ lib.ServiceInterface = (*Service)(nil)
 
 type Service struct {
-	storage lib.StorageInterface
+	storage      lib.StorageInterface
+	mfa          MFAPolicy
+	password     PasswordPolicy
+	notifier     notifier.Notifier
+	sso          map[string]SSOProvider
+	auditService auditLib.ServiceInterface
 }
 
-func NewService(storage lib.StorageInterface) *Service {
+func NewService(storage lib.StorageInterface, mfa MFAPolicy, password PasswordPolicy, notifier notifier.Notifier, sso map[string]SSOProvider, auditService auditLib.ServiceInterface) *Service {
 	return &Service{
-		storage: storage,
+		storage:      storage,
+		mfa:          mfa,
+		password:     password,
+		notifier:     notifier,
+		sso:          sso,
+		auditService: auditService,
 	}
 }
 
+// audit records the action in the audit trail, the caller fails with internal error when it is not recorded.
+// Login, refresh and logout are not audited here, they are in auth_events.
+func (s *Service) audit(ctx context.Context, in *auditLib.RecordIn) bool {
+	out := s.auditService.Record(ctx, in)
+	if !out.Success {
+		log.Error(in.Trace).Str("action", in.Action).Str("reason", out.Message).Msg("audit/ record failed")
+	}
+	return out.Success
+}
+
 func (s *Service) Login(ctx context.Context, in *lib.LoginIn) *lib.LoginOut {
 	resp := lib.LoginOut{}
 
 	if in.UserName == "" {
 		log.Warn(in.Trace).Msg("username is empty")
-		resp.Message = "username tidak boleh kosong"
+		resp.Fail(apperror.CodeInvalidInput, "username tidak boleh kosong")
 		return &resp
 	}
 
 	if in.Password == "" {
 		log.Warn(in.Trace).Msg("password is empty")
-		resp.Message = "password tidak boleh kosong"
+		resp.Fail(apperror.CodeInvalidInput, "password tidak boleh kosong")
 		return &resp
 	}
 
-	tx, err := s.storage.BeginTxReader(ctx)
+	tx, err := s.storage.BeginTxWriter(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("Failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	retryAfter, err := s.loginRetryAfter(ctx, in.Trace, in.UserName, in.IP)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if retryAfter > 0 {
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{Username: in.UserName, IP: in.IP, Event: data.AuthLoginThrottled})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeTooManyRequests, "Terlalu banyak percobaan login, coba lagi nanti")
+		resp.RetryAfter = retryAfter
+		return &resp
+	}
 
 	user, errType, err := s.storage.GetUserByUsername(ctx, in.UserName)
 	if err != nil && errType != database.ErrNotFound {
 		log.Error(in.Trace).Err(err).Str("type", string(errType)).Msg("failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
-	// Check is password is valid
-	valid := common.VerifyDjangoPBKDF2Password(in.Password, user.Password)
+	// Check is password is valid, unknown username is checked against a dummy hash
+	// so both cases take the same time
+	passwordHash := dummyPasswordHash()
+	if user != nil {
+		passwordHash = user.Password
+	}
+	valid := common.VerifyPassword(in.Password, passwordHash) && user != nil
 
 	if !valid {
-		log.Error(in.Trace).Msg("password invalid")
-		resp.Message = "Password tidak valid"
+		event := &data.AuthEvent{Username: in.UserName, IP: in.IP, Event: data.AuthLoginFailed, Detail: "unknown username"}
+		if user != nil {
+			event.UserId = user.Id
+			event.Detail = "invalid password"
+		}
+		log.Warn(in.Trace).Str("reason", event.Detail).Msg("login failed")
+
+		if err := s.recordLoginFailure(ctx, in.Trace, event); err != nil {
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeInvalidCredentials, "Username atau password tidak valid")
+		return &resp
+	}
+
+	if !user.IsActive {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("user not active")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginFailed, Detail: "user not active"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeUserInactive, "User tidak aktif")
+		return &resp
+	}
+
+	// the plain password is only known here, upgrade a legacy (Django) or weak hash transparently
+	if common.PasswordNeedsUpgrade(user.Password) {
+		s.rehashPassword(ctx, in.Trace, user, in.Password)
+	}
+
+	challenge, err := s.mfaChallenge(ctx, in.Trace, user)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if challenge != nil {
+		// the failed counter is only reset once the second factor is verified too
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginSuccess, Detail: "password verified, mfa challenge"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		return challenge
+	}
+
+	// every login starts a new refresh token family
+	token, refreshToken, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed issue token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if err := s.recordLoginSuccess(ctx, in.Trace, user, in.IP, ""); err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Token = token
+	resp.RefreshToken = refreshToken
+	resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	return &resp
+}
+
+// mfaChallenge is the login response asking for the second factor, nil when the user has no MFA enabled
+// and its role does not require it. The tokens are then issued by VerifyMFALogin or ConfirmMFAEnrollment.
+func (s *Service) mfaChallenge(ctx context.Context, trace *contextutil.Trace, user *data.User) (*lib.LoginOut, error) {
+	mfa, errType, err := s.storage.GetUserMFA(ctx, user.Id)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(trace).Err(err).Msg("failed get user mfa")
+		return nil, err
+	}
+
+	mfaEnabled := mfa != nil && mfa.Enabled
+	if !mfaEnabled && !s.mfa.requires(user.Role) {
+		return nil, nil
+	}
+
+	purpose := jwtutil.PurposeMFALogin
+	if !mfaEnabled {
+		purpose = jwtutil.PurposeMFAEnroll
+	}
+	token, err := jwtutil.GenerateChallengeJWT(user.Id, user.Username, user.Role, user.TokenVersion, purpose)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed generate challenge token")
+		return nil, err
+	}
+
+	return &lib.LoginOut{
+		Success:           true,
+		MFARequired:       true,
+		MFAEnrollRequired: !mfaEnabled,
+		ChallengeToken:    token,
+		ExpiresIn:         int(jwtutil.ChallengeTokenTTL.Seconds()),
+	}, nil
+}
+
+// issueTokens creates an access token and stores a new refresh token in the given family
+func (s *Service) issueTokens(ctx context.Context, user *data.User, familyId string) (string, string, error) {
+	token, err := jwtutil.GenerateJWT(user.Id, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.storage.InsertRefreshToken(ctx, user.Id, hashToken(refreshToken), familyId, time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+func (s *Service) RefreshToken(ctx context.Context, in *lib.RefreshTokenIn) *lib.RefreshTokenOut {
+	resp := lib.RefreshTokenOut{}
+
+	if in.RefreshToken == "" {
+		log.Warn(in.Trace).Msg("RefreshToken/ empty token")
+		resp.Fail(apperror.CodeTokenInvalid, "Refresh token tidak valid")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	stored, errType, err := s.storage.GetRefreshToken(ctx, hashToken(in.RefreshToken))
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Msg("RefreshToken/ token not found")
+			resp.Fail(apperror.CodeTokenInvalid, "Refresh token tidak valid")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed get token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		log.Warn(in.Trace).Int("userId", stored.UserId).Msg("RefreshToken/ token expired")
+		resp.Fail(apperror.CodeTokenExpired, "Refresh token kedaluwarsa")
+		return &resp
+	}
+
+	// a revoked token being used again means it was stolen (or replayed),
+	// revoke the whole family so neither the thief nor the owner can continue
+	rotated, err := s.storage.RevokeRefreshToken(ctx, stored.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed revoke token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !rotated {
+		log.Warn(in.Trace).Int("userId", stored.UserId).Str("family", stored.FamilyId).Msg("RefreshToken/ token reused, revoke family")
+		if err := s.storage.RevokeRefreshTokenFamily(ctx, stored.FamilyId); err != nil {
+			log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed revoke family")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		if err := tx.Commit(ctx); err != nil {
+			log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed commit")
+		}
+		resp.Fail(apperror.CodeTokenInvalid, "Refresh token tidak valid")
+		return &resp
+	}
+
+	user, _, err := s.storage.GetUserById(ctx, stored.UserId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !user.IsActive {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("RefreshToken/ user not active")
+		resp.Fail(apperror.CodeUserInactive, "User tidak aktif")
+		return &resp
+	}
+
+	token, refreshToken, err := s.issueTokens(ctx, user, stored.FamilyId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed issue token")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
-	token, err := jwtutil.GenerateJWT(user.Id, user.Username, user.Role)
+	err = tx.Commit(ctx)
 	if err != nil {
-		log.Error(in.Trace).Err(err).Msg("failed generate jwt")
-		resp.Message = "internal error"
+		log.Error(in.Trace).Err(err).Msg("RefreshToken/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 
 	resp.Success = true
 	resp.Token = token
+	resp.RefreshToken = refreshToken
+	resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	return &resp
+}
+
+func (s *Service) Logout(ctx context.Context, in *lib.LogoutIn) *lib.LogoutOut {
+	resp := lib.LogoutOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("Logout/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Logout/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// the access token used to call logout stops working right away
+	if user.SessionId != "" {
+		err = s.storage.InsertRevokedAccessToken(ctx, user.SessionId, user.Id, user.ExpiresAt)
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("Logout/ failed revoke access token")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	if in.RefreshToken != "" {
+		stored, errType, err := s.storage.GetRefreshToken(ctx, hashToken(in.RefreshToken))
+		if err != nil && errType != database.ErrNotFound {
+			log.Error(in.Trace).Err(err).Msg("Logout/ failed get refresh token")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+
+		// token of other user is ignored, logout must not be a way to kick someone else
+		if stored != nil && stored.UserId == user.Id {
+			if err := s.storage.RevokeRefreshTokenFamily(ctx, stored.FamilyId); err != nil {
+				log.Error(in.Trace).Err(err).Msg("Logout/ failed revoke refresh token")
+				resp.Fail(apperror.CodeInternal, "internal error")
+				return &resp
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Logout/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) RevokeSessions(ctx context.Context, in *lib.RevokeSessionsIn) *lib.RevokeSessionsOut {
+	resp := lib.RevokeSessionsOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("RevokeSessions/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("RevokeSessions/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeSessions/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	exists, err := s.storage.IsUserExists(ctx, in.UserId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeSessions/ failed check user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !exists {
+		log.Warn(in.Trace).Int("userId", in.UserId).Msg("RevokeSessions/ user not found")
+		resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+		return &resp
+	}
+
+	err = s.storage.IncrementTokenVersion(ctx, in.UserId, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeSessions/ failed increment token version")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = s.storage.RevokeUserRefreshTokens(ctx, in.UserId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeSessions/ failed revoke refresh tokens")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "session.revoke_all",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(in.UserId),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeSessions/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) ValidateSession(ctx context.Context, in *lib.ValidateSessionIn) *lib.ValidateSessionOut {
+	resp := lib.ValidateSessionOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ValidateSession/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	state, errType, err := s.storage.GetSessionState(ctx, in.UserId, in.SessionId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("ValidateSession/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("ValidateSession/ failed get session state")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if appErr := sessionRevokedReason(state, in.TokenVersion); appErr != nil {
+		log.Warn(in.Trace).Int("userId", in.UserId).Str("reason", appErr.ID).Msg("ValidateSession/ session revoked")
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	resp.Success = true
 	return &resp
 }
 
@@ -77,15 +490,17 @@ func (s *Service) UserSalary(ctx context.Context, in *lib.UserSalaryIn) *lib.Use
 	tx, err := s.storage.BeginTxReader(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
 		return &resp
 	}
 	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
 
 	salaries, errType, err := s.storage.GetAllUserBaseSalary(ctx)
 	if err != nil {
 		log.Error(in.Trace).Err(err).Str("type", string(errType)).Msg("failed get user base salary")
 		if errType == database.ErrNotFound {
-			resp.Message = "Tidak ada data user ditemukan"
+			resp.Fail(apperror.CodeUserNotFound, "Tidak ada data user ditemukan")
 		}
 		return &resp
 	}
@@ -94,3 +509,2182 @@ func (s *Service) UserSalary(ctx context.Context, in *lib.UserSalaryIn) *lib.Use
 	resp.Result = salaries
 	return &resp
 }
+
+func (s *Service) UserCostCenter(ctx context.Context, in *lib.UserCostCenterIn) *lib.UserCostCenterOut {
+	resp := lib.UserCostCenterOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	costCenters, errType, err := s.storage.GetAllUserCostCenter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Str("type", string(errType)).Msg("failed get user cost center")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Result = costCenters
+	return &resp
+}
+
+func (s *Service) UserTaxProfiles(ctx context.Context, in *lib.UserTaxProfilesIn) *lib.UserTaxProfilesOut {
+	resp := lib.UserTaxProfilesOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	profiles, errType, err := s.storage.GetAllUserTaxProfile(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Str("type", string(errType)).Msg("failed get user tax profile")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	result := make(map[int]*data.UserTaxProfile)
+	for _, p := range profiles {
+		result[p.UserId] = p
+	}
+
+	resp.Success = true
+	resp.Result = result
+	return &resp
+}
+
+func (s *Service) SetTaxProfile(ctx context.Context, in *lib.SetTaxProfileIn) *lib.SetTaxProfileOut {
+	resp := lib.SetTaxProfileOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SetTaxProfile/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SetTaxProfile/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	if !common.IsValidPTKPStatus(in.PTKPStatus) {
+		log.Warn(in.Trace).Str("ptkp", string(in.PTKPStatus)).Msg("SetTaxProfile/ invalid ptkp status")
+		resp.Fail(apperror.CodeInvalidInput, "Status PTKP tidak valid")
+		return &resp
+	}
+
+	if in.Npwp != "" && !common.ValidateNPWP(in.Npwp) {
+		log.Warn(in.Trace).Msg("SetTaxProfile/ invalid npwp")
+		resp.Fail(apperror.CodeInvalidInput, "NPWP harus 15 atau 16 digit angka")
+		return &resp
+	}
+
+	if in.Nik != "" && !common.ValidateNIK(in.Nik) {
+		log.Warn(in.Trace).Msg("SetTaxProfile/ invalid nik")
+		resp.Fail(apperror.CodeInvalidInput, "NIK harus 16 digit angka")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetTaxProfile/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	before, errType, err := s.storage.GetUserTaxProfile(ctx, in.UserId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("SetTaxProfile/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("SetTaxProfile/ failed get tax profile")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = s.storage.UpsertUserTaxProfile(ctx, in.UserId, in.Npwp, in.Nik, in.PTKPStatus, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetTaxProfile/ failed upsert tax profile")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "tax_profile.update",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(in.UserId),
+		Before:     map[string]any{"npwp": before.Npwp, "nik": before.Nik, "ptkp_status": before.PTKPStatus},
+		After:      map[string]any{"npwp": in.Npwp, "nik": in.Nik, "ptkp_status": in.PTKPStatus},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetTaxProfile/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) UserContacts(ctx context.Context, in *lib.UserContactsIn) *lib.UserContactsOut {
+	resp := lib.UserContactsOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	contacts, err := s.storage.GetActiveUserContacts(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed get user contacts")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Result = contacts
+	return &resp
+}
+
+func (s *Service) UserBankAccounts(ctx context.Context, in *lib.UserBankAccountsIn) *lib.UserBankAccountsOut {
+	resp := lib.UserBankAccountsOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	accounts, err := s.storage.GetAllUserBankAccount(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed get user bank account")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	result := make(map[int]*data.UserBankAccount)
+	for _, a := range accounts {
+		result[a.UserId] = a
+	}
+
+	resp.Success = true
+	resp.Result = result
+	return &resp
+}
+
+func (s *Service) SetBankAccount(ctx context.Context, in *lib.SetBankAccountIn) *lib.SetBankAccountOut {
+	resp := lib.SetBankAccountOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SetBankAccount/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SetBankAccount/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	if !common.ValidateBankCode(in.BankCode) {
+		resp.Fail(apperror.CodeInvalidInput, "Kode bank harus 3 digit angka")
+		return &resp
+	}
+	if !common.ValidateBankAccountNumber(in.AccountNumber) {
+		resp.Fail(apperror.CodeInvalidInput, "Nomor rekening harus 5-20 digit angka")
+		return &resp
+	}
+	in.AccountName = strings.TrimSpace(in.AccountName)
+	if in.AccountName == "" || len(in.AccountName) > 100 {
+		resp.Fail(apperror.CodeInvalidInput, "Nama pemilik rekening wajib diisi, maksimal 100 karakter")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetBankAccount/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	before, errType, err := s.storage.GetUserBankAccount(ctx, in.UserId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("SetBankAccount/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("SetBankAccount/ failed get bank account")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = s.storage.UpsertUserBankAccount(ctx, &data.UserBankAccount{
+		UserId:        in.UserId,
+		BankCode:      in.BankCode,
+		AccountNumber: in.AccountNumber,
+		AccountName:   in.AccountName,
+	}, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetBankAccount/ failed upsert bank account")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "bank_account.update",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(in.UserId),
+		Before:     map[string]any{"bank_code": before.BankCode, "account_number": before.AccountNumber, "account_name": before.AccountName},
+		After:      map[string]any{"bank_code": in.BankCode, "account_number": in.AccountNumber, "account_name": in.AccountName},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetBankAccount/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) UserEmployments(ctx context.Context, in *lib.UserEmploymentsIn) *lib.UserEmploymentsOut {
+	resp := lib.UserEmploymentsOut{}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	employments, errType, err := s.storage.GetAllUserEmployment(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Str("type", string(errType)).Msg("failed get user employment")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Result = employments
+	return &resp
+}
+
+func (s *Service) SetReligion(ctx context.Context, in *lib.SetReligionIn) *lib.SetReligionOut {
+	resp := lib.SetReligionOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SetReligion/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SetReligion/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	if !data.IsValidReligion(in.Religion) {
+		log.Warn(in.Trace).Str("religion", string(in.Religion)).Msg("SetReligion/ invalid religion")
+		resp.Fail(apperror.CodeInvalidInput, "Agama tidak valid")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetReligion/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	before, errType, err := s.storage.GetUserById(ctx, in.UserId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("SetReligion/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("SetReligion/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = s.storage.UpdateUserReligion(ctx, in.UserId, in.Religion, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetReligion/ failed update religion")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "user.update",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(in.UserId),
+		Before:     map[string]any{"religion": before.Religion},
+		After:      map[string]any{"religion": in.Religion},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetReligion/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+// challengeUser loads the user of a challenge token, the token is rejected once the user
+// is deactivated or the sessions are revoked after the challenge was issued
+func (s *Service) challengeUser(ctx context.Context, trace *contextutil.Trace, challengeToken string, purpose string) (*data.User, *apperror.Error) {
+	claims, err := jwtutil.ValidateChallengeJWT(challengeToken, purpose)
+	if err != nil {
+		log.Warn(trace).Err(err).Msg("invalid challenge token")
+		return nil, apperror.New(apperror.CodeTokenInvalid, "Challenge token tidak valid")
+	}
+
+	user, errType, err := s.storage.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(trace).Int("userId", claims.UserID).Msg("challenge user not found")
+			return nil, apperror.New(apperror.CodeTokenInvalid, "Challenge token tidak valid")
+		}
+		log.Error(trace).Err(err).Msg("failed get user")
+		return nil, apperror.Internal()
+	}
+
+	if !user.IsActive {
+		log.Warn(trace).Int("userId", user.Id).Msg("user not active")
+		return nil, apperror.New(apperror.CodeUserInactive, "User tidak aktif")
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		log.Warn(trace).Int("userId", user.Id).Msg("challenge token of revoked session")
+		return nil, apperror.New(apperror.CodeTokenInvalid, "Challenge token tidak valid")
+	}
+
+	return user, nil
+}
+
+// verifyTOTP checks the code and marks its time step as used, so a code seen by someone else is useless
+func (s *Service) verifyTOTP(ctx context.Context, trace *contextutil.Trace, mfa *data.UserMFA, code string) *apperror.Error {
+	if code == "" {
+		return apperror.New(apperror.CodeInvalidInput, "Kode OTP wajib diisi")
+	}
+
+	step, ok := common.VerifyTOTP(mfa.Secret, code, time.Now())
+	if !ok {
+		log.Warn(trace).Int("userId", mfa.UserId).Msg("invalid totp code")
+		return apperror.New(apperror.CodeMFAInvalidCode, "Kode OTP tidak valid")
+	}
+
+	fresh, err := s.storage.UseTOTPStep(ctx, mfa.UserId, step)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed use totp step")
+		return apperror.Internal()
+	}
+	if !fresh {
+		log.Warn(trace).Int("userId", mfa.UserId).Msg("totp code replayed")
+		return apperror.New(apperror.CodeMFAInvalidCode, "Kode OTP sudah dipakai")
+	}
+	return nil
+}
+
+func (s *Service) VerifyMFALogin(ctx context.Context, in *lib.VerifyMFALoginIn) *lib.LoginOut {
+	resp := lib.LoginOut{}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("VerifyMFALogin/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, appErr := s.challengeUser(ctx, in.Trace, in.ChallengeToken, jwtutil.PurposeMFALogin)
+	if appErr != nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	// the 6 digit code is throttled like the password, on the same username counter
+	retryAfter, err := s.loginRetryAfter(ctx, in.Trace, user.Username, in.IP)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if retryAfter > 0 {
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginThrottled, Detail: "mfa"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeTooManyRequests, "Terlalu banyak percobaan login, coba lagi nanti")
+		resp.RetryAfter = retryAfter
+		return &resp
+	}
+
+	mfa, errType, err := s.storage.GetUserMFA(ctx, user.Id)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("VerifyMFALogin/ failed get user mfa")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if mfa == nil || !mfa.Enabled {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("VerifyMFALogin/ mfa not enabled")
+		resp.Fail(apperror.CodeMFANotEnabled, "MFA belum aktif")
+		return &resp
+	}
+
+	if in.Code == "" && in.RecoveryCode != "" {
+		used, err := s.storage.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(in.RecoveryCode))
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("VerifyMFALogin/ failed use recovery code")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		if !used {
+			log.Warn(in.Trace).Int("userId", user.Id).Msg("VerifyMFALogin/ invalid recovery code")
+			appErr = apperror.New(apperror.CodeMFAInvalidCode, "Recovery code tidak valid")
+		} else {
+			log.Warn(in.Trace).Int("userId", user.Id).Msg("VerifyMFALogin/ login with recovery code")
+		}
+	} else {
+		appErr = s.verifyTOTP(ctx, in.Trace, mfa, in.Code)
+	}
+
+	if appErr != nil {
+		if appErr.Code != apperror.CodeInternal {
+			event := &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthMFAFailed, Detail: appErr.ID}
+			if err := s.recordLoginFailure(ctx, in.Trace, event); err != nil {
+				appErr = apperror.Internal()
+			} else {
+				s.commitAuthFailure(ctx, in.Trace, tx)
+			}
+		}
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	token, refreshToken, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("VerifyMFALogin/ failed issue token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	detail := "mfa totp"
+	if in.Code == "" {
+		detail = "mfa recovery code"
+	}
+	if err := s.recordLoginSuccess(ctx, in.Trace, user, in.IP, detail); err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("VerifyMFALogin/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Token = token
+	resp.RefreshToken = refreshToken
+	resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	return &resp
+}
+
+// enrollmentUser is the user of the enrolment challenge token, or the logged in user without challenge token
+func (s *Service) enrollmentUser(ctx context.Context, trace *contextutil.Trace, challengeToken string) (*data.User, *apperror.Error) {
+	if challengeToken != "" {
+		return s.challengeUser(ctx, trace, challengeToken, jwtutil.PurposeMFAEnroll)
+	}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(trace).Msg("unauthorized")
+		return nil, apperror.New(apperror.CodeUnauthorized, "unauthorized")
+	}
+
+	user, _, err := s.storage.GetUserById(ctx, authUser.Id)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed get user")
+		return nil, apperror.Internal()
+	}
+	return user, nil
+}
+
+func (s *Service) StartMFAEnrollment(ctx context.Context, in *lib.StartMFAEnrollmentIn) *lib.StartMFAEnrollmentOut {
+	resp := lib.StartMFAEnrollmentOut{}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartMFAEnrollment/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, appErr := s.enrollmentUser(ctx, in.Trace, in.ChallengeToken)
+	if appErr != nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	mfa, errType, err := s.storage.GetUserMFA(ctx, user.Id)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("StartMFAEnrollment/ failed get user mfa")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// re-enrolling would let a stolen access token replace the second factor
+	if mfa != nil && mfa.Enabled {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("StartMFAEnrollment/ mfa already enabled")
+		resp.Fail(apperror.CodeMFAEnabled, "MFA sudah aktif")
+		return &resp
+	}
+
+	secret, err := common.GenerateTOTPSecret()
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartMFAEnrollment/ failed generate secret")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = s.storage.UpsertPendingMFA(ctx, user.Id, secret)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartMFAEnrollment/ failed store secret")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// enrolment during login has no session yet, the user is the actor
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:       in.Trace,
+		Action:      "mfa.enroll",
+		EntityType:  "user",
+		EntityId:    strconv.Itoa(user.Id),
+		Actor:       user.Username,
+		ActorUserId: user.Id,
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartMFAEnrollment/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Secret = secret
+	resp.ProvisioningURI = common.TOTPProvisioningURI(s.mfa.Issuer, user.Username, secret)
+	return &resp
+}
+
+func (s *Service) ConfirmMFAEnrollment(ctx context.Context, in *lib.ConfirmMFAEnrollmentIn) *lib.ConfirmMFAEnrollmentOut {
+	resp := lib.ConfirmMFAEnrollmentOut{}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, appErr := s.enrollmentUser(ctx, in.Trace, in.ChallengeToken)
+	if appErr != nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	mfa, errType, err := s.storage.GetUserMFA(ctx, user.Id)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", user.Id).Msg("ConfirmMFAEnrollment/ enrollment not started")
+			resp.Fail(apperror.CodeMFANotEnabled, "MFA belum didaftarkan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed get user mfa")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if mfa.Enabled {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("ConfirmMFAEnrollment/ mfa already enabled")
+		resp.Fail(apperror.CodeMFAEnabled, "MFA sudah aktif")
+		return &resp
+	}
+
+	if appErr := s.verifyTOTP(ctx, in.Trace, mfa, in.Code); appErr != nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	err = s.storage.EnableMFA(ctx, user.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed enable mfa")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed generate recovery codes")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	err = s.storage.ReplaceRecoveryCodes(ctx, user.Id, hashes)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed store recovery codes")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:       in.Trace,
+		Action:      "mfa.enable",
+		EntityType:  "user",
+		EntityId:    strconv.Itoa(user.Id),
+		Before:      map[string]any{"mfa_enabled": false},
+		After:       map[string]any{"mfa_enabled": true},
+		Actor:       user.Username,
+		ActorUserId: user.Id,
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// enrolment during login completes the login
+	if in.ChallengeToken != "" {
+		token, refreshToken, err := s.issueTokens(ctx, user, uuid.NewString())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed issue token")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		resp.Token = token
+		resp.RefreshToken = refreshToken
+		resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ConfirmMFAEnrollment/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.RecoveryCodes = codes
+	return &resp
+}
+
+func (s *Service) MFAStepUp(ctx context.Context, in *lib.MFAStepUpIn) *lib.MFAStepUpOut {
+	resp := lib.MFAStepUpOut{}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("MFAStepUp/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("MFAStepUp/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// a stolen access token must not be a way to guess the 6 digit code without limit
+	retryAfter, err := s.loginRetryAfter(ctx, in.Trace, authUser.Username, in.IP)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if retryAfter > 0 {
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: authUser.Id, Username: authUser.Username, IP: in.IP, Event: data.AuthLoginThrottled, Detail: "mfa step-up"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeTooManyRequests, "Terlalu banyak percobaan, coba lagi nanti")
+		resp.RetryAfter = retryAfter
+		return &resp
+	}
+
+	mfa, errType, err := s.storage.GetUserMFA(ctx, authUser.Id)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("MFAStepUp/ failed get user mfa")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if mfa == nil || !mfa.Enabled {
+		log.Warn(in.Trace).Int("userId", authUser.Id).Msg("MFAStepUp/ mfa not enabled")
+		resp.Fail(apperror.CodeMFANotEnabled, "MFA belum aktif")
+		return &resp
+	}
+
+	// step-up accepts only TOTP, recovery code is for lost device on login
+	if appErr := s.verifyTOTP(ctx, in.Trace, mfa, in.Code); appErr != nil {
+		if appErr.Code != apperror.CodeInternal {
+			event := &data.AuthEvent{UserId: authUser.Id, Username: authUser.Username, IP: in.IP, Event: data.AuthMFAFailed, Detail: "step-up: " + appErr.ID}
+			if err := s.recordLoginFailure(ctx, in.Trace, event); err != nil {
+				appErr = apperror.Internal()
+			} else {
+				s.commitAuthFailure(ctx, in.Trace, tx)
+			}
+		}
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	token, err := jwtutil.GenerateChallengeJWT(authUser.Id, authUser.Username, authUser.Role, 0, jwtutil.PurposeStepUp)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("MFAStepUp/ failed generate token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("MFAStepUp/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.StepUpToken = token
+	resp.ExpiresIn = int(jwtutil.ChallengeTokenTTL.Seconds())
+	return &resp
+}
+
+// loginRetryAfter returns the seconds the client must wait before trying again, 0 when allowed
+func (s *Service) loginRetryAfter(ctx context.Context, trace *contextutil.Trace, username string, ip string) (int, error) {
+	throttles, err := s.storage.GetActiveLoginThrottles(ctx, username, ip)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed get login throttles")
+		return 0, err
+	}
+
+	retryAfter := retryAfterSeconds(throttles, time.Now())
+	if retryAfter > 0 {
+		log.Warn(trace).Str("username", username).Str("ip", ip).Int("retryAfter", retryAfter).Msg("login throttled")
+	}
+	return retryAfter, nil
+}
+
+// recordLoginFailure counts the failure on the username and the ip, then delays their next attempt
+func (s *Service) recordLoginFailure(ctx context.Context, trace *contextutil.Trace, event *data.AuthEvent) error {
+	s.logAuthEvent(ctx, trace, event)
+
+	keys := map[data.LoginThrottleScope]string{
+		data.ThrottleUsername: event.Username,
+		data.ThrottleIP:       event.IP,
+	}
+	for scope, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		count, err := s.storage.IncrementLoginFailure(ctx, scope, key, loginFailureWindow)
+		if err != nil {
+			log.Error(trace).Err(err).Msg("failed increment login failure")
+			return err
+		}
+
+		delay, locked := loginThrottleRules[scope].delay(count)
+		if delay == 0 {
+			continue
+		}
+
+		err = s.storage.SetLoginLockedUntil(ctx, scope, key, time.Now().Add(delay))
+		if err != nil {
+			log.Error(trace).Err(err).Msg("failed set login locked until")
+			return err
+		}
+
+		if locked {
+			log.Warn(trace).Str("scope", string(scope)).Str("key", key).Int("failed", count).Msg("login locked")
+			s.logAuthEvent(ctx, trace, &data.AuthEvent{
+				UserId:   event.UserId,
+				Username: event.Username,
+				IP:       event.IP,
+				Event:    data.AuthAccountLocked,
+				Detail:   fmt.Sprintf("%s locked after %d failed attempts", scope, count),
+			})
+		}
+	}
+	return nil
+}
+
+// recordLoginSuccess resets the username counter, the ip counter is kept
+// so an attacker can not reset it by logging in to their own account
+func (s *Service) recordLoginSuccess(ctx context.Context, trace *contextutil.Trace, user *data.User, ip string, detail string) error {
+	_, err := s.storage.ResetLoginThrottle(ctx, data.ThrottleUsername, user.Username)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed reset login throttle")
+		return err
+	}
+
+	s.logAuthEvent(ctx, trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: ip, Event: data.AuthLoginSuccess, Detail: detail})
+	return nil
+}
+
+// logAuthEvent never fails the request, a missing log entry is only logged
+func (s *Service) logAuthEvent(ctx context.Context, trace *contextutil.Trace, event *data.AuthEvent) {
+	countLoginFailure(event.Event)
+	err := s.savepoint(ctx, func(ctx context.Context) error {
+		return s.storage.InsertAuthEvent(ctx, event)
+	})
+	if err != nil {
+		log.Error(trace).Err(err).Str("event", string(event.Event)).Msg("failed insert auth event")
+	}
+}
+
+// savepoint runs a best effort write in a savepoint of the service transaction,
+// when it fails only the write is rolled back and the transaction can go on
+func (s *Service) savepoint(ctx context.Context, write func(ctx context.Context) error) error {
+	sp, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx)
+
+	if err := write(database.WithTx(ctx, sp)); err != nil {
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// commitAuthFailure keeps the failed counter and auth event of a rejected attempt
+func (s *Service) commitAuthFailure(ctx context.Context, trace *contextutil.Trace, tx pgx.Tx) {
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(trace).Err(err).Msg("failed commit auth failure")
+	}
+}
+
+func (s *Service) CreateUser(ctx context.Context, in *lib.CreateUserIn) *lib.CreateUserOut {
+	resp := lib.CreateUserOut{}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("CreateUser/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("CreateUser/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	in.Fullname = strings.TrimSpace(in.Fullname)
+	in.Email = strings.TrimSpace(in.Email)
+	if in.Role == "" {
+		in.Role = data.REmployee
+	}
+	if msg := validateNewUser(in.Fullname, in.Username, in.Email, in.Role, in.BaseSalary); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("CreateUser/ invalid input")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return &resp
+	}
+	if in.JoinDate.IsZero() {
+		resp.Fail(apperror.CodeInvalidInput, "Tanggal bergabung wajib diisi")
+		return &resp
+	}
+
+	password := in.Password
+	if password == "" {
+		var err error
+		password, err = newTemporaryPassword()
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("CreateUser/ failed generate password")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		resp.TemporaryPassword = password
+	} else if msg := s.password.validate(password, in.Username); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("CreateUser/ password rejected")
+		resp.Fail(apperror.CodePasswordPolicy, msg)
+		return &resp
+	}
+
+	hashed, err := common.HashPassword(password)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateUser/ failed hash password")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateUser/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	taken, err := s.storage.IsUsernameOrEmailTaken(ctx, in.Username, in.Email)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateUser/ failed check username")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if taken {
+		log.Warn(in.Trace).Str("username", in.Username).Msg("CreateUser/ username or email taken")
+		resp.Fail(apperror.CodeUserExists, "Username atau email sudah dipakai")
+		return &resp
+	}
+
+	joinDate := common.TruncateToJakartaDate(in.JoinDate)
+	userId, err := s.storage.InsertUser(ctx, in.Fullname, in.Username, in.Email, hashed, in.BaseSalary, joinDate)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateUser/ failed insert user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if in.Role != data.REmployee {
+		err = s.storage.UpdateUserRole(ctx, userId, in.Role, authUser.Actor())
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("CreateUser/ failed update role")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "user.create",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(userId),
+		After: map[string]any{
+			"fullname": in.Fullname, "username": in.Username, "email": in.Email, "role": in.Role,
+			"base_salary": in.BaseSalary, "join_date": joinDate.Format("2006-01-02"),
+		},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateUser/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.UserId = userId
+	return &resp
+}
+
+func (s *Service) SetPassword(ctx context.Context, in *lib.SetPasswordIn) *lib.SetPasswordOut {
+	resp := lib.SetPasswordOut{}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("SetPassword/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("SetPassword/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetPassword/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, errType, err := s.storage.GetUserById(ctx, in.UserId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("SetPassword/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("SetPassword/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	password := in.Password
+	if password == "" {
+		password, err = newTemporaryPassword()
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("SetPassword/ failed generate password")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		resp.TemporaryPassword = password
+	} else if msg := s.password.validate(password, user.Username); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("SetPassword/ password rejected")
+		resp.Fail(apperror.CodePasswordPolicy, msg)
+		return &resp
+	}
+
+	err = s.replacePassword(ctx, user, password, authUser.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetPassword/ failed replace password")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{
+		UserId:   user.Id,
+		Username: user.Username,
+		Event:    data.AuthPasswordReset,
+		Detail:   "by " + authUser.Actor(),
+	})
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "user.password_set",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(user.Id),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("SetPassword/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) UnlockUser(ctx context.Context, in *lib.UnlockUserIn) *lib.UnlockUserOut {
+	resp := lib.UnlockUserOut{}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("UnlockUser/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	if authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("UnlockUser/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("UnlockUser/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, errType, err := s.storage.GetUserById(ctx, in.UserId)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Int("userId", in.UserId).Msg("UnlockUser/ user not found")
+			resp.Fail(apperror.CodeUserNotFound, "User tidak ditemukan")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("UnlockUser/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	unlocked, err := s.storage.ResetLoginThrottle(ctx, data.ThrottleUsername, user.Username)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("UnlockUser/ failed reset login throttle")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if unlocked {
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{
+			UserId:   user.Id,
+			Username: user.Username,
+			Event:    data.AuthAccountUnlock,
+			Detail:   "by " + authUser.Actor(),
+		})
+
+		if !s.audit(ctx, &auditLib.RecordIn{
+			Trace:      in.Trace,
+			Action:     "user.unlock",
+			EntityType: "user",
+			EntityId:   strconv.Itoa(user.Id),
+		}) {
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("UnlockUser/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+// rehashPassword failure does not fail the login, the old hash is still valid
+func (s *Service) rehashPassword(ctx context.Context, trace *contextutil.Trace, user *data.User, password string) {
+	hashed, err := common.HashPassword(password)
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed rehash password")
+		return
+	}
+
+	err = s.savepoint(ctx, func(ctx context.Context) error {
+		return s.storage.UpdateUserPassword(ctx, user.Id, hashed, user.Username)
+	})
+	if err != nil {
+		log.Error(trace).Err(err).Msg("failed update rehashed password")
+		return
+	}
+	log.Info(trace).Int("userId", user.Id).Msg("password rehashed")
+}
+
+// replacePassword stores the new password and ends every session of the user,
+// the caller may issue new tokens with the returned user
+func (s *Service) replacePassword(ctx context.Context, user *data.User, password string, updatedBy string) error {
+	hashed, err := common.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.UpdateUserPassword(ctx, user.Id, hashed, updatedBy); err != nil {
+		return err
+	}
+	if err := s.storage.IncrementTokenVersion(ctx, user.Id, updatedBy); err != nil {
+		return err
+	}
+	if err := s.storage.RevokeUserRefreshTokens(ctx, user.Id); err != nil {
+		return err
+	}
+
+	user.TokenVersion++
+	return nil
+}
+
+func (s *Service) ChangePassword(ctx context.Context, in *lib.ChangePasswordIn) *lib.ChangePasswordOut {
+	resp := lib.ChangePasswordOut{}
+
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ChangePassword/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ChangePassword/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// a stolen access token must not be a way to guess the password without limit
+	retryAfter, err := s.loginRetryAfter(ctx, in.Trace, authUser.Username, in.IP)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if retryAfter > 0 {
+		resp.Fail(apperror.CodeTooManyRequests, "Terlalu banyak percobaan, coba lagi nanti")
+		resp.RetryAfter = retryAfter
+		return &resp
+	}
+
+	user, _, err := s.storage.GetUserById(ctx, authUser.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ChangePassword/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !common.VerifyPassword(in.CurrentPassword, user.Password) {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("ChangePassword/ invalid current password")
+		event := &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginFailed, Detail: "change password: invalid current password"}
+		if err := s.recordLoginFailure(ctx, in.Trace, event); err != nil {
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeInvalidCredentials, "Password lama tidak valid")
+		return &resp
+	}
+
+	if in.NewPassword == in.CurrentPassword {
+		resp.Fail(apperror.CodePasswordPolicy, "Password baru tidak boleh sama dengan password lama")
+		return &resp
+	}
+
+	if msg := s.password.validate(in.NewPassword, user.Username); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("ChangePassword/ password rejected")
+		resp.Fail(apperror.CodePasswordPolicy, msg)
+		return &resp
+	}
+
+	err = s.replacePassword(ctx, user, in.NewPassword, user.Username)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ChangePassword/ failed replace password")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// every other session is logged out, the caller continues with new tokens
+	token, refreshToken, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ChangePassword/ failed issue token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthPasswordChanged})
+
+	// the hash is never part of the diff
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "user.password_change",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(user.Id),
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ChangePassword/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Token = token
+	resp.RefreshToken = refreshToken
+	resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	return &resp
+}
+
+func (s *Service) ForgotPassword(ctx context.Context, in *lib.ForgotPasswordIn) *lib.ForgotPasswordOut {
+	resp := lib.ForgotPasswordOut{}
+
+	if in.Login == "" {
+		resp.Fail(apperror.CodeInvalidInput, "username atau email tidak boleh kosong")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ForgotPassword/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	user, errType, err := s.storage.GetUserByLogin(ctx, in.Login)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("ForgotPassword/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// unknown or inactive user gets the same answer, so the endpoint can not tell which account exists
+	if user == nil || !user.IsActive {
+		log.Warn(in.Trace).Msg("ForgotPassword/ user not found or not active")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{Username: in.Login, IP: in.IP, Event: data.AuthPasswordResetRequested, Detail: "unknown or inactive user"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Success = true
+		return &resp
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ForgotPassword/ failed generate token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	expiresAt := time.Now().Add(s.password.ResetTokenTTL)
+	err = s.storage.InsertPasswordResetToken(ctx, user.Id, hashToken(token), expiresAt)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ForgotPassword/ failed store token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthPasswordResetRequested})
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ForgotPassword/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// delivery failure is not shown to the client, it would tell the account exists
+	err = s.notifier.Send(ctx, &notifier.Message{
+		To:      user.Email,
+		Subject: "Reset password",
+		Body: fmt.Sprintf("Halo %s,\n\nBuka link berikut untuk membuat password baru, berlaku sampai %s:\n%s%s\n\nAbaikan email ini jika kamu tidak meminta reset password.",
+			user.Fullname, expiresAt.In(common.JakartaTZ).Format("02-01-2006 15:04 WIB"), s.password.ResetURL, token),
+	})
+	if err != nil {
+		log.Error(in.Trace).Err(err).Int("userId", user.Id).Msg("ForgotPassword/ failed send notification")
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) ResetPassword(ctx context.Context, in *lib.ResetPasswordIn) *lib.ResetPasswordOut {
+	resp := lib.ResetPasswordOut{}
+
+	if in.Token == "" {
+		resp.Fail(apperror.CodeTokenInvalid, "Token reset tidak valid atau kedaluwarsa")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	token, errType, err := s.storage.GetPasswordResetToken(ctx, hashToken(in.Token))
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Msg("ResetPassword/ token not found")
+			resp.Fail(apperror.CodeTokenInvalid, "Token reset tidak valid atau kedaluwarsa")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed get token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		log.Warn(in.Trace).Int("userId", token.UserId).Msg("ResetPassword/ token used or expired")
+		resp.Fail(apperror.CodeTokenInvalid, "Token reset tidak valid atau kedaluwarsa")
+		return &resp
+	}
+
+	user, _, err := s.storage.GetUserById(ctx, token.UserId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed get user")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !user.IsActive {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("ResetPassword/ user not active")
+		resp.Fail(apperror.CodeUserInactive, "User tidak aktif")
+		return &resp
+	}
+
+	// policy is checked before the token is used, so the user can retry with another password
+	if msg := s.password.validate(in.NewPassword, user.Username); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("ResetPassword/ password rejected")
+		resp.Fail(apperror.CodePasswordPolicy, msg)
+		return &resp
+	}
+
+	used, err := s.storage.UsePasswordResetToken(ctx, token.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed use token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !used {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("ResetPassword/ token used concurrently")
+		resp.Fail(apperror.CodeTokenInvalid, "Token reset tidak valid atau kedaluwarsa")
+		return &resp
+	}
+
+	err = s.replacePassword(ctx, user, in.NewPassword, user.Username)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed replace password")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// proving ownership of the email also lifts a lockout
+	_, err = s.storage.ResetLoginThrottle(ctx, data.ThrottleUsername, user.Username)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed reset login throttle")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthPasswordReset})
+
+	// proven by the emailed token, there is no session
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:       in.Trace,
+		Action:      "user.password_reset",
+		EntityType:  "user",
+		EntityId:    strconv.Itoa(user.Id),
+		Actor:       user.Username,
+		ActorUserId: user.Id,
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ResetPassword/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) StartSSOLogin(ctx context.Context, in *lib.StartSSOLoginIn) *lib.StartSSOLoginOut {
+	resp := lib.StartSSOLoginOut{}
+
+	provider, ok := s.sso[in.Provider]
+	if !ok {
+		log.Warn(in.Trace).Str("provider", in.Provider).Msg("StartSSOLogin/ unknown provider")
+		resp.Fail(apperror.CodeNotFound, "Provider SSO tidak dikenal")
+		return &resp
+	}
+
+	state := &data.OIDCLoginState{Provider: in.Provider, ExpiresAt: time.Now().Add(ssoStateTTL)}
+	for _, value := range []*string{&state.State, &state.Nonce, &state.CodeVerifier} {
+		random, err := oidc.NewRandom()
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("StartSSOLogin/ failed generate random")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		*value = random
+	}
+
+	authURL, err := provider.Client.AuthCodeURL(ctx, state.State, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Str("provider", in.Provider).Msg("StartSSOLogin/ failed build authorization url")
+		resp.Fail(apperror.CodeSSOUnavailable, "Provider SSO tidak bisa dihubungi")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartSSOLogin/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	err = s.storage.InsertOIDCLoginState(ctx, state)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartSSOLogin/ failed store state")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("StartSSOLogin/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.AuthorizationURL = authURL
+	return &resp
+}
+
+func (s *Service) CompleteSSOLogin(ctx context.Context, in *lib.CompleteSSOLoginIn) *lib.LoginOut {
+	resp := lib.LoginOut{}
+
+	provider, ok := s.sso[in.Provider]
+	if !ok {
+		log.Warn(in.Trace).Str("provider", in.Provider).Msg("CompleteSSOLogin/ unknown provider")
+		resp.Fail(apperror.CodeNotFound, "Provider SSO tidak dikenal")
+		return &resp
+	}
+
+	if in.Code == "" || in.State == "" {
+		resp.Fail(apperror.CodeSSOFailed, "Login SSO tidak valid atau kedaluwarsa")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	// the state proves the callback belongs to a login started here (CSRF), it is used once
+	state, errType, err := s.storage.TakeOIDCLoginState(ctx, in.State)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Msg("CompleteSSOLogin/ unknown state")
+			resp.Fail(apperror.CodeSSOFailed, "Login SSO tidak valid atau kedaluwarsa")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed get state")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if state.Provider != in.Provider || time.Now().After(state.ExpiresAt) {
+		log.Warn(in.Trace).Str("provider", in.Provider).Msg("CompleteSSOLogin/ state expired or of other provider")
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeSSOFailed, "Login SSO tidak valid atau kedaluwarsa")
+		return &resp
+	}
+
+	tokens, err := provider.Client.Exchange(ctx, in.Code, state.CodeVerifier)
+	if err != nil {
+		log.Warn(in.Trace).Err(err).Str("provider", in.Provider).Msg("CompleteSSOLogin/ failed exchange code")
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeSSOFailed, "Login SSO gagal")
+		return &resp
+	}
+
+	claims, err := provider.Client.VerifyIDToken(ctx, tokens.IDToken, state.Nonce)
+	if err != nil {
+		log.Warn(in.Trace).Err(err).Str("provider", in.Provider).Msg("CompleteSSOLogin/ invalid id token")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{IP: in.IP, Event: data.AuthLoginFailed, Detail: "sso " + in.Provider + ": invalid id token"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeSSOFailed, "Login SSO gagal")
+		return &resp
+	}
+
+	user, appErr := s.ssoUser(ctx, in, claims)
+	if user == nil {
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	if !user.IsActive {
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("CompleteSSOLogin/ user not active")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginFailed, Detail: "sso " + in.Provider + ": user not active"})
+		s.commitAuthFailure(ctx, in.Trace, tx)
+		resp.Fail(apperror.CodeUserInactive, "User tidak aktif")
+		return &resp
+	}
+
+	if role, synced := provider.role(claims.Strings(provider.GroupsClaim)); synced && role != user.Role {
+		err = s.syncRole(ctx, in, user, role)
+		if err != nil {
+			log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed sync role")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+	}
+
+	// the provider only replaces the password, the role may still require the second factor
+	challenge, err := s.mfaChallenge(ctx, in.Trace, user)
+	if err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if challenge != nil {
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginSuccess, Detail: "sso " + in.Provider + ": mfa challenge"})
+		// keeps the used state and the synced role
+		if err := tx.Commit(ctx); err != nil {
+			log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed commit")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		return challenge
+	}
+
+	token, refreshToken, err := s.issueTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed issue token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if err := s.recordLoginSuccess(ctx, in.Trace, user, in.IP, "sso "+in.Provider); err != nil {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Token = token
+	resp.RefreshToken = refreshToken
+	resp.ExpiresIn = int(jwtutil.AccessTokenTTL.Seconds())
+	return &resp
+}
+
+// ssoUser finds the user of the provider account, an unlinked account is linked just in time
+// to the existing user with the same verified email. It returns nil and the failure when there is none.
+func (s *Service) ssoUser(ctx context.Context, in *lib.CompleteSSOLoginIn, claims *oidc.IDClaims) (*data.User, *apperror.Error) {
+	user, errType, err := s.storage.GetUserByIdentity(ctx, in.Provider, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed get user by identity")
+		return nil, apperror.Internal()
+	}
+
+	// an unverified email could be anyone's address, it must not take over the account
+	if claims.Email == "" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		log.Warn(in.Trace).Str("provider", in.Provider).Msg("CompleteSSOLogin/ email missing or not verified")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{IP: in.IP, Event: data.AuthLoginFailed, Detail: "sso " + in.Provider + ": email not verified"})
+		return nil, apperror.New(apperror.CodeSSOFailed, "Email akun SSO belum terverifikasi")
+	}
+
+	user, errType, err = s.storage.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Str("provider", in.Provider).Msg("CompleteSSOLogin/ no user with the email")
+			s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{Username: claims.Email, IP: in.IP, Event: data.AuthLoginFailed, Detail: "sso " + in.Provider + ": email not registered"})
+			return nil, apperror.New(apperror.CodeForbidden, "Akun belum terdaftar di payroll")
+		}
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed get user by email")
+		return nil, apperror.Internal()
+	}
+
+	linked, err := s.storage.InsertUserIdentity(ctx, user.Id, in.Provider, claims.Subject, claims.Email)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CompleteSSOLogin/ failed link identity")
+		return nil, apperror.Internal()
+	}
+	if !linked {
+		// the email moved to another account of the provider, an admin has to unlink the old one first
+		log.Warn(in.Trace).Int("userId", user.Id).Msg("CompleteSSOLogin/ user linked to another account")
+		s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthLoginFailed, Detail: "sso " + in.Provider + ": linked to another account"})
+		return nil, apperror.New(apperror.CodeConflict, "User sudah terhubung dengan akun SSO lain")
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthSSOLinked, Detail: in.Provider})
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:       in.Trace,
+		Action:      "user_identity.create",
+		EntityType:  "user",
+		EntityId:    strconv.Itoa(user.Id),
+		After:       map[string]any{"provider": in.Provider, "subject": claims.Subject, "email": claims.Email},
+		Actor:       user.Username,
+		ActorUserId: user.Id,
+	}) {
+		return nil, apperror.Internal()
+	}
+	return user, nil
+}
+
+// syncRole applies the role mapped from the provider groups, the access tokens carrying the old role are revoked
+func (s *Service) syncRole(ctx context.Context, in *lib.CompleteSSOLoginIn, user *data.User, role data.UserRole) error {
+	updatedBy := "sso " + in.Provider
+	if err := s.storage.UpdateUserRole(ctx, user.Id, role, updatedBy); err != nil {
+		return err
+	}
+	if err := s.storage.IncrementTokenVersion(ctx, user.Id, updatedBy); err != nil {
+		return err
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "user.update",
+		EntityType: "user",
+		EntityId:   strconv.Itoa(user.Id),
+		Before:     map[string]any{"role": user.Role},
+		After:      map[string]any{"role": role},
+		Actor:      updatedBy,
+	}) {
+		return errors.New("role change not audited")
+	}
+
+	s.logAuthEvent(ctx, in.Trace, &data.AuthEvent{UserId: user.Id, Username: user.Username, IP: in.IP, Event: data.AuthRoleChanged,
+		Detail: fmt.Sprintf("sso %s: %s -> %s", in.Provider, user.Role, role)})
+	log.Info(in.Trace).Int("userId", user.Id).Str("role", string(role)).Msg("CompleteSSOLogin/ role synced from provider groups")
+
+	user.Role = role
+	user.TokenVersion++
+	return nil
+}
+
+// sessionUser is the user of a login session, managing api tokens with an api token is not allowed
+func sessionUser(ctx context.Context, trace *contextutil.Trace, method string) (*contextutil.AuthUser, *apperror.Error) {
+	authUser, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(trace).Msg(method + "/ unauthorized")
+		return nil, apperror.New(apperror.CodeUnauthorized, "unauthorized")
+	}
+	if authUser.TokenId != 0 {
+		log.Warn(trace).Int("tokenId", authUser.TokenId).Msg(method + "/ called with api token")
+		return nil, apperror.New(apperror.CodeForbidden, "forbidden: Hanya bisa lewat login")
+	}
+	return authUser, nil
+}
+
+func (s *Service) CreateAPIToken(ctx context.Context, in *lib.CreateAPITokenIn) *lib.CreateAPITokenOut {
+	resp := lib.CreateAPITokenOut{}
+
+	authUser, appErr := sessionUser(ctx, in.Trace, "CreateAPIToken")
+	if authUser == nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	if in.ServiceAccountId != 0 && authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("CreateAPIToken/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > 100 {
+		resp.Fail(apperror.CodeInvalidInput, "Nama token wajib diisi, maksimal 100 karakter")
+		return &resp
+	}
+
+	scopes, msg := normalizeScopes(in.Scopes)
+	if msg != "" {
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return &resp
+	}
+
+	if in.ExpiresInDays == 0 {
+		in.ExpiresInDays = apiTokenDefaultDays
+	}
+	if in.ExpiresInDays < 1 || in.ExpiresInDays > apiTokenMaxDays {
+		resp.Fail(apperror.CodeInvalidInput, fmt.Sprintf("Masa berlaku token 1 sampai %d hari", apiTokenMaxDays))
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateAPIToken/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	apiToken := &data.APIToken{
+		Name:      in.Name,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, in.ExpiresInDays),
+		CreatedBy: authUser.Actor(),
+	}
+	if in.ServiceAccountId != 0 {
+		_, errType, err := s.storage.GetServiceAccount(ctx, in.ServiceAccountId)
+		if err != nil {
+			if errType == database.ErrNotFound {
+				resp.Fail(apperror.CodeNotFound, "Service account tidak ditemukan")
+				return &resp
+			}
+			log.Error(in.Trace).Err(err).Msg("CreateAPIToken/ failed get service account")
+			resp.Fail(apperror.CodeInternal, "internal error")
+			return &resp
+		}
+		apiToken.ServiceAccountId = in.ServiceAccountId
+	} else {
+		apiToken.UserId = authUser.Id
+	}
+
+	token, prefix, err := newAPIToken()
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateAPIToken/ failed generate token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	apiToken.Prefix = prefix
+
+	apiToken.Id, err = s.storage.InsertAPIToken(ctx, apiToken, hashToken(token), authUser.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateAPIToken/ failed insert token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "api_token.create",
+		EntityType: "api_token",
+		EntityId:   strconv.Itoa(apiToken.Id),
+		After: map[string]any{
+			"user_id": apiToken.UserId, "service_account_id": apiToken.ServiceAccountId, "name": apiToken.Name,
+			"prefix": apiToken.Prefix, "scopes": apiToken.Scopes, "expires_at": apiToken.ExpiresAt,
+		},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateAPIToken/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.Token = token
+	resp.APIToken = apiToken
+	return &resp
+}
+
+func (s *Service) ListAPITokens(ctx context.Context, in *lib.ListAPITokensIn) *lib.ListAPITokensOut {
+	resp := lib.ListAPITokensOut{}
+
+	authUser, appErr := sessionUser(ctx, in.Trace, "ListAPITokens")
+	if authUser == nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	if in.ServiceAccountId != 0 && authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListAPITokens/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListAPITokens/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	tokens, err := s.storage.GetAPITokens(ctx, authUser.Id, in.ServiceAccountId)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListAPITokens/ failed get tokens")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.APITokens = tokens
+	return &resp
+}
+
+func (s *Service) RevokeAPIToken(ctx context.Context, in *lib.RevokeAPITokenIn) *lib.RevokeAPITokenOut {
+	resp := lib.RevokeAPITokenOut{}
+
+	authUser, appErr := sessionUser(ctx, in.Trace, "RevokeAPIToken")
+	if authUser == nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeAPIToken/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	token, errType, err := s.storage.GetAPIToken(ctx, in.TokenId)
+	if err != nil && errType != database.ErrNotFound {
+		log.Error(in.Trace).Err(err).Msg("RevokeAPIToken/ failed get token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	// a token of someone else looks the same as an unknown token
+	if token == nil || (token.UserId != authUser.Id && authUser.Role != data.RAdmin) {
+		log.Warn(in.Trace).Int("tokenId", in.TokenId).Msg("RevokeAPIToken/ token not found")
+		resp.Fail(apperror.CodeNotFound, "Token tidak ditemukan")
+		return &resp
+	}
+
+	revoked, err := s.storage.RevokeAPIToken(ctx, token.Id)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeAPIToken/ failed revoke token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	if !revoked {
+		resp.Fail(apperror.CodeTokenRevoked, "Token sudah dicabut")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "api_token.revoke",
+		EntityType: "api_token",
+		EntityId:   strconv.Itoa(token.Id),
+		Before:     map[string]any{"revoked": false},
+		After:      map[string]any{"revoked": true},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("RevokeAPIToken/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	log.Info(in.Trace).Int("tokenId", token.Id).Str("by", authUser.Actor()).Msg("RevokeAPIToken/ token revoked")
+	resp.Success = true
+	return &resp
+}
+
+func (s *Service) CreateServiceAccount(ctx context.Context, in *lib.CreateServiceAccountIn) *lib.CreateServiceAccountOut {
+	resp := lib.CreateServiceAccountOut{}
+
+	authUser, appErr := sessionUser(ctx, in.Trace, "CreateServiceAccount")
+	if authUser == nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	if authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("CreateServiceAccount/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > 50 {
+		resp.Fail(apperror.CodeInvalidInput, "Nama service account wajib diisi, maksimal 50 karakter")
+		return &resp
+	}
+	if len(in.Description) > 255 {
+		resp.Fail(apperror.CodeInvalidInput, "Deskripsi maksimal 255 karakter")
+		return &resp
+	}
+	if in.Role == "" {
+		in.Role = data.REmployee
+	}
+	if in.Role != data.RAdmin && in.Role != data.REmployee {
+		resp.Fail(apperror.CodeInvalidInput, "Role harus admin atau employee")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateServiceAccount/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	id, errType, err := s.storage.InsertServiceAccount(ctx, in.Name, in.Description, in.Role, authUser.Actor())
+	if err != nil {
+		if errType == database.ErrDuplicate {
+			resp.Fail(apperror.CodeUserExists, "Nama service account sudah dipakai")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("CreateServiceAccount/ failed insert")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	if !s.audit(ctx, &auditLib.RecordIn{
+		Trace:      in.Trace,
+		Action:     "service_account.create",
+		EntityType: "service_account",
+		EntityId:   strconv.Itoa(id),
+		After:      map[string]any{"name": in.Name, "description": in.Description, "role": in.Role},
+	}) {
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateServiceAccount/ failed commit")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.ServiceAccount = &data.ServiceAccount{
+		Id:          id,
+		Name:        in.Name,
+		Description: in.Description,
+		Role:        in.Role,
+		CreatedBy:   authUser.Actor(),
+	}
+	return &resp
+}
+
+func (s *Service) ListServiceAccounts(ctx context.Context, in *lib.ListServiceAccountsIn) *lib.ListServiceAccountsOut {
+	resp := lib.ListServiceAccountsOut{}
+
+	authUser, appErr := sessionUser(ctx, in.Trace, "ListServiceAccounts")
+	if authUser == nil {
+		resp.FailWith(appErr)
+		return &resp
+	}
+
+	if authUser.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListServiceAccounts/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return &resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListServiceAccounts/ failed begin tx")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	accounts, err := s.storage.GetAllServiceAccount(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListServiceAccounts/ failed get service accounts")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	resp.Success = true
+	resp.ServiceAccounts = accounts
+	return &resp
+}
+
+func (s *Service) AuthenticateAPIToken(ctx context.Context, in *lib.AuthenticateAPITokenIn) *lib.AuthenticateAPITokenOut {
+	resp := lib.AuthenticateAPITokenOut{}
+
+	if !strings.HasPrefix(in.Token, data.APITokenPrefix) {
+		resp.Fail(apperror.CodeTokenInvalid, "Token tidak valid")
+		return &resp
+	}
+
+	owner, errType, err := s.storage.GetAPITokenOwner(ctx, hashToken(in.Token))
+	if err != nil {
+		if errType == database.ErrNotFound {
+			log.Warn(in.Trace).Msg("AuthenticateAPIToken/ unknown token")
+			resp.Fail(apperror.CodeTokenInvalid, "Token tidak valid")
+			return &resp
+		}
+		log.Error(in.Trace).Err(err).Msg("AuthenticateAPIToken/ failed get token")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return &resp
+	}
+
+	token := owner.Token
+	switch {
+	case token.RevokedAt != nil:
+		resp.Fail(apperror.CodeTokenRevoked, "Token sudah dicabut")
+	case time.Now().After(token.ExpiresAt):
+		resp.Fail(apperror.CodeTokenExpired, "Token kedaluwarsa")
+	case !owner.IsActive:
+		resp.Fail(apperror.CodeUserInactive, "User tidak aktif")
+	}
+	if resp.Err != nil {
+		log.Warn(in.Trace).Int("tokenId", token.Id).Str("reason", resp.Message).Msg("AuthenticateAPIToken/ token rejected")
+		return &resp
+	}
+
+	// last use is informational, a failed write does not block the request
+	if err := s.storage.TouchAPIToken(ctx, token.Id, in.IP); err != nil {
+		log.Error(in.Trace).Err(err).Int("tokenId", token.Id).Msg("AuthenticateAPIToken/ failed record last use")
+	}
+
+	resp.Success = true
+	resp.User = &contextutil.AuthUser{
+		Id:        token.UserId,
+		Username:  owner.Username,
+		Role:      owner.Role,
+		ExpiresAt: token.ExpiresAt,
+		TokenId:   token.Id,
+		Scopes:    token.Scopes,
+	}
+	return &resp
+}
@@ -0,0 +1,53 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceMFAStepUpLockout(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+	service := NewService(storage, MFAPolicy{}, PasswordPolicy{}, nil, nil, nil)
+	trace := &contextutil.Trace{TraceID: "mfa-step-up-lockout-test"}
+
+	userId, err := storage.InsertUser(con.Context, "Test User", test.Username, "test_user@example.com", "hash", 5000000, common.NewDate(2024, 1, 1))
+	require.NoError(t, err)
+	secret, err := common.GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.NoError(t, storage.UpsertPendingMFA(con.Context, userId, secret))
+	require.NoError(t, storage.EnableMFA(con.Context, userId))
+
+	ctx := test.UserContext(con.Context, userId, data.RAdmin)
+	step := common.TOTPStep(time.Now())
+	wrong, err := common.TOTPCode(secret, step+100)
+	require.NoError(t, err)
+	valid, err := common.TOTPCode(secret, step)
+	require.NoError(t, err)
+
+	// the free attempts of the username are used by wrong codes
+	for i := 0; i < loginThrottleRules[data.ThrottleUsername].FreeAttempts; i++ {
+		out := service.MFAStepUp(ctx, &lib.MFAStepUpIn{Trace: trace, Code: wrong, IP: "10.0.0.1"})
+		require.False(t, out.Success)
+		assert.Equal(t, "Kode OTP tidak valid", out.Message)
+		assert.Equal(t, 0, out.RetryAfter)
+	}
+
+	// even the valid code waits now, a stolen access token can not guess the code without limit
+	out := service.MFAStepUp(ctx, &lib.MFAStepUpIn{Trace: trace, Code: valid, IP: "10.0.0.1"})
+	assert.False(t, out.Success)
+	assert.Equal(t, apperror.CodeTooManyRequests, out.Err.Code)
+	assert.Greater(t, out.RetryAfter, 0)
+	assert.Empty(t, out.StepUpToken)
+}
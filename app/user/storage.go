@@ -22,8 +22,13 @@ func NewStorage(pool *pgxpool.Pool) *Storage {
 	return &Storage{pool: pool}
 }
 
+// db runs the query in the transaction of the service when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
 func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
-	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +37,7 @@ func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
 
 // BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
 func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
-	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +47,7 @@ func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
 func (s *Storage) InsertUser(ctx context.Context, fullname string, username string, email string, password string, baseSalary int, joinDate time.Time) (int, error) {
 	var id int
 	currentTime := time.Now()
-	err := s.pool.QueryRow(ctx,
+	err := s.db(ctx).QueryRow(ctx,
 		`INSERT INTO users (fullname, username, email, password_hash, base_salary, join_date, created_at, updated_at) 
          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
          RETURNING id`,
@@ -50,13 +55,26 @@ func (s *Storage) InsertUser(ctx context.Context, fullname string, username stri
 	return id, err
 }
 
+func (s *Storage) IsUsernameOrEmailTaken(ctx context.Context, username string, email string) (bool, error) {
+	var taken bool
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT EXISTS (
+		    SELECT 1 FROM users
+		    WHERE username IN ($1, $2) OR LOWER(email) IN (LOWER($1), LOWER($2))
+		 )`,
+		username, email).Scan(&taken)
+	return taken, err
+}
+
 func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*data.User, database.ErrType, error) {
 	user := &data.User{}
-	err := s.pool.QueryRow(ctx,
-		`SELECT  id, fullname, username, email, password_hash, role, base_salary, join_date, created_at, updated_at
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT  id, fullname, username, email, password_hash, role, base_salary, join_date, cost_center, religion,
+		        COALESCE(is_active, false), token_version, created_at, updated_at
          FROM users WHERE username = $1`,
 		username).Scan(&user.Id, &user.Fullname, &user.Username, &user.Email, &user.Password,
-		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CreatedAt, &user.UpdatedAt)
+		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CostCenter, &user.Religion,
+		&user.IsActive, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		// Return ErrNotFound error type when no rows are found
@@ -74,9 +92,80 @@ func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*data
 	return user, database.ErrUnset, nil
 }
 
+func (s *Storage) GetUserByLogin(ctx context.Context, login string) (*data.User, database.ErrType, error) {
+	user := &data.User{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT  id, fullname, username, email, password_hash, role, base_salary, join_date, cost_center, religion,
+		        COALESCE(is_active, false), token_version, created_at, updated_at
+         FROM users WHERE username = $1 OR LOWER(email) = LOWER($1)
+         LIMIT 1`,
+		login).Scan(&user.Id, &user.Fullname, &user.Username, &user.Email, &user.Password,
+		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CostCenter, &user.Religion,
+		&user.IsActive, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+
+	user.JoinDate = common.TruncateToJakartaDate(user.JoinDate)
+	user.CreatedAt = common.TruncateToJakartaDate(user.CreatedAt)
+	user.UpdatedAt = common.TruncateToJakartaDate(user.UpdatedAt)
+	return user, database.ErrUnset, nil
+}
+
+func (s *Storage) GetUserByEmail(ctx context.Context, email string) (*data.User, database.ErrType, error) {
+	user := &data.User{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT  id, fullname, username, email, password_hash, role, base_salary, join_date, cost_center, religion,
+		        COALESCE(is_active, false), token_version, created_at, updated_at
+         FROM users WHERE LOWER(email) = LOWER($1)
+         LIMIT 1`,
+		email).Scan(&user.Id, &user.Fullname, &user.Username, &user.Email, &user.Password,
+		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CostCenter, &user.Religion,
+		&user.IsActive, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+
+	user.JoinDate = common.TruncateToJakartaDate(user.JoinDate)
+	user.CreatedAt = common.TruncateToJakartaDate(user.CreatedAt)
+	user.UpdatedAt = common.TruncateToJakartaDate(user.UpdatedAt)
+	return user, database.ErrUnset, nil
+}
+
+func (s *Storage) GetUserById(ctx context.Context, userId int) (*data.User, database.ErrType, error) {
+	user := &data.User{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT  id, fullname, username, email, password_hash, role, base_salary, join_date, cost_center, religion,
+		        COALESCE(is_active, false), token_version, created_at, updated_at
+         FROM users WHERE id = $1`,
+		userId).Scan(&user.Id, &user.Fullname, &user.Username, &user.Email, &user.Password,
+		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CostCenter, &user.Religion,
+		&user.IsActive, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+
+	user.JoinDate = common.TruncateToJakartaDate(user.JoinDate)
+	user.CreatedAt = common.TruncateToJakartaDate(user.CreatedAt)
+	user.UpdatedAt = common.TruncateToJakartaDate(user.UpdatedAt)
+	return user, database.ErrUnset, nil
+}
+
 func (s *Storage) GetAllUserBaseSalary(ctx context.Context) (map[int]int, database.ErrType, error) {
 	query := `SELECT id, base_salary FROM users`
-	rows, err := s.pool.Query(ctx, query)
+	rows, err := s.db(ctx).Query(ctx, query)
 	if err != nil {
 		return nil, database.ErrUnset, err
 	}
@@ -94,3 +183,715 @@ func (s *Storage) GetAllUserBaseSalary(ctx context.Context) (map[int]int, databa
 
 	return result, database.ErrUnset, nil
 }
+
+func (s *Storage) GetAllUserCostCenter(ctx context.Context) (map[int]string, database.ErrType, error) {
+	query := `SELECT id, cost_center FROM users`
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, database.ErrUnset, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var costCenter string
+		if err := rows.Scan(&id, &costCenter); err != nil {
+			return nil, database.ErrUnset, err
+		}
+		result[id] = costCenter
+	}
+
+	return result, database.ErrUnset, nil
+}
+
+func (s *Storage) GetAllUserTaxProfile(ctx context.Context) ([]*data.UserTaxProfile, database.ErrType, error) {
+	query := `
+		SELECT u.id, u.fullname, COALESCE(p.npwp, ''), COALESCE(p.nik, ''), COALESCE(p.ptkp_status, 'TK/0')
+		FROM users u
+		LEFT JOIN user_tax_profiles p ON p.user_id = u.id
+		ORDER BY u.id
+	`
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, database.ErrUnset, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.UserTaxProfile, 0)
+	for rows.Next() {
+		var p data.UserTaxProfile
+		if err := rows.Scan(&p.UserId, &p.Fullname, &p.Npwp, &p.Nik, &p.PTKPStatus); err != nil {
+			return nil, database.ErrUnset, err
+		}
+		result = append(result, &p)
+	}
+
+	return result, database.ErrUnset, nil
+}
+
+func (s *Storage) GetUserTaxProfile(ctx context.Context, userId int) (*data.UserTaxProfile, database.ErrType, error) {
+	query := `
+		SELECT u.id, u.fullname, COALESCE(p.npwp, ''), COALESCE(p.nik, ''), COALESCE(p.ptkp_status, 'TK/0')
+		FROM users u
+		LEFT JOIN user_tax_profiles p ON p.user_id = u.id
+		WHERE u.id = $1
+	`
+	var p data.UserTaxProfile
+	err := s.db(ctx).QueryRow(ctx, query, userId).Scan(&p.UserId, &p.Fullname, &p.Npwp, &p.Nik, &p.PTKPStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return &p, database.ErrUnset, nil
+}
+
+func (s *Storage) GetAllUserEmployment(ctx context.Context) ([]*data.UserEmployment, database.ErrType, error) {
+	query := `SELECT id, base_salary, join_date, religion FROM users WHERE is_active ORDER BY id`
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, database.ErrUnset, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.UserEmployment, 0)
+	for rows.Next() {
+		var e data.UserEmployment
+		if err := rows.Scan(&e.UserId, &e.BaseSalary, &e.JoinDate, &e.Religion); err != nil {
+			return nil, database.ErrUnset, err
+		}
+		e.JoinDate = common.TruncateToJakartaDate(e.JoinDate)
+		result = append(result, &e)
+	}
+
+	return result, database.ErrUnset, nil
+}
+
+func (s *Storage) UpdateUserReligion(ctx context.Context, userId int, religion data.Religion, updatedBy string) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		UPDATE users
+		SET religion = $2, updated_by = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userId, religion, updatedBy)
+	return err
+}
+
+func (s *Storage) IsUserExists(ctx context.Context, userId int) (bool, error) {
+	var exists bool
+	err := s.db(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userId).Scan(&exists)
+	return exists, err
+}
+
+func (s *Storage) UpsertUserTaxProfile(ctx context.Context, userId int, npwp string, nik string, ptkpStatus common.PTKPStatus, updatedBy string) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		INSERT INTO user_tax_profiles (user_id, npwp, nik, ptkp_status, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET npwp = EXCLUDED.npwp,
+		    nik = EXCLUDED.nik,
+		    ptkp_status = EXCLUDED.ptkp_status,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = CURRENT_TIMESTAMP
+	`, userId, npwp, nik, ptkpStatus, updatedBy)
+	return err
+}
+
+func (s *Storage) GetActiveUserContacts(ctx context.Context) ([]*data.UserContact, error) {
+	query := `SELECT id, fullname, email, role FROM users WHERE is_active ORDER BY id`
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.UserContact, 0)
+	for rows.Next() {
+		var c data.UserContact
+		if err := rows.Scan(&c.Id, &c.Fullname, &c.Email, &c.Role); err != nil {
+			return nil, err
+		}
+		result = append(result, &c)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) GetAllUserBankAccount(ctx context.Context) ([]*data.UserBankAccount, error) {
+	query := `
+		SELECT u.id, u.fullname, b.bank_code, b.account_number, b.account_name
+		FROM user_bank_accounts b
+		JOIN users u ON u.id = b.user_id
+		ORDER BY u.id
+	`
+	rows, err := s.db(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.UserBankAccount, 0)
+	for rows.Next() {
+		var a data.UserBankAccount
+		if err := rows.Scan(&a.UserId, &a.Fullname, &a.BankCode, &a.AccountNumber, &a.AccountName); err != nil {
+			return nil, err
+		}
+		result = append(result, &a)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) GetUserBankAccount(ctx context.Context, userId int) (*data.UserBankAccount, database.ErrType, error) {
+	query := `
+		SELECT u.id, u.fullname, COALESCE(b.bank_code, ''), COALESCE(b.account_number, ''), COALESCE(b.account_name, '')
+		FROM users u
+		LEFT JOIN user_bank_accounts b ON b.user_id = u.id
+		WHERE u.id = $1
+	`
+	var a data.UserBankAccount
+	err := s.db(ctx).QueryRow(ctx, query, userId).Scan(&a.UserId, &a.Fullname, &a.BankCode, &a.AccountNumber, &a.AccountName)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return &a, database.ErrUnset, nil
+}
+
+func (s *Storage) UpsertUserBankAccount(ctx context.Context, account *data.UserBankAccount, updatedBy string) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		INSERT INTO user_bank_accounts (user_id, bank_code, account_number, account_name, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET bank_code = EXCLUDED.bank_code,
+		    account_number = EXCLUDED.account_number,
+		    account_name = EXCLUDED.account_name,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = CURRENT_TIMESTAMP
+	`, account.UserId, account.BankCode, account.AccountNumber, account.AccountName, updatedBy)
+	return err
+}
+
+func (s *Storage) IncrementTokenVersion(ctx context.Context, userId int, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET token_version = token_version + 1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId, updatedBy)
+	return err
+}
+
+func (s *Storage) InsertRefreshToken(ctx context.Context, userId int, tokenHash string, familyId string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId, tokenHash, familyId, expiresAt)
+	return err
+}
+
+func (s *Storage) GetRefreshToken(ctx context.Context, tokenHash string) (*data.RefreshToken, database.ErrType, error) {
+	token := &data.RefreshToken{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT id, user_id, family_id::text, expires_at, revoked_at, created_at
+		 FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash).Scan(&token.Id, &token.UserId, &token.FamilyId, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return token, database.ErrUnset, nil
+}
+
+func (s *Storage) RevokeRefreshToken(ctx context.Context, tokenId int) (bool, error) {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`
+	tag, err := s.db(ctx).Exec(ctx, query, tokenId)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := s.db(ctx).Exec(ctx, query, familyId)
+	return err
+}
+
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userId int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := s.db(ctx).Exec(ctx, query, userId)
+	return err
+}
+
+func (s *Storage) InsertRevokedAccessToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error {
+	// revoked token past its expiry is rejected by the signature check anyway, no need to keep it
+	_, err := s.db(ctx).Exec(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err = s.db(ctx).Exec(ctx, query, jti, userId, expiresAt)
+	return err
+}
+
+func (s *Storage) GetSessionState(ctx context.Context, userId int, jti string) (*data.SessionState, database.ErrType, error) {
+	state := &data.SessionState{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT COALESCE(u.is_active, false), u.token_version,
+		        EXISTS (SELECT 1 FROM revoked_access_tokens r WHERE r.jti::text = $2)
+		 FROM users u WHERE u.id = $1`,
+		userId, jti).Scan(&state.IsActive, &state.TokenVersion, &state.AccessRevoked)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return state, database.ErrUnset, nil
+}
+
+func (s *Storage) GetUserMFA(ctx context.Context, userId int) (*data.UserMFA, database.ErrType, error) {
+	mfa := &data.UserMFA{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT user_id, secret, enabled, last_used_step, enabled_at FROM user_mfa WHERE user_id = $1`,
+		userId).Scan(&mfa.UserId, &mfa.Secret, &mfa.Enabled, &mfa.LastUsedStep, &mfa.EnabledAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return mfa, database.ErrUnset, nil
+}
+
+func (s *Storage) UpsertPendingMFA(ctx context.Context, userId int, secret string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+		    enabled = false,
+		    last_used_step = 0,
+		    enabled_at = NULL,
+		    updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId, secret)
+	return err
+}
+
+func (s *Storage) EnableMFA(ctx context.Context, userId int) error {
+	query := `
+		UPDATE user_mfa
+		SET enabled = true, enabled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId)
+	return err
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userId int, step int64) (bool, error) {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+	tag, err := s.db(ctx).Exec(ctx, query, userId, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error {
+	_, err := s.db(ctx).Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+	for _, hash := range codeHashes {
+		if _, err := s.db(ctx).Exec(ctx, query, userId, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := s.db(ctx).Exec(ctx, query, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) GetActiveLoginThrottles(ctx context.Context, username string, ip string) ([]*data.LoginThrottle, error) {
+	query := `
+		SELECT scope, key, failed_count, last_failed_at, locked_until
+		FROM login_throttles
+		WHERE ((scope = 'username' AND key = $1) OR (scope = 'ip' AND key = $2))
+		  AND locked_until > CURRENT_TIMESTAMP
+	`
+	rows, err := s.db(ctx).Query(ctx, query, username, ip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.LoginThrottle, 0)
+	for rows.Next() {
+		var t data.LoginThrottle
+		if err := rows.Scan(&t.Scope, &t.Key, &t.FailedCount, &t.LastFailedAt, &t.LockedUntil); err != nil {
+			return nil, err
+		}
+		result = append(result, &t)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) IncrementLoginFailure(ctx context.Context, scope data.LoginThrottleScope, key string, window time.Duration) (int, error) {
+	// failures older than the window are forgotten, the counter starts again from 1
+	query := `
+		INSERT INTO login_throttles (scope, key, failed_count, last_failed_at)
+		VALUES ($1, $2, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failed_count = CASE
+		        WHEN login_throttles.last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $3)
+		             AND (login_throttles.locked_until IS NULL OR login_throttles.locked_until < CURRENT_TIMESTAMP)
+		        THEN 1
+		        ELSE login_throttles.failed_count + 1
+		    END,
+		    last_failed_at = CURRENT_TIMESTAMP
+		RETURNING failed_count
+	`
+	var count int
+	err := s.db(ctx).QueryRow(ctx, query, scope, key, window.Seconds()).Scan(&count)
+	return count, err
+}
+
+func (s *Storage) SetLoginLockedUntil(ctx context.Context, scope data.LoginThrottleScope, key string, lockedUntil time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $3 WHERE scope = $1 AND key = $2`
+	_, err := s.db(ctx).Exec(ctx, query, scope, key, lockedUntil)
+	return err
+}
+
+func (s *Storage) ResetLoginThrottle(ctx context.Context, scope data.LoginThrottleScope, key string) (bool, error) {
+	tag, err := s.db(ctx).Exec(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Storage) InsertAuthEvent(ctx context.Context, event *data.AuthEvent) error {
+	query := `
+		INSERT INTO auth_events (user_id, username, ip, event, detail)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5)
+	`
+	_, err := s.db(ctx).Exec(ctx, query, event.UserId, event.Username, event.IP, event.Event, event.Detail)
+	return err
+}
+
+func (s *Storage) UpdateUserPassword(ctx context.Context, userId int, passwordHash string, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $2, updated_by = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId, passwordHash, updatedBy)
+	return err
+}
+
+func (s *Storage) InsertPasswordResetToken(ctx context.Context, userId int, tokenHash string, expiresAt time.Time) error {
+	// only the latest requested link works
+	_, err := s.db(ctx).Exec(ctx,
+		`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`,
+		userId)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err = s.db(ctx).Exec(ctx, query, userId, tokenHash, expiresAt)
+	return err
+}
+
+func (s *Storage) GetPasswordResetToken(ctx context.Context, tokenHash string) (*data.PasswordResetToken, database.ErrType, error) {
+	token := &data.PasswordResetToken{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = $1`,
+		tokenHash).Scan(&token.Id, &token.UserId, &token.ExpiresAt, &token.UsedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return token, database.ErrUnset, nil
+}
+
+func (s *Storage) UsePasswordResetToken(ctx context.Context, tokenId int) (bool, error) {
+	tag, err := s.db(ctx).Exec(ctx,
+		`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`,
+		tokenId)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) UpdateUserRole(ctx context.Context, userId int, role data.UserRole, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET role = $2, updated_by = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := s.db(ctx).Exec(ctx, query, userId, role, updatedBy)
+	return err
+}
+
+func (s *Storage) InsertOIDCLoginState(ctx context.Context, state *data.OIDCLoginState) error {
+	// abandoned logins are cleaned up on the way
+	_, err := s.db(ctx).Exec(ctx, `DELETE FROM oidc_login_states WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = s.db(ctx).Exec(ctx, query, state.State, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+func (s *Storage) TakeOIDCLoginState(ctx context.Context, state string) (*data.OIDCLoginState, database.ErrType, error) {
+	result := &data.OIDCLoginState{}
+	err := s.db(ctx).QueryRow(ctx,
+		`DELETE FROM oidc_login_states WHERE state = $1
+		 RETURNING state, provider, nonce, code_verifier, expires_at`,
+		state).Scan(&result.State, &result.Provider, &result.Nonce, &result.CodeVerifier, &result.ExpiresAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return result, database.ErrUnset, nil
+}
+
+func (s *Storage) GetUserByIdentity(ctx context.Context, provider string, subject string) (*data.User, database.ErrType, error) {
+	user := &data.User{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT  u.id, u.fullname, u.username, u.email, u.password_hash, u.role, u.base_salary, u.join_date, u.cost_center, u.religion,
+		        COALESCE(u.is_active, false), u.token_version, u.created_at, u.updated_at
+         FROM user_identities i
+         JOIN users u ON u.id = i.user_id
+         WHERE i.provider = $1 AND i.subject = $2`,
+		provider, subject).Scan(&user.Id, &user.Fullname, &user.Username, &user.Email, &user.Password,
+		&user.Role, &user.BaseSalary, &user.JoinDate, &user.CostCenter, &user.Religion,
+		&user.IsActive, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+
+	user.JoinDate = common.TruncateToJakartaDate(user.JoinDate)
+	user.CreatedAt = common.TruncateToJakartaDate(user.CreatedAt)
+	user.UpdatedAt = common.TruncateToJakartaDate(user.UpdatedAt)
+	return user, database.ErrUnset, nil
+}
+
+func (s *Storage) InsertUserIdentity(ctx context.Context, userId int, provider string, subject string, email string) (bool, error) {
+	query := `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	tag, err := s.db(ctx).Exec(ctx, query, provider, subject, userId, email)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) InsertServiceAccount(ctx context.Context, name string, description string, role data.UserRole, createdBy string) (int, database.ErrType, error) {
+	query := `
+		INSERT INTO service_accounts (name, description, role, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id
+	`
+	var id int
+	err := s.db(ctx).QueryRow(ctx, query, name, description, role, createdBy).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, database.ErrDuplicate, err
+		}
+		return 0, database.ErrUnset, err
+	}
+	return id, database.ErrUnset, nil
+}
+
+func (s *Storage) GetServiceAccount(ctx context.Context, serviceAccountId int) (*data.ServiceAccount, database.ErrType, error) {
+	account := &data.ServiceAccount{}
+	err := s.db(ctx).QueryRow(ctx,
+		`SELECT id, name, description, role, created_at, COALESCE(created_by, '')
+		 FROM service_accounts WHERE id = $1`,
+		serviceAccountId).Scan(&account.Id, &account.Name, &account.Description, &account.Role, &account.CreatedAt, &account.CreatedBy)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return account, database.ErrUnset, nil
+}
+
+func (s *Storage) GetAllServiceAccount(ctx context.Context) ([]*data.ServiceAccount, error) {
+	rows, err := s.db(ctx).Query(ctx,
+		`SELECT id, name, description, role, created_at, COALESCE(created_by, '')
+		 FROM service_accounts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.ServiceAccount, 0)
+	for rows.Next() {
+		var account data.ServiceAccount
+		if err := rows.Scan(&account.Id, &account.Name, &account.Description, &account.Role, &account.CreatedAt, &account.CreatedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, &account)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) InsertAPIToken(ctx context.Context, token *data.APIToken, tokenHash string, createdBy string) (int, error) {
+	query := `
+		INSERT INTO api_tokens (user_id, service_account_id, name, token_prefix, token_hash, scopes, expires_at, created_by)
+		VALUES (NULLIF($1, 0), NULLIF($2, 0), $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	var id int
+	err := s.db(ctx).QueryRow(ctx, query, token.UserId, token.ServiceAccountId, token.Name, token.Prefix, tokenHash,
+		token.Scopes, token.ExpiresAt, createdBy).Scan(&id)
+	return id, err
+}
+
+// apiTokenColumns are qualified, GetAPITokenOwner joins tables having the same column names
+const apiTokenColumns = `api_tokens.id, COALESCE(api_tokens.user_id, 0), COALESCE(api_tokens.service_account_id, 0),
+		api_tokens.name, api_tokens.token_prefix, api_tokens.scopes, api_tokens.expires_at, api_tokens.last_used_at,
+		api_tokens.last_used_ip, api_tokens.revoked_at, api_tokens.created_at, COALESCE(api_tokens.created_by, '')`
+
+func scanAPIToken(row pgx.Row, token *data.APIToken, extra ...interface{}) error {
+	dest := []interface{}{&token.Id, &token.UserId, &token.ServiceAccountId, &token.Name, &token.Prefix, &token.Scopes,
+		&token.ExpiresAt, &token.LastUsedAt, &token.LastUsedIP, &token.RevokedAt, &token.CreatedAt, &token.CreatedBy}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (s *Storage) GetAPIToken(ctx context.Context, tokenId int) (*data.APIToken, database.ErrType, error) {
+	token := &data.APIToken{}
+	err := scanAPIToken(s.db(ctx).QueryRow(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = $1`, tokenId), token)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return token, database.ErrUnset, nil
+}
+
+func (s *Storage) GetAPITokens(ctx context.Context, userId int, serviceAccountId int) ([]*data.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE user_id = $1 ORDER BY id DESC`
+	owner := userId
+	if serviceAccountId != 0 {
+		query = `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE service_account_id = $1 ORDER BY id DESC`
+		owner = serviceAccountId
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.APIToken, 0)
+	for rows.Next() {
+		var token data.APIToken
+		if err := scanAPIToken(rows, &token); err != nil {
+			return nil, err
+		}
+		result = append(result, &token)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) RevokeAPIToken(ctx context.Context, tokenId int) (bool, error) {
+	tag, err := s.db(ctx).Exec(ctx,
+		`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`,
+		tokenId)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) GetAPITokenOwner(ctx context.Context, tokenHash string) (*data.APITokenOwner, database.ErrType, error) {
+	query := `
+		SELECT ` + apiTokenColumns + `,
+		       COALESCE(u.username, sa.name), COALESCE(u.role, sa.role),
+		       CASE WHEN u.id IS NOT NULL THEN COALESCE(u.is_active, false) ELSE true END
+		FROM api_tokens
+		LEFT JOIN users u ON u.id = api_tokens.user_id
+		LEFT JOIN service_accounts sa ON sa.id = api_tokens.service_account_id
+		WHERE api_tokens.token_hash = $1
+	`
+	owner := &data.APITokenOwner{}
+	err := scanAPIToken(s.db(ctx).QueryRow(ctx, query, tokenHash), &owner.Token, &owner.Username, &owner.Role, &owner.IsActive)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.ErrNotFound, err
+		}
+		return nil, database.ErrUnset, err
+	}
+	return owner, database.ErrUnset, nil
+}
+
+func (s *Storage) TouchAPIToken(ctx context.Context, tokenId int, ip string) error {
+	query := `
+		UPDATE api_tokens
+		SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`
+	_, err := s.db(ctx).Exec(ctx, query, tokenId, ip)
+	return err
+}
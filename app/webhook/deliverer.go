@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/outbox"
+)
+
+var _ outbox.Sink = (*Sink)(nil)
+
+// Sink is the outbox sink of the webhooks, it queues a delivery of the event for every subscribed webhook.
+// The Deliverer sends them, so a slow endpoint does not hold back the outbox.
+type Sink struct {
+	storage lib.StorageInterface
+}
+
+func NewSink(storage lib.StorageInterface) *Sink {
+	return &Sink{storage: storage}
+}
+
+func (s *Sink) Name() string { return "webhook" }
+
+func (s *Sink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.InsertDeliveries(ctx, event, payload, time.Now())
+	return err
+}
+
+const (
+	deliveryBatchSize = 50
+
+	// pollInterval is how long the deliverer waits when there is nothing to send
+	pollInterval = 2 * time.Second
+
+	// sendTimeout bounds one request to a webhook
+	sendTimeout = 10 * time.Second
+
+	// leaseDuration is how long a leased batch is hidden from the other deliverers, long enough to send
+	// every delivery of the batch. A deliverer stopped mid batch leaves the rest to be sent after it.
+	leaseDuration = deliveryBatchSize*sendTimeout + time.Minute
+)
+
+// Deliverer sends the queued deliveries to the webhooks, signed with their secret. Instances may run a
+// deliverer each, a delivery being sent is leased so only one of them sends it.
+type Deliverer struct {
+	storage     lib.StorageInterface
+	client      *http.Client
+	maxAttempts int
+	now         func() time.Time
+}
+
+func NewDeliverer(storage lib.StorageInterface, maxAttempts int) *Deliverer {
+	return &Deliverer{
+		storage:     storage,
+		client:      &http.Client{Timeout: sendTimeout},
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+// Run sends the deliveries until ctx is done
+func (d *Deliverer) Run(ctx context.Context) {
+	for {
+		sent, err := d.Deliver(ctx)
+		if err != nil {
+			log.Error(nil).Err(err).Msg("webhook/ deliver failed")
+		}
+		// a full batch means more may be waiting
+		if err == nil && sent == deliveryBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Deliver sends one batch of due deliveries and returns how many were tried
+func (d *Deliverer) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := d.lease(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// the requests run outside any transaction, every attempt is stored on its own so a failed
+	// update does not lose the result of the deliveries already sent
+	for _, delivery := range deliveries {
+		code, failure := d.send(ctx, delivery)
+		recordAttempt(delivery, code, failure, d.now().UTC(), d.maxAttempts)
+		if delivery.Status != data.DeliveryDelivered {
+			log.Warn(nil).Int64("deliveryId", delivery.Id).Int("webhookId", delivery.WebhookId).Int("attempts", delivery.Attempts).
+				Str("status", string(delivery.Status)).Str("reason", delivery.LastError).Msg("webhook/ delivery failed")
+		}
+		if err := d.storage.UpdateDeliveryAttempt(ctx, delivery); err != nil {
+			// the lease runs out and the delivery is sent again, the receiver drops the repeated event id
+			log.Error(nil).Err(err).Int64("deliveryId", delivery.Id).Msg("webhook/ update delivery attempt failed")
+		}
+	}
+	return len(deliveries), nil
+}
+
+// lease takes a batch of due deliveries in a short transaction, the other deliverers skip them until
+// the lease runs out
+func (d *Deliverer) lease(ctx context.Context) ([]*data.WebhookDelivery, error) {
+	tx, err := d.storage.BeginTxWriter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	now := d.now()
+	deliveries, err := d.storage.LeaseDueDeliveries(ctx, now, now.Add(leaseDuration), deliveryBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// send posts the payload of the delivery, it returns the response code and why it failed, empty on 2xx
+func (d *Deliverer) send(ctx context.Context, delivery *data.WebhookDelivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "payroll-webhook/1")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	// the receiver drops an event id it already processed, a redelivery has the same id
+	req.Header.Set("X-Webhook-Event-Id", strconv.FormatInt(delivery.EventId, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set("X-Webhook-Signature", signature(delivery.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("status %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, ""
+}
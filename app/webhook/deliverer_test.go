@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelivererSend(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		if status >= 300 {
+			w.Write([]byte("maintenance"))
+		}
+	}))
+	defer receiver.Close()
+
+	now := time.Unix(1718600000, 0)
+	deliverer := NewDeliverer(nil, DefaultMaxAttempts)
+	deliverer.now = func() time.Time { return now }
+
+	delivery := &data.WebhookDelivery{
+		Id: 9, EventId: 42, EventType: data.EventPayrollFinalized,
+		Payload: json.RawMessage(`{"id":42,"type":"payroll.finalized"}`), URL: receiver.URL, Secret: "whsec_test",
+	}
+
+	code, failure := deliverer.send(context.Background(), delivery)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "", failure)
+	assert.Equal(t, `{"id":42,"type":"payroll.finalized"}`, string(body))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, data.EventPayrollFinalized, got.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, "42", got.Header.Get("X-Webhook-Event-Id"))
+	assert.Equal(t, "9", got.Header.Get("X-Webhook-Delivery"))
+	// the receiver verifies the body with its secret
+	assert.Equal(t, signature("whsec_test", now, body), got.Header.Get("X-Webhook-Signature"))
+
+	status = http.StatusServiceUnavailable
+	code, failure = deliverer.send(context.Background(), delivery)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "status 503: maintenance", failure)
+
+	receiver.Close()
+	code, failure = deliverer.send(context.Background(), delivery)
+	require.Equal(t, 0, code)
+	assert.Contains(t, failure, "connection refused")
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	// DefaultMaxAttempts is how many times a delivery is tried before it is dead
+	DefaultMaxAttempts = 8
+
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+
+	// maxErrorLength is how much of the response body is kept in the delivery log
+	maxErrorLength = 1024
+)
+
+// validateWebhook checks the endpoint and the event types, it returns the message for the user or empty string
+func validateWebhook(endpoint string, eventTypes []string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL harus http atau https"
+	}
+	if len(eventTypes) == 0 {
+		return "Event type wajib diisi"
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(data.EventTypes, eventType) {
+			return fmt.Sprintf("Event type %s tidak dikenal", eventType)
+		}
+	}
+	return ""
+}
+
+// newSecret returns the HMAC key of a webhook
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// signature is the X-Webhook-Signature header: t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">.
+// The receiver recomputes it with the secret and rejects an old timestamp, so a captured request can not be replayed.
+func signature(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay doubles the wait after every failed attempt, from 30 seconds up to 6 hours
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// recordAttempt sets the outcome of one attempt on the delivery. A failed delivery is retried later,
+// it is dead once it failed maxAttempts times.
+func recordAttempt(delivery *data.WebhookDelivery, responseCode int, failure string, now time.Time, maxAttempts int) {
+	delivery.Attempts++
+	delivery.ResponseCode = responseCode
+	if len(failure) > maxErrorLength {
+		failure = failure[:maxErrorLength]
+	}
+	delivery.LastError = failure
+
+	if failure == "" {
+		delivery.Status = data.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = data.DeliveryDead
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(retryDelay(delivery.Attempts))
+	delivery.Status = data.DeliveryFailed
+	delivery.NextAttemptAt = &next
+}
+
+func validateFilter(filter *data.WebhookDeliveryFilter) string {
+	switch filter.Status {
+	case "", data.DeliveryPending, data.DeliveryFailed, data.DeliveryDelivered, data.DeliveryDead:
+	default:
+		return "Status tidak valid"
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return "Limit atau offset tidak valid"
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	return ""
+}
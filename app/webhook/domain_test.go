@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	scenarios := []struct {
+		name       string
+		url        string
+		eventTypes []string
+		msg        string
+	}{
+		{name: "valid", url: "https://erp.example.com/hooks/payroll", eventTypes: []string{data.EventPayrollFinalized}},
+		{name: "http is allowed", url: "http://10.0.0.5:8080/hook", eventTypes: data.EventTypes},
+		{name: "no scheme", url: "erp.example.com/hook", eventTypes: data.EventTypes, msg: "URL harus http atau https"},
+		{name: "other scheme", url: "ftp://erp.example.com", eventTypes: data.EventTypes, msg: "URL harus http atau https"},
+		{name: "no event type", url: "https://erp.example.com", msg: "Event type wajib diisi"},
+		{
+			name: "unknown event type", url: "https://erp.example.com", eventTypes: []string{"payroll.deleted"},
+			msg: "Event type payroll.deleted tidak dikenal",
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.msg, validateWebhook(sc.url, sc.eventTypes))
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := newSecret()
+	require.NoError(t, err)
+	b, err := newSecret()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(a, "whsec_"))
+	assert.Len(t, a, len("whsec_")+64)
+	assert.NotEqual(t, a, b)
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	ts := time.Unix(1718600000, 0)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1718600000.{\"id\":1}"))
+	assert.Equal(t, "t=1718600000,v1="+hex.EncodeToString(mac.Sum(nil)), signature("whsec_test", ts, body))
+
+	assert.NotEqual(t, signature("whsec_test", ts, body), signature("whsec_other", ts, body))
+	assert.NotEqual(t, signature("whsec_test", ts, body), signature("whsec_test", ts.Add(time.Second), body))
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryDelay(1))
+	assert.Equal(t, time.Minute, retryDelay(2))
+	assert.Equal(t, 4*time.Minute, retryDelay(4))
+	assert.Equal(t, 6*time.Hour, retryDelay(20))
+}
+
+func TestRecordAttempt(t *testing.T) {
+	now := time.Date(2025, 6, 17, 18, 0, 0, 0, time.UTC)
+	delivery := &data.WebhookDelivery{Status: data.DeliveryPending}
+
+	recordAttempt(delivery, 503, "status 503: down", now, 3)
+	assert.Equal(t, data.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 503, delivery.ResponseCode)
+	require.NotNil(t, delivery.NextAttemptAt)
+	assert.Equal(t, now.Add(30*time.Second), *delivery.NextAttemptAt)
+
+	recordAttempt(delivery, 0, strings.Repeat("x", 2000), now, 3)
+	assert.Equal(t, data.DeliveryFailed, delivery.Status)
+	assert.Len(t, delivery.LastError, maxErrorLength)
+	assert.Equal(t, now.Add(time.Minute), *delivery.NextAttemptAt)
+
+	// the third failure of 3 is the last
+	recordAttempt(delivery, 500, "status 500: ", now, 3)
+	assert.Equal(t, data.DeliveryDead, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	delivery = &data.WebhookDelivery{Status: data.DeliveryFailed, Attempts: 2}
+	recordAttempt(delivery, 204, "", now, 3)
+	assert.Equal(t, data.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, "", delivery.LastError)
+	assert.Nil(t, delivery.NextAttemptAt)
+	require.NotNil(t, delivery.DeliveredAt)
+	assert.Equal(t, now, *delivery.DeliveredAt)
+}
+
+func TestValidateFilter(t *testing.T) {
+	filter := data.WebhookDeliveryFilter{}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, defaultListLimit, filter.Limit)
+
+	filter = data.WebhookDeliveryFilter{Status: data.DeliveryDead, Limit: 10000}
+	assert.Equal(t, "", validateFilter(&filter))
+	assert.Equal(t, maxListLimit, filter.Limit)
+
+	filter = data.WebhookDeliveryFilter{Status: "sent"}
+	assert.Equal(t, "Status tidak valid", validateFilter(&filter))
+
+	filter = data.WebhookDeliveryFilter{Limit: -1}
+	assert.Equal(t, "Limit atau offset tidak valid", validateFilter(&filter))
+}
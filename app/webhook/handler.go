@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service lib.ServiceInterface
+}
+
+func NewHandler(service lib.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// parseInt parses an optional number, empty is 0
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, "Invalid request body")
+		return
+	}
+
+	out := h.service.CreateWebhook(r.Context(), &lib.CreateWebhookIn{
+		Trace:      trace,
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Webhook)
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	out := h.service.ListWebhooks(r.Context(), &lib.ListWebhooksIn{Trace: trace})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	webhookId, err := strconv.Atoi(chi.URLParam(r, "webhookId"))
+	if err != nil {
+		response.Error(w, r, "Param 'webhookId' harus angka")
+		return
+	}
+
+	out := h.service.DeleteWebhook(r.Context(), &lib.DeleteWebhookIn{
+		Trace: trace,
+		Id:    webhookId,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", nil)
+}
+
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := data.WebhookDeliveryFilter{
+		Status: data.WebhookDeliveryStatus(query.Get("status")),
+	}
+
+	var err error
+	if filter.WebhookId, err = parseInt(query.Get("webhook_id")); err != nil {
+		response.Error(w, r, "Param 'webhook_id' harus angka")
+		return
+	}
+	if filter.Limit, err = parseInt(query.Get("limit")); err != nil {
+		response.Error(w, r, "Param 'limit' harus angka")
+		return
+	}
+	if filter.Offset, err = parseInt(query.Get("offset")); err != nil {
+		response.Error(w, r, "Param 'offset' harus angka")
+		return
+	}
+
+	out := h.service.ListDeliveries(r.Context(), &lib.ListDeliveriesIn{
+		Trace:  trace,
+		Filter: filter,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Result)
+}
+
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	trace, ok := contextutil.GetTrace(r.Context())
+	if !ok {
+		response.Error(w, r, "Trace not found")
+		return
+	}
+
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		response.Error(w, r, "Param 'deliveryId' harus angka")
+		return
+	}
+
+	out := h.service.Redeliver(r.Context(), &lib.RedeliverIn{
+		Trace:      trace,
+		DeliveryId: deliveryId,
+	})
+
+	if !out.Success {
+		response.Fail(w, r, out.Err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, trace.TraceID, true, "", out.Delivery)
+}
@@ -0,0 +1,89 @@
+// Code generated by instrumentgen. DO NOT EDIT.
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named webhook.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="webhook"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+
+func (s *InstrumentedService) CreateWebhook(ctx context.Context, in *CreateWebhookIn) *CreateWebhookOut {
+	ctx, span := tracing.Start(ctx, "webhook.CreateWebhook")
+	start := time.Now()
+	out := s.next.CreateWebhook(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("webhook", "CreateWebhook", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListWebhooks(ctx context.Context, in *ListWebhooksIn) *ListWebhooksOut {
+	ctx, span := tracing.Start(ctx, "webhook.ListWebhooks")
+	start := time.Now()
+	out := s.next.ListWebhooks(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("webhook", "ListWebhooks", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) DeleteWebhook(ctx context.Context, in *DeleteWebhookIn) *DeleteWebhookOut {
+	ctx, span := tracing.Start(ctx, "webhook.DeleteWebhook")
+	start := time.Now()
+	out := s.next.DeleteWebhook(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("webhook", "DeleteWebhook", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) ListDeliveries(ctx context.Context, in *ListDeliveriesIn) *ListDeliveriesOut {
+	ctx, span := tracing.Start(ctx, "webhook.ListDeliveries")
+	start := time.Now()
+	out := s.next.ListDeliveries(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("webhook", "ListDeliveries", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+
+func (s *InstrumentedService) Redeliver(ctx context.Context, in *RedeliverIn) *RedeliverOut {
+	ctx, span := tracing.Start(ctx, "webhook.Redeliver")
+	start := time.Now()
+	out := s.next.Redeliver(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("webhook", "Redeliver", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
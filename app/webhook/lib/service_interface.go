@@ -0,0 +1,82 @@
+package lib
+
+//go:generate go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+type ServiceInterface interface {
+	// CreateWebhook registers an endpoint for the event types, the secret is only returned here (admin only)
+	CreateWebhook(ctx context.Context, in *CreateWebhookIn) *CreateWebhookOut
+
+	// ListWebhooks returns the registered webhooks without their secret (admin only)
+	ListWebhooks(ctx context.Context, in *ListWebhooksIn) *ListWebhooksOut
+
+	// DeleteWebhook deactivates the webhook, its delivery log is kept (admin only)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookIn) *DeleteWebhookOut
+
+	// ListDeliveries searches the delivery log (admin only)
+	ListDeliveries(ctx context.Context, in *ListDeliveriesIn) *ListDeliveriesOut
+
+	// Redeliver sends the delivery again, eg: a dead delivery once the endpoint is fixed (admin only)
+	Redeliver(ctx context.Context, in *RedeliverIn) *RedeliverOut
+}
+
+type CreateWebhookIn struct {
+	Trace      *contextutil.Trace
+	URL        string
+	EventTypes []string
+}
+
+type CreateWebhookOut struct {
+	Success bool
+	apperror.Failure
+	Webhook *data.Webhook
+}
+
+type ListWebhooksIn struct {
+	Trace *contextutil.Trace
+}
+
+type ListWebhooksOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.Webhook
+}
+
+type DeleteWebhookIn struct {
+	Trace *contextutil.Trace
+	Id    int
+}
+
+type DeleteWebhookOut struct {
+	Success bool
+	apperror.Failure
+}
+
+type ListDeliveriesIn struct {
+	Trace  *contextutil.Trace
+	Filter data.WebhookDeliveryFilter
+}
+
+type ListDeliveriesOut struct {
+	Success bool
+	apperror.Failure
+	Result []*data.WebhookDelivery
+}
+
+type RedeliverIn struct {
+	Trace      *contextutil.Trace
+	DeliveryId int64
+}
+
+type RedeliverOut struct {
+	Success bool
+	apperror.Failure
+	Delivery *data.WebhookDelivery
+}
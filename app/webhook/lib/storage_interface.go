@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/jackc/pgx/v4"
+)
+
+type StorageInterface interface {
+	BeginTxReader(ctx context.Context) (pgx.Tx, error)
+	BeginTxWriter(ctx context.Context) (pgx.Tx, error)
+
+	// InsertWebhook stores an active webhook, fills Id and CreatedAt
+	InsertWebhook(ctx context.Context, webhook *data.Webhook, createdBy string) error
+
+	// GetWebhooks returns every webhook without its secret, latest first
+	GetWebhooks(ctx context.Context) ([]*data.Webhook, error)
+
+	// DeactivateWebhook stops the deliveries to the webhook, it returns false when there is no active webhook of id
+	DeactivateWebhook(ctx context.Context, id int, updatedBy string) (bool, error)
+
+	// InsertDeliveries queues the event for every active webhook subscribed to its type, an event already
+	// queued for a webhook is skipped. It returns the number of deliveries queued.
+	InsertDeliveries(ctx context.Context, event *data.OutboxEvent, payload []byte, now time.Time) (int64, error)
+
+	// LeaseDueDeliveries moves the next attempt of the pending and failed deliveries due at now to leaseUntil
+	// and returns them with their URL, secret and payload, oldest first. Deliveries locked by another
+	// transaction are skipped.
+	LeaseDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*data.WebhookDelivery, error)
+
+	// UpdateDeliveryAttempt stores the status, attempts, response and next attempt of the delivery
+	UpdateDeliveryAttempt(ctx context.Context, delivery *data.WebhookDelivery) error
+
+	// GetDeliveries returns the delivery log matching the filter, latest first
+	GetDeliveries(ctx context.Context, filter *data.WebhookDeliveryFilter) ([]*data.WebhookDelivery, error)
+
+	// ResetDelivery queues the delivery again from its first attempt, it returns nil when it does not exist
+	ResetDelivery(ctx context.Context, id int64, now time.Time) (*data.WebhookDelivery, error)
+}
@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRoutes(r chi.Router, handler *Handler) {
+	r.Route("/webhooks", func(r chi.Router) {
+
+		// Private endpoint - require auth middleware
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
+
+			// (admin)
+			r.Post("/", handler.CreateWebhook)
+			r.Get("/", handler.ListWebhooks)
+			r.Delete("/{webhookId}", handler.DeleteWebhook)
+			r.Get("/deliveries", handler.ListDeliveries)
+			r.Post("/deliveries/{deliveryId}/redeliver", handler.Redeliver)
+		})
+	})
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+var _ lib.ServiceInterface = (*Service)(nil)
+
+type Service struct {
+	storage lib.StorageInterface
+}
+
+func NewService(storage lib.StorageInterface) *Service {
+	return &Service{storage: storage}
+}
+
+func (s *Service) CreateWebhook(ctx context.Context, in *lib.CreateWebhookIn) *lib.CreateWebhookOut {
+	resp := &lib.CreateWebhookOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("CreateWebhook/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("CreateWebhook/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	if msg := validateWebhook(in.URL, in.EventTypes); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("CreateWebhook/ invalid webhook")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return resp
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateWebhook/ generate secret failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateWebhook/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	webhook := &data.Webhook{URL: in.URL, EventTypes: in.EventTypes, Secret: secret}
+	if err := s.storage.InsertWebhook(ctx, webhook, user.Actor()); err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateWebhook/ insert webhook failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("CreateWebhook/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Webhook = webhook
+	return resp
+}
+
+func (s *Service) ListWebhooks(ctx context.Context, in *lib.ListWebhooksIn) *lib.ListWebhooksOut {
+	resp := &lib.ListWebhooksOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListWebhooks/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListWebhooks/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListWebhooks/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	webhooks, err := s.storage.GetWebhooks(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListWebhooks/ get webhooks failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = webhooks
+	return resp
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, in *lib.DeleteWebhookIn) *lib.DeleteWebhookOut {
+	resp := &lib.DeleteWebhookOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("DeleteWebhook/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("DeleteWebhook/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("DeleteWebhook/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	found, err := s.storage.DeactivateWebhook(ctx, in.Id, user.Actor())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("DeleteWebhook/ deactivate webhook failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if !found {
+		log.Warn(in.Trace).Int("webhookId", in.Id).Msg("DeleteWebhook/ webhook not found")
+		resp.Fail(apperror.CodeWebhookNotFound, "Webhook tidak ditemukan")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("DeleteWebhook/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+func (s *Service) ListDeliveries(ctx context.Context, in *lib.ListDeliveriesIn) *lib.ListDeliveriesOut {
+	resp := &lib.ListDeliveriesOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("ListDeliveries/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("ListDeliveries/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	filter := in.Filter
+	if msg := validateFilter(&filter); msg != "" {
+		log.Warn(in.Trace).Str("reason", msg).Msg("ListDeliveries/ invalid filter")
+		resp.Fail(apperror.CodeInvalidInput, msg)
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxReader(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListDeliveries/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	deliveries, err := s.storage.GetDeliveries(ctx, &filter)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("ListDeliveries/ get deliveries failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Result = deliveries
+	return resp
+}
+
+func (s *Service) Redeliver(ctx context.Context, in *lib.RedeliverIn) *lib.RedeliverOut {
+	resp := &lib.RedeliverOut{}
+
+	user, ok := contextutil.GetUser(ctx)
+	if !ok {
+		log.Warn(in.Trace).Msg("Redeliver/ unauthorized")
+		resp.Fail(apperror.CodeUnauthorized, "unauthorized")
+		return resp
+	}
+	if user.Role != data.RAdmin {
+		log.Warn(in.Trace).Msg("Redeliver/ user not admin")
+		resp.Fail(apperror.CodeForbidden, "forbidden: Hanya admin yang bisa akses")
+		return resp
+	}
+
+	tx, err := s.storage.BeginTxWriter(ctx)
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Redeliver/ begin tx failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	defer tx.Rollback(ctx)
+	ctx = database.WithTx(ctx, tx)
+
+	delivery, err := s.storage.ResetDelivery(ctx, in.DeliveryId, time.Now())
+	if err != nil {
+		log.Error(in.Trace).Err(err).Msg("Redeliver/ reset delivery failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+	if delivery == nil {
+		log.Warn(in.Trace).Int64("deliveryId", in.DeliveryId).Msg("Redeliver/ delivery not found")
+		resp.Fail(apperror.CodeDeliveryNotFound, "Delivery tidak ditemukan")
+		return resp
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(in.Trace).Err(err).Msg("Redeliver/ commit failed")
+		resp.Fail(apperror.CodeInternal, "internal error")
+		return resp
+	}
+
+	resp.Success = true
+	resp.Delivery = delivery
+	return resp
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/outbox"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a local webhook endpoint answering status, it keeps the signature and body of every request
+type receiver struct {
+	mu         sync.Mutex
+	status     int
+	signatures []string
+	bodies     []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rc.signatures = append(rc.signatures, r.Header.Get("X-Webhook-Signature"))
+	rc.bodies = append(rc.bodies, string(body))
+	w.WriteHeader(rc.status)
+}
+
+func TestServiceWebhookDelivery(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+	service := NewService(storage)
+	sink := NewSink(storage)
+	deliverer := NewDeliverer(storage, 2)
+	now := time.Now()
+	deliverer.now = func() time.Time { return now }
+
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	employeeCtx := test.UserContext(con.Context, 2, data.REmployee)
+	trace := &contextutil.Trace{TraceID: "webhook-delivery-test"}
+
+	erp := &receiver{status: http.StatusOK}
+	erpServer := httptest.NewServer(erp)
+	defer erpServer.Close()
+	slack := &receiver{status: http.StatusInternalServerError}
+	slackServer := httptest.NewServer(slack)
+	defer slackServer.Close()
+
+	created := service.CreateWebhook(employeeCtx, &lib.CreateWebhookIn{Trace: trace, URL: erpServer.URL, EventTypes: data.EventTypes})
+	assert.False(t, created.Success)
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", created.Message)
+
+	created = service.CreateWebhook(adminCtx, &lib.CreateWebhookIn{Trace: trace, URL: erpServer.URL, EventTypes: []string{"payroll.deleted"}})
+	assert.False(t, created.Success)
+	assert.Equal(t, "Event type payroll.deleted tidak dikenal", created.Message)
+
+	created = service.CreateWebhook(adminCtx, &lib.CreateWebhookIn{Trace: trace, URL: erpServer.URL, EventTypes: []string{data.EventPayrollFinalized}})
+	require.True(t, created.Success, created.Message)
+	erpHook := created.Webhook
+	assert.NotEmpty(t, erpHook.Secret)
+	assert.True(t, erpHook.IsActive)
+
+	created = service.CreateWebhook(adminCtx, &lib.CreateWebhookIn{Trace: trace, URL: slackServer.URL, EventTypes: data.EventTypes})
+	require.True(t, created.Success, created.Message)
+	slackHook := created.Webhook
+
+	list := service.ListWebhooks(adminCtx, &lib.ListWebhooksIn{Trace: trace})
+	require.True(t, list.Success, list.Message)
+	require.Len(t, list.Result, 2)
+	assert.Equal(t, "", list.Result[0].Secret, "the secret is only returned on create")
+
+	// the outbox relays payroll.finalized twice (at least once), it is queued once per webhook
+	event := &data.OutboxEvent{Id: 100, Type: data.EventPayrollFinalized, AggregateType: "payroll", AggregateId: "3", Payload: []byte(`{"id":3}`)}
+	require.NoError(t, sink.Publish(con.Context, event))
+	require.NoError(t, sink.Publish(con.Context, event))
+	checkedIn := &data.OutboxEvent{Id: 101, Type: data.EventAttendanceCheckedIn, AggregateType: "employee", AggregateId: "2", Payload: []byte(`{}`)}
+	require.NoError(t, sink.Publish(con.Context, checkedIn))
+
+	sent, err := deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+
+	require.Len(t, erp.bodies, 1)
+	assert.Equal(t, signature(erpHook.Secret, now, []byte(erp.bodies[0])), erp.signatures[0])
+	assert.Contains(t, erp.bodies[0], `"type":"payroll.finalized"`)
+
+	deliveries := service.ListDeliveries(adminCtx, &lib.ListDeliveriesIn{Trace: trace, Filter: data.WebhookDeliveryFilter{WebhookId: erpHook.Id}})
+	require.True(t, deliveries.Success, deliveries.Message)
+	require.Len(t, deliveries.Result, 1)
+	assert.Equal(t, data.DeliveryDelivered, deliveries.Result[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries.Result[0].ResponseCode)
+
+	// slack fails, it is retried after the backoff then dead after 2 attempts
+	sent, err = deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	now = now.Add(retryDelay(1))
+	sent, err = deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	deliveries = service.ListDeliveries(adminCtx, &lib.ListDeliveriesIn{Trace: trace, Filter: data.WebhookDeliveryFilter{Status: data.DeliveryDead}})
+	require.True(t, deliveries.Success, deliveries.Message)
+	require.Len(t, deliveries.Result, 2)
+	dead := deliveries.Result[0]
+	assert.Equal(t, slackHook.Id, dead.WebhookId)
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead.ResponseCode)
+	assert.Nil(t, dead.NextAttemptAt)
+
+	now = now.Add(time.Hour)
+	sent, err = deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "a dead delivery is not retried")
+
+	// redelivered by the admin once slack is fixed
+	slack.status = http.StatusAccepted
+	redeliver := service.Redeliver(employeeCtx, &lib.RedeliverIn{Trace: trace, DeliveryId: dead.Id})
+	assert.Equal(t, "forbidden: Hanya admin yang bisa akses", redeliver.Message)
+	redeliver = service.Redeliver(adminCtx, &lib.RedeliverIn{Trace: trace, DeliveryId: 999999})
+	assert.Equal(t, "Delivery tidak ditemukan", redeliver.Message)
+
+	redeliver = service.Redeliver(adminCtx, &lib.RedeliverIn{Trace: trace, DeliveryId: dead.Id})
+	require.True(t, redeliver.Success, redeliver.Message)
+	assert.Equal(t, data.DeliveryPending, redeliver.Delivery.Status)
+	assert.Equal(t, 0, redeliver.Delivery.Attempts)
+
+	sent, err = deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	deliveries = service.ListDeliveries(adminCtx, &lib.ListDeliveriesIn{Trace: trace, Filter: data.WebhookDeliveryFilter{Status: data.DeliveryDelivered}})
+	require.True(t, deliveries.Success, deliveries.Message)
+	assert.Len(t, deliveries.Result, 2)
+
+	// a deleted webhook gets no more event
+	deleted := service.DeleteWebhook(adminCtx, &lib.DeleteWebhookIn{Trace: trace, Id: erpHook.Id})
+	require.True(t, deleted.Success, deleted.Message)
+	deleted = service.DeleteWebhook(adminCtx, &lib.DeleteWebhookIn{Trace: trace, Id: erpHook.Id})
+	assert.Equal(t, "Webhook tidak ditemukan", deleted.Message)
+
+	queued, err := storage.InsertDeliveries(con.Context, &data.OutboxEvent{Id: 102, Type: data.EventPayrollFinalized}, []byte(`{}`), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), queued, "only slack is still subscribed")
+}
+
+func TestServiceWebhookLease(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+	deliverer := NewDeliverer(storage, DefaultMaxAttempts)
+	now := time.Now()
+	deliverer.now = func() time.Time { return now }
+
+	erp := &receiver{status: http.StatusOK}
+	erpServer := httptest.NewServer(erp)
+	defer erpServer.Close()
+
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	created := NewService(storage).CreateWebhook(adminCtx, &lib.CreateWebhookIn{Trace: &contextutil.Trace{TraceID: "webhook-lease-test"}, URL: erpServer.URL, EventTypes: data.EventTypes})
+	require.True(t, created.Success, created.Message)
+	require.NoError(t, NewSink(storage).Publish(con.Context, &data.OutboxEvent{Id: 100, Type: data.EventPayrollFinalized}))
+
+	// another deliverer leased the delivery and is still sending it
+	leased, err := storage.LeaseDueDeliveries(con.Context, now, now.Add(leaseDuration), deliveryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, leased, 1)
+
+	sent, err := deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, erp.bodies)
+
+	// that deliverer stopped, the delivery is sent once its lease runs out
+	now = now.Add(leaseDuration)
+	sent, err = deliverer.Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, erp.bodies, 1)
+}
+
+func TestServiceWebhookFromOutbox(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	storage := NewStorage(con.Pool)
+	service := NewService(storage)
+	adminCtx := test.UserContext(con.Context, 1, data.RAdmin)
+	trace := &contextutil.Trace{TraceID: "webhook-outbox-test"}
+
+	erp := &receiver{status: http.StatusOK}
+	erpServer := httptest.NewServer(erp)
+	defer erpServer.Close()
+
+	created := service.CreateWebhook(adminCtx, &lib.CreateWebhookIn{Trace: trace, URL: erpServer.URL, EventTypes: []string{data.EventOvertimeSubmitted}})
+	require.True(t, created.Success, created.Message)
+
+	event, err := outbox.NewEvent(data.EventOvertimeSubmitted, "employee", "2", map[string]any{"hours": 2}, trace)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Insert(con.Context, con.Pool, event))
+
+	handled, err := outbox.NewDispatcher(con.Pool, NewSink(storage)).Dispatch(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 1, handled)
+
+	sent, err := NewDeliverer(storage, DefaultMaxAttempts).Deliver(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, erp.bodies, 1)
+	assert.Contains(t, erp.bodies[0], `"data":{"hours":2}`)
+	assert.Contains(t, erp.bodies[0], `"trace_id":"webhook-outbox-test"`)
+}
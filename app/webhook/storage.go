@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var _ lib.StorageInterface = (*Storage)(nil)
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStorage(pool *pgxpool.Pool) *Storage {
+	return &Storage{pool: pool}
+}
+
+// db runs the query in the transaction of the caller when there is one
+func (s *Storage) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, s.pool)
+}
+
+func (s *Storage) BeginTxReader(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// BeginTxWriter starts a read-write transaction and returns a pointer to pgx.Tx
+func (s *Storage) BeginTxWriter(ctx context.Context) (pgx.Tx, error) {
+	tx, err := database.BeginTx(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Storage) InsertWebhook(ctx context.Context, webhook *data.Webhook, createdBy string) error {
+	const query = `
+		INSERT INTO webhooks (url, event_types, secret, is_active, created_by)
+		VALUES ($1, $2, $3, true, $4)
+		RETURNING id, created_at
+	`
+	err := s.db(ctx).QueryRow(ctx, query, webhook.URL, webhook.EventTypes, webhook.Secret, createdBy).Scan(&webhook.Id, &webhook.CreatedAt)
+	if err != nil {
+		return err
+	}
+	webhook.IsActive = true
+	webhook.CreatedBy = createdBy
+	return nil
+}
+
+func (s *Storage) GetWebhooks(ctx context.Context) ([]*data.Webhook, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT id, url, event_types, is_active, created_at, created_by
+		FROM webhooks
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.Webhook, 0)
+	for rows.Next() {
+		var w data.Webhook
+		if err := rows.Scan(&w.Id, &w.URL, &w.EventTypes, &w.IsActive, &w.CreatedAt, &w.CreatedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, &w)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) DeactivateWebhook(ctx context.Context, id int, updatedBy string) (bool, error) {
+	tag, err := s.db(ctx).Exec(ctx, `
+		UPDATE webhooks
+		SET is_active = false, updated_at = CURRENT_TIMESTAMP, updated_by = $2
+		WHERE id = $1 AND is_active
+	`, id, updatedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) InsertDeliveries(ctx context.Context, event *data.OutboxEvent, payload []byte, now time.Time) (int64, error) {
+	tag, err := s.db(ctx).Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		SELECT id, $1, $2::text, $3, $4, $5, $5
+		FROM webhooks
+		WHERE is_active AND $2::text = ANY(event_types)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`, event.Id, event.Type, string(payload), data.DeliveryPending, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) LeaseDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*data.WebhookDelivery, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status IN ($1, $2) AND d.next_attempt_at <= $3 AND w.is_active
+			ORDER BY d.id
+			LIMIT $5
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $4
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.status, d.attempts, d.response_code, d.last_error,
+			d.next_attempt_at, d.delivered_at, d.created_at, d.payload, w.url, w.secret
+	`, data.DeliveryPending, data.DeliveryFailed, now.UTC(), leaseUntil.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.WebhookDelivery, 0)
+	for rows.Next() {
+		var d data.WebhookDelivery
+		var payload string
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Status, &d.Attempts, &d.ResponseCode, &d.LastError,
+			&d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt, &payload, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		result = append(result, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// UPDATE ... RETURNING has no order
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+func (s *Storage) UpdateDeliveryAttempt(ctx context.Context, delivery *data.WebhookDelivery) error {
+	_, err := s.db(ctx).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_code = $4, last_error = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1
+	`, delivery.Id, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.LastError,
+		delivery.NextAttemptAt, delivery.DeliveredAt)
+	return err
+}
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, status, attempts, response_code, last_error, next_attempt_at, delivered_at, created_at`
+
+func scanDelivery(row pgx.Row, d *data.WebhookDelivery) error {
+	return row.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Status, &d.Attempts, &d.ResponseCode, &d.LastError,
+		&d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt)
+}
+
+func (s *Storage) GetDeliveries(ctx context.Context, filter *data.WebhookDeliveryFilter) ([]*data.WebhookDelivery, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.WebhookId != 0 {
+		add("webhook_id = $%d", filter.WebhookId)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.WebhookDelivery, 0)
+	for rows.Next() {
+		var d data.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+	return result, rows.Err()
+}
+
+func (s *Storage) ResetDelivery(ctx context.Context, id int64, now time.Time) (*data.WebhookDelivery, error) {
+	row := s.db(ctx).QueryRow(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = 0, next_attempt_at = $3
+		WHERE id = $1
+		RETURNING `+deliveryColumns, id, data.DeliveryPending, now.UTC())
+
+	var d data.WebhookDelivery
+	if err := scanDelivery(row, &d); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,171 @@
+// instrumentgen writes the metrics and tracing decorator of the ServiceInterface of an app, so every service
+// method is counted, timed and traced without code in the service or the handler. Run it in the lib package of the app:
+//
+//	cd app/timeclock/lib && go run github.com/ariesmaulana/payroll/cmd/instrumentgen
+//
+// or go generate ./app/... through the directive in service_interface.go. It reads service_interface.go
+// and writes service_instrument.go. Every method must be Method(ctx context.Context, in *XIn) *XOut with
+// XOut having a Success field, like every service of the app.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+type method struct {
+	Name string
+	In   string
+	Out  string
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by instrumentgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var _ ServiceInterface = (*InstrumentedService)(nil)
+
+// InstrumentedService records every call of the service in a span named {{.Service}}.<method>, failed when
+// Success is false, and in payroll_service_calls_total and payroll_service_call_duration_seconds with service="{{.Service}}"
+type InstrumentedService struct {
+	next ServiceInterface
+}
+
+func Instrument(next ServiceInterface) *InstrumentedService {
+	return &InstrumentedService{next: next}
+}
+{{range .Methods}}
+func (s *InstrumentedService) {{.Name}}(ctx context.Context, in *{{.In}}) *{{.Out}} {
+	ctx, span := tracing.Start(ctx, "{{$.Service}}.{{.Name}}")
+	start := time.Now()
+	out := s.next.{{.Name}}(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("{{$.Service}}", "{{.Name}}", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}
+{{end}}`))
+
+func main() {
+	input := flag.String("in", "service_interface.go", "file declaring ServiceInterface")
+	output := flag.String("out", "service_instrument.go", "generated file")
+	service := flag.String("service", "", "service label, default is the app directory name")
+	flag.Parse()
+
+	if err := run(*input, *output, *service); err != nil {
+		fmt.Fprintln(os.Stderr, "instrumentgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, output string, service string) error {
+	if service == "" {
+		dir, err := filepath.Abs(filepath.Dir(input))
+		if err != nil {
+			return err
+		}
+		service = filepath.Base(filepath.Dir(dir)) // app/<service>/lib
+	}
+
+	src, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	code, err := generate(src, service)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(filepath.Dir(input), output), code, 0o644)
+}
+
+// generate returns the formatted decorator of the ServiceInterface declared in src
+func generate(src []byte, service string) ([]byte, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var iface *ast.InterfaceType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == "ServiceInterface" {
+			iface, _ = spec.Type.(*ast.InterfaceType)
+		}
+		return iface == nil
+	})
+	if iface == nil {
+		return nil, fmt.Errorf("ServiceInterface not found")
+	}
+
+	methods := make([]method, 0, len(iface.Methods.List))
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) != 1 {
+			return nil, fmt.Errorf("ServiceInterface may only declare methods")
+		}
+		name := field.Names[0].Name
+		in, out, ok := signature(fn)
+		if !ok {
+			return nil, fmt.Errorf("%s must be %s(ctx context.Context, in *XIn) *XOut", name, name)
+		}
+		methods = append(methods, method{Name: name, In: in, Out: out})
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]any{"Package": file.Name.Name, "Service": service, "Methods": methods})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// signature returns the input and output type names of func(ctx context.Context, in *XIn) *XOut
+func signature(fn *ast.FuncType) (string, string, bool) {
+	if fn.Params == nil || len(fn.Params.List) != 2 || fn.Results == nil || len(fn.Results.List) != 1 {
+		return "", "", false
+	}
+	ctx, ok := fn.Params.List[0].Type.(*ast.SelectorExpr)
+	if !ok || ctx.Sel.Name != "Context" {
+		return "", "", false
+	}
+	in, ok := pointerTo(fn.Params.List[1].Type)
+	if !ok {
+		return "", "", false
+	}
+	out, ok := pointerTo(fn.Results.List[0].Type)
+	if !ok {
+		return "", "", false
+	}
+	return in, out, true
+}
+
+func pointerTo(expr ast.Expr) (string, bool) {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := star.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	return ident.Name, true
+}
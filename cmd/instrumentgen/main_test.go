@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	src := []byte(`package lib
+
+import "context"
+
+type ServiceInterface interface {
+	// ListRuns searches the run history
+	ListRuns(ctx context.Context, in *ListRunsIn) *ListRunsOut
+}
+`)
+
+	code, err := generate(src, "job")
+	require.NoError(t, err)
+	assert.Contains(t, string(code), "// Code generated by instrumentgen. DO NOT EDIT.")
+	assert.Contains(t, string(code), `func (s *InstrumentedService) ListRuns(ctx context.Context, in *ListRunsIn) *ListRunsOut {
+	ctx, span := tracing.Start(ctx, "job.ListRuns")
+	start := time.Now()
+	out := s.next.ListRuns(ctx, in)
+	success := out != nil && out.Success
+	metrics.ObserveServiceCall("job", "ListRuns", start, success)
+	if !success && out != nil {
+		span.SetStatus(codes.Error, out.Message)
+	}
+	span.End()
+	return out
+}`)
+}
+
+func TestGenerateRejectsOtherSignature(t *testing.T) {
+	scenarios := map[string]string{
+		"no interface": `package lib
+type StorageInterface interface{}`,
+		"no context": `package lib
+type ServiceInterface interface {
+	ListRuns(in *ListRunsIn) *ListRunsOut
+}`,
+		"value output": `package lib
+import "context"
+type ServiceInterface interface {
+	ListRuns(ctx context.Context, in *ListRunsIn) ListRunsOut
+}`,
+		"embedded interface": `package lib
+type ServiceInterface interface {
+	Other
+}`,
+	}
+
+	for name, src := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			_, err := generate([]byte(src), "job")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"time"
+
+	"github.com/ariesmaulana/payroll/app/audit"
+	"github.com/ariesmaulana/payroll/app/loan"
+	"github.com/ariesmaulana/payroll/app/timeclock"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userApp "github.com/ariesmaulana/payroll/app/user"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/config"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+	"github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/notifier"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// app holds the services the commands call, wired the same way as the http server
+type app struct {
+	pool      *pgxpool.Pool
+	user      userLib.ServiceInterface
+	timeclock timeclockLib.ServiceInterface
+}
+
+func newApp() (*app, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	// the log goes to the file only, stdout is the output of the command
+	if err := logger.Init(logger.LogConfig{
+		FilePath:   cfg.LogDir,
+		MaxSize:    int64(cfg.LogMaxSizeMB),
+		MaxAge:     cfg.LogMaxAgeDays,
+		MaxBackups: cfg.LogMaxBackups,
+		Compress:   cfg.LogCompress,
+	}); err != nil {
+		return nil, fmt.Errorf("init logger: %w", err)
+	}
+
+	if err := common.SetDefaultPasswordHasher(cfg.PasswordHasher); err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_HASHER: %w", err)
+	}
+
+	passwordPolicy := userApp.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		ResetTokenTTL: 30 * time.Minute,
+		ResetURL:      cfg.PasswordResetURL,
+	}
+	if cfg.PasswordBreachList != "" {
+		passwordPolicy.Breached, err = userApp.LoadBreachedPasswords(cfg.PasswordBreachList)
+		if err != nil {
+			return nil, fmt.Errorf("load password breach list: %w", err)
+		}
+	}
+
+	pool, err := database.NewPostgresPool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect database: %w", err)
+	}
+
+	auditService := audit.NewService(audit.NewStorage(pool))
+	userService := userApp.NewService(userApp.NewStorage(pool), userApp.MFAPolicy{}, passwordPolicy,
+		notifier.LogNotifier{}, nil, auditService)
+	loanService := loan.NewService(loan.NewStorage(pool), loan.Policy{MinTakeHome: cfg.LoanMinTakeHome})
+	timeClockService := timeclock.NewService(timeclock.NewStorage(pool), userService, loanService, auditService)
+
+	return &app{
+		pool:      pool,
+		user:      userService,
+		timeclock: timeClockService,
+	}, nil
+}
+
+func (a *app) close() {
+	a.pool.Close()
+}
+
+// cliActor is who the audit trail and created_by record for the command, eg: cli:budi
+func cliActor() string {
+	name := "unknown"
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		name = current.Username
+	}
+	actor := "cli:" + name
+	// created_by is VARCHAR(50)
+	if len(actor) > 50 {
+		actor = actor[:50]
+	}
+	return actor
+}
+
+// adminContext runs the services as admin, whoever can run payrollctl already has the database credentials
+func adminContext(ctx context.Context) context.Context {
+	return contextutil.WithUser(ctx, &contextutil.AuthUser{
+		Username: cliActor(),
+		Role:     data.RAdmin,
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"strconv"
+
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+)
+
+type attendanceImportResult struct {
+	Imported int                `json:"imported"`
+	Failed   []attendanceFailed `json:"failed"`
+}
+
+type attendanceFailed struct {
+	attendanceRow
+	Reason string `json:"reason"`
+}
+
+// attendanceImport adds the attendance of every line, a rejected line (weekend, payroll already run)
+// does not stop the others but the command exits with failure so cron reports it
+func attendanceImport(e *env, args []string) error {
+	fs := newFlagSet("attendance import")
+	path := fs.String("file", "", `csv file of "user_id,date" lines, - for stdin`)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return usageError("--file is required")
+	}
+
+	input := e.stdin
+	if *path != "-" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return usageError("%v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	rows, err := readAttendanceCSV(input)
+	if err != nil {
+		return usageError("%s: %v", *path, err)
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+
+	result := attendanceImportResult{Failed: []attendanceFailed{}}
+	for _, row := range rows {
+		out := a.timeclock.AddAttendancePeriod(e.ctx, &timeclockLib.AddAttendancePeriodIn{
+			Trace:       e.trace,
+			UserID:      row.UserId,
+			CheckInDate: row.Date,
+		})
+		if !out.Success {
+			reason := out.Message
+			if reason == "" {
+				reason = "internal error"
+			}
+			result.Failed = append(result.Failed, attendanceFailed{attendanceRow: row, Reason: reason})
+			continue
+		}
+		result.Imported++
+	}
+
+	t := table{header: []string{"LINE", "USER_ID", "DATE", "REASON"}}
+	for _, f := range result.Failed {
+		t.rows = append(t.rows, []string{strconv.Itoa(f.Line), strconv.Itoa(f.UserId), f.Date.Format("2006-01-02"), f.Reason})
+	}
+	t.rows = append(t.rows, []string{"", "", "imported", strconv.Itoa(result.Imported)})
+	if err := e.out.print(result, t); err != nil {
+		return err
+	}
+
+	if len(result.Failed) > 0 {
+		return failedError("%d of %d lines rejected", len(result.Failed), len(rows))
+	}
+	return nil
+}
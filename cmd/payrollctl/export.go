@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/data"
+)
+
+type exportResult struct {
+	File    string `json:"file"`
+	Rows    int    `json:"rows"`
+	Total   int    `json:"total"`
+	Skipped []int  `json:"skipped"` // bank file: employee with nothing to transfer
+}
+
+func (r exportResult) table() table {
+	t := fields("file", r.File, "rows", strconv.Itoa(r.Rows), "total", strconv.Itoa(r.Total))
+	for _, userId := range r.Skipped {
+		t.rows = append(t.rows, []string{"skipped", strconv.Itoa(userId) + ", net pay is not positive"})
+	}
+	return t
+}
+
+// writeExport writes the csv to the file, or to stdout when the file is "-".
+// The summary is only printed when stdout is not the csv itself.
+func writeExport(e *env, path string, result exportResult, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(e.out.w)
+	}
+
+	// salary and account numbers, only the owner may read it
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return failedError("%v", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return failedError("write %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return failedError("write %s: %v", path, err)
+	}
+
+	result.File = path
+	return e.out.print(result, result.table())
+}
+
+// payslipTotals sums the runs of a month, net is what the employee received
+type payslipTotals struct {
+	tax, loanDeduction, net int
+}
+
+func sumPayslipRuns(runs []*data.PayslipRun) payslipTotals {
+	var totals payslipTotals
+	for _, run := range runs {
+		totals.tax += run.TaxAmount
+		totals.loanDeduction += run.LoanDeduction
+		totals.net += run.NetSalary
+	}
+	return totals
+}
+
+func writePayslipCSV(w io.Writer, payslips []*data.UserPayslip) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"user_id", "attendance_count", "overtime_hours", "reimbursement", "gross", "tax", "loan_deduction", "net", "loan_outstanding"})
+	for _, p := range payslips {
+		totals := sumPayslipRuns(p.Runs)
+		writer.Write([]string{
+			strconv.Itoa(p.UserID), strconv.Itoa(p.AttendanceCount), strconv.Itoa(p.OvertimeHours), strconv.Itoa(p.ReimbursementSum),
+			strconv.Itoa(p.TotalSalary), strconv.Itoa(totals.tax), strconv.Itoa(totals.loanDeduction), strconv.Itoa(totals.net),
+			strconv.Itoa(p.LoanOutstanding),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportPayslips(e *env, args []string) error {
+	fs := newFlagSet("export payslips")
+	month := fs.Int("month", 0, "month, 1-12")
+	year := fs.Int("year", 0, "year")
+	path := fs.String("file", "-", "csv file to write, - for stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *month < 1 || *month > 12 || *year <= 0 {
+		return usageError("--month and --year are required")
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.timeclock.GenerateAllPaySlips(e.ctx, &timeclockLib.GenerateAllPaySlipsIn{
+		Trace: e.trace,
+		Month: *month,
+		Year:  *year,
+	})
+	if !out.Success {
+		return failedError("%s", out.Message)
+	}
+
+	result := exportResult{Rows: len(out.ListUserPayslips), Total: out.TotalSalaryAll, Skipped: []int{}}
+	return writeExport(e, *path, result, func(w io.Writer) error {
+		return writePayslipCSV(w, out.ListUserPayslips)
+	})
+}
+
+// bankTransfer is one line of the bank transfer file
+type bankTransfer struct {
+	account *data.UserBankAccount
+	amount  int
+}
+
+// bankTransfers pays the net of every item to the account of the employee. Nothing is transferred
+// for a net that is not positive (eg: a negative correction), those are returned as skipped.
+// An employee to pay without account makes the whole file invalid, they are returned as missing.
+func bankTransfers(items []*data.PayrollItem, accounts map[int]*data.UserBankAccount) (transfers []bankTransfer, skipped []int, missing []int) {
+	skipped = []int{}
+	for _, item := range items {
+		net := newPayrollItemRow(item).Net
+		if net <= 0 {
+			skipped = append(skipped, item.UserId)
+			continue
+		}
+		account, ok := accounts[item.UserId]
+		if !ok {
+			missing = append(missing, item.UserId)
+			continue
+		}
+		transfers = append(transfers, bankTransfer{account: account, amount: net})
+	}
+	return transfers, skipped, missing
+}
+
+// writeBankCSV writes the bulk transfer file, remark is shown on the statement of the employee
+func writeBankCSV(w io.Writer, transfers []bankTransfer, remark string) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"bank_code", "account_number", "account_name", "amount", "remark"})
+	for _, t := range transfers {
+		writer.Write([]string{t.account.BankCode, t.account.AccountNumber, t.account.AccountName, strconv.Itoa(t.amount), remark})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportBankFile(e *env, args []string) error {
+	fs := newFlagSet("export bank-file")
+	payrollId := fs.Int("payroll-id", 0, "payroll to transfer")
+	path := fs.String("file", "-", "csv file to write, - for stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *payrollId <= 0 {
+		return usageError("--payroll-id is required")
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	detail := a.timeclock.GetPayrollDetail(e.ctx, &timeclockLib.GetPayrollDetailIn{
+		Trace:     e.trace,
+		PayrollId: *payrollId,
+	})
+	if !detail.Success {
+		return failedError("%s", detail.Message)
+	}
+
+	accounts := a.user.UserBankAccounts(e.ctx, &userLib.UserBankAccountsIn{Trace: e.trace})
+	if !accounts.Success {
+		return failedError("failed get bank accounts, trace %s", e.trace.TraceID)
+	}
+
+	transfers, skipped, missing := bankTransfers(detail.Items, accounts.Result)
+	if len(missing) > 0 {
+		return failedError("no bank account for user %v, set it with `payrollctl user set-bank`", missing)
+	}
+
+	result := exportResult{Rows: len(transfers), Skipped: skipped}
+	for _, t := range transfers {
+		result.Total += t.amount
+	}
+	remark := fmt.Sprintf("PAYROLL %d %s", detail.Payroll.Id, detail.Payroll.PeriodEnd.Format("2006-01"))
+	return writeExport(e, *path, result, func(w io.Writer) error {
+		return writeBankCSV(w, transfers, remark)
+	})
+}
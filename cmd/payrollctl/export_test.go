@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBankTransfers(t *testing.T) {
+	t.Parallel()
+
+	items := []*data.PayrollItem{
+		{UserId: 1, TotalSalary: 5000000, TaxAmount: 100000, LoanDeduction: 400000},
+		{UserId: 2, BaseSalaryAmount: -50000, TotalSalary: -50000},
+		{UserId: 3, TotalSalary: 3000000},
+	}
+	accounts := map[int]*data.UserBankAccount{
+		1: {UserId: 1, BankCode: "014", AccountNumber: "1234567890", AccountName: "BUDI SANTOSO"},
+	}
+
+	transfers, skipped, missing := bankTransfers(items, accounts)
+	assert.Len(t, transfers, 1)
+	assert.Equal(t, 4500000, transfers[0].amount)
+	assert.Equal(t, []int{2}, skipped)
+	assert.Equal(t, []int{3}, missing)
+
+	var out strings.Builder
+	assert.NoError(t, writeBankCSV(&out, transfers, "PAYROLL 7 2025-01"))
+	assert.Equal(t, "bank_code,account_number,account_name,amount,remark\n"+
+		"014,1234567890,BUDI SANTOSO,4500000,PAYROLL 7 2025-01\n", out.String())
+}
+
+func TestWritePayslipCSV(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	err := writePayslipCSV(&out, []*data.UserPayslip{{
+		UserID: 1, TotalSalary: 5500000, AttendanceCount: 20, LoanOutstanding: 800000,
+		Runs: []*data.PayslipRun{
+			{TotalSalary: 5000000, TaxAmount: 100000, LoanDeduction: 400000, NetSalary: 4500000},
+			{TotalSalary: 500000, TaxAmount: 25000, NetSalary: 475000},
+		},
+	}})
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "1,20,0,0,5500000,125000,400000,4975000,800000", lines[1])
+}
+
+func TestOutputTable(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	o, err := newOutput(formatTable, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, o.print(nil, table{header: []string{"ID", "NAME"}, rows: [][]string{{"1", "budi"}, {"12", "gita"}}}))
+	assert.Equal(t, "ID  NAME\n1   budi\n12  gita\n", out.String())
+
+	out.Reset()
+	o, _ = newOutput(formatJSON, &out)
+	assert.NoError(t, o.print(userResult{UserId: 1}, table{}))
+	assert.JSONEq(t, `{"user_id": 1}`, out.String())
+}
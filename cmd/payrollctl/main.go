@@ -0,0 +1,203 @@
+// payrollctl runs the operational tasks of payroll from the shell: the first admin, password reset,
+// payroll run from cron, bank transfer file. It calls the same services as the http api as an admin
+// named cli:<os user>, so every change is validated the same way and lands in the audit trail.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/google/uuid"
+)
+
+const usage = `usage: payrollctl [-o table|json] <command> [flags]
+
+commands:
+  user create        --username --fullname --email --base-salary --join-date [--admin] [--password-stdin]
+  user set-password  --id [--password-stdin]
+  user set-bank      --id --bank-code --account-number --account-name
+  payroll preview    --start --end [--type] [--users] [--amounts] [--note]
+  payroll run        same flags as preview, the stored payroll is final
+  payroll show       --id
+  attendance import  --file
+  export payslips    --month --year [--file]
+  export bank-file   --payroll-id [--file]
+  migrate            up | down [steps] | status
+
+Without --password-stdin a temporary password is generated and printed.
+Run "payrollctl <command> -h" for the flags of a command.
+
+exit status: 0 success, 1 rejected or partly failed, 2 invalid usage, 3 config or database unavailable`
+
+// exit status, cron and scripts only look at these
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+	exitSetup  = 3
+)
+
+// exitError carries the exit status of a failed command
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+func usageError(format string, args ...any) error {
+	return &exitError{code: exitUsage, msg: fmt.Sprintf(format, args...)}
+}
+
+func failedError(format string, args ...any) error {
+	return &exitError{code: exitFailed, msg: fmt.Sprintf(format, args...)}
+}
+
+// env is what a command runs with, the database is only connected once the flags are valid
+type env struct {
+	ctx   context.Context
+	trace *contextutil.Trace
+	out   *output
+	stdin io.Reader
+
+	app *app
+}
+
+// services connects to the database on first use
+func (e *env) services() (*app, error) {
+	if e.app != nil {
+		return e.app, nil
+	}
+	a, err := newApp()
+	if err != nil {
+		return nil, &exitError{code: exitSetup, msg: err.Error()}
+	}
+	e.app = a
+	return a, nil
+}
+
+// command is one "<group> <name>" of payrollctl, run parses its own flags
+type command struct {
+	name string
+	run  func(e *env, args []string) error
+}
+
+var commands = []command{
+	{name: "user create", run: userCreate},
+	{name: "user set-password", run: userSetPassword},
+	{name: "user set-bank", run: userSetBank},
+	{name: "payroll preview", run: payrollPreview},
+	{name: "payroll run", run: payrollRun},
+	{name: "payroll show", run: payrollShow},
+	{name: "attendance import", run: attendanceImport},
+	{name: "export payslips", run: exportPayslips},
+	{name: "export bank-file", run: exportBankFile},
+	{name: "migrate", run: migrate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	global := flag.NewFlagSet("payrollctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprintln(stderr, usage) }
+	format := global.String("o", formatTable, "output format: table or json")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	out, err := newOutput(*format, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	cmd, rest, ok := findCommand(global.Args())
+	if !ok {
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+
+	trace := &contextutil.Trace{
+		TraceID: uuid.NewString(),
+		Method:  "CLI",
+		Path:    "payrollctl " + cmd.name,
+	}
+	e := &env{
+		ctx:   adminContext(contextutil.WithTrace(context.Background(), trace)),
+		trace: trace,
+		out:   out,
+		stdin: stdin,
+	}
+	defer func() {
+		if e.app != nil {
+			e.app.close()
+		}
+	}()
+
+	err = cmd.run(e, rest)
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) {
+		exitErr = &exitError{code: exitFailed, msg: err.Error()}
+	}
+	if exitErr.msg != "" {
+		fmt.Fprintf(stderr, "payrollctl %s: %s\n", cmd.name, exitErr.msg)
+	}
+	return exitErr.code
+}
+
+// findCommand matches the command name at the start of args, eg: "user create --username x"
+func findCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// newFlagSet returns the flag set of a command, a parse error is reported as usage error
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("payrollctl "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseFlags parses args and rejects positional arguments, they are usually a typo of a flag
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// the flag package already printed the error and the flags
+		return &exitError{code: exitUsage}
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
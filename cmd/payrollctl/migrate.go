@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/lib/migration"
+	"github.com/ariesmaulana/payroll/migrations"
+)
+
+type migrationRow struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	State     string `json:"state"` // applied, reverted, pending
+	AppliedAt string `json:"applied_at,omitempty"`
+	Unknown   bool   `json:"unknown,omitempty"` // applied but has no file in this build
+}
+
+func migrationTable(rows []migrationRow) table {
+	t := table{header: []string{"VERSION", "NAME", "STATE", "APPLIED_AT"}}
+	for _, row := range rows {
+		state := row.State
+		if row.Unknown {
+			state += " (no file in this build)"
+		}
+		t.rows = append(t.rows, []string{strconv.FormatInt(row.Version, 10), row.Name, state, row.AppliedAt})
+	}
+	return t
+}
+
+func migratedRows(done []migration.Migration, state string) []migrationRow {
+	rows := []migrationRow{}
+	for _, m := range done {
+		rows = append(rows, migrationRow{Version: m.Version, Name: m.Name, State: state})
+	}
+	return rows
+}
+
+// migrate is `payroll migrate` of the server binary with table / json output
+func migrate(e *env, args []string) error {
+	if len(args) == 0 {
+		return usageError("use up, down [steps] or status")
+	}
+
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+		if len(args) > 1 {
+			return usageError("unexpected argument %q", args[1])
+		}
+	case "down":
+		if len(args) > 2 {
+			return usageError("unexpected argument %q", args[2])
+		}
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return usageError("steps must be a positive number")
+			}
+		}
+	default:
+		return usageError("use up, down [steps] or status")
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	migrator, err := migration.New(a.pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	var rows []migrationRow
+	switch args[0] {
+	case "up":
+		var applied []migration.Migration
+		applied, err = migrator.Up(e.ctx)
+		rows = migratedRows(applied, "applied")
+	case "down":
+		var reverted []migration.Migration
+		reverted, err = migrator.Down(e.ctx, steps)
+		rows = migratedRows(reverted, "reverted")
+	case "status":
+		var statuses []migration.Status
+		statuses, err = migrator.Status(e.ctx)
+		rows = []migrationRow{}
+		for _, s := range statuses {
+			row := migrationRow{Version: s.Version, Name: s.Name, State: "pending", Unknown: s.Unknown}
+			if s.AppliedAt != nil {
+				row.State = "applied"
+				row.AppliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	// what was done before the failure is still printed, the failed migration was rolled back
+	if printErr := e.out.print(rows, migrationTable(rows)); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
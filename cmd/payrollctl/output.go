@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// output prints the result of a command as an aligned table for people or as JSON for scripts
+type output struct {
+	format string
+	w      io.Writer
+}
+
+func newOutput(format string, w io.Writer) (*output, error) {
+	if format != formatTable && format != formatJSON {
+		return nil, fmt.Errorf("unknown output format %q, use table or json", format)
+	}
+	return &output{format: format, w: w}, nil
+}
+
+// table is the table view of a result
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// print writes v as JSON, or t as table
+func (o *output) print(v any, t table) error {
+	if o.format == formatJSON {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	if len(t.header) > 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// fields is the table of a single record, one "name value" row per field
+func fields(pairs ...string) table {
+	t := table{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		t.rows = append(t.rows, []string{pairs[i], pairs[i+1]})
+	}
+	return t
+}
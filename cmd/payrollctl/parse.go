@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDate parses YYYY-MM-DD the same way the http api does
+func parseDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+// parseIds parses a comma separated list of user ids, eg: 1,2,3
+func parseIds(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid user id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseAmounts parses the amount per user of a bonus or correction run, eg: 1=500000,2=-25000
+func parseAmounts(value string) (map[int]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	amounts := make(map[int]int)
+	for _, part := range strings.Split(value, ",") {
+		userPart, amountPart, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q, use <user id>=<amount>", part)
+		}
+		userId, err := strconv.Atoi(userPart)
+		if err != nil || userId <= 0 {
+			return nil, fmt.Errorf("invalid user id %q", userPart)
+		}
+		amount, err := strconv.Atoi(amountPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q", amountPart)
+		}
+		if _, ok := amounts[userId]; ok {
+			return nil, fmt.Errorf("user %d appears twice", userId)
+		}
+		amounts[userId] = amount
+	}
+	return amounts, nil
+}
+
+// attendanceRow is one line of the attendance import file
+type attendanceRow struct {
+	Line   int       `json:"line"`
+	UserId int       `json:"user_id"`
+	Date   time.Time `json:"date"`
+}
+
+// readAttendanceCSV reads "user_id,date" lines, eg: exported from the fingerprint machine.
+// A header line is skipped, any invalid line rejects the whole file so nothing is half imported.
+func readAttendanceCSV(r io.Reader) ([]attendanceRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var rows []attendanceRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && record[0] == "user_id" {
+			continue
+		}
+
+		userId, err := strconv.Atoi(record[0])
+		if err != nil || userId <= 0 {
+			return nil, fmt.Errorf("line %d: invalid user id %q", line, record[0])
+		}
+		date, err := parseDate(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rows = append(rows, attendanceRow{Line: line, UserId: userId, Date: date})
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no attendance in the file")
+	}
+	return rows, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmounts(t *testing.T) {
+	t.Parallel()
+
+	amounts, err := parseAmounts("1=500000, 2=-25000")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 500000, 2: -25000}, amounts)
+
+	amounts, err = parseAmounts("")
+	assert.NoError(t, err)
+	assert.Nil(t, amounts)
+
+	for _, value := range []string{"1:500", "x=500", "1=abc", "1=5,1=6", "0=5"} {
+		_, err := parseAmounts(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseIds(t *testing.T) {
+	t.Parallel()
+
+	ids, err := parseIds("3,1, 2")
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2}, ids)
+
+	_, err = parseIds("1,,2")
+	assert.Error(t, err)
+}
+
+func TestReadAttendanceCSV(t *testing.T) {
+	t.Parallel()
+
+	rows, err := readAttendanceCSV(strings.NewReader("user_id,date\n2,2025-01-06\n3, 2025-01-07\n"))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, 2, rows[0].UserId)
+	assert.Equal(t, "2025-01-06", rows[0].Date.Format("2006-01-02"))
+	assert.Equal(t, 3, rows[1].UserId)
+
+	// one bad line rejects the file
+	_, err = readAttendanceCSV(strings.NewReader("2,2025-01-06\n3,07/01/2025\n"))
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = readAttendanceCSV(strings.NewReader("2,2025-01-06,extra\n"))
+	assert.Error(t, err)
+
+	_, err = readAttendanceCSV(strings.NewReader("user_id,date\n"))
+	assert.Error(t, err)
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, exitUsage, run(nil, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"payroll", "close"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"-o", "xml", "migrate", "status"}, strings.NewReader(""), &stdout, &stderr))
+
+	// the flags are checked before connecting to the database
+	stderr.Reset()
+	assert.Equal(t, exitUsage, run([]string{"payroll", "run", "--start", "2025-01-01"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "--start and --end are required")
+	assert.Empty(t, stdout.String())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
+	"github.com/ariesmaulana/payroll/data"
+)
+
+// payrollItemRow is what one employee gets from a payroll, net is what is transferred
+type payrollItemRow struct {
+	UserId          int `json:"user_id"`
+	AttendanceCount int `json:"attendance_count"`
+	OvertimeHours   int `json:"overtime_hours"`
+	BaseSalary      int `json:"base_salary"`
+	Overtime        int `json:"overtime"`
+	Bonus           int `json:"bonus"`
+	Reimbursement   int `json:"reimbursement"`
+	Tax             int `json:"tax"`
+	Bpjs            int `json:"bpjs"`
+	LoanDeduction   int `json:"loan_deduction"`
+	Gross           int `json:"gross"`
+	Net             int `json:"net"`
+}
+
+func newPayrollItemRow(item *data.PayrollItem) payrollItemRow {
+	return payrollItemRow{
+		UserId:          item.UserId,
+		AttendanceCount: item.AttendanceCount,
+		OvertimeHours:   item.OvertimeHours,
+		BaseSalary:      item.BaseSalaryAmount,
+		Overtime:        item.OvertimeAmount,
+		Bonus:           item.BonusAmount,
+		Reimbursement:   item.ReimbursementTotal,
+		Tax:             item.TaxAmount,
+		Bpjs:            item.BpjsAmount,
+		LoanDeduction:   item.LoanDeduction,
+		Gross:           item.TotalSalary,
+		Net:             item.TotalSalary - item.TaxAmount - item.BpjsAmount - item.LoanDeduction,
+	}
+}
+
+type payrollResult struct {
+	PayrollId int              `json:"payroll_id,omitempty"` // 0 on preview
+	Type      data.PayrollType `json:"type,omitempty"`
+	Preview   bool             `json:"preview"`
+	Skipped   []int            `json:"skipped"`
+	Items     []payrollItemRow `json:"items"`
+	TotalNet  int              `json:"total_net"`
+}
+
+func newPayrollResult(payrollId int, items []*data.PayrollItem) payrollResult {
+	result := payrollResult{PayrollId: payrollId, Skipped: []int{}, Items: []payrollItemRow{}}
+	for _, item := range items {
+		row := newPayrollItemRow(item)
+		result.Items = append(result.Items, row)
+		result.TotalNet += row.Net
+	}
+	return result
+}
+
+func (r payrollResult) table() table {
+	t := table{header: []string{"USER_ID", "DAYS", "OT_HOURS", "BASE", "OVERTIME", "BONUS", "REIMBURSE", "TAX", "LOAN", "GROSS", "NET"}}
+	for _, row := range r.Items {
+		t.rows = append(t.rows, []string{
+			strconv.Itoa(row.UserId), strconv.Itoa(row.AttendanceCount), strconv.Itoa(row.OvertimeHours),
+			strconv.Itoa(row.BaseSalary), strconv.Itoa(row.Overtime), strconv.Itoa(row.Bonus), strconv.Itoa(row.Reimbursement),
+			strconv.Itoa(row.Tax), strconv.Itoa(row.LoanDeduction), strconv.Itoa(row.Gross), strconv.Itoa(row.Net),
+		})
+	}
+
+	summary := "payroll " + strconv.Itoa(r.PayrollId)
+	if r.Preview {
+		summary = "preview, not stored"
+	}
+	t.rows = append(t.rows, []string{summary, "", "", "", "", "", "", "", "", "total", strconv.Itoa(r.TotalNet)})
+	for _, userId := range r.Skipped {
+		t.rows = append(t.rows, []string{"skipped " + strconv.Itoa(userId), "already paid in the period"})
+	}
+	return t
+}
+
+// payrollRunFlags are shared by preview and run, so a previewed payroll is run with the same command line
+type payrollRunFlags struct {
+	start, end, payrollType, users, amounts, note *string
+}
+
+func definePayrollRunFlags(fs *flag.FlagSet) payrollRunFlags {
+	return payrollRunFlags{
+		start:       fs.String("start", "", "first day of the period, YYYY-MM-DD"),
+		end:         fs.String("end", "", "last day of the period, YYYY-MM-DD"),
+		payrollType: fs.String("type", string(data.PayrollRegular), "regular, bonus, correction or final_settlement"),
+		users:       fs.String("users", "", "comma separated user ids, empty means every employee"),
+		amounts:     fs.String("amounts", "", "bonus or correction per user, eg: 1=500000,2=-25000"),
+		note:        fs.String("note", "", "reason of an off-cycle run"),
+	}
+}
+
+func (f payrollRunFlags) input(e *env) (*timeclockLib.RunPayrollIn, error) {
+	if *f.start == "" || *f.end == "" {
+		return nil, usageError("--start and --end are required")
+	}
+	start, err := parseDate(*f.start)
+	if err != nil {
+		return nil, usageError("--start: %v", err)
+	}
+	end, err := parseDate(*f.end)
+	if err != nil {
+		return nil, usageError("--end: %v", err)
+	}
+	userIds, err := parseIds(*f.users)
+	if err != nil {
+		return nil, usageError("--users: %v", err)
+	}
+	amounts, err := parseAmounts(*f.amounts)
+	if err != nil {
+		return nil, usageError("--amounts: %v", err)
+	}
+
+	return &timeclockLib.RunPayrollIn{
+		Trace:       e.trace,
+		Type:        data.PayrollType(*f.payrollType),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		UserIds:     userIds,
+		Amounts:     amounts,
+		Note:        *f.note,
+	}, nil
+}
+
+func payrollPreview(e *env, args []string) error {
+	return runPayroll(e, "payroll preview", args, true)
+}
+
+func payrollRun(e *env, args []string) error {
+	return runPayroll(e, "payroll run", args, false)
+}
+
+func runPayroll(e *env, name string, args []string, preview bool) error {
+	fs := newFlagSet(name)
+	f := definePayrollRunFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	in, err := f.input(e)
+	if err != nil {
+		return err
+	}
+	in.DryRun = preview
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.timeclock.RunPayroll(e.ctx, in)
+	if !out.Success {
+		if out.PayrollId != 0 {
+			// stored but the loan deductions were not recorded, see the log of the trace
+			return failedError("payroll %d stored with error: %s, trace %s", out.PayrollId, out.Message, e.trace.TraceID)
+		}
+		return failedError("%s", out.Message)
+	}
+
+	result := newPayrollResult(out.PayrollId, out.Items)
+	result.Type = in.Type
+	result.Preview = preview
+	if len(out.Skipped) > 0 {
+		result.Skipped = out.Skipped
+	}
+	return e.out.print(result, result.table())
+}
+
+func payrollShow(e *env, args []string) error {
+	fs := newFlagSet("payroll show")
+	payrollId := fs.Int("id", 0, "payroll id")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *payrollId <= 0 {
+		return usageError("--id is required")
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.timeclock.GetPayrollDetail(e.ctx, &timeclockLib.GetPayrollDetailIn{
+		Trace:     e.trace,
+		PayrollId: *payrollId,
+	})
+	if !out.Success {
+		return failedError("%s", out.Message)
+	}
+
+	result := newPayrollResult(out.Payroll.Id, out.Items)
+	result.Type = out.Payroll.Type
+	return e.out.print(result, result.table())
+}
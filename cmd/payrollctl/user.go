@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/data"
+)
+
+type userResult struct {
+	UserId            int    `json:"user_id"`
+	Username          string `json:"username,omitempty"`
+	Role              string `json:"role,omitempty"`
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+}
+
+func (r userResult) table() table {
+	t := fields("user_id", strconv.Itoa(r.UserId))
+	if r.Username != "" {
+		t.rows = append(t.rows, []string{"username", r.Username}, []string{"role", r.Role})
+	}
+	if r.TemporaryPassword != "" {
+		t.rows = append(t.rows, []string{"temporary_password", r.TemporaryPassword})
+	}
+	return t
+}
+
+// readPassword reads the first line of stdin, so the password is not in the shell history or process list
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", usageError("empty password on stdin")
+	}
+	return password, nil
+}
+
+func userCreate(e *env, args []string) error {
+	fs := newFlagSet("user create")
+	username := fs.String("username", "", "login name, 5-20 letters, digits or underscore")
+	fullname := fs.String("fullname", "", "full name")
+	email := fs.String("email", "", "email address")
+	baseSalary := fs.Int("base-salary", 0, "monthly base salary in rupiah")
+	joinDate := fs.String("join-date", "", "join date, YYYY-MM-DD")
+	admin := fs.Bool("admin", false, "create an admin instead of an employee")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *username == "" || *fullname == "" || *email == "" || *joinDate == "" || *baseSalary == 0 {
+		return usageError("--username, --fullname, --email, --base-salary and --join-date are required")
+	}
+	joined, err := parseDate(*joinDate)
+	if err != nil {
+		return usageError("--join-date: %v", err)
+	}
+
+	var password string
+	if *passwordStdin {
+		if password, err = readPassword(e.stdin); err != nil {
+			return err
+		}
+	}
+
+	role := data.REmployee
+	if *admin {
+		role = data.RAdmin
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.user.CreateUser(e.ctx, &userLib.CreateUserIn{
+		Trace:      e.trace,
+		Fullname:   *fullname,
+		Username:   *username,
+		Email:      *email,
+		Password:   password,
+		Role:       role,
+		BaseSalary: *baseSalary,
+		JoinDate:   joined,
+	})
+	if !out.Success {
+		return failedError("%s", out.Message)
+	}
+
+	result := userResult{
+		UserId:            out.UserId,
+		Username:          *username,
+		Role:              string(role),
+		TemporaryPassword: out.TemporaryPassword,
+	}
+	return e.out.print(result, result.table())
+}
+
+func userSetPassword(e *env, args []string) error {
+	fs := newFlagSet("user set-password")
+	userId := fs.Int("id", 0, "user id")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *userId <= 0 {
+		return usageError("--id is required")
+	}
+
+	var password string
+	if *passwordStdin {
+		var err error
+		if password, err = readPassword(e.stdin); err != nil {
+			return err
+		}
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.user.SetPassword(e.ctx, &userLib.SetPasswordIn{
+		Trace:    e.trace,
+		UserId:   *userId,
+		Password: password,
+	})
+	if !out.Success {
+		return failedError("%s", out.Message)
+	}
+
+	result := userResult{UserId: *userId, TemporaryPassword: out.TemporaryPassword}
+	return e.out.print(result, result.table())
+}
+
+func userSetBank(e *env, args []string) error {
+	fs := newFlagSet("user set-bank")
+	userId := fs.Int("id", 0, "user id")
+	bankCode := fs.String("bank-code", "", "3 digit bank code, eg: 014 for BCA")
+	accountNumber := fs.String("account-number", "", "account number without separator")
+	accountName := fs.String("account-name", "", "name of the account holder as registered at the bank")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *userId <= 0 || *bankCode == "" || *accountNumber == "" || *accountName == "" {
+		return usageError("--id, --bank-code, --account-number and --account-name are required")
+	}
+
+	a, err := e.services()
+	if err != nil {
+		return err
+	}
+	out := a.user.SetBankAccount(e.ctx, &userLib.SetBankAccountIn{
+		Trace:         e.trace,
+		UserId:        *userId,
+		BankCode:      *bankCode,
+		AccountNumber: *accountNumber,
+		AccountName:   *accountName,
+	})
+	if !out.Success {
+		return failedError("%s", out.Message)
+	}
+
+	result := userResult{UserId: *userId}
+	return e.out.print(result, result.table())
+}
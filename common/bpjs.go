@@ -0,0 +1,23 @@
+package common
+
+const (
+	bpjsJHTRate = 2 // percent of the wage, Jaminan Hari Tua (PP 46/2015)
+	bpjsJPRate  = 1 // percent of the wage up to bpjsJPWageCap, Jaminan Pensiun (PP 45/2015)
+
+	// bpjsJPWageCap is the highest wage the JP contribution is counted on, set yearly by BPJS Ketenagakerjaan (2025)
+	bpjsJPWageCap = 10547400
+)
+
+// EmployeeBpjs returns the BPJS Ketenagakerjaan contribution withheld from the monthly wage of the employee:
+// 2% JHT plus 1% JP. Both are iuran pensiun / JHT, deducted from the yearly income in the 1721-A1.
+// BPJS Kesehatan is not withheld by the payroll, it is not a pension contribution.
+func EmployeeBpjs(wage int) int {
+	if wage <= 0 {
+		return 0
+	}
+	jpWage := wage
+	if jpWage > bpjsJPWageCap {
+		jpWage = bpjsJPWageCap
+	}
+	return wage*bpjsJHTRate/100 + jpWage*bpjsJPRate/100
+}
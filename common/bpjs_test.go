@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmployeeBpjs(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name string
+		wage int
+		bpjs int
+	}{
+		{name: "zero", wage: 0, bpjs: 0},
+		{name: "negative correction", wage: -500000, bpjs: 0},
+		{name: "below jp cap", wage: 5000000, bpjs: 100000 + 50000},
+		{name: "jp cap", wage: 10547400, bpjs: 210948 + 105474},
+		{name: "above jp cap", wage: 20000000, bpjs: 400000 + 105474},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, sc.bpjs, EmployeeBpjs(sc.wage))
+		})
+	}
+}
@@ -63,6 +63,20 @@ func VerifyDjangoPBKDF2Password(password, hashedPassword string) bool {
 	return hmac.Equal([]byte(newHashB64), []byte(hashB64))
 }
 
+// DjangoPBKDF2Iterations returns the iteration count of a Django PBKDF2 hash, 0 when the hash is not parseable
+func DjangoPBKDF2Iterations(hashedPassword string) int {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2_sha256" {
+		return 0
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	return iterations
+}
+
 func GenerateRandomSalt() (string, error) {
 	randomBytes := make([]byte, 16) // Django uses 16 bytes for salt
 	_, err := rand.Read(randomBytes)
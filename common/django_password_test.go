@@ -33,3 +33,14 @@ func TestFailValidateDjangoPassword(t *testing.T) {
 	assert.False(t, verify)
 
 }
+
+func TestDjangoPBKDF2Iterations(t *testing.T) {
+
+	hashedPassword, err := CreateDjangoPBKDF2Password("SecurePassword123!", "", 600000)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 600000, DjangoPBKDF2Iterations(hashedPassword))
+	assert.Equal(t, 0, DjangoPBKDF2Iterations("argon2$something"))
+	assert.Equal(t, 0, DjangoPBKDF2Iterations("pbkdf2_sha256$many$salt$hash"))
+
+}
@@ -0,0 +1,233 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher is one password hash format, the encoded hash starts with "<Algorithm>$"
+// the same way Django stores it, so hashes migrated from Django keep working.
+type PasswordHasher interface {
+	Algorithm() string
+	Encode(password string) (string, error)
+	Verify(password string, encoded string) bool
+
+	// MustUpdate is true when the hash uses weaker parameters than the hasher currently produces
+	MustUpdate(encoded string) bool
+}
+
+var (
+	passwordHashers       = map[string]PasswordHasher{}
+	defaultPasswordHasher = "argon2"
+)
+
+func init() {
+	RegisterPasswordHasher(Argon2Hasher{Memory: 64 * 1024, Time: 3, Threads: 4})
+	RegisterPasswordHasher(PBKDF2Hasher{Iterations: 600000})
+	RegisterPasswordHasher(BcryptSHA256Hasher{Cost: 12})
+}
+
+// RegisterPasswordHasher adds or replaces the hasher of its algorithm
+func RegisterPasswordHasher(hasher PasswordHasher) {
+	passwordHashers[hasher.Algorithm()] = hasher
+}
+
+// SetDefaultPasswordHasher picks the algorithm of new hashes, an older algorithm is upgraded on login
+func SetDefaultPasswordHasher(algorithm string) error {
+	if _, ok := passwordHashers[algorithm]; !ok {
+		return fmt.Errorf("unknown password hasher %q", algorithm)
+	}
+	defaultPasswordHasher = algorithm
+	return nil
+}
+
+func passwordHasherOf(encoded string) (PasswordHasher, bool) {
+	algorithm, _, found := strings.Cut(encoded, "$")
+	if !found {
+		return nil, false
+	}
+	hasher, ok := passwordHashers[algorithm]
+	return hasher, ok
+}
+
+// HashPassword encodes the password with the default hasher
+func HashPassword(password string) (string, error) {
+	return passwordHashers[defaultPasswordHasher].Encode(password)
+}
+
+// VerifyPassword checks the password with the hasher of the encoded algorithm, unknown algorithm never matches
+func VerifyPassword(password string, encoded string) bool {
+	hasher, ok := passwordHasherOf(encoded)
+	if !ok {
+		return false
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// PasswordNeedsUpgrade is true when the hash is not the default algorithm or has weaker parameters,
+// the caller rehashes it while the plain password is known (on login)
+func PasswordNeedsUpgrade(encoded string) bool {
+	hasher, ok := passwordHasherOf(encoded)
+	if !ok {
+		return true
+	}
+	return hasher.Algorithm() != defaultPasswordHasher || hasher.MustUpdate(encoded)
+}
+
+// PBKDF2Hasher is Django PBKDF2PasswordHasher: pbkdf2_sha256$<iterations>$<salt>$<base64 hash>
+type PBKDF2Hasher struct {
+	Iterations int
+}
+
+func (h PBKDF2Hasher) Algorithm() string {
+	return "pbkdf2_sha256"
+}
+
+func (h PBKDF2Hasher) Encode(password string) (string, error) {
+	return CreateDjangoPBKDF2Password(password, "", h.Iterations)
+}
+
+func (h PBKDF2Hasher) Verify(password string, encoded string) bool {
+	return VerifyDjangoPBKDF2Password(password, encoded)
+}
+
+func (h PBKDF2Hasher) MustUpdate(encoded string) bool {
+	return DjangoPBKDF2Iterations(encoded) < h.Iterations
+}
+
+// Argon2Hasher is Django Argon2PasswordHasher:
+// argon2$argon2id$v=19$m=<memory KiB>,t=<time>,p=<threads>$<base64 salt>$<base64 hash>.
+// Old Django hashes of the argon2i variant are still verified.
+type Argon2Hasher struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+func (h Argon2Hasher) Algorithm() string {
+	return "argon2"
+}
+
+func (h Argon2Hasher) Encode(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, argon2KeyLen)
+	return fmt.Sprintf("argon2$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+type argon2Params struct {
+	variant string
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2(encoded string) (*argon2Params, bool) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "argon2" {
+		return nil, false
+	}
+
+	p := &argon2Params{variant: parts[1]}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return nil, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, false
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, false
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return nil, false
+	}
+	return p, true
+}
+
+func (h Argon2Hasher) Verify(password string, encoded string) bool {
+	p, ok := parseArgon2(encoded)
+	if !ok || p.version != argon2.Version {
+		return false
+	}
+
+	var key []byte
+	switch p.variant {
+	case "argon2id":
+		key = argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	case "argon2i":
+		key = argon2.Key([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, p.key) == 1
+}
+
+func (h Argon2Hasher) MustUpdate(encoded string) bool {
+	p, ok := parseArgon2(encoded)
+	if !ok {
+		return true
+	}
+	return p.variant != "argon2id" || p.memory < h.Memory || p.time < h.Time || p.threads < h.Threads
+}
+
+// BcryptSHA256Hasher is Django BCryptSHA256PasswordHasher: bcrypt_sha256$<bcrypt hash>.
+// The password is pre-hashed to hex sha256 so a password longer than 72 bytes is not truncated.
+type BcryptSHA256Hasher struct {
+	Cost int
+}
+
+func (h BcryptSHA256Hasher) Algorithm() string {
+	return "bcrypt_sha256"
+}
+
+func bcryptSHA256Input(password string) []byte {
+	sum := sha256.Sum256([]byte(password))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+func (h BcryptSHA256Hasher) Encode(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword(bcryptSHA256Input(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return "bcrypt_sha256$" + string(hashed), nil
+}
+
+func (h BcryptSHA256Hasher) Verify(password string, encoded string) bool {
+	hashed, found := strings.CutPrefix(encoded, "bcrypt_sha256$")
+	if !found {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), bcryptSHA256Input(password)) == nil
+}
+
+func (h BcryptSHA256Hasher) MustUpdate(encoded string) bool {
+	hashed, _ := strings.CutPrefix(encoded, "bcrypt_sha256$")
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost < h.Cost
+}
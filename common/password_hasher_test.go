@@ -0,0 +1,82 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHasherRoundTrip(t *testing.T) {
+
+	hashers := []PasswordHasher{
+		Argon2Hasher{Memory: 8 * 1024, Time: 1, Threads: 1},
+		PBKDF2Hasher{Iterations: 1000},
+		BcryptSHA256Hasher{Cost: 4},
+	}
+
+	for _, hasher := range hashers {
+		t.Run(hasher.Algorithm(), func(t *testing.T) {
+			encoded, err := hasher.Encode("SecurePassword123!")
+			assert.Nil(t, err)
+			assert.True(t, strings.HasPrefix(encoded, hasher.Algorithm()+"$"))
+
+			assert.True(t, hasher.Verify("SecurePassword123!", encoded))
+			assert.False(t, hasher.Verify("securepassword123!", encoded))
+			assert.False(t, hasher.MustUpdate(encoded))
+		})
+	}
+}
+
+func TestArgon2HasherDjangoFormat(t *testing.T) {
+
+	// hash of argon2-cffi as stored by Django (prefixed with "argon2")
+	encoded := "argon2$argon2id$v=19$m=65536,t=3,p=4$MIIRqgvgQbgj220jfp0MPA$YfwJSVjtjSU0zzV/P3S9nnQ/USre2wvJMjfCIjrTQbg"
+	hasher := Argon2Hasher{Memory: 64 * 1024, Time: 3, Threads: 4}
+
+	assert.True(t, hasher.Verify("correct horse battery staple", encoded))
+	assert.False(t, hasher.Verify("Tr0ub4dor&3", encoded))
+	assert.False(t, hasher.MustUpdate(encoded))
+
+	// weaker parameter or argon2i variant is upgraded
+	assert.True(t, Argon2Hasher{Memory: 128 * 1024, Time: 3, Threads: 4}.MustUpdate(encoded))
+	assert.True(t, hasher.MustUpdate(strings.Replace(encoded, "argon2id", "argon2i", 1)))
+
+	assert.False(t, hasher.Verify("correct horse battery staple", "argon2$argon2id$v=19$broken"))
+}
+
+func TestBcryptSHA256HasherLongPassword(t *testing.T) {
+
+	hasher := BcryptSHA256Hasher{Cost: 4}
+	long := strings.Repeat("a", 80)
+
+	encoded, err := hasher.Encode(long)
+	assert.Nil(t, err)
+
+	// plain bcrypt would ignore everything after 72 bytes
+	assert.True(t, hasher.Verify(long, encoded))
+	assert.False(t, hasher.Verify(long[:72], encoded))
+	assert.True(t, BcryptSHA256Hasher{Cost: 12}.MustUpdate(encoded))
+}
+
+func TestVerifyPasswordRegistry(t *testing.T) {
+
+	pbkdf2, err := CreateDjangoPBKDF2Password("SecurePassword123!", "customSalt123", 390000)
+	assert.Nil(t, err)
+
+	assert.True(t, VerifyPassword("SecurePassword123!", pbkdf2))
+	assert.False(t, VerifyPassword("SecurePassword123!", "md5$salt$hash"))
+	assert.False(t, VerifyPassword("SecurePassword123!", "no-algorithm"))
+
+	// legacy algorithm is upgraded to the default argon2id
+	assert.True(t, PasswordNeedsUpgrade(pbkdf2))
+	assert.True(t, PasswordNeedsUpgrade("md5$salt$hash"))
+
+	encoded, err := HashPassword("SecurePassword123!")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "argon2$argon2id$"))
+	assert.True(t, VerifyPassword("SecurePassword123!", encoded))
+	assert.False(t, PasswordNeedsUpgrade(encoded))
+
+	assert.NotNil(t, SetDefaultPasswordHasher("sha1"))
+}
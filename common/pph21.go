@@ -0,0 +1,207 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PTKPStatus is the marital / dependant status used to decide PTKP (Penghasilan Tidak Kena Pajak)
+type PTKPStatus string
+
+const (
+	PTKPTK0 PTKPStatus = "TK/0"
+	PTKPTK1 PTKPStatus = "TK/1"
+	PTKPTK2 PTKPStatus = "TK/2"
+	PTKPTK3 PTKPStatus = "TK/3"
+	PTKPK0  PTKPStatus = "K/0"
+	PTKPK1  PTKPStatus = "K/1"
+	PTKPK2  PTKPStatus = "K/2"
+	PTKPK3  PTKPStatus = "K/3"
+)
+
+// ptkpAmounts is the yearly PTKP based on PMK 101/PMK.010/2016
+var ptkpAmounts = map[PTKPStatus]int{
+	PTKPTK0: 54000000,
+	PTKPTK1: 58500000,
+	PTKPTK2: 63000000,
+	PTKPTK3: 67500000,
+	PTKPK0:  58500000,
+	PTKPK1:  63000000,
+	PTKPK2:  67500000,
+	PTKPK3:  72000000,
+}
+
+// IsValidPTKPStatus checks if status is one of the known PTKP status
+func IsValidPTKPStatus(status PTKPStatus) bool {
+	_, ok := ptkpAmounts[status]
+	return ok
+}
+
+// PTKPAmount returns the yearly PTKP of a status, unknown status is treated as TK/0
+func PTKPAmount(status PTKPStatus) int {
+	if amount, ok := ptkpAmounts[status]; ok {
+		return amount
+	}
+	return ptkpAmounts[PTKPTK0]
+}
+
+const (
+	biayaJabatanRate     = 5 // percent of gross income
+	biayaJabatanMaxMonth = 500000
+)
+
+// BiayaJabatan returns the occupational cost deduction: 5% of gross, max Rp500.000 per month worked
+func BiayaJabatan(gross int, months int) int {
+	deduction := gross * biayaJabatanRate / 100
+	max := biayaJabatanMaxMonth * months
+	if deduction > max {
+		return max
+	}
+	return deduction
+}
+
+// pph21Brackets is the progressive rate of Pasal 17 UU HPP, upper bound of each bracket and the rate in percent
+var pph21Brackets = []struct {
+	upTo int
+	rate int
+}{
+	{upTo: 60000000, rate: 5},
+	{upTo: 250000000, rate: 15},
+	{upTo: 500000000, rate: 25},
+	{upTo: 5000000000, rate: 30},
+	{upTo: -1, rate: 35}, // no upper bound
+}
+
+// RoundDownThousand rounds PKP (Penghasilan Kena Pajak) down to the nearest thousand rupiah
+func RoundDownThousand(amount int) int {
+	if amount <= 0 {
+		return 0
+	}
+	return amount - amount%1000
+}
+
+// AnnualPPh21 calculates the yearly PPh 21 of a taxable income (PKP) using Pasal 17 progressive rates.
+// pkp should already be rounded down to thousand.
+func AnnualPPh21(pkp int) int {
+	if pkp <= 0 {
+		return 0
+	}
+
+	tax := 0
+	lower := 0
+	for _, bracket := range pph21Brackets {
+		if bracket.upTo != -1 && pkp > bracket.upTo {
+			tax += (bracket.upTo - lower) * bracket.rate / 100
+			lower = bracket.upTo
+			continue
+		}
+		tax += (pkp - lower) * bracket.rate / 100
+		break
+	}
+	return tax
+}
+
+// FormatRupiah formats amount with dot as thousand separator, eg: 1500000 -> "1.500.000"
+func FormatRupiah(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.Itoa(amount)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+// TERCategory is the category of Tarif Efektif Rata-rata (PP 58/2023) used for monthly withholding
+type TERCategory string
+
+const (
+	TERA TERCategory = "A" // TK/0, TK/1, K/0
+	TERB TERCategory = "B" // TK/2, TK/3, K/1, K/2
+	TERC TERCategory = "C" // K/3
+)
+
+// TERCategoryOf returns the TER category of a PTKP status, unknown status is treated as TK/0
+func TERCategoryOf(status PTKPStatus) TERCategory {
+	switch status {
+	case PTKPTK2, PTKPTK3, PTKPK1, PTKPK2:
+		return TERB
+	case PTKPK3:
+		return TERC
+	default:
+		return TERA
+	}
+}
+
+// terBracket is the upper bound of the monthly gross and the rate in basis point (1/100 percent)
+type terBracket struct {
+	upTo int
+	rate int
+}
+
+// terBrackets is the monthly TER table of Lampiran PP 58/2023, the last bracket has no upper bound
+var terBrackets = map[TERCategory][]terBracket{
+	TERA: {
+		{5400000, 0}, {5650000, 25}, {5950000, 50}, {6300000, 75}, {6750000, 100},
+		{7500000, 125}, {8550000, 150}, {9650000, 175}, {10050000, 200}, {10350000, 225},
+		{10700000, 250}, {11050000, 300}, {11600000, 350}, {12500000, 400}, {13750000, 500},
+		{15100000, 600}, {16950000, 700}, {19750000, 800}, {24150000, 900}, {26450000, 1000},
+		{28000000, 1100}, {30050000, 1200}, {32400000, 1300}, {35400000, 1400}, {39100000, 1500},
+		{43850000, 1600}, {47800000, 1700}, {51400000, 1800}, {56300000, 1900}, {62200000, 2000},
+		{68600000, 2100}, {77500000, 2200}, {89000000, 2300}, {103000000, 2400}, {125000000, 2500},
+		{157000000, 2600}, {206000000, 2700}, {337000000, 2800}, {454000000, 2900}, {550000000, 3000},
+		{695000000, 3100}, {910000000, 3200}, {1400000000, 3300}, {-1, 3400},
+	},
+	TERB: {
+		{6200000, 0}, {6500000, 25}, {6850000, 50}, {7300000, 75}, {9200000, 100},
+		{10750000, 150}, {11250000, 200}, {11600000, 250}, {12600000, 300}, {13600000, 400},
+		{14950000, 500}, {16400000, 600}, {18450000, 700}, {21850000, 800}, {26000000, 900},
+		{27700000, 1000}, {29350000, 1100}, {31450000, 1200}, {33950000, 1300}, {37100000, 1400},
+		{41100000, 1500}, {45800000, 1600}, {49500000, 1700}, {53800000, 1800}, {58500000, 1900},
+		{64000000, 2000}, {71000000, 2100}, {80000000, 2200}, {93000000, 2300}, {109000000, 2400},
+		{129000000, 2500}, {163000000, 2600}, {211000000, 2700}, {374000000, 2800}, {459000000, 2900},
+		{555000000, 3000}, {704000000, 3100}, {957000000, 3200}, {1405000000, 3300}, {-1, 3400},
+	},
+	TERC: {
+		{6600000, 0}, {6950000, 25}, {7350000, 50}, {7800000, 75}, {8850000, 100},
+		{9800000, 125}, {10950000, 150}, {11200000, 175}, {12050000, 200}, {12950000, 300},
+		{14150000, 400}, {15550000, 500}, {17050000, 600}, {19500000, 700}, {22700000, 800},
+		{26600000, 900}, {28100000, 1000}, {30100000, 1100}, {32600000, 1200}, {35400000, 1300},
+		{38900000, 1400}, {43000000, 1500}, {47400000, 1600}, {51200000, 1700}, {55800000, 1800},
+		{60400000, 1900}, {66700000, 2000}, {74500000, 2100}, {83200000, 2200}, {95600000, 2300},
+		{110000000, 2400}, {134000000, 2500}, {169000000, 2600}, {221000000, 2700}, {390000000, 2800},
+		{463000000, 2900}, {561000000, 3000}, {709000000, 3100}, {965000000, 3200}, {1419000000, 3300},
+		{-1, 3400},
+	},
+}
+
+// TERRate returns the monthly TER rate in basis point for the monthly gross income
+func TERRate(category TERCategory, gross int) int {
+	brackets, ok := terBrackets[category]
+	if !ok {
+		brackets = terBrackets[TERA]
+	}
+	for _, bracket := range brackets {
+		if bracket.upTo == -1 || gross <= bracket.upTo {
+			return bracket.rate
+		}
+	}
+	return 0
+}
+
+// MonthlyPPh21 calculates the PPh 21 withheld in January - November: monthly gross times the TER rate.
+// The difference with the yearly tax is settled in the last month through the 1721-A1.
+func MonthlyPPh21(status PTKPStatus, gross int) int {
+	if gross <= 0 {
+		return 0
+	}
+	return gross * TERRate(TERCategoryOf(status), gross) / 10000
+}
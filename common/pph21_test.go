@@ -0,0 +1,110 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnualPPh21(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name string
+		pkp  int
+		tax  int
+	}{
+		{name: "zero", pkp: 0, tax: 0},
+		{name: "negative", pkp: -1000, tax: 0},
+		{name: "first bracket", pkp: 50000000, tax: 2500000},
+		{name: "first bracket limit", pkp: 60000000, tax: 3000000},
+		{name: "second bracket", pkp: 100000000, tax: 3000000 + 6000000},
+		{name: "third bracket", pkp: 300000000, tax: 3000000 + 28500000 + 12500000},
+		{name: "fourth bracket", pkp: 1000000000, tax: 3000000 + 28500000 + 62500000 + 150000000},
+		{name: "fifth bracket", pkp: 6000000000, tax: 3000000 + 28500000 + 62500000 + 1350000000 + 350000000},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, sc.tax, AnnualPPh21(sc.pkp))
+		})
+	}
+}
+
+func TestBiayaJabatan(t *testing.T) {
+	t.Parallel()
+
+	// 5% of gross
+	assert.Equal(t, 3000000, BiayaJabatan(60000000, 12))
+	// capped to 500.000 per month
+	assert.Equal(t, 6000000, BiayaJabatan(200000000, 12))
+	assert.Equal(t, 1500000, BiayaJabatan(100000000, 3))
+}
+
+func TestPTKPAmount(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 54000000, PTKPAmount(PTKPTK0))
+	assert.Equal(t, 72000000, PTKPAmount(PTKPK3))
+	assert.Equal(t, 54000000, PTKPAmount("unknown"))
+	assert.True(t, IsValidPTKPStatus(PTKPK1))
+	assert.False(t, IsValidPTKPStatus("K/4"))
+}
+
+func TestRoundDownThousand(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 12345000, RoundDownThousand(12345678))
+	assert.Equal(t, 0, RoundDownThousand(-5))
+}
+
+func TestFormatRupiah(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "0", FormatRupiah(0))
+	assert.Equal(t, "500", FormatRupiah(500))
+	assert.Equal(t, "1.500.000", FormatRupiah(1500000))
+	assert.Equal(t, "-12.000", FormatRupiah(-12000))
+}
+
+func TestMonthlyPPh21(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name   string
+		status PTKPStatus
+		gross  int
+		tax    int
+	}{
+		{name: "zero", status: PTKPTK0, gross: 0, tax: 0},
+		{name: "below threshold A", status: PTKPTK0, gross: 5400000, tax: 0},
+		{name: "A 10 juta", status: PTKPTK0, gross: 10000000, tax: 200000},
+		{name: "A bracket limit", status: PTKPK0, gross: 10050000, tax: 201000},
+		{name: "B 10 juta", status: PTKPK1, gross: 10000000, tax: 150000},
+		{name: "C 10 juta", status: PTKPK3, gross: 10000000, tax: 150000},
+		{name: "C 9 juta", status: PTKPK3, gross: 9000000, tax: 112500},
+		{name: "A top bracket", status: PTKPTK1, gross: 2000000000, tax: 680000000},
+		{name: "unknown status as A", status: "X", gross: 10000000, tax: 200000},
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, sc.tax, MonthlyPPh21(sc.status, sc.gross))
+		})
+	}
+}
+
+func TestTERCategoryOf(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, TERA, TERCategoryOf(PTKPTK0))
+	assert.Equal(t, TERA, TERCategoryOf(PTKPTK1))
+	assert.Equal(t, TERA, TERCategoryOf(PTKPK0))
+	assert.Equal(t, TERB, TERCategoryOf(PTKPTK2))
+	assert.Equal(t, TERB, TERCategoryOf(PTKPK2))
+	assert.Equal(t, TERC, TERCategoryOf(PTKPK3))
+}
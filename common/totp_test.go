@@ -0,0 +1,79 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// secret "12345678901234567890" of the RFC 6238 test vectors
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	scenarios := []struct {
+		unix int64
+		code string
+	}{
+		// last 6 digits of the 8 digit SHA1 vectors of RFC 6238 appendix B
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, sc := range scenarios {
+		code, err := TOTPCode(rfcTOTPSecret, TOTPStep(time.Unix(sc.unix, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, sc.code, code)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	step, ok := VerifyTOTP(rfcTOTPSecret, "005924", now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	// code of the previous step is still accepted for clock drift
+	step, ok = VerifyTOTP(rfcTOTPSecret, "005924", now.Add(TOTPPeriod*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	_, ok = VerifyTOTP(rfcTOTPSecret, "005924", now.Add(3*TOTPPeriod*time.Second))
+	assert.False(t, ok)
+
+	_, ok = VerifyTOTP(rfcTOTPSecret, "123456", now)
+	assert.False(t, ok)
+
+	_, ok = VerifyTOTP(rfcTOTPSecret, "5924", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+
+	other, err := GenerateTOTPSecret()
+	assert.Nil(t, err)
+	assert.NotEqual(t, secret, other)
+
+	code, err := TOTPCode(secret, TOTPStep(time.Now()))
+	assert.Nil(t, err)
+	_, ok := VerifyTOTP(secret, code, time.Now())
+	assert.True(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Payroll", "budi santoso", rfcTOTPSecret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Payroll:budi%20santoso?"))
+	assert.Contains(t, uri, "secret="+rfcTOTPSecret)
+	assert.Contains(t, uri, "issuer=Payroll")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
@@ -15,3 +15,29 @@ func ValidateUsername(username string) bool {
 	re := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_]{1,19}$`)
 	return re.MatchString(username)
 }
+
+var digitsOnly = regexp.MustCompile(`^[0-9]+$`)
+
+// ValidateNPWP checks NPWP (Nomor Pokok Wajib Pajak) without separator:
+// - 15 digits (old format) or 16 digits (new format since 2024, same as NIK)
+func ValidateNPWP(npwp string) bool {
+	if len(npwp) != 15 && len(npwp) != 16 {
+		return false
+	}
+	return digitsOnly.MatchString(npwp)
+}
+
+// ValidateNIK checks NIK (Nomor Induk Kependudukan), it must be exactly 16 digits
+func ValidateNIK(nik string) bool {
+	return len(nik) == 16 && digitsOnly.MatchString(nik)
+}
+
+// ValidateBankCode checks the 3 digit bank code of the clearing system (kode bank), eg: 014 for BCA
+func ValidateBankCode(code string) bool {
+	return len(code) == 3 && digitsOnly.MatchString(code)
+}
+
+// ValidateBankAccountNumber checks the account number without separator, 5 to 20 digits
+func ValidateBankAccountNumber(number string) bool {
+	return len(number) >= 5 && len(number) <= 20 && digitsOnly.MatchString(number)
+}
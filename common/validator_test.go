@@ -61,3 +61,34 @@ func TestValidateUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNPWP(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, ValidateNPWP("012345678901000"))       // 15 digits
+	assert.True(t, ValidateNPWP("3175012345670001"))      // 16 digits
+	assert.False(t, ValidateNPWP("01.234.567.8-901.000")) // with separator
+	assert.False(t, ValidateNPWP("12345"))
+	assert.False(t, ValidateNPWP(""))
+}
+
+func TestValidateNIK(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, ValidateNIK("3175012345670001"))
+	assert.False(t, ValidateNIK("317501234567000"))
+	assert.False(t, ValidateNIK("317501234567000A"))
+}
+
+func TestValidateBankAccount(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, ValidateBankCode("014"))
+	assert.False(t, ValidateBankCode("14"))
+	assert.False(t, ValidateBankCode("BCA"))
+
+	assert.True(t, ValidateBankAccountNumber("1234567890"))
+	assert.False(t, ValidateBankAccountNumber("123-456-7890"))
+	assert.False(t, ValidateBankAccountNumber("1234"))
+	assert.False(t, ValidateBankAccountNumber("123456789012345678901"))
+}
@@ -1,12 +1,26 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/joho/godotenv"
 )
 
+// defaultJWTSecret is only meant for local development, production refuses to start with it
+const defaultJWTSecret = "2387126871hsadhajksdh89789"
+
+const EnvProduction = "production"
+
 type Config struct {
+	// AppEnv is development or production
+	AppEnv string
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -15,13 +29,195 @@ type Config struct {
 	ServerPort string
 	Debug      bool
 	JWTSecret  string
+
+	// TrustedProxies may set the client address in X-Forwarded-For / X-Real-IP, from TRUSTED_PROXIES="10.0.0.0/8,192.168.1.10".
+	// The headers of any other remote address are ignored.
+	TrustedProxies []*net.IPNet
+
+	// JWTKeysDir holds the asymmetric signing keys as <kid>.pem, when empty tokens are signed with JWTSecret (HS256)
+	JWTKeysDir   string
+	JWTActiveKid string
+
+	// Employer identity printed on tax forms (bukti potong)
+	EmployerName string
+	EmployerNpwp string
+
+	// MFARequiredRoles must login with TOTP, MFAIssuer is shown on the authenticator app
+	MFARequiredRoles []string
+	MFAIssuer        string
+
+	// PasswordMinLength and PasswordBreachList (file of common / leaked password, optional) are the password policy
+	PasswordMinLength  int
+	PasswordBreachList string
+
+	// PasswordHasher is the algorithm of new hashes: argon2 (argon2id), pbkdf2_sha256 or bcrypt_sha256
+	PasswordHasher string
+
+	// PasswordResetURL is the forgot password page, the reset token is appended to it
+	PasswordResetURL string
+
+	// Notifier delivers password reset link: log (development) or smtp
+	Notifier     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// OIDCProviders are the single sign-on providers, from OIDC_PROVIDERS and OIDC_<NAME>_* env
+	OIDCProviders []OIDCProvider
+
+	// LoanMinTakeHome is the lowest take home pay left after kasbon / loan deduction, in rupiah
+	LoanMinTakeHome int
+
+	// SchedulerEnabled runs the scheduled jobs in this process, the instances elect one leader to run them
+	SchedulerEnabled bool
+
+	// ShiftEnd is the checkout time (clock only) set on an attendance left without checkout
+	ShiftEnd time.Time
+
+	// PayrollCutoffDay is the last day of the payroll period (1-28), 0 is the end of the month
+	PayrollCutoffDay int
+
+	// cron expression (Asia/Jakarta) of each built-in job, empty disables the job
+	JobAutoCloseCron        string
+	JobClockInReminderCron  string
+	JobPayrollDraftCron     string
+	JobApprovalReminderCron string
+
+	// OutboxSinks receive the domain events of the outbox: log, http and/or webhook, none disables the dispatcher
+	OutboxSinks []string
+	// OutboxHTTPURL receives the events as JSON POST when OutboxSinks has http, OutboxHTTPToken is its bearer token
+	OutboxHTTPURL   string
+	OutboxHTTPToken string
+
+	// WebhookMaxAttempts is how many times a webhook delivery is tried before it is dead
+	WebhookMaxAttempts int
+
+	// MetricsToken is the bearer token Prometheus sends to scrape /metrics, empty leaves /metrics open
+	MetricsToken string
+
+	// OTLPEndpoint is the OTLP/HTTP collector the spans are exported to, empty keeps the trace ids without exporting.
+	// TraceSampleRatio (0-1) of the traces started here are exported, a caller's traceparent keeps its own decision.
+	OTLPEndpoint     string
+	TraceServiceName string
+	TraceSampleRatio float64
+
+	// LogDir keeps app-<date>.json, a new file is started every day and at LogMaxSizeMB. The rotated files
+	// older than LogMaxAgeDays or beyond the newest LogMaxBackups are deleted (0 keeps them), LogCompress gzips them.
+	LogDir        string
+	LogDebug      bool
+	LogMaxSizeMB  int
+	LogMaxAgeDays int
+	LogMaxBackups int
+	LogCompress   bool
+
+	// LokiURL also ships the log to Loki when set, labeled with LokiLabels, from LOKI_LABELS="env=production,region=jkt"
+	LokiURL       string
+	LokiLabels    map[string]string
+	LokiTenantID  string
+	LokiBatchSize int
+	LokiQueueSize int
+}
+
+// OIDCProvider is one OpenID Connect provider, eg: OIDC_PROVIDERS=google gives OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID, ...
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+
+	// RoleMap is group=role, from OIDC_<NAME>_ROLE_MAP="payroll-admins=admin,staff=employee"
+	RoleMap map[string]data.UserRole
 }
 
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 	// is okay if .env file not found, we can read directly on os level
 
-	return &Config{
+	loanMinTakeHome, err := strconv.Atoi(getEnv("LOAN_MIN_TAKE_HOME", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOAN_MIN_TAKE_HOME: %w", err)
+	}
+
+	passwordMinLength, err := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "12"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_LENGTH: %w", err)
+	}
+
+	shiftEnd, err := time.Parse("15:04", getEnv("SHIFT_END", "17:00"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHIFT_END, must be HH:MM: %w", err)
+	}
+
+	payrollCutoffDay, err := strconv.Atoi(getEnv("PAYROLL_CUTOFF_DAY", "0"))
+	if err != nil || payrollCutoffDay < 0 || payrollCutoffDay > 28 {
+		return nil, fmt.Errorf("invalid PAYROLL_CUTOFF_DAY, must be 1-28 or 0 for the end of the month")
+	}
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	if err != nil || webhookMaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS, must be at least 1")
+	}
+
+	traceSampleRatio, err := strconv.ParseFloat(getEnv("OTEL_TRACES_SAMPLER_ARG", "1"), 64)
+	if err != nil || traceSampleRatio < 0 || traceSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG, must be 0-1")
+	}
+
+	logMaxSizeMB, err := strconv.Atoi(getEnv("LOG_MAX_SIZE_MB", "100"))
+	if err != nil || logMaxSizeMB < 0 {
+		return nil, fmt.Errorf("invalid LOG_MAX_SIZE_MB, must be 0 or more")
+	}
+	logMaxAgeDays, err := strconv.Atoi(getEnv("LOG_MAX_AGE_DAYS", "30"))
+	if err != nil || logMaxAgeDays < 0 {
+		return nil, fmt.Errorf("invalid LOG_MAX_AGE_DAYS, must be 0 or more")
+	}
+	logMaxBackups, err := strconv.Atoi(getEnv("LOG_MAX_BACKUPS", "0"))
+	if err != nil || logMaxBackups < 0 {
+		return nil, fmt.Errorf("invalid LOG_MAX_BACKUPS, must be 0 or more")
+	}
+	lokiBatchSize, err := strconv.Atoi(getEnv("LOKI_BATCH_SIZE", "1000"))
+	if err != nil || lokiBatchSize < 1 {
+		return nil, fmt.Errorf("invalid LOKI_BATCH_SIZE, must be at least 1")
+	}
+	lokiQueueSize, err := strconv.Atoi(getEnv("LOKI_QUEUE_SIZE", "10000"))
+	if err != nil || lokiQueueSize < 1 {
+		return nil, fmt.Errorf("invalid LOKI_QUEUE_SIZE, must be at least 1")
+	}
+
+	var trustedProxies []*net.IPNet
+	for _, item := range splitList(getEnv("TRUSTED_PROXIES", "")) {
+		cidr := item
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES item %q, must be an ip or a cidr", item)
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+
+	lokiLabels := map[string]string{"app": "payroll", "env": getEnv("APP_ENV", "development")}
+	for _, item := range splitList(getEnv("LOKI_LABELS", "")) {
+		name, value, found := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !found || name == "" || value == "" || name == "level" {
+			return nil, fmt.Errorf("invalid LOKI_LABELS item %q, must be name=value, level is set by the log", item)
+		}
+		lokiLabels[name] = value
+	}
+
+	cfg := &Config{
+		AppEnv:     getEnv("APP_ENV", "development"),
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -29,8 +225,140 @@ func LoadConfig() (*Config, error) {
 		DBName:     getEnv("DB_NAME", "myapp"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		Debug:      getEnv("DEBUG", "false") == "true",
-		JWTSecret:  getEnv("SECRET_KEY", "2387126871hsadhajksdh89789"),
-	}, nil
+		JWTSecret:  getEnv("SECRET_KEY", defaultJWTSecret),
+
+		TrustedProxies: trustedProxies,
+
+		JWTKeysDir:   getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKid: getEnv("JWT_ACTIVE_KID", ""),
+
+		EmployerName: getEnv("EMPLOYER_NAME", ""),
+		EmployerNpwp: getEnv("EMPLOYER_NPWP", ""),
+
+		MFARequiredRoles: splitList(getEnv("MFA_REQUIRED_ROLES", "admin")),
+		MFAIssuer:        getEnv("MFA_ISSUER", "Payroll"),
+
+		PasswordMinLength:  passwordMinLength,
+		PasswordBreachList: getEnv("PASSWORD_BREACH_LIST", ""),
+		PasswordHasher:     getEnv("PASSWORD_HASHER", "argon2"),
+		PasswordResetURL:   getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password?token="),
+
+		Notifier:     getEnv("NOTIFIER", "log"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		LoanMinTakeHome: loanMinTakeHome,
+
+		SchedulerEnabled:        getEnv("SCHEDULER_ENABLED", "true") == "true",
+		ShiftEnd:                shiftEnd,
+		PayrollCutoffDay:        payrollCutoffDay,
+		JobAutoCloseCron:        getEnv("JOB_ATTENDANCE_AUTO_CLOSE_CRON", "0 18 * * *"),
+		JobClockInReminderCron:  getEnv("JOB_CLOCK_IN_REMINDER_CRON", "0 9 * * 1-5"),
+		JobPayrollDraftCron:     getEnv("JOB_PAYROLL_DRAFT_CRON", "0 6 * * *"),
+		JobApprovalReminderCron: getEnv("JOB_APPROVAL_REMINDER_CRON", "0 9 * * 1-5"),
+
+		OutboxSinks:     splitList(getEnv("OUTBOX_SINKS", "log,webhook")),
+		OutboxHTTPURL:   getEnv("OUTBOX_HTTP_URL", ""),
+		OutboxHTTPToken: getEnv("OUTBOX_HTTP_TOKEN", ""),
+
+		WebhookMaxAttempts: webhookMaxAttempts,
+
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		OTLPEndpoint:     getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TraceServiceName: getEnv("OTEL_SERVICE_NAME", "payroll"),
+		TraceSampleRatio: traceSampleRatio,
+
+		LogDir:        getEnv("LOG_DIR", "logs"),
+		LogDebug:      getEnv("LOG_DEBUG", "true") == "true",
+		LogMaxSizeMB:  logMaxSizeMB,
+		LogMaxAgeDays: logMaxAgeDays,
+		LogMaxBackups: logMaxBackups,
+		LogCompress:   getEnv("LOG_COMPRESS", "true") == "true",
+
+		LokiURL:       getEnv("LOKI_URL", ""),
+		LokiLabels:    lokiLabels,
+		LokiTenantID:  getEnv("LOKI_TENANT_ID", ""),
+		LokiBatchSize: lokiBatchSize,
+		LokiQueueSize: lokiQueueSize,
+	}
+
+	if err := cfg.validateJWT(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range splitList(getEnv("OIDC_PROVIDERS", "")) {
+		provider, err := loadOIDCProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		cfg.OIDCProviders = append(cfg.OIDCProviders, *provider)
+	}
+
+	if cfg.Notifier != "log" && cfg.Notifier != "smtp" {
+		return nil, fmt.Errorf("invalid NOTIFIER %q, must be log or smtp", cfg.Notifier)
+	}
+	if cfg.Notifier == "smtp" && (cfg.SMTPHost == "" || cfg.SMTPFrom == "") {
+		return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required when NOTIFIER=smtp")
+	}
+
+	for _, sink := range cfg.OutboxSinks {
+		if sink != "log" && sink != "http" && sink != "webhook" {
+			return nil, fmt.Errorf("invalid OUTBOX_SINKS item %q, must be log, http or webhook", sink)
+		}
+		if sink == "http" && cfg.OutboxHTTPURL == "" {
+			return nil, fmt.Errorf("OUTBOX_HTTP_URL is required when OUTBOX_SINKS has http")
+		}
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) validateJWT() error {
+	if c.JWTKeysDir != "" && c.JWTActiveKid == "" {
+		return fmt.Errorf("JWT_ACTIVE_KID is required when JWT_KEYS_DIR is set")
+	}
+
+	if c.AppEnv != EnvProduction || c.JWTKeysDir != "" {
+		return nil
+	}
+
+	// production without asymmetric keys still signs with HS256, at least the secret must not be public
+	if c.JWTSecret == "" || c.JWTSecret == defaultJWTSecret {
+		return fmt.Errorf("SECRET_KEY must be set to a non default value in production, or configure JWT_KEYS_DIR")
+	}
+	return nil
+}
+
+func loadOIDCProvider(name string) (*OIDCProvider, error) {
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	provider := &OIDCProvider{
+		Name:         name,
+		Issuer:       getEnv(prefix+"ISSUER", ""),
+		ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+		ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+		RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+		Scopes:       splitList(getEnv(prefix+"SCOPES", "openid,email,profile")),
+		GroupsClaim:  getEnv(prefix+"GROUPS_CLAIM", "groups"),
+		RoleMap:      map[string]data.UserRole{},
+	}
+
+	if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+		return nil, fmt.Errorf("%sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", prefix, prefix, prefix)
+	}
+
+	for _, item := range splitList(getEnv(prefix+"ROLE_MAP", "")) {
+		group, role, found := strings.Cut(item, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !found || group == "" || (data.UserRole(role) != data.RAdmin && data.UserRole(role) != data.REmployee) {
+			return nil, fmt.Errorf("invalid %sROLE_MAP item %q, must be group=admin or group=employee", prefix, item)
+		}
+		provider.RoleMap[group] = data.UserRole(role)
+	}
+	return provider, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -39,3 +367,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList parses comma separated env value, empty items are skipped
+func splitList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package data
+
+import "time"
+
+// AccountKey identifies a role in the payroll journal, independent of the
+// account code used by the company's chart of accounts
+type AccountKey string
+
+const (
+	AccSalaryExpense        AccountKey = "salary_expense"
+	AccOvertimeExpense      AccountKey = "overtime_expense"
+	AccBonusExpense         AccountKey = "bonus_expense"
+	AccReimbursementExpense AccountKey = "reimbursement_expense"
+	AccTaxPayable           AccountKey = "tax_payable"
+	AccBpjsPayable          AccountKey = "bpjs_payable"
+	AccEmployeeReceivable   AccountKey = "employee_receivable"
+	AccSalaryPayable        AccountKey = "salary_payable"
+)
+
+// AccountMapping maps an AccountKey (optionally per cost center) to an account in the chart of accounts.
+// An empty CostCenter is the fallback used for every cost center without its own mapping.
+type AccountMapping struct {
+	Id          int
+	AccountKey  AccountKey
+	CostCenter  string
+	AccountCode string
+	AccountName string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CreatedBy   string
+	UpdatedBy   string
+}
+
+// JournalEntry is a double-entry journal generated from one payroll batch.
+// Sum of Debit must always equal sum of Credit.
+type JournalEntry struct {
+	PayrollId   int            `json:"payroll_id"`
+	Date        time.Time      `json:"date"`
+	Reference   string         `json:"reference"`
+	Description string         `json:"description"`
+	Lines       []*JournalLine `json:"lines"`
+	TotalDebit  int            `json:"total_debit"`
+	TotalCredit int            `json:"total_credit"`
+}
+
+type JournalLine struct {
+	AccountKey  AccountKey `json:"account_key"`
+	AccountCode string     `json:"account_code"`
+	AccountName string     `json:"account_name"`
+	CostCenter  string     `json:"cost_center"`
+	Description string     `json:"description"`
+	Debit       int        `json:"debit"`
+	Credit      int        `json:"credit"`
+}
@@ -0,0 +1,64 @@
+package data
+
+import "time"
+
+// APITokenPrefix starts every api token, AuthMiddleware tells it apart from a JWT by it
+const APITokenPrefix = "pyr_"
+
+// Scope of an api token, a token only reaches the routes of its scopes
+const (
+	ScopeAttendanceWrite    = "attendance:write"    // attendance period, clock in / out and overtime
+	ScopeReimbursementWrite = "reimbursement:write" // submit reimbursement
+	ScopePayslipRead        = "payslip:read"        // salary and THR slips
+	ScopeLoanRead           = "loan:read"           // kasbon / loan and its schedule
+	ScopeTaxRead            = "tax:read"            // 1721-A1 and e-Bupot PPh 21
+	ScopeAccountingRead     = "accounting:read"     // chart of accounts and payroll journal
+	ScopeEmployeeWrite      = "employee:write"      // tax profile and religion of employees, for HRIS sync
+)
+
+// APIScopes lists every scope a token can be given
+var APIScopes = []string{
+	ScopeAttendanceWrite,
+	ScopeReimbursementWrite,
+	ScopePayslipRead,
+	ScopeLoanRead,
+	ScopeTaxRead,
+	ScopeAccountingRead,
+	ScopeEmployeeWrite,
+}
+
+// ServiceAccount is a non human client of the api (HRIS, BI tool), it only authenticates with api tokens.
+// It has no employee data, Role decides which admin endpoints its tokens may call.
+type ServiceAccount struct {
+	Id          int
+	Name        string
+	Description string
+	Role        UserRole
+	CreatedAt   time.Time
+	CreatedBy   string
+}
+
+// APIToken is a personal access token (UserId set) or a service account token (ServiceAccountId set),
+// only the sha256 hash of the token is stored, Prefix is kept to recognize it in the list
+type APIToken struct {
+	Id               int
+	UserId           int
+	ServiceAccountId int
+	Name             string
+	Prefix           string
+	Scopes           []string
+	ExpiresAt        time.Time
+	LastUsedAt       *time.Time
+	LastUsedIP       string
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+	CreatedBy        string
+}
+
+// APITokenOwner is the token with the user or service account it acts as
+type APITokenOwner struct {
+	Token    APIToken
+	Username string // username of the user, name of the service account
+	Role     UserRole
+	IsActive bool
+}
@@ -0,0 +1,41 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent is one entry of the append only audit trail.
+// Hash is sha256 of PrevHash and the event, editing or removing an event breaks every later hash.
+type AuditEvent struct {
+	Id          int64
+	OccurredAt  time.Time
+	Actor       string // username or token:<id>, "system" when there is no logged in user
+	ActorUserId int
+	Action      string // <entity>.<verb>, eg: attendance.create, payslip.read_all
+	EntityType  string
+	EntityId    string
+	Diff        json.RawMessage // {"field": {"before": .., "after": ..}} of the changed fields
+	TraceId     string
+	IP          string
+	PrevHash    string
+	Hash        string
+}
+
+// AuditChange is the value of a field before and after the action, nil before means created
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditFilter selects audit events, zero value fields are not filtered
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityId   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,32 @@
+package data
+
+import "time"
+
+// JobRunStatus is the state of one run of a scheduled job
+type JobRunStatus string
+
+const (
+	JobRunning JobRunStatus = "running"
+	JobSuccess JobRunStatus = "success"
+	JobFailed  JobRunStatus = "failed"
+)
+
+// JobRun is one run of a scheduled job, a job runs at most once per ScheduledAt over every instance
+type JobRun struct {
+	Id          int64        `json:"id"`
+	Job         string       `json:"job"`
+	ScheduledAt time.Time    `json:"scheduled_at"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at"`
+	Status      JobRunStatus `json:"status"`
+	Detail      string       `json:"detail"`   // what the job did, or the error when it failed
+	Instance    string       `json:"instance"` // host that ran it, the leader at that time
+}
+
+// JobRunFilter narrows the run history, zero value fields are not filtered
+type JobRunFilter struct {
+	Job    string
+	Status JobRunStatus
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,76 @@
+package data
+
+import "time"
+
+// LoanType is the kind of money lent by the company to an employee
+type LoanType string
+
+const (
+	LoanKasbon   LoanType = "kasbon"   // salary advance, interest free and repaid in one payroll
+	LoanEmployee LoanType = "employee" // employee loan, repaid in monthly installments with optional flat interest
+)
+
+type LoanStatus string
+
+const (
+	LoanPending  LoanStatus = "pending"  // waiting for admin approval
+	LoanActive   LoanStatus = "active"   // approved, installments are deducted from payroll
+	LoanRejected LoanStatus = "rejected" // rejected by admin
+	LoanPaidOff  LoanStatus = "paid_off" // outstanding is zero
+)
+
+type LoanRepaymentSource string
+
+const (
+	RepaymentPayroll    LoanRepaymentSource = "payroll"    // deducted from a payroll run
+	RepaymentSettlement LoanRepaymentSource = "settlement" // settled outside payroll, eg: paid in cash on termination
+)
+
+// Loan is a kasbon or an employee loan. Interest is flat, InterestRate is in basis points per year.
+type Loan struct {
+	Id           int        `json:"id"`
+	UserId       int        `json:"user_id"`
+	Type         LoanType   `json:"type"`
+	Principal    int        `json:"principal"`
+	InterestRate int        `json:"interest_rate"`
+	Tenor        int        `json:"tenor"`         // number of monthly installments
+	TotalPayable int        `json:"total_payable"` // principal + interest
+	Outstanding  int        `json:"outstanding"`   // total payable - every repayment
+	Status       LoanStatus `json:"status"`
+	Reason       string     `json:"reason"`
+	ReviewNote   string     `json:"review_note"`
+	ApprovedBy   string     `json:"approved_by"`
+	ApprovedAt   *time.Time `json:"approved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	CreatedBy    string     `json:"created_by"`
+	UpdatedBy    string     `json:"updated_by"`
+}
+
+// LoanInstallment is one row of the repayment schedule, DueDate is the last day of the payroll month
+type LoanInstallment struct {
+	LoanId  int       `json:"loan_id"`
+	Seq     int       `json:"seq"`
+	DueDate time.Time `json:"due_date"`
+	Amount  int       `json:"amount"`
+}
+
+type LoanRepayment struct {
+	Id        int                 `json:"id"`
+	LoanId    int                 `json:"loan_id"`
+	PayrollId int                 `json:"payroll_id"` // 0 for settlement
+	Source    LoanRepaymentSource `json:"source"`
+	Amount    int                 `json:"amount"`
+	Note      string              `json:"note"`
+	CreatedAt time.Time           `json:"created_at"`
+	CreatedBy string              `json:"created_by"`
+}
+
+// LoanDue is the state of an active loan needed to calculate its payroll deduction
+type LoanDue struct {
+	LoanId      int
+	UserId      int
+	Outstanding int
+	Scheduled   int // sum of installments due up to the payroll period
+	Repaid      int // sum of every repayment so far
+}
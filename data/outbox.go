@@ -0,0 +1,39 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event recorded in the transaction of the change it describes and relayed
+// to the sinks by the dispatcher. The JSON form is what the sinks receive.
+type OutboxEvent struct {
+	Id            int64           `json:"id"`   // consumers drop an id they already processed, delivery is at least once
+	Type          string          `json:"type"` // <entity>.<past tense verb>, eg: attendance.checked_in
+	AggregateType string          `json:"aggregate_type"`
+	AggregateId   string          `json:"aggregate_id"` // events of one aggregate are delivered in order
+	Payload       json.RawMessage `json:"data"`
+	TraceId       string          `json:"trace_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+
+	Attempts      int        `json:"-"`
+	LastError     string     `json:"-"`
+	NextAttemptAt time.Time  `json:"-"`
+	PublishedAt   *time.Time `json:"-"`
+}
+
+// domain events of the outbox
+const (
+	EventAttendanceCheckedIn    = "attendance.checked_in"
+	EventOvertimeSubmitted      = "overtime.submitted"
+	EventReimbursementSubmitted = "reimbursement.submitted"
+	EventPayrollFinalized       = "payroll.finalized"
+)
+
+// EventTypes lists every domain event, what a webhook can subscribe to
+var EventTypes = []string{
+	EventAttendanceCheckedIn,
+	EventOvertimeSubmitted,
+	EventReimbursementSubmitted,
+	EventPayrollFinalized,
+}
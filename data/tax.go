@@ -0,0 +1,63 @@
+package data
+
+import (
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+)
+
+// Form1721A1 is the annual withholding certificate (bukti potong PPh 21) for permanent employee.
+// The numbered comment follows the line number printed on the official form.
+type Form1721A1 struct {
+	Id         int
+	UserId     int
+	Year       int
+	Number     string // nomor bukti potong
+	MonthStart int    // masa perolehan awal
+	MonthEnd   int    // masa perolehan akhir
+
+	EmployerName string
+	EmployerNpwp string
+	Fullname     string
+	Npwp         string
+	Nik          string
+	PTKPStatus   common.PTKPStatus
+
+	Salary              int // 1. gaji
+	OtherAllowance      int // 3. tunjangan lainnya, uang lembur
+	Bonus               int // 7. tantiem, bonus, gratifikasi, THR
+	Gross               int // 8. jumlah penghasilan bruto (1 s/d 7)
+	BiayaJabatan        int // 9. biaya jabatan
+	PensionContribution int // 10. iuran pensiun / JHT
+	TotalDeduction      int // 11. jumlah pengurangan (9 + 10)
+	Netto               int // 12. jumlah penghasilan neto (8 - 11)
+	PTKP                int // 15. penghasilan tidak kena pajak
+	PKP                 int // 16. penghasilan kena pajak setahun
+	TaxDue              int // 17 & 19. PPh 21 terutang
+	TaxWithheld         int // 20. PPh 21 yang telah dipotong
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy string
+	UpdatedBy string
+}
+
+// BupotPPh21 is one row of the monthly PPh 21 withholding (bukti potong bulanan pegawai tetap)
+type BupotPPh21 struct {
+	UserId        int               `json:"user_id"`
+	Fullname      string            `json:"fullname"`
+	Npwp          string            `json:"npwp"`
+	Nik           string            `json:"nik"`
+	PTKPStatus    common.PTKPStatus `json:"ptkp_status"`
+	TaxObjectCode string            `json:"tax_object_code"`
+	Gross         int               `json:"gross"`
+	TERRate       int               `json:"ter_rate"` // basis point, 25 means 0.25%
+	TaxWithheld   int               `json:"tax_withheld"`
+}
+
+// TaxProfileError tells which employee can not be reported because the tax profile is incomplete
+type TaxProfileError struct {
+	UserId   int    `json:"user_id"`
+	Fullname string `json:"fullname"`
+	Message  string `json:"message"`
+}
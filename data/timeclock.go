@@ -12,6 +12,7 @@ type Attendance struct {
 	Periode      time.Time
 	CheckinTime  time.Time
 	CheckoutTime pgtype.Timestamp
+	AutoClosed   bool // checkout was set to the shift end by the scheduler, the employee forgot to check out
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	CreatedBy    string
@@ -42,15 +43,28 @@ type Reimbursement struct {
 	UpdatedBy   string
 }
 
+// PayrollType tells the kind of payroll run
+type PayrollType string
+
+const (
+	PayrollRegular         PayrollType = "regular"          // monthly salary, overtime and reimbursement
+	PayrollBonus           PayrollType = "bonus"            // off-cycle bonus, taxed as irregular income
+	PayrollCorrection      PayrollType = "correction"       // adjustment of salary already paid, can be negative
+	PayrollFinalSettlement PayrollType = "final_settlement" // last salary of resigning employee, paid before the regular run
+	PayrollTHR             PayrollType = "thr"              // tunjangan hari raya, paid once a year before the religious holiday
+)
+
 // Payroll is the main record that marks payroll has been processed for a specific period
 type Payroll struct {
-	Id                 int       // unique ID
-	PeriodStart        time.Time // start date of the payroll period
-	PeriodEnd          time.Time // end date of the payroll period
-	TotalAttendance    int       // number of employees who had attendance in this period
-	TotalOvertime      int       // total overtime hours from all employees
-	TotalReimbursement int       // total reimbursement nominal from all employees
-	TotalSalary        int       // total salary paid for all employees (including base, overtime, reimbursement)
+	Id                 int         // unique ID
+	Type               PayrollType // kind of payroll run
+	PeriodStart        time.Time   // start date of the payroll period
+	PeriodEnd          time.Time   // end date of the payroll period
+	TotalAttendance    int         // number of employees who had attendance in this period
+	TotalOvertime      int         // total overtime hours from all employees
+	TotalReimbursement int         // total reimbursement nominal from all employees
+	TotalSalary        int         // total salary paid for all employees (including base, overtime, reimbursement)
+	Note               string      // reason of an off-cycle run
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	CreatedBy          string
@@ -64,18 +78,99 @@ type PayrollItem struct {
 	UserId             int // user/employee this payroll item belongs to
 	AttendanceCount    int // total days present during the payroll period
 	OvertimeHours      int // total hours of overtime in the payroll period
+	BaseSalaryAmount   int // prorated base salary paid in this period
+	OvertimeAmount     int // overtime pay in this period
+	BonusAmount        int // irregular income paid in this period, eg: THR
 	ReimbursementTotal int // total amount of approved reimbursements
-	TotalSalary        int // final take-home pay for this user (base + overtime + reimbursement)
+	TaxAmount          int // PPh 21 withheld from this user
+	BpjsAmount         int // BPJS contribution withheld from this user
+	LoanDeduction      int // loan installment (kasbon) deducted from this user
+	TotalSalary        int // gross pay for this user (base + overtime + bonus + reimbursement)
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	CreatedBy          string
 	UpdatedBy          string
 }
 
+// PayrollDraft is the regular payroll of a period calculated at the cutoff date for review before it is run.
+// It is not paid, PayrollId is set once the regular payroll of the period is run.
+type PayrollDraft struct {
+	Id          int
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Employees   int
+	TotalSalary int            // gross of every employee, like Payroll.TotalSalary
+	Items       []*PayrollItem // calculation per employee, PayrollId of the items is 0
+	PayrollId   *int
+	CreatedAt   time.Time
+	CreatedBy   string
+}
+
 type UserPayslip struct {
 	UserID           int
 	TotalSalary      int
 	AttendanceCount  int
 	OvertimeHours    int
 	ReimbursementSum int
+	LoanOutstanding  int           // outstanding of active loans after every deduction so far
+	Runs             []*PayslipRun // every payroll run that paid the user in the month
+}
+
+// PayslipRun is what one payroll run paid to a user
+type PayslipRun struct {
+	PayrollId          int
+	UserId             int
+	Type               PayrollType
+	PeriodStart        time.Time
+	PeriodEnd          time.Time
+	Note               string
+	AttendanceCount    int
+	OvertimeHours      int
+	BaseSalaryAmount   int
+	OvertimeAmount     int
+	BonusAmount        int
+	ReimbursementTotal int
+	TaxAmount          int
+	BpjsAmount         int
+	LoanDeduction      int
+	TotalSalary        int // gross
+	NetSalary          int // gross - tax - bpjs - loan deduction
+}
+
+// UserTHRPayslip is the THR payslip of a user, NetAmount is what the user receives after PPh 21
+type UserTHRPayslip struct {
+	UserID    int
+	PayrollId int
+	THRAmount int
+	TaxAmount int
+	NetAmount int
+}
+
+// YearlyPayrollSummary is the accumulation of every payroll item of a user in one year
+type YearlyPayrollSummary struct {
+	UserId             int
+	FirstMonth         int // first month (1-12) the user received salary in the year
+	LastMonth          int // last month (1-12) the user received salary in the year
+	BaseSalaryAmount   int
+	OvertimeAmount     int
+	BonusAmount        int
+	ReimbursementTotal int
+	TaxAmount          int
+	BpjsAmount         int
+}
+
+// UserMonthlyTax is the taxable gross and PPh 21 already withheld from a user in one month,
+// summed over every payroll run paid in that month
+type UserMonthlyTax struct {
+	UserId int
+	Gross  int
+	Tax    int
+}
+
+// PayrollPeriodTotal sums the payrolls of one type paid (period end) in one month
+type PayrollPeriodTotal struct {
+	Type        PayrollType
+	Period      string // YYYY-MM of the period end
+	Payrolls    int
+	TotalSalary int
 }
@@ -1,6 +1,10 @@
 package data
 
-import "time"
+import (
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+)
 
 type UserRole string
 
@@ -9,6 +13,27 @@ const (
 	REmployee UserRole = "employee"
 )
 
+// Religion decides which religious holiday (hari raya keagamaan) the THR of an employee follows
+type Religion string
+
+const (
+	ReligionIslam     Religion = "islam"     // Idul Fitri
+	ReligionProtestan Religion = "protestan" // Natal
+	ReligionKatolik   Religion = "katolik"   // Natal
+	ReligionHindu     Religion = "hindu"     // Nyepi
+	ReligionBuddha    Religion = "buddha"    // Waisak
+	ReligionKonghucu  Religion = "konghucu"  // Imlek
+)
+
+// IsValidReligion checks if religion is one of the religion recognized for THR
+func IsValidReligion(religion Religion) bool {
+	switch religion {
+	case ReligionIslam, ReligionProtestan, ReligionKatolik, ReligionHindu, ReligionBuddha, ReligionKonghucu:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	Id         int
 	Fullname   string
@@ -18,7 +43,136 @@ type User struct {
 	Role       UserRole
 	BaseSalary int
 	JoinDate   time.Time
+	CostCenter string
+	Religion   Religion
+
+	IsActive     bool
+	TokenVersion int // bumped to revoke every access token of the user
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// UserTaxProfile holds the tax identity of an employee, used for PPh 21 calculation and reporting
+type UserTaxProfile struct {
+	UserId     int
+	Fullname   string
+	Npwp       string
+	Nik        string
+	PTKPStatus common.PTKPStatus
+}
+
+// UserContact is who to notify, used by the reminders of the scheduler
+type UserContact struct {
+	Id       int
+	Fullname string
+	Email    string
+	Role     UserRole
+}
+
+// UserBankAccount is where the net salary of an employee is transferred, used for the bank transfer file
+type UserBankAccount struct {
+	UserId        int
+	Fullname      string
+	BankCode      string // kode bank of the clearing system, eg: 014 for BCA
+	AccountNumber string
+	AccountName   string
+}
+
+// UserEmployment is the employment data of an active employee, used to calculate THR
+type UserEmployment struct {
+	UserId     int
+	BaseSalary int
+	JoinDate   time.Time
+	Religion   Religion
+}
+
+// RefreshToken is a long lived token exchanged for a new access token, only its sha256 hash is stored.
+// Every token rotated from the same login shares the FamilyId.
+type RefreshToken struct {
+	Id        int
+	UserId    int
+	FamilyId  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// SessionState is checked on every authenticated request on top of the token signature
+type SessionState struct {
+	IsActive      bool
+	TokenVersion  int
+	AccessRevoked bool // the access token was revoked by logout
+}
+
+// UserMFA is the TOTP second factor of a user, Secret is base32 as shown to the authenticator app.
+// LastUsedStep is the last accepted time step, a code can not be used twice.
+type UserMFA struct {
+	UserId       int
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	EnabledAt    *time.Time
+}
+
+// LoginThrottleScope is what failed login attempts are counted on
+type LoginThrottleScope string
+
+const (
+	ThrottleUsername LoginThrottleScope = "username"
+	ThrottleIP       LoginThrottleScope = "ip"
+)
+
+// LoginThrottle is the failed login counter of a username or a client ip
+type LoginThrottle struct {
+	Scope        LoginThrottleScope
+	Key          string
+	FailedCount  int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
+
+type AuthEventType string
+
+const (
+	AuthLoginSuccess   AuthEventType = "login_success"
+	AuthLoginFailed    AuthEventType = "login_failed"
+	AuthLoginThrottled AuthEventType = "login_throttled"
+	AuthAccountLocked  AuthEventType = "account_locked"
+	AuthAccountUnlock  AuthEventType = "account_unlocked"
+	AuthMFAFailed      AuthEventType = "mfa_failed"
+
+	AuthPasswordChanged        AuthEventType = "password_changed"
+	AuthPasswordResetRequested AuthEventType = "password_reset_requested"
+	AuthPasswordReset          AuthEventType = "password_reset"
+
+	AuthSSOLinked   AuthEventType = "sso_linked"
+	AuthRoleChanged AuthEventType = "role_changed"
+)
+
+// AuthEvent is one entry of the authentication event log, UserId is 0 for unknown username
+type AuthEvent struct {
+	UserId   int
+	Username string
+	IP       string
+	Event    AuthEventType
+	Detail   string
+}
+
+// PasswordResetToken is a single use forgot password token, only its sha256 hash is stored
+type PasswordResetToken struct {
+	Id        int
+	UserId    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// OIDCLoginState is a pending single sign-on login, kept until the identity provider redirects back.
+// The nonce and PKCE code verifier never leave the server.
+type OIDCLoginState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
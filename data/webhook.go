@@ -0,0 +1,55 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint registered to receive the domain events of EventTypes
+type Webhook struct {
+	Id         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret,omitempty"` // HMAC key of the signature, only returned when the webhook is created
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  string    `json:"created_by"`
+}
+
+// WebhookDeliveryStatus is the state of the delivery of one event to one webhook
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"   // not tried yet, or redelivery requested
+	DeliveryFailed    WebhookDeliveryStatus = "failed"    // last attempt failed, retried at NextAttemptAt
+	DeliveryDelivered WebhookDeliveryStatus = "delivered" // the endpoint answered 2xx
+	DeliveryDead      WebhookDeliveryStatus = "dead"      // gave up after the max attempts, only redelivered manually
+)
+
+// WebhookDelivery is the delivery log of one event to one webhook
+type WebhookDelivery struct {
+	Id            int64                 `json:"id"`
+	WebhookId     int                   `json:"webhook_id"`
+	EventId       int64                 `json:"event_id"`
+	EventType     string                `json:"event_type"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	ResponseCode  int                   `json:"response_code"` // of the last attempt, 0 when the endpoint was not reached
+	LastError     string                `json:"last_error"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at"`
+
+	// the request of the delivery, loaded for sending only
+	Payload json.RawMessage `json:"-"`
+	URL     string          `json:"-"`
+	Secret  string          `json:"-"`
+}
+
+// WebhookDeliveryFilter narrows the delivery log, zero value fields are not filtered
+type WebhookDeliveryFilter struct {
+	WebhookId int
+	Status    WebhookDeliveryStatus
+	Limit     int
+	Offset    int
+}
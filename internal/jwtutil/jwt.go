@@ -6,42 +6,117 @@ import (
 
 	"github.com/ariesmaulana/payroll/data"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtSecretKey []byte
+// AccessTokenTTL is kept short so a revoked session or an inactive user loses access quickly,
+// the client uses the refresh token to get a new one
+const AccessTokenTTL = 15 * time.Minute
 
+// ChallengeTokenTTL is the lifetime of MFA login / enrolment challenge and step-up token
+const ChallengeTokenTTL = 5 * time.Minute
+
+// Purpose of a token that is not an access token, AuthMiddleware rejects any token with purpose
+const (
+	PurposeMFALogin  = "mfa_login"  // password verified, waiting for TOTP code
+	PurposeMFAEnroll = "mfa_enroll" // password verified, role requires MFA but not enrolled yet
+	PurposeStepUp    = "step_up"    // TOTP re-verified for payroll-finalizing action
+)
+
+var keys *KeySet
+
+// SetSecret signs and verifies with HS256, only for local development
 func SetSecret(secret string) {
-	jwtSecretKey = []byte(secret)
+	keys = NewHMACKeySet(secret)
+}
+
+// SetKeySet signs with the active asymmetric key and verifies with every key of the set
+func SetKeySet(ks *KeySet) {
+	keys = ks
 }
 
 type Claims struct {
 	UserID   int           `json:"user_id"`
 	Username string        `json:"username"`
 	Role     data.UserRole `json:"role"`
+
+	// TokenVersion must match the user token version, admin bumps it to revoke every session
+	TokenVersion int `json:"token_version"`
+
+	// Purpose is empty for access token, see PurposeMFALogin etc
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT bikin token baru
-func GenerateJWT(userID int, username string, role data.UserRole) (string, error) {
+// GenerateJWT bikin token baru, jti (claims ID) dipakai untuk revoke token saat logout
+func GenerateJWT(userID int, username string, role data.UserRole, tokenVersion int) (string, error) {
+	return sign(userID, username, role, tokenVersion, "", AccessTokenTTL)
+}
+
+// GenerateChallengeJWT bikin token pendek untuk langkah MFA, tidak bisa dipakai sebagai access token
+func GenerateChallengeJWT(userID int, username string, role data.UserRole, tokenVersion int, purpose string) (string, error) {
+	if purpose == "" {
+		return "", errors.New("challenge token requires purpose")
+	}
+	return sign(userID, username, role, tokenVersion, purpose, ChallengeTokenTTL)
+}
+
+func sign(userID int, username string, role data.UserRole, tokenVersion int, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		TokenVersion: tokenVersion,
+		Purpose:      purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecretKey)
+	if keys == nil {
+		return "", errors.New("jwt key is not configured")
+	}
+
+	token := jwt.NewWithClaims(keys.method, claims)
+	if keys.activeKid != "" {
+		token.Header["kid"] = keys.activeKid
+	}
+	return token.SignedString(keys.signingKey)
 }
 
-// ValidateJWT parsing token dan balikin claim
+// ValidateJWT parsing access token dan balikin claim
 func ValidateJWT(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecretKey, nil
-	})
+	claims, err := parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+// ValidateChallengeJWT parsing challenge token, purpose harus sama
+func ValidateChallengeJWT(tokenStr string, purpose string) (*Claims, error) {
+	claims, err := parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
+}
+
+func parse(tokenStr string) (*Claims, error) {
+	if keys == nil {
+		return nil, errors.New("jwt key is not configured")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, keys.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,190 @@
+package jwtutil
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir string, kid string, blockType string, der []byte) {
+	t.Helper()
+	raw := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), raw, 0600))
+}
+
+func writeEd25519Key(t *testing.T, dir string, kid string) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	writeKey(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writeRSAKey(t *testing.T, dir string, kid string, bits int) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	writeKey(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private))
+}
+
+func TestHMACKeySet(t *testing.T) {
+	SetSecret("secret")
+
+	token, err := GenerateJWT(1, "admin", data.RAdmin, 0)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claims.UserID)
+	assert.Equal(t, data.RAdmin, claims.Role)
+	assert.NotEmpty(t, claims.ID)
+
+	// the secret is never published
+	assert.Empty(t, JWKS().Keys)
+
+	SetSecret("other")
+	_, err = ValidateJWT(token)
+	assert.Error(t, err)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "2025-01")
+	writeRSAKey(t, dir, "2025-02", 2048)
+
+	oldKeys, err := LoadKeySet(dir, "2025-01")
+	require.NoError(t, err)
+	SetKeySet(oldKeys)
+
+	oldToken, err := GenerateJWT(2, "staff", data.REmployee, 1)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+	// rotate to the RSA key, the token of the previous key is still valid
+	newKeys, err := LoadKeySet(dir, "2025-02")
+	require.NoError(t, err)
+	SetKeySet(newKeys)
+
+	newToken, err := GenerateJWT(2, "staff", data.REmployee, 1)
+	require.NoError(t, err)
+	parsed, _, err = jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02", parsed.Header["kid"])
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, err := ValidateJWT(token)
+		require.NoError(t, err)
+		assert.Equal(t, 2, claims.UserID)
+		assert.Equal(t, 1, claims.TokenVersion)
+	}
+
+	jwks := JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "2025-01", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+
+	// once the old key is removed its tokens are rejected
+	require.NoError(t, os.Remove(filepath.Join(dir, "2025-01.pem")))
+	retired, err := LoadKeySet(dir, "2025-02")
+	require.NoError(t, err)
+	SetKeySet(retired)
+
+	_, err = ValidateJWT(oldToken)
+	assert.Error(t, err)
+	_, err = ValidateJWT(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeySetRejectsHMACWithPublicKey(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "main")
+	ks, err := LoadKeySet(dir, "main")
+	require.NoError(t, err)
+	SetKeySet(ks)
+
+	// a token signed with HS256 must not be accepted even if it names a known kid
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1, Role: data.RAdmin})
+	forged.Header["kid"] = "main"
+	raw, err := forged.SignedString([]byte("guess"))
+	require.NoError(t, err)
+
+	_, err = ValidateJWT(raw)
+	assert.Error(t, err)
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	t.Run("empty dir", func(t *testing.T) {
+		_, err := LoadKeySet(t.TempDir(), "main")
+		assert.Error(t, err)
+	})
+
+	t.Run("active key missing", func(t *testing.T) {
+		dir := t.TempDir()
+		writeEd25519Key(t, dir, "main")
+		_, err := LoadKeySet(dir, "other")
+		assert.Error(t, err)
+	})
+
+	t.Run("active key without private key", func(t *testing.T) {
+		dir := t.TempDir()
+		public, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(public)
+		require.NoError(t, err)
+		writeKey(t, dir, "main", "PUBLIC KEY", der)
+
+		_, err = LoadKeySet(dir, "main")
+		assert.Error(t, err)
+	})
+
+	t.Run("rsa key too small", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "main", 1024)
+		_, err := LoadKeySet(dir, "main")
+		assert.Error(t, err)
+	})
+}
+
+func TestChallengeJWT(t *testing.T) {
+	SetSecret("secret")
+
+	challenge, err := GenerateChallengeJWT(1, "admin", data.RAdmin, 0, PurposeMFALogin)
+	require.NoError(t, err)
+
+	// challenge token can not be used as access token
+	_, err = ValidateJWT(challenge)
+	assert.Error(t, err)
+
+	_, err = ValidateChallengeJWT(challenge, PurposeStepUp)
+	assert.Error(t, err)
+
+	claims, err := ValidateChallengeJWT(challenge, PurposeMFALogin)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claims.UserID)
+
+	// and access token is not a challenge token
+	access, err := GenerateJWT(1, "admin", data.RAdmin, 0)
+	require.NoError(t, err)
+	_, err = ValidateChallengeJWT(access, PurposeMFALogin)
+	assert.Error(t, err)
+
+	_, err = GenerateChallengeJWT(1, "admin", data.RAdmin, 0, "")
+	assert.Error(t, err)
+}
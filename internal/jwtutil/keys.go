@@ -0,0 +1,179 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256
+const minRSABits = 2048
+
+// verifyKey is a public key that can verify tokens with a given kid
+type verifyKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// KeySet holds the key used to sign new tokens and every key still accepted when verifying.
+// A rotation adds a new key, makes it active and keeps the old public key until the
+// tokens signed with it are expired.
+type KeySet struct {
+	activeKid  string
+	method     jwt.SigningMethod
+	signingKey interface{}
+	verifyKeys map[string]verifyKey
+
+	// secret is only set for the legacy HS256 mode, it is never published on JWKS
+	secret []byte
+}
+
+// NewHMACKeySet keeps the legacy behaviour: HS256 with a shared secret and no kid
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		method:     jwt.SigningMethodHS256,
+		secret:     []byte(secret),
+		signingKey: []byte(secret),
+	}
+}
+
+// LoadKeySet reads every *.pem file of dir, the file name without extension is the kid.
+// A file may hold a private key (PKCS#8, or PKCS#1 for RSA) or only a public key (PKIX).
+// The active kid must be a private key, RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func LoadKeySet(dir string, activeKid string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.pem key found in %s", dir)
+	}
+
+	ks := &KeySet{
+		activeKid:  activeKid,
+		verifyKeys: make(map[string]verifyKey),
+	}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		private, public, err := parsePEMKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		method, err := methodForKey(public)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		ks.verifyKeys[kid] = verifyKey{method: method, key: public}
+
+		if kid == activeKid {
+			if private == nil {
+				return nil, fmt.Errorf("active key %s has no private key", kid)
+			}
+			ks.method = method
+			ks.signingKey = private
+		}
+	}
+
+	if ks.signingKey == nil {
+		return nil, fmt.Errorf("active key %s not found in %s", activeKid, dir)
+	}
+
+	return ks, nil
+}
+
+// parsePEMKey returns the private key (nil for public key file) and its public key
+func parsePEMKey(raw []byte) (crypto.Signer, crypto.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, nil, errors.New("invalid PEM")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.New("unsupported private key")
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, key.Public(), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+}
+
+func methodForKey(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+}
+
+// kids returns the verification key ids in stable order
+func (ks *KeySet) kids() []string {
+	kids := make([]string, 0, len(ks.verifyKeys))
+	for kid := range ks.verifyKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+// keyFunc picks the verification key by the kid header, the algorithm must be the one of that key
+// so a public key can never be used as HMAC secret
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.secret != nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.key, nil
+}
@@ -0,0 +1,316 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config of one identity provider (Google Workspace, Keycloak realm, ...)
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // empty for public client, PKCE protects the code exchange
+	RedirectURL  string
+	Scopes       []string
+
+	// CacheTTL of discovery document and JWKS, default 1 hour
+	CacheTTL   time.Duration
+	HTTPClient *http.Client
+}
+
+// minKeyRefresh limits JWKS refetch when a token comes with unknown kid, so garbage tokens can not flood the provider
+const minKeyRefresh = time.Minute
+
+// clockSkew tolerated on exp / iat / nbf of the ID token
+const clockSkew = time.Minute
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID Connect provider with authorization code + PKCE flow
+type Provider struct {
+	cfg Config
+
+	mu            sync.Mutex
+	discovery     *discovery
+	discoveredAt  time.Time
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(cfg Config) *Provider {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &Provider{cfg: cfg}
+}
+
+// Tokens is the answer of the token endpoint
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// IDClaims is the verified content of the ID token
+type IDClaims struct {
+	Subject string
+	Email   string
+
+	// EmailVerified is nil when the provider does not send the claim
+	EmailVerified *bool
+
+	raw map[string]interface{}
+}
+
+// Strings returns a claim holding a list of string (eg: groups), a single string is returned as one item
+func (c *IDClaims) Strings(claim string) []string {
+	switch v := c.raw[claim].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// NewRandom returns a random url safe value for state, nonce and PKCE code verifier (43 chars)
+func NewRandom() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEChallenge is the S256 code challenge of a code verifier (RFC 7636)
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < p.cfg.CacheTTL {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing endpoint")
+	}
+
+	p.discovery = &d
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// AuthCodeURL is where the browser is sent to login on the provider
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PKCEChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (*Tokens, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens Tokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("oidc token: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc token: no id_token in response")
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken checks signature (JWKS), issuer, audience, expiry and nonce of the ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*IDClaims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, d, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+
+	// a token issued to several audiences must be for us (authorized party)
+	if azp, ok := claims["azp"].(string); ok && azp != p.cfg.ClientID {
+		return nil, errors.New("oidc id token: azp does not match client id")
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("oidc id token: nonce mismatch")
+	}
+
+	result := &IDClaims{raw: claims}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = &v
+	case string: // some providers send "true" / "false"
+		verified := v == "true"
+		result.EmailVerified = &verified
+	}
+
+	if result.Subject == "" {
+		return nil, errors.New("oidc id token: missing sub")
+	}
+	return result, nil
+}
+
+// key returns the JWKS key of kid, the set is refetched when it is stale or the kid is new (key rotation)
+func (p *Provider) key(ctx context.Context, d *discovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := time.Since(p.keysFetchedAt) >= p.cfg.CacheTTL
+	key, found := p.keys[kid]
+	if found && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(p.keysFetchedAt) < minKeyRefresh {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	var set jwkSet
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys, err := set.publicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	key, found = p.keys[kid]
+	if !found {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
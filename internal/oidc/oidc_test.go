@@ -0,0 +1,324 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider is an in-process OpenID provider: discovery, JWKS, authorize (auto approve) and token endpoint
+type stubProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu            sync.Mutex
+	kid           string
+	key           *rsa.PrivateKey
+	codes         map[string]authRequest
+	claims        jwt.MapClaims // extra claims of the next ID token
+	issuer        string        // discovery issuer, default the server url
+	discoveryHits int
+	jwksHits      int
+	lastTokenForm url.Values
+}
+
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	p := &stubProvider{t: t, codes: map[string]authRequest{}}
+	p.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.discoveryHits++
+		issuer := p.issuer
+		p.mu.Unlock()
+		if issuer == "" {
+			issuer = p.server.URL
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.jwksHits++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": p.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		code, _ := NewRandom()
+		p.mu.Lock()
+		p.codes[code] = authRequest{
+			clientID:      q.Get("client_id"),
+			redirectURI:   q.Get("redirect_uri"),
+			nonce:         q.Get("nonce"),
+			codeChallenge: q.Get("code_challenge"),
+		}
+		p.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.lastTokenForm = r.PostForm
+
+		req, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		if !ok || req.redirectURI != r.PostForm.Get("redirect_uri") || req.codeChallenge != PKCEChallenge(r.PostForm.Get("code_verifier")) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"id_token":     p.signLocked(req.clientID, req.nonce),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *stubProvider) rotateKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(p.t, err)
+	p.mu.Lock()
+	p.kid, p.key = kid, key
+	p.mu.Unlock()
+}
+
+func (p *stubProvider) signLocked(audience string, nonce string) string {
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "stub-subject-1",
+		"aud":            audience,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "gita@example.com",
+		"email_verified": true,
+		"groups":         []string{"payroll-admins", "staff"},
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.key)
+	require.NoError(p.t, err)
+	return signed
+}
+
+func (p *stubProvider) sign(audience string, nonce string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.signLocked(audience, nonce)
+}
+
+func (p *stubProvider) newClient(redirectURL string) *Provider {
+	return NewProvider(Config{
+		Issuer:       p.server.URL,
+		ClientID:     "payroll",
+		ClientSecret: "client-secret",
+		RedirectURL:  redirectURL,
+	})
+}
+
+// authorize follows the authorization url like the browser would and returns the code and state of the callback
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	// the callback of the app is not running, stop at the redirect
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.newClient("http://payroll.local/callback")
+	ctx := context.Background()
+
+	state, _ := NewRandom()
+	nonce, _ := NewRandom()
+	verifier, _ := NewRandom()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email profile", parsed.Query().Get("scope"))
+	assert.Equal(t, PKCEChallenge(verifier), parsed.Query().Get("code_challenge"))
+	assert.Empty(t, parsed.Query().Get("code_verifier"), "the verifier never leaves the server")
+
+	code, gotState := authorize(t, authURL)
+	assert.Equal(t, state, gotState)
+
+	tokens, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", stub.lastTokenForm.Get("client_secret"))
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "stub-subject-1", claims.Subject)
+	assert.Equal(t, "gita@example.com", claims.Email)
+	require.NotNil(t, claims.EmailVerified)
+	assert.True(t, *claims.EmailVerified)
+	assert.Equal(t, []string{"payroll-admins", "staff"}, claims.Strings("groups"))
+	assert.Nil(t, claims.Strings("roles"))
+
+	// the code is single use
+	_, err = provider.Exchange(ctx, code, verifier)
+	assert.Error(t, err)
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.newClient("http://payroll.local/callback")
+	ctx := context.Background()
+
+	verifier, _ := NewRandom()
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+	require.NoError(t, err)
+	code, _ := authorize(t, authURL)
+
+	// an intercepted code is useless without the verifier
+	other, _ := NewRandom()
+	_, err = provider.Exchange(ctx, code, other)
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.newClient("http://payroll.local/callback")
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		aud    string
+		nonce  string
+	}{
+		{name: "nonce mismatch", aud: "payroll", nonce: "other"},
+		{name: "other audience", aud: "other-app", nonce: "nonce"},
+		{name: "other issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, aud: "payroll", nonce: "nonce"},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, aud: "payroll", nonce: "nonce"},
+		{name: "authorized party is another client", claims: jwt.MapClaims{"azp": "other-app"}, aud: "payroll", nonce: "nonce"},
+		{name: "missing subject", claims: jwt.MapClaims{"sub": ""}, aud: "payroll", nonce: "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub.mu.Lock()
+			stub.claims = tt.claims
+			stub.mu.Unlock()
+
+			_, err := provider.VerifyIDToken(ctx, stub.sign(tt.aud, "nonce"), tt.nonce)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("signed by unknown key", func(t *testing.T) {
+		stub.mu.Lock()
+		stub.claims = nil
+		stub.mu.Unlock()
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": stub.server.URL, "sub": "x", "aud": "payroll", "nonce": "nonce",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(other)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, signed, "nonce")
+		assert.Error(t, err)
+	})
+}
+
+func TestDiscoveryAndJWKSCached(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.newClient("http://payroll.local/callback")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.VerifyIDToken(ctx, stub.sign("payroll", "nonce"), "nonce")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, stub.discoveryHits)
+	assert.Equal(t, 1, stub.jwksHits)
+
+	// the provider rotates its key, the new kid triggers one refetch once the refresh limit passed
+	stub.rotateKey("key-2")
+	_, err := provider.VerifyIDToken(ctx, stub.sign("payroll", "nonce"), "nonce")
+	assert.Error(t, err, "refetch is rate limited")
+	assert.Equal(t, 1, stub.jwksHits)
+
+	provider.mu.Lock()
+	provider.keysFetchedAt = provider.keysFetchedAt.Add(-minKeyRefresh)
+	provider.mu.Unlock()
+
+	_, err = provider.VerifyIDToken(ctx, stub.sign("payroll", "nonce"), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stub.jwksHits)
+	assert.Equal(t, 1, stub.discoveryHits)
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	stub := newStubProvider(t)
+	stub.issuer = "https://evil.example.com"
+	provider := stub.newClient("http://payroll.local/callback")
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	assert.Error(t, err)
+}
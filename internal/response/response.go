@@ -3,10 +3,14 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
 )
 
 type ApiResponse struct {
 	Success bool        `json:"success"`
+	Code    string      `json:"code,omitempty"` // stable code of a failure, eg: PAYROLL_LOCKED
 	Msg     string      `json:"msg"`
 	Trace   string      `json:"trace"`
 	Data    interface{} `json:"data"`
@@ -23,3 +27,48 @@ func WriteJSON(w http.ResponseWriter, statusCode int, trace string, success bool
 	}
 	json.NewEncoder(w).Encode(resp)
 }
+
+// Error answers the failure msg of a handler or middleware with the code and status of its apperror catalog
+// entry, a message outside the catalog is an INTERNAL
+func Error(w http.ResponseWriter, r *http.Request, msg string) {
+	ErrorData(w, r, msg, nil)
+}
+
+// ErrorData is Error with the details of the failure in data
+func ErrorData(w http.ResponseWriter, r *http.Request, msg string, data interface{}) {
+	appErr, ok := apperror.Lookup(msg)
+	if !ok {
+		appErr = apperror.Internal()
+	}
+	FailData(w, r, appErr, data)
+}
+
+// Fail answers the failure of a service (out.Err) with the status of its code, the message is in the
+// language of the Accept-Language of the request. A failure without error is an INTERNAL.
+func Fail(w http.ResponseWriter, r *http.Request, appErr *apperror.Error) {
+	FailData(w, r, appErr, nil)
+}
+
+// FailData is Fail with the details of the failure in data, eg: the employees missing tax data
+func FailData(w http.ResponseWriter, r *http.Request, appErr *apperror.Error, data interface{}) {
+	if appErr == nil {
+		appErr = apperror.Internal()
+	}
+	lang := apperror.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+
+	resp := ApiResponse{
+		Success: false,
+		Code:    string(appErr.Code),
+		Msg:     appErr.Message(lang),
+		Data:    data,
+	}
+	if trace, ok := contextutil.GetTrace(r.Context()); ok {
+		resp.Trace = trace.TraceID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", string(lang))
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(appErr.Status())
+	json.NewEncoder(w).Encode(resp)
+}
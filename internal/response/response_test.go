@@ -0,0 +1,104 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	scenarios := map[string]struct {
+		msg            string
+		acceptLanguage string
+		status         int
+		expected       ApiResponse
+	}{
+		"payroll locked in indonesian": {
+			"Data tidak bisa diubah karena payroll sudah dijalankan", "", http.StatusConflict,
+			ApiResponse{Code: "PAYROLL_LOCKED", Msg: "Data tidak bisa diubah karena payroll sudah dijalankan", Trace: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		"payroll locked in english": {
+			"Data tidak bisa diubah karena payroll sudah dijalankan", "en-US,en;q=0.9", http.StatusConflict,
+			ApiResponse{Code: "PAYROLL_LOCKED", Msg: "Data cannot be changed because the payroll has been run", Trace: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		"internal error is not a bad request": {
+			"internal error", "id-ID", http.StatusInternalServerError,
+			ApiResponse{Code: "INTERNAL", Msg: "internal error", Trace: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		"english message of a handler in indonesian": {
+			"Invalid request body", "id", http.StatusBadRequest,
+			ApiResponse{Code: "INVALID_INPUT", Msg: "Body request tidak valid", Trace: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/timeclock/attendance", nil)
+			req.Header.Set("Accept-Language", sc.acceptLanguage)
+			req = req.WithContext(contextutil.WithTrace(req.Context(), &contextutil.Trace{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}))
+			rec := httptest.NewRecorder()
+			Error(rec, req, sc.msg)
+
+			assert.Equal(t, sc.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var resp ApiResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, sc.expected, resp)
+		})
+	}
+}
+
+func TestErrorData(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/tax/bupot", nil)
+	req.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	ErrorData(rec, req, "Data pajak karyawan belum lengkap", []string{"user 7: NPWP"})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "en-US", rec.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"success":false,"code":"TAX_DATA_INCOMPLETE","msg":"Tax data of the employee is incomplete","trace":"","data":["user 7: NPWP"]}`, rec.Body.String())
+}
+
+func TestFail(t *testing.T) {
+	scenarios := map[string]struct {
+		appErr   *apperror.Error
+		status   int
+		expected string
+	}{
+		"code of the service": {
+			apperror.New(apperror.CodeLoanNotFound, "Pinjaman tidak ditemukan"), http.StatusNotFound,
+			`{"success":false,"code":"LOAN_NOT_FOUND","msg":"Loan not found","trace":"","data":null}`,
+		},
+		"failure without error is internal": {
+			nil, http.StatusInternalServerError,
+			`{"success":false,"code":"INTERNAL","msg":"internal error","trace":"","data":null}`,
+		},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/loan/1", nil)
+			req.Header.Set("Accept-Language", "en")
+			rec := httptest.NewRecorder()
+			Fail(rec, req, sc.appErr)
+
+			assert.Equal(t, sc.status, rec.Code)
+			assert.JSONEq(t, sc.expected, rec.Body.String())
+		})
+	}
+}
+
+func TestErrorUnknownMessage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/loan/1", nil)
+	rec := httptest.NewRecorder()
+	Error(rec, req, "")
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"success":false,"code":"INTERNAL","msg":"internal error","trace":"","data":null}`, rec.Body.String())
+}
@@ -0,0 +1,170 @@
+// Package apperror gives the failures of the services a stable code and HTTP status, and their message
+// in the languages of the clients. A service fails with the code and the Indonesian message on the Failure
+// of its Out, the catalog gives the message its translation: clients branch on the code (eg: PAYROLL_LOCKED)
+// and not on the text, which may change.
+package apperror
+
+import "net/http"
+
+type Code string
+
+const (
+	CodeInternal         Code = "INTERNAL"
+	CodeUnauthorized     Code = "UNAUTHORIZED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeInvalidInput     Code = "INVALID_INPUT"
+	CodeConflict         Code = "CONFLICT"
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+	CodeTooManyRequests  Code = "TOO_MANY_REQUESTS"
+
+	// auth
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeTokenInvalid       Code = "TOKEN_INVALID"
+	CodeTokenExpired       Code = "TOKEN_EXPIRED"
+	CodeTokenRevoked       Code = "TOKEN_REVOKED"
+	CodeScopeMissing       Code = "SCOPE_MISSING"
+	CodeStepUpRequired     Code = "STEP_UP_REQUIRED"
+	CodeMFAInvalidCode     Code = "MFA_INVALID_CODE"
+	CodeMFAEnabled         Code = "MFA_ALREADY_ENABLED"
+	CodeMFANotEnabled      Code = "MFA_NOT_ENABLED"
+	CodeSSOFailed          Code = "SSO_FAILED"
+	CodeSSOUnavailable     Code = "SSO_UNAVAILABLE"
+	CodePasswordPolicy     Code = "PASSWORD_POLICY"
+
+	// user
+	CodeUserNotFound Code = "USER_NOT_FOUND"
+	CodeUserInactive Code = "USER_INACTIVE"
+	CodeUserExists   Code = "USER_EXISTS"
+
+	// timeclock
+	CodeAttendanceDuplicate  Code = "ATTENDANCE_DUPLICATE"
+	CodeAttendanceWeekend    Code = "ATTENDANCE_WEEKEND"
+	CodeAttendanceNotChecked Code = "ATTENDANCE_NOT_CHECKED_IN"
+	CodePayrollLocked        Code = "PAYROLL_LOCKED"
+	CodePayrollNotFound      Code = "PAYROLL_NOT_FOUND"
+	CodeNoEligibleEmployee   Code = "NO_ELIGIBLE_EMPLOYEE"
+	CodeTHRDeadline          Code = "THR_DEADLINE_PASSED"
+
+	// loan, tax, accounting, webhook
+	CodeLoanNotFound      Code = "LOAN_NOT_FOUND"
+	CodeLoanProcessed     Code = "LOAN_ALREADY_PROCESSED"
+	CodeLoanNotActive     Code = "LOAN_NOT_ACTIVE"
+	CodeTaxDataIncomplete Code = "TAX_DATA_INCOMPLETE"
+	CodeJournalUnbalanced Code = "JOURNAL_UNBALANCED"
+	CodeJournalIncomplete Code = "JOURNAL_ITEMS_INCOMPLETE"
+	CodeWebhookNotFound   Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound  Code = "WEBHOOK_DELIVERY_NOT_FOUND"
+)
+
+var statuses = map[Code]int{
+	CodeInternal:         http.StatusInternalServerError,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeInvalidInput:     http.StatusBadRequest,
+	CodeConflict:         http.StatusConflict,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	CodeTooManyRequests:  http.StatusTooManyRequests,
+
+	CodeInvalidCredentials: http.StatusUnauthorized,
+	CodeTokenInvalid:       http.StatusUnauthorized,
+	CodeTokenExpired:       http.StatusUnauthorized,
+	CodeTokenRevoked:       http.StatusUnauthorized,
+	CodeScopeMissing:       http.StatusForbidden,
+	CodeStepUpRequired:     http.StatusForbidden,
+	CodeMFAInvalidCode:     http.StatusUnprocessableEntity, // not 401, the session of a step-up stays valid
+	CodeMFAEnabled:         http.StatusConflict,
+	CodeMFANotEnabled:      http.StatusConflict,
+	CodeSSOFailed:          http.StatusUnauthorized,
+	CodeSSOUnavailable:     http.StatusBadGateway,
+	CodePasswordPolicy:     http.StatusUnprocessableEntity,
+
+	CodeUserNotFound: http.StatusNotFound,
+	CodeUserInactive: http.StatusForbidden,
+	CodeUserExists:   http.StatusConflict,
+
+	CodeAttendanceDuplicate:  http.StatusConflict,
+	CodeAttendanceWeekend:    http.StatusUnprocessableEntity,
+	CodeAttendanceNotChecked: http.StatusConflict,
+	CodePayrollLocked:        http.StatusConflict,
+	CodePayrollNotFound:      http.StatusNotFound,
+	CodeNoEligibleEmployee:   http.StatusUnprocessableEntity,
+	CodeTHRDeadline:          http.StatusUnprocessableEntity,
+
+	CodeLoanNotFound:      http.StatusNotFound,
+	CodeLoanProcessed:     http.StatusConflict,
+	CodeLoanNotActive:     http.StatusConflict,
+	CodeTaxDataIncomplete: http.StatusUnprocessableEntity,
+	CodeJournalUnbalanced: http.StatusUnprocessableEntity,
+	CodeJournalIncomplete: http.StatusUnprocessableEntity,
+	CodeWebhookNotFound:   http.StatusNotFound,
+	CodeDeliveryNotFound:  http.StatusNotFound,
+}
+
+// Status is the HTTP status of the code, 500 for a code without one
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a failure resolved from the catalog
+type Error struct {
+	Code Code
+	ID   string // id-ID
+	EN   string // en-US
+}
+
+func (e *Error) Error() string {
+	return e.Message(LangID)
+}
+
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// Message is the message in lang, id-ID for any other language
+func (e *Error) Message(lang Lang) string {
+	if lang == LangEN && e.EN != "" {
+		return e.EN
+	}
+	return e.ID
+}
+
+// Internal is the failure of an unexpected error (database, commit, ...), its cause is only logged
+func Internal() *Error {
+	return New(CodeInternal, "internal error")
+}
+
+// New is the failure of code with the id-ID msg, its en-US text is from the catalog
+func New(code Code, msg string) *Error {
+	e, _ := Lookup(msg)
+	return &Error{Code: code, ID: e.ID, EN: e.EN}
+}
+
+// Failure is embedded in the Out of every service method, Err is nil until the method fails
+type Failure struct {
+	Message string // id-ID message of Err, what the logs and the CLI show
+	Err     *Error `json:"-"`
+}
+
+// Failed is the Failure of code with the id-ID msg, eg: the Out returned by a mock
+func Failed(code Code, msg string) Failure {
+	var f Failure
+	f.Fail(code, msg)
+	return f
+}
+
+// Fail records the failure of code with the id-ID msg
+func (f *Failure) Fail(code Code, msg string) {
+	f.Err = New(code, msg)
+	f.Message = f.Err.ID
+}
+
+// FailWith records err, eg: the failure returned by a helper of the service
+func (f *Failure) FailWith(err *Error) {
+	f.Err = err
+	f.Message = err.ID
+}
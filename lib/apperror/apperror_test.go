@@ -0,0 +1,112 @@
+package apperror
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	scenarios := map[string]struct {
+		msg    string
+		found  bool
+		code   Code
+		status int
+		id     string
+		en     string
+	}{
+		"payroll locked": {
+			"Data tidak bisa diubah karena payroll sudah dijalankan", true, CodePayrollLocked, http.StatusConflict,
+			"Data tidak bisa diubah karena payroll sudah dijalankan", "Data cannot be changed because the payroll has been run",
+		},
+		"attendance duplicate": {
+			"Terjadi kesalahan, kemungkinan anda telah tercatat di hari ini", true, CodeAttendanceDuplicate, http.StatusConflict,
+			"Terjadi kesalahan, kemungkinan anda telah tercatat di hari ini", "Attendance for today has already been recorded",
+		},
+		"internal":                 {"internal error", true, CodeInternal, http.StatusInternalServerError, "internal error", "internal error"},
+		"commit error is internal": {"commit error", true, CodeInternal, http.StatusInternalServerError, "commit error", "internal error"},
+		"forbidden": {
+			"forbidden: Hanya admin yang bisa akses", true, CodeForbidden, http.StatusForbidden,
+			"forbidden: Hanya admin yang bisa akses", "forbidden: admin only",
+		},
+		"english message of a handler": {
+			"Invalid request body", true, CodeInvalidInput, http.StatusBadRequest,
+			"Body request tidak valid", "Invalid request body",
+		},
+		"english message shared with a service": {
+			"Invalid username or password", true, CodeInvalidCredentials, http.StatusUnauthorized,
+			"Username atau password tidak valid", "Invalid username or password",
+		},
+		"with a value": {
+			"Param 'loanId' harus angka", true, CodeInvalidInput, http.StatusBadRequest,
+			"Param 'loanId' harus angka", "Param 'loanId' must be a number",
+		},
+		"with a number": {
+			"Password minimal 12 karakter", true, CodePasswordPolicy, http.StatusUnprocessableEntity,
+			"Password minimal 12 karakter", "Password must be at least 12 characters",
+		},
+		"english with a value": {
+			"Token does not have scope payroll:run", true, CodeScopeMissing, http.StatusForbidden,
+			"Token tidak punya scope payroll:run", "Token does not have scope payroll:run",
+		},
+		"unknown is internal": {"Sesuatu yang baru", false, CodeInternal, http.StatusInternalServerError, "Sesuatu yang baru", "Sesuatu yang baru"},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			appErr, found := Lookup(sc.msg)
+			assert.Equal(t, sc.found, found)
+			assert.Equal(t, sc.code, appErr.Code)
+			assert.Equal(t, sc.status, appErr.Status())
+			assert.Equal(t, sc.id, appErr.Message(LangID))
+			assert.Equal(t, sc.en, appErr.Message(LangEN))
+		})
+	}
+}
+
+func TestFailure(t *testing.T) {
+	var f Failure
+	f.Fail(CodeLoanNotActive, "Pinjaman tidak aktif")
+
+	assert.Equal(t, CodeLoanNotActive, f.Err.Code)
+	assert.Equal(t, http.StatusConflict, f.Err.Status())
+	assert.Equal(t, "Pinjaman tidak aktif", f.Message)
+	assert.Equal(t, "Loan is not active", f.Err.Message(LangEN))
+
+	// the code is the one of the service, the catalog only translates
+	assert.Equal(t, CodeInvalidInput, New(CodeInvalidInput, "User tidak aktif").Code)
+	assert.Equal(t, http.StatusInternalServerError, Code("SOMETHING_NEW").Status())
+}
+
+func TestCodesHaveStatus(t *testing.T) {
+	for _, e := range catalog {
+		_, ok := statuses[e.code]
+		assert.True(t, ok, "%s has no status", e.code)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	scenarios := map[string]Lang{
+		"":                           LangID,
+		"en-US":                      LangEN,
+		"en":                         LangEN,
+		"EN-gb":                      LangEN,
+		"id-ID":                      LangID,
+		"in":                         LangID,
+		"en-US,en;q=0.9,id;q=0.8":    LangEN,
+		"id;q=0.5, en;q=0.4":         LangID,
+		"fr-FR, en;q=0.7":            LangEN,
+		"fr-FR, de":                  LangID,
+		"en;q=0, id;q=0.1":           LangID,
+		"en;q=abc":                   LangID,
+		"*":                          LangID,
+		"de-DE;q=1, en-US;q=0.5, id": LangID,
+	}
+
+	for header, expected := range scenarios {
+		t.Run(header, func(t *testing.T) {
+			assert.Equal(t, expected, ParseAcceptLanguage(header))
+		})
+	}
+}
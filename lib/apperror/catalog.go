@@ -0,0 +1,249 @@
+package apperror
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// entry is a message of the catalog, a message with verbs (eg: %d) matches any value in their place
+type entry struct {
+	code Code
+	id   string
+	en   string
+}
+
+// catalog lists every failure message of the services, the handlers and the middleware. A message is
+// written either in Indonesian (the services) or in English (the older handlers), it is found by both.
+// A message missing here is answered as INTERNAL untranslated, TestCatalogCoversMessages fails on it.
+var catalog = []entry{
+	// generic
+	{CodeInternal, "internal error", "internal error"},
+	{CodeInternal, "commit error", "internal error"},
+	{CodeInternal, "Trace tidak ditemukan", "Trace not found"},
+	{CodeUnauthorized, "unauthorized", "unauthorized"},
+	{CodeForbidden, "forbidden: Hanya admin yang bisa akses", "forbidden: admin only"},
+	{CodeForbidden, "forbidden: Hanya bisa lewat login", "forbidden: login session only"},
+	{CodeNotFound, "Route tidak ditemukan", "Route not found"},
+	{CodeMethodNotAllowed, "Method tidak diizinkan", "Method not allowed"},
+	{CodeInvalidInput, "Body request tidak valid", "Invalid request body"},
+	{CodeInvalidInput, "Request tidak sesuai spesifikasi API", "Request does not match the API specification"},
+	{CodeInvalidInput, "Param '%s' harus angka", "Param '%s' must be a number"},
+	{CodeInvalidInput, "Param 'format' harus json atau csv", "Param 'format' must be json or csv"},
+	{CodeInvalidInput, "Param 'format' harus json atau pdf", "Param 'format' must be json or pdf"},
+	{CodeInvalidInput, "Param '%s' harus tanggal (YYYY-MM-DD) atau RFC3339", "Param '%s' must be a date (YYYY-MM-DD) or RFC3339"},
+	{CodeInvalidInput, "Query param 'month' dan 'year' wajib diisi", "Query params 'month' and 'year' are required"},
+	{CodeInvalidInput, "Limit tidak valid", "Invalid limit"},
+	{CodeInvalidInput, "Limit atau offset tidak valid", "Invalid limit or offset"},
+	{CodeInvalidInput, "Status tidak valid", "Invalid status"},
+	{CodeInvalidInput, "Rentang waktu tidak valid", "Invalid time range"},
+	{CodeInvalidInput, "Action dan entity wajib diisi", "Action and entity are required"},
+
+	// auth
+	{CodeUnauthorized, "Header Authorization tidak ada", "Missing Authorization header"},
+	{CodeUnauthorized, "Format header Authorization tidak valid", "Invalid Authorization header format"},
+	{CodeTokenInvalid, "Token tidak valid atau kedaluwarsa", "Invalid or expired token"},
+	{CodeTokenInvalid, "Token tidak valid, kedaluwarsa atau sudah dicabut", "Invalid, expired or revoked token"},
+	{CodeTokenRevoked, "Sesi sudah dicabut", "Session has been revoked"},
+	{CodeTokenRevoked, "Sesi sudah logout", "Session has been logged out"},
+	{CodeStepUpRequired, "Wajib verifikasi step-up", "Step-up authentication required"},
+	{CodeStepUpRequired, "Token step-up tidak valid atau kedaluwarsa", "Invalid or expired step-up token"},
+	{CodeScopeMissing, "Token tidak punya scope %s", "Token does not have scope %s"},
+	{CodeInvalidCredentials, "Username atau password tidak valid", "Invalid username or password"},
+	{CodeInvalidCredentials, "Password lama tidak valid", "Current password is invalid"},
+	{CodeInvalidInput, "username tidak boleh kosong", "username is required"},
+	{CodeInvalidInput, "password tidak boleh kosong", "password is required"},
+	{CodeInvalidInput, "username atau email tidak boleh kosong", "username or email is required"},
+	{CodeTooManyRequests, "Terlalu banyak percobaan login", "Too many login attempts"},
+	{CodeTooManyRequests, "Terlalu banyak percobaan login, coba lagi nanti", "Too many login attempts, try again later"},
+	{CodeTooManyRequests, "Terlalu banyak percobaan, coba lagi nanti", "Too many attempts, try again later"},
+	{CodeTokenInvalid, "Refresh token tidak valid", "Invalid refresh token"},
+	{CodeTokenExpired, "Refresh token kedaluwarsa", "Refresh token has expired"},
+	{CodeTokenInvalid, "Token reset tidak valid atau kedaluwarsa", "Invalid or expired reset token"},
+	{CodeTokenInvalid, "Challenge token tidak valid", "Invalid challenge token"},
+	{CodeInvalidInput, "Kode OTP wajib diisi", "OTP code is required"},
+	{CodeMFAInvalidCode, "Kode OTP tidak valid", "Invalid OTP code"},
+	{CodeMFAInvalidCode, "Kode OTP sudah dipakai", "OTP code has already been used"},
+	{CodeMFAInvalidCode, "Recovery code tidak valid", "Invalid recovery code"},
+	{CodeMFANotEnabled, "MFA belum didaftarkan", "MFA is not enrolled"},
+	{CodeMFANotEnabled, "MFA belum aktif", "MFA is not enabled"},
+	{CodeMFAEnabled, "MFA sudah aktif", "MFA is already enabled"},
+	{CodePasswordPolicy, "Password minimal %d karakter", "Password must be at least %s characters"},
+	{CodePasswordPolicy, "Password maksimal %d karakter", "Password must be at most %s characters"},
+	{CodePasswordPolicy, "Password tidak boleh sama dengan username", "Password must not be the same as the username"},
+	{CodePasswordPolicy, "Password terlalu umum, pernah bocor di internet", "Password is too common, it has been leaked on the internet"},
+	{CodePasswordPolicy, "Password baru tidak boleh sama dengan password lama", "New password must differ from the current password"},
+	{CodeSSOUnavailable, "Provider SSO tidak bisa dihubungi", "SSO provider is unreachable"},
+	{CodeNotFound, "Provider SSO tidak dikenal", "Unknown SSO provider"},
+	{CodeSSOFailed, "Login SSO tidak valid atau kedaluwarsa", "Invalid or expired SSO login"},
+	{CodeSSOFailed, "Login SSO gagal", "SSO login failed"},
+	{CodeSSOFailed, "Login SSO dibatalkan: %s", "SSO login cancelled: %s"},
+	{CodeSSOFailed, "Email akun SSO belum terverifikasi", "Email of the SSO account is not verified"},
+	{CodeForbidden, "Akun belum terdaftar di payroll", "Account is not registered in payroll"},
+	{CodeConflict, "User sudah terhubung dengan akun SSO lain", "User is already linked to another SSO account"},
+	{CodeInvalidInput, "Nama token wajib diisi, maksimal 100 karakter", "Token name is required, at most 100 characters"},
+	{CodeInvalidInput, "Masa berlaku token 1 sampai %d hari", "Token lifetime must be 1 to %s days"},
+	{CodeInvalidInput, "Scope token tidak boleh kosong", "Token scopes are required"},
+	{CodeInvalidInput, "Scope %q tidak dikenal", "Unknown scope %s"},
+	{CodeNotFound, "Token tidak ditemukan", "Token not found"},
+	{CodeTokenInvalid, "Token tidak valid", "Invalid token"},
+	{CodeTokenRevoked, "Token sudah dicabut", "Token has been revoked"},
+	{CodeTokenExpired, "Token kedaluwarsa", "Token has expired"},
+	{CodeInvalidInput, "Nama service account wajib diisi, maksimal 50 karakter", "Service account name is required, at most 50 characters"},
+	{CodeInvalidInput, "Deskripsi maksimal 255 karakter", "Description must be at most 255 characters"},
+	{CodeInvalidInput, "Role harus admin atau employee", "Role must be admin or employee"},
+	{CodeUserExists, "Nama service account sudah dipakai", "Service account name is already taken"},
+	{CodeNotFound, "Service account tidak ditemukan", "Service account not found"},
+
+	// user
+	{CodeUserNotFound, "User tidak ditemukan", "User not found"},
+	{CodeUserNotFound, "Tidak ada data user ditemukan", "No user found"},
+	{CodeUserInactive, "User tidak aktif", "User is inactive"},
+	{CodeUserExists, "Username atau email sudah dipakai", "Username or email is already taken"},
+	{CodeInvalidInput, "Nama lengkap wajib diisi, maksimal 100 karakter", "Full name is required, at most 100 characters"},
+	{CodeInvalidInput, "Username harus 5-20 karakter huruf, angka atau underscore", "Username must be 5-20 letters, digits or underscores"},
+	{CodeInvalidInput, "Email tidak valid", "Invalid email"},
+	{CodeInvalidInput, "Gaji pokok harus lebih dari 0", "Base salary must be greater than 0"},
+	{CodeInvalidInput, "Tanggal bergabung wajib diisi", "Join date is required"},
+	{CodeInvalidInput, "Agama tidak valid", "Invalid religion"},
+	{CodeInvalidInput, "Status PTKP tidak valid", "Invalid PTKP status"},
+	{CodeInvalidInput, "NPWP harus 15 atau 16 digit angka", "NPWP must be 15 or 16 digits"},
+	{CodeInvalidInput, "NIK harus 16 digit angka", "NIK must be 16 digits"},
+	{CodeInvalidInput, "Kode bank harus 3 digit angka", "Bank code must be 3 digits"},
+	{CodeInvalidInput, "Nomor rekening harus 5-20 digit angka", "Account number must be 5-20 digits"},
+	{CodeInvalidInput, "Nama pemilik rekening wajib diisi, maksimal 100 karakter", "Account holder name is required, at most 100 characters"},
+
+	// timeclock
+	{CodeInvalidInput, "Format periode tidak valid, harus YYYY-MM-DD", "Invalid period format, must be YYYY-MM-DD"},
+	{CodeInvalidInput, "Format tanggal mulai tidak valid", "Invalid start date format"},
+	{CodeInvalidInput, "Format tanggal selesai tidak valid", "Invalid end date format"},
+	{CodeInvalidInput, "Format tanggal pembayaran tidak valid", "Invalid pay date format"},
+	{CodeInvalidInput, "Format tanggal hari raya tidak valid", "Invalid holiday date format"},
+	{CodeInvalidInput, "User id tidak valid di amounts", "Invalid user id in amounts"},
+	{CodeInvalidInput, "Wajib pilih periode waktu checkin", "Checkin period is required"},
+	{CodeInvalidInput, "Checkin date wajib diisi", "Checkin date is required"},
+	{CodeInvalidInput, "Wajib pilih periode waktu overtime", "Overtime period is required"},
+	{CodeInvalidInput, "Tanggal wajib diisi", "Date is required"},
+	{CodeInvalidInput, "Periode wajib diisi", "Period is required"},
+	{CodeInvalidInput, "Periode tidak valid", "Invalid period"},
+	{CodeInvalidInput, "Bulan atau tahun tidak valid", "Invalid month or year"},
+	{CodeInvalidInput, "Tahun tidak valid", "Invalid year"},
+	{CodeAttendanceWeekend, "Tidak bisa mengisi kehadiran saat Sabtu dan Minggu.", "Attendance cannot be submitted on Saturday and Sunday."},
+	{CodeAttendanceDuplicate, "Terjadi kesalahan, kemungkinan anda telah tercatat di hari ini", "Attendance for today has already been recorded"},
+	{CodeAttendanceNotChecked, "Anda belum absen di hari tersebut", "You have no attendance on that day"},
+	{CodeAttendanceNotChecked, "Anda belum check-in atau sudah checkout", "You have not checked in or have already checked out"},
+	{CodeInvalidInput, "Jumlah jam lembur tidak boleh lebih dari 3", "Overtime must not exceed 3 hours"},
+	{CodeInvalidInput, "Format tanggal lembur tidak valid", "Invalid overtime date format"},
+	{CodeInvalidInput, "Tanggal lembur tidak boleh setelah hari ini", "Overtime date must not be after today"},
+	{CodeInvalidInput, "Alasan harus diisi", "Reason is required"},
+	{CodeInvalidInput, "Lembur hanya bisa diajukan setelah jam kerja selesai", "Overtime can only be submitted after working hours"},
+	{CodeInvalidInput, "Jumlah reimbursement harus lebih dari 0", "Reimbursement amount must be greater than 0"},
+	{CodeInvalidInput, "Deskripsi reimbursement wajib diisi", "Reimbursement description is required"},
+	{CodePayrollLocked, "Data tidak bisa diubah karena payroll sudah dijalankan", "Data cannot be changed because the payroll has been run"},
+	{CodeUserNotFound, "tidak ditemukan employee", "no employee found"},
+	{CodeNoEligibleEmployee, "Tidak ada karyawan yang bisa diproses pada payroll ini", "No employee can be processed in this payroll"},
+	{CodeNoEligibleEmployee, "Tidak ada karyawan yang berhak menerima THR", "No employee is eligible for THR"},
+	{CodeInvalidInput, "Tipe payroll tidak valid", "Invalid payroll type"},
+	{CodeInvalidInput, "Karyawan wajib dipilih untuk final settlement", "An employee is required for a final settlement"},
+	{CodeInvalidInput, "Karyawan tidak boleh dipilih lebih dari sekali", "An employee must not be selected more than once"},
+	{CodeInvalidInput, "Nominal per karyawan wajib diisi", "Amount per employee is required"},
+	{CodeInvalidInput, "Nominal bonus harus lebih dari 0", "Bonus amount must be greater than 0"},
+	{CodeInvalidInput, "Nominal koreksi tidak boleh 0", "Correction amount must not be 0"},
+	{CodeInvalidInput, "Catatan wajib diisi untuk payroll off-cycle", "A note is required for an off-cycle payroll"},
+	{CodeInvalidInput, "Tanggal pembayaran dan hari raya wajib diisi", "Pay date and holiday date are required"},
+	{CodeTHRDeadline, "THR wajib dibayar paling lambat 7 hari sebelum hari raya", "THR must be paid at least 7 days before the holiday"},
+	{CodeInvalidInput, "Payroll tidak valid", "Invalid payroll"},
+	{CodePayrollNotFound, "Payroll tidak ditemukan", "Payroll not found"},
+	{CodePayrollNotFound, "Payroll belum tersedia untuk periode ini", "Payroll is not available for this period yet"},
+	{CodePayrollNotFound, "THR belum tersedia untuk tahun ini", "THR is not available for this year yet"},
+	{CodePayrollNotFound, "Data payslip tidak tersedia", "Payslip is not available"},
+
+	// loan
+	{CodeInvalidInput, "Tipe pinjaman tidak valid", "Invalid loan type"},
+	{CodeInvalidInput, "Nominal pinjaman harus lebih dari 0", "Loan amount must be greater than 0"},
+	{CodeInvalidInput, "Bunga pinjaman tidak valid", "Invalid loan interest"},
+	{CodeInvalidInput, "Tenor pinjaman tidak valid", "Invalid loan tenor"},
+	{CodeInvalidInput, "Kasbon tidak boleh berbunga", "A cash advance must not bear interest"},
+	{CodeInvalidInput, "Kasbon harus lunas dalam 1 kali potong gaji", "A cash advance must be repaid in a single deduction"},
+	{CodeInvalidInput, "Alasan pinjaman wajib diisi", "Loan reason is required"},
+	{CodeInvalidInput, "Alasan penolakan wajib diisi", "Rejection reason is required"},
+	{CodeInvalidInput, "Bulan potongan pertama tidak valid", "Invalid first deduction month"},
+	{CodeInvalidInput, "Status pinjaman tidak valid", "Invalid loan status"},
+	{CodeLoanNotFound, "Pinjaman tidak ditemukan", "Loan not found"},
+	{CodeLoanProcessed, "Pinjaman sudah diproses", "Loan has already been processed"},
+	{CodeLoanNotActive, "Pinjaman tidak aktif", "Loan is not active"},
+
+	// tax
+	{CodePayrollNotFound, "Belum ada payroll di tahun ini", "No payroll in this year yet"},
+	{CodeNotFound, "Bukti potong 1721-A1 belum tersedia", "Form 1721-A1 is not available yet"},
+	{CodeTaxDataIncomplete, "Data pajak karyawan belum lengkap", "Tax data of the employee is incomplete"},
+
+	// accounting
+	{CodeJournalUnbalanced, "Jurnal tidak balance", "Journal is not balanced"},
+	{CodeJournalIncomplete, "Komponen gaji payroll ini tidak tersimpan, jurnal tidak bisa dibuat", "The pay components of this payroll are not stored, its journal can not be built"},
+	{CodeInvalidInput, "Account key tidak valid", "Invalid account key"},
+	{CodeInvalidInput, "Kode dan nama akun wajib diisi", "Account code and name are required"},
+
+	// webhook
+	{CodeInvalidInput, "URL harus http atau https", "URL must be http or https"},
+	{CodeInvalidInput, "Event type wajib diisi", "Event types are required"},
+	{CodeInvalidInput, "Event type %s tidak dikenal", "Unknown event type %s"},
+	{CodeWebhookNotFound, "Webhook tidak ditemukan", "Webhook not found"},
+	{CodeDeliveryNotFound, "Delivery tidak ditemukan", "Delivery not found"},
+}
+
+// pattern matches a message with verbs, its values are put in the %s of en
+type pattern struct {
+	entry
+	re *regexp.Regexp
+}
+
+var (
+	messages = make(map[string]entry, len(catalog)*2)
+	patterns []pattern
+	verb     = regexp.MustCompile(`%[a-z]`)
+)
+
+func init() {
+	for _, e := range catalog {
+		if !verb.MatchString(e.id) {
+			messages[e.id] = e
+			if _, ok := messages[e.en]; !ok { // eg: "internal error" stays the message of itself
+				messages[e.en] = e
+			}
+			continue
+		}
+		for _, format := range []string{e.id, e.en} {
+			parts := verb.Split(format, -1)
+			for i := range parts {
+				parts[i] = regexp.QuoteMeta(parts[i])
+			}
+			patterns = append(patterns, pattern{e, regexp.MustCompile("^" + strings.Join(parts, "(.+)") + "$")})
+		}
+	}
+}
+
+// Lookup resolves a message, in either language, to its Error. A message outside the catalog is an
+// INTERNAL in its own words and false.
+func Lookup(msg string) (*Error, bool) {
+	if e, ok := messages[msg]; ok {
+		return &Error{Code: e.code, ID: e.id, EN: e.en}, true
+	}
+	for _, p := range patterns {
+		match := p.re.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		args := make([]any, 0, len(match)-1)
+		for _, value := range match[1:] {
+			args = append(args, value)
+		}
+		return &Error{Code: p.code, ID: fill(p.id, args), EN: fill(p.en, args)}, true
+	}
+	return &Error{Code: CodeInternal, ID: msg}, false
+}
+
+// fill puts the values in the verbs of format
+func fill(format string, args []any) string {
+	return fmt.Sprintf(verb.ReplaceAllString(format, "%s"), args...)
+}
@@ -0,0 +1,137 @@
+package apperror
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failureMessages collects the messages a package may fail with: what is passed to Fail or apperror.New, what is
+// assigned to msg, what is passed to response.Error or ErrorData, and what is returned by the functions whose result
+// ends up in msg (eg: validateRequestLoan). A fmt.Sprintf gives its format.
+func failureMessages(t *testing.T, dir string) map[string]string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		require.NoError(t, err)
+		parsed = append(parsed, file)
+	}
+
+	found := make(map[string]string)
+	add := func(expr ast.Expr) {
+		if call, ok := expr.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" {
+				expr = call.Args[0]
+			}
+		}
+		if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			msg, _ := strconv.Unquote(lit.Value)
+			found[msg] = fset.Position(lit.Pos()).String()
+		}
+	}
+	calledName := func(expr ast.Expr) string {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return ""
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return fun.Name
+		case *ast.SelectorExpr:
+			return fun.Sel.Name
+		}
+		return ""
+	}
+
+	helpers := make(map[string]bool)
+	for _, file := range parsed {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range x.Lhs {
+					if l, ok := lhs.(*ast.Ident); ok && l.Name == "msg" {
+						rhs := x.Rhs[0] // eg: scopes, msg := normalizeScopes(...)
+						if len(x.Rhs) == len(x.Lhs) {
+							rhs = x.Rhs[i]
+						}
+						add(rhs)
+						if name := calledName(rhs); name != "" {
+							helpers[name] = true
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if sel, ok := x.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Fail" || sel.Sel.Name == "New") && len(x.Args) == 2 {
+					add(x.Args[1])
+				}
+				if sel, ok := x.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Error" || sel.Sel.Name == "ErrorData") && len(x.Args) >= 3 {
+					if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "response" {
+						add(x.Args[2])
+						// eg: "Login SSO dibatalkan: "+providerErr, checked as "Login SSO dibatalkan: %s"
+						if bin, ok := x.Args[2].(*ast.BinaryExpr); ok {
+							if lit, ok := bin.X.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+								prefix, _ := strconv.Unquote(lit.Value)
+								found[prefix+"%s"] = fset.Position(lit.Pos()).String()
+							}
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	for _, file := range parsed {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !helpers[fn.Name.Name] || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if _, ok := n.(*ast.FuncLit); ok {
+					return false
+				}
+				if ret, ok := n.(*ast.ReturnStmt); ok && len(ret.Results) > 0 {
+					add(ret.Results[len(ret.Results)-1])
+				}
+				return true
+			})
+		}
+	}
+	delete(found, "")
+	return found
+}
+
+func TestCatalogCoversMessages(t *testing.T) {
+	dirs, err := filepath.Glob("../../app/*")
+	require.NoError(t, err)
+	dirs = append(dirs, "../middleware")
+
+	total := 0
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		for msg, at := range failureMessages(t, dir) {
+			total++
+			_, ok := Lookup(msg)
+			assert.True(t, ok, "%q of %s is not in the catalog", msg, at)
+		}
+	}
+	assert.Greater(t, total, 150, "the messages are found")
+}
@@ -0,0 +1,46 @@
+package apperror
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Lang is a language the messages are written in
+type Lang string
+
+const (
+	LangID Lang = "id-ID"
+	LangEN Lang = "en-US"
+)
+
+// ParseAcceptLanguage picks the language of an Accept-Language header (eg: "en-US,en;q=0.9,id;q=0.8")
+// by its q value, a tag is matched on its primary subtag. id-ID when nothing matches.
+func ParseAcceptLanguage(header string) Lang {
+	lang, best := LangID, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		var match Lang
+		switch primary {
+		case "id", "in": // "in" is the deprecated code of Indonesian
+			match = LangID
+		case "en":
+			match = LangEN
+		default:
+			continue
+		}
+		if q > best {
+			lang, best = match, q
+		}
+	}
+	return lang
+}
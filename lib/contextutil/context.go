@@ -8,9 +8,11 @@ import (
 type contextKey string
 
 const (
-	TraceKey    contextKey = "trace"
-	TraceIdKey  contextKey = "traceId"
-	authUserKey contextKey = "auth_user"
+	TraceKey     contextKey = "trace"
+	TraceIdKey   contextKey = "traceId"
+	authUserKey  contextKey = "auth_user"
+	tokenUserKey contextKey = "token_user"
+	bodyKey      contextKey = "request_body"
 )
 
 // GetTraceID retrieves the trace ID from the context
@@ -42,3 +44,18 @@ func WithTraceID(ctx context.Context, traceId string) context.Context {
 func WithTrace(ctx context.Context, trace *Trace) context.Context {
 	return context.WithValue(ctx, TraceKey, trace)
 }
+
+// WithRequestBody keeps the request body read by the trace middleware, the middlewares after it
+// read the body from here instead of buffering it again. It is not redacted, never log it.
+func WithRequestBody(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, bodyKey, body)
+}
+
+// GetRequestBody retrieves the request body, ok is false when the trace middleware did not run
+func GetRequestBody(ctx context.Context) ([]byte, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	body, ok := ctx.Value(bodyKey).([]byte)
+	return body, ok
+}
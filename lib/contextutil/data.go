@@ -2,17 +2,22 @@ package contextutil
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/ariesmaulana/payroll/data"
 )
 
 // Trace struct to hold trace information
 type Trace struct {
-	TraceID string
+	TraceID string // OpenTelemetry trace id of the request
+	SpanID  string // span of the request in the trace
 	Method  string
 	Path    string
 	Headers map[string][]string
 	Body    string
+	IP      string // client address, after middleware.RealIP
 }
 
 // AuthUser untuk menyimpan info user hasil verifikasi JWT
@@ -20,6 +25,28 @@ type AuthUser struct {
 	Id       int
 	Username string
 	Role     data.UserRole
+
+	// SessionId is the jti of the access token and ExpiresAt its expiry, used to revoke it on logout
+	SessionId string
+	ExpiresAt time.Time
+
+	// TokenId is set when authenticated with an api token, the token is limited to its Scopes.
+	// Id is 0 for a service account token.
+	TokenId int
+	Scopes  []string
+}
+
+// HasScope tells whether the api token grants the scope, a login session is not limited by scope
+func (u *AuthUser) HasScope(scope string) bool {
+	return u.TokenId == 0 || slices.Contains(u.Scopes, scope)
+}
+
+// Actor is recorded in created_by / updated_by, an action through an api token is attributed to the token
+func (u *AuthUser) Actor() string {
+	if u.TokenId != 0 {
+		return fmt.Sprintf("token:%d", u.TokenId)
+	}
+	return u.Username
 }
 
 //
@@ -36,3 +63,14 @@ func GetUser(ctx context.Context) (*AuthUser, bool) {
 	user, ok := ctx.Value(authUserKey).(*AuthUser)
 	return user, ok
 }
+
+// WithTokenUser keeps the owner of an api token until RequireScope checks the route scope,
+// only then the owner becomes the user of the context (see GetUser)
+func WithTokenUser(ctx context.Context, user *AuthUser) context.Context {
+	return context.WithValue(ctx, tokenUserKey, user)
+}
+
+func GetTokenUser(ctx context.Context) (*AuthUser, bool) {
+	user, ok := ctx.Value(tokenUserKey).(*AuthUser)
+	return user, ok
+}
@@ -0,0 +1,60 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	// monday 6 january 2025 10:30 in Jakarta
+	from := common.NewDateTime(2025, 1, 6, 10, 30, 0)
+
+	scenarios := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "* * * * *", next: common.NewDateTime(2025, 1, 6, 10, 31, 0)},
+		{spec: "*/15 * * * *", next: common.NewDateTime(2025, 1, 6, 10, 45, 0)},
+		{spec: "0 17 * * 1-5", next: common.NewDateTime(2025, 1, 6, 17, 0, 0)},
+		{spec: "0 9 * * 1-5", next: common.NewDateTime(2025, 1, 7, 9, 0, 0)},
+		{spec: "0 9 * * 6", next: common.NewDateTime(2025, 1, 11, 9, 0, 0)},
+		{spec: "0 0 * * 7", next: common.NewDateTime(2025, 1, 12, 0, 0, 0)},
+		{spec: "0 6 25 * *", next: common.NewDateTime(2025, 1, 25, 6, 0, 0)},
+		{spec: "@monthly", next: common.NewDateTime(2025, 2, 1, 0, 0, 0)},
+		{spec: "0 8 1,15 3 *", next: common.NewDateTime(2025, 3, 1, 8, 0, 0)},
+		// both day fields restricted: the 10th or any saturday
+		{spec: "0 0 10 * 6", next: common.NewDateTime(2025, 1, 10, 0, 0, 0)},
+		{spec: "30 10 * * *", next: common.NewDateTime(2025, 1, 7, 10, 30, 0)},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.spec, func(t *testing.T) {
+			schedule, err := Parse(sc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, sc.next, schedule.Next(from))
+		})
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
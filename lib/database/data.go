@@ -5,6 +5,7 @@ type ErrType string
 
 // Define the error constant
 const (
-	ErrUnset    ErrType = ""
-	ErrNotFound ErrType = "NOT_FOUND"
+	ErrUnset     ErrType = ""
+	ErrNotFound  ErrType = "NOT_FOUND"
+	ErrDuplicate ErrType = "DUPLICATE"
 )
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RegisterPoolMetrics exposes the statistics of the connection pool, read at every scrape.
+// A growing pgxpool_empty_acquire_total and pgxpool_acquire_duration_seconds_total means requests
+// wait for a connection, the pool (pool_max_conns) may be too small.
+func RegisterPoolMetrics(registry prometheus.Registerer, pool *pgxpool.Pool) {
+	factory := promauto.With(registry)
+	gauge := func(name string, help string, value func(stat *pgxpool.Stat) float64) {
+		factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+			return value(pool.Stat())
+		})
+	}
+	counter := func(name string, help string, value func(stat *pgxpool.Stat) float64) {
+		factory.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return value(pool.Stat())
+		})
+	}
+
+	gauge("pgxpool_acquired_conns", "Connections in use",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.AcquiredConns()) })
+	gauge("pgxpool_idle_conns", "Connections idle in the pool",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.IdleConns()) })
+	gauge("pgxpool_total_conns", "Connections open, in use, idle or being opened",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.TotalConns()) })
+	gauge("pgxpool_max_conns", "Maximum connections of the pool",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.MaxConns()) })
+	counter("pgxpool_acquire_total", "Connections acquired from the pool",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.AcquireCount()) })
+	counter("pgxpool_acquire_duration_seconds_total", "Time spent acquiring connections, including the wait for a free one",
+		func(stat *pgxpool.Stat) float64 { return stat.AcquireDuration().Seconds() })
+	counter("pgxpool_empty_acquire_total", "Acquires that waited because the pool had no idle connection",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.EmptyAcquireCount()) })
+	counter("pgxpool_canceled_acquire_total", "Acquires canceled by the context while waiting",
+		func(stat *pgxpool.Stat) float64 { return float64(stat.CanceledAcquireCount()) })
+}
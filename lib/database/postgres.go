@@ -24,6 +24,10 @@ func NewPostgresPool(cfg *config.Config) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("unable to parse pool config: %v", err)
 	}
 
+	// a span for every query of a traced request, pgx only logs the queries at info level
+	poolConfig.ConnConfig.Logger = QueryTracer{}
+	poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+
 	// make sure we select the correct schemae
 	poolConfig.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
 		// Set search_path for every new connection
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer records a client span for every query of a sampled trace (a request, service method or job).
+// pgx v4 has no tracer hook, it logs every query with its duration when done, the span is started back from that.
+// The arguments are never recorded, they hold salaries and personal data.
+type QueryTracer struct{}
+
+func (QueryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	duration, ok := data["time"].(time.Duration)
+	if !ok || !oteltrace.SpanFromContext(ctx).IsRecording() {
+		return
+	}
+
+	end := time.Now()
+	_, span := tracing.Start(ctx, "postgres "+msg,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithTimestamp(end.Add(-duration)))
+	span.SetAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.operation", msg))
+	if sql, ok := data["sql"].(string); ok {
+		span.SetAttributes(attribute.String("db.statement", sql))
+	}
+	if table, ok := data["tableName"]; ok {
+		span.SetAttributes(attribute.String("db.sql.table", fmt.Sprint(table)))
+	}
+	if rows, ok := data["rowCount"].(int64); ok {
+		span.SetAttributes(attribute.Int64("db.rows", rows))
+	}
+	if err, ok := data["err"].(error); ok && err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(oteltrace.WithTimestamp(end))
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// a query outside a traced request or job is not recorded, eg: the migrations
+	QueryTracer{}.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "SELECT 1", "time": time.Millisecond})
+
+	ctx, _ := tracing.Start(context.Background(), "user.GetProfile")
+	QueryTracer{}.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql": "SELECT id FROM users WHERE username = $1", "args": []interface{}{"gitawulandari1"}, "time": time.Millisecond, "rowCount": int64(1),
+	})
+	QueryTracer{}.Log(ctx, pgx.LogLevelError, "Exec", map[string]interface{}{
+		"sql": "INSERT INTO users (username) VALUES ($1)", "args": []interface{}{"gitawulandari1"}, "err": errors.New("duplicate key"), "time": time.Millisecond,
+	})
+	QueryTracer{}.Log(ctx, pgx.LogLevelInfo, "Dialing PostgreSQL server", map[string]interface{}{"host": "localhost"})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.NotContains(t, fmt.Sprint(span.Attributes()), "gitawulandari1", "the arguments are never exported")
+	}
+
+	assert.Equal(t, "postgres Query", spans[0].Name())
+	assert.Equal(t, oteltrace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, time.Millisecond, spans[0].EndTime().Sub(spans[0].StartTime()))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.statement", "SELECT id FROM users WHERE username = $1"))
+	assert.Contains(t, spans[0].Attributes(), attribute.Int64("db.rows", 1))
+	assert.Equal(t, "postgres Exec", spans[1].Name())
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "duplicate key"}, spans[1].Status())
+}
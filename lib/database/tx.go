@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type txKey struct{}
+
+// Querier is what a pool and a transaction have in common
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// WithTx makes the storages that query through Conn run in tx, so the writes of a service
+// (eg: the attendance and its outbox event) are committed or rolled back together
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Conn returns the transaction of the context, or the pool when there is none
+func Conn(ctx context.Context, pool *pgxpool.Pool) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// BeginTx starts a transaction on pool, or a savepoint of the transaction of the context. A service
+// called by another one inside its transaction (eg: the audit event of a payroll run) then commits or
+// rolls back with the caller, its own commit only releases the savepoint.
+func BeginTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
+	}
+	return pool.BeginTx(ctx, opts)
+}
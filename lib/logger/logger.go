@@ -1,37 +1,38 @@
 package logger
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 	"github.com/rs/zerolog"
 )
 
-var log zerolog.Logger
+var (
+	log zerolog.Logger
+
+	// the writers closed on shutdown
+	fileWriter *RotatingFile
+	loki       *LokiWriter
+)
 
 // LogConfig holds configuration for the logger
 type LogConfig struct {
 	Debug    bool
 	FilePath string
-	MaxSize  int64
-}
+	MaxSize  int64 // megabytes of a log file before a new one is started
 
-// LevelWriter implements zerolog.LevelWriter interface
-type LevelWriter struct {
-	file *os.File
-}
-
-// Write implements io.Writer
-func (w *LevelWriter) Write(p []byte) (n int, err error) {
-	return w.file.Write(p)
-}
+	// MaxAge (days) and MaxBackups limit the rotated files kept, 0 keeps them all. Compress gzips them.
+	MaxAge     int
+	MaxBackups int
+	Compress   bool
 
-// WriteLevel implements zerolog.LevelWriter
-func (w *LevelWriter) WriteLevel(level zerolog.Level, p []byte) (n int, err error) {
-	return w.Write(p)
+	// Loki ships the log to Loki too when set
+	Loki *LokiConfig
 }
 
 // Init initializes the global logger
@@ -47,32 +48,31 @@ func Init(cfg LogConfig) error {
 		return fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	// Create or open log file
-	currentTime := time.Now().Format("2006-01-02")
-	logFile := filepath.Join(cfg.FilePath, fmt.Sprintf("app-%s.json", currentTime))
-
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+	// app-<date>.json, rotated by date and size
+	fileWriter = &RotatingFile{
+		Dir:        cfg.FilePath,
+		MaxSize:    cfg.MaxSize * 1024 * 1024,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
 	}
+	writers := []io.Writer{fileWriter}
 
-	// Create our custom LevelWriter
-	fileWriter := &LevelWriter{file: file}
+	if cfg.Loki != nil {
+		loki = NewLokiWriter(*cfg.Loki)
+		writers = append(writers, loki)
+	}
 
-	// Create multi-writer if in debug mode
-	var writer zerolog.LevelWriter
+	// Add the console in debug mode
 	if cfg.Debug {
-		consoleWriter := zerolog.ConsoleWriter{
+		writers = append(writers, zerolog.ConsoleWriter{
 			Out:        os.Stdout,
 			TimeFormat: time.RFC3339,
-		}
-		writer = zerolog.MultiLevelWriter(fileWriter, consoleWriter)
-	} else {
-		writer = fileWriter
+		})
 	}
 
 	// Set global logger
-	log = zerolog.New(writer).
+	log = zerolog.New(zerolog.MultiLevelWriter(writers...)).
 		With().
 		Timestamp().
 		Caller().
@@ -81,6 +81,18 @@ func Init(cfg LogConfig) error {
 	return nil
 }
 
+// Close pushes the lines queued for Loki and closes the log file
+func Close(ctx context.Context) error {
+	var err error
+	if loki != nil {
+		err = loki.Close(ctx)
+	}
+	if fileWriter != nil {
+		err = errors.Join(err, fileWriter.Close())
+	}
+	return err
+}
+
 // Debug returns a new Event with trace information
 func Debug(trace *contextutil.Trace) *zerolog.Event {
 	if trace == nil {
@@ -88,6 +100,7 @@ func Debug(trace *contextutil.Trace) *zerolog.Event {
 	}
 	return log.Debug().
 		Str("traceId", trace.TraceID).
+		Str("spanId", trace.SpanID).
 		Str("method", trace.Method).
 		Str("path", trace.Path).
 		Str("body", trace.Body)
@@ -100,6 +113,7 @@ func Info(trace *contextutil.Trace) *zerolog.Event {
 	}
 	return log.Info().
 		Str("traceId", trace.TraceID).
+		Str("spanId", trace.SpanID).
 		Str("method", trace.Method).
 		Str("path", trace.Path).
 		Str("body", trace.Body)
@@ -112,6 +126,7 @@ func Warn(trace *contextutil.Trace) *zerolog.Event {
 	}
 	return log.Warn().
 		Str("traceId", trace.TraceID).
+		Str("spanId", trace.SpanID).
 		Str("method", trace.Method).
 		Str("path", trace.Path).
 		Str("body", trace.Body)
@@ -124,6 +139,7 @@ func Error(trace *contextutil.Trace) *zerolog.Event {
 	}
 	return log.Error().
 		Str("traceId", trace.TraceID).
+		Str("spanId", trace.SpanID).
 		Str("method", trace.Method).
 		Str("path", trace.Path).
 		Str("body", trace.Body)
@@ -136,6 +152,7 @@ func Fatal(trace *contextutil.Trace) *zerolog.Event {
 	}
 	return log.Fatal().
 		Str("traceId", trace.TraceID).
+		Str("spanId", trace.SpanID).
 		Str("method", trace.Method).
 		Str("path", trace.Path).
 		Str("body", trace.Body)
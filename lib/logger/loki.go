@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ariesmaulana/payroll/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+)
+
+var lokiDropped = promauto.With(metrics.Default).NewCounterVec(prometheus.CounterOpts{
+	Name: "payroll_log_lines_dropped_total",
+	Help: "Log lines not shipped to Loki, reason is queue_full (Loki is slower than the log) or push_failed",
+}, []string{"reason"})
+
+// LokiConfig ships the log to Loki, a line is one stream entry labeled with Labels and its level
+type LokiConfig struct {
+	URL      string // eg: http://loki:3100, pushed to <URL>/loki/api/v1/push
+	Labels   map[string]string
+	TenantID string // X-Scope-OrgID of a multi tenant Loki
+
+	BatchSize int           // lines per push
+	BatchWait time.Duration // longest wait before a partial batch is pushed
+	QueueSize int           // lines waiting to be pushed, beyond it new lines are dropped
+}
+
+const lokiMaxAttempts = 3
+
+// LokiWriter pushes the log lines to Loki in batches from a background goroutine. Logging never waits for Loki:
+// when the queue is full the line is dropped and counted in payroll_log_lines_dropped_total, the file keeps it.
+type LokiWriter struct {
+	cfg    LokiConfig
+	client *http.Client
+
+	queue chan lokiEntry
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type lokiEntry struct {
+	at    time.Time
+	level string
+	line  string
+}
+
+func NewLokiWriter(cfg LokiConfig) *LokiWriter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	w := &LokiWriter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan lokiEntry, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *LokiWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel queues the line, zerolog reuses p so it is copied
+func (w *LokiWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	entry := lokiEntry{at: time.Now(), level: level.String(), line: strings.TrimSuffix(string(p), "\n")}
+	if level == zerolog.NoLevel {
+		entry.level = "info"
+	}
+
+	select {
+	case w.queue <- entry:
+	default:
+		lokiDropped.WithLabelValues("queue_full").Inc()
+	}
+	return len(p), nil
+}
+
+// Close pushes the queued lines, it returns when they are pushed or ctx is done
+func (w *LokiWriter) Close(ctx context.Context) error {
+	close(w.stop)
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *LokiWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+
+	batch := make([]lokiEntry, 0, w.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.push(batch); err != nil {
+			lokiDropped.WithLabelValues("push_failed").Add(float64(len(batch)))
+			// not to the log itself, a failing Loki would feed its own queue
+			fmt.Fprintf(os.Stderr, "logger: loki push of %d lines failed: %v\n", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) == w.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-w.stop:
+			for {
+				select {
+				case entry := <-w.queue:
+					batch = append(batch, entry)
+					if len(batch) == w.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends the batch, one stream per level, retrying a 429 or 5xx with backoff
+func (w *LokiWriter) push(batch []lokiEntry) error {
+	streams := make([]lokiStream, 0, 4)
+	byLevel := make(map[string]int)
+	for _, entry := range batch {
+		i, ok := byLevel[entry.level]
+		if !ok {
+			labels := make(map[string]string, len(w.cfg.Labels)+1)
+			for k, v := range w.cfg.Labels {
+				labels[k] = v
+			}
+			labels["level"] = entry.level
+			streams = append(streams, lokiStream{Stream: labels})
+			i = len(streams) - 1
+			byLevel[entry.level] = i
+		}
+		streams[i].Values = append(streams[i].Values, [2]string{strconv.FormatInt(entry.at.UnixNano(), 10), entry.line})
+	}
+
+	body, err := json.Marshal(lokiPush{Streams: streams})
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		retry, err := w.send(body)
+		if err == nil || !retry || attempt == lokiMaxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.stop: // shutting down, retry without waiting
+		}
+		backoff *= 2
+	}
+}
+
+func (w *LokiWriter) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(w.cfg.URL, "/")+"/loki/api/v1/push", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.cfg.TenantID)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return false, nil
+	}
+	retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retry, fmt.Errorf("loki responded %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+}
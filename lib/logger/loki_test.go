@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLokiWriter(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []lokiPush
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		assert.Equal(t, "payroll-prod", r.Header.Get("X-Scope-OrgID"))
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "ingester busy", http.StatusServiceUnavailable) // retried
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var push lokiPush
+		require.NoError(t, json.Unmarshal(body, &push))
+		pushes = append(pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewLokiWriter(LokiConfig{
+		URL:       server.URL,
+		Labels:    map[string]string{"app": "payroll"},
+		TenantID:  "payroll-prod",
+		BatchSize: 3,
+		BatchWait: time.Hour,
+	})
+	logger := zerolog.New(w)
+	logger.Info().Msg("first")
+	logger.Error().Msg("second")
+	logger.Info().Msg("third")
+	logger.Info().Msg("fourth")
+	require.NoError(t, w.Close(context.Background()))
+
+	require.Len(t, pushes, 2, "a full batch, then the rest on close")
+	assert.Equal(t, map[string]string{"app": "payroll", "level": "info"}, pushes[0].Streams[0].Stream)
+	require.Len(t, pushes[0].Streams[0].Values, 2)
+	assert.Equal(t, `{"level":"info","message":"first"}`, pushes[0].Streams[0].Values[0][1])
+	assert.Equal(t, `{"level":"info","message":"third"}`, pushes[0].Streams[0].Values[1][1])
+	assert.Equal(t, map[string]string{"app": "payroll", "level": "error"}, pushes[0].Streams[1].Stream)
+	assert.Equal(t, `{"level":"error","message":"second"}`, pushes[0].Streams[1].Values[0][1])
+	assert.Equal(t, `{"level":"info","message":"fourth"}`, pushes[1].Streams[0].Values[0][1])
+}
+
+// droppedLines reads payroll_log_lines_dropped_total of the reason
+func droppedLines(reason string) int {
+	return int(testutil.ToFloat64(lokiDropped.WithLabelValues(reason)))
+}
+
+func TestLokiWriterBackpressure(t *testing.T) {
+	dropped := droppedLines("queue_full")
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // Loki hangs
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewLokiWriter(LokiConfig{URL: server.URL, BatchSize: 1, BatchWait: time.Hour, QueueSize: 2})
+	logger := zerolog.New(w)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			logger.Info().Int("i", i).Msg("line")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging waited for loki")
+	}
+	close(release)
+	require.NoError(t, w.Close(context.Background()))
+
+	// the queue holds 2 lines and at most 1 is being pushed
+	assert.GreaterOrEqual(t, droppedLines("queue_full")-dropped, 17)
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	redacted = "[REDACTED]"
+
+	// maxLoggedBody is the longest body kept in the log after redaction
+	maxLoggedBody = 2048
+)
+
+// sensitiveKeys are the request fields never written to the log (matched case insensitive), with any key
+// ending in a sensitiveSuffixes word, eg: new_password, refresh_token, step_up_token
+var (
+	sensitiveKeys = map[string]bool{
+		"code": true, "recovery_code": true, "recovery_codes": true, "otp": true, "pin": true,
+		"authorization": true, "nik": true, "npwp": true, "account_number": true, "salary": true, "base_salary": true,
+	}
+	sensitiveSuffixes = []string{"password", "secret", "token"}
+)
+
+// RedactBody returns the request body for the log: the values of sensitive JSON or form fields are replaced,
+// any other body (a CSV import, a file) is only logged by its size
+func RedactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || (mediaType == "" && json.Valid(body)):
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			return fmt.Sprintf("[invalid json, %d bytes]", len(body))
+		}
+		out, _ := json.Marshal(redactValue(value))
+		return truncate(string(out))
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return fmt.Sprintf("[invalid form, %d bytes]", len(body))
+		}
+		for key := range form {
+			if sensitiveKey(key) {
+				form[key] = []string{redacted}
+			}
+		}
+		return truncate(form.Encode())
+	default:
+		return fmt.Sprintf("[%s, %d bytes]", mediaType, len(body))
+	}
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitiveKey(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if key == suffix || strings.HasSuffix(key, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(body string) string {
+	if len(body) <= maxLoggedBody {
+		return body
+	}
+	return body[:maxLoggedBody] + "...[truncated]"
+}
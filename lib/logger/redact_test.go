@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBody(t *testing.T) {
+	scenarios := map[string]struct {
+		contentType string
+		body        string
+		expected    string
+	}{
+		"login": {
+			"application/json", `{"username":"gitawulandari1","password":"SecurePassword123!"}`,
+			`{"password":"[REDACTED]","username":"gitawulandari1"}`,
+		},
+		"change password without content type": {
+			"", `{"current_password":"old","new_password":"new"}`,
+			`{"current_password":"[REDACTED]","new_password":"[REDACTED]"}`,
+		},
+		"nested and arrays": {
+			"application/json; charset=utf-8", `{"users":[{"nik":"3174","refresh_token":"r"}],"code":"123456","account_code":"5100"}`,
+			`{"account_code":"5100","code":"[REDACTED]","users":[{"nik":"[REDACTED]","refresh_token":"[REDACTED]"}]}`,
+		},
+		"form": {
+			"application/x-www-form-urlencoded", "username=gita&Password=secret",
+			"Password=%5BREDACTED%5D&username=gita",
+		},
+		"csv import": {
+			"text/csv", "user_id,date\n2,2025-06-17\n",
+			"[text/csv, 26 bytes]",
+		},
+		"invalid json": {
+			"application/json", `{"password":`,
+			"[invalid json, 12 bytes]",
+		},
+		"empty": {"application/json", "", ""},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, RedactBody(sc.contentType, []byte(sc.body)))
+		})
+	}
+
+	long := RedactBody("application/json", []byte(`{"note":"`+strings.Repeat("a", 3000)+`"}`))
+	assert.Len(t, long, maxLoggedBody+len("...[truncated]"))
+}
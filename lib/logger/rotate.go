@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile writes the log to <dir>/app-<date>.json and starts a new file every day and whenever the file
+// reaches MaxSize, eg: app-2025-06-17.json, app-2025-06-17.1.json, app-2025-06-18.json. A closed file is gzipped
+// when Compress, files older than MaxAge days or beyond the newest MaxBackups are deleted.
+type RotatingFile struct {
+	Dir        string
+	MaxSize    int64 // bytes, 0 never rotates by size
+	MaxAge     int   // days, 0 keeps every day
+	MaxBackups int   // closed files kept, 0 keeps every file
+	Compress   bool
+
+	now func() time.Time
+
+	mu    sync.Mutex
+	file  *os.File
+	date  string
+	index int
+	size  int64
+	wg    sync.WaitGroup // running compress and cleanup
+}
+
+const logFilePrefix = "app-"
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	date := f.clock().Format("2006-01-02")
+	switch {
+	case f.file == nil:
+		if err := f.open(date); err != nil {
+			return 0, err
+		}
+	case date != f.date:
+		if err := f.rotate(date, 0); err != nil {
+			return 0, err
+		}
+	case f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize:
+		if err := f.rotate(date, f.index+1); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file and waits for the compression of the rotated ones
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	file := f.file
+	f.file = nil
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
+func (f *RotatingFile) clock() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+func (f *RotatingFile) name(date string, index int) string {
+	if index == 0 {
+		return filepath.Join(f.Dir, logFilePrefix+date+".json")
+	}
+	return filepath.Join(f.Dir, fmt.Sprintf("%s%s.%d.json", logFilePrefix, date, index))
+}
+
+// open continues the last file of the date after a restart, a new one when it is full
+func (f *RotatingFile) open(date string) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	index := 0
+	for {
+		_, err := os.Stat(f.name(date, index+1))
+		_, errGz := os.Stat(f.name(date, index+1) + ".gz")
+		if err != nil && errGz != nil {
+			break
+		}
+		index++
+	}
+	if info, err := os.Stat(f.name(date, index)); err == nil && f.MaxSize > 0 && info.Size() >= f.MaxSize {
+		index++
+	}
+	return f.openFile(date, index)
+}
+
+func (f *RotatingFile) openFile(date string, index int) error {
+	file, err := os.OpenFile(f.name(date, index), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.date, f.index, f.size = file, date, index, info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate(date string, index int) error {
+	closed := f.file.Name()
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := f.openFile(date, index); err != nil {
+		return err
+	}
+
+	// compress and clean up outside the lock, the writers do not wait for gzip
+	current := f.file.Name()
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		if f.Compress {
+			if err := compressFile(closed); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: failed compress %s: %v\n", closed, err)
+			}
+		}
+		f.cleanup(current)
+	}()
+	return nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+type logFile struct {
+	path  string
+	date  string
+	index int
+}
+
+// cleanup deletes the closed files beyond the retention, never the current one
+func (f *RotatingFile) cleanup(current string) {
+	if f.MaxAge <= 0 && f.MaxBackups <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(f.Dir)
+	if err != nil {
+		return
+	}
+	files := make([]logFile, 0, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(f.Dir, entry.Name())
+		if path == current {
+			continue
+		}
+		if file, ok := parseLogFile(entry.Name()); ok {
+			file.path = path
+			files = append(files, file)
+		}
+	}
+
+	// newest first
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].date != files[j].date {
+			return files[i].date > files[j].date
+		}
+		return files[i].index > files[j].index
+	})
+
+	oldest := f.clock().AddDate(0, 0, -f.MaxAge).Format("2006-01-02")
+	for i, file := range files {
+		if (f.MaxBackups > 0 && i >= f.MaxBackups) || (f.MaxAge > 0 && file.date < oldest) {
+			os.Remove(file.path)
+		}
+	}
+}
+
+// parseLogFile reads app-<date>[.<index>].json[.gz]
+func parseLogFile(name string) (logFile, bool) {
+	if !strings.HasPrefix(name, logFilePrefix) {
+		return logFile{}, false
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, logFilePrefix), ".gz")
+	if !strings.HasSuffix(name, ".json") {
+		return logFile{}, false
+	}
+	date, index, _ := strings.Cut(strings.TrimSuffix(name, ".json"), ".")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return logFile{}, false
+	}
+	file := logFile{date: date}
+	if index != "" {
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			return logFile{}, false
+		}
+		file.index = n
+	}
+	return file, true
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 6, 17, 23, 0, 0, 0, time.UTC)
+	f := &RotatingFile{Dir: dir, MaxSize: 11, Compress: true, now: func() time.Time { return now }}
+
+	write := func(line string) {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	write("12345\n")
+	write("1234\n") // fits the 11 bytes
+	write("123\n")  // does not, starts app-2025-06-17.1.json
+	now = now.Add(2 * time.Hour)
+	write("next day\n")
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"app-2025-06-17.1.json.gz", "app-2025-06-17.json.gz", "app-2025-06-18.json"}, listDir(t, dir))
+
+	gz, err := os.Open(filepath.Join(dir, "app-2025-06-17.json.gz"))
+	require.NoError(t, err)
+	defer gz.Close()
+	r, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "12345\n1234\n", string(content))
+
+	// a restart continues the last file of the day
+	f = &RotatingFile{Dir: dir, MaxSize: 100, now: func() time.Time { return now }}
+	write("restarted\n")
+	require.NoError(t, f.Close())
+	content, err = os.ReadFile(filepath.Join(dir, "app-2025-06-18.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "next day\nrestarted\n", string(content))
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app-2025-05-01.json.gz", // older than MaxAge
+		"app-2025-06-10.json.gz",
+		"app-2025-06-15.json.gz",
+		"app-2025-06-16.json",
+		"app-2025-06-16.1.json",
+		"notes.txt", // not a log file
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644))
+	}
+
+	now := time.Date(2025, 6, 16, 12, 0, 0, 0, time.UTC)
+	f := &RotatingFile{Dir: dir, MaxAge: 30, MaxBackups: 3, now: func() time.Time { return now }}
+	_, err := f.Write([]byte("a\n")) // continues app-2025-06-16.1.json
+	require.NoError(t, err)
+	now = now.AddDate(0, 0, 1)
+	_, err = f.Write([]byte("b\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{
+		"app-2025-06-15.json.gz",
+		"app-2025-06-16.1.json",
+		"app-2025-06-16.json",
+		"app-2025-06-17.json",
+		"notes.txt",
+	}, listDir(t, dir))
+}
+
+func TestParseLogFile(t *testing.T) {
+	scenarios := map[string]struct {
+		ok    bool
+		date  string
+		index int
+	}{
+		"app-2025-06-17.json":      {true, "2025-06-17", 0},
+		"app-2025-06-17.3.json.gz": {true, "2025-06-17", 3},
+		"app-2025-06-17.x.json":    {false, "", 0},
+		"app-latest.json":          {false, "", 0},
+		"app-2025-06-17.log":       {false, "", 0},
+		"other-2025-06-17.json":    {false, "", 0},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			file, ok := parseLogFile(name)
+			assert.Equal(t, sc.ok, ok)
+			assert.Equal(t, sc.date, file.date)
+			assert.Equal(t, sc.index, file.index)
+		})
+	}
+}
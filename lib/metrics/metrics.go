@@ -0,0 +1,27 @@
+// Package metrics holds the Prometheus registry of the app, served at /metrics. The metrics are
+// registered on Default with promauto.With(metrics.Default), values read at scrape time (eg: the
+// connection pool, the database) are a GaugeFunc or a prometheus.Collector.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry served by Handler, with the Go runtime and process metrics
+var Default = prometheus.NewRegistry()
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the Default registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveServiceCall(t *testing.T) {
+	before := testutil.ToFloat64(serviceCalls.WithLabelValues("loan", "Approve", "failure"))
+
+	ObserveServiceCall("loan", "Approve", time.Now(), true)
+	ObserveServiceCall("loan", "Approve", time.Now(), false)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(serviceCalls.WithLabelValues("loan", "Approve", "failure")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(serviceCalls.WithLabelValues("loan", "Approve", "success")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveServiceCall("user", "Login", time.Now(), true)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `payroll_service_calls_total{method="Login",result="success",service="user"} 1`)
+	assert.Contains(t, string(body), `payroll_service_call_duration_seconds_count{method="Login",service="user"} 1`)
+	assert.Contains(t, string(body), "go_goroutines ")
+}
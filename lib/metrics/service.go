@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	serviceCalls = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "payroll_service_calls_total",
+		Help: "Calls of the service methods, result is success or failure (Success false in the output)",
+	}, []string{"service", "method", "result"})
+	serviceDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "payroll_service_call_duration_seconds",
+		Help:    "Duration of the service methods",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+)
+
+// ObserveServiceCall records one call of a service method started at start, see the Instrument of each app lib
+func ObserveServiceCall(service string, method string, start time.Time, success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	serviceCalls.WithLabelValues(service, method, result).Inc()
+	serviceDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
@@ -1,40 +1,116 @@
 package middleware
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/ariesmaulana/payroll/data"
 	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/ariesmaulana/payroll/lib/contextutil"
 )
 
+// SessionCheck tells whether the session of a token with valid signature is still alive:
+// not logged out, not revoked by admin and the user is still active. It returns nil or the failure.
+type SessionCheck func(ctx context.Context, claims *jwtutil.Claims) *apperror.Error
+
+var sessionCheck SessionCheck
+
+// SetSessionCheck registers the revocation check of AuthMiddleware, without it only the signature is checked
+func SetSessionCheck(check SessionCheck) {
+	sessionCheck = check
+}
+
+// TokenCheck resolves an api token (personal access token, service account token) to the user it acts as,
+// or to the failure
+type TokenCheck func(ctx context.Context, token string, ip string) (*contextutil.AuthUser, *apperror.Error)
+
+var tokenCheck TokenCheck
+
+// SetTokenCheck lets AuthMiddleware accept api tokens, without it only JWT is accepted
+func SetTokenCheck(check TokenCheck) {
+	tokenCheck = check
+}
+
+// AuthMiddleware accepts a JWT of a login session or an api token. The owner of an api token
+// is only put in the context once RequireScope accepted the scope of the route.
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			response.Error(w, r, "Missing Authorization header")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+			response.Error(w, r, "Invalid Authorization header format")
 			return
 		}
 
 		tokenStr := parts[1]
+		if strings.HasPrefix(tokenStr, data.APITokenPrefix) {
+			if tokenCheck == nil {
+				response.Error(w, r, "Invalid or expired token")
+				return
+			}
+
+			user, appErr := tokenCheck(r.Context(), tokenStr, remoteIP(r))
+			if appErr != nil {
+				rejected(w, r, appErr, "Invalid, expired or revoked token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(contextutil.WithTokenUser(r.Context(), user)))
+			return
+		}
+
 		claims, err := jwtutil.ValidateJWT(tokenStr)
 		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			response.Error(w, r, "Invalid or expired token")
 			return
 		}
 
+		if sessionCheck != nil {
+			if appErr := sessionCheck(r.Context(), claims); appErr != nil {
+				rejected(w, r, appErr, "Session has been revoked")
+				return
+			}
+		}
+
 		// Inject user info ke context
-		ctx := contextutil.WithUser(r.Context(), &contextutil.AuthUser{
-			Id:       claims.UserID,
-			Username: claims.Username,
-		})
+		user := &contextutil.AuthUser{
+			Id:        claims.UserID,
+			Username:  claims.Username,
+			Role:      claims.Role,
+			SessionId: claims.ID,
+		}
+		if claims.ExpiresAt != nil {
+			user.ExpiresAt = claims.ExpiresAt.Time
+		}
+		ctx := contextutil.WithUser(r.Context(), user)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// remoteIP is the client address without port, RealIP middleware already applied the proxy headers
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rejected answers a token the check refused with msg, the reason is not told to the caller. A check that
+// could not be done (eg: the database is down) is an internal error and not a 401 making the client log out.
+func rejected(w http.ResponseWriter, r *http.Request, appErr *apperror.Error, msg string) {
+	if appErr.Code == apperror.CodeInternal {
+		response.Fail(w, r, appErr)
+		return
+	}
+	response.Error(w, r, msg)
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/lib/apperror"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAPIToken = data.APITokenPrefix + "valid"
+
+func newTestRouter(t *testing.T) (chi.Router, *string) {
+	t.Helper()
+	jwtutil.SetSecret("secret")
+	SetSessionCheck(nil)
+	SetTokenCheck(func(ctx context.Context, token string, ip string) (*contextutil.AuthUser, *apperror.Error) {
+		switch token {
+		case testAPIToken:
+			return &contextutil.AuthUser{Id: 7, Username: "hris", Role: data.RAdmin, TokenId: 42, Scopes: []string{data.ScopePayslipRead}}, nil
+		case data.APITokenPrefix + "dbdown":
+			return nil, apperror.Internal()
+		}
+		return nil, apperror.New(apperror.CodeTokenRevoked, "Token sudah dicabut")
+	})
+	t.Cleanup(func() { SetTokenCheck(nil) })
+
+	// actor is the created_by the service would record, "-" when there is no user in the context
+	actor := new(string)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		*actor = "-"
+		if user, ok := contextutil.GetUser(r.Context()); ok {
+			*actor = user.Actor()
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.With(RequireScope(data.ScopePayslipRead)).Get("/payslip", handler)
+		r.With(RequireScope(data.ScopeAttendanceWrite)).Post("/clock-in", handler)
+		r.Post("/tokens", handler)
+	})
+	return r, actor
+}
+
+func serve(r http.Handler, method string, path string, bearer string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthMiddlewareAPIToken(t *testing.T) {
+	r, actor := newTestRouter(t)
+
+	// the action is attributed to the token
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/payslip", testAPIToken))
+	assert.Equal(t, "token:42", *actor)
+
+	*actor = ""
+	assert.Equal(t, http.StatusForbidden, serve(r, http.MethodPost, "/clock-in", testAPIToken))
+	assert.Empty(t, *actor, "handler must not run without the scope")
+
+	// a route without scope never sees the token owner
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodPost, "/tokens", testAPIToken))
+	assert.Equal(t, "-", *actor)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(r, http.MethodGet, "/payslip", data.APITokenPrefix+"revoked"))
+	// a token that could not be checked is not a bad token, the client must not drop it
+	assert.Equal(t, http.StatusInternalServerError, serve(r, http.MethodGet, "/payslip", data.APITokenPrefix+"dbdown"))
+}
+
+func TestAuthMiddlewareSessionIgnoresScope(t *testing.T) {
+	r, actor := newTestRouter(t)
+
+	token, err := jwtutil.GenerateJWT(1, "gita", data.REmployee, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodPost, "/clock-in", token))
+	assert.Equal(t, "gita", *actor)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodPost, "/tokens", token))
+	assert.Equal(t, "gita", *actor)
+}
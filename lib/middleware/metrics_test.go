@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.Route("/loans", func(r chi.Router) {
+		r.Get("/{loanId}", func(w http.ResponseWriter, r *http.Request) {
+			if chi.URLParam(r, "loanId") == "404" {
+				http.Error(w, "Loan tidak ditemukan", http.StatusNotFound)
+				return
+			}
+			w.Write([]byte("ok"))
+		})
+	})
+
+	for _, path := range []string{"/loans/1", "/loans/2", "/loans/404", "/wp-login.php"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	MetricsHandler("").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/loans/{loanId}",status="200"} 2`)
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/loans/{loanId}",status="404"} 1`)
+	assert.Contains(t, out, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/loans/{loanId}"} 3`)
+	assert.Contains(t, out, "http_requests_in_flight 0")
+}
+
+func TestMetricsHandlerToken(t *testing.T) {
+	handler := MetricsHandler("scrape-secret")
+
+	scenarios := map[string]struct {
+		authorization string
+		status        int
+	}{
+		"no token":    {"", http.StatusUnauthorized},
+		"wrong token": {"Bearer other", http.StatusUnauthorized},
+		"token":       {"Bearer scrape-secret", http.StatusOK},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if sc.authorization != "" {
+				req.Header.Set("Authorization", sc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, sc.status, rec.Code)
+		})
+	}
+}
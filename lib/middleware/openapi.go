@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/openapi"
+)
+
+// OpenAPIValidator rejects a request that does not match its operation in spec (parameters and JSON body)
+// with 400 INVALID_INPUT, the violations are in data. A route outside the spec passes through.
+// Must be used after TraceMiddleware, the body it buffered is validated.
+func OpenAPIValidator(spec *openapi.Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, params, ok := spec.Find(r.Method, r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, ok := contextutil.GetRequestBody(r.Context())
+			if !ok {
+				response.Error(w, r, "Trace not found")
+				return
+			}
+
+			if violations := route.ValidateRequest(r, params, body); len(violations) > 0 {
+				response.ErrorData(w, r, "Request tidak sesuai spesifikasi API", violations)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
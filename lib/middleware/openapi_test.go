@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/openapi"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidator(t *testing.T) {
+	spec, err := openapi.Load([]byte(`{
+	  "openapi": "3.1.0",
+	  "paths": {
+	    "/loans": {"post": {
+	      "requestBody": {"required": true, "content": {"application/json": {"schema": {
+	        "type": "object",
+	        "properties": {"amount": {"type": "integer", "minimum": 1}},
+	        "required": ["amount"],
+	        "additionalProperties": false
+	      }}}},
+	      "responses": {}
+	    }}
+	  }
+	}`))
+	require.NoError(t, err)
+
+	var received string
+	r := chi.NewRouter()
+	r.Use(TraceMiddleware)
+	r.Use(OpenAPIValidator(spec))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}
+	r.Post("/loans", handler)
+	r.Post("/loans/{loanId}/approve", handler)
+
+	scenarios := map[string]struct {
+		path       string
+		body       string
+		status     int
+		violations []openapi.Violation
+	}{
+		"valid request reaches the handler with its body": {"/loans", `{"amount": 5000000}`, http.StatusOK, nil},
+		"invalid request": {"/loans", `{"amount": "5000000", "tenor": 12}`, http.StatusBadRequest, []openapi.Violation{
+			{Field: "body.amount", Reason: "must be integer"},
+			{Field: "body.tenor", Reason: "is not allowed"},
+		}},
+		"route outside the spec passes through": {"/loans/7/approve", `{"note": 1}`, http.StatusOK, nil},
+	}
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, sc.path, strings.NewReader(sc.body))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, sc.status, rec.Code)
+			if sc.violations == nil {
+				assert.Equal(t, sc.body, received)
+				return
+			}
+			assert.Empty(t, received)
+			var resp struct {
+				response.ApiResponse
+				Data []openapi.Violation `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "INVALID_INPUT", resp.Code)
+			assert.Equal(t, "Request tidak sesuai spesifikasi API", resp.Msg)
+			assert.Equal(t, rec.Header().Get("X-Trace-ID"), resp.Trace)
+			assert.Equal(t, sc.violations, resp.Data)
+		})
+	}
+}
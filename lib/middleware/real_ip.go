@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP replaces the remote address with the client address of X-Forwarded-For / X-Real-IP, only when the
+// request comes from one of the trusted proxies. Any other client sets those headers itself and would pick
+// the ip counted by the login throttle and written in the audit trail.
+func RealIP(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	trusted := func(ip net.IP) bool {
+		for _, network := range trustedProxies {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := net.ParseIP(remoteIP(r)); ip != nil && trusted(ip) {
+				if client := forwardedIP(r, trusted); client != "" {
+					r.RemoteAddr = client
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP is the first address of X-Forwarded-For from the right that is not a trusted proxy,
+// the ones on its left are written by the client. X-Real-IP is used when there is no X-Forwarded-For.
+func forwardedIP(r *http.Request, trusted func(ip net.IP) bool) string {
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return ""
+	}
+
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !trusted(ip) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	assert.NoError(t, err)
+
+	var remoteAddr string
+	handler := RealIP([]*net.IPNet{proxies})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}))
+
+	scenarios := map[string]struct {
+		remoteAddr string
+		header     map[string]string
+		expected   string
+	}{
+		"direct client without header":      {"203.0.113.7:51000", nil, "203.0.113.7:51000"},
+		"direct client spoofing the header": {"203.0.113.7:51000", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}, "203.0.113.7:51000"},
+		"trusted proxy":                     {"10.0.0.2:443", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		"client prefix before the proxy":    {"10.0.0.2:443", map[string]string{"X-Forwarded-For": "192.0.2.9, 198.51.100.1"}, "198.51.100.1"},
+		"chain of trusted proxies":          {"10.0.0.2:443", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		"trusted proxy with x-real-ip":      {"10.0.0.2:443", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2"},
+		"trusted proxy with invalid header": {"10.0.0.2:443", map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.2:443"},
+		"trusted proxy without header":      {"10.0.0.2:443", nil, "10.0.0.2:443"},
+	}
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = sc.remoteAddr
+			for key, value := range sc.header {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, sc.expected, remoteAddr)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+// RequireScope opens the route to api tokens having the scope. A login session passes through,
+// its role is checked by the service as usual. A route without RequireScope is never reached
+// with an api token, the service finds no user in the context. Must be used after AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := contextutil.GetUser(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, ok := contextutil.GetTokenUser(r.Context())
+			if !ok {
+				response.Error(w, r, "unauthorized")
+				return
+			}
+			if !user.HasScope(scope) {
+				response.Error(w, r, "Token does not have scope "+scope)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(contextutil.WithUser(r.Context(), user)))
+		})
+	}
+}
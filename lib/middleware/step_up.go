@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+// StepUpHeader carries the step-up token returned by POST /users/mfa/step-up
+const StepUpHeader = "X-Step-Up-Token"
+
+// RequireStepUp guards payroll-finalizing endpoints, the caller must have re-verified
+// their TOTP code in the last few minutes. Must be used after AuthMiddleware.
+func RequireStepUp(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := contextutil.GetUser(r.Context())
+		if !ok {
+			response.Error(w, r, "unauthorized")
+			return
+		}
+
+		tokenStr := r.Header.Get(StepUpHeader)
+		if tokenStr == "" {
+			response.Error(w, r, "Step-up authentication required")
+			return
+		}
+
+		claims, err := jwtutil.ValidateChallengeJWT(tokenStr, jwtutil.PurposeStepUp)
+		if err != nil || claims.UserID != user.Id {
+			response.Error(w, r, "Invalid or expired step-up token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,34 +2,39 @@ package middleware
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/ariesmaulana/payroll/lib/contextutil"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/tracing"
 	"github.com/go-chi/chi/middleware"
-	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func TraceMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Generate new UUID for trace ID
-		traceId := uuid.New().String()
+		// Continue the trace of the caller (traceparent header) or start one, the trace ID is the OpenTelemetry trace id
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "HTTP "+r.Method, oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+		traceId := span.SpanContext().TraceID().String()
 
 		// Create a custom response writer to capture the status code
 		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
-		// Read request body if it's available (to capture in trace)
+		// Read request body if it's available (to capture in trace), passwords and personal data are redacted
 		var body string
+		bodyBytes := []byte{}
 		if r.Body != nil {
-			bodyBytes, err := io.ReadAll(r.Body)
+			read, err := io.ReadAll(r.Body)
 			if err == nil {
-				// Restore the body for downstream handlers
-				body = string(bodyBytes)
+				bodyBytes = read
+				body = log.RedactBody(r.Header.Get("Content-Type"), bodyBytes)
 				r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // Restore body for further use
 			}
 		}
@@ -37,33 +42,59 @@ func TraceMiddleware(next http.Handler) http.Handler {
 		// Create a Trace object with additional request info
 		trace := &contextutil.Trace{
 			TraceID: traceId,
+			SpanID:  span.SpanContext().SpanID().String(),
 			Method:  r.Method,
 			Path:    r.URL.Path,
 			Headers: r.Header,
-			Body:    body, // Optional, capture body content
+			Body:    body, // Optional, redacted body content
+			IP:      remoteIP(r),
 		}
 
 		// Add trace ID to context with the defined constant key
-		ctx := contextutil.WithTrace(r.Context(), trace)
-		fmt.Println(ctx)
+		ctx = contextutil.WithTrace(ctx, trace)
+		ctx = contextutil.WithRequestBody(ctx, bodyBytes)
+
 		// Add trace ID to response headers
 		w.Header().Set("X-Trace-ID", traceId)
 
 		// Create new request with updated context
 		r = r.WithContext(ctx)
 
-		// Log the request details (including trace information)
-		log.Info().
-			Str("traceId", traceId).
-			Str("method", r.Method).
-			Str("path", r.URL.Path).
-			Str("remote_ip", r.RemoteAddr).
-			Int("status", ww.Status()).
+		// Continue with the next handler
+		next.ServeHTTP(ww, r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK // nothing written
+		}
+		endServerSpan(span, r, status)
+
+		// Log the request details (including trace information) once the status is known
+		log.Info(trace).
+			Str("remote_ip", trace.IP).
+			Int("status", status).
 			Dur("latency", time.Since(start)).
 			Str("user_agent", r.UserAgent()).
 			Msg("Request handled")
-
-		// Continue with the next handler
-		next.ServeHTTP(w, r)
 	})
 }
+
+// endServerSpan names the span by the route pattern, eg: GET /loans/{loanId}, known once chi has routed the request
+func endServerSpan(span oteltrace.Span, r *http.Request, status int) {
+	route := unmatchedRoute
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+
+	span.SetName(r.Method + " " + route)
+	span.SetAttributes(
+		attribute.String("http.request.method", r.Method),
+		attribute.String("http.route", route),
+		attribute.String("url.path", r.URL.Path),
+		attribute.Int("http.response.status_code", status),
+		attribute.String("client.address", remoteIP(r)),
+	)
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var trace *contextutil.Trace
+	r := chi.NewRouter()
+	r.Use(TraceMiddleware)
+	r.Get("/loans/{loanId}", func(w http.ResponseWriter, r *http.Request) {
+		trace, _ = contextutil.GetTrace(r.Context())
+		_, span := tracing.Start(r.Context(), "loan.GetLoan")
+		span.End()
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/loans/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.NotNil(t, trace)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID, "the trace of the caller continues")
+	assert.Equal(t, trace.TraceID, rec.Header().Get("X-Trace-ID"))
+	assert.Len(t, trace.SpanID, 16)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "loan.GetLoan", spans[0].Name())
+	assert.Equal(t, trace.SpanID, spans[0].Parent().SpanID().String())
+	assert.Equal(t, "GET /loans/{loanId}", spans[1].Name())
+	assert.Equal(t, oteltrace.SpanKindServer, spans[1].SpanKind())
+	assert.Equal(t, trace.SpanID, spans[1].SpanContext().SpanID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent().SpanID().String())
+	assert.Contains(t, spans[1].Attributes(), attribute.String("http.route", "/loans/{loanId}"))
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "Internal Server Error"}, spans[1].Status())
+}
+
+func TestTraceMiddlewareRedactsBody(t *testing.T) {
+	var trace *contextutil.Trace
+	var body []byte
+	r := chi.NewRouter()
+	r.Use(TraceMiddleware)
+	r.Post("/users/login", func(w http.ResponseWriter, r *http.Request) {
+		trace, _ = contextutil.GetTrace(r.Context())
+		body, _ = io.ReadAll(r.Body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader(`{"username":"gitawulandari1","password":"SecurePassword123!"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NotNil(t, trace)
+	assert.Equal(t, `{"password":"[REDACTED]","username":"gitawulandari1"}`, trace.Body)
+	assert.Equal(t, `{"username":"gitawulandari1","password":"SecurePassword123!"}`, string(body), "the handler reads the whole body")
+}
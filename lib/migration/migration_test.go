@@ -0,0 +1,108 @@
+package migration_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/ariesmaulana/payroll/lib/migration"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/ariesmaulana/payroll/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	loaded, err := migration.Load(fstest.MapFS{
+		"0002_loan.up.sql":   file("CREATE TABLE loans (id INT);"),
+		"0002_loan.down.sql": file("DROP TABLE loans;"),
+		"0010_tax.up.sql":    file("CREATE TABLE tax (id INT);"),
+		"0010_tax.down.sql":  file("DROP TABLE tax;"),
+		"0001_user.up.sql":   file("CREATE TABLE users (id INT);"),
+		"0001_user.down.sql": file("DROP TABLE users;"),
+		"README.md":          file("not a migration"),
+	})
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "user", loaded[0].Name)
+	assert.Equal(t, "DROP TABLE users;", loaded[0].Down)
+	assert.Equal(t, int64(2), loaded[1].Version)
+	assert.Equal(t, int64(10), loaded[2].Version, "sorted by number, not by name")
+
+	scenarios := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{name: "missing down", files: fstest.MapFS{"0001_user.up.sql": file("x")}},
+		{name: "missing up", files: fstest.MapFS{"0001_user.down.sql": file("x")}},
+		{name: "bad name", files: fstest.MapFS{"user.up.sql": file("x")}},
+		{name: "version zero", files: fstest.MapFS{"0000_user.up.sql": file("x"), "0000_user.down.sql": file("x")}},
+		{name: "same version two names", files: fstest.MapFS{"0001_user.up.sql": file("x"), "0001_users.down.sql": file("x")}},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			_, err := migration.Load(sc.files)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMigrationsFiles(t *testing.T) {
+	t.Parallel()
+
+	loaded, err := migration.Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, m := range loaded {
+		assert.Equal(t, int64(i+1), m.Version, "versions have no gap")
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	t.Parallel()
+
+	// the suite already applied every migration
+	con := test.DbTestPool(t)
+	migrator, err := migration.New(con.Pool, migrations.FS)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(con.Context)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "up is idempotent")
+
+	statuses, err := migrator.Status(con.Context)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt, s.Name)
+	}
+	last := statuses[len(statuses)-1]
+
+	reverted, err := migrator.Down(con.Context, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, last.Version, reverted[0].Version)
+
+	statuses, err = migrator.Status(con.Context)
+	require.NoError(t, err)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	applied, err = migrator.Up(con.Context)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, last.Version, applied[0].Version)
+
+	// every down file reverts its up file, the whole schema can be rebuilt
+	reverted, err = migrator.Down(con.Context, len(statuses))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(statuses))
+
+	applied, err = migrator.Up(con.Context)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(statuses))
+}
@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+// Message is a notification to one user, To is the email address
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers a message to the user, the implementation is picked by config (NOTIFIER)
+type Notifier interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// LogNotifier writes the message to the application log, only for local development
+// because the body may contain a secret such as password reset link
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, msg *Message) error {
+	log.Info(nil).Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).Msg("notification")
+	return nil
+}
+
+// SMTPNotifier sends the message as plain text email
+type SMTPNotifier struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func (n SMTPNotifier) Send(ctx context.Context, msg *Message) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host := n.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		n.From, msg.To, msg.Subject, msg.Body)
+
+	return smtp.SendMail(n.Addr, auth, n.From, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// SpecHandler serves the document as it is
+func SpecHandler(raw []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(raw)
+	})
+}
+
+// swagger-ui-dist is loaded from the CDN, the binary stays small
+var uiPage = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`))
+
+// UIHandler serves Swagger UI showing the document at specURL
+func UIHandler(title string, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		uiPage.Execute(w, struct{ Title, SpecURL string }{title, specURL})
+	})
+}
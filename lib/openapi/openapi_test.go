@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/loans": {
+      "post": {
+        "operationId": "requestLoan",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/LoanRequest"}}}
+        },
+        "responses": {
+          "200": {"content": {"application/json": {"schema": {
+            "type": "object",
+            "properties": {"success": {"type": "boolean"}, "data": {"oneOf": [{"$ref": "#/components/schemas/Loan"}, {"type": "null"}]}},
+            "required": ["success", "data"],
+            "additionalProperties": false
+          }}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/loans/{loanId}": {
+      "parameters": [{"$ref": "#/components/parameters/LoanId"}],
+      "get": {
+        "operationId": "getLoan",
+        "parameters": [{"name": "statuses", "in": "query", "schema": {"type": "array", "items": {"type": "string", "enum": ["active", "paid"]}}}],
+        "responses": {"default": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/loans/pending": {
+      "get": {
+        "operationId": "listPendingLoans",
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100}},
+          {"name": "X-Step-Up-Token", "in": "header", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {"default": {"$ref": "#/components/responses/Error"}}
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "LoanId": {"name": "loanId", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}}
+    },
+    "responses": {
+      "Error": {"content": {"application/json": {"schema": {"type": "object", "properties": {"code": {"type": "string"}}, "required": ["code"]}}}}
+    },
+    "schemas": {
+      "LoanRequest": {
+        "type": "object",
+        "properties": {
+          "amount": {"type": "integer", "minimum": 1},
+          "reason": {"type": ["string", "null"], "maxLength": 10},
+          "start": {"type": "string", "format": "date"},
+          "installments": {"type": "object", "additionalProperties": {"type": "integer"}}
+        },
+        "required": ["amount", "start"],
+        "additionalProperties": false
+      },
+      "Loan": {
+        "type": "object",
+        "properties": {"ID": {"type": "integer"}, "Status": {"type": "string", "pattern": "^[a-z]+$"}},
+        "required": ["ID", "Status"]
+      }
+    }
+  }
+}`
+
+func TestLoad(t *testing.T) {
+	spec, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+
+	var names []string
+	for _, route := range spec.Routes() {
+		names = append(names, route.Method+" "+route.Path+" "+route.Operation.OperationID)
+	}
+	assert.Equal(t, []string{
+		"POST /loans requestLoan",
+		"GET /loans/pending listPendingLoans",
+		"GET /loans/{loanId} getLoan",
+	}, names)
+
+	_, err = Load([]byte(strings.Replace(testSpec, "#/components/schemas/Loan\"", "#/components/schemas/Debt\"", 1)))
+	assert.ErrorContains(t, err, "unknown #/components/schemas/Debt")
+
+	_, err = Load([]byte(strings.Replace(testSpec, `^[a-z]+$`, `^[a-z+$`, 1)))
+	assert.ErrorContains(t, err, "pattern")
+
+	_, err = Load([]byte(strings.Replace(testSpec, `"Loan": {`, `"Loan": {"$ref": "#/components/schemas/Loan",`, 1)))
+	assert.ErrorContains(t, err, "circular #/components/schemas/Loan")
+}
+
+func TestFind(t *testing.T) {
+	spec, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		method    string
+		path      string
+		operation string
+		params    map[string]string
+	}{
+		"static path":                      {http.MethodPost, "/loans", "requestLoan", map[string]string{}},
+		"path parameter":                   {http.MethodGet, "/loans/7", "getLoan", map[string]string{"loanId": "7"}},
+		"static segment wins over a param": {http.MethodGet, "/loans/pending", "listPendingLoans", map[string]string{}},
+		"other method":                     {http.MethodDelete, "/loans/7", "", nil},
+		"unknown path":                     {http.MethodGet, "/loans/7/installments", "", nil},
+		"empty parameter":                  {http.MethodGet, "/loans/", "", nil},
+	}
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			route, params, ok := spec.Find(sc.method, sc.path)
+			if sc.operation == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, sc.operation, route.Operation.OperationID)
+			assert.Equal(t, sc.params, params)
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		method     string
+		target     string
+		header     map[string]string
+		body       string
+		violations []Violation
+	}{
+		"valid body": {
+			http.MethodPost, "/loans", nil, `{"amount": 5000000, "start": "2025-07-01", "reason": null, "installments": {"7": 500000}}`, nil,
+		},
+		"missing body": {
+			http.MethodPost, "/loans", nil, ``, []Violation{{"body", "is required"}},
+		},
+		"not JSON": {
+			http.MethodPost, "/loans", nil, `amount=5`, []Violation{{"body", "must be JSON: invalid character 'a' looking for beginning of value"}},
+		},
+		"wrong types and unknown field": {
+			http.MethodPost, "/loans", nil, `{"amount": 1.5, "start": "01-07-2025", "reason": "renovasi rumah", "installments": {"7": "x"}, "tenor": 12}`,
+			[]Violation{
+				{"body.amount", "must be integer"},
+				{"body.installments.7", "must be integer"},
+				{"body.reason", "must be at most 10 characters"},
+				{"body.start", "must be a date"},
+				{"body.tenor", "is not allowed"},
+			},
+		},
+		"missing required field": {
+			http.MethodPost, "/loans", nil, `{"amount": 0}`,
+			[]Violation{{"body.start", "is required"}, {"body.amount", "must be at least 1"}},
+		},
+		"path parameter": {
+			http.MethodGet, "/loans/abc", nil, ``, []Violation{{"path.loanId", "must be integer"}},
+		},
+		"query array": {
+			http.MethodGet, "/loans/7?statuses=active&statuses=late", nil, ``,
+			[]Violation{{"query.statuses.1", "must be one of active, paid"}},
+		},
+		"query bounds and required header": {
+			http.MethodGet, "/loans/pending?limit=500", nil, ``,
+			[]Violation{{"query.limit", "must be at most 100"}, {"header.X-Step-Up-Token", "is required"}},
+		},
+		"valid query and header": {
+			http.MethodGet, "/loans/pending?limit=10&unknown=1", map[string]string{"X-Step-Up-Token": "token"}, ``, nil,
+		},
+	}
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(sc.method, sc.target, nil)
+			for key, value := range sc.header {
+				req.Header.Set(key, value)
+			}
+			route, params, ok := spec.Find(req.Method, req.URL.Path)
+			require.True(t, ok)
+			assert.Equal(t, sc.violations, route.ValidateRequest(req, params, []byte(sc.body)))
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+	route, _, ok := spec.Find(http.MethodPost, "/loans")
+	require.True(t, ok)
+
+	assert.Empty(t, route.ValidateResponse(http.StatusOK, []byte(`{"success": true, "data": {"ID": 7, "Status": "active", "Amount": 5000000}}`)))
+	assert.Empty(t, route.ValidateResponse(http.StatusOK, []byte(`{"success": true, "data": null}`)))
+	assert.Empty(t, route.ValidateResponse(http.StatusConflict, []byte(`{"code": "LOAN_ALREADY_PROCESSED"}`)), "the default response")
+
+	assert.Equal(t, []Violation{{"response.data", "must match exactly one schema of oneOf, matched 0"}},
+		route.ValidateResponse(http.StatusOK, []byte(`{"success": true, "data": {"ID": 7, "Status": "Active"}}`)))
+	assert.Equal(t, []Violation{{"response.trace", "is not allowed"}},
+		route.ValidateResponse(http.StatusOK, []byte(`{"success": true, "data": null, "trace": "4bf92f35"}`)))
+	assert.Equal(t, []Violation{{"response.code", "is required"}},
+		route.ValidateResponse(http.StatusNotFound, []byte(`{}`)))
+}
@@ -0,0 +1,253 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string               `json:"$ref"`
+	Type                 Types                `json:"type"`
+	Format               string               `json:"format"`
+	Enum                 []any                `json:"enum"`
+	Properties           map[string]*Schema   `json:"properties"`
+	Required             []string             `json:"required"`
+	AdditionalProperties AdditionalProperties `json:"additionalProperties"`
+	Items                *Schema              `json:"items"`
+	OneOf                []*Schema            `json:"oneOf"`
+	Pattern              string               `json:"pattern"`
+	Minimum              *float64             `json:"minimum"`
+	Maximum              *float64             `json:"maximum"`
+	MinLength            *int                 `json:"minLength"`
+	MaxLength            *int                 `json:"maxLength"`
+	MinItems             *int                 `json:"minItems"`
+	MaxItems             *int                 `json:"maxItems"`
+
+	target  *Schema // of Ref
+	pattern *regexp.Regexp
+}
+
+// Types is the type keyword, a string or a list (eg: ["string", "null"])
+type Types []string
+
+func (t *Types) UnmarshalJSON(raw []byte) error {
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t Types) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AdditionalProperties is false (Forbidden) or the schema of the values, absent allows anything
+type AdditionalProperties struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(raw []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(raw, &allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	a.Schema = &Schema{}
+	return json.Unmarshal(raw, a.Schema)
+}
+
+// Violation is a value not matching its schema, Field is where it is (eg: body.amounts.7, query.month)
+type Violation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Reason
+}
+
+// Validate checks a JSON value decoded with UseNumber (see Decode)
+func (s *Schema) Validate(field string, value any) []Violation {
+	var violations []Violation
+	s.validate(field, value, &violations)
+	return violations
+}
+
+// Decode reads a JSON document keeping its numbers as json.Number, an integer is told from a number
+func Decode(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("more than one JSON value")
+	}
+	return value, nil
+}
+
+func (s *Schema) validate(field string, value any, violations *[]Violation) {
+	if s == nil {
+		return
+	}
+	if s.target != nil {
+		s.target.validate(field, value, violations)
+		return
+	}
+	add := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, one := range s.OneOf {
+			if len(one.Validate(field, value)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			add("must match exactly one schema of oneOf, matched %d", matched)
+			return
+		}
+	}
+
+	if len(s.Type) > 0 && !s.Type.Has(typeOf(value)) && !(s.Type.Has("number") && typeOf(value) == "integer") {
+		add("must be %s", strings.Join(s.Type, " or "))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		values := make([]string, 0, len(s.Enum))
+		for _, e := range s.Enum {
+			values = append(values, fmt.Sprint(e))
+		}
+		add("must be one of %s", strings.Join(values, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			add("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && len([]rune(v)) > *s.MaxLength {
+			add("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			add("must match %s", s.Pattern)
+		}
+		if !validFormat(s.Format, v) {
+			add("must be a %s", s.Format)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			add("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			add("must be at most %v", *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			add("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			add("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s.%d", field, i), item, violations)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Field: field + "." + name, Reason: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(field+"."+name, v[name], violations)
+				continue
+			}
+			if s.AdditionalProperties.Forbidden {
+				*violations = append(*violations, Violation{Field: field + "." + name, Reason: "is not allowed"})
+				continue
+			}
+			s.AdditionalProperties.Schema.validate(field+"."+name, v[name], violations)
+		}
+	}
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func validFormat(format string, value string) bool {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "email":
+		_, err = mail.ParseAddress(value)
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && u.Scheme == "" {
+			return false
+		}
+	}
+	return err == nil
+}
@@ -0,0 +1,260 @@
+// Package openapi loads an OpenAPI 3.1 document and validates requests and responses against it. It covers
+// the part of JSON Schema the payroll spec uses: type (a list for nullable), properties, required,
+// additionalProperties, items, enum, oneOf, format (date, date-time, email, uri), pattern and the bounds.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Spec struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	routes []*Route
+}
+
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+}
+
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Patch      *Operation   `json:"patch"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Parameters  []*Parameter          `json:"parameters"`
+	RequestBody *RequestBody          `json:"requestBody"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path, query or header
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+	Example  any     `json:"example"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema  *Schema         `json:"schema"`
+	Example json.RawMessage `json:"example"`
+}
+
+// Route is an operation of the spec, Path is its template (eg: /users/{userId}/unlock)
+type Route struct {
+	Method    string
+	Path      string
+	Operation *Operation
+
+	Parameters []*Parameter // of the path item and the operation
+	segments   []string
+}
+
+// Load parses the document and resolves its $ref, only local references (#/components/...) are supported
+func Load(raw []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+
+	r := resolver{spec: &spec, resolved: make(map[*Schema]bool)}
+	for _, schema := range spec.Components.Schemas {
+		r.schema(schema, "components")
+	}
+	for _, param := range spec.Components.Parameters {
+		r.schema(param.Schema, "parameter "+param.Name)
+	}
+	for _, resp := range spec.Components.Responses {
+		for _, media := range resp.Content {
+			r.schema(media.Schema, "components")
+		}
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, op := range []struct {
+			method    string
+			operation *Operation
+		}{
+			{http.MethodGet, item.Get}, {http.MethodPut, item.Put}, {http.MethodPost, item.Post},
+			{http.MethodDelete, item.Delete}, {http.MethodPatch, item.Patch},
+		} {
+			if op.operation == nil {
+				continue
+			}
+			where := op.method + " " + path
+			route := &Route{Method: op.method, Path: path, Operation: op.operation, segments: strings.Split(strings.Trim(path, "/"), "/")}
+			for _, param := range append(append([]*Parameter{}, item.Parameters...), op.operation.Parameters...) {
+				param = r.parameter(param, where)
+				r.schema(param.Schema, where)
+				route.Parameters = append(route.Parameters, param)
+			}
+			if body := op.operation.RequestBody; body != nil {
+				for _, media := range body.Content {
+					r.schema(media.Schema, where)
+				}
+			}
+			for status, resp := range op.operation.Responses {
+				resp = r.response(resp, where)
+				op.operation.Responses[status] = resp
+				for _, media := range resp.Content {
+					r.schema(media.Schema, where)
+				}
+			}
+			spec.routes = append(spec.routes, route)
+		}
+	}
+	if len(r.errs) > 0 {
+		return nil, fmt.Errorf("openapi: %s", strings.Join(r.errs, "; "))
+	}
+	return &spec, nil
+}
+
+// Routes are the operations of the spec ordered by path
+func (s *Spec) Routes() []*Route {
+	return s.routes
+}
+
+// Find is the route of the request path, a static segment wins over a parameter
+// (eg: /users/tokens over /users/{userId}). The path parameters are returned by name.
+func (s *Spec) Find(method string, path string) (*Route, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var best *Route
+	var bestParams map[string]string
+	bestStatic := -1
+	for _, route := range s.routes {
+		if route.Method != method || len(route.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		static := 0
+		match := true
+		for i, segment := range route.segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				if segments[i] == "" {
+					match = false
+					break
+				}
+				params[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				match = false
+				break
+			}
+			static++
+		}
+		if match && static > bestStatic {
+			best, bestParams, bestStatic = route, params, static
+		}
+	}
+	return best, bestParams, best != nil
+}
+
+type resolver struct {
+	spec     *Spec
+	resolved map[*Schema]bool
+	errs     []string
+}
+
+const (
+	schemaRef    = "#/components/schemas/"
+	parameterRef = "#/components/parameters/"
+	responseRef  = "#/components/responses/"
+)
+
+func (r *resolver) parameter(param *Parameter, where string) *Parameter {
+	if param.Ref == "" {
+		return param
+	}
+	found, ok := r.spec.Components.Parameters[strings.TrimPrefix(param.Ref, parameterRef)]
+	if !ok || !strings.HasPrefix(param.Ref, parameterRef) {
+		r.errs = append(r.errs, fmt.Sprintf("%s: unknown %s", where, param.Ref))
+		return &Parameter{}
+	}
+	return found
+}
+
+func (r *resolver) response(resp *Response, where string) *Response {
+	if resp.Ref == "" {
+		return resp
+	}
+	found, ok := r.spec.Components.Responses[strings.TrimPrefix(resp.Ref, responseRef)]
+	if !ok || !strings.HasPrefix(resp.Ref, responseRef) {
+		r.errs = append(r.errs, fmt.Sprintf("%s: unknown %s", where, resp.Ref))
+		return &Response{}
+	}
+	return found
+}
+
+// schema links every $ref of the schema to its component and compiles the patterns
+func (r *resolver) schema(schema *Schema, where string) {
+	if schema == nil || r.resolved[schema] {
+		return
+	}
+	r.resolved[schema] = true
+
+	if schema.Ref != "" {
+		found, ok := r.spec.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRef)]
+		if !ok || !strings.HasPrefix(schema.Ref, schemaRef) {
+			r.errs = append(r.errs, fmt.Sprintf("%s: unknown %s", where, schema.Ref))
+			return
+		}
+		for next := found; next != nil; next = next.target {
+			if next == schema {
+				r.errs = append(r.errs, fmt.Sprintf("%s: circular %s", where, schema.Ref))
+				return
+			}
+		}
+		schema.target = found
+		r.schema(found, where)
+		return
+	}
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Sprintf("%s: pattern %q: %v", where, schema.Pattern, err))
+		}
+		schema.pattern = re
+	}
+	for _, property := range schema.Properties {
+		r.schema(property, where)
+	}
+	r.schema(schema.Items, where)
+	r.schema(schema.AdditionalProperties.Schema, where)
+	for _, one := range schema.OneOf {
+		r.schema(one, where)
+	}
+}
@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const jsonContent = "application/json"
+
+// ValidateRequest checks the parameters and the JSON body of a request to the route, params are the path
+// parameters given by Find and body is the request body already read. A query parameter outside the spec
+// is ignored.
+func (route *Route) ValidateRequest(r *http.Request, params map[string]string, body []byte) []Violation {
+	var violations []Violation
+	query := r.URL.Query()
+	for _, param := range route.Parameters {
+		field := param.In + "." + param.Name
+		var values []string
+		switch param.In {
+		case "path":
+			if value, ok := params[param.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = r.Header.Values(param.Name)
+		}
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if param.Required {
+				violations = append(violations, Violation{Field: field, Reason: "is required"})
+			}
+			continue
+		}
+
+		var value any
+		if schema := param.Schema.resolve(); schema != nil && schema.Type.Has("array") {
+			items := make([]any, 0, len(values))
+			for _, v := range values {
+				items = append(items, parameterValue(schema.Items, v))
+			}
+			value = items
+		} else {
+			value = parameterValue(param.Schema, values[0])
+		}
+		violations = append(violations, param.Schema.Validate(field, value)...)
+	}
+
+	requestBody := route.Operation.RequestBody
+	if requestBody == nil {
+		return violations
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if requestBody.Required {
+			violations = append(violations, Violation{Field: "body", Reason: "is required"})
+		}
+		return violations
+	}
+	media, ok := requestBody.Content[jsonContent]
+	if !ok {
+		return violations
+	}
+	value, err := Decode(body)
+	if err != nil {
+		return append(violations, Violation{Field: "body", Reason: "must be JSON: " + err.Error()})
+	}
+	return append(violations, media.Schema.Validate("body", value)...)
+}
+
+// ValidateResponse checks a JSON response of the route against the response of its status,
+// or the default response
+func (route *Route) ValidateResponse(status int, body []byte) []Violation {
+	resp, ok := route.Operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = route.Operation.Responses["default"]
+	}
+	if !ok {
+		return []Violation{{Field: "status", Reason: strconv.Itoa(status) + " is not documented"}}
+	}
+	media, ok := resp.Content[jsonContent]
+	if !ok {
+		return nil
+	}
+	value, err := Decode(body)
+	if err != nil {
+		return []Violation{{Field: "response", Reason: "must be JSON: " + err.Error()}}
+	}
+	return media.Schema.Validate("response", value)
+}
+
+// parameterValue converts the text of a parameter to the type of its schema, a text that does not
+// convert stays a string and fails the type check
+func parameterValue(schema *Schema, text string) any {
+	schema = schema.resolve()
+	if schema == nil {
+		return text
+	}
+	switch {
+	case schema.Type.Has("integer"), schema.Type.Has("number"):
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(text)
+		}
+	case schema.Type.Has("boolean"):
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}
+
+// resolve follows the $ref of the schema
+func (s *Schema) resolve() *Schema {
+	if s != nil && s.target != nil {
+		return s.target
+	}
+	return s
+}
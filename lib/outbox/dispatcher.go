@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Sink receives the events, an error makes the dispatcher retry the event later
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event *data.OutboxEvent) error
+}
+
+const (
+	defaultBatchSize = 100
+
+	// pollInterval is how long the dispatcher waits when there is nothing to deliver
+	pollInterval = 2 * time.Second
+
+	firstRetryDelay = 5 * time.Second
+	maxRetryDelay   = time.Hour
+)
+
+// Dispatcher relays the events of the outbox table to every sink. Instances may run a dispatcher each,
+// an event being delivered is locked so only one of them delivers it.
+type Dispatcher struct {
+	pool      *pgxpool.Pool
+	sinks     []Sink
+	batchSize int
+	now       func() time.Time
+}
+
+func NewDispatcher(pool *pgxpool.Pool, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		pool:      pool,
+		sinks:     sinks,
+		batchSize: defaultBatchSize,
+		now:       time.Now,
+	}
+}
+
+// Run delivers the events until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		delivered, err := d.Dispatch(ctx)
+		if err != nil {
+			log.Error(nil).Err(err).Msg("outbox/ dispatch failed")
+		}
+		// a full batch means more may be waiting
+		if err == nil && delivered == d.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// pendingQuery locks the events due for delivery. An event waits until the earlier events of its aggregate
+// are published, so a failing event holds back the later ones of the same aggregate only.
+const pendingQuery = `
+	SELECT id, event_type, aggregate_type, aggregate_id, payload::text, trace_id, occurred_at, attempts, last_error, next_attempt_at
+	FROM outbox_events e
+	WHERE e.published_at IS NULL AND e.next_attempt_at <= $1
+	  AND NOT EXISTS (
+	      SELECT 1 FROM outbox_events p
+	      WHERE p.published_at IS NULL AND p.aggregate_type = e.aggregate_type
+	        AND p.aggregate_id = e.aggregate_id AND p.id < e.id
+	  )
+	ORDER BY e.id
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+`
+
+// Dispatch delivers one batch of due events and returns how many were handled, delivered or not
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	tx, err := d.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := d.now().UTC()
+	events, err := pendingEvents(ctx, tx, now, d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := publish(ctx, d.sinks, event); err != nil {
+			event.Attempts++
+			delay := retryDelay(event.Attempts)
+			log.Warn(nil).Err(err).Int64("eventId", event.Id).Str("type", event.Type).Int("attempts", event.Attempts).
+				Dur("retryIn", delay).Msg("outbox/ delivery failed")
+			_, err = tx.Exec(ctx, `UPDATE outbox_events SET attempts = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1`,
+				event.Id, event.Attempts, err.Error(), now.Add(delay))
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE outbox_events SET attempts = attempts + 1, last_error = '', published_at = $2 WHERE id = $1`,
+				event.Id, d.now().UTC())
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+func pendingEvents(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]*data.OutboxEvent, error) {
+	rows, err := tx.Query(ctx, pendingQuery, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*data.OutboxEvent, 0)
+	for rows.Next() {
+		var e data.OutboxEvent
+		var payload string
+		err := rows.Scan(&e.Id, &e.Type, &e.AggregateType, &e.AggregateId, &payload, &e.TraceId, &e.OccurredAt,
+			&e.Attempts, &e.LastError, &e.NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		result = append(result, &e)
+	}
+	return result, rows.Err()
+}
+
+// publish gives the event to every sink. When one sink fails the event is retried on every sink,
+// the sinks that already got it receive it again.
+func publish(ctx context.Context, sinks []Sink, event *data.OutboxEvent) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryDelay doubles the wait after every failed attempt, from 5 seconds up to an hour
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,48 @@
+// Package outbox is the transactional outbox: a service records the domain event of a change in the
+// transaction of the change, the dispatcher relays committed events to the sinks (ERP, chat, warehouse, ...).
+// A sink receives every event at least once, events of one aggregate in the order they were recorded.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/database"
+)
+
+// NewEvent builds the event of a change, payload becomes the data of the event
+func NewEvent(eventType string, aggregateType string, aggregateId string, payload any, trace *contextutil.Trace) (*data.OutboxEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond) // precision of postgres timestamp
+	event := &data.OutboxEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		Payload:       raw,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	}
+	if trace != nil {
+		event.TraceId = trace.TraceID
+	}
+	return event, nil
+}
+
+// Insert stores the event and fills Id, q must be the transaction of the change (see database.Conn)
+// so the event is committed or rolled back with it
+func Insert(ctx context.Context, q database.Querier, event *data.OutboxEvent) error {
+	const query = `
+		INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload, trace_id, occurred_at, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	return q.QueryRow(ctx, query, event.Type, event.AggregateType, event.AggregateId, string(event.Payload),
+		event.TraceId, event.OccurredAt, event.NextAttemptAt).Scan(&event.Id)
+}
@@ -0,0 +1,173 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+	"github.com/ariesmaulana/payroll/lib/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink keeps the delivered events, it fails while failing is set
+type fakeSink struct {
+	name      string
+	delivered []*data.OutboxEvent
+	failing   bool
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	if s.failing {
+		return errors.New("unavailable")
+	}
+	s.delivered = append(s.delivered, event)
+	return nil
+}
+
+func TestNewEvent(t *testing.T) {
+	event, err := NewEvent(data.EventOvertimeSubmitted, "employee", "7", map[string]any{"user_id": 7, "hours": 2},
+		&contextutil.Trace{TraceID: "trace-1"})
+	require.NoError(t, err)
+	assert.Equal(t, data.EventOvertimeSubmitted, event.Type)
+	assert.Equal(t, "employee", event.AggregateType)
+	assert.Equal(t, "7", event.AggregateId)
+	assert.JSONEq(t, `{"user_id":7,"hours":2}`, string(event.Payload))
+	assert.Equal(t, "trace-1", event.TraceId)
+	assert.Equal(t, event.OccurredAt, event.NextAttemptAt)
+
+	event, err = NewEvent(data.EventPayrollFinalized, "payroll", "1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", event.TraceId)
+	assert.Equal(t, "null", string(event.Payload))
+
+	_, err = NewEvent(data.EventPayrollFinalized, "payroll", "1", func() {}, nil)
+	assert.Error(t, err)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 5*time.Second, retryDelay(1))
+	assert.Equal(t, 10*time.Second, retryDelay(2))
+	assert.Equal(t, 40*time.Second, retryDelay(4))
+	assert.Equal(t, time.Hour, retryDelay(11))
+	assert.Equal(t, time.Hour, retryDelay(1000))
+}
+
+func TestPublish(t *testing.T) {
+	erp := &fakeSink{name: "erp"}
+	slack := &fakeSink{name: "slack", failing: true}
+	event := &data.OutboxEvent{Id: 1, Type: data.EventAttendanceCheckedIn}
+
+	err := publish(context.Background(), []Sink{erp, slack}, event)
+	assert.EqualError(t, err, "slack: unavailable")
+	// the healthy sink still gets the event
+	assert.Len(t, erp.delivered, 1)
+
+	slack.failing = false
+	require.NoError(t, publish(context.Background(), []Sink{erp, slack}, event))
+	assert.Len(t, erp.delivered, 2)
+	assert.Len(t, slack.delivered, 1)
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	event := &data.OutboxEvent{
+		Id: 42, Type: data.EventPayrollFinalized, AggregateType: "payroll", AggregateId: "3",
+		Payload: json.RawMessage(`{"id":3}`), TraceId: "trace-1", OccurredAt: time.Date(2025, 3, 25, 6, 0, 0, 0, time.UTC),
+		Attempts: 2, LastError: "status 500",
+	}
+
+	sink := NewHTTPSink(server.URL, "secret")
+	require.NoError(t, sink.Publish(context.Background(), event))
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "Bearer secret", got.Header.Get("Authorization"))
+	assert.Equal(t, "42", got.Header.Get("X-Event-Id"))
+	assert.Equal(t, data.EventPayrollFinalized, got.Header.Get("X-Event-Type"))
+	assert.JSONEq(t, `{"id":42,"type":"payroll.finalized","aggregate_type":"payroll","aggregate_id":"3",
+		"data":{"id":3},"trace_id":"trace-1","occurred_at":"2025-03-25T06:00:00Z"}`, string(body))
+
+	status = http.StatusInternalServerError
+	assert.EqualError(t, sink.Publish(context.Background(), event), "status 500")
+
+	sink = NewHTTPSink(server.URL, "")
+	status = http.StatusOK
+	require.NoError(t, sink.Publish(context.Background(), event))
+	assert.Equal(t, "", got.Header.Get("Authorization"))
+}
+
+func TestDispatcherOrderAndRetry(t *testing.T) {
+	t.Parallel()
+
+	con := test.DbTestPool(t)
+	insert := func(eventType string, aggregateId string) *data.OutboxEvent {
+		event, err := NewEvent(eventType, "employee", aggregateId, map[string]string{"user_id": aggregateId}, nil)
+		require.NoError(t, err)
+		require.NoError(t, Insert(con.Context, con.Pool, event))
+		return event
+	}
+	first := insert(data.EventAttendanceCheckedIn, "2")
+	second := insert(data.EventOvertimeSubmitted, "2")
+	other := insert(data.EventAttendanceCheckedIn, "3")
+
+	now := time.Now()
+	sink := &fakeSink{name: "erp", failing: true}
+	dispatcher := NewDispatcher(con.Pool, sink)
+	dispatcher.now = func() time.Time { return now }
+
+	// the first event of employee 2 fails and holds back the second, employee 3 is not blocked
+	handled, err := dispatcher.Dispatch(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 2, handled)
+
+	var attempts int
+	var lastError string
+	err = con.Pool.QueryRow(con.Context, `SELECT attempts, last_error FROM outbox_events WHERE id = $1`, first.Id).Scan(&attempts, &lastError)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, "erp: unavailable", lastError)
+
+	// not due before the retry delay
+	sink.failing = false
+	handled, err = dispatcher.Dispatch(con.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 0, handled)
+
+	now = now.Add(retryDelay(1))
+	handled, err = dispatcher.Dispatch(con.Context)
+	require.NoError(t, err)
+	require.Equal(t, 2, handled)
+
+	// the second event of employee 2 waited for the first to be published
+	handled, err = dispatcher.Dispatch(con.Context)
+	require.NoError(t, err)
+	require.Equal(t, 1, handled)
+
+	ids := []int64{}
+	for _, event := range sink.delivered {
+		ids = append(ids, event.Id)
+	}
+	assert.ElementsMatch(t, []int64{first.Id, other.Id}, ids[:2])
+	assert.Equal(t, second.Id, ids[2])
+
+	var pending int
+	require.NoError(t, con.Pool.QueryRow(con.Context, `SELECT COUNT(*) FROM outbox_events WHERE published_at IS NULL`).Scan(&pending))
+	assert.Equal(t, 0, pending)
+}
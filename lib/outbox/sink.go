@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+)
+
+// LogSink writes the events to the application log, for local development
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	log.Info(nil).Int64("eventId", event.Id).Str("type", event.Type).
+		Str("aggregate", event.AggregateType+"/"+event.AggregateId).RawJSON("data", event.Payload).Msg("outbox event")
+	return nil
+}
+
+// HTTPSink posts every event as JSON to one endpoint, eg: the ERP integration. Any status other than 2xx is a failure.
+type HTTPSink struct {
+	URL string
+
+	// Token is sent as bearer token when set
+	Token  string
+	Client *http.Client
+}
+
+func NewHTTPSink(url string, token string) *HTTPSink {
+	return &HTTPSink{URL: url, Token: token, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *HTTPSink) Name() string { return "http" }
+
+func (s *HTTPSink) Publish(ctx context.Context, event *data.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// the receiver drops an event id it already processed
+	req.Header.Set("X-Event-Id", strconv.FormatInt(event.Id, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
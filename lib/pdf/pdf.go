@@ -0,0 +1,119 @@
+// Package pdf is a minimal PDF writer for text only documents (payslip, tax form, etc).
+// It only support the standard Helvetica fonts and A4 pages, which is enough for our reports
+// without pulling a full PDF library.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// A4 size in point (1/72 inch)
+	PageWidth  = 595
+	PageHeight = 842
+)
+
+type Font string
+
+const (
+	FontRegular Font = "F1"
+	FontBold    Font = "F2"
+)
+
+type Document struct {
+	pages []*bytes.Buffer
+}
+
+func NewDocument() *Document {
+	return &Document{}
+}
+
+// AddPage starts a new page, next Text call is written to this page
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// Text writes text at position x,y (in point, origin is the top left of the page)
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PageHeight-y, escape(text))
+}
+
+// Line draws a horizontal line from x1 to x2 at position y
+func (d *Document) Line(x1, x2, y float64) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PageHeight-y, x2, PageHeight-y)
+}
+
+// WriteTo writes the whole document as PDF 1.4
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// object 1: catalog, object 2: pages, object 3 & 4: fonts, then page & content per page
+	pageCount := len(d.pages)
+	kids := make([]string, pageCount)
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	writeObj("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		writeObj(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 6+i*2,
+		))
+		writeObj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// escape makes text safe inside a PDF string literal, non ASCII character is replaced with '?'
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
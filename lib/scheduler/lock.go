@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// the lock is per schema, so the parallel test schemas do not elect one leader for all of them
+const (
+	tryLockQuery = `SELECT pg_try_advisory_lock(hashtext('scheduler.' || current_schema()))`
+	unlockQuery  = `SELECT pg_advisory_unlock(hashtext('scheduler.' || current_schema()))`
+)
+
+// AdvisoryLocker is a Postgres session advisory lock. The lock belongs to one connection of the pool
+// that is kept until Unlock, when the connection breaks Postgres releases the lock for another instance.
+type AdvisoryLocker struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+func NewAdvisoryLocker(pool *pgxpool.Pool) *AdvisoryLocker {
+	return &AdvisoryLocker{pool: pool}
+}
+
+func (l *AdvisoryLocker) TryLock(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		return true, nil
+	}
+
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, tryLockQuery).Scan(&acquired); err != nil {
+		conn.Release()
+		return false, err
+	}
+	if !acquired {
+		conn.Release()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+func (l *AdvisoryLocker) Held(ctx context.Context) bool {
+	if l.conn == nil {
+		return false
+	}
+	if err := l.conn.Conn().Ping(ctx); err != nil {
+		// the lock went with the connection, do not put a broken connection back
+		l.conn.Conn().Close(context.Background())
+		l.conn.Release()
+		l.conn = nil
+		return false
+	}
+	return true
+}
+
+func (l *AdvisoryLocker) Unlock(ctx context.Context) {
+	if l.conn == nil {
+		return
+	}
+	l.conn.Exec(ctx, unlockQuery)
+	l.conn.Release()
+	l.conn = nil
+}
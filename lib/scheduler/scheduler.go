@@ -0,0 +1,196 @@
+// Package scheduler runs jobs on cron schedules inside the server process. Every instance runs a scheduler
+// but only the one holding the leader lock runs jobs, when it dies another instance takes over.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/cron"
+	log "github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// JobFunc does the work of a job, detail is stored in the run history
+type JobFunc func(ctx context.Context, scheduledAt time.Time) (detail string, err error)
+
+// Locker elects the leader, only the instance holding the lock runs jobs
+type Locker interface {
+	// TryLock returns false when another instance holds the lock
+	TryLock(ctx context.Context) (bool, error)
+
+	// Held checks the lock is still ours, eg: the database connection holding it is alive
+	Held(ctx context.Context) bool
+	Unlock(ctx context.Context)
+}
+
+// Recorder stores the run history
+type Recorder interface {
+	// StartRun returns false when the run of the job at scheduledAt was already started, eg: by the previous leader
+	StartRun(ctx context.Context, job string, scheduledAt time.Time, instance string) (int64, bool, error)
+	FinishRun(ctx context.Context, runId int64, status data.JobRunStatus, detail string) error
+}
+
+const (
+	// tickInterval is how often the due jobs and the leadership are checked
+	tickInterval = 15 * time.Second
+
+	// catchUp is how far back a new leader still runs a missed schedule, eg: the previous leader died at 17:00:05
+	catchUp = 5 * time.Minute
+)
+
+type entry struct {
+	name     string
+	spec     string
+	schedule *cron.Schedule
+	run      JobFunc
+	next     time.Time
+}
+
+type Scheduler struct {
+	locker   Locker
+	recorder Recorder
+	location *time.Location
+	instance string
+
+	entries []*entry
+	leader  bool
+	now     func() time.Time
+}
+
+// New returns a scheduler, the cron expressions are read in location. Instance names the host in the run history.
+func New(locker Locker, recorder Recorder, location *time.Location, instance string) *Scheduler {
+	return &Scheduler{
+		locker:   locker,
+		recorder: recorder,
+		location: location,
+		instance: instance,
+		now:      time.Now,
+	}
+}
+
+// Add registers a job, an empty spec disables it
+func (s *Scheduler) Add(name string, spec string, run JobFunc) error {
+	if spec == "" {
+		log.Info(nil).Str("job", name).Msg("scheduler/ job disabled")
+		return nil
+	}
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	s.entries = append(s.entries, &entry{name: name, spec: spec, schedule: schedule, run: run})
+	return nil
+}
+
+// Run blocks until ctx is done, then releases the leader lock
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			if s.leader {
+				s.locker.Unlock(context.Background())
+				s.leader = false
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick keeps the leadership up to date and runs the due jobs when leader
+func (s *Scheduler) tick(ctx context.Context) {
+	now := s.now().In(s.location)
+
+	if s.leader && !s.locker.Held(ctx) {
+		log.Warn(nil).Str("instance", s.instance).Msg("scheduler/ leader lock lost")
+		s.leader = false
+	}
+
+	if !s.leader {
+		acquired, err := s.locker.TryLock(ctx)
+		if err != nil {
+			log.Error(nil).Err(err).Msg("scheduler/ failed try leader lock")
+			return
+		}
+		if !acquired {
+			return
+		}
+
+		log.Info(nil).Str("instance", s.instance).Msg("scheduler/ became leader")
+		s.leader = true
+		for _, e := range s.entries {
+			e.next = e.schedule.Next(now.Add(-catchUp))
+		}
+	}
+
+	s.runDue(ctx, now)
+}
+
+// runDue runs every job whose schedule has come, one after another. A schedule missed more than once
+// (eg: the previous job took long) is run only once.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		scheduledAt := e.next
+		e.next = e.schedule.Next(now)
+
+		if ctx.Err() != nil {
+			return
+		}
+		s.runJob(ctx, e, scheduledAt)
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, e *entry, scheduledAt time.Time) {
+	runId, started, err := s.recorder.StartRun(ctx, e.name, scheduledAt, s.instance)
+	if err != nil {
+		log.Error(nil).Err(err).Str("job", e.name).Msg("scheduler/ failed start run")
+		return
+	}
+	if !started {
+		log.Info(nil).Str("job", e.name).Time("scheduledAt", scheduledAt).Msg("scheduler/ run already done")
+		return
+	}
+
+	// the run is the root span of the service calls and queries of the job
+	jobCtx, span := tracing.Start(ctx, "job "+e.name)
+	detail, err := safeRun(jobCtx, e.run, scheduledAt)
+	status := data.JobSuccess
+	if err != nil {
+		status = data.JobFailed
+		detail = err.Error()
+		span.SetStatus(codes.Error, detail)
+		log.Error(nil).Err(err).Str("job", e.name).Str("traceId", span.SpanContext().TraceID().String()).Msg("scheduler/ job failed")
+	} else {
+		log.Info(nil).Str("job", e.name).Str("detail", detail).Str("traceId", span.SpanContext().TraceID().String()).Msg("scheduler/ job done")
+	}
+	span.End()
+
+	// the run is finished even when the job ctx was cancelled on shutdown
+	if err := s.recorder.FinishRun(context.Background(), runId, status, detail); err != nil {
+		log.Error(nil).Err(err).Str("job", e.name).Msg("scheduler/ failed finish run")
+	}
+}
+
+// safeRun turns a panic of the job into an error, a broken job must not stop the others
+func safeRun(ctx context.Context, run JobFunc, scheduledAt time.Time) (detail string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			log.Error(nil).Str("stack", string(debug.Stack())).Msg("scheduler/ job panic")
+		}
+	}()
+	return run(ctx, scheduledAt)
+}
@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ariesmaulana/payroll/common"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLocker struct {
+	free bool // another instance does not hold the lock
+	held bool
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context) (bool, error) {
+	if !l.free {
+		return false, nil
+	}
+	l.held = true
+	return true, nil
+}
+
+func (l *fakeLocker) Held(ctx context.Context) bool { return l.held }
+
+func (l *fakeLocker) Unlock(ctx context.Context) { l.held = false }
+
+type fakeRun struct {
+	job         string
+	scheduledAt time.Time
+	status      data.JobRunStatus
+	detail      string
+}
+
+// fakeRecorder keeps the runs like the job_runs table, one per job and schedule time
+type fakeRecorder struct {
+	runs []*fakeRun
+}
+
+func (r *fakeRecorder) StartRun(ctx context.Context, job string, scheduledAt time.Time, instance string) (int64, bool, error) {
+	for _, run := range r.runs {
+		if run.job == job && run.scheduledAt.Equal(scheduledAt) {
+			return 0, false, nil
+		}
+	}
+	r.runs = append(r.runs, &fakeRun{job: job, scheduledAt: scheduledAt, status: data.JobRunning})
+	return int64(len(r.runs)), true, nil
+}
+
+func (r *fakeRecorder) FinishRun(ctx context.Context, runId int64, status data.JobRunStatus, detail string) error {
+	r.runs[runId-1].status = status
+	r.runs[runId-1].detail = detail
+	return nil
+}
+
+func newTestScheduler(locker Locker, recorder Recorder, now *time.Time) *Scheduler {
+	s := New(locker, recorder, common.JakartaTZ, "test")
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestSchedulerRunsDueJobOnce(t *testing.T) {
+	now := time.Date(2025, 3, 10, 16, 59, 50, 0, common.JakartaTZ)
+	recorder := &fakeRecorder{}
+	s := newTestScheduler(&fakeLocker{free: true}, recorder, &now)
+
+	var calls []time.Time
+	require.NoError(t, s.Add("close", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		calls = append(calls, scheduledAt)
+		return "closed 2", nil
+	}))
+
+	s.tick(context.Background())
+	assert.Empty(t, calls, "not due yet")
+
+	now = now.Add(15 * time.Second)
+	s.tick(context.Background())
+	now = now.Add(15 * time.Second)
+	s.tick(context.Background())
+
+	require.Len(t, calls, 1)
+	assert.Equal(t, time.Date(2025, 3, 10, 17, 0, 0, 0, common.JakartaTZ), calls[0])
+	require.Len(t, recorder.runs, 1)
+	assert.Equal(t, data.JobSuccess, recorder.runs[0].status)
+	assert.Equal(t, "closed 2", recorder.runs[0].detail)
+
+	now = time.Date(2025, 3, 11, 17, 0, 5, 0, common.JakartaTZ)
+	s.tick(context.Background())
+	assert.Len(t, calls, 2, "next day")
+}
+
+func TestSchedulerOnlyLeaderRuns(t *testing.T) {
+	now := time.Date(2025, 3, 10, 17, 0, 5, 0, common.JakartaTZ)
+	locker := &fakeLocker{}
+	s := newTestScheduler(locker, &fakeRecorder{}, &now)
+
+	calls := 0
+	require.NoError(t, s.Add("close", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		calls++
+		return "", nil
+	}))
+
+	s.tick(context.Background())
+	assert.Equal(t, 0, calls, "another instance is the leader")
+
+	// the leader died at 17:00:05, the run it missed is still done
+	locker.free = true
+	now = now.Add(30 * time.Second)
+	s.tick(context.Background())
+	assert.Equal(t, 1, calls)
+}
+
+func TestSchedulerSkipsRunDoneByPreviousLeader(t *testing.T) {
+	now := time.Date(2025, 3, 10, 17, 1, 0, 0, common.JakartaTZ)
+	recorder := &fakeRecorder{}
+	recorder.runs = append(recorder.runs, &fakeRun{job: "close", scheduledAt: time.Date(2025, 3, 10, 17, 0, 0, 0, common.JakartaTZ), status: data.JobSuccess})
+	s := newTestScheduler(&fakeLocker{free: true}, recorder, &now)
+
+	calls := 0
+	require.NoError(t, s.Add("close", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		calls++
+		return "", nil
+	}))
+
+	s.tick(context.Background())
+	assert.Equal(t, 0, calls)
+	assert.Len(t, recorder.runs, 1)
+}
+
+func TestSchedulerLostLock(t *testing.T) {
+	now := time.Date(2025, 3, 10, 16, 0, 0, 0, common.JakartaTZ)
+	locker := &fakeLocker{free: true}
+	s := newTestScheduler(locker, &fakeRecorder{}, &now)
+
+	calls := 0
+	require.NoError(t, s.Add("hourly", "@hourly", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		calls++
+		return "", nil
+	}))
+
+	s.tick(context.Background())
+	assert.True(t, s.leader)
+
+	// the connection holding the lock broke and another instance took it
+	locker.held = false
+	locker.free = false
+	now = now.Add(time.Hour)
+	s.tick(context.Background())
+	assert.False(t, s.leader)
+	assert.Equal(t, 1, calls, "only the run of 16:00")
+}
+
+func TestSchedulerFailedJob(t *testing.T) {
+	now := time.Date(2025, 3, 10, 17, 0, 0, 0, common.JakartaTZ)
+	recorder := &fakeRecorder{}
+	s := newTestScheduler(&fakeLocker{free: true}, recorder, &now)
+
+	require.NoError(t, s.Add("fail", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		return "", errors.New("smtp down")
+	}))
+	require.NoError(t, s.Add("panic", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		panic("nil map")
+	}))
+	afterPanic := false
+	require.NoError(t, s.Add("after", "0 17 * * *", func(ctx context.Context, scheduledAt time.Time) (string, error) {
+		afterPanic = true
+		return "", nil
+	}))
+
+	s.tick(context.Background())
+
+	require.Len(t, recorder.runs, 3)
+	assert.Equal(t, data.JobFailed, recorder.runs[0].status)
+	assert.Equal(t, "smtp down", recorder.runs[0].detail)
+	assert.Equal(t, data.JobFailed, recorder.runs[1].status)
+	assert.Equal(t, "panic: nil map", recorder.runs[1].detail)
+	assert.True(t, afterPanic)
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	s := New(&fakeLocker{}, &fakeRecorder{}, common.JakartaTZ, "test")
+
+	assert.NoError(t, s.Add("disabled", "", nil))
+	assert.Empty(t, s.entries)
+
+	err := s.Add("broken", "0 25 * * *", nil)
+	assert.ErrorContains(t, err, "job broken")
+}
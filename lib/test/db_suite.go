@@ -2,16 +2,20 @@ package test
 
 import (
 	"context"
+	_ "embed"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/ariesmaulana/payroll/lib/migration"
+	"github.com/ariesmaulana/payroll/migrations"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+//go:embed seed.sql
+var seedSQL string
+
 type DbTestSuite struct {
 	Pool    *pgxpool.Pool
 	Context context.Context
@@ -69,30 +73,18 @@ func DbTestPool(t *testing.T) *DbTestSuite {
 		t.Fatalf("error while switching to schema. err: %v", err)
 	}
 
-	// populate the table
-	schemaDir := "../../schema" // Adjust path if needed
-	files, err := filepath.Glob(filepath.Join(schemaDir, "*.sql"))
+	// build the schema from the same migrations as the app, then the test users
+	migrator, err := migration.New(pool, migrations.FS)
 	if err != nil {
-		t.Fatalf("failed to read schema directory: %v", err)
+		t.Fatalf("failed to load migrations: %v", err)
 	}
-
-	if len(files) == 0 {
-		t.Fatalf("no SQL files found in schema directory")
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
 	}
 
-	for _, schemaPath := range files {
-		file, err := os.ReadFile(schemaPath)
-		if err != nil {
-			t.Fatalf("error reading file %s: %v", schemaPath, err)
-		}
-
-		_, err = pool.Exec(ctx, string(file))
-		if err != nil {
-			fmt.Printf("error executing %s: %v\n", schemaPath, err)
-			t.Fatal("Failed to execute file")
-		} else {
-			fmt.Printf("executed %s successfully\n", schemaPath)
-		}
+	_, err = pool.Exec(ctx, seedSQL)
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
 	}
 
 	return &DbTestSuite{
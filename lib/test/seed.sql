@@ -0,0 +1,7 @@
+-- users the service tests refer to by id, the test schema is built from the migrations first
+INSERT INTO users (username, email, fullname, password_hash, role, base_salary, join_date)
+VALUES 
+    ('testuser1', 'test1@example.com', 'User One', 'hashedpassword1' , 'admin', 100, '2025-01-01'),
+    ('testuser2', 'test2@example.com', 'User Two', 'hashedpassword2', 'employee', 12121, '2025-02-01'),
+    ('testuser3', 'test3@example.com', 'User Three', 'hashedpassword3', 'employee', 12121, '2025-03-01')
+ON CONFLICT (email) DO NOTHING;
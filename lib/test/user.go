@@ -0,0 +1,20 @@
+package test
+
+import (
+	"context"
+
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
+)
+
+// Username of the user put in the context by UserContext
+const Username = "test_user"
+
+// UserContext logs the user in ctx as AuthMiddleware does, for calling a service directly
+func UserContext(ctx context.Context, userId int, role data.UserRole) context.Context {
+	return contextutil.WithUser(ctx, &contextutil.AuthUser{
+		Id:       userId,
+		Username: Username,
+		Role:     role,
+	})
+}
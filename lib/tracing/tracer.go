@@ -0,0 +1,63 @@
+// Package tracing sets up OpenTelemetry: W3C trace context in and out of HTTP, one span per request,
+// service method (the generated InstrumentedService of each app) and Postgres query (database.QueryTracer),
+// exported by OTLP/HTTP to a collector (Jaeger, Tempo, the OpenTelemetry Collector).
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the scope of the spans of the app
+const instrumentationName = "github.com/ariesmaulana/payroll"
+
+// Until Setup the spans only get their ids, to link the logs and propagate the trace, nothing is exported
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Setup installs the global tracer provider. It samples the ratio (0-1) of the new traces, a trace started
+// by a caller keeps the caller's decision. The sampled spans are exported to endpoint, eg: http://otel-collector:4318,
+// when it is not empty. Shutdown of the provider sends the queued spans.
+func Setup(ctx context.Context, endpoint string, serviceName string, ratio float64) (*sdktrace.TracerProvider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	}
+	if endpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider, nil
+}
+
+// Start starts a span of the global tracer provider, child of the span in ctx
+func Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Extract continues the trace of the traceparent header of the caller, an invalid header is ignored
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject sets the traceparent header of an outgoing request to the current span
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExtract(t *testing.T) {
+	scenarios := map[string]struct {
+		header  string
+		ok      bool
+		sampled bool
+	}{
+		"sampled":         {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		"not sampled":     {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		"later version":   {"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		"version 00 tail": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		"invalid version": {"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		"zero trace id":   {"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		"zero span id":    {"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		"short":           {"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		"empty":           {"", false, false},
+	}
+
+	for name, sc := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("traceparent", sc.header)
+			spanContext := oteltrace.SpanContextFromContext(Extract(context.Background(), header))
+			assert.Equal(t, sc.ok, spanContext.IsValid())
+			if !sc.ok {
+				return
+			}
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+			assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+			assert.Equal(t, sc.sampled, spanContext.IsSampled())
+			assert.True(t, spanContext.IsRemote())
+		})
+	}
+}
+
+func TestStartContinuesTrace(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	_, err := Setup(context.Background(), "", "payroll", 0)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, server := Start(Extract(context.Background(), header), "GET /loans", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.(sdktrace.ReadOnlySpan).Parent().SpanID().String())
+	assert.True(t, server.SpanContext().IsSampled(), "the caller sampled the trace, the ratio is for new traces only")
+
+	ctx, child := Start(ctx, "loan.ListLoans")
+	assert.Equal(t, server.SpanContext().TraceID(), child.SpanContext().TraceID())
+	assert.Equal(t, server.SpanContext().SpanID(), child.(sdktrace.ReadOnlySpan).Parent().SpanID())
+	assert.NotEqual(t, server.SpanContext().SpanID(), child.SpanContext().SpanID())
+
+	out := http.Header{}
+	Inject(ctx, out)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+child.SpanContext().SpanID().String()+"-01", out.Get("traceparent"))
+
+	_, root := Start(context.Background(), "job payroll-draft")
+	assert.True(t, root.SpanContext().IsValid(), "a trace not sampled still gets ids for the logs")
+	assert.NotEqual(t, server.SpanContext().TraceID(), root.SpanContext().TraceID())
+	assert.False(t, root.SpanContext().IsSampled(), "ratio 0")
+}
+
+func TestSetupExports(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	var mu sync.Mutex
+	var requests []*coltracepb.ExportTraceServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	provider, err := Setup(context.Background(), collector.URL+"/", "payroll", 1)
+	require.NoError(t, err)
+
+	ctx, server := Start(context.Background(), "HTTP POST", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	server.SetName("POST /timeclock/payroll")
+	_, query := Start(ctx, "postgres Exec", oteltrace.WithSpanKind(oteltrace.SpanKindClient))
+	query.SetStatus(codes.Error, "duplicate key")
+	query.End()
+	server.End()
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	require.Len(t, requests, 1)
+	resource := requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+	assert.Equal(t, "payroll", resource.Resource.Attributes[0].Value.GetStringValue())
+
+	spans := resource.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, "postgres Exec", spans[0].Name)
+	assert.Equal(t, "duplicate key", spans[0].Status.Message)
+	assert.Equal(t, "POST /timeclock/payroll", spans[1].Name)
+	assert.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+}
@@ -1,20 +1,51 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/ariesmaulana/payroll/api"
+	"github.com/ariesmaulana/payroll/app/accounting"
+	accountingLib "github.com/ariesmaulana/payroll/app/accounting/lib"
+	"github.com/ariesmaulana/payroll/app/audit"
+	auditLib "github.com/ariesmaulana/payroll/app/audit/lib"
+	"github.com/ariesmaulana/payroll/app/job"
+	jobLib "github.com/ariesmaulana/payroll/app/job/lib"
+	"github.com/ariesmaulana/payroll/app/loan"
+	loanLib "github.com/ariesmaulana/payroll/app/loan/lib"
+	"github.com/ariesmaulana/payroll/app/tax"
+	taxLib "github.com/ariesmaulana/payroll/app/tax/lib"
 	"github.com/ariesmaulana/payroll/app/timeclock"
+	timeclockLib "github.com/ariesmaulana/payroll/app/timeclock/lib"
 	"github.com/ariesmaulana/payroll/app/user"
+	userLib "github.com/ariesmaulana/payroll/app/user/lib"
+	"github.com/ariesmaulana/payroll/app/webhook"
+	webhookLib "github.com/ariesmaulana/payroll/app/webhook/lib"
+	"github.com/ariesmaulana/payroll/common"
 	"github.com/ariesmaulana/payroll/config"
+	"github.com/ariesmaulana/payroll/data"
+	"github.com/ariesmaulana/payroll/lib/apperror"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog/log"
 
 	"github.com/ariesmaulana/payroll/internal/jwtutil"
+	"github.com/ariesmaulana/payroll/internal/oidc"
+	"github.com/ariesmaulana/payroll/internal/response"
+	"github.com/ariesmaulana/payroll/lib/contextutil"
 	"github.com/ariesmaulana/payroll/lib/database"
 	"github.com/ariesmaulana/payroll/lib/logger"
+	"github.com/ariesmaulana/payroll/lib/metrics"
 	customMiddleware "github.com/ariesmaulana/payroll/lib/middleware"
+	"github.com/ariesmaulana/payroll/lib/notifier"
+	"github.com/ariesmaulana/payroll/lib/openapi"
+	"github.com/ariesmaulana/payroll/lib/outbox"
+	"github.com/ariesmaulana/payroll/lib/scheduler"
+	"github.com/ariesmaulana/payroll/lib/tracing"
 )
 
 func main() {
@@ -25,16 +56,44 @@ func main() {
 	}
 
 	logCfg := logger.LogConfig{
-		Debug:    true,   // set to false in production
-		FilePath: "logs", // logs will be stored in ./logs directory
-		MaxSize:  100,    // rotate logs when file reaches 100MB
+		Debug:      cfg.LogDebug,
+		FilePath:   cfg.LogDir,
+		MaxSize:    int64(cfg.LogMaxSizeMB),
+		MaxAge:     cfg.LogMaxAgeDays,
+		MaxBackups: cfg.LogMaxBackups,
+		Compress:   cfg.LogCompress,
+	}
+	if cfg.LokiURL != "" {
+		logCfg.Loki = &logger.LokiConfig{
+			URL:       cfg.LokiURL,
+			Labels:    cfg.LokiLabels,
+			TenantID:  cfg.LokiTenantID,
+			BatchSize: cfg.LokiBatchSize,
+			QueueSize: cfg.LokiQueueSize,
+		}
 	}
 
 	if err := logger.Init(logCfg); err != nil {
 		panic(fmt.Sprintf("failed to initialize logger: %v", err))
 	}
+	defer logger.Close(context.Background())
+
+	// Spans are exported to the OTLP collector, without one the requests still get a trace id
+	tracerProvider, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint, cfg.TraceServiceName, cfg.TraceSampleRatio)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup tracing")
+	}
+	defer tracerProvider.Shutdown(context.Background())
 
-	jwtutil.SetSecret(cfg.JWTSecret)
+	if cfg.JWTKeysDir != "" {
+		keySet, err := jwtutil.LoadKeySet(cfg.JWTKeysDir, cfg.JWTActiveKid)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load jwt keys")
+		}
+		jwtutil.SetKeySet(keySet)
+	} else {
+		jwtutil.SetSecret(cfg.JWTSecret)
+	}
 
 	// Initialize database connection pool
 	pool, err := database.NewPostgresPool(cfg)
@@ -43,22 +102,211 @@ func main() {
 	}
 	defer pool.Close()
 
+	// `payroll migrate up|down|status` manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), pool, os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("Migration failed")
+		}
+		return
+	}
+
+	if pending, err := pendingMigrations(context.Background(), pool); err != nil {
+		log.Fatal().Err(err).Msg("Failed to read migration status")
+	} else if pending > 0 {
+		log.Warn().Int("pending", pending).Msg("Database schema is behind, run `payroll migrate up`")
+	}
+
+	// Connection pool stats are read at every scrape of /metrics
+	database.RegisterPoolMetrics(metrics.Default, pool)
+
+	// Every service is wrapped by its generated InstrumentedService (go generate ./app/...),
+	// so each method is counted and timed for the handlers and the other services alike
+
+	// Initialize audit trail, every other component records its mutations here
+	auditStorage := audit.NewStorage(pool)
+	auditService := auditLib.Instrument(audit.NewService(auditStorage))
+	auditHandler := audit.NewHandler(auditService)
+
 	// Initialize user components
 	userStorage := user.NewStorage(pool)
-	userService := user.NewService(userStorage)
+	mfaRequiredRoles := make([]data.UserRole, 0, len(cfg.MFARequiredRoles))
+	for _, role := range cfg.MFARequiredRoles {
+		mfaRequiredRoles = append(mfaRequiredRoles, data.UserRole(role))
+	}
+	// stored hash of other algorithm (eg: migrated from Django) is upgraded on login
+	if err := common.SetDefaultPasswordHasher(cfg.PasswordHasher); err != nil {
+		log.Fatal().Err(err).Msg("Invalid PASSWORD_HASHER")
+	}
+
+	passwordPolicy := user.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		ResetTokenTTL: 30 * time.Minute,
+		ResetURL:      cfg.PasswordResetURL,
+	}
+	if cfg.PasswordBreachList != "" {
+		passwordPolicy.Breached, err = user.LoadBreachedPasswords(cfg.PasswordBreachList)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load password breach list")
+		}
+	}
+
+	var userNotifier notifier.Notifier = notifier.LogNotifier{}
+	if cfg.Notifier == "smtp" {
+		userNotifier = notifier.SMTPNotifier{
+			Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}
+	}
+
+	ssoProviders := make(map[string]user.SSOProvider, len(cfg.OIDCProviders))
+	for _, provider := range cfg.OIDCProviders {
+		ssoProviders[provider.Name] = user.SSOProvider{
+			Client: oidc.NewProvider(oidc.Config{
+				Issuer:       provider.Issuer,
+				ClientID:     provider.ClientID,
+				ClientSecret: provider.ClientSecret,
+				RedirectURL:  provider.RedirectURL,
+				Scopes:       provider.Scopes,
+			}),
+			GroupsClaim: provider.GroupsClaim,
+			RoleMap:     provider.RoleMap,
+		}
+	}
+
+	userService := userLib.Instrument(user.NewService(userStorage, user.MFAPolicy{
+		RequiredRoles: mfaRequiredRoles,
+		Issuer:        cfg.MFAIssuer,
+	}, passwordPolicy, userNotifier, ssoProviders, auditService))
 	userHandler := user.NewHandler(userService)
 
+	// Reject access tokens that were logged out, revoked by admin or belong to inactive user
+	customMiddleware.SetSessionCheck(func(ctx context.Context, claims *jwtutil.Claims) *apperror.Error {
+		trace, _ := contextutil.GetTrace(ctx)
+		out := userService.ValidateSession(ctx, &userLib.ValidateSessionIn{
+			Trace:        trace,
+			UserId:       claims.UserID,
+			TokenVersion: claims.TokenVersion,
+			SessionId:    claims.ID,
+		})
+		if out.Success {
+			return nil
+		}
+		if out.Err == nil {
+			return apperror.Internal()
+		}
+		return out.Err
+	})
+
+	// Personal access tokens and service account tokens, for integrations without a human login
+	customMiddleware.SetTokenCheck(func(ctx context.Context, token string, ip string) (*contextutil.AuthUser, *apperror.Error) {
+		trace, _ := contextutil.GetTrace(ctx)
+		out := userService.AuthenticateAPIToken(ctx, &userLib.AuthenticateAPITokenIn{
+			Trace: trace,
+			Token: token,
+			IP:    ip,
+		})
+		if out.Success {
+			return out.User, nil
+		}
+		if out.Err == nil {
+			return nil, apperror.Internal()
+		}
+		return nil, out.Err
+	})
+
+	// Initialize loan (kasbon) component
+	loanStorage := loan.NewStorage(pool)
+	loanService := loanLib.Instrument(loan.NewService(loanStorage, loan.Policy{
+		MinTakeHome: cfg.LoanMinTakeHome,
+	}))
+	loanHandler := loan.NewHandler(loanService)
+
 	//Initialize timeclock component
 	// Setup order (tanpa storage, dummy service aja)
 	timeClockStorage := timeclock.NewStorage(pool)
-	timeClockService := timeclock.NewService(timeClockStorage, userService)
+	timeClockService := timeclockLib.Instrument(timeclock.NewService(timeClockStorage, userService, loanService, auditService))
+	timeclock.RegisterPayrollMetrics(metrics.Default, timeClockStorage)
 	timeClockHandler := timeclock.NewHandler(timeClockService)
 
+	// Initialize accounting component
+	accountingStorage := accounting.NewStorage(pool)
+	accountingService := accountingLib.Instrument(accounting.NewService(accountingStorage, timeClockService, userService))
+	accountingHandler := accounting.NewHandler(accountingService)
+
+	// Initialize tax component
+	taxStorage := tax.NewStorage(pool)
+	taxService := taxLib.Instrument(tax.NewService(taxStorage, timeClockService, userService, tax.Employer{
+		Name: cfg.EmployerName,
+		Npwp: cfg.EmployerNpwp,
+	}))
+	taxHandler := tax.NewHandler(taxService)
+
+	// Initialize scheduled jobs, their run history is kept even when this instance does not run them
+	jobStorage := job.NewStorage(pool)
+	jobService := jobLib.Instrument(job.NewService(jobStorage))
+	jobHandler := job.NewHandler(jobService)
+
+	if cfg.SchedulerEnabled {
+		instance, _ := os.Hostname()
+		jobScheduler := scheduler.New(scheduler.NewAdvisoryLocker(pool), job.NewRecorder(jobStorage), common.JakartaTZ, instance)
+		jobs := job.NewJobs(timeClockService, userService, loanService, userNotifier, job.Policy{
+			ShiftEnd:         cfg.ShiftEnd,
+			PayrollCutoffDay: cfg.PayrollCutoffDay,
+		})
+		if err := jobs.Register(jobScheduler, map[string]string{
+			job.JobAttendanceAutoClose: cfg.JobAutoCloseCron,
+			job.JobClockInReminder:     cfg.JobClockInReminderCron,
+			job.JobPayrollDraft:        cfg.JobPayrollDraftCron,
+			job.JobApprovalReminder:    cfg.JobApprovalReminderCron,
+		}); err != nil {
+			log.Fatal().Err(err).Msg("Invalid job schedule")
+		}
+		go jobScheduler.Run(context.Background())
+	}
+
+	// Initialize webhooks, their deliveries are queued by the webhook sink of the outbox
+	webhookStorage := webhook.NewStorage(pool)
+	webhookService := webhookLib.Instrument(webhook.NewService(webhookStorage))
+	webhookHandler := webhook.NewHandler(webhookService)
+
+	// Relay the domain events of the outbox, every instance may run a dispatcher
+	if len(cfg.OutboxSinks) > 0 {
+		sinks := make([]outbox.Sink, 0, len(cfg.OutboxSinks))
+		for _, name := range cfg.OutboxSinks {
+			switch name {
+			case "log":
+				sinks = append(sinks, outbox.LogSink{})
+			case "http":
+				sinks = append(sinks, outbox.NewHTTPSink(cfg.OutboxHTTPURL, cfg.OutboxHTTPToken))
+			case "webhook":
+				sinks = append(sinks, webhook.NewSink(webhookStorage))
+				go webhook.NewDeliverer(webhookStorage, cfg.WebhookMaxAttempts).Run(context.Background())
+			}
+		}
+		go outbox.NewDispatcher(pool, sinks...).Run(context.Background())
+	}
+
+	// Requests to the routes of the spec are validated before reaching the handlers
+	apiSpec, err := openapi.Load(api.OpenAPI)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load OpenAPI spec")
+	}
+
 	// Setup router with middleware
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(customMiddleware.RealIP(cfg.TrustedProxies))
+	r.Use(customMiddleware.MetricsMiddleware)
 	r.Use(customMiddleware.TraceMiddleware) // Our custom trace middleware
+	r.Use(customMiddleware.OpenAPIValidator(apiSpec))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, r, "Route not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, r, "Method not allowed")
+	})
 
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -66,9 +314,25 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	// Prometheus scrape endpoint
+	r.Handle("/metrics", customMiddleware.MetricsHandler(cfg.MetricsToken))
+
+	// API documentation
+	r.Handle("/openapi.json", openapi.SpecHandler(api.OpenAPI))
+	r.Handle("/docs", openapi.UIHandler("Payroll API", "/openapi.json"))
+
+	// Public keys for other services to verify our access tokens
+	r.Get("/.well-known/jwks.json", jwtutil.JWKSHandler)
+
 	// Register routes
 	user.RegisterRoutes(r, userHandler)
 	timeclock.RegisterRoutes(r, timeClockHandler)
+	accounting.RegisterRoutes(r, accountingHandler)
+	tax.RegisterRoutes(r, taxHandler)
+	loan.RegisterRoutes(r, loanHandler)
+	audit.RegisterRoutes(r, auditHandler)
+	job.RegisterRoutes(r, jobHandler)
+	webhook.RegisterRoutes(r, webhookHandler)
 
 	// Start the server
 	addr := fmt.Sprintf(":%s", cfg.ServerPort)
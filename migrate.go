@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ariesmaulana/payroll/lib/migration"
+	"github.com/ariesmaulana/payroll/migrations"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const migrateUsage = "usage: payroll migrate up | down [steps] | status"
+
+// runMigrate handles `payroll migrate ...`, the schema is versioned in the migrations directory
+func runMigrate(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	migrator, err := migration.New(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migration")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return errors.New("steps must be a positive number")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Unknown {
+				state += " (no file in this build)"
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%-20s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+// pendingMigrations counts the migrations not applied yet, the server still starts but warns
+func pendingMigrations(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	migrator, err := migration.New(pool, migrations.FS)
+	if err != nil {
+		return 0, err
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS payroll_items;
+DROP TABLE IF EXISTS payrolls;
+DROP TABLE IF EXISTS reimbursements;
+DROP TABLE IF EXISTS overtimes;
+DROP TABLE IF EXISTS attendances;
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS user_roles;
@@ -1,5 +1,12 @@
-CREATE TYPE user_roles AS ENUM ('employee', 'admin');
-
+-- the schema of init.sql, every later change is its own version.
+-- The type has no IF NOT EXISTS, a database created from init.sql already has it.
+DO $$
+BEGIN
+    CREATE TYPE user_roles AS ENUM ('employee', 'admin');
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
 
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS account_mappings;
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS bpjs_amount;
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS bonus_amount;
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS overtime_amount;
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS base_salary_amount;
+ALTER TABLE users DROP COLUMN IF EXISTS cost_center;
@@ -0,0 +1,22 @@
+-- expense account of the employee in the payroll journal
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cost_center VARCHAR(50) NOT NULL DEFAULT 'GENERAL';
+
+-- the components of total_salary, every item run before this has them 0
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS base_salary_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS overtime_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS bonus_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS tax_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS bpjs_amount INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS account_mappings (
+    id SERIAL PRIMARY KEY,
+    account_key VARCHAR(50) NOT NULL,
+    cost_center VARCHAR(50) NOT NULL DEFAULT '',
+    account_code VARCHAR(50) NOT NULL,
+    account_name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    updated_by VARCHAR(50),
+    CONSTRAINT unique_account_mapping UNIQUE (account_key, cost_center)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tax_forms_1721a1;
+DROP TABLE IF EXISTS user_tax_profiles;
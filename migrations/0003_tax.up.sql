@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS user_tax_profiles (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    npwp VARCHAR(16) NOT NULL DEFAULT '',
+    nik VARCHAR(16) NOT NULL DEFAULT '',
+    ptkp_status VARCHAR(4) NOT NULL DEFAULT 'TK/0',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    updated_by VARCHAR(50)
+);
+
+CREATE TABLE IF NOT EXISTS tax_forms_1721a1 (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    year INT NOT NULL,
+    number VARCHAR(30) NOT NULL,
+    month_start INT NOT NULL,
+    month_end INT NOT NULL,
+    employer_name VARCHAR(100) NOT NULL DEFAULT '',
+    employer_npwp VARCHAR(16) NOT NULL DEFAULT '',
+    fullname VARCHAR(100) NOT NULL,
+    npwp VARCHAR(16) NOT NULL DEFAULT '',
+    nik VARCHAR(16) NOT NULL DEFAULT '',
+    ptkp_status VARCHAR(4) NOT NULL,
+    salary BIGINT NOT NULL DEFAULT 0,
+    other_allowance BIGINT NOT NULL DEFAULT 0,
+    bonus BIGINT NOT NULL DEFAULT 0,
+    gross BIGINT NOT NULL DEFAULT 0,
+    biaya_jabatan BIGINT NOT NULL DEFAULT 0,
+    pension_contribution BIGINT NOT NULL DEFAULT 0,
+    total_deduction BIGINT NOT NULL DEFAULT 0,
+    netto BIGINT NOT NULL DEFAULT 0,
+    ptkp BIGINT NOT NULL DEFAULT 0,
+    pkp BIGINT NOT NULL DEFAULT 0,
+    tax_due BIGINT NOT NULL DEFAULT 0,
+    tax_withheld BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    updated_by VARCHAR(50),
+    CONSTRAINT unique_1721a1_user_year UNIQUE (user_id, year)
+);
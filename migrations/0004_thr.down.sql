@@ -0,0 +1,2 @@
+ALTER TABLE payrolls DROP COLUMN IF EXISTS payroll_type;
+ALTER TABLE users DROP COLUMN IF EXISTS religion;
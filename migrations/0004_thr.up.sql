@@ -0,0 +1,5 @@
+-- the religious holiday the THR of the employee is paid for
+ALTER TABLE users ADD COLUMN IF NOT EXISTS religion VARCHAR(20) NOT NULL DEFAULT '';
+
+-- regular, thr, ... every payroll run before this is regular
+ALTER TABLE payrolls ADD COLUMN IF NOT EXISTS payroll_type VARCHAR(20) NOT NULL DEFAULT 'regular';
@@ -0,0 +1,4 @@
+-- fails while a period has several runs, they have to be removed first
+DROP INDEX IF EXISTS idx_payrolls_period;
+ALTER TABLE payrolls ADD CONSTRAINT unique_payroll_period UNIQUE (period_start, period_end);
+ALTER TABLE payrolls DROP COLUMN IF EXISTS note;
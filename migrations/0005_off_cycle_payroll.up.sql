@@ -0,0 +1,5 @@
+ALTER TABLE payrolls ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+
+-- a period can have several runs (regular for a subset, bonus, correction, final settlement)
+ALTER TABLE payrolls DROP CONSTRAINT IF EXISTS unique_payroll_period;
+CREATE INDEX IF NOT EXISTS idx_payrolls_period ON payrolls (period_start, period_end);
@@ -0,0 +1,4 @@
+ALTER TABLE payroll_items DROP COLUMN IF EXISTS loan_deduction;
+DROP TABLE IF EXISTS loan_repayments;
+DROP TABLE IF EXISTS loan_installments;
+DROP TABLE IF EXISTS loans;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS loans (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    loan_type VARCHAR(20) NOT NULL,
+    principal INT NOT NULL,
+    interest_rate INT NOT NULL DEFAULT 0, -- basis points per year, flat
+    tenor INT NOT NULL,
+    total_payable INT NOT NULL,
+    outstanding INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reason TEXT NOT NULL DEFAULT '',
+    review_note TEXT NOT NULL DEFAULT '',
+    approved_by VARCHAR(50) NOT NULL DEFAULT '',
+    approved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    updated_by VARCHAR(50)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_user_status ON loans (user_id, status);
+
+CREATE TABLE IF NOT EXISTS loan_installments (
+    id SERIAL PRIMARY KEY,
+    loan_id INT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    due_date DATE NOT NULL,
+    amount INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    CONSTRAINT unique_loan_installment UNIQUE (loan_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS loan_repayments (
+    id SERIAL PRIMARY KEY,
+    loan_id INT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    payroll_id INT,
+    source VARCHAR(20) NOT NULL,
+    amount INT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    -- a payroll deducts a loan at most once, recording the same payroll again is a no-op
+    CONSTRAINT unique_loan_repayment_payroll UNIQUE (loan_id, payroll_id)
+);
+
+-- installments deducted from the net pay of the item
+ALTER TABLE payroll_items ADD COLUMN IF NOT EXISTS loan_deduction INT NOT NULL DEFAULT 0;
@@ -0,0 +1,22 @@
+-- failed login counter per username and per client ip, locked_until grows exponentially with failures
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL, -- username, ip
+    key VARCHAR(255) NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+-- every login attempt and account lock / unlock
+CREATE TABLE IF NOT EXISTS auth_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT,
+    username VARCHAR(50) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    event VARCHAR(30) NOT NULL,
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_username ON auth_events (username, created_at);
@@ -0,0 +1,20 @@
+-- account of an OpenID Connect provider linked to a user, linked on the first SSO login by verified email
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (provider, user_id)
+);
+
+-- pending SSO login, single use: state, nonce and PKCE verifier until the provider redirects back
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,36 @@
+-- append only audit trail of every mutation and of reads of other people's salary,
+-- each event is hash chained to the previous one of its entity type so editing history is detectable
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL,
+    actor VARCHAR(50) NOT NULL,
+    actor_user_id INT NOT NULL DEFAULT 0, -- 0 for system and service account
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(100) NOT NULL DEFAULT '',
+    diff JSONB NOT NULL DEFAULT '{}',
+    trace_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '', -- empty for the first event of the entity type
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events (entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_chain ON audit_events (entity_type, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor, id);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,11 @@
+-- account the net salary is transferred to, exported as bank transfer file after payroll
+CREATE TABLE IF NOT EXISTS user_bank_accounts (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    bank_code VARCHAR(10) NOT NULL,
+    account_number VARCHAR(30) NOT NULL,
+    account_name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    updated_by VARCHAR(50)
+);
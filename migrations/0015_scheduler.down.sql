@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS payroll_drafts;
+ALTER TABLE attendances DROP COLUMN IF EXISTS auto_closed;
@@ -0,0 +1,32 @@
+-- checkout set to the shift end by the scheduler because the employee forgot to check out
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS auto_closed BOOLEAN NOT NULL DEFAULT false;
+
+-- payroll calculated at the cutoff date for review, items is the calculation per employee.
+-- It is not paid, payroll_id is set once the regular payroll of the period is run.
+CREATE TABLE IF NOT EXISTS payroll_drafts (
+    id SERIAL PRIMARY KEY,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    employees INT NOT NULL DEFAULT 0,
+    total_salary INT NOT NULL DEFAULT 0,
+    items JSONB NOT NULL DEFAULT '[]',
+    payroll_id INT REFERENCES payrolls(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(50),
+    UNIQUE (period_start, period_end)
+);
+
+-- every run of a scheduled job, one row per job and schedule time so a run is never done twice
+CREATE TABLE IF NOT EXISTS job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    job VARCHAR(50) NOT NULL,
+    scheduled_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    status VARCHAR(10) NOT NULL, -- running, success, failed
+    detail TEXT NOT NULL DEFAULT '',
+    instance VARCHAR(100) NOT NULL DEFAULT '',
+    UNIQUE (job, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_started ON job_runs (started_at);
@@ -0,0 +1,17 @@
+-- domain events written in the same transaction as the change, relayed to the sinks by the dispatcher
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    trace_id VARCHAR(100) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+-- the dispatcher only reads what is not published yet, oldest first per aggregate
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (aggregate_type, aggregate_id, id) WHERE published_at IS NULL;
@@ -0,0 +1,10 @@
+// Package migrations is the versioned schema of the database, applied with `migrate up`.
+//
+// Each change is a pair of files <version>_<name>.up.sql and <version>_<name>.down.sql.
+// A migration that reached any database is never edited, the change goes into a new version.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS